@@ -0,0 +1,313 @@
+package deployer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	sdkImage "github.com/kairos-io/kairos-sdk/types/images"
+)
+
+// CheckpointFile is the file in the state dir the deployer records the
+// completed steps in.
+const CheckpointFile = ".auroraboot-checkpoints.json"
+
+// checkpoint is a completed step: the fingerprint of what it was run with and
+// of the files it left behind.
+type checkpoint struct {
+	Inputs  string   `json:"inputs"`
+	Outputs []output `json:"outputs,omitempty"`
+}
+
+// output is the fingerprint of a file or directory a step wrote. Directories
+// are only checked to still be there and not be empty, as the steps after
+// the one that fills them may add files to them.
+type output struct {
+	Path    string    `json:"path"`
+	Dir     bool      `json:"dir,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"mod_time,omitempty"`
+}
+
+func (o output) present() bool {
+	info, err := os.Stat(o.Path)
+	if err != nil || info.IsDir() != o.Dir {
+		return false
+	}
+	if o.Dir {
+		entries, err := os.ReadDir(o.Path)
+		return err == nil && len(entries) > 0
+	}
+	return info.Size() == o.Size && info.ModTime().Equal(o.ModTime)
+}
+
+// checkpoints is the state of the checkpoints during a run.
+type checkpoints struct {
+	mu   sync.Mutex
+	path string
+	// steps are the valid checkpoints, from the previous run on resume.
+	steps map[string]checkpoint
+	// inputs are the fingerprints of the inputs of the steps this run.
+	inputs map[string]string
+	deps   map[string][]string
+	// ran are the checkpointed steps that ran this time rather than being
+	// skipped. The steps after them, however far down, run again even when
+	// their own inputs did not change, as the files they read did.
+	ran map[string]bool
+}
+
+// Run runs the registered steps. Each step that completes is recorded in
+// CheckpointFile in the state dir and, when Config.Resume is set, the steps a
+// previous run recorded are skipped as long as their inputs are unchanged,
+// their outputs are still there and none of the steps before them ran again.
+func (d *Deployer) Run(ctx context.Context) error {
+	d.checkpoints = d.loadCheckpoints(ctx)
+	return d.Graph.Run(ctx)
+}
+
+// Resumable reports whether the last Run recorded steps a rerun with
+// Config.Resume can skip.
+func (d *Deployer) Resumable() bool {
+	if d.checkpoints == nil {
+		return false
+	}
+	d.checkpoints.mu.Lock()
+	defer d.checkpoints.mu.Unlock()
+	return len(d.checkpoints.steps) > 0
+}
+
+func (d *Deployer) loadCheckpoints(ctx context.Context) *checkpoints {
+	c := &checkpoints{
+		path:   d.Config.StateDir(CheckpointFile),
+		steps:  map[string]checkpoint{},
+		inputs: map[string]string{},
+		deps:   map[string][]string{},
+		ran:    map[string]bool{},
+	}
+	for _, layer := range d.Analyze() {
+		for _, op := range layer {
+			c.deps[op.Name] = op.Dependencies
+			c.inputs[op.Name] = d.stepInputs(ctx, op.Name)
+		}
+	}
+	if !d.Config.Resume {
+		return c
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			d.Log.Logger.Warn().Err(err).Str("path", c.path).Msg("Failed to read the checkpoints, running every step")
+		}
+		return c
+	}
+	if err := json.Unmarshal(data, &c.steps); err != nil {
+		d.Log.Logger.Warn().Err(err).Str("path", c.path).Msg("Failed to parse the checkpoints, running every step")
+		c.steps = map[string]checkpoint{}
+	}
+	return c
+}
+
+// stepInputs returns the fingerprint of what step is run with. Pulling the
+// source image and writing the cloud config only depend on their own
+// options, so that changing the rest of the config keeps them; the other
+// steps depend on all of it. The images pulled count with the digest their
+// reference points at now, so a moved tag is pulled again.
+func (d *Deployer) stepInputs(ctx context.Context, step string) string {
+	var inputs any
+	switch step {
+	case constants.OpDumpSource:
+		if !d.fromImage() {
+			break
+		}
+		digest, err := d.imageDigest(ctx, d.Artifact.ContainerImage)
+		if err != nil {
+			d.Log.Logger.Warn().Err(err).Str("image", d.Artifact.ContainerImage).Msg("Failed to resolve the image digest, it is pulled again")
+			return ""
+		}
+		inputs = []any{d.Artifact.ContainerImage, digest, d.Config.Arch, d.Config.AllowInsecureRegistriesBool()}
+	case constants.OpCopyCloudConfig:
+		inputs = d.Config.CloudConfig
+	case constants.OpDumpISOImages:
+		var images []string
+		for _, img := range d.Config.ISO.Images {
+			digest, err := d.imageDigest(ctx, img.ContainerImage)
+			if err != nil {
+				d.Log.Logger.Warn().Err(err).Str("image", img.ContainerImage).Msg("Failed to resolve the image digest, it is pulled again")
+				return ""
+			}
+			images = append(images, img.ContainerImage, digest, img.Dir())
+		}
+		inputs = []any{images, d.Config.Arch, d.Config.AllowInsecureRegistriesBool()}
+	default:
+		config := d.Config
+		config.Resume = false
+		inputs = []any{config, d.Artifact}
+	}
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		// Nothing to compare against: the step always runs.
+		return ""
+	}
+	sum := sha256.Sum256(append([]byte(step+"\n"), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// imageDigest returns the digest image, a source of the dump steps, points
+// at now. Sources other than registry images have none and return "".
+func (d *Deployer) imageDigest(ctx context.Context, image string) (string, error) {
+	src, err := sdkImage.NewSrcFromURI(image)
+	if err != nil || !src.IsDocker() {
+		// An invalid reference fails the pull itself
+		return "", nil
+	}
+	if digest := builder.ImageRefDigest(src.Value()); digest != "" {
+		return digest, nil
+	}
+	if d.ImageDigest != nil {
+		return d.ImageDigest(ctx, src.Value())
+	}
+	var opts []name.Option
+	if d.Config.AllowInsecureRegistriesBool() {
+		opts = append(opts, name.Insecure)
+	}
+	ref, err := name.ParseReference(src.Value(), opts...)
+	if err != nil {
+		return "", err
+	}
+	desc, err := remote.Head(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
+
+// checkpointed reports whether step records a checkpoint. Preparing the
+// directories and the servers leave nothing behind to resume from.
+func checkpointed(step string) bool {
+	switch step {
+	case constants.OpPrepareDirs, constants.OpStartHTTPServer, constants.OpStartNetboot:
+		return false
+	}
+	return true
+}
+
+// resumable reports whether step can be skipped: Config.Resume is set and
+// the step has a valid checkpoint.
+func (d *Deployer) resumable(step string) bool {
+	c := d.checkpoints
+	if !d.Config.Resume || c == nil || !checkpointed(step) {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp, ok := c.steps[step]
+	if !ok || cp.Inputs == "" || cp.Inputs != c.inputs[step] {
+		return false
+	}
+	if c.ancestorRan(step) {
+		return false
+	}
+	for _, o := range cp.Outputs {
+		if !o.present() {
+			return false
+		}
+	}
+	return true
+}
+
+// ancestorRan reports whether any step step depends on, directly or through
+// other steps, ran this time. A step in between may not have run itself,
+// being disabled or not checkpointed, and still pass on the files the one
+// before it wrote. c.mu must be held.
+func (c *checkpoints) ancestorRan(step string) bool {
+	seen := map[string]bool{}
+	pending := append([]string(nil), c.deps[step]...)
+	for len(pending) > 0 {
+		dep := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if seen[dep] {
+			continue
+		}
+		seen[dep] = true
+		if c.ran[dep] {
+			return true
+		}
+		pending = append(pending, c.deps[dep]...)
+	}
+	return false
+}
+
+// recordStep records that step ran, with err as its result: a checkpoint of
+// it when it completed, and dropping its previous one when it failed.
+func (d *Deployer) recordStep(step string, err error) {
+	c := d.checkpoints
+	if c == nil || !checkpointed(step) {
+		return
+	}
+	var cp checkpoint
+	if err == nil {
+		cp = checkpoint{Inputs: c.inputs[step], Outputs: d.stepOutputs(step)}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ran[step] = true
+	if err != nil {
+		delete(c.steps, step)
+	} else {
+		c.steps[step] = cp
+	}
+	if err := c.save(); err != nil {
+		d.Log.Logger.Warn().Err(err).Str("path", c.path).Msg("Failed to write the checkpoints")
+	}
+}
+
+// stepOutputs returns the fingerprints of the outputs the plan lists for step.
+func (d *Deployer) stepOutputs(step string) []output {
+	plan := StepPlan{Name: step, Run: true}
+	d.explainStep(&plan)
+
+	var outputs []output
+	for _, pattern := range plan.Outputs {
+		paths, _ := filepath.Glob(pattern)
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			o := output{Path: path, Dir: info.IsDir()}
+			if !o.Dir {
+				o.Size, o.ModTime = info.Size(), info.ModTime()
+			}
+			outputs = append(outputs, o)
+		}
+	}
+	return outputs
+}
+
+// save writes the checkpoints. c.mu must be held.
+func (c *checkpoints) save() error {
+	data, err := json.MarshalIndent(c.steps, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
@@ -0,0 +1,251 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/spectrocloud-labs/herd"
+)
+
+// resumeRun registers a dump-source step unpacking a file into the temp
+// rootfs and a gen-iso step after it, runs them and returns how often each
+// ran. gen-iso fails when failISO is set. With disabledStep, gen-iso only
+// depends on dump-source through that step, which never runs. The image tag
+// points at digest.
+type resumeRun struct {
+	config       schema.Config
+	failISO      bool
+	disabledStep string
+	digest       string
+
+	ran    map[string]int
+	events []Event
+	d      *Deployer
+}
+
+func (r *resumeRun) run(t *testing.T) error {
+	t.Helper()
+	d := NewDeployer(r.config, schema.ReleaseArtifact{ContainerImage: "quay.io/kairos/ubuntu:24.04"})
+	t.Cleanup(func() { os.RemoveAll(d.tmpRootFs()) })
+	d.OnEvent = func(e Event) { r.events = append(r.events, e) }
+	d.ImageDigest = func(context.Context, string) (string, error) { return r.digest, nil }
+	if r.ran == nil {
+		r.ran = map[string]int{}
+	}
+	r.events = nil
+	r.d = d
+
+	step := func(name string, fn func() error, deps ...string) {
+		t.Helper()
+		err := d.Add(name, herd.WithDeps(deps...), herd.WithCallback(d.track(name, func(context.Context) error {
+			r.ran[name]++
+			return fn()
+		})))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	step(constants.OpDumpSource, func() error {
+		if err := os.MkdirAll(d.tmpRootFs(), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(d.tmpRootFs(), "os-release"), []byte("ID=kairos\n"), 0644)
+	})
+	isoDep := constants.OpDumpSource
+	if r.disabledStep != "" {
+		if err := d.Add(r.disabledStep, herd.EnableIf(func() bool { return false }), herd.WithDeps(constants.OpDumpSource)); err != nil {
+			t.Fatal(err)
+		}
+		isoDep = r.disabledStep
+	}
+	step(constants.OpGenISO, func() error {
+		if r.failISO {
+			return errors.New("xorriso failed")
+		}
+		return os.WriteFile(filepath.Join(d.destination(), "kairos.iso"), []byte("iso"), 0644)
+	}, isoDep)
+
+	if err := d.Run(context.Background()); err != nil {
+		return err
+	}
+	return d.CollectErrors()
+}
+
+func TestResumeSkipsCompletedSteps(t *testing.T) {
+	r := &resumeRun{config: schema.Config{State: t.TempDir()}, failISO: true}
+	if err := r.run(t); err == nil {
+		t.Fatal("the first run should fail in gen-iso")
+	}
+	if !r.d.Resumable() {
+		t.Fatal("the first run should have recorded dump-source")
+	}
+
+	r.config.Resume = true
+	r.failISO = false
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+	if r.ran[constants.OpDumpSource] != 1 {
+		t.Errorf("dump-source ran %d times, want it skipped on resume", r.ran[constants.OpDumpSource])
+	}
+	if r.ran[constants.OpGenISO] != 2 {
+		t.Errorf("gen-iso ran %d times, want it run again", r.ran[constants.OpGenISO])
+	}
+	if len(r.events) == 0 || r.events[0].Type != EventStepSkipped || r.events[0].Step != constants.OpDumpSource {
+		t.Errorf("events %v should start with dump-source skipped", r.events)
+	}
+
+	// Both completed: a resume skips everything
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+	if r.ran[constants.OpDumpSource] != 1 || r.ran[constants.OpGenISO] != 2 {
+		t.Errorf("steps ran %v, want both skipped", r.ran)
+	}
+}
+
+func TestResumeRunsStepsWithChangedInputs(t *testing.T) {
+	r := &resumeRun{config: schema.Config{State: t.TempDir()}}
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+
+	// The ISO options are not an input of dump-source
+	r.config.Resume = true
+	r.config.ISO.OverrideName = "custom"
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+	if r.ran[constants.OpDumpSource] != 1 {
+		t.Errorf("dump-source ran %d times, want it skipped", r.ran[constants.OpDumpSource])
+	}
+	if r.ran[constants.OpGenISO] != 2 {
+		t.Errorf("gen-iso ran %d times, want it run again for its new options", r.ran[constants.OpGenISO])
+	}
+}
+
+func TestResumeRunsStepsWithMissingOutputs(t *testing.T) {
+	r := &resumeRun{config: schema.Config{State: t.TempDir()}}
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(r.d.tmpRootFs()); err != nil {
+		t.Fatal(err)
+	}
+
+	r.config.Resume = true
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+	if r.ran[constants.OpDumpSource] != 2 {
+		t.Errorf("dump-source ran %d times, want it run again for its removed rootfs", r.ran[constants.OpDumpSource])
+	}
+	// Its own checkpoint is valid, but the rootfs it read was unpacked again
+	if r.ran[constants.OpGenISO] != 2 {
+		t.Errorf("gen-iso ran %d times, want it run again after dump-source", r.ran[constants.OpGenISO])
+	}
+}
+
+func TestResumeRunsStepsAfterARerunAncestor(t *testing.T) {
+	r := &resumeRun{config: schema.Config{State: t.TempDir()}, disabledStep: constants.OpInjectCC}
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(r.d.tmpRootFs()); err != nil {
+		t.Fatal(err)
+	}
+
+	r.config.Resume = true
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+	if r.ran[constants.OpDumpSource] != 2 {
+		t.Errorf("dump-source ran %d times, want it run again for its removed rootfs", r.ran[constants.OpDumpSource])
+	}
+	// gen-iso reaches dump-source only through a step that did not run
+	if r.ran[constants.OpGenISO] != 2 {
+		t.Errorf("gen-iso ran %d times, want it run again after dump-source", r.ran[constants.OpGenISO])
+	}
+}
+
+func TestResumePullsAMovedTagAgain(t *testing.T) {
+	r := &resumeRun{config: schema.Config{State: t.TempDir()}, digest: "sha256:1111"}
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+
+	r.config.Resume = true
+	r.digest = "sha256:2222"
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+	if r.ran[constants.OpDumpSource] != 2 {
+		t.Errorf("dump-source ran %d times, want it run again for the moved tag", r.ran[constants.OpDumpSource])
+	}
+	if r.ran[constants.OpGenISO] != 2 {
+		t.Errorf("gen-iso ran %d times, want it run again after dump-source", r.ran[constants.OpGenISO])
+	}
+
+	// Unmoved: nothing to do
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+	if r.ran[constants.OpDumpSource] != 2 {
+		t.Errorf("dump-source ran %d times, want it skipped", r.ran[constants.OpDumpSource])
+	}
+}
+
+func TestSourceInputsNeedTheImageDigest(t *testing.T) {
+	d := NewDeployer(schema.Config{}, schema.ReleaseArtifact{ContainerImage: "quay.io/kairos/ubuntu:24.04"})
+	d.ImageDigest = func(context.Context, string) (string, error) { return "", errors.New("registry unreachable") }
+	if got := d.stepInputs(context.Background(), constants.OpDumpSource); got != "" {
+		t.Errorf("inputs %q, want none so that dump-source always runs", got)
+	}
+
+	// A pinned reference needs no lookup
+	d.Artifact.ContainerImage = "quay.io/kairos/ubuntu@sha256:3333"
+	if got := d.stepInputs(context.Background(), constants.OpDumpSource); got == "" {
+		t.Error("want inputs for a pinned reference")
+	}
+}
+
+func TestRunWithoutResumeRunsEverything(t *testing.T) {
+	r := &resumeRun{config: schema.Config{State: t.TempDir()}}
+	for range 2 {
+		if err := r.run(t); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.ran[constants.OpDumpSource] != 2 || r.ran[constants.OpGenISO] != 2 {
+		t.Errorf("steps ran %v, want both run twice", r.ran)
+	}
+	if _, err := os.Stat(filepath.Join(r.config.State, CheckpointFile)); err != nil {
+		t.Errorf("checkpoints not written: %v", err)
+	}
+}
+
+func TestFailedStepDropsItsCheckpoint(t *testing.T) {
+	r := &resumeRun{config: schema.Config{State: t.TempDir()}}
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+
+	// A forced rerun that fails must not leave the older ISO to resume from
+	r.failISO = true
+	if err := r.run(t); err == nil {
+		t.Fatal("gen-iso should fail")
+	}
+	r.config.Resume = true
+	r.failISO = false
+	if err := r.run(t); err != nil {
+		t.Fatal(err)
+	}
+	if r.ran[constants.OpGenISO] != 3 {
+		t.Errorf("gen-iso ran %d times, want it run again after failing", r.ran[constants.OpGenISO])
+	}
+}
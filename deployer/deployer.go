@@ -1,6 +1,7 @@
 package deployer
 
 import (
+	"context"
 	"os"
 
 	"github.com/hashicorp/go-multierror"
@@ -22,6 +23,18 @@ type Deployer struct {
 	// NewDeployer defaults it to internal.Log so callers that don't care
 	// (the CLI) keep their existing terminal output.
 	Log sdklogger.KairosLogger
+	// OnEvent, when set, receives the structured progress of the steps: when
+	// each starts and ends, how long it took, its error, and the bytes done
+	// by the steps that download, pull or write large files. It is called
+	// from the steps' goroutines, concurrently for steps of the same layer,
+	// and must not block. Set it before Run.
+	OnEvent func(Event)
+	// ImageDigest, when set, replaces the registry lookup of the digest a
+	// source image reference points at, which the checkpoints record so a
+	// resume pulls a moved tag again. Tests set it to run offline.
+	ImageDigest func(ctx context.Context, ref string) (string, error)
+
+	checkpoints *checkpoints
 }
 
 func NewDeployer(c schema.Config, a schema.ReleaseArtifact, opts ...herd.GraphOption) *Deployer {
@@ -0,0 +1,95 @@
+package deployer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kairos-io/AuroraBoot/pkg/ops"
+)
+
+// EventType is the kind of an Event.
+type EventType string
+
+const (
+	// EventStepStart is sent when a step callback starts.
+	EventStepStart EventType = "step_start"
+	// EventStepEnd is sent when a step callback returns, with its duration
+	// and error.
+	EventStepEnd EventType = "step_end"
+	// EventProgress is sent while a step transfers or writes bytes: release
+	// downloads, image pulls and the squashfs of an ISO.
+	EventProgress EventType = "progress"
+	// EventStepSkipped is sent instead of EventStepStart and EventStepEnd
+	// for a step a resumed run skips, as a previous run completed it.
+	EventStepSkipped EventType = "step_skipped"
+)
+
+// progressInterval is how often a step sends EventProgress at most.
+const progressInterval = 500 * time.Millisecond
+
+// Event is the structured progress of a deployer step.
+type Event struct {
+	Type EventType `json:"type"`
+	Step string    `json:"step"`
+	Time time.Time `json:"time"`
+	// DurationMS is how long the step ran, on EventStepEnd.
+	DurationMS int64 `json:"duration_ms,omitempty"`
+	// Error is what the step failed with, on EventStepEnd.
+	Error string `json:"error,omitempty"`
+	// Bytes and Total are the bytes done so far and the bytes expected, on
+	// EventProgress. Total is zero when the step does not know it.
+	Bytes int64 `json:"bytes,omitempty"`
+	Total int64 `json:"total,omitempty"`
+}
+
+// Percent returns how far along an EventProgress is, from 0 to 100, or -1
+// when its total is unknown.
+func (e Event) Percent() int {
+	if e.Total <= 0 {
+		return -1
+	}
+	return int(min(100, e.Bytes*100/e.Total))
+}
+
+func (d *Deployer) emit(e Event) {
+	if d.OnEvent == nil {
+		return
+	}
+	e.Time = time.Now()
+	d.OnEvent(e)
+}
+
+// track wraps the callback of step to send its events to OnEvent, and to
+// record or resume from its checkpoint.
+func (d *Deployer) track(step string, fn func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if d.resumable(step) {
+			d.Log.Logger.Info().Str("step", step).Msg("Skipping step completed by a previous run")
+			d.emit(Event{Type: EventStepSkipped, Step: step})
+			return nil
+		}
+		start := time.Now()
+		d.emit(Event{Type: EventStepStart, Step: step})
+
+		var mu sync.Mutex
+		var last time.Time
+		ctx = ops.WithProgress(ctx, func(done, total int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			if now := time.Now(); now.Sub(last) >= progressInterval || (total > 0 && done >= total) {
+				last = now
+				d.emit(Event{Type: EventProgress, Step: step, Bytes: done, Total: total})
+			}
+		})
+
+		err := fn(ctx)
+		d.recordStep(step, err)
+		end := Event{Type: EventStepEnd, Step: step, DurationMS: time.Since(start).Milliseconds()}
+		if err != nil {
+			end.Error = err.Error()
+		}
+		d.emit(end)
+		return err
+	}
+}
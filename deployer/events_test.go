@@ -0,0 +1,81 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/ops"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+)
+
+func TestTrack(t *testing.T) {
+	d := NewDeployer(schema.Config{}, schema.ReleaseArtifact{})
+	var events []Event
+	d.OnEvent = func(e Event) { events = append(events, e) }
+
+	err := d.track("download-iso", func(ctx context.Context) error {
+		progress := ops.ProgressFrom(ctx)
+		progress(10, 100)
+		// Within progressInterval of the last report: dropped
+		progress(20, 100)
+		// Done: always reported
+		progress(100, 100)
+		return errors.New("boom")
+	})(context.Background())
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("track should return the error of the step, got %v", err)
+	}
+
+	var types []EventType
+	for _, e := range events {
+		if e.Step != "download-iso" {
+			t.Errorf("event %v is not for the step", e)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("event %v has no time", e)
+		}
+		types = append(types, e.Type)
+	}
+	want := []EventType{EventStepStart, EventProgress, EventProgress, EventStepEnd}
+	if len(types) != len(want) {
+		t.Fatalf("events %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("events %v, want %v", types, want)
+		}
+	}
+	if p := events[1].Percent(); p != 10 {
+		t.Errorf("first progress at %d%%, want 10", p)
+	}
+	if p := events[2].Percent(); p != 100 {
+		t.Errorf("last progress at %d%%, want 100", p)
+	}
+	if events[3].Error != "boom" {
+		t.Errorf("step_end error %q, want boom", events[3].Error)
+	}
+}
+
+func TestTrackWithoutOnEvent(t *testing.T) {
+	d := NewDeployer(schema.Config{}, schema.ReleaseArtifact{})
+	ran := false
+	if err := d.track("prepare-dirs", func(ctx context.Context) error {
+		ops.ProgressFrom(ctx)(1, 0)
+		ran = true
+		return nil
+	})(context.Background()); err != nil || !ran {
+		t.Errorf("the step should run without OnEvent (ran=%v err=%v)", ran, err)
+	}
+}
+
+func TestEventPercent(t *testing.T) {
+	for _, tc := range []struct {
+		bytes, total int64
+		want         int
+	}{{0, 0, -1}, {5, 0, -1}, {50, 200, 25}, {300, 200, 100}} {
+		if got := (Event{Bytes: tc.bytes, Total: tc.total}).Percent(); got != tc.want {
+			t.Errorf("Percent(%d/%d) = %d, want %d", tc.bytes, tc.total, got, tc.want)
+		}
+	}
+}
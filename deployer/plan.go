@@ -0,0 +1,295 @@
+package deployer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/kairos-io/AuroraBoot/pkg/ops"
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
+	"github.com/spectrocloud-labs/herd"
+)
+
+// Plan is what a deployer would do for its configuration.
+type Plan struct {
+	Steps []StepPlan `json:"steps"`
+}
+
+// StepPlan is what the deployer would do for one registered step.
+type StepPlan struct {
+	Name string `json:"name"`
+	// Layer is the position of the step in the execution order, from 1.
+	// Steps of a layer run concurrently.
+	Layer      int  `json:"layer"`
+	Run        bool `json:"run"`
+	Background bool `json:"background,omitempty"`
+	// Reason says why the step runs or is skipped, in terms of the config.
+	Reason    string   `json:"reason"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Inputs and Outputs are the paths, URLs and addresses the step reads and
+	// writes. Outputs named after the source image are globs, as the name is
+	// only known once it is unpacked.
+	Inputs  []string `json:"inputs,omitempty"`
+	Outputs []string `json:"outputs,omitempty"`
+}
+
+// Plan returns the steps registered on the deployer in execution order, with
+// whether each of them runs and why. It only evaluates the conditions of the
+// steps: nothing is downloaded, built or written. The steps of a layer are
+// sorted by name.
+func (d *Deployer) Plan() Plan {
+	var plan Plan
+	for i, layer := range d.Analyze() {
+		slices.SortFunc(layer, func(a, b herd.GraphEntry) int { return strings.Compare(a.Name, b.Name) })
+		for _, op := range layer {
+			if !op.WithCallback {
+				// The init step of herd.EnableInit
+				continue
+			}
+			// A dependency added by two options of the step is listed twice
+			deps := slices.Clone(op.Dependencies)
+			slices.Sort(deps)
+			step := StepPlan{
+				Name:       op.Name,
+				Layer:      i + 1,
+				Run:        !op.Ignored,
+				Background: op.Background,
+				DependsOn:  slices.Compact(deps),
+			}
+			d.explainStep(&step)
+			plan.Steps = append(plan.Steps, step)
+		}
+	}
+	return plan
+}
+
+// WriteText writes the plan as text, one layer after the other.
+func (p Plan) WriteText(w io.Writer) error {
+	skipped := map[string]bool{}
+	for _, step := range p.Steps {
+		skipped[step.Name] = !step.Run
+	}
+
+	var b strings.Builder
+	layer := 0
+	for _, step := range p.Steps {
+		if step.Layer != layer {
+			if layer != 0 {
+				b.WriteString("\n")
+			}
+			layer = step.Layer
+			fmt.Fprintf(&b, "%d.\n", layer)
+		}
+		state := "run"
+		if !step.Run {
+			state = "skip"
+		} else if step.Background {
+			state = "run in background"
+		}
+		fmt.Fprintf(&b, "  %-20s %s: %s\n", step.Name, state, step.Reason)
+		if !step.Run {
+			continue
+		}
+		if len(step.DependsOn) > 0 {
+			var deps []string
+			for _, dep := range step.DependsOn {
+				if skipped[dep] {
+					dep += " (skipped)"
+				}
+				deps = append(deps, dep)
+			}
+			fmt.Fprintf(&b, "    after:  %s\n", strings.Join(deps, ", "))
+		}
+		for _, in := range step.Inputs {
+			fmt.Fprintf(&b, "    input:  %s\n", in)
+		}
+		for _, out := range step.Outputs {
+			fmt.Fprintf(&b, "    output: %s\n", out)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// explainStep fills in the reason, inputs and outputs of a step. The reasons
+// follow the conditions the steps are registered with in steps.go.
+func (d *Deployer) explainStep(step *StepPlan) {
+	run := step.Run
+	reason := func(ifRun, ifSkipped string) {
+		if run {
+			step.Reason = ifRun
+		} else {
+			step.Reason = ifSkipped
+		}
+	}
+	rawDisk := filepath.Join(d.rawDiskPath(), "kairos-*.raw")
+	rawDiskOutput := func(option string, output string) {
+		reason(fmt.Sprintf("disk.%s is set", option), fmt.Sprintf("disk.%s is not set", option))
+		step.Inputs = []string{rawDisk}
+		step.Outputs = []string{filepath.Join(d.rawDiskPath(), output)}
+	}
+
+	switch step.Name {
+	case constants.OpPrepareDirs:
+		step.Reason = "always runs first"
+		step.Outputs = []string{d.destination(), d.tmpRootFs(), d.dstNetboot()}
+	case constants.OpCopyCloudConfig:
+		step.Reason = "always runs"
+		step.Outputs = []string{d.cloudConfigPath()}
+	case constants.OpDumpSource:
+		reason("container_image is set", "no container_image: the release artifacts are downloaded instead")
+		step.Inputs = []string{d.Artifact.ContainerImage}
+		step.Outputs = []string{d.tmpRootFs()}
+	case constants.OpDumpISOImages:
+		switch {
+		case run:
+			step.Reason = "iso.images is set"
+		case !d.fromImage():
+			step.Reason = "no container_image to build an ISO from"
+		default:
+			step.Reason = "iso.images is not set"
+		}
+		for _, img := range d.Config.ISO.Images {
+			step.Inputs = append(step.Inputs, img.ContainerImage)
+			step.Outputs = append(step.Outputs, d.isoImageRootFs(img))
+		}
+	case constants.OpGenSBOM:
+		switch {
+		case run:
+			step.Reason = fmt.Sprintf("sbom.format is %s", d.Config.SBOM.Format)
+		case !d.fromImage():
+			step.Reason = "no container_image to list the packages of"
+		default:
+			step.Reason = "sbom.format is not set"
+		}
+		step.Inputs = []string{d.tmpRootFs()}
+		step.Outputs = []string{filepath.Join(d.destination(), sbom.FileName(d.Config.SBOM.Format))}
+		if d.Config.SBOM.VulnDB != "" {
+			step.Inputs = append(step.Inputs, d.Config.SBOM.VulnDB)
+			step.Outputs = append(step.Outputs, filepath.Join(d.destination(), sbom.ReportFileName))
+		}
+	case constants.OpGenISO:
+		switch {
+		case run && !d.Config.DisableISOboot:
+			step.Reason = "container_image is set and disable_iso is not"
+		case run:
+			step.Reason = "netboot extracts its artifacts from the ISO"
+		case !d.fromImage():
+			step.Reason = "no container_image: the release ISO is downloaded instead"
+		case d.rawDiskIsSet():
+			step.Reason = "a raw disk output is requested"
+		default:
+			step.Reason = "disable_iso and disable_netboot are set"
+		}
+		step.Inputs = []string{d.tmpRootFs(), d.cloudConfigPath()}
+		for _, img := range d.Config.ISO.Images {
+			step.Inputs = append(step.Inputs, d.isoImageRootFs(img))
+		}
+		step.Outputs = []string{filepath.Join(d.destination(), "*.iso")}
+	case constants.OpDownloadISO:
+		switch {
+		case run:
+			step.Reason = "no container_image: the release ISO is downloaded"
+			step.Outputs = []string{d.getIsoFile()}
+		case d.rawDiskIsSet():
+			step.Reason = "a raw disk output is requested"
+		default:
+			step.Reason = "container_image is set: the ISO is built instead"
+		}
+		step.Inputs = []string{d.Artifact.ISOUrl()}
+	case constants.OpExtractNetboot:
+		switch {
+		case run:
+			step.Reason = "disable_netboot is not set"
+			step.Inputs = []string{d.getIsoFile()}
+		case d.Config.DisableNetboot:
+			step.Reason = "disable_netboot is set"
+		default:
+			step.Reason = "a raw disk output is requested"
+		}
+		step.Outputs = []string{d.squashFSfile(), d.kernelFile(), d.initrdFile()}
+	case constants.OpGenEFIRawDisk:
+		var by []string
+		for option, set := range map[string]bool{
+			"efi": d.Config.Disk.EFI, "gce": d.Config.Disk.GCE, "vhd": d.Config.Disk.VHD, "partitions": d.Config.Disk.Partitions,
+			"maas": d.Config.Disk.MAAS, "qcow2": d.Config.Disk.QCOW2, "vmdk": d.Config.Disk.VMDK, "ova": d.Config.Disk.OVA, "vhdx": d.Config.Disk.VHDX,
+		} {
+			if set {
+				by = append(by, "disk."+option)
+			}
+		}
+		slices.Sort(by)
+		reason("requested by "+strings.Join(by, ", "), "no EFI disk output is requested")
+		step.Inputs = []string{d.tmpRootFs()}
+		step.Outputs = []string{rawDisk}
+		if d.Config.Disk.Partitions {
+			step.Outputs = []string{
+				filepath.Join(d.rawDiskPath(), "efi.img"),
+				filepath.Join(d.rawDiskPath(), "oem.img"),
+				filepath.Join(d.rawDiskPath(), "recovery_partition.img"),
+			}
+		}
+	case constants.OpGenBIOSRawDisk:
+		reason("disk.bios is set", "disk.bios is not set")
+		step.Inputs = []string{d.tmpRootFs()}
+		step.Outputs = []string{rawDisk}
+	case constants.OpConvertQCOW2:
+		rawDiskOutput("qcow2", "kairos-*.qcow2")
+	case constants.OpConvertVMDK:
+		rawDiskOutput("vmdk", "kairos-*.vmdk")
+	case constants.OpConvertOVA:
+		rawDiskOutput("ova", "kairos-*.ova")
+	case constants.OpConvertVHDX:
+		rawDiskOutput("vhdx", "kairos-*.vhdx")
+	case constants.OpConvertGCE:
+		rawDiskOutput("gce", "kairos-*.raw.gce.tar.gz")
+	case constants.OpConvertVHD:
+		rawDiskOutput("vhd", "kairos-*.raw.vhd")
+	case constants.OpConvertMAAS:
+		rawDiskOutput("maas", "kairos-*.raw.gz")
+	case constants.OpInjectCC:
+		switch {
+		case run:
+			step.Reason = "the downloaded release ISO gets the cloud config"
+			step.Inputs = []string{d.cloudConfigPath(), d.getIsoFile()}
+			step.Outputs = []string{d.getIsoFile()}
+		case d.rawDiskIsSet():
+			step.Reason = "a raw disk output is requested"
+		default:
+			step.Reason = "container_image is set: the cloud config is built into the ISO"
+		}
+	case constants.OpCompressOutputs:
+		reason(fmt.Sprintf("compression.format is %s", d.Config.Compression.Format), "compression.format is not set")
+		for _, pattern := range ops.CompressedOutputs {
+			step.Inputs = append(step.Inputs, filepath.Join(d.destination(), pattern))
+			step.Outputs = append(step.Outputs, filepath.Join(d.destination(), pattern+d.Config.Compression.Extension()))
+		}
+		// Compression only updates a manifest that is already there.
+		sums := filepath.Join(d.destination(), checksums.FileName)
+		if _, err := os.Stat(sums); err == nil {
+			step.Outputs = append(step.Outputs, sums)
+		}
+	case constants.OpStartHTTPServer:
+		switch {
+		case run:
+			step.Reason = "serves the artifacts until interrupted"
+		case d.Config.DisableISOboot:
+			step.Reason = "disable_iso is set"
+		default:
+			step.Reason = "disable_http_server is set"
+		}
+		step.Inputs = []string{d.destination()}
+		step.Outputs = []string{"http://" + d.listenAddr()}
+	case constants.OpStartNetboot:
+		reason("netboots machines until interrupted", "disable_netboot is set")
+		step.Inputs = []string{d.cloudConfigPath(), d.squashFSfile(), d.kernelFile(), d.initrdFile()}
+		step.Outputs = []string{fmt.Sprintf("pxe on %s, http on port %s", d.netBootListenAddr(), d.netbootPort())}
+	default:
+		reason("enabled", "disabled")
+	}
+}
@@ -0,0 +1,120 @@
+package deployer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+)
+
+func planFor(t *testing.T, c schema.Config, a schema.ReleaseArtifact) Plan {
+	t.Helper()
+	d := NewDeployer(c, a)
+	if err := RegisterAll(d); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+	return d.Plan()
+}
+
+func planStep(t *testing.T, p Plan, name string) StepPlan {
+	t.Helper()
+	for _, step := range p.Steps {
+		if step.Name == name {
+			return step
+		}
+	}
+	t.Fatalf("%s is not in the plan", name)
+	return StepPlan{}
+}
+
+// The plan of a raw disk build skips the ISO and netboot steps and says which
+// options ask for the raw disk.
+func TestPlanRawDisk(t *testing.T) {
+	p := planFor(t, schema.Config{
+		State: "/tmp/plan",
+		Disk:  schema.Disk{EFI: true, QCOW2: true},
+	}, schema.ReleaseArtifact{ContainerImage: "quay.io/kairos/opensuse:leap-15.6-core-amd64-generic-v3.5.0"})
+
+	iso := planStep(t, p, constants.OpGenISO)
+	if iso.Run || iso.Reason != "a raw disk output is requested" {
+		t.Errorf("gen-iso: run=%v reason=%q", iso.Run, iso.Reason)
+	}
+	raw := planStep(t, p, constants.OpGenEFIRawDisk)
+	if !raw.Run || raw.Reason != "requested by disk.efi, disk.qcow2" {
+		t.Errorf("raw disk: run=%v reason=%q", raw.Run, raw.Reason)
+	}
+	if len(raw.Outputs) != 1 || raw.Outputs[0] != "/tmp/plan/kairos-*.raw" {
+		t.Errorf("raw disk outputs = %v", raw.Outputs)
+	}
+	qcow2 := planStep(t, p, constants.OpConvertQCOW2)
+	if !qcow2.Run || qcow2.Layer <= raw.Layer {
+		t.Errorf("qcow2 should run after the raw disk: run=%v layer %d, raw disk layer %d", qcow2.Run, qcow2.Layer, raw.Layer)
+	}
+	if vmdk := planStep(t, p, constants.OpConvertVMDK); vmdk.Run || vmdk.Reason != "disk.vmdk is not set" {
+		t.Errorf("vmdk: run=%v reason=%q", vmdk.Run, vmdk.Reason)
+	}
+
+	var b strings.Builder
+	if err := p.WriteText(&b); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	for _, want := range []string{
+		"  " + constants.OpGenISO + strings.Repeat(" ", 20-len(constants.OpGenISO)) + " skip: a raw disk output is requested\n",
+		"    output: /tmp/plan/kairos-*.qcow2\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("text plan is missing %q:\n%s", want, b.String())
+		}
+	}
+}
+
+// A multi-image ISO unpacks the additional images before generating the ISO
+// from all of them.
+func TestPlanISOImages(t *testing.T) {
+	c := schema.Config{State: "/tmp/plan", ISO: schema.ISO{Images: []schema.ISOImage{
+		{Name: "Ubuntu standard", ContainerImage: "quay.io/kairos/ubuntu:24.04-standard"},
+	}}}
+	d := NewDeployer(c, schema.ReleaseArtifact{ContainerImage: "quay.io/kairos/ubuntu:24.04-core"})
+	if err := RegisterAll(d); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+	p := d.Plan()
+	rootfs := d.tmpRootFs() + "-ubuntu-standard"
+
+	images := planStep(t, p, constants.OpDumpISOImages)
+	if !images.Run || images.Reason != "iso.images is set" {
+		t.Errorf("dump-iso-images: run=%v reason=%q", images.Run, images.Reason)
+	}
+	if len(images.Outputs) != 1 || images.Outputs[0] != rootfs {
+		t.Errorf("dump-iso-images outputs = %v", images.Outputs)
+	}
+	iso := planStep(t, p, constants.OpGenISO)
+	if !iso.Run || iso.Layer <= images.Layer {
+		t.Errorf("gen-iso should run after dump-iso-images: run=%v layer %d, images layer %d", iso.Run, iso.Layer, images.Layer)
+	}
+	if !slices.Contains(iso.Inputs, rootfs) {
+		t.Errorf("gen-iso inputs %v should include %s", iso.Inputs, rootfs)
+	}
+
+	p = planFor(t, schema.Config{State: "/tmp/plan"}, schema.ReleaseArtifact{ContainerImage: "quay.io/kairos/ubuntu:24.04-core"})
+	if images := planStep(t, p, constants.OpDumpISOImages); images.Run || images.Reason != "iso.images is not set" {
+		t.Errorf("dump-iso-images: run=%v reason=%q", images.Run, images.Reason)
+	}
+}
+
+// Every registered step is explained, not just marked enabled or disabled.
+func TestPlanExplainsEveryStep(t *testing.T) {
+	for _, c := range []schema.Config{
+		{State: "/tmp/plan"},
+		{State: "/tmp/plan", Disk: schema.Disk{BIOS: true}},
+		{State: "/tmp/plan", DisableNetboot: true, DisableHTTPServer: true},
+	} {
+		for _, step := range planFor(t, c, schema.ReleaseArtifact{ContainerImage: "docker:alpine"}).Steps {
+			if step.Reason == "" || step.Reason == "enabled" || step.Reason == "disabled" {
+				t.Errorf("%s has no reason of its own: %q", step.Name, step.Reason)
+			}
+		}
+	}
+}
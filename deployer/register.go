@@ -23,17 +23,25 @@ func RegisterAll(d *Deployer) error {
 		d.PrepDirs,
 		d.StepCopyCloudConfig,
 		d.StepDumpSource,
+		d.StepDumpISOImages,
+		d.StepGenSBOM,
 		d.StepGenISO,
 		d.StepDownloadISO,
 		d.StepExtractNetboot,
 		// Ops to generate RAW disk images
 		d.StepGenRawDisk,
 		d.StepGenMBRRawDisk,
+		d.StepConvertQCOW2,
+		d.StepConvertVMDK,
+		d.StepConvertOVA,
+		d.StepConvertVHDX,
 		d.StepConvertGCE,
 		d.StepConvertVHD,
 		d.StepConvertMAAS,
 		// Inject the data into the ISO
 		d.StepInjectCC,
+		// Compress the outputs once nothing else reads them
+		d.StepCompressOutputs,
 		// Start servers
 		d.StepStartHTTPServer,
 		d.StepStartNetboot,
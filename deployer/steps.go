@@ -12,6 +12,7 @@ import (
 	"github.com/kairos-io/AuroraBoot/pkg/constants"
 
 	"github.com/kairos-io/AuroraBoot/pkg/ops"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
 	"github.com/spectrocloud-labs/herd"
 )
 
@@ -20,6 +21,9 @@ func (d *Deployer) CleanTmpDirs() error {
 	var err *multierror.Error
 	d.Log.Logger.Debug().Str("tmpRootFs", d.tmpRootFs()).Msg("Cleaning up temp rootfs directory")
 	err = multierror.Append(err, os.RemoveAll(d.tmpRootFs()))
+	for _, img := range d.Config.ISO.Images {
+		err = multierror.Append(err, os.RemoveAll(d.isoImageRootFs(img)))
+	}
 	if err.ErrorOrNil() != nil {
 		d.Log.Logger.Error().Err(err).Msg("Failed to remove temp rootfs")
 	}
@@ -38,7 +42,7 @@ func (d *Deployer) CleanTmpDirs() error {
 // PrepDirs prepares the destination directory for the rest of the steps.
 // This is the first step always executed in the deployer, it creates the destination directory in which other build steps will operate.
 func (d *Deployer) PrepDirs() error {
-	return d.Add(constants.OpPrepareDirs, herd.WithCallback(func(ctx context.Context) error {
+	return d.Add(constants.OpPrepareDirs, herd.WithCallback(d.track(constants.OpPrepareDirs, func(ctx context.Context) error {
 		d.Log.Logger.Debug().Str("destination", d.destination()).Msg("Preparing destination temporal directory")
 		if d.destination() == "" {
 			d.Log.Logger.Error().Msg("Destination directory is not set, cannot prepare ISO directory")
@@ -49,23 +53,37 @@ func (d *Deployer) PrepDirs() error {
 			d.Log.Logger.Error().Err(err).Msg("Failed to create destination directory")
 			return err
 		}
+		// A resumed run keeps what the skipped steps unpacked
 		d.Log.Logger.Debug().Str("destination", d.tmpRootFs()).Msg("Preparing temp rootfs directory")
-		err = os.RemoveAll(d.tmpRootFs())
-		if err != nil {
-			d.Log.Logger.Error().Err(err).Msg("Failed to remove temp rootfs")
-			return err
+		if !d.resumable(constants.OpDumpSource) {
+			err = os.RemoveAll(d.tmpRootFs())
+			if err != nil {
+				d.Log.Logger.Error().Err(err).Msg("Failed to remove temp rootfs")
+				return err
+			}
 		}
 		err = os.MkdirAll(d.tmpRootFs(), 0755)
 		if err != nil {
 			d.Log.Logger.Error().Err(err).Msg("Failed to create temp rootfs directory")
 			return err
 		}
+		if !d.resumable(constants.OpDumpISOImages) {
+			for _, img := range d.Config.ISO.Images {
+				err = os.RemoveAll(d.isoImageRootFs(img))
+				if err != nil {
+					d.Log.Logger.Error().Err(err).Msg("Failed to remove temp rootfs")
+					return err
+				}
+			}
+		}
 
 		d.Log.Logger.Debug().Str("destination", d.dstNetboot()).Msg("Preparing temp netboot directory")
-		err = os.RemoveAll(d.dstNetboot())
-		if err != nil {
-			d.Log.Logger.Error().Err(err).Msg("Failed to remove temp netboot dir")
-			return err
+		if !d.resumable(constants.OpExtractNetboot) {
+			err = os.RemoveAll(d.dstNetboot())
+			if err != nil {
+				d.Log.Logger.Error().Err(err).Msg("Failed to remove temp netboot dir")
+				return err
+			}
 		}
 		err = os.MkdirAll(d.dstNetboot(), 0755)
 		if err != nil {
@@ -74,13 +92,13 @@ func (d *Deployer) PrepDirs() error {
 		}
 
 		return nil
-	}))
+	})))
 }
 
 func (d *Deployer) StepCopyCloudConfig() error {
 	return d.Add(constants.OpCopyCloudConfig,
 		herd.WithDeps(constants.OpPrepareDirs),
-		herd.WithCallback(func(ctx context.Context) error {
+		herd.WithCallback(d.track(constants.OpCopyCloudConfig, func(ctx context.Context) error {
 			d.Log.Logger.Info().Str("cloudConfig", d.Config.CloudConfig).Msg("Copying cloud config")
 			if _, err := os.Stat(d.destination()); err != nil && os.IsNotExist(err) {
 				d.Log.Logger.Error().Err(err).Msg("Destination directory does not exist, creating it")
@@ -90,7 +108,7 @@ func (d *Deployer) StepCopyCloudConfig() error {
 			}
 
 			return os.WriteFile(d.cloudConfigPath(), []byte(d.Config.CloudConfig), 0600)
-		}))
+		})))
 }
 
 func (d *Deployer) StepDumpSource() error {
@@ -98,7 +116,37 @@ func (d *Deployer) StepDumpSource() error {
 	d.Log.Logger.Debug().Str("arch", d.Config.Arch).Str("image", d.Artifact.ContainerImage).Msg("StepDumpSource: config arch and image")
 	return d.Add(constants.OpDumpSource,
 		herd.EnableIf(d.fromImage),
-		herd.WithDeps(constants.OpPrepareDirs), herd.WithCallback(ops.DumpSource(d.Artifact.ContainerImage, d.tmpRootFs, d.Config.Arch, d.Config.AllowInsecureRegistriesBool())))
+		herd.WithDeps(constants.OpPrepareDirs), herd.WithCallback(d.track(constants.OpDumpSource, ops.DumpSource(d.Artifact.ContainerImage, d.tmpRootFs, d.Config.Arch, d.Config.AllowInsecureRegistriesBool()))))
+}
+
+// StepDumpISOImages unpacks the additional images of a multi-image ISO, one
+// after the other, each into its own temp rootfs.
+func (d *Deployer) StepDumpISOImages() error {
+	return d.Add(constants.OpDumpISOImages,
+		herd.EnableIf(func() bool { return d.fromImage() && len(d.Config.ISO.Images) > 0 }),
+		herd.WithDeps(constants.OpPrepareDirs),
+		herd.WithCallback(d.track(constants.OpDumpISOImages, func(ctx context.Context) error {
+			for _, img := range d.Config.ISO.Images {
+				d.Log.Logger.Info().Str("image", img.ContainerImage).Str("name", img.Title()).Msg("Unpacking ISO image")
+				dst := d.isoImageRootFs(img)
+				err := ops.DumpSource(img.ContainerImage, func() string { return dst }, d.Config.Arch, d.Config.AllowInsecureRegistriesBool())(ctx)
+				if err != nil {
+					return fmt.Errorf("unpacking %s: %w", img.ContainerImage, err)
+				}
+			}
+			return nil
+		})))
+}
+
+// StepGenSBOM writes an SBOM of the packages installed in the unpacked
+// container image, plus a vulnerability report when a local advisory mirror
+// is configured. It only reads the rootfs, so it runs alongside the output
+// steps rather than ahead of them.
+func (d *Deployer) StepGenSBOM() error {
+	return d.Add(constants.OpGenSBOM,
+		herd.EnableIf(func() bool { return d.fromImage() && d.Config.SBOM.Format != "" }),
+		herd.WithDeps(constants.OpDumpSource),
+		herd.WithCallback(d.track(constants.OpGenSBOM, ops.GenSBOM(d.tmpRootFs, d.destination, d.Config.SBOM, d.Artifact.ContainerImage))))
 }
 
 func (d *Deployer) StepGenISO() error {
@@ -117,14 +165,14 @@ func (d *Deployer) StepGenISO() error {
 			netbootRequested := !d.Config.DisableNetboot
 			return isoRequested || netbootRequested
 		}),
-		herd.WithDeps(constants.OpDumpSource, constants.OpCopyCloudConfig, constants.OpPrepareDirs), herd.WithCallback(ops.GenISO(d.tmpRootFs, d.destination, d.Config.ISO)))
+		herd.WithDeps(constants.OpDumpSource, constants.OpDumpISOImages, constants.OpCopyCloudConfig, constants.OpPrepareDirs), herd.WithCallback(d.track(constants.OpGenISO, ops.GenISO(d.tmpRootFs, d.destination, d.Config.ISO, d.isoImageRootFs))))
 }
 
 func (d *Deployer) StepDownloadISO() error {
 	return d.Add(constants.OpDownloadISO,
 		herd.EnableIf(func() bool { return !d.rawDiskIsSet() && d.isoOption() }),
 		herd.WithDeps(constants.OpPrepareDirs),
-		herd.WithCallback(ops.DownloadArtifact(d.Artifact.ISOUrl(), d.getIsoFile))) // This is okay to call the getIsoFile function here as we want a destination for the ISO file
+		herd.WithCallback(d.track(constants.OpDownloadISO, ops.DownloadArtifact(d.Artifact.ISOUrl(), d.getIsoFile)))) // This is okay to call the getIsoFile function here as we want a destination for the ISO file
 }
 
 // StepExtractNetboot Extract netboot artifacts from the ISO file
@@ -136,39 +184,86 @@ func (d *Deployer) StepExtractNetboot() error {
 		herd.EnableIf(func() bool { return !d.Config.DisableNetboot && !d.rawDiskIsSet() }),
 		herd.ConditionalOption(func() bool { return d.isoOption() }, herd.WithDeps(constants.OpDownloadISO)),
 		herd.ConditionalOption(func() bool { return d.fromImage() }, herd.WithDeps(constants.OpGenISO)),
-		herd.WithDeps(constants.OpGenISO), herd.WithCallback(ops.ExtractNetboot(d.getIsoFile, d.dstNetboot, d.Config.ISO.Name)))
+		herd.WithDeps(constants.OpGenISO), herd.WithCallback(d.track(constants.OpExtractNetboot, ops.ExtractNetboot(d.getIsoFile, d.dstNetboot, d.Config.ISO.Name))))
 }
 
 // StepGenRawDisk Generate the raw disk image.
-// Enabled if is explicitly set the disk.efi or disk.vhd or disk.gce as they depend on the efi disk
+// Enabled if is explicitly set the disk.efi or disk.vhd or disk.gce or disk.qcow2 or disk.vmdk or disk.ova or disk.vhdx as they depend on the efi disk
 func (d *Deployer) StepGenRawDisk() error {
 	return d.Add(constants.OpGenEFIRawDisk,
 		herd.EnableIf(func() bool {
-			return d.Config.Disk.EFI || d.Config.Disk.GCE || d.Config.Disk.VHD || d.Config.Disk.Partitions || d.Config.Disk.MAAS
+			return d.Config.Disk.EFI || d.Config.Disk.GCE || d.Config.Disk.VHD || d.Config.Disk.Partitions || d.Config.Disk.MAAS || d.Config.Disk.QCOW2 || d.Config.Disk.VMDK || d.Config.Disk.OVA || d.Config.Disk.VHDX
 		}),
 		herd.WithDeps(constants.OpDumpSource),
-		herd.WithCallback(ops.GenEFIRawDisk(d.tmpRootFs(), d.rawDiskPath(), d.rawDiskSize(), d.rawDiskStateSize(), d.rawDiskRecoveryImageSize(), d.Config.NoDefaultCloudConfig, d.Config.Disk.Partitions, d.Config.Disk.MAAS)))
+		herd.WithCallback(d.track(constants.OpGenEFIRawDisk, ops.GenEFIRawDisk(d.tmpRootFs(), d.rawDiskPath(), d.rawDiskSize(), d.rawDiskStateSize(), d.rawDiskRecoveryImageSize(), d.Config.NoDefaultCloudConfig, d.Config.Disk.Partitions, d.Config.Disk.MAAS, d.Config.Disk.Layout))))
 }
 
 func (d *Deployer) StepGenMBRRawDisk() error {
 	return d.Add(constants.OpGenBIOSRawDisk,
 		herd.EnableIf(func() bool { return d.Config.Disk.BIOS }),
 		herd.WithDeps(constants.OpDumpSource),
-		herd.WithCallback(ops.GenBiosRawDisk(d.tmpRootFs(), d.rawDiskPath(), d.rawDiskSize(), d.rawDiskStateSize(), d.rawDiskRecoveryImageSize(), d.Config.NoDefaultCloudConfig)))
+		herd.WithCallback(d.track(constants.OpGenBIOSRawDisk, ops.GenBiosRawDisk(d.tmpRootFs(), d.rawDiskPath(), d.rawDiskSize(), d.rawDiskStateSize(), d.rawDiskRecoveryImageSize(), d.Config.NoDefaultCloudConfig, d.Config.Disk.Layout))))
 }
 
+// StepConvertQCOW2 writes a qcow2 image of the raw disk for KVM, Proxmox and
+// OpenStack. It leaves the raw disk in place.
+func (d *Deployer) StepConvertQCOW2() error {
+	return d.Add(constants.OpConvertQCOW2,
+		herd.EnableIf(func() bool { return d.Config.Disk.QCOW2 }),
+		herd.WithDeps(constants.OpGenEFIRawDisk),
+		herd.WithCallback(d.track(constants.OpConvertQCOW2, ops.ConvertRawDiskToQCOW2(d.rawDiskPath(), d.Config.Disk.QCOW2Compress))))
+}
+
+// StepConvertVMDK writes a stream-optimized VMDK of the raw disk for vSphere.
+// It leaves the raw disk in place.
+func (d *Deployer) StepConvertVMDK() error {
+	return d.Add(constants.OpConvertVMDK,
+		herd.EnableIf(func() bool { return d.Config.Disk.VMDK }),
+		herd.WithDeps(constants.OpGenEFIRawDisk),
+		herd.WithCallback(d.track(constants.OpConvertVMDK, ops.ConvertRawDiskToVMDK(d.rawDiskPath()))))
+}
+
+// StepConvertOVA packs the raw disk into an OVA. When a VMDK is produced too,
+// it waits for it and packs it rather than converting the disk twice.
+func (d *Deployer) StepConvertOVA() error {
+	return d.Add(constants.OpConvertOVA,
+		herd.EnableIf(func() bool { return d.Config.Disk.OVA }),
+		herd.WithDeps(constants.OpGenEFIRawDisk),
+		herd.ConditionalOption(func() bool { return d.Config.Disk.VMDK }, herd.WithDeps(constants.OpConvertVMDK)),
+		herd.WithCallback(d.track(constants.OpConvertOVA, ops.ConvertRawDiskToOVA(d.rawDiskPath(), d.Config.Disk.OVF, d.Config.Disk.VMDK))))
+}
+
+// StepConvertVHDX writes a VHDX of the raw disk for Hyper-V generation 2
+// virtual machines. It leaves the raw disk in place.
+func (d *Deployer) StepConvertVHDX() error {
+	return d.Add(constants.OpConvertVHDX,
+		herd.EnableIf(func() bool { return d.Config.Disk.VHDX }),
+		herd.WithDeps(constants.OpGenEFIRawDisk),
+		herd.WithCallback(d.track(constants.OpConvertVHDX, ops.ConvertRawDiskToVHDX(d.rawDiskPath()))))
+}
+
+// The GCE and fixed VHD conversions consume the raw disk, so they wait for
+// the qcow2, VMDK, OVA and VHDX conversions to have read it.
 func (d *Deployer) StepConvertGCE() error {
 	return d.Add(constants.OpConvertGCE,
 		herd.EnableIf(func() bool { return d.Config.Disk.GCE }),
 		herd.WithDeps(constants.OpGenEFIRawDisk),
-		herd.WithCallback(ops.ConvertRawDiskToGCE(d.rawDiskPath())))
+		herd.ConditionalOption(func() bool { return d.Config.Disk.QCOW2 }, herd.WithDeps(constants.OpConvertQCOW2)),
+		herd.ConditionalOption(func() bool { return d.Config.Disk.VMDK }, herd.WithDeps(constants.OpConvertVMDK)),
+		herd.ConditionalOption(func() bool { return d.Config.Disk.OVA }, herd.WithDeps(constants.OpConvertOVA)),
+		herd.ConditionalOption(func() bool { return d.Config.Disk.VHDX }, herd.WithDeps(constants.OpConvertVHDX)),
+		herd.WithCallback(d.track(constants.OpConvertGCE, ops.ConvertRawDiskToGCE(d.rawDiskPath()))))
 }
 
 func (d *Deployer) StepConvertVHD() error {
 	return d.Add(constants.OpConvertVHD,
 		herd.EnableIf(func() bool { return d.Config.Disk.VHD }),
 		herd.WithDeps(constants.OpGenEFIRawDisk),
-		herd.WithCallback(ops.ConvertRawDiskToVHD(d.rawDiskPath())))
+		herd.ConditionalOption(func() bool { return d.Config.Disk.QCOW2 }, herd.WithDeps(constants.OpConvertQCOW2)),
+		herd.ConditionalOption(func() bool { return d.Config.Disk.VMDK }, herd.WithDeps(constants.OpConvertVMDK)),
+		herd.ConditionalOption(func() bool { return d.Config.Disk.OVA }, herd.WithDeps(constants.OpConvertOVA)),
+		herd.ConditionalOption(func() bool { return d.Config.Disk.VHDX }, herd.WithDeps(constants.OpConvertVHDX)),
+		herd.WithCallback(d.track(constants.OpConvertVHD, ops.ConvertRawDiskToVHD(d.rawDiskPath(), d.Config.Disk.VHDDynamic))))
 }
 
 // StepConvertMAAS compresses the raw disk into the ddgz format MAAS expects for
@@ -177,7 +272,7 @@ func (d *Deployer) StepConvertMAAS() error {
 	return d.Add(constants.OpConvertMAAS,
 		herd.EnableIf(func() bool { return d.Config.Disk.MAAS }),
 		herd.WithDeps(constants.OpGenEFIRawDisk),
-		herd.WithCallback(ops.ConvertRawDiskToMAAS(d.rawDiskPath())))
+		herd.WithCallback(d.track(constants.OpConvertMAAS, ops.ConvertRawDiskToMAAS(d.rawDiskPath()))))
 }
 
 func (d *Deployer) StepInjectCC() error {
@@ -186,7 +281,22 @@ func (d *Deployer) StepInjectCC() error {
 		herd.WithDeps(constants.OpCopyCloudConfig),
 		herd.ConditionalOption(d.isoOption, herd.WithDeps(constants.OpDownloadISO)),
 		herd.ConditionalOption(d.fromImage, herd.WithDeps(constants.OpGenISO)),
-		herd.WithCallback(ops.InjectISO(d.destination, d.getIsoFile, d.Config.ISO)))
+		herd.WithCallback(d.track(constants.OpInjectCC, ops.InjectISO(d.destination, d.getIsoFile, d.Config.ISO))))
+}
+
+// StepCompressOutputs replaces the raw disks and VHD with compressed copies
+// and adds one next to the ISO. It waits for every step that writes or reads them; depending on a
+// step that is not enabled does not hold it back.
+func (d *Deployer) StepCompressOutputs() error {
+	return d.Add(constants.OpCompressOutputs,
+		herd.EnableIf(func() bool { return d.Config.Compression.Format != "" }),
+		herd.WithDeps(
+			constants.OpGenISO, constants.OpDownloadISO, constants.OpInjectCC, constants.OpExtractNetboot,
+			constants.OpGenEFIRawDisk, constants.OpGenBIOSRawDisk,
+			constants.OpConvertQCOW2, constants.OpConvertVMDK, constants.OpConvertOVA, constants.OpConvertVHDX,
+			constants.OpConvertGCE, constants.OpConvertVHD, constants.OpConvertMAAS,
+		),
+		herd.WithCallback(d.track(constants.OpCompressOutputs, ops.CompressOutputs(d.destination(), d.Config.Compression))))
 }
 
 func (d *Deployer) StepStartHTTPServer() error {
@@ -198,7 +308,8 @@ func (d *Deployer) StepStartHTTPServer() error {
 			herd.WithDeps(constants.OpGenISO, constants.OpCopyCloudConfig, constants.OpInjectCC),
 			herd.WithDeps(constants.OpDownloadISO, constants.OpCopyCloudConfig, constants.OpInjectCC),
 		),
-		herd.WithCallback(ops.ServeArtifacts(d.listenAddr(), d.destination)),
+		herd.ConditionalOption(func() bool { return d.Config.Compression.Format != "" }, herd.WithDeps(constants.OpCompressOutputs)),
+		herd.WithCallback(d.track(constants.OpStartHTTPServer, ops.ServeArtifacts(d.listenAddr(), d.destination))),
 	)
 }
 
@@ -207,9 +318,9 @@ func (d *Deployer) StepStartNetboot() error {
 		herd.EnableIf(d.netbootOption),
 		herd.Background,
 		herd.WithDeps(constants.OpExtractNetboot, constants.OpCopyCloudConfig),
-		herd.WithCallback(
+		herd.WithCallback(d.track(constants.OpStartNetboot,
 			ops.StartPixiecore(d.cloudConfigPath(), d.netBootListenAddr(), d.netbootPort(), d.squashFSfile, d.initrdFile, d.kernelFile, d.Config.NetBoot),
-		),
+		)),
 	)
 }
 
@@ -246,6 +357,12 @@ func (d *Deployer) tmpRootFs() string {
 	return filepath.Join(os.TempDir(), fmt.Sprintf("auroraboot-temp-rootfs-%x", sum[:]))
 }
 
+// isoImageRootFs returns the temp rootfs an additional image of a
+// multi-image ISO is unpacked into, next to tmpRootFs.
+func (d *Deployer) isoImageRootFs(img schema.ISOImage) string {
+	return d.tmpRootFs() + "-" + img.Dir()
+}
+
 func (d *Deployer) destination() string {
 	return d.Config.State
 }
@@ -285,7 +402,7 @@ func (d *Deployer) dstNetboot() string {
 
 // Returns true if any of the options for raw disk is set
 func (d *Deployer) rawDiskIsSet() bool {
-	return d.Config.Disk.VHD || d.Config.Disk.EFI || d.Config.Disk.GCE || d.Config.Disk.BIOS || d.Config.Disk.Partitions || d.Config.Disk.MAAS
+	return d.Config.Disk.VHD || d.Config.Disk.EFI || d.Config.Disk.GCE || d.Config.Disk.BIOS || d.Config.Disk.Partitions || d.Config.Disk.MAAS || d.Config.Disk.QCOW2 || d.Config.Disk.VMDK || d.Config.Disk.OVA || d.Config.Disk.VHDX
 }
 
 func (d *Deployer) netbootReleaseOption() bool {
@@ -0,0 +1,44 @@
+package deployer
+
+import (
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+)
+
+// Compression runs last, after every output it could compress and every
+// conversion that reads the raw disk.
+func TestStepCompressOutputs(t *testing.T) {
+	d := NewDeployer(schema.Config{
+		Disk:        schema.Disk{EFI: true, QCOW2: true, VHD: true, MAAS: true},
+		Compression: schema.Compression{Format: schema.CompressionZstd},
+	}, schema.ReleaseArtifact{})
+	if err := RegisterAll(d); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+	if found, enabled := opEnabled(d, constants.OpCompressOutputs); !found || !enabled {
+		t.Fatalf("%s should be registered and enabled when compression.format is set (found=%v enabled=%v)", constants.OpCompressOutputs, found, enabled)
+	}
+	layer := map[string]int{}
+	for i, ops := range d.Analyze() {
+		for _, op := range ops {
+			layer[op.Name] = i
+		}
+	}
+	for _, op := range []string{constants.OpGenEFIRawDisk, constants.OpConvertQCOW2, constants.OpConvertVHD, constants.OpConvertMAAS} {
+		if layer[constants.OpCompressOutputs] <= layer[op] {
+			t.Errorf("%s (layer %d) should run after %s (layer %d)", constants.OpCompressOutputs, layer[constants.OpCompressOutputs], op, layer[op])
+		}
+	}
+}
+
+func TestStepCompressOutputsDisabledByDefault(t *testing.T) {
+	d := NewDeployer(schema.Config{Disk: schema.Disk{EFI: true}}, schema.ReleaseArtifact{})
+	if err := RegisterAll(d); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+	if found, enabled := opEnabled(d, constants.OpCompressOutputs); !found || enabled {
+		t.Errorf("%s should be registered and disabled (found=%v enabled=%v)", constants.OpCompressOutputs, found, enabled)
+	}
+}
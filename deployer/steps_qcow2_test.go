@@ -0,0 +1,60 @@
+package deployer
+
+import (
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+)
+
+// A qcow2 output needs the EFI raw disk, and skips the ISO-only steps like
+// the other raw-disk outputs.
+func TestStepConvertQCOW2EnabledForQCOW2(t *testing.T) {
+	d := NewDeployer(schema.Config{Disk: schema.Disk{QCOW2: true, QCOW2Compress: true}}, schema.ReleaseArtifact{})
+	if !d.rawDiskIsSet() {
+		t.Fatal("rawDiskIsSet should be true when Disk.QCOW2 is set")
+	}
+	if err := RegisterAll(d); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+
+	if _, enabled := opEnabled(d, constants.OpGenEFIRawDisk); !enabled {
+		t.Errorf("%s should be enabled when Disk.QCOW2 is set", constants.OpGenEFIRawDisk)
+	}
+	if found, enabled := opEnabled(d, constants.OpConvertQCOW2); !found || !enabled {
+		t.Errorf("%s should be registered and enabled when Disk.QCOW2 is set (found=%v enabled=%v)", constants.OpConvertQCOW2, found, enabled)
+	}
+}
+
+func TestStepConvertQCOW2DisabledWithoutQCOW2(t *testing.T) {
+	d := NewDeployer(schema.Config{}, schema.ReleaseArtifact{})
+	if err := RegisterAll(d); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+
+	found, enabled := opEnabled(d, constants.OpConvertQCOW2)
+	if !found {
+		t.Fatalf("%s should be registered", constants.OpConvertQCOW2)
+	}
+	if enabled {
+		t.Errorf("%s should be disabled when Disk.QCOW2 is not set", constants.OpConvertQCOW2)
+	}
+}
+
+// The VHD conversion renames the raw disk away, so with both outputs it has
+// to run after the qcow2 conversion has read it.
+func TestStepConvertVHDWaitsForQCOW2(t *testing.T) {
+	d := NewDeployer(schema.Config{Disk: schema.Disk{QCOW2: true, VHD: true}}, schema.ReleaseArtifact{})
+	if err := RegisterAll(d); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+	layer := map[string]int{}
+	for i, ops := range d.Analyze() {
+		for _, op := range ops {
+			layer[op.Name] = i
+		}
+	}
+	if layer[constants.OpConvertVHD] <= layer[constants.OpConvertQCOW2] {
+		t.Errorf("%s (layer %d) should run after %s (layer %d)", constants.OpConvertVHD, layer[constants.OpConvertVHD], constants.OpConvertQCOW2, layer[constants.OpConvertQCOW2])
+	}
+}
@@ -0,0 +1,44 @@
+package deployer
+
+import (
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+)
+
+// The SBOM step reads the unpacked container image, so it only runs for builds
+// from an image that asked for an SBOM format.
+func TestStepGenSBOM(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		config  schema.Config
+		image   string
+		enabled bool
+	}{
+		{"requested", schema.Config{SBOM: schema.SBOM{Format: "spdx-json"}}, "quay.io/kairos/ubuntu:24.04", true},
+		{"no format", schema.Config{}, "quay.io/kairos/ubuntu:24.04", false},
+		{"not from an image", schema.Config{SBOM: schema.SBOM{Format: "spdx-json"}}, "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDeployer(tc.config, schema.ReleaseArtifact{ContainerImage: tc.image})
+			if err := RegisterAll(d); err != nil {
+				t.Fatalf("RegisterAll: %v", err)
+			}
+			found, enabled := opEnabled(d, constants.OpGenSBOM)
+			if !found {
+				t.Fatalf("%s should be registered", constants.OpGenSBOM)
+			}
+			if enabled != tc.enabled {
+				t.Errorf("%s enabled = %v, want %v", constants.OpGenSBOM, enabled, tc.enabled)
+			}
+		})
+	}
+}
+
+func TestRegisterAllRejectsUnknownSBOMFormat(t *testing.T) {
+	d := NewDeployer(schema.Config{SBOM: schema.SBOM{Format: "syft-json"}}, schema.ReleaseArtifact{ContainerImage: "img"})
+	if err := RegisterAll(d); err == nil {
+		t.Fatal("RegisterAll should reject an unsupported sbom.format")
+	}
+}
@@ -0,0 +1,45 @@
+package deployer
+
+import (
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+)
+
+// A VHDX output needs the EFI raw disk, and has to read it before the fixed
+// VHD conversion renames it away.
+func TestStepConvertVHDX(t *testing.T) {
+	d := NewDeployer(schema.Config{Disk: schema.Disk{VHDX: true, VHD: true}}, schema.ReleaseArtifact{})
+	if !d.rawDiskIsSet() {
+		t.Fatal("rawDiskIsSet should be true when Disk.VHDX is set")
+	}
+	if err := RegisterAll(d); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+	if _, enabled := opEnabled(d, constants.OpGenEFIRawDisk); !enabled {
+		t.Errorf("%s should be enabled when Disk.VHDX is set", constants.OpGenEFIRawDisk)
+	}
+	if found, enabled := opEnabled(d, constants.OpConvertVHDX); !found || !enabled {
+		t.Errorf("%s should be registered and enabled when Disk.VHDX is set (found=%v enabled=%v)", constants.OpConvertVHDX, found, enabled)
+	}
+	layer := map[string]int{}
+	for i, ops := range d.Analyze() {
+		for _, op := range ops {
+			layer[op.Name] = i
+		}
+	}
+	if layer[constants.OpConvertVHD] <= layer[constants.OpConvertVHDX] {
+		t.Errorf("%s (layer %d) should run after %s (layer %d)", constants.OpConvertVHD, layer[constants.OpConvertVHD], constants.OpConvertVHDX, layer[constants.OpConvertVHDX])
+	}
+}
+
+func TestStepConvertVHDXDisabledWithoutVHDX(t *testing.T) {
+	d := NewDeployer(schema.Config{Disk: schema.Disk{VHD: true, VHDDynamic: true}}, schema.ReleaseArtifact{})
+	if err := RegisterAll(d); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+	if found, enabled := opEnabled(d, constants.OpConvertVHDX); !found || enabled {
+		t.Errorf("%s should be registered and disabled (found=%v enabled=%v)", constants.OpConvertVHDX, found, enabled)
+	}
+}
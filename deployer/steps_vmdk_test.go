@@ -0,0 +1,51 @@
+package deployer
+
+import (
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+)
+
+// VMDK and OVA outputs need the EFI raw disk, like the other raw-disk
+// outputs.
+func TestStepConvertVMDKAndOVAEnabled(t *testing.T) {
+	for _, disk := range []schema.Disk{{VMDK: true}, {OVA: true}} {
+		d := NewDeployer(schema.Config{Disk: disk}, schema.ReleaseArtifact{})
+		if !d.rawDiskIsSet() {
+			t.Fatalf("rawDiskIsSet should be true for %+v", disk)
+		}
+		if err := RegisterAll(d); err != nil {
+			t.Fatalf("RegisterAll: %v", err)
+		}
+		if _, enabled := opEnabled(d, constants.OpGenEFIRawDisk); !enabled {
+			t.Errorf("%s should be enabled for %+v", constants.OpGenEFIRawDisk, disk)
+		}
+		_, vmdk := opEnabled(d, constants.OpConvertVMDK)
+		_, ova := opEnabled(d, constants.OpConvertOVA)
+		if vmdk != disk.VMDK || ova != disk.OVA {
+			t.Errorf("for %+v: %s enabled=%v, %s enabled=%v", disk, constants.OpConvertVMDK, vmdk, constants.OpConvertOVA, ova)
+		}
+	}
+}
+
+// With both outputs the OVA packs the VMDK, so it runs after it; the VHD
+// conversion renames the raw disk away, so it runs after both.
+func TestStepConvertOVAOrdering(t *testing.T) {
+	d := NewDeployer(schema.Config{Disk: schema.Disk{VMDK: true, OVA: true, VHD: true}}, schema.ReleaseArtifact{})
+	if err := RegisterAll(d); err != nil {
+		t.Fatalf("RegisterAll: %v", err)
+	}
+	layer := map[string]int{}
+	for i, ops := range d.Analyze() {
+		for _, op := range ops {
+			layer[op.Name] = i
+		}
+	}
+	if layer[constants.OpConvertOVA] <= layer[constants.OpConvertVMDK] {
+		t.Errorf("%s (layer %d) should run after %s (layer %d)", constants.OpConvertOVA, layer[constants.OpConvertOVA], constants.OpConvertVMDK, layer[constants.OpConvertVMDK])
+	}
+	if layer[constants.OpConvertVHD] <= layer[constants.OpConvertOVA] {
+		t.Errorf("%s (layer %d) should run after %s (layer %d)", constants.OpConvertVHD, layer[constants.OpConvertVHD], constants.OpConvertOVA, layer[constants.OpConvertOVA])
+	}
+}
@@ -92,6 +92,59 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v1/artifacts/diff": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Returns what changed from build ` + "`" + `from` + "`" + ` to build ` + "`" + `to` + "`" + `: the build inputs that differ (base image and digest, Hadron layers, Git commit, ...), line diffs of the Dockerfile and cloud-config, and, read from the outputs, the packages added, removed or upgraded according to the SBOMs, the kernel version, and the files added, removed or changed in the initrd and rootfs. A section is left out when one of the builds does not have it; ` + "`" + `notes` + "`" + ` says why.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Artifacts"
+                ],
+                "summary": "Compare two artifacts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Artifact ID of build A",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Artifact ID of build B",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/artifactdiff.Diff"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/artifacts/{id}": {
             "get": {
                 "security": [
@@ -260,6 +313,174 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v1/artifacts/{id}/publish": {
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Pushes the build's OS container image to \u003chost\u003e/\u003cnamespace\u003e/\u003crepository\u003e under every tag, and/or its output files as one OCI artifact tagged \u003ctag\u003e-artifacts, with one layer per file named by its org.opencontainers.image.title annotation (the ORAS layout). When both are pushed the artifact refers to the image as its subject. The call returns once the push completes and records it on the artifact.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Artifacts"
+                ],
+                "summary": "Publish an artifact to a registry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Artifact ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "What to publish and where",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIPublishRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.Publication"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/artifacts/{id}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Reruns a failed build in place from its stored request. The steps it completed are skipped as long as their inputs are unchanged and their outputs are still there, so a build that failed late does not start from zero. Only the local backend supports it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Artifacts"
+                ],
+                "summary": "Retry a failed build from the failed step",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Artifact ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/builder.BuildStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/artifacts/{id}/sbom": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Names the SBOM document the build produced and, when the builder matched it against a local advisory database, returns the vulnerability summary and findings. The document itself is served by the download endpoint. 404 until the build has written it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Artifacts"
+                ],
+                "summary": "Get an artifact's SBOM and vulnerability report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Artifact ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIArtifactSBOM"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/artifacts/{id}/upload/{filename}": {
             "put": {
                 "description": "Per-build endpoint the operator backend's exporter uses to ship finished artifacts back to AuroraBoot. Bearer is the UploadToken minted at Create time; the admin bearer does not grant access.",
@@ -313,11 +534,17 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handlers.APIError"
                         }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/groups": {
+        "/api/v1/build-sets": {
             "get": {
                 "security": [
                     {
@@ -328,16 +555,16 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Groups"
+                    "Artifacts"
                 ],
-                "summary": "List groups",
+                "summary": "List build sets",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/store.NodeGroup"
+                                "$ref": "#/definitions/handlers.APIBuildSet"
                             }
                         }
                     }
@@ -349,6 +576,7 @@ const docTemplate = `{
                         "AdminBearer": []
                     }
                 ],
+                "description": "Expands the matrix into one artifact build per combination and starts them all. If any child fails to start, the children already started are cancelled and the set is discarded; the error names the failing combination.",
                 "consumes": [
                     "application/json"
                 ],
@@ -356,17 +584,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Groups"
+                    "Artifacts"
                 ],
-                "summary": "Create a group",
+                "summary": "Start a build matrix",
                 "parameters": [
                     {
-                        "description": "Group payload",
+                        "description": "Build matrix",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.APICreateGroupRequest"
+                            "$ref": "#/definitions/handlers.APICreateBuildSetRequest"
                         }
                     }
                 ],
@@ -374,7 +602,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/store.NodeGroup"
+                            "$ref": "#/definitions/handlers.APIBuildSet"
                         }
                     },
                     "400": {
@@ -382,11 +610,17 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handlers.APIError"
                         }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/groups/{id}": {
+        "/api/v1/build-sets/{id}": {
             "get": {
                 "security": [
                     {
@@ -397,13 +631,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Groups"
+                    "Artifacts"
                 ],
-                "summary": "Get a group",
+                "summary": "Get a build set with its children",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Group ID",
+                        "description": "Build set ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -413,7 +647,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/store.NodeGroup"
+                            "$ref": "#/definitions/handlers.APIBuildSet"
                         }
                     },
                     "404": {
@@ -424,46 +658,29 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
+            "delete": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Removes the grouping only. The child artifacts stay and can be managed individually.",
                 "tags": [
-                    "Groups"
+                    "Artifacts"
                 ],
-                "summary": "Update a group",
+                "summary": "Delete a build set",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Group ID",
+                        "description": "Build set ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Update payload",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.APIUpdateGroupRequest"
-                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/store.NodeGroup"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "404": {
                         "description": "Not Found",
@@ -472,80 +689,83 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/v1/build-sets/{id}/cancel": {
+            "post": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
-                "description": "Member nodes are detached in the same transaction; nodes are never deleted.",
+                "description": "Cancels each child still Pending or Building. Finished children are left alone. Returns the set as it stands after the cancellations.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Groups"
+                    "Artifacts"
                 ],
-                "summary": "Delete a group",
+                "summary": "Cancel every unfinished build of a set",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Group ID",
+                        "description": "Build set ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIBuildSet"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/groups/{id}/claim": {
+        "/api/v1/build-sets/{id}/clone": {
             "post": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Starts a new build set from the stored request of an existing one.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Groups"
+                    "Artifacts"
                 ],
-                "summary": "Claim a node from a group",
+                "summary": "Rebuild a whole build matrix",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Group ID",
+                        "description": "Build set ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Claim payload",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.APIClaimRequest"
-                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/store.ManagedNode"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/handlers.APIError"
+                            "$ref": "#/definitions/handlers.APIBuildSet"
                         }
                     },
                     "404": {
@@ -553,57 +773,79 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handlers.APIError"
                         }
-                    },
-                    "409": {
-                        "description": "No unclaimed node available (code=NoCapacity)",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.APIError"
-                        }
                     }
                 }
             }
         },
-        "/api/v1/nodes": {
+        "/api/v1/build-templates": {
             "get": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
-                "description": "Returns every registered node. Optional group/label filters.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Nodes"
-                ],
-                "summary": "List nodes",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Filter by group ID",
-                        "name": "group",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by a single key:value label pair",
-                        "name": "label",
-                        "in": "query"
-                    }
+                    "Artifacts"
                 ],
+                "summary": "List build templates",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/store.ManagedNode"
+                                "$ref": "#/definitions/handlers.APIBuildTemplate"
                             }
                         }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Stores a partial build request under a unique name. String values of the spec may reference the declared parameters as ${{ name }}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Artifacts"
+                ],
+                "summary": "Create a build template",
+                "parameters": [
+                    {
+                        "description": "Template",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIBuildTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIBuildTemplate"
+                        }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/handlers.APIError"
                         }
@@ -611,27 +853,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/nodes/register": {
+        "/api/v1/build-templates/import": {
             "post": {
-                "description": "Idempotent by machineID: if a node with the same machineID already exists, the existing record is returned so the agent can resume with its persisted API key. Authenticated by the registrationToken inside the request body.",
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Creates the template in the YAML document, or replaces the one with the same name. Responds 201 when it was created and 200 when it replaced one.",
                 "consumes": [
-                    "application/json"
+                    "application/yaml"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Agent bootstrap"
+                    "Artifacts"
                 ],
-                "summary": "Register a node",
+                "summary": "Import a build template from YAML",
                 "parameters": [
                     {
-                        "description": "Registration payload",
+                        "description": "Template document",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.APIRegisterRequest"
+                            "$ref": "#/definitions/handlers.APIBuildTemplateRequest"
                         }
                     }
                 ],
@@ -639,13 +886,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.APIRegisterResponse"
+                            "$ref": "#/definitions/handlers.APIBuildTemplate"
                         }
                     },
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/handlers.APIRegisterResponse"
+                            "$ref": "#/definitions/handlers.APIBuildTemplate"
                         }
                     },
                     "400": {
@@ -653,17 +900,11 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handlers.APIError"
                         }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.APIError"
-                        }
                     }
                 }
             }
         },
-        "/api/v1/nodes/{nodeID}": {
+        "/api/v1/build-templates/{id}": {
             "get": {
                 "security": [
                     {
@@ -674,14 +915,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Nodes"
+                    "Artifacts"
                 ],
-                "summary": "Get a node",
+                "summary": "Get a build template",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Node ID",
-                        "name": "nodeID",
+                        "description": "Template ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -690,7 +931,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/store.ManagedNode"
+                            "$ref": "#/definitions/handlers.APIBuildTemplate"
                         }
                     },
                     "404": {
@@ -701,45 +942,102 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
+            "put": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
+                "description": "Replaces the name, description, parameters and spec. Builds already started from the template are not affected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Nodes"
+                    "Artifacts"
                 ],
-                "summary": "Delete a node",
+                "summary": "Replace a build template",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Node ID",
-                        "name": "nodeID",
+                        "description": "Template ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Template",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIBuildTemplateRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIBuildTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/handlers.APIError"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "tags": [
+                    "Artifacts"
+                ],
+                "summary": "Delete a build template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
                     }
                 }
             }
         },
-        "/api/v1/nodes/{nodeID}/commands": {
+        "/api/v1/build-templates/{id}/build": {
             "post": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
+                "description": "Renders the template and starts a build from it. Overrides is a partial build request merged over the spec: objects merge key by key, a cloudConfig override is merged into the template's cloud-config as YAML, and any other value replaces the template's. Parameters are then substituted into every string value; a required parameter without a value, or an undeclared one, is a 400.",
                 "consumes": [
                     "application/json"
                 ],
@@ -747,24 +1045,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Commands"
+                    "Artifacts"
                 ],
-                "summary": "Queue a command for a single node",
+                "summary": "Build from a template",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Node ID",
-                        "name": "nodeID",
+                        "description": "Template ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Command payload",
+                        "description": "Parameters and overrides",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.APICreateCommandRequest"
+                            "$ref": "#/definitions/handlers.APIBuildFromTemplateRequest"
                         }
                     }
                 ],
@@ -772,29 +1070,50 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/store.NodeCommand"
+                            "$ref": "#/definitions/store.ArtifactRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
                         }
                     }
                 }
             }
         },
-        "/api/v1/nodes/{nodeID}/decommission": {
-            "post": {
+        "/api/v1/build-templates/{id}/export": {
+            "get": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
-                "description": "Sends an ` + "`" + `unregister` + "`" + ` command to the node if it is currently online. The UI subscribes to the returned commandID via the UI WebSocket for live progress, then issues DELETE /api/v1/nodes/{nodeID} when the command reaches Completed. When the node is offline, this returns nodeOnline=false with an empty commandID — the operator should then force-delete and run ` + "`" + `kairos-agent phone-home uninstall` + "`" + ` on the box manually.",
+                "description": "Returns the template as a YAML document that POST /api/v1/build-templates/import accepts, for keeping recipes in version control or moving them between servers.",
+                "produces": [
+                    "application/yaml"
+                ],
                 "tags": [
-                    "Nodes"
+                    "Artifacts"
                 ],
-                "summary": "Dispatch remote teardown before deleting a node",
+                "summary": "Export a build template as YAML",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Node ID",
-                        "name": "nodeID",
+                        "description": "Template ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -803,7 +1122,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.decommissionResponse"
+                            "type": "string"
                         }
                     },
                     "404": {
@@ -815,134 +1134,159 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/nodes/{nodeID}/group": {
-            "put": {
+        "/api/v1/channels": {
+            "get": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
-                "consumes": [
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Nodes"
+                    "Channels"
                 ],
-                "summary": "Assign a node to a group",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Node ID",
-                        "name": "nodeID",
-                        "in": "path",
-                        "required": true
-                    },
+                "summary": "List channels",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.Channel"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
                     {
-                        "description": "Target group",
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Creates an empty channel. Names are lowercase and may contain slashes, e.g. \"ubuntu-k3s/stable\".",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Channels"
+                ],
+                "summary": "Create a channel",
+                "parameters": [
+                    {
+                        "description": "Channel",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.APISetGroupRequest"
+                            "$ref": "#/definitions/handlers.APICreateChannelRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/store.Channel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/nodes/{nodeID}/heartbeat": {
-            "post": {
+        "/api/v1/channels/{id}": {
+            "delete": {
                 "security": [
                     {
-                        "NodeAPIKey": []
+                        "AdminBearer": []
                     }
                 ],
-                "description": "Transitions the node to Online and records the latest agent version and OS info.",
-                "consumes": [
-                    "application/json"
-                ],
                 "tags": [
-                    "Agent"
+                    "Channels"
                 ],
-                "summary": "Agent heartbeat",
+                "summary": "Delete a channel",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Node ID",
-                        "name": "nodeID",
+                        "description": "Channel ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Heartbeat payload",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.APIHeartbeatRequest"
-                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "204": {
+                        "description": "No Content"
                     }
                 }
             }
         },
-        "/api/v1/nodes/{nodeID}/labels": {
-            "put": {
+        "/api/v1/channels/{id}/history": {
+            "get": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Nodes"
+                    "Channels"
                 ],
-                "summary": "Replace a node's labels",
+                "summary": "List a channel's promotions and rollbacks",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Node ID",
-                        "name": "nodeID",
+                        "description": "Channel ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Labels payload",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.APISetLabelsRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.ChannelEntry"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/nodes/{nodeID}/release": {
+        "/api/v1/channels/{id}/promote": {
             "post": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
+                "description": "Points the channel at artifactId, or at whatever fromChannel currently points at (dev → staging → prod). The artifact must be Ready and have a container image. Every node in a group following the channel gets an upgrade queued.",
                 "consumes": [
                     "application/json"
                 ],
@@ -950,24 +1294,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Nodes"
+                    "Channels"
                 ],
-                "summary": "Release a node's claim",
+                "summary": "Promote an artifact into a channel",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Node ID",
-                        "name": "nodeID",
+                        "description": "Channel ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Release payload",
+                        "description": "Artifact or source channel",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.APIReleaseRequest"
+                            "$ref": "#/definitions/handlers.APIMoveChannelRequest"
                         }
                     }
                 ],
@@ -975,7 +1319,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.APIReleaseResponse"
+                            "$ref": "#/definitions/handlers.APIChannelMove"
                         }
                     },
                     "400": {
@@ -991,7 +1335,7 @@ const docTemplate = `{
                         }
                     },
                     "409": {
-                        "description": "Claimed by a different key (code=ClaimMismatch)",
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/handlers.APIError"
                         }
@@ -999,40 +1343,77 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/secureboot-keys": {
-            "get": {
+        "/api/v1/channels/{id}/rollback": {
+            "post": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
+                "description": "Points the channel back at the artifact it held before the current one was promoted, or at artifactId if given, which must appear in the channel's history. Rolling back repeatedly walks further back through the promotions. Groups following the channel get an upgrade queued.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecureBoot"
+                    "Channels"
+                ],
+                "summary": "Roll a channel back",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Channel ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Optional target artifact",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIMoveChannelRequest"
+                        }
+                    }
                 ],
-                "summary": "List SecureBoot key sets",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/store.SecureBootKeySet"
-                            }
+                            "$ref": "#/definitions/handlers.APIChannelMove"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
                         }
                     }
                 }
             }
         },
-        "/api/v1/secureboot-keys/generate": {
+        "/api/v1/cloud-config/validate": {
             "post": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
+                "description": "Checks a cloud-config against the Kairos config schema and reports errors (YAML that does not parse, duplicate keys, values the schema rejects) and warnings (misspelt keys, unknown install or stage step options, a missing header), each with its line and column. A build is refused when its cloud-config has errors. Set ` + "`" + `fragment` + "`" + ` for the extra YAML of a build request, which is merged into the generated cloud-config and needs no header. The response is 200 whether or not the config is valid.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1040,31 +1421,62 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "SecureBoot"
+                    "Artifacts"
                 ],
-                "summary": "Generate a SecureBoot key set",
+                "summary": "Lint a cloud-config",
                 "parameters": [
                     {
-                        "description": "Key set name + enroll mode",
+                        "description": "Cloud-config to lint",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.APIGenerateKeySetRequest"
+                            "$ref": "#/definitions/handlers.APIValidateCloudConfigRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/store.SecureBootKeySet"
+                            "$ref": "#/definitions/cloudconfig.Report"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
                         }
                     }
                 }
             }
         },
-        "/api/v1/secureboot-keys/import": {
+        "/api/v1/groups": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Groups"
+                ],
+                "summary": "List groups",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.NodeGroup"
+                            }
+                        }
+                    }
+                }
+            },
             "post": {
                 "security": [
                     {
@@ -1072,39 +1484,35 @@ const docTemplate = `{
                     }
                 ],
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecureBoot"
+                    "Groups"
                 ],
-                "summary": "Import a key set exported from another instance",
+                "summary": "Create a group",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Override the manifest name to avoid a collision",
-                        "name": "name",
-                        "in": "query"
-                    },
-                    {
-                        "type": "file",
-                        "description": "tar.gz produced by the export endpoint",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "description": "Group payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APICreateGroupRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/store.SecureBootKeySet"
+                            "$ref": "#/definitions/store.NodeGroup"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/handlers.APIError"
                         }
@@ -1112,69 +1520,184 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/secureboot-keys/{id}": {
-            "delete": {
+        "/api/v1/groups/{id}": {
+            "get": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
-                "description": "Removes the database record. Key files on disk are left in place.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "SecureBoot"
+                    "Groups"
                 ],
-                "summary": "Delete a key set record",
+                "summary": "Get a group",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Key set ID",
+                        "description": "Group ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
-                    }
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.NodeGroup"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Groups"
+                ],
+                "summary": "Update a group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIUpdateGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.NodeGroup"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Member nodes are detached in the same transaction; nodes are never deleted.",
+                "tags": [
+                    "Groups"
+                ],
+                "summary": "Delete a group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
                 }
             }
         },
-        "/api/v1/secureboot-keys/{id}/export": {
-            "get": {
+        "/api/v1/groups/{id}/claim": {
+            "post": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
-                "description": "Streams a gzipped tar with manifest.json at the root and every key file under keys/. File permissions preserved so private keys remain 0600.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
-                    "application/gzip"
+                    "application/json"
                 ],
                 "tags": [
-                    "SecureBoot"
+                    "Groups"
                 ],
-                "summary": "Export a key set as a portable tar.gz",
+                "summary": "Claim a node from a group",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Key set ID",
+                        "description": "Group ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Claim payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIClaimRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/store.ManagedNode"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "No unclaimed node available (code=NoCapacity)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
                         }
                     }
                 }
             }
         },
-        "/api/v1/settings/image-source": {
+        "/api/v1/image-watch": {
             "get": {
                 "security": [
                     {
@@ -1185,14 +1708,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Settings"
+                    "Artifacts"
                 ],
-                "summary": "Read the runtime image-source settings",
+                "summary": "Read the base image watcher schedule and last run",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.imageSourceResponse"
+                            "$ref": "#/definitions/handlers.APIImageWatchStatus"
                         }
                     }
                 }
@@ -1203,7 +1726,7 @@ const docTemplate = `{
                         "AdminBearer": []
                     }
                 ],
-                "description": "Sets the global default image URL and the local-serve enable flag / advertised URL. Enabling local serve requires a launch-configured listener.",
+                "description": "A five-field cron expression (minute hour day-of-month month day-of-week, in the server's time zone) or one of @hourly, @daily, @weekly and @monthly. An empty schedule turns the watcher off. Saved artifacts and builds made from a template are watched.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1211,21 +1734,38 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Settings"
+                    "Artifacts"
+                ],
+                "summary": "Set the base image watcher schedule",
+                "parameters": [
+                    {
+                        "description": "Schedule",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIImageWatchSettings"
+                        }
+                    }
                 ],
-                "summary": "Update the runtime image-source settings",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.imageSourceResponse"
+                            "$ref": "#/definitions/handlers.APIImageWatchStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
                         }
                     }
                 }
             }
         },
-        "/api/v1/settings/registration-token": {
-            "get": {
+        "/api/v1/image-watch/run": {
+            "post": {
                 "security": [
                     {
                         "AdminBearer": []
@@ -1235,78 +1775,2211 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Settings"
+                    "Artifacts"
                 ],
-                "summary": "Read the current registration token",
+                "summary": "Check watched base images now",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.APIRegistrationTokenResponse"
+                            "$ref": "#/definitions/handlers.APIImageWatchRun"
                         }
                     }
                 }
             }
         },
-        "/api/v1/settings/registration-token/rotate": {
-            "post": {
+        "/api/v1/nodes": {
+            "get": {
                 "security": [
                     {
                         "AdminBearer": []
                     }
                 ],
-                "description": "Generates and persists a new token. Already-registered nodes keep working because they authenticate with their api-key.",
+                "description": "Returns every registered node. Optional group/label filters.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Settings"
+                    "Nodes"
+                ],
+                "summary": "List nodes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by group ID",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by a single key:value label pair",
+                        "name": "label",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Rotate the registration token",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.APIRegistrationTokenResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.ManagedNode"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
                         }
                     }
                 }
             }
         },
-        "/api/v1/system/builder": {
-            "get": {
-                "security": [
-                    {
-                        "AdminBearer": []
-                    }
+        "/api/v1/nodes/register": {
+            "post": {
+                "description": "Idempotent by machineID: if a node with the same machineID already exists, the existing record is returned so the agent can resume with its persisted API key. Authenticated by the registrationToken inside the request body.",
+                "consumes": [
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "System"
+                    "Agent bootstrap"
+                ],
+                "summary": "Register a node",
+                "parameters": [
+                    {
+                        "description": "Registration payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIRegisterRequest"
+                        }
+                    }
                 ],
-                "summary": "Report the active builder backend",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.APISystemBuilder"
+                            "$ref": "#/definitions/handlers.APIRegisterResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIRegisterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.APIArtifactOutputs": {
-            "type": "object",
-            "properties": {
-                "cloudImage": {
-                    "type": "boolean"
-                },
-                "fips": {
-                    "type": "boolean"
+        },
+        "/api/v1/nodes/{nodeID}": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Nodes"
+                ],
+                "summary": "Get a node",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Node ID",
+                        "name": "nodeID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.ManagedNode"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "tags": [
+                    "Nodes"
+                ],
+                "summary": "Delete a node",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Node ID",
+                        "name": "nodeID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/nodes/{nodeID}/commands": {
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Commands"
+                ],
+                "summary": "Queue a command for a single node",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Node ID",
+                        "name": "nodeID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Command payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APICreateCommandRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/store.NodeCommand"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/nodes/{nodeID}/decommission": {
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Sends an ` + "`" + `unregister` + "`" + ` command to the node if it is currently online. The UI subscribes to the returned commandID via the UI WebSocket for live progress, then issues DELETE /api/v1/nodes/{nodeID} when the command reaches Completed. When the node is offline, this returns nodeOnline=false with an empty commandID — the operator should then force-delete and run ` + "`" + `kairos-agent phone-home uninstall` + "`" + ` on the box manually.",
+                "tags": [
+                    "Nodes"
+                ],
+                "summary": "Dispatch remote teardown before deleting a node",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Node ID",
+                        "name": "nodeID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.decommissionResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/nodes/{nodeID}/group": {
+            "put": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Nodes"
+                ],
+                "summary": "Assign a node to a group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Node ID",
+                        "name": "nodeID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target group",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APISetGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/v1/nodes/{nodeID}/heartbeat": {
+            "post": {
+                "security": [
+                    {
+                        "NodeAPIKey": []
+                    }
+                ],
+                "description": "Transitions the node to Online and records the latest agent version and OS info.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Agent"
+                ],
+                "summary": "Agent heartbeat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Node ID",
+                        "name": "nodeID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Heartbeat payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIHeartbeatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/v1/nodes/{nodeID}/labels": {
+            "put": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Nodes"
+                ],
+                "summary": "Replace a node's labels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Node ID",
+                        "name": "nodeID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Labels payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APISetLabelsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/v1/nodes/{nodeID}/release": {
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Nodes"
+                ],
+                "summary": "Release a node's claim",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Node ID",
+                        "name": "nodeID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Release payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIReleaseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIReleaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Claimed by a different key (code=ClaimMismatch)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/plan": {
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Builds the steps of an AuroraBoot configuration, the YAML ` + "`" + `auroraboot --dry-run` + "`" + ` takes, and returns them in execution order: whether each step runs, why, and the paths it reads and writes. Nothing is downloaded or built. Steps of the same layer run concurrently. A configuration that does not validate is a 400.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Artifacts"
+                ],
+                "summary": "Dry-run an AuroraBoot configuration",
+                "parameters": [
+                    {
+                        "description": "AuroraBoot configuration",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIPlanRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/deployer.Plan"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/registries": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Registries"
+                ],
+                "summary": "List publishing registries",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.Registry"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Saves an OCI registry and the credentials to push to it. The password is encrypted at rest and never returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Registries"
+                ],
+                "summary": "Add a publishing registry",
+                "parameters": [
+                    {
+                        "description": "Registry",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIRegistryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/store.Registry"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/registries/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Registries"
+                ],
+                "summary": "Update a publishing registry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Registry",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIRegistryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.Registry"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "tags": [
+                    "Registries"
+                ],
+                "summary": "Remove a publishing registry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/v1/retention": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Retention"
+                ],
+                "summary": "Read the artifact retention policy",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/retention.Policy"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Zero disables a rule. Saved artifacts, running builds and artifacts used by an active deployment, a channel (now or in its history) or the netboot server are never removed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Retention"
+                ],
+                "summary": "Set the artifact retention policy",
+                "parameters": [
+                    {
+                        "description": "Policy",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/retention.Policy"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/retention.Policy"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/retention/plan": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Retention"
+                ],
+                "summary": "Preview what the retention policy would remove",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/retention.Plan"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/retention/run": {
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Retention"
+                ],
+                "summary": "Apply the retention policy now",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIRetentionRun"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/secrets": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Secrets"
+                ],
+                "summary": "List secrets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.Secret"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/secrets/{name}": {
+            "put": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Saves the value under name, replacing any previous one. The value is encrypted at rest and never returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Secrets"
+                ],
+                "summary": "Create or replace a secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Secret name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Secret",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APISecretRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.Secret"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "tags": [
+                    "Secrets"
+                ],
+                "summary": "Delete a secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Secret name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/v1/secureboot-keys": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecureBoot"
+                ],
+                "summary": "List SecureBoot key sets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.SecureBootKeySet"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/secureboot-keys/generate": {
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecureBoot"
+                ],
+                "summary": "Generate a SecureBoot key set",
+                "parameters": [
+                    {
+                        "description": "Key set name + enroll mode",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIGenerateKeySetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/store.SecureBootKeySet"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/secureboot-keys/import": {
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecureBoot"
+                ],
+                "summary": "Import a key set exported from another instance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Override the manifest name to avoid a collision",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "tar.gz produced by the export endpoint",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/store.SecureBootKeySet"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/secureboot-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Removes the database record. Key files on disk are left in place.",
+                "tags": [
+                    "SecureBoot"
+                ],
+                "summary": "Delete a key set record",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/v1/secureboot-keys/{id}/export": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Streams a gzipped tar with manifest.json at the root and every key file under keys/. File permissions preserved so private keys remain 0600.",
+                "produces": [
+                    "application/gzip"
+                ],
+                "tags": [
+                    "SecureBoot"
+                ],
+                "summary": "Export a key set as a portable tar.gz",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/image-source": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Settings"
+                ],
+                "summary": "Read the runtime image-source settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.imageSourceResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Sets the global default image URL and the local-serve enable flag / advertised URL. Enabling local serve requires a launch-configured listener.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Settings"
+                ],
+                "summary": "Update the runtime image-source settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.imageSourceResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/registration-token": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Settings"
+                ],
+                "summary": "Read the current registration token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIRegistrationTokenResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/registration-token/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Generates and persists a new token. Already-registered nodes keep working because they authenticate with their api-key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Settings"
+                ],
+                "summary": "Rotate the registration token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIRegistrationTokenResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/system/builder": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "System"
+                ],
+                "summary": "Report the active builder backend",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APISystemBuilder"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/git": {
+            "post": {
+                "description": "Receives a push event from GitHub, Gitea/Forgejo (X-Hub-Signature-256 over the body with the webhook secret) or GitLab (X-Gitlab-Token set to it). For every artifact whose Git source follows the pushed branch or tag, the most recent build is started again from its original request unless it was already built from the pushed commit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Artifacts"
+                ],
+                "summary": "Rebuild on a Git push",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIGitPushResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/worker/builds/{id}/finish": {
+            "post": {
+                "security": [
+                    {
+                        "WorkerAPIKey": []
+                    }
+                ],
+                "description": "Marks the build Ready or Error and releases it from the queue. On success the worker must have uploaded every output through PUT /api/v1/artifacts/{id}/upload/{filename} first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Workers"
+                ],
+                "summary": "Report the result of a leased build",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Build ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Result",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/builder.WorkerResult"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/worker/builds/{id}/logs": {
+            "post": {
+                "security": [
+                    {
+                        "WorkerAPIKey": []
+                    }
+                ],
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Workers"
+                ],
+                "summary": "Stream build output for a leased build",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Build ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/worker/heartbeat": {
+            "post": {
+                "security": [
+                    {
+                        "WorkerAPIKey": []
+                    }
+                ],
+                "description": "Reports the worker alive and renews the lease of the build it is running. 409 means the lease was lost (expired or cancelled) and the worker must abandon the build.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Workers"
+                ],
+                "summary": "Build worker heartbeat",
+                "parameters": [
+                    {
+                        "description": "Current build",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIWorkerHeartbeatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/worker/lease": {
+            "post": {
+                "security": [
+                    {
+                        "WorkerAPIKey": []
+                    }
+                ],
+                "description": "Hands the oldest queued build for the worker's architecture to the calling worker. 204 means the queue is empty; poll again later. Every call also counts as a heartbeat.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Workers"
+                ],
+                "summary": "Lease the next queued build",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/builder.WorkerLease"
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/workers": {
+            "get": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Workers"
+                ],
+                "summary": "List build workers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.BuildWorker"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/workers/register": {
+            "post": {
+                "description": "Enrolls an ` + "`" + `auroraboot worker` + "`" + ` process. Authenticated by the worker registration token inside the request body. The returned apiKey is shown once; only its digest is stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Workers"
+                ],
+                "summary": "Register a build worker",
+                "parameters": [
+                    {
+                        "description": "Registration payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIWorkerRegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIWorkerRegisterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/workers/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "AdminBearer": []
+                    }
+                ],
+                "description": "Revokes the worker's API key. A build it was running goes back to the queue once its lease lapses.",
+                "tags": [
+                    "Workers"
+                ],
+                "summary": "Remove a build worker",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Worker ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "artifactdiff.Change": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "type": "string"
+                },
+                "b": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                }
+            }
+        },
+        "artifactdiff.Diff": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "type": "string"
+                },
+                "b": {
+                    "type": "string"
+                },
+                "cloudConfig": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "dockerfile": {
+                    "description": "Dockerfile and CloudConfig are line diffs, each line prefixed with\n\"+\", \"-\" or \" \" and runs of unchanged lines cut to a few lines of\ncontext around \"@@\" markers. Empty when the text is the same.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "initrd": {
+                    "$ref": "#/definitions/artifactdiff.FileDiff"
+                },
+                "inputs": {
+                    "description": "Inputs lists the build inputs that differ.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/artifactdiff.Change"
+                    }
+                },
+                "kernel": {
+                    "$ref": "#/definitions/artifactdiff.Change"
+                },
+                "notes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "packages": {
+                    "$ref": "#/definitions/artifactdiff.PackageDiff"
+                },
+                "rootfs": {
+                    "$ref": "#/definitions/artifactdiff.FileDiff"
+                }
+            }
+        },
+        "artifactdiff.File": {
+            "type": "object",
+            "properties": {
+                "link": {
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "artifactdiff.FileChange": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "$ref": "#/definitions/artifactdiff.File"
+                },
+                "b": {
+                    "$ref": "#/definitions/artifactdiff.File"
+                },
+                "path": {
+                    "type": "string"
+                }
+            }
+        },
+        "artifactdiff.FileCounts": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "integer"
+                },
+                "changed": {
+                    "type": "integer"
+                },
+                "removed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "artifactdiff.FileDiff": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/artifactdiff.File"
+                    }
+                },
+                "changed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/artifactdiff.FileChange"
+                    }
+                },
+                "counts": {
+                    "$ref": "#/definitions/artifactdiff.FileCounts"
+                },
+                "removed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/artifactdiff.File"
+                    }
+                },
+                "truncated": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "artifactdiff.PackageChange": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "type": "string"
+                },
+                "arch": {
+                    "type": "string"
+                },
+                "b": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "artifactdiff.PackageDiff": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/sbom.Package"
+                    }
+                },
+                "changed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/artifactdiff.PackageChange"
+                    }
+                },
+                "removed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/sbom.Package"
+                    }
+                }
+            }
+        },
+        "builder.BuildOptions": {
+            "type": "object",
+            "properties": {
+                "baseImage": {
+                    "description": "Legacy flat fields — kept for backward compatibility with existing callers.\nNew code should use the grouped sub-structs above instead.",
+                    "type": "string"
+                },
+                "buildContextDir": {
+                    "description": "directory with files available to COPY in Dockerfile",
+                    "type": "string"
+                },
+                "cloudConfig": {
+                    "description": "YAML cloud-config to bake in",
+                    "type": "string"
+                },
+                "cloudImage": {
+                    "type": "boolean"
+                },
+                "dockerfile": {
+                    "description": "optional Dockerfile content (builds image via docker before ISO)",
+                    "type": "string"
+                },
+                "fips": {
+                    "type": "boolean"
+                },
+                "git": {
+                    "description": "Git, when set, is cloned into the build context before the build. Its\nDockerfile and overlay/ are used unless Dockerfile or OverlayRootfs are\nset, and the commit it resolved to is recorded on the artifact.\nGit.Password is filled in by the handler from the secret store.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/gitsource.Source"
+                        }
+                    ]
+                },
+                "hadronBase": {
+                    "description": "Hadron composition (metadata only — not consumed by the build; the\nrendered Dockerfile is what actually runs). Persisted on the artifact\nrecord so the frontend can rehydrate the composer when cloning.",
+                    "type": "string"
+                },
+                "hadronExtra": {
+                    "type": "string"
+                },
+                "hadronFirmware": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "hadronLayers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "description": "unique build ID",
+                    "type": "string"
+                },
+                "iso": {
+                    "type": "boolean"
+                },
+                "kairosInitImage": {
+                    "type": "string"
+                },
+                "kairosVersion": {
+                    "type": "string"
+                },
+                "kubernetesDistro": {
+                    "type": "string"
+                },
+                "kubernetesVersion": {
+                    "type": "string"
+                },
+                "logRedactValues": {
+                    "description": "LogRedactValues are substrings the builder scrubs from every log line\nbefore persisting to the store or broadcasting to the UI. The Create\nhandler populates this with per-build secrets it just injected into\nthe cloud-config (registration token, default node password) so a\nbuild step that echoes the cloud-config cannot land those values in\nthe GET /logs response or the live log pane. Best-effort: values\nshorter than 8 characters are skipped to avoid replacing unrelated\ntext that happens to contain them, and only verbatim occurrences\nare redacted - anything transformed (base64, JSON-escaped) still\npasses.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "model": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "optional friendly name",
+                    "type": "string"
+                },
+                "netboot": {
+                    "type": "boolean"
+                },
+                "outputDir": {
+                    "description": "where to write artifacts",
+                    "type": "string"
+                },
+                "outputs": {
+                    "$ref": "#/definitions/builder.OutputOptions"
+                },
+                "overlayRootfs": {
+                    "description": "Customization options:",
+                    "type": "string"
+                },
+                "provisioning": {
+                    "$ref": "#/definitions/builder.ProvisioningOptions"
+                },
+                "resume": {
+                    "description": "Resume reruns the failed build ID in place, skipping the steps it\ncompleted whose inputs are unchanged and whose outputs are still in\nits output dir. Only the local backend keeps those between runs; the\nothers return ErrNotSupported.",
+                    "type": "boolean"
+                },
+                "secrets": {
+                    "description": "Secrets are the values of the secrets CloudConfig references as\n{{ secret \"name\" }}, by name. The handler reads them from the secret\nstore and the builder substitutes them only into the config it bakes\nin, so the artifact record and the build manifest keep the reference.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "signing": {
+                    "$ref": "#/definitions/builder.SigningOptions"
+                },
+                "source": {
+                    "description": "Grouped options (preferred for new code).",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/builder.ImageSource"
+                        }
+                    ]
+                },
+                "trustedBoot": {
+                    "type": "boolean"
+                },
+                "uploadToken": {
+                    "description": "UploadToken is the per-build bearer the operator backend's exporter\nJob uses to PUT finished artifacts back to AuroraBoot's upload\nendpoint. Populated by the Create handler on every build regardless\nof backend; the local backend simply ignores it. Never derived from\nthe user request.",
+                    "type": "string"
+                }
+            }
+        },
+        "builder.BuildStatus": {
+            "type": "object",
+            "properties": {
+                "artifacts": {
+                    "description": "paths to built files",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "phase": {
+                    "description": "Pending, Building, Ready, Error",
+                    "type": "string"
+                }
+            }
+        },
+        "builder.ImageSource": {
+            "type": "object",
+            "properties": {
+                "allowInsecureRegistries": {
+                    "description": "AllowInsecureRegistries allows pulling the base image from a registry\nserved over plain HTTP or presenting an untrusted/self-signed TLS\ncertificate.",
+                    "type": "boolean"
+                },
+                "arch": {
+                    "description": "\"amd64\" or \"arm64\"",
+                    "type": "string"
+                },
+                "baseImage": {
+                    "type": "string"
+                },
+                "kairosVersion": {
+                    "type": "string"
+                },
+                "kubernetesDistro": {
+                    "type": "string"
+                },
+                "kubernetesVersion": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "variant": {
+                    "description": "\"core\" or \"standard\"",
+                    "type": "string"
+                }
+            }
+        },
+        "builder.OutputOptions": {
+            "type": "object",
+            "properties": {
+                "cloudImage": {
+                    "type": "boolean"
+                },
+                "compression": {
+                    "description": "Compression replaces the raw disk and VHD outputs with copies\ncompressed as \"zstd\", \"xz\" or \"gzip\" and adds one next to the ISO,\nwhich is kept for booting. Empty leaves them uncompressed.",
+                    "type": "string"
+                },
+                "fips": {
+                    "type": "boolean"
+                },
+                "gce": {
+                    "type": "boolean"
+                },
+                "iso": {
+                    "type": "boolean"
+                },
+                "maas": {
+                    "type": "boolean"
+                },
+                "netboot": {
+                    "type": "boolean"
+                },
+                "ova": {
+                    "type": "boolean"
+                },
+                "ovacpus": {
+                    "type": "integer"
+                },
+                "ovamemoryMB": {
+                    "type": "integer"
+                },
+                "ovauserDataProperty": {
+                    "type": "boolean"
+                },
+                "qcow2": {
+                    "description": "QCOW2 writes a qcow2 image of the raw disk for KVM, Proxmox and\nOpenStack; QCOW2Compress deflates its clusters.",
+                    "type": "boolean"
+                },
+                "qcow2Compress": {
+                    "type": "boolean"
+                },
+                "rawDisk": {
+                    "type": "boolean"
+                },
+                "sbom": {
+                    "description": "SBOM writes a software bill of materials of the packages installed in\nthe built image next to the other outputs, in SBOMFormat\n(sbom.FormatSPDX when empty).",
+                    "type": "boolean"
+                },
+                "sbomformat": {
+                    "type": "string"
+                },
+                "tar": {
+                    "type": "boolean"
+                },
+                "trustedBoot": {
+                    "type": "boolean"
+                },
+                "uki": {
+                    "type": "boolean"
+                },
+                "vhd": {
+                    "type": "boolean"
+                },
+                "vhddynamic": {
+                    "description": "VHDDynamic makes the VHD output a sparse, dynamic VHD (Azure only\ntakes fixed ones). VHDX writes a VHDX for Hyper-V generation 2\nvirtual machines.",
+                    "type": "boolean"
+                },
+                "vhdx": {
+                    "type": "boolean"
+                },
+                "vmdk": {
+                    "description": "VMDK writes a stream-optimized VMDK of the raw disk for vSphere; OVA\npacks one with an OVF descriptor of an EFI virtual machine with\nOVACPUs and OVAMemoryMB (2 and 4096 when zero), and a user-data vApp\nproperty with OVAUserDataProperty.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "builder.ProvisioningOptions": {
+            "type": "object",
+            "properties": {
+                "allowedCommands": {
+                    "description": "AllowedCommands is the explicit phonehome.allowed_commands list baked\ninto the cloud-config. The AuroraBoot backend substitutes the safe\ndefault set when the caller leaves this nil, so the emitted YAML always\ncarries the key — nodes never inherit an implicit agent-side default.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "autoInstall": {
+                    "type": "boolean"
+                },
+                "kubernetesEnabled": {
+                    "description": "KubernetesEnabled controls whether k3s/k0s starts on first boot on a\nStandard-variant image. The handler resolves the request's tri-state\n(nil defaults to true) into this bool before handing it to the builder,\nwhich persists it on the artifact record so the frontend can rehydrate\nthe Kubernetes card when cloning.",
+                    "type": "boolean"
+                },
+                "registerAuroraBoot": {
+                    "type": "boolean"
+                },
+                "targetGroupID": {
+                    "type": "string"
+                }
+            }
+        },
+        "builder.SigningOptions": {
+            "type": "object",
+            "properties": {
+                "manifestKey": {
+                    "description": "ManifestKey is the PEM private key the SHA256SUMS manifest of the\noutputs is signed with (see pkg/checksums). Empty leaves it unsigned.\nIt is a path on the host running the builder, so backends that build\nelsewhere leave signing to the server as the files are uploaded.",
+                    "type": "string"
+                },
+                "manifestKeySetID": {
+                    "description": "ManifestKeySetID names the SecureBoot key set ManifestKey came from,\nrecorded on the artifact; empty for the server's own manifest key.",
+                    "type": "string"
+                },
+                "ukipublicKeysDir": {
+                    "type": "string"
+                },
+                "ukisecureBootCert": {
+                    "type": "string"
+                },
+                "ukisecureBootEnroll": {
+                    "type": "string"
+                },
+                "ukisecureBootKey": {
+                    "type": "string"
+                },
+                "ukitpmpcrkey": {
+                    "type": "string"
+                }
+            }
+        },
+        "builder.WorkerLease": {
+            "type": "object",
+            "properties": {
+                "buildId": {
+                    "type": "string"
+                },
+                "leaseExpiresAt": {
+                    "type": "string"
+                },
+                "options": {
+                    "$ref": "#/definitions/builder.BuildOptions"
+                }
+            }
+        },
+        "builder.WorkerResult": {
+            "type": "object",
+            "properties": {
+                "baseImageDigest": {
+                    "type": "string"
+                },
+                "containerImage": {
+                    "type": "string"
+                },
+                "containerImageDigest": {
+                    "type": "string"
+                },
+                "gitCommit": {
+                    "description": "GitCommit is what the build's Git ref resolved to, for builds with a\nGit source.",
+                    "type": "string"
+                },
+                "kairosInitImage": {
+                    "type": "string"
+                },
+                "kairosInitImageDigest": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "vulnerabilities": {
+                    "description": "Vulnerabilities is the summary of the worker's offline advisory match,\nnil when the build had no SBOM or the worker has no advisory database.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/store.VulnerabilitySummary"
+                        }
+                    ]
+                }
+            }
+        },
+        "cloudconfig.Issue": {
+            "type": "object",
+            "properties": {
+                "column": {
+                    "type": "integer"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "severity": {
+                    "enum": [
+                        "error",
+                        "warning"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/cloudconfig.Severity"
+                        }
+                    ]
+                }
+            }
+        },
+        "cloudconfig.Report": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/cloudconfig.Issue"
+                    }
+                },
+                "valid": {
+                    "type": "boolean"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/cloudconfig.Issue"
+                    }
+                }
+            }
+        },
+        "cloudconfig.Severity": {
+            "type": "string",
+            "enum": [
+                "error",
+                "warning"
+            ],
+            "x-enum-varnames": [
+                "SeverityError",
+                "SeverityWarning"
+            ]
+        },
+        "deployer.Plan": {
+            "type": "object",
+            "properties": {
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/deployer.StepPlan"
+                    }
+                }
+            }
+        },
+        "deployer.StepPlan": {
+            "type": "object",
+            "properties": {
+                "background": {
+                    "type": "boolean"
+                },
+                "depends_on": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "inputs": {
+                    "description": "Inputs and Outputs are the paths, URLs and addresses the step reads and\nwrites. Outputs named after the source image are globs, as the name is\nonly known once it is unpacked.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "layer": {
+                    "description": "Layer is the position of the step in the execution order, from 1.\nSteps of a layer run concurrently.",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "outputs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "reason": {
+                    "description": "Reason says why the step runs or is skipped, in terms of the config.",
+                    "type": "string"
+                },
+                "run": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "gitsource.Source": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "ref": {
+                    "description": "Ref is a branch, tag or commit. Empty means the default branch.",
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Secret names the secret store entry Password was read from. Clone\nignores it; builders record it on the artifact.",
+                    "type": "string"
+                },
+                "subpath": {
+                    "description": "Subpath is the directory inside the repository holding the\nDockerfile and overlay. Empty means the repository root.",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "URL is an http(s) clone URL.",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Username and Password authenticate over HTTP. Password is usually a\ntoken; most forges accept any non-empty username with one.",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIArtifactOutputs": {
+            "type": "object",
+            "properties": {
+                "cloudImage": {
+                    "type": "boolean"
+                },
+                "compression": {
+                    "description": "Compression replaces the raw disk and VHD outputs with compressed\ncopies and adds one next to the ISO, which is kept for booting.\nEmpty leaves them uncompressed.",
+                    "type": "string",
+                    "enum": [
+                        "zstd",
+                        "xz",
+                        "gzip"
+                    ]
+                },
+                "fips": {
+                    "type": "boolean"
                 },
                 "gce": {
                     "type": "boolean"
@@ -1320,87 +3993,330 @@ const docTemplate = `{
                 "netboot": {
                     "type": "boolean"
                 },
+                "ova": {
+                    "type": "boolean"
+                },
+                "ovaCpus": {
+                    "type": "integer"
+                },
+                "ovaMemoryMB": {
+                    "type": "integer"
+                },
+                "ovaUserDataProperty": {
+                    "type": "boolean"
+                },
+                "qcow2": {
+                    "description": "QCOW2 adds a qcow2 image of the raw disk for KVM, Proxmox and\nOpenStack; QCOW2Compress deflates its clusters.",
+                    "type": "boolean"
+                },
+                "qcow2Compress": {
+                    "type": "boolean"
+                },
                 "rawDisk": {
                     "type": "boolean"
                 },
-                "tar": {
-                    "type": "boolean"
+                "sbom": {
+                    "description": "SBOM adds a software bill of materials of the image's installed\npackages to the outputs. SBOMFormat defaults to spdx-json.",
+                    "type": "boolean"
+                },
+                "sbomFormat": {
+                    "type": "string",
+                    "enum": [
+                        "spdx-json",
+                        "cyclonedx-json"
+                    ]
+                },
+                "tar": {
+                    "type": "boolean"
+                },
+                "trustedBoot": {
+                    "type": "boolean"
+                },
+                "uki": {
+                    "type": "boolean"
+                },
+                "vhd": {
+                    "type": "boolean"
+                },
+                "vhdDynamic": {
+                    "description": "VHDDynamic makes the VHD output a sparse, dynamic VHD instead of the\nfixed one Azure requires. VHDX adds a VHDX for Hyper-V generation 2\nvirtual machines.",
+                    "type": "boolean"
+                },
+                "vhdx": {
+                    "type": "boolean"
+                },
+                "vmdk": {
+                    "description": "VMDK adds a stream-optimized VMDK for vSphere. OVA packs one with an\nOVF descriptor of an EFI virtual machine of OVACPUs CPUs and\nOVAMemoryMB MiB (2 and 4096 when zero); OVAUserDataProperty adds a\nuser-data vApp property taking a base64 cloud-config at deployment.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.APIArtifactProvisioning": {
+            "type": "object",
+            "properties": {
+                "allowedCommands": {
+                    "description": "AllowedCommands is the explicit list emitted under phonehome.allowed_commands.\nNil means \"use AuroraBoot's safe default set\". An empty slice means deny-all\n(observe-only node) — the UI warns when the operator picks this.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "autoInstall": {
+                    "type": "boolean"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "registerAuroraBoot": {
+                    "type": "boolean"
+                },
+                "sshKeys": {
+                    "type": "string"
+                },
+                "targetGroupId": {
+                    "type": "string"
+                },
+                "userMode": {
+                    "type": "string",
+                    "enum": [
+                        "default",
+                        "custom",
+                        "none"
+                    ]
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIArtifactSBOM": {
+            "type": "object",
+            "properties": {
+                "file": {
+                    "type": "string",
+                    "example": "sbom.spdx.json"
+                },
+                "findings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/sbom.Finding"
+                    }
+                },
+                "format": {
+                    "type": "string",
+                    "example": "spdx-json"
+                },
+                "vulnerabilities": {
+                    "$ref": "#/definitions/store.VulnerabilitySummary"
+                }
+            }
+        },
+        "handlers.APIArtifactSigning": {
+            "type": "object",
+            "properties": {
+                "ukiKeySetId": {
+                    "type": "string"
+                },
+                "ukiPublicKeysDir": {
+                    "type": "string"
+                },
+                "ukiSecureBootCert": {
+                    "type": "string"
+                },
+                "ukiSecureBootEnroll": {
+                    "type": "string",
+                    "enum": [
+                        "off",
+                        "manual",
+                        "if-safe",
+                        "force"
+                    ]
+                },
+                "ukiSecureBootKey": {
+                    "type": "string"
+                },
+                "ukiTpmPcrKey": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIBuildFromTemplateRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "overrides": {
+                    "type": "object"
+                },
+                "parameters": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "version": "v4.1.2"
+                    }
+                }
+            }
+        },
+        "handlers.APIBuildMatrix": {
+            "type": "object",
+            "properties": {
+                "arches": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "amd64"
+                    ]
+                },
+                "baseImages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "quay.io/kairos/ubuntu:24.04"
+                    ]
+                },
+                "kubernetes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.APIMatrixKubernetes"
+                    }
+                },
+                "outputs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.APIArtifactOutputs"
+                    }
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "core"
+                    ]
+                }
+            }
+        },
+        "handlers.APIBuildSet": {
+            "type": "object",
+            "properties": {
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/store.ArtifactRecord"
+                    }
+                },
+                "counts": {
+                    "$ref": "#/definitions/handlers.APIBuildSetCounts"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phase": {
+                    "type": "string",
+                    "enum": [
+                        "Pending",
+                        "Building",
+                        "Ready",
+                        "Error"
+                    ]
+                }
+            }
+        },
+        "handlers.APIBuildSetCounts": {
+            "type": "object",
+            "properties": {
+                "building": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "integer"
                 },
-                "trustedBoot": {
-                    "type": "boolean"
+                "pending": {
+                    "type": "integer"
                 },
-                "uki": {
-                    "type": "boolean"
+                "ready": {
+                    "type": "integer"
                 },
-                "vhd": {
-                    "type": "boolean"
+                "total": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.APIArtifactProvisioning": {
+        "handlers.APIBuildTemplate": {
             "type": "object",
             "properties": {
-                "allowedCommands": {
-                    "description": "AllowedCommands is the explicit list emitted under phonehome.allowed_commands.\nNil means \"use AuroraBoot's safe default set\". An empty slice means deny-all\n(observe-only node) — the UI warns when the operator picks this.",
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "autoInstall": {
-                    "type": "boolean"
-                },
-                "password": {
+                "createdAt": {
                     "type": "string"
                 },
-                "registerAuroraBoot": {
-                    "type": "boolean"
+                "description": {
+                    "type": "string"
                 },
-                "sshKeys": {
+                "id": {
                     "type": "string"
                 },
-                "targetGroupId": {
+                "name": {
                     "type": "string"
                 },
-                "userMode": {
-                    "type": "string",
-                    "enum": [
-                        "default",
-                        "custom",
-                        "none"
-                    ]
+                "parameters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/store.TemplateParameter"
+                    }
                 },
-                "username": {
+                "spec": {
+                    "type": "object"
+                },
+                "updatedAt": {
                     "type": "string"
                 }
             }
         },
-        "handlers.APIArtifactSigning": {
+        "handlers.APIBuildTemplateRequest": {
             "type": "object",
             "properties": {
-                "ukiKeySetId": {
+                "description": {
                     "type": "string"
                 },
-                "ukiPublicKeysDir": {
-                    "type": "string"
+                "name": {
+                    "type": "string",
+                    "example": "edge-ubuntu"
                 },
-                "ukiSecureBootCert": {
-                    "type": "string"
+                "parameters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/store.TemplateParameter"
+                    }
                 },
-                "ukiSecureBootEnroll": {
-                    "type": "string",
-                    "enum": [
-                        "off",
-                        "manual",
-                        "if-safe",
-                        "force"
-                    ]
+                "spec": {
+                    "$ref": "#/definitions/handlers.APICreateArtifactRequest"
+                }
+            }
+        },
+        "handlers.APIChannelMove": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "$ref": "#/definitions/store.Channel"
                 },
-                "ukiSecureBootKey": {
-                    "type": "string"
+                "entry": {
+                    "$ref": "#/definitions/store.ChannelEntry"
                 },
-                "ukiTpmPcrKey": {
-                    "type": "string"
+                "queued": {
+                    "type": "integer"
                 }
             }
         },
@@ -1437,6 +4353,9 @@ const docTemplate = `{
                 "dockerfile": {
                     "type": "string"
                 },
+                "git": {
+                    "$ref": "#/definitions/handlers.APIGitSource"
+                },
                 "hadronBase": {
                     "type": "string"
                 },
@@ -1504,6 +4423,33 @@ const docTemplate = `{
                 }
             }
         },
+        "handlers.APICreateBuildSetRequest": {
+            "type": "object",
+            "properties": {
+                "matrix": {
+                    "$ref": "#/definitions/handlers.APIBuildMatrix"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "release v3.6.0"
+                },
+                "template": {
+                    "$ref": "#/definitions/handlers.APICreateArtifactRequest"
+                }
+            }
+        },
+        "handlers.APICreateChannelRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ubuntu-k3s/stable"
+                }
+            }
+        },
         "handlers.APICreateCommandRequest": {
             "type": "object",
             "properties": {
@@ -1527,81 +4473,289 @@ const docTemplate = `{
                 }
             }
         },
-        "handlers.APICreateGroupRequest": {
+        "handlers.APICreateGroupRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Production fleet nodes"
+                },
+                "followChannelId": {
+                    "description": "FollowChannelID makes every promotion or rollback of the channel queue\nan upgrade on the group's nodes.",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "production"
+                }
+            }
+        },
+        "handlers.APIError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIGenerateKeySetRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "production"
+                },
+                "secureBootEnroll": {
+                    "type": "string",
+                    "enum": [
+                        "off",
+                        "manual",
+                        "if-safe",
+                        "force"
+                    ]
+                }
+            }
+        },
+        "handlers.APIGitPushFailure": {
+            "type": "object",
+            "properties": {
+                "artifactId": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIGitPushResult": {
+            "type": "object",
+            "properties": {
+                "builds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "commit": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.APIGitPushFailure"
+                    }
+                },
+                "ref": {
+                    "type": "string",
+                    "example": "refs/heads/main"
+                }
+            }
+        },
+        "handlers.APIGitSource": {
+            "type": "object",
+            "properties": {
+                "ref": {
+                    "type": "string",
+                    "example": "main"
+                },
+                "secret": {
+                    "type": "string",
+                    "example": "github-token"
+                },
+                "subpath": {
+                    "type": "string",
+                    "example": "images/edge"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://github.com/acme/edge-images.git"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIHeartbeatRequest": {
+            "type": "object",
+            "properties": {
+                "addresses": {
+                    "description": "Addresses are the node's current network addresses (optional, multi-NIC).\nWhen present they replace the stored list (e.g. after a DHCP change); when\nomitted the stored list is preserved.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/store.NodeAddress"
+                    }
+                },
+                "agentVersion": {
+                    "type": "string",
+                    "example": "v2.27.0"
+                },
+                "bootState": {
+                    "description": "BootState is the node's current boot state (optional): one of\nactive | passive | recovery | autoreset (unknown values pass through).",
+                    "type": "string",
+                    "example": "active"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "osRelease": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.APIImageCheck": {
+            "type": "object",
+            "properties": {
+                "artifactId": {
+                    "type": "string"
+                },
+                "changed": {
+                    "type": "boolean"
+                },
+                "digest": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "previousDigest": {
+                    "type": "string"
+                },
+                "rebuildId": {
+                    "type": "string"
+                },
+                "templateId": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIImageWatchRun": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.APIImageCheck"
+                    }
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "startedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIImageWatchSettings": {
+            "type": "object",
+            "properties": {
+                "schedule": {
+                    "type": "string",
+                    "example": "0 3 * * *"
+                }
+            }
+        },
+        "handlers.APIImageWatchStatus": {
+            "type": "object",
+            "properties": {
+                "lastRun": {
+                    "$ref": "#/definitions/handlers.APIImageWatchRun"
+                },
+                "nextRun": {
+                    "type": "string"
+                },
+                "schedule": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIMatrixKubernetes": {
             "type": "object",
             "properties": {
-                "description": {
+                "distro": {
                     "type": "string",
-                    "example": "Production fleet nodes"
+                    "enum": [
+                        "k3s",
+                        "k0s"
+                    ]
                 },
-                "name": {
-                    "type": "string",
-                    "example": "production"
+                "version": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.APIError": {
+        "handlers.APIMoveChannelRequest": {
             "type": "object",
             "properties": {
-                "code": {
+                "artifactId": {
                     "type": "string"
                 },
-                "detail": {
-                    "type": "string"
+                "fromChannel": {
+                    "type": "string",
+                    "example": "ubuntu-k3s/staging"
                 },
-                "error": {
+                "note": {
                     "type": "string"
                 }
             }
         },
-        "handlers.APIGenerateKeySetRequest": {
+        "handlers.APIPlanRequest": {
             "type": "object",
             "properties": {
-                "name": {
+                "config": {
                     "type": "string",
-                    "example": "production"
-                },
-                "secureBootEnroll": {
-                    "type": "string",
-                    "enum": [
-                        "off",
-                        "manual",
-                        "if-safe",
-                        "force"
-                    ]
+                    "example": "container_image: quay.io/kairos/ubuntu:24.04-core-amd64-generic-v3.5.0\ndisk:\n  efi: true\n"
                 }
             }
         },
-        "handlers.APIHeartbeatRequest": {
+        "handlers.APIPublishRequest": {
             "type": "object",
             "properties": {
-                "addresses": {
-                    "description": "Addresses are the node's current network addresses (optional, multi-NIC).\nWhen present they replace the stored list (e.g. after a DHCP change); when\nomitted the stored list is preserved.",
+                "files": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/store.NodeAddress"
-                    }
+                        "type": "string"
+                    },
+                    "example": [
+                        "kairos.iso"
+                    ]
                 },
-                "agentVersion": {
-                    "type": "string",
-                    "example": "v2.27.0"
+                "image": {
+                    "type": "boolean"
                 },
-                "bootState": {
-                    "description": "BootState is the node's current boot state (optional): one of\nactive | passive | recovery | autoreset (unknown values pass through).",
-                    "type": "string",
-                    "example": "active"
+                "outputs": {
+                    "type": "boolean"
                 },
-                "labels": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
+                "registryId": {
+                    "type": "string"
                 },
-                "osRelease": {
-                    "type": "object",
-                    "additionalProperties": {
+                "repository": {
+                    "type": "string",
+                    "example": "kairos/edge-os"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
                         "type": "string"
-                    }
+                    },
+                    "example": [
+                        "v1.2.0",
+                        "latest"
+                    ]
                 }
             }
         },
@@ -1659,6 +4813,32 @@ const docTemplate = `{
                 }
             }
         },
+        "handlers.APIRegistryRequest": {
+            "type": "object",
+            "properties": {
+                "host": {
+                    "type": "string",
+                    "example": "ghcr.io"
+                },
+                "insecure": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ghcr"
+                },
+                "namespace": {
+                    "type": "string",
+                    "example": "acme"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
         "handlers.APIReleaseRequest": {
             "type": "object",
             "properties": {
@@ -1676,6 +4856,51 @@ const docTemplate = `{
                 }
             }
         },
+        "handlers.APIRetentionRun": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "freedBytes": {
+                    "type": "integer"
+                },
+                "kept": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/retention.Kept"
+                    }
+                },
+                "overBudget": {
+                    "description": "OverBudget is set when the builds left after Remove still exceed\nMaxTotalBytes, because the rest are saved, running or protected.",
+                    "type": "boolean"
+                },
+                "remove": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/retention.Removal"
+                    }
+                },
+                "totalBytes": {
+                    "description": "TotalBytes is what all builds take now, FreedBytes what Remove frees.",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.APISecretRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
         "handlers.APISetGroupRequest": {
             "type": "object",
             "properties": {
@@ -1703,7 +4928,8 @@ const docTemplate = `{
                     "type": "string",
                     "enum": [
                         "local",
-                        "operator"
+                        "operator",
+                        "worker"
                     ],
                     "example": "operator"
                 },
@@ -1737,7 +4963,65 @@ const docTemplate = `{
                 "description": {
                     "type": "string"
                 },
+                "followChannelId": {
+                    "description": "FollowChannelID is left unchanged when omitted; \"\" stops following.",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIValidateCloudConfigRequest": {
+            "type": "object",
+            "properties": {
+                "cloudConfig": {
+                    "type": "string",
+                    "example": "#cloud-config\ninstall:\n  auto: true\n"
+                },
+                "fragment": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.APIWorkerHeartbeatRequest": {
+            "type": "object",
+            "properties": {
+                "buildId": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIWorkerRegisterRequest": {
+            "type": "object",
+            "properties": {
+                "arch": {
+                    "type": "string",
+                    "enum": [
+                        "amd64",
+                        "arm64"
+                    ],
+                    "example": "arm64"
+                },
                 "name": {
+                    "type": "string",
+                    "example": "builder-arm64-01"
+                },
+                "registrationToken": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIWorkerRegisterResponse": {
+            "type": "object",
+            "properties": {
+                "apiKey": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "string"
                 }
             }
@@ -1760,28 +5044,166 @@ const docTemplate = `{
                     "description": "AdvertisedURL is the advertised base URL the BMC reaches the served ISO at.",
                     "type": "string"
                 },
-                "configured": {
-                    "description": "Configured reports whether a local ISO-serve listener was configured at\nlaunch (--redfish-serve-addr). Without it, local serving cannot be enabled.",
-                    "type": "boolean"
+                "configured": {
+                    "description": "Configured reports whether a local ISO-serve listener was configured at\nlaunch (--redfish-serve-addr). Without it, local serving cannot be enabled.",
+                    "type": "boolean"
+                },
+                "enabled": {
+                    "description": "Enabled is the runtime gate (only meaningful when Configured).",
+                    "type": "boolean"
+                },
+                "usesTLS": {
+                    "description": "UsesTLS reports whether the listener serves over HTTPS.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.imageSourceResponse": {
+            "type": "object",
+            "properties": {
+                "defaultImageURL": {
+                    "type": "string"
+                },
+                "localServe": {
+                    "$ref": "#/definitions/handlers.imageSourceLocalServe"
+                }
+            }
+        },
+        "retention.Kept": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "protected": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "retention.Plan": {
+            "type": "object",
+            "properties": {
+                "freedBytes": {
+                    "type": "integer"
+                },
+                "kept": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/retention.Kept"
+                    }
+                },
+                "overBudget": {
+                    "description": "OverBudget is set when the builds left after Remove still exceed\nMaxTotalBytes, because the rest are saved, running or protected.",
+                    "type": "boolean"
+                },
+                "remove": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/retention.Removal"
+                    }
+                },
+                "totalBytes": {
+                    "description": "TotalBytes is what all builds take now, FreedBytes what Remove frees.",
+                    "type": "integer"
+                }
+            }
+        },
+        "retention.Policy": {
+            "type": "object",
+            "properties": {
+                "keepLastPerBase": {
+                    "description": "KeepLastPerBase keeps the newest N unsaved working builds of each base\nimage. Failed builds do not count toward N; one goes once N working\nbuilds newer than it exist.",
+                    "type": "integer"
+                },
+                "maxAgeDays": {
+                    "description": "MaxAgeDays removes unsaved builds older than this many days.",
+                    "type": "integer"
+                },
+                "maxTotalBytes": {
+                    "description": "MaxTotalBytes removes the oldest unsaved builds until all builds,\nsaved ones included, fit in this many bytes.",
+                    "type": "integer"
+                }
+            }
+        },
+        "retention.Removal": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "type": "string"
+                },
+                "bytes": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "sbom.Finding": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "fixedVersion": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "package": {
+                    "type": "string"
                 },
-                "enabled": {
-                    "description": "Enabled is the runtime gate (only meaningful when Configured).",
-                    "type": "boolean"
+                "severity": {
+                    "type": "string"
                 },
-                "usesTLS": {
-                    "description": "UsesTLS reports whether the listener serves over HTTPS.",
-                    "type": "boolean"
+                "summary": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.imageSourceResponse": {
+        "sbom.Package": {
             "type": "object",
             "properties": {
-                "defaultImageURL": {
+                "arch": {
                     "type": "string"
                 },
-                "localServe": {
-                    "$ref": "#/definitions/handlers.imageSourceLocalServe"
+                "license": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "source": {
+                    "description": "Source is the source package (dpkg Source, apk origin, rpm SOURCERPM\nname) when it differs from Name. Distribution advisories are keyed by\nit, so Match prefers it over Name.",
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
                 }
             }
         },
@@ -1806,15 +5228,29 @@ const docTemplate = `{
                 "baseImage": {
                     "type": "string"
                 },
+                "baseImageDigest": {
+                    "description": "The digests below pin what BaseImage, KairosInitImage and\nContainerImage resolved to when the build ran. The tags can move\nafterwards; \"clone exactly\" rebuilds from these instead. Empty when the\nbuilder could not resolve them (local-only images, the operator\nbackend).",
+                    "type": "string"
+                },
+                "buildSetId": {
+                    "description": "BuildSetID links a matrix child to its BuildSet. Written only by\nBuildSetStore.Attach; the full-row ArtifactStore.Update leaves it alone\nso a builder's final Save cannot unlink a child it never knew about.",
+                    "type": "string"
+                },
                 "cloudConfig": {
                     "type": "string"
                 },
                 "cloudImage": {
                     "type": "boolean"
                 },
+                "compression": {
+                    "type": "string"
+                },
                 "containerImage": {
                     "type": "string"
                 },
+                "containerImageDigest": {
+                    "type": "string"
+                },
                 "createdAt": {
                     "type": "string"
                 },
@@ -1827,6 +5263,14 @@ const docTemplate = `{
                 "gce": {
                     "type": "boolean"
                 },
+                "git": {
+                    "description": "Git is the repository the build's Dockerfile and overlay came from,\nnil for builds without one.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/store.GitSource"
+                        }
+                    ]
+                },
                 "hadronBase": {
                     "type": "string"
                 },
@@ -1854,6 +5298,9 @@ const docTemplate = `{
                 "kairosInitImage": {
                     "type": "string"
                 },
+                "kairosInitImageDigest": {
+                    "type": "string"
+                },
                 "kairosVersion": {
                     "type": "string"
                 },
@@ -1869,6 +5316,10 @@ const docTemplate = `{
                 "maas": {
                     "type": "boolean"
                 },
+                "manifestKeySetId": {
+                    "description": "ManifestKeySetID is the SecureBoot key set whose db.key signs the\nSHA256SUMS of the outputs. Empty signs with the server's manifest key,\nif one is configured. Kept on the record because remote backends ship\ntheir outputs back after Create returns, and the Upload handler signs\nthe manifest when it arrives.",
+                    "type": "string"
+                },
                 "message": {
                     "type": "string"
                 },
@@ -1881,12 +5332,37 @@ const docTemplate = `{
                 "netboot": {
                     "type": "boolean"
                 },
+                "ova": {
+                    "type": "boolean"
+                },
+                "ovaCpus": {
+                    "type": "integer"
+                },
+                "ovaMemoryMB": {
+                    "type": "integer"
+                },
+                "ovaUserDataProperty": {
+                    "type": "boolean"
+                },
                 "overlayRootfs": {
                     "type": "string"
                 },
                 "phase": {
                     "type": "string"
                 },
+                "publications": {
+                    "description": "Publications lists every push of this build to an OCI registry,\noldest first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/store.Publication"
+                    }
+                },
+                "qcow2": {
+                    "type": "boolean"
+                },
+                "qcow2Compress": {
+                    "type": "boolean"
+                },
                 "rawDisk": {
                     "type": "boolean"
                 },
@@ -1896,12 +5372,30 @@ const docTemplate = `{
                 "saved": {
                     "type": "boolean"
                 },
+                "sbom": {
+                    "description": "SBOM records that the build was asked for an SBOM in SBOMFormat.\nVulnerabilities summarises the offline advisory match run alongside\nit; nil until the build finishes, and stays nil when the builder has\nno advisory database configured.",
+                    "type": "boolean"
+                },
+                "sbomFormat": {
+                    "type": "string"
+                },
+                "step": {
+                    "description": "Step is the deployer step a Building artifact is at, and StepProgress\nhow far along that step is in percent when it reports its bytes\n(release downloads, image pulls). Both are left as they were when the\nbuild ends.",
+                    "type": "string"
+                },
+                "stepProgress": {
+                    "type": "integer"
+                },
                 "tar": {
                     "type": "boolean"
                 },
                 "targetGroupId": {
                     "type": "string"
                 },
+                "templateId": {
+                    "description": "TemplateID links a build to the BuildTemplate it was started from.\nWritten only by BuildTemplateStore.Attach, for the same reason.",
+                    "type": "string"
+                },
                 "trustedBoot": {
                     "type": "boolean"
                 },
@@ -1916,6 +5410,132 @@ const docTemplate = `{
                 },
                 "vhd": {
                     "type": "boolean"
+                },
+                "vhdDynamic": {
+                    "type": "boolean"
+                },
+                "vhdx": {
+                    "type": "boolean"
+                },
+                "vmdk": {
+                    "type": "boolean"
+                },
+                "vulnerabilities": {
+                    "$ref": "#/definitions/store.VulnerabilitySummary"
+                }
+            }
+        },
+        "store.BuildWorker": {
+            "type": "object",
+            "properties": {
+                "arch": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "currentBuildId": {
+                    "description": "CurrentBuildID is the build the worker last reported working on in its\nheartbeat; empty when idle.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastHeartbeat": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "store.Channel": {
+            "type": "object",
+            "properties": {
+                "artifactId": {
+                    "description": "ArtifactID is the artifact the channel currently points at. Empty\nuntil the first promotion.",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "store.ChannelEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "promote",
+                        "rollback"
+                    ]
+                },
+                "artifactId": {
+                    "description": "ArtifactID is the artifact the channel moved to, PreviousArtifactID\nthe one it pointed at before.",
+                    "type": "string"
+                },
+                "channelId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "fromChannel": {
+                    "description": "FromChannel names the channel the artifact was promoted from, when\nit was.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "previousArtifactId": {
+                    "type": "string"
+                }
+            }
+        },
+        "store.GitSource": {
+            "type": "object",
+            "properties": {
+                "commit": {
+                    "description": "Commit is what Ref resolved to when the build ran. Empty until the\nbuild finishes.",
+                    "type": "string"
+                },
+                "ref": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "subpath": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Username and Secret authenticate the clone: Secret names the entry\nin the SecretStore holding the password or token.",
+                    "type": "string"
                 }
             }
         },
@@ -2055,9 +5675,87 @@ const docTemplate = `{
                 "description": {
                     "type": "string"
                 },
+                "followChannelId": {
+                    "description": "FollowChannelID makes the group track a Channel: every promotion or\nrollback of the channel queues an upgrade to it on each node in the\ngroup. Empty follows nothing.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "store.Publication": {
+            "type": "object",
+            "properties": {
+                "files": {
+                    "description": "Files is the OCI artifact holding the ISO, raw disk and other\noutputs. Empty when none were pushed.",
+                    "type": "string"
+                },
+                "image": {
+                    "description": "Image is the OS container image, consumable by\n` + "`" + `kairos-agent upgrade --source oci:\u003cImage\u003e` + "`" + `. Empty when it was not\npushed.",
+                    "type": "string"
+                },
+                "publishedAt": {
+                    "type": "string"
+                },
+                "registryId": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "store.Registry": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "host": {
+                    "description": "Host is the registry host, with a port if it is not the default\n(e.g. \"ghcr.io\", \"registry.lan:5000\").",
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
+                "insecure": {
+                    "description": "Insecure allows plain HTTP, for lab registries without TLS.",
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "description": "Namespace is prefixed to every repository pushed here, e.g. an\norganisation. Optional.",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "store.Secret": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
                 "name": {
                     "type": "string"
                 },
@@ -2091,6 +5789,52 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "store.TemplateParameter": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "required": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "store.VulnerabilitySummary": {
+            "type": "object",
+            "properties": {
+                "critical": {
+                    "type": "integer"
+                },
+                "database": {
+                    "type": "string"
+                },
+                "high": {
+                    "type": "integer"
+                },
+                "low": {
+                    "type": "integer"
+                },
+                "medium": {
+                    "type": "integer"
+                },
+                "scannedAt": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "unknown": {
+                    "type": "integer"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -2105,6 +5849,12 @@ const docTemplate = `{
             "type": "apiKey",
             "name": "Authorization",
             "in": "header"
+        },
+        "WorkerAPIKey": {
+            "description": "Supply as \"Bearer \u003capi-key\u003e\" returned from POST /api/v1/workers/register.",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -2116,7 +5866,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/",
 	Schemes:          []string{},
 	Title:            "AuroraBoot API",
-	Description:      "HTTP API for the AuroraBoot Kairos node manager.\n\nThree security schemes are used across the API:\n* AdminBearer — Authorization: Bearer <admin-password>. Human/UI/automation callers.\n* NodeAPIKey — Authorization: Bearer <api-key>. Registered Kairos nodes calling their own endpoints. The api-key is returned from POST /api/v1/nodes/register.\n* RegistrationToken — shared secret carried inside the body of POST /api/v1/nodes/register.\n\nTwo WebSocket endpoints also exist but are not described in this spec:\n* GET /api/v1/ws — agent command channel (auth via ?token=<api-key>).\n* GET /api/v1/ws/ui — UI live update channel (auth via ?token=<admin-password>).",
+	Description:      "HTTP API for the AuroraBoot Kairos node manager.\n\nFour security schemes are used across the API:\n* AdminBearer — Authorization: Bearer <admin-password>. Human/UI/automation callers.\n* NodeAPIKey — Authorization: Bearer <api-key>. Registered Kairos nodes calling their own endpoints. The api-key is returned from POST /api/v1/nodes/register.\n* RegistrationToken — shared secret carried inside the body of POST /api/v1/nodes/register.\n* WorkerAPIKey — Authorization: Bearer <api-key>. Remote build workers (`auroraboot worker`) calling the lease protocol. The api-key is returned from POST /api/v1/workers/register.\n\nTwo WebSocket endpoints also exist but are not described in this spec:\n* GET /api/v1/ws — agent command channel (auth via ?token=<api-key>).\n* GET /api/v1/ws/ui — UI live update channel (auth via ?token=<admin-password>).",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -3,8 +3,10 @@ package auroraboot
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,8 +18,13 @@ import (
 	"github.com/google/uuid"
 	"github.com/kairos-io/AuroraBoot/deployer"
 	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/kairos-io/AuroraBoot/pkg/cloudconfig"
 	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/kairos-io/AuroraBoot/pkg/gitsource"
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
 	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
 	"github.com/kairos-io/AuroraBoot/pkg/store"
 	"github.com/kairos-io/AuroraBoot/pkg/uki"
 	sdklogger "github.com/kairos-io/kairos-sdk/types/logger"
@@ -27,8 +34,9 @@ import (
 // DeployerFunc abstracts the AuroraBoot deployer execution so we can mock it
 // in tests. logSink, when non-nil, receives a copy of every deployer step's
 // zerolog output alongside the process stdout, so the caller's per-build log
-// pane sees the same progress the terminal does.
-type DeployerFunc func(ctx context.Context, config schema.Config, artifact schema.ReleaseArtifact, outputDir string, logSink io.Writer) error
+// pane sees the same progress the terminal does. onEvent, when non-nil,
+// receives the deployer's structured step events.
+type DeployerFunc func(ctx context.Context, config schema.Config, artifact schema.ReleaseArtifact, outputDir string, logSink io.Writer, onEvent func(deployer.Event)) error
 
 // UKIBuildFunc abstracts the AuroraBoot pkg/uki.Build call so tests can
 // capture the options we pass without running a real build.
@@ -42,11 +50,12 @@ type UKIBuildFunc func(opts uki.Options) error
 // emit through zerolog - the Lchown/dump-source path in particular) are
 // echoed to logSink before we return so the log pane surfaces them, not
 // only the artifact record's message field.
-func DefaultDeployerFunc(ctx context.Context, config schema.Config, artifact schema.ReleaseArtifact, outputDir string, logSink io.Writer) error {
+func DefaultDeployerFunc(ctx context.Context, config schema.Config, artifact schema.ReleaseArtifact, outputDir string, logSink io.Writer, onEvent func(deployer.Event)) error {
 	d := deployer.NewDeployer(config, artifact, herd.EnableInit)
 	if logSink != nil {
 		d.Log = teeKairosLogger(d.Log, logSink)
 	}
+	d.OnEvent = onEvent
 	if err := deployer.RegisterAll(d); err != nil {
 		return fmt.Errorf("registering deployer steps: %w", err)
 	}
@@ -75,6 +84,9 @@ type Builder struct {
 	baseDir        string
 	deployFunc     DeployerFunc
 	ukiBuildFn     UKIBuildFunc
+	digestFn       ImageDigestFunc
+	vulnDB         string
+	storage        storage.Backend
 	store          store.ArtifactStore
 	logBroadcaster builder.LogBroadcaster
 }
@@ -142,6 +154,7 @@ func New(baseDir string, deployFunc DeployerFunc, artifactStore store.ArtifactSt
 		baseDir:    baseDir,
 		deployFunc: deployFunc,
 		ukiBuildFn: DefaultUKIBuildFunc,
+		digestFn:   DefaultImageDigestFunc,
 		store:      artifactStore,
 	}
 }
@@ -171,9 +184,20 @@ func (b *Builder) Build(ctx context.Context, opts builder.BuildOptions) (*builde
 
 	id := opts.ID
 	if id == "" {
+		if opts.Resume {
+			return nil, fmt.Errorf("%w: resuming a build needs its ID", builder.ErrInvalidBuildOptions)
+		}
 		id = uuid.New().String()
 		opts.ID = id
 	}
+	if opts.Resume {
+		b.mu.RLock()
+		prev, ok := b.builds[id]
+		b.mu.RUnlock()
+		if ok && (prev.status.Phase == builder.BuildPending || prev.status.Phase == builder.BuildBuilding) {
+			return nil, fmt.Errorf("%w: build %s is still running", builder.ErrInvalidBuildOptions, id)
+		}
+	}
 
 	outputDir := filepath.Join(b.baseDir, id)
 	if err := os.MkdirAll(outputDir, 0o755); err != nil {
@@ -196,8 +220,19 @@ func (b *Builder) Build(ctx context.Context, opts builder.BuildOptions) (*builde
 	b.builds[id] = bs
 	b.mu.Unlock()
 
-	// Persist artifact record in DB if store is available.
-	if b.store != nil {
+	// Persist artifact record in DB if store is available. A resumed build
+	// keeps its record and its logs, and goes back to pending.
+	if b.store != nil && opts.Resume {
+		err := b.store.UpdatePhaseMessage(ctx, id, store.ArtifactPending, "")
+		if err == nil {
+			err = b.store.UpdateStep(ctx, id, "", 0)
+		}
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("resetting artifact record: %w", err)
+		}
+		_ = b.store.AppendLog(ctx, id, "\n=== resuming from the failed step ===\n")
+	} else if b.store != nil {
 		kubernetesEnabled := opts.Provisioning.KubernetesEnabled
 		rec := &store.ArtifactRecord{
 			ID:                      id,
@@ -219,7 +254,18 @@ func (b *Builder) Build(ctx context.Context, opts builder.BuildOptions) (*builde
 			GCE:                     opts.Outputs.GCE,
 			VHD:                     opts.Outputs.VHD,
 			MAAS:                    opts.Outputs.MAAS,
+			QCOW2:                   opts.Outputs.QCOW2,
+			QCOW2Compress:           opts.Outputs.QCOW2Compress,
+			VHDDynamic:              opts.Outputs.VHDDynamic,
+			VHDX:                    opts.Outputs.VHDX,
+			VMDK:                    opts.Outputs.VMDK,
+			OVA:                     opts.Outputs.OVA,
+			OVACPUs:                 opts.Outputs.OVACPUs,
+			OVAMemoryMB:             opts.Outputs.OVAMemoryMB,
+			OVAUserDataProperty:     opts.Outputs.OVAUserDataProperty,
+			Compression:             opts.Outputs.Compression,
 			UKI:                     opts.Outputs.UKI,
+			SBOM:                    opts.Outputs.SBOM,
 			KairosInitImage:         opts.KairosInitImage,
 			AutoInstall:             opts.Provisioning.AutoInstall,
 			RegisterAuroraBoot:      opts.Provisioning.RegisterAuroraBoot,
@@ -239,9 +285,14 @@ func (b *Builder) Build(ctx context.Context, opts builder.BuildOptions) (*builde
 			KubernetesEnabled: &kubernetesEnabled,
 			TargetGroupID:     opts.Provisioning.TargetGroupID,
 			OverlayRootfs:     opts.OverlayRootfs,
+			Git:               gitRecord(opts.Git),
+			ManifestKeySetID:  opts.Signing.ManifestKeySetID,
 			CreatedAt:         time.Now(),
 			UpdatedAt:         time.Now(),
 		}
+		if opts.Outputs.SBOM {
+			rec.SBOMFormat = sbomFormat(opts)
+		}
 		if err := b.store.Create(ctx, rec); err != nil {
 			cancel()
 			return nil, fmt.Errorf("persisting artifact record: %w", err)
@@ -277,6 +328,27 @@ func (b *Builder) run(ctx context.Context, bs *buildState, opts builder.BuildOpt
 		}
 	}
 
+	// Step 0: Check out the Git source into the build context. Its
+	// Dockerfile and overlay fill in whatever the request left empty, so
+	// everything below sees them as if they had been sent inline.
+	var gitCommit string
+	if opts.Git != nil {
+		commit, err := b.fetchGitSource(ctx, &opts, outputDir, logWriter)
+		if err != nil {
+			msg := fmt.Sprintf("git checkout failed: %v", err)
+			b.setPhase(bs, builder.BuildError, msg)
+			if b.store != nil {
+				if logWriter != nil {
+					logWriter.Flush()
+				}
+				_ = b.updateDBPhase(context.Background(), bs.status.ID, store.ArtifactError, msg)
+			}
+			return
+		}
+		gitCommit = commit
+		defer os.RemoveAll(filepath.Join(outputDir, gitSourceDir))
+	}
+
 	// Step 1: If Dockerfile is provided, build a container image from it.
 	// The Dockerfile takes precedence — BaseImage is only used when no Dockerfile is set.
 	containerImage := opts.BaseImage
@@ -308,9 +380,50 @@ func (b *Builder) run(ctx context.Context, bs *buildState, opts builder.BuildOpt
 		logWriter.Flush()
 	}
 
+	// Step 1.4: Pin the inputs. The base image and kairos-init image are
+	// resolved to digests once, recorded, and the kairosify Dockerfile is
+	// written against repo@digest so the image that gets built is the one
+	// that was recorded even if a tag moves mid-build. Without a store the
+	// builder runs in test mode and never derives an image, so there is
+	// nothing to pin.
+	manifest := builder.BuildManifest{
+		ArtifactID: bs.status.ID,
+		Inputs:     builder.ManifestInputs{Arch: opts.Source.Arch},
+	}
+	if opts.Dockerfile != "" {
+		manifest.Inputs.DockerfileSHA256 = dockerfileChecksum(outputDir)
+	}
+	if opts.Git != nil {
+		manifest.Inputs.GitURL = opts.Git.URL
+		manifest.Inputs.GitCommit = gitCommit
+	}
+	kairosInitImage := resolveKairosInitImage(opts)
+	var kairosInitDigest string
+	if b.store != nil {
+		if opts.Dockerfile == "" {
+			manifest.Inputs.BaseImage = containerImage
+			manifest.Inputs.BaseImageDigest = b.resolveDigest(ctx, containerImage, false, opts, logWriter)
+			containerImage = builder.PinnedImageRef(containerImage, manifest.Inputs.BaseImageDigest)
+		}
+		kairosInitDigest = b.resolveDigest(ctx, kairosInitImage, false, opts, logWriter)
+		opts.KairosInitImage = builder.PinnedImageRef(kairosInitImage, kairosInitDigest)
+		if logWriter != nil && (manifest.Inputs.BaseImageDigest != "" || kairosInitDigest != "") {
+			fmt.Fprintf(logWriter, "Pinned inputs:\n")
+			if manifest.Inputs.BaseImageDigest != "" {
+				fmt.Fprintf(logWriter, "  base image: %s\n", containerImage)
+			}
+			if kairosInitDigest != "" {
+				fmt.Fprintf(logWriter, "  kairos-init: %s\n", opts.KairosInitImage)
+			}
+			fmt.Fprintf(logWriter, "\n")
+			logWriter.Flush()
+		}
+	}
+
 	// Step 1.5: Direct base images must always be derived so requested provider
 	// and variant settings are applied. Dockerfile images only need derivation
 	// when the Dockerfile did not produce a complete Kairos image.
+	derivedFrom := containerImage
 	var err error
 	if opts.Dockerfile == "" && b.store != nil {
 		containerImage, err = b.kairosify(ctx, containerImage, opts, outputDir, logWriter)
@@ -329,6 +442,17 @@ func (b *Builder) run(ctx context.Context, bs *buildState, opts builder.BuildOpt
 		return
 	}
 
+	// kairos-init only went into the image when kairosify actually ran;
+	// an already-complete Dockerfile image passes through unchanged.
+	if containerImage != derivedFrom {
+		manifest.Inputs.KairosInitImage = kairosInitImage
+		manifest.Inputs.KairosInitImageDigest = kairosInitDigest
+	}
+	manifest.Image.Ref = containerImage
+	if b.store != nil {
+		manifest.Image.Digest = b.resolveDigest(ctx, containerImage, true, opts, logWriter)
+	}
+
 	// Step 2: Assemble AuroraBoot config and run deployer.
 	if logWriter != nil {
 		fmt.Fprintf(logWriter, "=== Starting AuroraBoot build ===\n")
@@ -342,6 +466,12 @@ func (b *Builder) run(ctx context.Context, bs *buildState, opts builder.BuildOpt
 		if opts.Netboot {
 			fmt.Fprintf(logWriter, "Output: Netboot\n")
 		}
+		if opts.Outputs.SBOM {
+			fmt.Fprintf(logWriter, "Output: SBOM (%s)\n", sbomFormat(opts))
+		}
+		if opts.Outputs.Compression != "" {
+			fmt.Fprintf(logWriter, "Compression: %s\n", opts.Outputs.Compression)
+		}
 		fmt.Fprintf(logWriter, "Output dir: %s\n", outputDir)
 		if opts.CloudConfig != "" {
 			fmt.Fprintf(logWriter, "Cloud config:\n%s\n", opts.CloudConfig)
@@ -349,12 +479,27 @@ func (b *Builder) run(ctx context.Context, bs *buildState, opts builder.BuildOpt
 		fmt.Fprintf(logWriter, "\n")
 		logWriter.Flush()
 	}
+	// Secret references are resolved only now, after the config was
+	// logged, into the copy handed to the deployer.
+	cloudConfig, err := cloudconfig.ResolveSecrets(opts.CloudConfig, opts.Secrets)
+	if err != nil {
+		msg := fmt.Sprintf("resolving cloud-config secrets: %v", err)
+		b.setPhase(bs, builder.BuildError, msg)
+		if b.store != nil {
+			if logWriter != nil {
+				logWriter.Flush()
+			}
+			_ = b.updateDBPhase(context.Background(), bs.status.ID, store.ArtifactError, msg)
+		}
+		return
+	}
+	opts.CloudConfig = cloudConfig
 	config, artifact := b.assembleConfig(opts, containerImage, outputDir)
 	var sink io.Writer
 	if logWriter != nil {
 		sink = logWriter
 	}
-	if err := b.deployFunc(ctx, config, artifact, outputDir, sink); err != nil {
+	if err := b.deployFunc(ctx, config, artifact, outputDir, sink, b.stepRecorder(bs.status.ID)); err != nil {
 		msg := fmt.Sprintf("auroraboot failed: %v", err)
 		b.setPhase(bs, builder.BuildError, msg)
 		if b.store != nil {
@@ -416,7 +561,7 @@ func (b *Builder) run(ctx context.Context, bs *buildState, opts builder.BuildOpt
 	// build outputs. The raw-disk paths don't produce ISOs at all, so the
 	// only files present here would be that scaffolding ISO.
 	if !opts.Outputs.ISO {
-		for _, pattern := range []string{"*.iso", "*.iso.sha256"} {
+		for _, pattern := range []string{"*.iso", "*.iso.sha256", "*.iso.zst", "*.iso.xz", "*.iso.gz"} {
 			matches, _ := filepath.Glob(filepath.Join(outputDir, pattern))
 			for _, p := range matches {
 				if err := os.Remove(p); err != nil && logWriter != nil {
@@ -426,8 +571,74 @@ func (b *Builder) run(ctx context.Context, bs *buildState, opts builder.BuildOpt
 		}
 	}
 
-	// Step 3: Collect artifact paths.
+	// Step 2.8: Pick up the vulnerability summary the SBOM step wrote, if
+	// it matched against an advisory database.
+	var vulns *store.VulnerabilitySummary
+	if opts.Outputs.SBOM {
+		var err error
+		if vulns, err = readVulnerabilitySummary(outputDir); err != nil && logWriter != nil {
+			fmt.Fprintf(logWriter, "Warning: could not read %s: %v\n", sbom.ReportFileName, err)
+		}
+	}
+
+	// Step 3: Collect artifact paths and record their checksums in the
+	// build manifest, which is itself listed as an output so it travels
+	// with the files it describes.
 	artifacts := collectArtifacts(outputDir)
+	outputs, err := checksumArtifacts(artifacts)
+	if err != nil {
+		msg := fmt.Sprintf("checksumming outputs failed: %v", err)
+		if logWriter != nil {
+			fmt.Fprintf(logWriter, "%s\n", msg)
+			logWriter.Flush()
+		}
+		b.setPhase(bs, builder.BuildError, msg)
+		if b.store != nil {
+			_ = b.updateDBPhase(context.Background(), bs.status.ID, store.ArtifactError, msg)
+		}
+		return
+	}
+	if manifestPath, err := writeBuildManifest(outputDir, manifest, outputs); err != nil {
+		if logWriter != nil {
+			fmt.Fprintf(logWriter, "Warning: could not write %s: %v\n", builder.ManifestFileName, err)
+		}
+	} else if f, err := builder.ChecksumFile(manifestPath); err == nil {
+		artifacts = append(artifacts, manifestPath)
+		outputs = append(outputs, f)
+	}
+
+	// Step 3.1: List every output in SHA256SUMS and, when a key was given,
+	// sign it. A signature was asked for, so failing to produce one fails
+	// the build rather than shipping outputs that will not verify.
+	sums, err := writeChecksums(outputDir, outputs, opts.Signing.ManifestKey)
+	if err != nil {
+		msg := fmt.Sprintf("writing %s failed: %v", checksums.FileName, err)
+		if logWriter != nil {
+			fmt.Fprintf(logWriter, "%s\n", msg)
+			logWriter.Flush()
+		}
+		b.setPhase(bs, builder.BuildError, msg)
+		if b.store != nil {
+			_ = b.updateDBPhase(context.Background(), bs.status.ID, store.ArtifactError, msg)
+		}
+		return
+	}
+	artifacts = append(artifacts, sums...)
+
+	// Step 3.2: Push the outputs to the artifact storage. A build whose
+	// files cannot be downloaded is not Ready.
+	if err := b.storeArtifacts(ctx, bs.status.ID, artifacts); err != nil {
+		msg := fmt.Sprintf("storing artifacts failed: %v", err)
+		if logWriter != nil {
+			fmt.Fprintf(logWriter, "%s\n", msg)
+			logWriter.Flush()
+		}
+		b.setPhase(bs, builder.BuildError, msg)
+		if b.store != nil {
+			_ = b.updateDBPhase(context.Background(), bs.status.ID, store.ArtifactError, msg)
+		}
+		return
+	}
 	if logWriter != nil {
 		fmt.Fprintf(logWriter, "Artifacts:\n")
 		for _, a := range artifacts {
@@ -453,6 +664,19 @@ func (b *Builder) run(ctx context.Context, bs *buildState, opts builder.BuildOpt
 			rec.Message = ""
 			rec.ArtifactFiles = artifacts
 			rec.ContainerImage = containerImage
+			rec.BaseImageDigest = manifest.Inputs.BaseImageDigest
+			rec.KairosInitImageDigest = manifest.Inputs.KairosInitImageDigest
+			rec.ContainerImageDigest = manifest.Image.Digest
+			rec.Vulnerabilities = vulns
+			if rec.Git != nil {
+				rec.Git.Commit = gitCommit
+			}
+			// An empty KairosInitImage means "the server default", which
+			// changes between releases. Record the image actually used so
+			// the digest above names something a clone can pin.
+			if rec.KairosInitImage == "" {
+				rec.KairosInitImage = manifest.Inputs.KairosInitImage
+			}
 			rec.UpdatedAt = time.Now()
 			_ = b.store.Update(context.Background(), rec)
 		}
@@ -470,6 +694,36 @@ func (b *Builder) updateDBPhase(ctx context.Context, id, phase, message string)
 	return b.store.Update(ctx, rec)
 }
 
+// stepRecorder returns the deployer event handler keeping the step a build is
+// at, and how far along it is, on its artifact record. Progress is written
+// only when its percent moves, not for every chunk of a pull.
+func (b *Builder) stepRecorder(id string) func(deployer.Event) {
+	if b.store == nil {
+		return nil
+	}
+	var mu sync.Mutex
+	lastStep, lastPercent := "", -1
+	return func(e deployer.Event) {
+		var percent int
+		switch e.Type {
+		case deployer.EventStepStart:
+		case deployer.EventProgress:
+			if percent = e.Percent(); percent < 0 {
+				return
+			}
+		default:
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if e.Step == lastStep && percent == lastPercent {
+			return
+		}
+		lastStep, lastPercent = e.Step, percent
+		_ = b.store.UpdateStep(context.Background(), id, e.Step, percent)
+	}
+}
+
 func (b *Builder) setPhase(bs *buildState, phase, message string) {
 	b.mu.Lock()
 	bs.status.Phase = phase
@@ -552,13 +806,7 @@ func (b *Builder) kairosify(ctx context.Context, image string, opts builder.Buil
 	if err := validateKairosInitOptions(opts); err != nil {
 		return "", fmt.Errorf("validating kairos-init options: %w", err)
 	}
-	kairosInitImage := opts.KairosInitImage
-	if kairosInitImage == "" {
-		kairosInitImage = os.Getenv("KAIROS_INIT_IMAGE")
-	}
-	if kairosInitImage == "" {
-		kairosInitImage = defaultKairosInitImage + ":" + defaultKairosInitVersion
-	}
+	kairosInitImage := resolveKairosInitImage(opts)
 
 	// Build kairos-init flags
 	var flags []string
@@ -616,10 +864,23 @@ RUN /kairos-init -l debug -s install %s && \
 	return tag, nil
 }
 
+// resolveKairosInitImage picks the kairos-init image a build derives with:
+// the per-build override, then $KAIROS_INIT_IMAGE, then the pinned default.
+func resolveKairosInitImage(opts builder.BuildOptions) string {
+	if opts.KairosInitImage != "" {
+		return opts.KairosInitImage
+	}
+	if img := os.Getenv("KAIROS_INIT_IMAGE"); img != "" {
+		return img
+	}
+	return defaultKairosInitImage + ":" + defaultKairosInitVersion
+}
+
 // assembleConfig builds the AuroraBoot schema.Config and schema.ReleaseArtifact from BuildOptions.
 func (b *Builder) assembleConfig(opts builder.BuildOptions, containerImage, outputDir string) (schema.Config, schema.ReleaseArtifact) {
 	config := schema.Config{
 		State:             outputDir,
+		Resume:            opts.Resume,
 		DisableHTTPServer: true,
 		DisableNetboot:    !opts.Netboot,
 		DisableISOboot:    !opts.ISO,
@@ -632,12 +893,23 @@ func (b *Builder) assembleConfig(opts builder.BuildOptions, containerImage, outp
 	allowInsecure := opts.Source.AllowInsecureRegistries
 	config.AllowInsecureRegistries = &allowInsecure
 
-	if opts.CloudImage || opts.Outputs.RawDisk || opts.Outputs.GCE || opts.Outputs.VHD || opts.Outputs.MAAS {
+	if opts.CloudImage || opts.Outputs.RawDisk || opts.Outputs.GCE || opts.Outputs.VHD || opts.Outputs.MAAS || opts.Outputs.QCOW2 || opts.Outputs.VMDK || opts.Outputs.OVA || opts.Outputs.VHDX {
 		config.Disk.EFI = true
 	}
 	config.Disk.GCE = opts.Outputs.GCE
 	config.Disk.VHD = opts.Outputs.VHD
 	config.Disk.MAAS = opts.Outputs.MAAS
+	config.Disk.QCOW2 = opts.Outputs.QCOW2
+	config.Disk.QCOW2Compress = opts.Outputs.QCOW2Compress
+	config.Disk.VHDDynamic = opts.Outputs.VHDDynamic
+	config.Disk.VHDX = opts.Outputs.VHDX
+	config.Disk.VMDK = opts.Outputs.VMDK
+	config.Disk.OVA = opts.Outputs.OVA
+	config.Disk.OVF = schema.OVF{
+		CPUs:             opts.Outputs.OVACPUs,
+		MemoryMB:         opts.Outputs.OVAMemoryMB,
+		UserDataProperty: opts.Outputs.OVAUserDataProperty,
+	}
 
 	if opts.CloudConfig != "" {
 		config.CloudConfig = opts.CloudConfig
@@ -647,6 +919,9 @@ func (b *Builder) assembleConfig(opts builder.BuildOptions, containerImage, outp
 		config.ISO.OverlayRootfs = opts.OverlayRootfs
 	}
 
+	config.SBOM = b.sbomConfig(opts)
+	config.Compression = schema.Compression{Format: opts.Outputs.Compression}
+
 	artifact := schema.ReleaseArtifact{}
 	if containerImage != "" {
 		artifact.ContainerImage = containerImage
@@ -696,6 +971,73 @@ func (b *Builder) buildUKI(ctx context.Context, opts builder.BuildOptions, conta
 	}
 }
 
+// gitSourceDir is where a Git source is checked out inside the output
+// directory. It is removed once the build finishes.
+const gitSourceDir = "source"
+
+// fetchGitSource clones opts.Git into the output directory and points the
+// build context at its subpath. The repository's Dockerfile and overlay/ are
+// used when the request did not set its own. It returns the checked-out
+// commit.
+func (b *Builder) fetchGitSource(ctx context.Context, opts *builder.BuildOptions, outputDir string, logWriter *dbLogWriter) (string, error) {
+	var progress io.Writer
+	if logWriter != nil {
+		ref := opts.Git.Ref
+		if ref == "" {
+			ref = "default branch"
+		}
+		fmt.Fprintf(logWriter, "=== Cloning %s (%s) ===\n", opts.Git.URL, ref)
+		progress = logWriter
+	}
+	dir := filepath.Join(outputDir, gitSourceDir)
+	commit, err := gitsource.Clone(ctx, *opts.Git, dir, progress)
+	if err != nil {
+		return "", err
+	}
+	// The subpath, Dockerfile and overlay may be symlinks, which must not
+	// lead the build to files of the server. A missing one resolves to "".
+	src, err := gitsource.InCheckout(dir, opts.Git.Dir(dir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("subpath %q not found in the repository", opts.Git.Subpath)
+	}
+	opts.BuildContextDir = src
+	if opts.Dockerfile == "" {
+		path, err := gitsource.InCheckout(dir, filepath.Join(src, gitsource.DockerfileName))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			opts.Dockerfile = string(data)
+		}
+	}
+	if opts.OverlayRootfs == "" {
+		overlay, err := gitsource.InCheckout(dir, filepath.Join(src, gitsource.OverlayDirName))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if info, err := os.Stat(overlay); err == nil && info.IsDir() {
+			opts.OverlayRootfs = overlay
+		}
+	}
+	if logWriter != nil {
+		fmt.Fprintf(logWriter, "Checked out %s\n\n", commit)
+		logWriter.Flush()
+	}
+	return commit, nil
+}
+
+// gitRecord is the Git source as recorded on the artifact, without the
+// password.
+func gitRecord(src *gitsource.Source) *store.GitSource {
+	if src == nil {
+		return nil
+	}
+	return &store.GitSource{URL: src.URL, Ref: src.Ref, Subpath: src.Subpath, Username: src.Username, Secret: src.Secret}
+}
+
 // dockerBuild runs `docker build` when a Dockerfile is provided.
 func (b *Builder) dockerBuild(ctx context.Context, opts builder.BuildOptions, outputDir string, logWriter *dbLogWriter) (string, error) {
 	// The UI's Hadron composer emits middle content only (no FROM line) so
@@ -844,7 +1186,21 @@ func collectArtifacts(dir string) []string {
 		".tar":    true,
 		".tar.gz": true,
 		".vhd":    true,
+		".qcow2":  true,
+		".vhdx":   true,
+		".vmdk":   true,
+		".ova":    true,
 		".sha256": true,
+		// Outputs compressed with the compression option; gzip ones end in
+		// .raw.gz, .iso.gz or .vhd.gz.
+		".zst":    true,
+		".xz":     true,
+		".iso.gz": true,
+		".vhd.gz": true,
+		// SBOM documents and the vulnerability report written next to them
+		".spdx.json":        true,
+		".cdx.json":         true,
+		sbom.ReportFileName: true,
 	}
 
 	var artifacts []string
@@ -853,7 +1209,7 @@ func collectArtifacts(dir string) []string {
 			return nil
 		}
 		// Skip intermediate directories (unpacked rootfs, build temps)
-		if info.IsDir() && (info.Name() == "temp-rootfs" || info.Name() == "build" || info.Name() == "rootfs") {
+		if info.IsDir() && (info.Name() == "temp-rootfs" || info.Name() == "build" || info.Name() == "rootfs" || info.Name() == gitSourceDir) {
 			return filepath.SkipDir
 		}
 		if info.IsDir() {
@@ -9,13 +9,14 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/kairos-io/AuroraBoot/deployer"
 	"github.com/kairos-io/AuroraBoot/internal/builder/auroraboot"
 	"github.com/kairos-io/AuroraBoot/pkg/builder"
 	"github.com/kairos-io/AuroraBoot/pkg/schema"
 	"github.com/kairos-io/AuroraBoot/pkg/store"
 )
 
-func noopDeploy(_ context.Context, _ schema.Config, _ schema.ReleaseArtifact, _ string, _ io.Writer) error {
+func noopDeploy(_ context.Context, _ schema.Config, _ schema.ReleaseArtifact, _ string, _ io.Writer, _ func(deployer.Event)) error {
 	return nil
 }
 
@@ -83,6 +84,15 @@ func (s *recStore) UpdateFiles(_ context.Context, id string, files []string) err
 	}
 	return nil
 }
+func (s *recStore) UpdateStep(_ context.Context, id, step string, progress int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.recs[id]; ok {
+		r.Step = step
+		r.StepProgress = progress
+	}
+	return nil
+}
 func (s *recStore) ClearUploadToken(_ context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -91,6 +101,14 @@ func (s *recStore) ClearUploadToken(_ context.Context, id string) error {
 	}
 	return nil
 }
+func (s *recStore) SetUploadToken(_ context.Context, id, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.recs[id]; ok {
+		r.UploadToken = token
+	}
+	return nil
+}
 func (s *recStore) Delete(_ context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
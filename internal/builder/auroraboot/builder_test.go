@@ -10,6 +10,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/kairos-io/AuroraBoot/deployer"
 	"github.com/kairos-io/AuroraBoot/pkg/schema"
 	"github.com/kairos-io/AuroraBoot/pkg/uki"
 	"github.com/kairos-io/AuroraBoot/pkg/builder"
@@ -34,7 +35,7 @@ var _ = Describe("AuroraBoot Builder", func() {
 		ch := make(chan struct{}, 1)
 		deployCalled = ch
 
-		mockDeploy := func(_ context.Context, config schema.Config, artifact schema.ReleaseArtifact, _ string, _ io.Writer) error {
+		mockDeploy := func(_ context.Context, config schema.Config, artifact schema.ReleaseArtifact, _ string, _ io.Writer, _ func(deployer.Event)) error {
 			mu.Lock()
 			capturedConfig = config
 			capturedArtifact = artifact
@@ -67,7 +68,7 @@ var _ = Describe("AuroraBoot Builder", func() {
 		It("should transition to Building status", func() {
 			// Use a deployer that blocks until we release it.
 			blocked := make(chan struct{})
-			slowDeploy := func(ctx context.Context, _ schema.Config, _ schema.ReleaseArtifact, _ string, _ io.Writer) error {
+			slowDeploy := func(ctx context.Context, _ schema.Config, _ schema.ReleaseArtifact, _ string, _ io.Writer, _ func(deployer.Event)) error {
 				select {
 				case <-blocked:
 				case <-ctx.Done():
@@ -221,7 +222,7 @@ var _ = Describe("AuroraBoot Builder", func() {
 	Describe("Cancel", func() {
 		It("should cancel a running build", func() {
 			blocked := make(chan struct{})
-			slowDeploy := func(ctx context.Context, _ schema.Config, _ schema.ReleaseArtifact, _ string, _ io.Writer) error {
+			slowDeploy := func(ctx context.Context, _ schema.Config, _ schema.ReleaseArtifact, _ string, _ io.Writer, _ func(deployer.Event)) error {
 				select {
 				case <-blocked:
 				case <-ctx.Done():
@@ -0,0 +1,30 @@
+package auroraboot
+
+import (
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+)
+
+// writeChecksums writes the SHA256SUMS manifest for outputs into outputDir
+// and, when keyPath is set, its detached signature. It returns the paths it
+// wrote so they can be listed with the other artifacts. The checksums come
+// from the build manifest pass, so each output is only read once.
+func writeChecksums(outputDir string, outputs []builder.ManifestFile, keyPath string) ([]string, error) {
+	entries := make([]checksums.Entry, 0, len(outputs))
+	for _, o := range outputs {
+		entries = append(entries, checksums.Entry{Name: o.Name, SHA256: o.SHA256})
+	}
+	sumsPath, err := checksums.Write(outputDir, entries)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{sumsPath}
+	if keyPath != "" {
+		sigPath, err := checksums.SignDir(outputDir, keyPath)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, sigPath)
+	}
+	return paths, nil
+}
@@ -0,0 +1,100 @@
+package auroraboot_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/deployer"
+	"github.com/kairos-io/AuroraBoot/internal/builder/auroraboot"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("AuroraBoot Builder checksums", func() {
+	var baseDir string
+
+	BeforeEach(func() {
+		baseDir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(baseDir, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755)).To(Succeed())
+		GinkgoT().Setenv("PATH", baseDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	})
+
+	deploy := func(_ context.Context, _ schema.Config, _ schema.ReleaseArtifact, outputDir string, _ io.Writer, _ func(deployer.Event)) error {
+		return os.WriteFile(filepath.Join(outputDir, "kairos.iso"), []byte("hello\n"), 0o644)
+	}
+
+	waitFor := func(s store.ArtifactStore, id, phase string) *store.ArtifactRecord {
+		var rec *store.ArtifactRecord
+		Eventually(func() string {
+			rec, _ = s.GetByID(context.Background(), id)
+			return rec.Phase
+		}, "5s").Should(Equal(phase))
+		return rec
+	}
+
+	It("lists every output in a signed SHA256SUMS", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		keyPath := filepath.Join(baseDir, "cosign.key")
+		Expect(os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)).To(Succeed())
+
+		s := newRecStore()
+		b := auroraboot.New(baseDir, deploy, s)
+		_, err = b.Build(context.Background(), builder.BuildOptions{
+			ID:        "signed",
+			BaseImage: "quay.io/kairos/ubuntu:24.04",
+			Outputs:   builder.OutputOptions{ISO: true},
+			Signing:   builder.SigningOptions{ManifestKey: keyPath},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		rec := waitFor(s, "signed", store.ArtifactReady)
+
+		outDir := filepath.Join(baseDir, "signed")
+		Expect(rec.ArtifactFiles).To(ContainElements(
+			filepath.Join(outDir, checksums.FileName),
+			filepath.Join(outDir, checksums.SignatureFileName),
+		))
+		entries, err := checksums.Read(outDir)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		Expect(names).To(ConsistOf("kairos.iso", builder.ManifestFileName))
+
+		results, err := checksums.VerifyDir(outDir)
+		Expect(err).NotTo(HaveOccurred())
+		for _, r := range results {
+			Expect(r.Err).NotTo(HaveOccurred(), r.Name)
+		}
+		Expect(checksums.VerifyDirSignature(outDir, key.Public())).To(Succeed())
+	})
+
+	It("fails the build when the signing key cannot be used", func() {
+		s := newRecStore()
+		b := auroraboot.New(baseDir, deploy, s)
+		_, err := b.Build(context.Background(), builder.BuildOptions{
+			ID:        "bad-key",
+			BaseImage: "quay.io/kairos/ubuntu:24.04",
+			Outputs:   builder.OutputOptions{ISO: true},
+			Signing:   builder.SigningOptions{ManifestKey: filepath.Join(baseDir, "missing.key")},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		rec := waitFor(s, "bad-key", store.ArtifactError)
+		Expect(rec.Message).To(ContainSubstring(checksums.FileName))
+	})
+})
@@ -0,0 +1,124 @@
+package auroraboot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+)
+
+// ImageDigestFunc resolves image to the content digest it refers to right
+// now. With local false the image is a registry reference and the digest is
+// the manifest (or index) digest, which can be pinned as repo@digest. With
+// local true the image was produced by this build in the docker daemon and
+// the digest is its image ID. Tests swap it out so builds run offline.
+type ImageDigestFunc func(ctx context.Context, image string, local bool, opts builder.BuildOptions) (string, error)
+
+// DefaultImageDigestFunc asks the registry (HEAD on the manifest, using the
+// docker credential helpers) for remote references and `docker image
+// inspect` for local ones. A reference that already carries a digest is
+// returned as-is without touching the network.
+func DefaultImageDigestFunc(ctx context.Context, image string, local bool, opts builder.BuildOptions) (string, error) {
+	if local {
+		out, err := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{.Id}}", image).Output()
+		if err != nil {
+			return "", fmt.Errorf("docker image inspect %s: %w", image, err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	if d := builder.ImageRefDigest(image); d != "" {
+		return d, nil
+	}
+	var nameOpts []name.Option
+	if opts.Source.AllowInsecureRegistries {
+		nameOpts = append(nameOpts, name.Insecure)
+	}
+	ref, err := name.ParseReference(image, nameOpts...)
+	if err != nil {
+		return "", err
+	}
+	desc, err := remote.Head(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
+
+// WithImageDigestFunc swaps the digest resolver used to pin build inputs and
+// record the final image digest.
+func (b *Builder) WithImageDigestFunc(fn ImageDigestFunc) *Builder {
+	b.digestFn = fn
+	return b
+}
+
+// resolveDigest wraps digestFn so a failure degrades to an unpinned build
+// with a warning in the build log rather than failing it: a base image that
+// only exists in the local daemon, or a registry that refuses HEAD, must
+// still build exactly as it did before digests were recorded.
+func (b *Builder) resolveDigest(ctx context.Context, image string, local bool, opts builder.BuildOptions, logWriter *dbLogWriter) string {
+	if image == "" {
+		return ""
+	}
+	d, err := b.digestFn(ctx, image, local, opts)
+	if err != nil {
+		if logWriter != nil {
+			fmt.Fprintf(logWriter, "Warning: could not resolve digest of %s: %v\n", image, err)
+			logWriter.Flush()
+		}
+		return ""
+	}
+	return d
+}
+
+// checksumArtifacts hashes every output in artifacts, in order.
+func checksumArtifacts(artifacts []string) ([]builder.ManifestFile, error) {
+	outputs := make([]builder.ManifestFile, 0, len(artifacts))
+	for _, p := range artifacts {
+		f, err := builder.ChecksumFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("checksumming %s: %w", filepath.Base(p), err)
+		}
+		outputs = append(outputs, f)
+	}
+	return outputs, nil
+}
+
+// writeBuildManifest writes the manifest, recording outputs, into outputDir
+// and returns its path.
+func writeBuildManifest(outputDir string, m builder.BuildManifest, outputs []builder.ManifestFile) (string, error) {
+	m.Outputs = outputs
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(outputDir, builder.ManifestFileName)
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// dockerfileChecksum returns the sha256 of the Dockerfile dockerBuild wrote
+// into outputDir, or "" when there is none.
+func dockerfileChecksum(outputDir string) string {
+	data, err := os.ReadFile(filepath.Join(outputDir, "Dockerfile"))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
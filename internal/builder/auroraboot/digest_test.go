@@ -0,0 +1,134 @@
+package auroraboot_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/deployer"
+	"github.com/kairos-io/AuroraBoot/internal/builder/auroraboot"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("AuroraBoot Builder image pinning", func() {
+	const (
+		baseImage       = "quay.io/kairos/ubuntu:24.04"
+		kairosInitImage = "quay.io/kairos/kairos-init:v0.5.0"
+	)
+
+	var baseDir string
+
+	BeforeEach(func() {
+		// docker only has to succeed: kairosify's build is the one call
+		// that reaches it, the digest lookups go through the fake resolver.
+		baseDir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(baseDir, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755)).To(Succeed())
+		GinkgoT().Setenv("PATH", baseDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	})
+
+	digests := func(_ context.Context, image string, local bool, _ builder.BuildOptions) (string, error) {
+		switch {
+		case !local && image == baseImage:
+			return "sha256:base", nil
+		case !local && image == kairosInitImage:
+			return "sha256:init", nil
+		case local && image == "auroraboot-kairos:pinned":
+			return "sha256:image", nil
+		}
+		return "", fmt.Errorf("unexpected lookup of %s (local=%v)", image, local)
+	}
+
+	It("derives from the pinned inputs and records every digest", func() {
+		s := newRecStore()
+		deploy := func(_ context.Context, _ schema.Config, _ schema.ReleaseArtifact, outputDir string, _ io.Writer, _ func(deployer.Event)) error {
+			return os.WriteFile(filepath.Join(outputDir, "kairos.iso"), []byte("hello\n"), 0o644)
+		}
+		b := auroraboot.New(baseDir, deploy, s).WithImageDigestFunc(digests)
+
+		_, err := b.Build(context.Background(), builder.BuildOptions{
+			ID:              "pinned",
+			BaseImage:       baseImage,
+			KairosInitImage: kairosInitImage,
+			ISO:             true,
+			Source:          builder.ImageSource{BaseImage: baseImage, Arch: "amd64"},
+			Outputs:         builder.OutputOptions{ISO: true},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var rec *store.ArtifactRecord
+		Eventually(func() string {
+			rec, _ = s.GetByID(context.Background(), "pinned")
+			return rec.Phase
+		}, "5s").Should(Equal(store.ArtifactReady))
+
+		Expect(rec.BaseImage).To(Equal(baseImage), "the record keeps the tag the admin asked for")
+		Expect(rec.BaseImageDigest).To(Equal("sha256:base"))
+		Expect(rec.KairosInitImageDigest).To(Equal("sha256:init"))
+		Expect(rec.ContainerImageDigest).To(Equal("sha256:image"))
+
+		dockerfile, err := os.ReadFile(filepath.Join(baseDir, "pinned", "Dockerfile.kairosify"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(dockerfile)).To(And(
+			ContainSubstring("FROM quay.io/kairos/kairos-init@sha256:init AS kairos-init"),
+			ContainSubstring("FROM quay.io/kairos/ubuntu@sha256:base\n"),
+		))
+
+		manifestPath := filepath.Join(baseDir, "pinned", builder.ManifestFileName)
+		Expect(rec.ArtifactFiles).To(ContainElement(manifestPath))
+		data, err := os.ReadFile(manifestPath)
+		Expect(err).NotTo(HaveOccurred())
+		var m builder.BuildManifest
+		Expect(json.Unmarshal(data, &m)).To(Succeed())
+		Expect(m.ArtifactID).To(Equal("pinned"))
+		Expect(m.Inputs).To(Equal(builder.ManifestInputs{
+			BaseImage:             baseImage,
+			BaseImageDigest:       "sha256:base",
+			KairosInitImage:       kairosInitImage,
+			KairosInitImageDigest: "sha256:init",
+			Arch:                  "amd64",
+		}))
+		Expect(m.Image).To(Equal(builder.ManifestImage{Ref: "auroraboot-kairos:pinned", Digest: "sha256:image"}))
+		Expect(m.Outputs).To(ConsistOf(builder.ManifestFile{
+			Name:   "kairos.iso",
+			Size:   6,
+			SHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		}))
+	})
+
+	It("still builds, unpinned, when a digest cannot be resolved", func() {
+		s := newRecStore()
+		offline := func(context.Context, string, bool, builder.BuildOptions) (string, error) {
+			return "", fmt.Errorf("registry unreachable")
+		}
+		b := auroraboot.New(baseDir, noopDeploy, s).WithImageDigestFunc(offline)
+		GinkgoT().Setenv("KAIROS_INIT_IMAGE", kairosInitImage)
+
+		_, err := b.Build(context.Background(), builder.BuildOptions{
+			ID:        "unpinned",
+			BaseImage: baseImage,
+			Source:    builder.ImageSource{BaseImage: baseImage},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var rec *store.ArtifactRecord
+		Eventually(func() string {
+			rec, _ = s.GetByID(context.Background(), "unpinned")
+			return rec.Phase
+		}, "5s").Should(Equal(store.ArtifactReady))
+		Expect(rec.BaseImageDigest).To(BeEmpty())
+		Expect(rec.ContainerImageDigest).To(BeEmpty())
+		Expect(rec.KairosInitImage).To(Equal(kairosInitImage), "the server default in effect is recorded")
+
+		dockerfile, err := os.ReadFile(filepath.Join(baseDir, "unpinned", "Dockerfile.kairosify"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(dockerfile)).To(ContainSubstring("FROM " + baseImage + "\n"))
+	})
+})
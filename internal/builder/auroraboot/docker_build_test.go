@@ -69,9 +69,11 @@ func (noopArtifactStore) Update(context.Context, *store.ArtifactRecord) error
 func (noopArtifactStore) UpdatePhaseMessage(context.Context, string, string, string) error {
 	return nil
 }
-func (noopArtifactStore) UpdateFiles(context.Context, string, []string) error { return nil }
-func (noopArtifactStore) ClearUploadToken(context.Context, string) error      { return nil }
-func (noopArtifactStore) Delete(context.Context, string) error                { return nil }
-func (noopArtifactStore) DeleteByPhase(context.Context, string) error         { return nil }
-func (noopArtifactStore) GetLogs(context.Context, string) (string, error)     { return "", nil }
-func (noopArtifactStore) AppendLog(context.Context, string, string) error     { return nil }
+func (noopArtifactStore) UpdateFiles(context.Context, string, []string) error   { return nil }
+func (noopArtifactStore) UpdateStep(context.Context, string, string, int) error { return nil }
+func (noopArtifactStore) ClearUploadToken(context.Context, string) error        { return nil }
+func (noopArtifactStore) SetUploadToken(context.Context, string, string) error  { return nil }
+func (noopArtifactStore) Delete(context.Context, string) error                  { return nil }
+func (noopArtifactStore) DeleteByPhase(context.Context, string) error           { return nil }
+func (noopArtifactStore) GetLogs(context.Context, string) (string, error)       { return "", nil }
+func (noopArtifactStore) AppendLog(context.Context, string, string) error       { return nil }
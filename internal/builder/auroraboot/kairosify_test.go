@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/kairos-io/AuroraBoot/deployer"
 	"github.com/kairos-io/AuroraBoot/pkg/builder"
 	"github.com/kairos-io/AuroraBoot/pkg/schema"
 	"github.com/kairos-io/AuroraBoot/pkg/store"
@@ -28,12 +29,14 @@ func (*kairosifyTestStore) Update(context.Context, *store.ArtifactRecord) error
 func (*kairosifyTestStore) UpdatePhaseMessage(context.Context, string, string, string) error {
 	return nil
 }
-func (*kairosifyTestStore) UpdateFiles(context.Context, string, []string) error { return nil }
-func (*kairosifyTestStore) ClearUploadToken(context.Context, string) error      { return nil }
-func (*kairosifyTestStore) Delete(context.Context, string) error                { return nil }
-func (*kairosifyTestStore) DeleteByPhase(context.Context, string) error         { return nil }
-func (*kairosifyTestStore) GetLogs(context.Context, string) (string, error)     { return "", nil }
-func (*kairosifyTestStore) AppendLog(context.Context, string, string) error     { return nil }
+func (*kairosifyTestStore) UpdateFiles(context.Context, string, []string) error   { return nil }
+func (*kairosifyTestStore) UpdateStep(context.Context, string, string, int) error { return nil }
+func (*kairosifyTestStore) ClearUploadToken(context.Context, string) error        { return nil }
+func (*kairosifyTestStore) SetUploadToken(context.Context, string, string) error  { return nil }
+func (*kairosifyTestStore) Delete(context.Context, string) error                  { return nil }
+func (*kairosifyTestStore) DeleteByPhase(context.Context, string) error           { return nil }
+func (*kairosifyTestStore) GetLogs(context.Context, string) (string, error)       { return "", nil }
+func (*kairosifyTestStore) AppendLog(context.Context, string, string) error       { return nil }
 
 func installFakeDocker(t *testing.T) string {
 	t.Helper()
@@ -94,7 +97,7 @@ func TestBuildUsesGeneratedIDForDerivedImage(t *testing.T) {
 	tmpDir := t.TempDir()
 	installFakeDocker(t)
 	deployedImage := make(chan string, 1)
-	b := New(tmpDir, func(_ context.Context, _ schema.Config, artifact schema.ReleaseArtifact, _ string, _ io.Writer) error {
+	b := New(tmpDir, func(_ context.Context, _ schema.Config, artifact schema.ReleaseArtifact, _ string, _ io.Writer, _ func(deployer.Event)) error {
 		deployedImage <- artifact.ContainerImage
 		return nil
 	}, &kairosifyTestStore{})
@@ -0,0 +1,65 @@
+package auroraboot_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/deployer"
+	"github.com/kairos-io/AuroraBoot/internal/builder/auroraboot"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("AuroraBoot Builder step progress", func() {
+	BeforeEach(func() {
+		bin := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(bin, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755)).To(Succeed())
+		GinkgoT().Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	})
+
+	It("keeps the step the deployer is at and its percent on the record", func() {
+		var steps []string
+		s := newRecStore()
+		deploy := func(_ context.Context, _ schema.Config, _ schema.ReleaseArtifact, _ string, _ io.Writer, onEvent func(deployer.Event)) error {
+			record := func() {
+				rec, _ := s.GetByID(context.Background(), "progress")
+				steps = append(steps, rec.Step)
+			}
+			onEvent(deployer.Event{Type: deployer.EventStepStart, Step: "dump-source"})
+			record()
+			onEvent(deployer.Event{Type: deployer.EventProgress, Step: "dump-source", Bytes: 25, Total: 100})
+			// Progress of a step that does not know its total is not a percent
+			onEvent(deployer.Event{Type: deployer.EventProgress, Step: "dump-source", Bytes: 50})
+			onEvent(deployer.Event{Type: deployer.EventStepEnd, Step: "dump-source"})
+			rec, _ := s.GetByID(context.Background(), "progress")
+			Expect(rec.StepProgress).To(Equal(25))
+			onEvent(deployer.Event{Type: deployer.EventStepStart, Step: "gen-iso"})
+			record()
+			return nil
+		}
+
+		b := auroraboot.New(GinkgoT().TempDir(), deploy, s)
+		_, err := b.Build(context.Background(), builder.BuildOptions{
+			ID:        "progress",
+			BaseImage: "quay.io/kairos/ubuntu:24.04",
+			Outputs:   builder.OutputOptions{ISO: true},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() string {
+			rec, _ := s.GetByID(context.Background(), "progress")
+			return rec.Phase
+		}, "5s").Should(Equal(store.ArtifactReady))
+
+		Expect(steps).To(Equal([]string{"dump-source", "gen-iso"}))
+		rec, err := s.GetByID(context.Background(), "progress")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rec.Step).To(Equal("gen-iso"))
+		Expect(rec.StepProgress).To(BeZero())
+	})
+})
@@ -0,0 +1,87 @@
+package auroraboot_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/deployer"
+	"github.com/kairos-io/AuroraBoot/internal/builder/auroraboot"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("AuroraBoot Builder resume", func() {
+	BeforeEach(func() {
+		bin := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(bin, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755)).To(Succeed())
+		GinkgoT().Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	})
+
+	phase := func(s *recStore, id string) func() string {
+		return func() string {
+			rec, _ := s.GetByID(context.Background(), id)
+			return rec.Phase
+		}
+	}
+
+	It("reruns a failed build in place with the deployer resuming", func() {
+		var mu sync.Mutex
+		var configs []schema.Config
+		var dirs []string
+		deploy := func(_ context.Context, c schema.Config, _ schema.ReleaseArtifact, outputDir string, _ io.Writer, _ func(deployer.Event)) error {
+			mu.Lock()
+			defer mu.Unlock()
+			configs = append(configs, c)
+			dirs = append(dirs, outputDir)
+			if len(configs) == 1 {
+				return errors.New("inject-cloud-config failed")
+			}
+			return nil
+		}
+
+		s := newRecStore()
+		b := auroraboot.New(GinkgoT().TempDir(), deploy, s)
+		opts := builder.BuildOptions{
+			ID:        "resume",
+			BaseImage: "quay.io/kairos/ubuntu:24.04",
+			Outputs:   builder.OutputOptions{ISO: true},
+		}
+		_, err := b.Build(context.Background(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(phase(s, "resume"), "5s").Should(Equal(store.ArtifactError))
+		failed, err := s.GetByID(context.Background(), "resume")
+		Expect(err).NotTo(HaveOccurred())
+
+		opts.Resume = true
+		status, err := b.Build(context.Background(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.ID).To(Equal("resume"))
+		Eventually(phase(s, "resume"), "5s").Should(Equal(store.ArtifactReady))
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(configs).To(HaveLen(2))
+		Expect(configs[0].Resume).To(BeFalse())
+		Expect(configs[1].Resume).To(BeTrue())
+		Expect(dirs[1]).To(Equal(dirs[0]))
+
+		rec, err := s.GetByID(context.Background(), "resume")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rec.Message).To(BeEmpty())
+		Expect(rec.CreatedAt).To(Equal(failed.CreatedAt))
+	})
+
+	It("needs the ID of the build to resume", func() {
+		b := auroraboot.New(GinkgoT().TempDir(), nil, newRecStore())
+		_, err := b.Build(context.Background(), builder.BuildOptions{Resume: true, Outputs: builder.OutputOptions{ISO: true}})
+		Expect(err).To(MatchError(builder.ErrInvalidBuildOptions))
+	})
+})
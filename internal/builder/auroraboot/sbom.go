@@ -0,0 +1,65 @@
+package auroraboot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+// WithVulnDB points SBOM builds at a local mirror of OSV advisories. Every
+// build that asks for an SBOM then also gets a vulnerability report, matched
+// offline against dir. The mirror is host configuration, not part of the
+// build request, so a worker uses its own copy.
+func (b *Builder) WithVulnDB(dir string) *Builder {
+	b.vulnDB = dir
+	return b
+}
+
+// sbomFormat is the document format opts asks for, defaulting to SPDX.
+func sbomFormat(opts builder.BuildOptions) string {
+	if opts.Outputs.SBOMFormat != "" {
+		return opts.Outputs.SBOMFormat
+	}
+	return sbom.FormatSPDX
+}
+
+// sbomConfig is the deployer's sbom block for opts; empty (disabled) when no
+// SBOM was requested.
+func (b *Builder) sbomConfig(opts builder.BuildOptions) schema.SBOM {
+	if !opts.Outputs.SBOM {
+		return schema.SBOM{}
+	}
+	return schema.SBOM{Format: sbomFormat(opts), VulnDB: b.vulnDB}
+}
+
+// readVulnerabilitySummary loads the summary of the report the deployer wrote
+// to outputDir. nil when there is none, which is the normal case for builds
+// without an advisory database.
+func readVulnerabilitySummary(outputDir string) (*store.VulnerabilitySummary, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, sbom.ReportFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r sbom.VulnReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &store.VulnerabilitySummary{
+		Database:  r.Database,
+		Critical:  r.Summary.Critical,
+		High:      r.Summary.High,
+		Medium:    r.Summary.Medium,
+		Low:       r.Summary.Low,
+		Unknown:   r.Summary.Unknown,
+		Total:     r.Summary.Total,
+		ScannedAt: r.ScannedAt,
+	}, nil
+}
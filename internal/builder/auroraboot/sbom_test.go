@@ -0,0 +1,102 @@
+package auroraboot_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/deployer"
+	"github.com/kairos-io/AuroraBoot/internal/builder/auroraboot"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("AuroraBoot Builder SBOM", func() {
+	var baseDir string
+
+	BeforeEach(func() {
+		baseDir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(baseDir, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755)).To(Succeed())
+		GinkgoT().Setenv("PATH", baseDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	})
+
+	It("asks the deployer for the SBOM and records the vulnerability summary", func() {
+		s := newRecStore()
+		var got schema.SBOM
+		// Stand in for the deployer's gen-sbom step: write the document and
+		// the report where it would.
+		deploy := func(_ context.Context, cfg schema.Config, _ schema.ReleaseArtifact, outputDir string, _ io.Writer, _ func(deployer.Event)) error {
+			got = cfg.SBOM
+			report, err := json.Marshal(sbom.VulnReport{
+				Database: "/srv/osv",
+				Summary:  sbom.Summary{Critical: 2, Low: 1, Total: 3},
+			})
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(outputDir, "sbom.cdx.json"), []byte("{}"), 0o644); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(outputDir, sbom.ReportFileName), report, 0o644)
+		}
+		b := auroraboot.New(baseDir, deploy, s).WithVulnDB("/srv/osv")
+
+		_, err := b.Build(context.Background(), builder.BuildOptions{
+			ID:        "with-sbom",
+			BaseImage: "quay.io/kairos/ubuntu:24.04",
+			Outputs:   builder.OutputOptions{ISO: true, SBOM: true, SBOMFormat: sbom.FormatCycloneDX},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var rec *store.ArtifactRecord
+		Eventually(func() string {
+			rec, _ = s.GetByID(context.Background(), "with-sbom")
+			return rec.Phase
+		}, "5s").Should(Equal(store.ArtifactReady))
+
+		Expect(got).To(Equal(schema.SBOM{Format: sbom.FormatCycloneDX, VulnDB: "/srv/osv"}))
+		Expect(rec.SBOM).To(BeTrue())
+		Expect(rec.SBOMFormat).To(Equal(sbom.FormatCycloneDX))
+		Expect(rec.Vulnerabilities).NotTo(BeNil())
+		Expect(rec.Vulnerabilities.Database).To(Equal("/srv/osv"))
+		Expect(rec.Vulnerabilities.Critical).To(Equal(2))
+		Expect(rec.Vulnerabilities.Total).To(Equal(3))
+		Expect(rec.ArtifactFiles).To(ContainElements(
+			filepath.Join(baseDir, "with-sbom", "sbom.cdx.json"),
+			filepath.Join(baseDir, "with-sbom", sbom.ReportFileName),
+		))
+	})
+
+	It("leaves the SBOM step disabled when no SBOM is requested", func() {
+		s := newRecStore()
+		var got schema.SBOM
+		deploy := func(_ context.Context, cfg schema.Config, _ schema.ReleaseArtifact, _ string, _ io.Writer, _ func(deployer.Event)) error {
+			got = cfg.SBOM
+			return nil
+		}
+		b := auroraboot.New(baseDir, deploy, s).WithVulnDB("/srv/osv")
+
+		_, err := b.Build(context.Background(), builder.BuildOptions{
+			ID:        "no-sbom",
+			BaseImage: "quay.io/kairos/ubuntu:24.04",
+			Outputs:   builder.OutputOptions{ISO: true},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var rec *store.ArtifactRecord
+		Eventually(func() string {
+			rec, _ = s.GetByID(context.Background(), "no-sbom")
+			return rec.Phase
+		}, "5s").Should(Equal(store.ArtifactReady))
+		Expect(got).To(Equal(schema.SBOM{}))
+		Expect(rec.SBOM).To(BeFalse())
+		Expect(rec.Vulnerabilities).To(BeNil())
+	})
+})
@@ -0,0 +1,29 @@
+package auroraboot
+
+import (
+	"context"
+
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
+)
+
+// WithStorage has finished builds push their outputs to s before they are
+// marked Ready, so every listed file can be downloaded from it. nil (the
+// default) leaves them in the build directory only, which is also what a
+// Local storage rooted at the builder's base directory amounts to.
+func (b *Builder) WithStorage(s storage.Backend) *Builder {
+	b.storage = s
+	return b
+}
+
+// storeArtifacts pushes the paths of a finished build to the storage.
+func (b *Builder) storeArtifacts(ctx context.Context, id string, paths []string) error {
+	if b.storage == nil {
+		return nil
+	}
+	for _, p := range paths {
+		if err := b.storage.Put(ctx, storage.Key(id, p), p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package auroraboot_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/deployer"
+	"github.com/kairos-io/AuroraBoot/internal/builder/auroraboot"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
+	"github.com/kairos-io/AuroraBoot/pkg/storage/s3fake"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("AuroraBoot Builder storage", func() {
+	var (
+		baseDir string
+		fake    *s3fake.Server
+		backend *storage.S3
+	)
+
+	BeforeEach(func() {
+		baseDir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(baseDir, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755)).To(Succeed())
+		GinkgoT().Setenv("PATH", baseDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+		fake = s3fake.New("artifacts", "minio")
+		DeferCleanup(fake.Close)
+		var err error
+		backend, err = storage.NewS3(storage.S3Config{
+			Endpoint: fake.URL, Bucket: "artifacts", AccessKey: "minio", SecretKey: "minio123", PathStyle: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	deploy := func(_ context.Context, _ schema.Config, _ schema.ReleaseArtifact, outputDir string, _ io.Writer, _ func(deployer.Event)) error {
+		return os.WriteFile(filepath.Join(outputDir, "kairos.iso"), []byte("hello\n"), 0o644)
+	}
+
+	build := func(id string) *store.ArtifactRecord {
+		s := newRecStore()
+		b := auroraboot.New(baseDir, deploy, s).WithStorage(backend)
+		_, err := b.Build(context.Background(), builder.BuildOptions{
+			ID:        id,
+			BaseImage: "quay.io/kairos/ubuntu:24.04",
+			Outputs:   builder.OutputOptions{ISO: true},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		var rec *store.ArtifactRecord
+		Eventually(func() string {
+			rec, _ = s.GetByID(context.Background(), id)
+			return rec.Phase
+		}, "5s").ShouldNot(Or(Equal(store.ArtifactPending), Equal(store.ArtifactBuilding)))
+		return rec
+	}
+
+	It("pushes every output to the storage before the build is Ready", func() {
+		rec := build("stored")
+		Expect(rec.Phase).To(Equal(store.ArtifactReady))
+		Expect(fake.Keys()).To(ConsistOf(
+			"stored/kairos.iso",
+			"stored/"+builder.ManifestFileName,
+			"stored/"+checksums.FileName,
+		))
+		data, _ := fake.Object("stored/kairos.iso")
+		Expect(string(data)).To(Equal("hello\n"))
+	})
+
+	It("fails the build when the storage refuses the outputs", func() {
+		fake.Close()
+		rec := build("unstored")
+		Expect(rec.Phase).To(Equal(store.ArtifactError))
+		Expect(rec.Message).To(ContainSubstring("storing artifacts failed"))
+	})
+})
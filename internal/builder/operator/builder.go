@@ -22,6 +22,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/cloudconfig"
 	"github.com/kairos-io/AuroraBoot/pkg/store"
 )
 
@@ -140,6 +141,10 @@ func (b *Builder) Build(ctx context.Context, opts builder.BuildOptions) (*builde
 	if err != nil {
 		return nil, err
 	}
+	// The resolved config only ever lands in the cloud-config Secret.
+	if opts.CloudConfig, err = cloudconfig.ResolveSecrets(opts.CloudConfig, opts.Secrets); err != nil {
+		return nil, fmt.Errorf("%w: cloudConfig: %v", builder.ErrInvalidBuildOptions, err)
+	}
 
 	// Inject the exporter that ships finished artifacts back to AuroraBoot
 	// (via PUT /api/v1/artifacts/:id/upload/*). Only emit when we have both
@@ -105,6 +105,18 @@ func (s *stubArtifactStore) UpdateFiles(_ context.Context, id string, files []st
 	return nil
 }
 
+func (s *stubArtifactStore) UpdateStep(_ context.Context, id, step string, progress int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[id]
+	if !ok {
+		return fmt.Errorf("not found")
+	}
+	rec.Step = step
+	rec.StepProgress = progress
+	return nil
+}
+
 func (s *stubArtifactStore) ClearUploadToken(_ context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -117,6 +129,18 @@ func (s *stubArtifactStore) ClearUploadToken(_ context.Context, id string) error
 	return nil
 }
 
+func (s *stubArtifactStore) SetUploadToken(_ context.Context, id, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[id]
+	if !ok {
+		return fmt.Errorf("not found")
+	}
+	rec.UploadToken = token
+	s.updates++
+	return nil
+}
+
 func (s *stubArtifactStore) Delete(_ context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -258,6 +282,38 @@ var _ = Describe("Operator Builder", func() {
 			Expect(sec.OwnerReferences[0].Name).To(Equal("build-cc"))
 		})
 
+		It("puts the resolved cloud-config only in the Secret", func() {
+			ctx := context.Background()
+			b, fc := newFakeBuilder("kairos-builds")
+
+			_, err := b.Build(ctx, builder.BuildOptions{
+				ID:          "build-sec",
+				BaseImage:   "quay.io/kairos/ubuntu:v3.6.0",
+				Source:      builder.ImageSource{Arch: "amd64"},
+				Outputs:     builder.OutputOptions{ISO: true},
+				CloudConfig: "#cloud-config\nwifi:\n  psk: '{{ secret \"wifi-psk\" }}'\n",
+				Secrets:     map[string]string{"wifi-psk": "correct-horse-battery"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			cancelOnCleanup(b, "build-sec")
+
+			sec := &corev1.Secret{}
+			Expect(fc.Get(ctx, types.NamespacedName{Name: "build-sec-cloud-config", Namespace: "kairos-builds"}, sec)).To(Succeed())
+			Expect(string(sec.Data["cloud-config"])).To(Equal("#cloud-config\nwifi:\n  psk: correct-horse-battery\n"))
+		})
+
+		It("returns ErrInvalidBuildOptions for a secret it has no value for", func() {
+			b, _ := newFakeBuilder("kairos-builds")
+			_, err := b.Build(context.Background(), builder.BuildOptions{
+				ID:          "build-nosec",
+				BaseImage:   "quay.io/kairos/ubuntu:v3.6.0",
+				Source:      builder.ImageSource{Arch: "amd64"},
+				Outputs:     builder.OutputOptions{ISO: true},
+				CloudConfig: "wifi:\n  psk: '{{ secret \"wifi-psk\" }}'\n",
+			})
+			Expect(errors.Is(err, builder.ErrInvalidBuildOptions)).To(BeTrue())
+		})
+
 		It("returns ErrInvalidBuildOptions on invalid arch", func() {
 			ctx := context.Background()
 			b, _ := newFakeBuilder("kairos-builds")
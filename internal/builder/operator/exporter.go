@@ -32,6 +32,10 @@ func uploadSecretName(id string) string { return id + "-upload" }
 // endpoint, authenticated by the token minted in the Create handler and
 // mounted via envFrom on the upload Secret.
 //
+// The operator does not produce a SHA256SUMS of its own, so the exporter
+// builds one from the checksums it sends with each file and uploads it last;
+// AuroraBoot checks it against the files it received and signs it.
+//
 // The operator itself injects the "artifacts" Volume (backed by the build's
 // artifacts PVC, read-only); we only declare the VolumeMount so the container
 // can see /artifacts.
@@ -48,15 +52,24 @@ func uploadExporter(id string) batchv1.JobSpec {
 						Image:   curlUploaderImage,
 						Command: []string{"sh", "-ec"},
 						Args: []string{`
+put() {
+  curl -fsS --retry 3 --connect-timeout 30 --max-time 3600 -X PUT \
+    -H "Authorization: Bearer $AURORABOOT_UPLOAD_TOKEN" \
+    -H "X-Checksum-Sha256: $3" \
+    --upload-file "$1" \
+    "$AURORABOOT_URL/api/v1/artifacts/$BUILD_ID/upload/$2" || exit 1
+}
+: > /tmp/SHA256SUMS
 for f in /artifacts/*; do
   [ -f "$f" ] || continue
   base=$(basename "$f")
+  sum=$(sha256sum "$f" | cut -d' ' -f1)
   echo "Uploading $base to $AURORABOOT_URL"
-  curl -fsS --retry 3 --connect-timeout 30 --max-time 3600 -X PUT \
-    -H "Authorization: Bearer $AURORABOOT_UPLOAD_TOKEN" \
-    --upload-file "$f" \
-    "$AURORABOOT_URL/api/v1/artifacts/$BUILD_ID/upload/$base" || exit 1
+  put "$f" "$base" "$sum"
+  echo "$sum  $base" >> /tmp/SHA256SUMS
 done
+echo "Uploading SHA256SUMS to $AURORABOOT_URL"
+put /tmp/SHA256SUMS SHA256SUMS "$(sha256sum /tmp/SHA256SUMS | cut -d' ' -f1)"
 echo "Upload done"
 `},
 						Env: []corev1.EnvVar{
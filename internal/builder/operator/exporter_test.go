@@ -1,6 +1,9 @@
 package operator
 
 import (
+	"strings"
+
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -36,4 +39,13 @@ var _ = Describe("uploadExporter", func() {
 		Expect(c.EnvFrom[0].SecretRef.Name).To(Equal(uploadSecretName("build-42")),
 			"exporter reads AURORABOOT_URL and AURORABOOT_UPLOAD_TOKEN from this Secret")
 	})
+
+	It("sends a checksum with every file and uploads SHA256SUMS last", func() {
+		script := uploadExporter("build-42").Template.Spec.Containers[0].Args[0]
+
+		Expect(script).To(ContainSubstring(`-H "` + checksums.Header + `: $3"`))
+		Expect(script).To(ContainSubstring("put /tmp/" + checksums.FileName + " " + checksums.FileName))
+		Expect(strings.Index(script, "put /tmp/"+checksums.FileName)).To(BeNumerically(">", strings.Index(script, "done")),
+			"the manifest is checked against the files already received, so it goes after them")
+	})
 })
@@ -25,6 +25,39 @@ func translateBuildOptions(id string, opts builder.BuildOptions) (buildv1alpha2.
 		return buildv1alpha2.OSArtifactSpec{}, fmt.Errorf("%w: source.arch must be 'amd64' or 'arm64', got %q", builder.ErrInvalidBuildOptions, arch)
 	}
 
+	// The OSArtifact CRD has no SBOM stage and the operator's pods never
+	// expose the unpacked rootfs to AuroraBoot, so there is nothing to scan.
+	if opts.Outputs.SBOM {
+		return buildv1alpha2.OSArtifactSpec{}, fmt.Errorf("%w: outputs.sbom is not produced by the operator backend", builder.ErrNotSupported)
+	}
+
+	// The OSArtifact CRD only knows the raw, GCE and Azure disk formats, and
+	// the raw disk is uploaded as is.
+	if opts.Outputs.QCOW2 {
+		return buildv1alpha2.OSArtifactSpec{}, fmt.Errorf("%w: outputs.qcow2 is not produced by the operator backend", builder.ErrNotSupported)
+	}
+	if opts.Outputs.VHDDynamic || opts.Outputs.VHDX {
+		return buildv1alpha2.OSArtifactSpec{}, fmt.Errorf("%w: dynamic VHD and VHDX outputs are not produced by the operator backend", builder.ErrNotSupported)
+	}
+	if opts.Outputs.VMDK || opts.Outputs.OVA {
+		return buildv1alpha2.OSArtifactSpec{}, fmt.Errorf("%w: outputs.vmdk and outputs.ova are not produced by the operator backend", builder.ErrNotSupported)
+	}
+	if opts.Outputs.Compression != "" {
+		return buildv1alpha2.OSArtifactSpec{}, fmt.Errorf("%w: outputs.compression is not supported by the operator backend", builder.ErrNotSupported)
+	}
+
+	// Each OSArtifact is built from scratch in fresh pods: nothing of a
+	// failed build is left to resume from.
+	if opts.Resume {
+		return buildv1alpha2.OSArtifactSpec{}, fmt.Errorf("%w: resuming a build is not supported by the operator backend", builder.ErrNotSupported)
+	}
+
+	// The operator builds from an inline Dockerfile; it has no step that
+	// checks out a repository first.
+	if opts.Git != nil {
+		return buildv1alpha2.OSArtifactSpec{}, fmt.Errorf("%w: git sources are not supported by the operator backend", builder.ErrNotSupported)
+	}
+
 	// A caller may pass the base image via the legacy flat field
 	// (opts.BaseImage) or the grouped shape (opts.Source.BaseImage). Both are
 	// valid; treat them as one so validation and translation cannot disagree.
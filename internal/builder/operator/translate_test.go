@@ -277,6 +277,61 @@ var _ = Describe("translateBuildOptions", func() {
 			},
 			wantErr: builder.ErrInvalidBuildOptions,
 		},
+		{
+			name: "SBOM is not supported",
+			opts: builder.BuildOptions{
+				BaseImage: "quay.io/kairos/ubuntu:v3.6.0",
+				Source:    builder.ImageSource{Arch: "amd64"},
+				Outputs:   builder.OutputOptions{ISO: true, SBOM: true},
+			},
+			wantErr: builder.ErrNotSupported,
+		},
+		{
+			name: "qcow2 is not supported",
+			opts: builder.BuildOptions{
+				BaseImage: "quay.io/kairos/ubuntu:v3.6.0",
+				Source:    builder.ImageSource{Arch: "amd64"},
+				Outputs:   builder.OutputOptions{RawDisk: true, QCOW2: true},
+			},
+			wantErr: builder.ErrNotSupported,
+		},
+		{
+			name: "vhdx is not supported",
+			opts: builder.BuildOptions{
+				BaseImage: "quay.io/kairos/ubuntu:v3.6.0",
+				Source:    builder.ImageSource{Arch: "amd64"},
+				Outputs:   builder.OutputOptions{VHDX: true},
+			},
+			wantErr: builder.ErrNotSupported,
+		},
+		{
+			name: "ova is not supported",
+			opts: builder.BuildOptions{
+				BaseImage: "quay.io/kairos/ubuntu:v3.6.0",
+				Source:    builder.ImageSource{Arch: "amd64"},
+				Outputs:   builder.OutputOptions{OVA: true},
+			},
+			wantErr: builder.ErrNotSupported,
+		},
+		{
+			name: "compression is not supported",
+			opts: builder.BuildOptions{
+				BaseImage: "quay.io/kairos/ubuntu:v3.6.0",
+				Source:    builder.ImageSource{Arch: "amd64"},
+				Outputs:   builder.OutputOptions{RawDisk: true, Compression: "zstd"},
+			},
+			wantErr: builder.ErrNotSupported,
+		},
+		{
+			name: "resume is not supported",
+			opts: builder.BuildOptions{
+				BaseImage: "quay.io/kairos/ubuntu:v3.6.0",
+				Source:    builder.ImageSource{Arch: "amd64"},
+				Outputs:   builder.OutputOptions{ISO: true},
+				Resume:    true,
+			},
+			wantErr: builder.ErrNotSupported,
+		},
 		{
 			name: "FIPS on pre-built ref is invalid",
 			opts: builder.BuildOptions{
@@ -0,0 +1,450 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kairos-io/AuroraBoot/internal/builder/auroraboot"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/kairos-io/AuroraBoot/pkg/client"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+const (
+	// defaultPollInterval is how long an idle worker waits between lease
+	// polls. Each poll doubles as the idle heartbeat.
+	defaultPollInterval = 5 * time.Second
+	// defaultHeartbeatInterval is how often a busy worker renews its lease.
+	// A third of DefaultLeaseDuration leaves room for two missed beats
+	// before the server re-queues the build.
+	defaultHeartbeatInterval = DefaultLeaseDuration / 3
+	// statusPollInterval is how often the agent checks its in-process
+	// builder for a terminal phase.
+	statusPollInterval = time.Second
+)
+
+// LocalBuilderFactory constructs the in-process builder an Agent runs each
+// leased build on. st is the agent's proxy store: log appends go to the
+// server, everything else stays in memory on the worker. Production uses
+// DefaultLocalBuilder; tests substitute a builder that does not need docker.
+type LocalBuilderFactory func(baseDir string, st store.ArtifactStore) builder.ArtifactBuilder
+
+// DefaultLocalBuilder runs leased builds on the same AuroraBoot pipeline the
+// local backend uses, so a worker produces byte-for-byte what `--builder=local`
+// would on a host of the same architecture.
+func DefaultLocalBuilder(baseDir string, st store.ArtifactStore) builder.ArtifactBuilder {
+	return auroraboot.New(baseDir, nil, st)
+}
+
+// AgentConfig configures an Agent.
+type AgentConfig struct {
+	// Client points at the AuroraBoot server. It does not need credentials;
+	// the agent registers and binds its own worker API key.
+	Client *client.Client
+	// RegistrationToken is the server's worker registration token. Only
+	// needed when APIKey is empty.
+	RegistrationToken string
+	// WorkerID and APIKey resume a previous registration. When APIKey is
+	// empty the agent registers on first use.
+	WorkerID string
+	APIKey   string
+	Name     string
+	Arch     string
+	Version  string
+	// WorkDir is where leased builds are staged before upload. Each build
+	// gets its own <WorkDir>/<id> directory, removed once the build is done.
+	WorkDir string
+
+	PollInterval      time.Duration
+	HeartbeatInterval time.Duration
+	NewBuilder        LocalBuilderFactory
+	// VulnDB is this host's local mirror of OSV advisories, handed to the
+	// default local builder so SBOM builds also get a vulnerability report.
+	// Ignored when NewBuilder is set.
+	VulnDB string
+	// Log receives the agent's own progress messages. Defaults to stderr.
+	Log io.Writer
+	// OnRegister, when set, is called after every successful registration so
+	// the caller can persist the credentials and resume with them later.
+	OnRegister func(workerID, apiKey string) error
+}
+
+// Agent is the worker half of the remote build protocol: it leases builds
+// for its architecture, runs them in-process, streams their logs, uploads
+// their outputs with the per-build upload token and reports the result.
+type Agent struct {
+	cfg AgentConfig
+	cli *client.Client
+}
+
+// NewAgent returns an Agent with defaults applied to cfg.
+func NewAgent(cfg AgentConfig) *Agent {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	if cfg.NewBuilder == nil {
+		cfg.NewBuilder = DefaultLocalBuilder
+		if cfg.VulnDB != "" {
+			vulnDB := cfg.VulnDB
+			cfg.NewBuilder = func(baseDir string, st store.ArtifactStore) builder.ArtifactBuilder {
+				return auroraboot.New(baseDir, nil, st).WithVulnDB(vulnDB)
+			}
+		}
+	}
+	if cfg.Log == nil {
+		cfg.Log = os.Stderr
+	}
+	a := &Agent{cfg: cfg}
+	if cfg.APIKey != "" {
+		a.cli = cfg.Client.WithWorkerAPIKey(cfg.APIKey)
+	}
+	return a
+}
+
+// Register enrolls the worker with the server unless it already holds an
+// API key.
+func (a *Agent) Register(ctx context.Context) error {
+	if a.cli != nil {
+		return nil
+	}
+	resp, err := a.cfg.Client.Workers.Register(ctx, client.WorkerRegisterRequest{
+		RegistrationToken: a.cfg.RegistrationToken,
+		Name:              a.cfg.Name,
+		Arch:              a.cfg.Arch,
+		Version:           a.cfg.Version,
+	})
+	if err != nil {
+		return fmt.Errorf("register worker: %w", err)
+	}
+	a.cfg.WorkerID = resp.ID
+	a.cfg.APIKey = resp.APIKey
+	a.cli = a.cfg.Client.WithWorkerAPIKey(resp.APIKey)
+	fmt.Fprintf(a.cfg.Log, "worker: registered as %s (%s, %s)\n", a.cfg.Name, a.cfg.Arch, resp.ID)
+	if a.cfg.OnRegister != nil {
+		if err := a.cfg.OnRegister(resp.ID, resp.APIKey); err != nil {
+			fmt.Fprintf(a.cfg.Log, "worker: persisting credentials: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// Run leases and runs builds until ctx is cancelled. Transient server errors
+// are logged and retried on the next poll; only a failed registration is
+// fatal.
+func (a *Agent) Run(ctx context.Context) error {
+	if err := a.Register(ctx); err != nil {
+		return err
+	}
+	for {
+		ran, err := a.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			fmt.Fprintf(a.cfg.Log, "worker: %v\n", err)
+		}
+		if ran {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(a.cfg.PollInterval):
+		}
+	}
+}
+
+// RunOnce leases at most one build and runs it to completion. ran reports
+// whether a build was leased.
+func (a *Agent) RunOnce(ctx context.Context) (ran bool, err error) {
+	if err := a.Register(ctx); err != nil {
+		return false, err
+	}
+	lease, err := a.cli.Workers.Lease(ctx)
+	if err != nil {
+		if client.IsUnauthorized(err) {
+			// The worker was removed by an admin (or the server lost its
+			// database): drop the stale key and enroll again next time.
+			a.cli = nil
+			a.cfg.WorkerID, a.cfg.APIKey = "", ""
+		}
+		return false, fmt.Errorf("lease: %w", err)
+	}
+	if lease == nil {
+		return false, nil
+	}
+	fmt.Fprintf(a.cfg.Log, "worker: leased build %s\n", lease.BuildID)
+	return true, a.runLease(ctx, lease)
+}
+
+func (a *Agent) runLease(ctx context.Context, lease *builder.WorkerLease) error {
+	id := lease.BuildID
+	opts := lease.Options
+	opts.ID = id
+	defer os.RemoveAll(filepath.Join(a.cfg.WorkDir, id))
+
+	proxy := newProxyStore(a.cli, id)
+	local := a.cfg.NewBuilder(a.cfg.WorkDir, proxy)
+	if _, err := local.Build(ctx, opts); err != nil {
+		return a.finish(ctx, id, builder.WorkerResult{Phase: builder.BuildError, Message: err.Error()})
+	}
+
+	st, lost, err := a.wait(ctx, local, id)
+	if lost {
+		fmt.Fprintf(a.cfg.Log, "worker: lease on %s lost, build abandoned\n", id)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if st.Phase != builder.BuildReady {
+		return a.finish(ctx, id, builder.WorkerResult{Phase: builder.BuildError, Message: st.Message})
+	}
+
+	// Uploading a multi-gigabyte image can outlast the lease, so keep
+	// renewing it until the result is reported.
+	uploadCtx, stop := a.keepLease(ctx, id)
+	var uploadErr error
+	for _, path := range st.Artifacts {
+		if err := a.upload(uploadCtx, id, opts.UploadToken, path); err != nil {
+			uploadErr = fmt.Errorf("upload %s failed: %v", filepath.Base(path), err)
+			break
+		}
+	}
+	if stop() {
+		fmt.Fprintf(a.cfg.Log, "worker: lease on %s lost during upload, build abandoned\n", id)
+		return nil
+	}
+	if uploadErr != nil {
+		msg := uploadErr.Error()
+		_ = a.cli.Workers.AppendLog(ctx, id, msg+"\n")
+		return a.finish(ctx, id, builder.WorkerResult{Phase: builder.BuildError, Message: msg})
+	}
+	return a.finish(ctx, id, proxy.readyResult())
+}
+
+// keepLease renews the lease on id every heartbeat tick until stop is called.
+// A lost lease cancels the returned context so in-flight uploads abort; stop
+// reports whether that happened.
+func (a *Agent) keepLease(ctx context.Context, id string) (context.Context, func() (lost bool)) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	var lost bool
+	go func() {
+		defer close(done)
+		beat := time.NewTicker(a.cfg.HeartbeatInterval)
+		defer beat.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-beat.C:
+				err := a.cli.Workers.Heartbeat(ctx, id)
+				if err == nil || ctx.Err() != nil {
+					continue
+				}
+				if client.IsConflict(err) {
+					lost = true
+					cancel()
+					return
+				}
+				fmt.Fprintf(a.cfg.Log, "worker: heartbeat for %s: %v\n", id, err)
+			}
+		}
+	}()
+	return ctx, func() bool {
+		cancel()
+		<-done
+		return lost
+	}
+}
+
+// wait polls the in-process builder until the build is terminal, renewing the
+// lease on every heartbeat tick. A lost lease cancels the local build.
+func (a *Agent) wait(ctx context.Context, local builder.ArtifactBuilder, id string) (*builder.BuildStatus, bool, error) {
+	poll := time.NewTicker(statusPollInterval)
+	defer poll.Stop()
+	beat := time.NewTicker(a.cfg.HeartbeatInterval)
+	defer beat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = local.Cancel(context.Background(), id)
+			return nil, false, ctx.Err()
+		case <-beat.C:
+			if err := a.cli.Workers.Heartbeat(ctx, id); err != nil {
+				if client.IsConflict(err) {
+					_ = local.Cancel(context.Background(), id)
+					return nil, true, nil
+				}
+				fmt.Fprintf(a.cfg.Log, "worker: heartbeat for %s: %v\n", id, err)
+			}
+		case <-poll.C:
+			st, err := local.Status(ctx, id)
+			if err != nil {
+				continue
+			}
+			if st.Phase == builder.BuildReady || st.Phase == builder.BuildError {
+				return st, false, nil
+			}
+		}
+	}
+}
+
+func (a *Agent) upload(ctx context.Context, id, token, path string) error {
+	sum, err := checksums.HashFile(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return a.cli.Workers.Upload(ctx, id, token, filepath.Base(path), sum, f)
+}
+
+func (a *Agent) finish(ctx context.Context, id string, res builder.WorkerResult) error {
+	if err := a.cli.Workers.Finish(ctx, id, res); err != nil {
+		if client.IsConflict(err) {
+			fmt.Fprintf(a.cfg.Log, "worker: lease on %s lost before the result was reported\n", id)
+			return nil
+		}
+		return fmt.Errorf("report result for %s: %w", id, err)
+	}
+	fmt.Fprintf(a.cfg.Log, "worker: build %s finished: %s\n", id, res.Phase)
+	return nil
+}
+
+// proxyStore is the store.ArtifactStore the in-process builder writes to on
+// a worker. The artifact record lives on the server, which the worker only
+// reaches through the lease protocol, so log appends are forwarded there and
+// everything else (phase transitions, the final file list, the container
+// image) is kept in memory until the agent reports the result.
+type proxyStore struct {
+	cli *client.Client
+	id  string
+
+	mu  sync.Mutex
+	rec store.ArtifactRecord
+}
+
+func newProxyStore(cli *client.Client, id string) *proxyStore {
+	return &proxyStore{cli: cli, id: id, rec: store.ArtifactRecord{ID: id, Phase: store.ArtifactPending}}
+}
+
+// readyResult is the success report built from what the local builder wrote
+// into its final record.
+func (p *proxyStore) readyResult() builder.WorkerResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := builder.WorkerResult{
+		Phase:                 builder.BuildReady,
+		ContainerImage:        p.rec.ContainerImage,
+		KairosInitImage:       p.rec.KairosInitImage,
+		BaseImageDigest:       p.rec.BaseImageDigest,
+		KairosInitImageDigest: p.rec.KairosInitImageDigest,
+		ContainerImageDigest:  p.rec.ContainerImageDigest,
+		Vulnerabilities:       p.rec.Vulnerabilities,
+	}
+	if p.rec.Git != nil {
+		res.GitCommit = p.rec.Git.Commit
+	}
+	return res
+}
+
+func (p *proxyStore) check(id string) error {
+	if id != p.id {
+		return errors.New("proxy store: unknown artifact")
+	}
+	return nil
+}
+
+func (p *proxyStore) Create(_ context.Context, rec *store.ArtifactRecord) error {
+	if err := p.check(rec.ID); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rec = *rec
+	return nil
+}
+
+func (p *proxyStore) GetByID(_ context.Context, id string) (*store.ArtifactRecord, error) {
+	if err := p.check(id); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rec := p.rec
+	return &rec, nil
+}
+
+func (p *proxyStore) List(context.Context) ([]*store.ArtifactRecord, error) {
+	rec, _ := p.GetByID(context.Background(), p.id)
+	return []*store.ArtifactRecord{rec}, nil
+}
+
+func (p *proxyStore) Update(_ context.Context, rec *store.ArtifactRecord) error {
+	return p.Create(context.Background(), rec)
+}
+
+func (p *proxyStore) UpdatePhaseMessage(_ context.Context, id, phase, message string) error {
+	if err := p.check(id); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rec.Phase = phase
+	p.rec.Message = message
+	return nil
+}
+
+func (p *proxyStore) UpdateFiles(_ context.Context, id string, files []string) error {
+	if err := p.check(id); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rec.ArtifactFiles = files
+	return nil
+}
+
+// UpdateStep keeps the step on the local record only: the worker API has no
+// call for it, the server sees the build's progress through its logs.
+func (p *proxyStore) UpdateStep(_ context.Context, id, step string, progress int) error {
+	if err := p.check(id); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rec.Step = step
+	p.rec.StepProgress = progress
+	return nil
+}
+
+func (p *proxyStore) ClearUploadToken(context.Context, string) error       { return nil }
+func (p *proxyStore) SetUploadToken(context.Context, string, string) error { return nil }
+func (p *proxyStore) Delete(context.Context, string) error                 { return nil }
+func (p *proxyStore) DeleteByPhase(context.Context, string) error          { return nil }
+func (p *proxyStore) GetLogs(context.Context, string) (string, error) {
+	return "", nil
+}
+
+// AppendLog forwards a chunk to the server. The in-process builder has
+// already applied LogRedactValues, so the chunk is safe to ship as-is.
+func (p *proxyStore) AppendLog(ctx context.Context, id, text string) error {
+	if err := p.check(id); err != nil {
+		return err
+	}
+	return p.cli.Workers.AppendLog(ctx, id, text)
+}
+
+var _ store.ArtifactStore = (*proxyStore)(nil)
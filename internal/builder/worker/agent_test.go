@@ -0,0 +1,315 @@
+package worker_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/internal/builder/worker"
+	gormstore "github.com/kairos-io/AuroraBoot/internal/store/gorm"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/kairos-io/AuroraBoot/pkg/client"
+	"github.com/kairos-io/AuroraBoot/pkg/server"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/kairos-io/AuroraBoot/pkg/ws"
+)
+
+const (
+	adminPassword = "admin"
+	workerToken   = "worker-token"
+)
+
+// fakeLocalBuilder stands in for the docker-backed local pipeline on the
+// worker side. It writes one output file and its SHA256SUMS per build,
+// as the real builder does, streams a log line
+// through the agent's proxy store and optionally blocks until released so
+// specs can act while a build is in flight.
+type fakeLocalBuilder struct {
+	baseDir string
+	st      store.ArtifactStore
+	hold    chan struct{}
+
+	mu     sync.Mutex
+	builds map[string]*builder.BuildStatus
+}
+
+func (f *fakeLocalBuilder) Build(ctx context.Context, opts builder.BuildOptions) (*builder.BuildStatus, error) {
+	status := &builder.BuildStatus{ID: opts.ID, Phase: builder.BuildBuilding}
+	f.mu.Lock()
+	f.builds[opts.ID] = status
+	f.mu.Unlock()
+	go func() {
+		_ = f.st.AppendLog(ctx, opts.ID, "building "+opts.Source.BaseImage+"\n")
+		if f.hold != nil {
+			<-f.hold
+		}
+		dir := filepath.Join(f.baseDir, opts.ID)
+		_ = os.MkdirAll(dir, 0o755)
+		out := filepath.Join(dir, "kairos.iso")
+		_ = os.WriteFile(out, []byte("iso-bytes"), 0o644)
+		sum, _ := checksums.HashFile(out)
+		sums, _ := checksums.Write(dir, []checksums.Entry{{Name: "kairos.iso", SHA256: sum}})
+		rec, _ := f.st.GetByID(ctx, opts.ID)
+		rec.ContainerImage = "kairos-" + opts.ID + ":latest"
+		rec.BaseImageDigest = "sha256:base"
+		rec.ContainerImageDigest = "sha256:image"
+		rec.Vulnerabilities = &store.VulnerabilitySummary{High: 1, Total: 1}
+		_ = f.st.Update(ctx, rec)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if status.Phase == builder.BuildBuilding {
+			status.Phase = builder.BuildReady
+			status.Artifacts = []string{out, sums}
+		}
+	}()
+	return status, nil
+}
+
+func (f *fakeLocalBuilder) Status(_ context.Context, id string) (*builder.BuildStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := *f.builds[id]
+	return &s, nil
+}
+
+func (f *fakeLocalBuilder) List(context.Context) ([]*builder.BuildStatus, error) { return nil, nil }
+
+func (f *fakeLocalBuilder) Cancel(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.builds[id].Phase = builder.BuildError
+	return nil
+}
+
+var _ = Describe("Agent", func() {
+	var (
+		ctx          context.Context
+		srv          *httptest.Server
+		admin        *client.Client
+		artifacts    *gormstore.ArtifactStoreAdapter
+		jobs         *gormstore.BuildJobStoreAdapter
+		artifactsDir string
+		signingKey   *ecdsa.PrivateKey
+		hold         chan struct{}
+		// leaseDuration and slowUpload are read when the server is built,
+		// so nested containers can set them in their own BeforeEach.
+		leaseDuration time.Duration
+		slowUpload    func()
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		hold = nil
+		leaseDuration = 0
+		slowUpload = nil
+	})
+
+	JustBeforeEach(func() {
+		s, err := gormstore.New(":memory:")
+		Expect(err).NotTo(HaveOccurred())
+		artifacts = &gormstore.ArtifactStoreAdapter{S: s}
+		jobs = &gormstore.BuildJobStoreAdapter{S: s}
+		workers := &gormstore.BuildWorkerStoreAdapter{S: s}
+
+		b, err := worker.New(worker.Config{Jobs: jobs, Workers: workers, Artifacts: artifacts, LeaseDuration: leaseDuration})
+		Expect(err).NotTo(HaveOccurred())
+
+		artifactsDir = GinkgoT().TempDir()
+		signingKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalPKCS8PrivateKey(signingKey)
+		Expect(err).NotTo(HaveOccurred())
+		keyPath := filepath.Join(GinkgoT().TempDir(), "cosign.key")
+		Expect(os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)).To(Succeed())
+
+		e := server.New(server.Config{
+			NodeStore:        &gormstore.NodeStoreAdapter{S: s},
+			CommandStore:     &gormstore.CommandStoreAdapter{S: s},
+			GroupStore:       &gormstore.GroupStoreAdapter{S: s},
+			ArtifactStore:    artifacts,
+			Builder:          b,
+			BuildWorkerStore: workers,
+			WorkerQueue:      b,
+			WorkerToken:      workerToken,
+			AdminPassword:    adminPassword,
+			RegToken:         "reg-token",
+			AuroraBootURL:    "http://localhost",
+			ArtifactsDir:     artifactsDir,
+			Hub:              ws.NewHub(),
+			// The key lives on the server only; the worker never sees it.
+			ManifestSigningKey: keyPath,
+		})
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slowUpload != nil && strings.HasSuffix(r.URL.Path, "/upload/kairos.iso") {
+				slowUpload()
+			}
+			e.ServeHTTP(w, r)
+		}))
+		DeferCleanup(srv.Close)
+		admin = client.New(srv.URL, client.WithAdminPassword(adminPassword))
+	})
+
+	newAgent := func(arch string) *worker.Agent {
+		return worker.NewAgent(worker.AgentConfig{
+			Client:            client.New(srv.URL),
+			RegistrationToken: workerToken,
+			Name:              "builder-" + arch,
+			Arch:              arch,
+			WorkDir:           GinkgoT().TempDir(),
+			HeartbeatInterval: 50 * time.Millisecond,
+			Log:               GinkgoWriter,
+			NewBuilder: func(baseDir string, st store.ArtifactStore) builder.ArtifactBuilder {
+				return &fakeLocalBuilder{baseDir: baseDir, st: st, hold: hold, builds: map[string]*builder.BuildStatus{}}
+			},
+		})
+	}
+
+	create := func(arch string) string {
+		a, err := admin.Artifacts.Create(ctx, client.CreateArtifactRequest{
+			Name:      "remote",
+			BaseImage: "quay.io/kairos/ubuntu:24.04",
+			Arch:      arch,
+			Outputs:   client.ArtifactOutputs{ISO: true},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return a.ID
+	}
+
+	It("runs a queued build and uploads its outputs to the server", func() {
+		id := create("amd64")
+		rec, err := artifacts.GetByID(ctx, id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rec.Phase).To(Equal(store.ArtifactPending))
+
+		ran, err := newAgent("amd64").RunOnce(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ran).To(BeTrue())
+
+		rec, err = artifacts.GetByID(ctx, id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rec.Phase).To(Equal(store.ArtifactReady))
+		Expect(rec.ContainerImage).To(Equal("kairos-" + id + ":latest"))
+		Expect(rec.BaseImageDigest).To(Equal("sha256:base"))
+		Expect(rec.ContainerImageDigest).To(Equal("sha256:image"))
+		Expect(rec.Vulnerabilities).To(Equal(&store.VulnerabilitySummary{High: 1, Total: 1}))
+		Expect(rec.ArtifactFiles).To(ConsistOf("kairos.iso", checksums.FileName, checksums.SignatureFileName))
+		Expect(rec.UploadToken).To(BeEmpty(), "the upload token must not outlive the build")
+
+		data, err := os.ReadFile(filepath.Join(artifactsDir, id, "kairos.iso"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("iso-bytes"))
+		Expect(checksums.VerifyDirSignature(filepath.Join(artifactsDir, id), signingKey.Public())).To(Succeed(),
+			"the server signs the SHA256SUMS the worker uploads")
+
+		logs, err := admin.Artifacts.Logs(ctx, id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logs).To(ContainSubstring("building quay.io/kairos/ubuntu:24.04"))
+		Expect(logs).To(ContainSubstring("Leased by worker builder-amd64"))
+
+		rc, err := admin.Artifacts.Download(ctx, id, "kairos.iso")
+		Expect(err).NotTo(HaveOccurred())
+		defer rc.Close()
+		body, _ := io.ReadAll(rc)
+		Expect(string(body)).To(Equal("iso-bytes"))
+
+		_, err = jobs.GetByID(ctx, id)
+		Expect(err).To(HaveOccurred(), "a finished build leaves the queue")
+	})
+
+	It("only hands builds to workers of the requested architecture", func() {
+		id := create("arm64")
+
+		ran, err := newAgent("amd64").RunOnce(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ran).To(BeFalse())
+
+		ran, err = newAgent("arm64").RunOnce(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ran).To(BeTrue())
+
+		rec, err := artifacts.GetByID(ctx, id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rec.Phase).To(Equal(store.ArtifactReady))
+	})
+
+	It("abandons a build that is cancelled while it runs", func() {
+		hold = make(chan struct{})
+		id := create("amd64")
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := newAgent("amd64").RunOnce(ctx)
+			done <- err
+		}()
+		Eventually(func() string {
+			rec, _ := artifacts.GetByID(ctx, id)
+			return rec.Phase
+		}).Should(Equal(store.ArtifactBuilding))
+
+		Expect(admin.Artifacts.Cancel(ctx, id)).To(Succeed())
+		Eventually(done, 5*time.Second).Should(Receive(BeNil()))
+		close(hold)
+
+		rec, err := artifacts.GetByID(ctx, id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rec.Phase).To(Equal(store.ArtifactError))
+		Expect(rec.Message).To(Equal("cancelled"))
+		Expect(rec.ArtifactFiles).To(BeEmpty())
+	})
+
+	Context("with a lease shorter than the upload", func() {
+		var requeued []string
+
+		BeforeEach(func() {
+			leaseDuration = 300 * time.Millisecond
+			requeued = nil
+			slowUpload = func() {
+				time.Sleep(3 * leaseDuration)
+				// Run the reaper as the server would mid-upload.
+				ids, _ := jobs.RequeueExpired(ctx, time.Now())
+				requeued = append(requeued, ids...)
+			}
+		})
+
+		It("keeps renewing the lease until the result is reported", func() {
+			id := create("amd64")
+
+			ran, err := newAgent("amd64").RunOnce(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ran).To(BeTrue())
+			Expect(requeued).To(BeEmpty(), "the lease must not lapse while the worker uploads")
+
+			rec, err := artifacts.GetByID(ctx, id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rec.Phase).To(Equal(store.ArtifactReady))
+			Expect(rec.ArtifactFiles).To(ContainElement("kairos.iso"))
+		})
+	})
+
+	It("lists registered workers for the admin", func() {
+		_, err := newAgent("arm64").RunOnce(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		list, err := admin.Workers.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Name).To(Equal("builder-arm64"))
+		Expect(list[0].Arch).To(Equal("arm64"))
+	})
+})
@@ -0,0 +1,406 @@
+// Package worker implements the remote build-worker backend: builds are queued
+// in the store and pulled by `auroraboot worker` processes running on other
+// hosts (typically one per architecture), which run the local AuroraBoot
+// pipeline and ship the results back through the per-build upload endpoint.
+//
+// The server half (Builder) is a builder.ArtifactBuilder plus the lease
+// protocol the worker REST endpoints call into. The worker half (Agent) lives
+// in agent.go and talks to the server only through pkg/client.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kairos-io/AuroraBoot/internal/secrets"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+// DefaultLeaseDuration is how long a leased build stays with a worker without
+// a heartbeat. Workers heartbeat well inside this window (see
+// defaultHeartbeatInterval), so only a worker that crashed, lost the network
+// or was killed lets it lapse; the build then goes back to the queue.
+const DefaultLeaseDuration = 2 * time.Minute
+
+// reapInterval is how often Run looks for lapsed leases. It bounds how late a
+// dead worker's build is re-queued past its deadline.
+const reapInterval = 15 * time.Second
+
+// ErrNotFound is returned by Status when the id is neither queued nor a
+// known artifact record.
+var ErrNotFound = errors.New("worker builder: build not found")
+
+// Config wires the worker backend to its stores.
+type Config struct {
+	Jobs      store.BuildJobStore
+	Workers   store.BuildWorkerStore
+	Artifacts store.ArtifactStore
+	// Cipher, when set, encrypts the queued BuildOptions at rest. The web
+	// server always wires the same data key it uses for BMC passwords; tests
+	// may leave it nil to store the spec as plain JSON.
+	Cipher *secrets.Cipher
+	// LeaseDuration overrides DefaultLeaseDuration. Zero means the default.
+	LeaseDuration time.Duration
+}
+
+// Builder queues builds for remote workers and serves the lease protocol.
+type Builder struct {
+	cfg            Config
+	logBroadcaster builder.LogBroadcaster
+	now            func() time.Time
+}
+
+// New validates cfg and returns a worker-backend builder.
+func New(cfg Config) (*Builder, error) {
+	if cfg.Jobs == nil || cfg.Workers == nil || cfg.Artifacts == nil {
+		return nil, errors.New("worker builder: Jobs, Workers and Artifacts stores are required")
+	}
+	if cfg.LeaseDuration <= 0 {
+		cfg.LeaseDuration = DefaultLeaseDuration
+	}
+	return &Builder{cfg: cfg, now: time.Now}, nil
+}
+
+// WithLogBroadcaster attaches a broadcaster that receives every log chunk a
+// worker streams back, so the UI live-log pane behaves the same as for local
+// builds. Returns the receiver so callers can chain.
+func (b *Builder) WithLogBroadcaster(lb builder.LogBroadcaster) *Builder {
+	b.logBroadcaster = lb
+	return b
+}
+
+// Build queues opts for the next idle worker of the requested architecture.
+// The artifact record itself is persisted by the Create handler (as for the
+// operator backend); the queue only holds the scheduling state and the sealed
+// options the worker replays.
+func (b *Builder) Build(ctx context.Context, opts builder.BuildOptions) (*builder.BuildStatus, error) {
+	if err := checkRemoteBuildable(opts); err != nil {
+		return nil, err
+	}
+	arch, err := normalizeArch(opts.Source.Arch)
+	if err != nil {
+		return nil, err
+	}
+
+	id := opts.ID
+	if id == "" {
+		id = uuid.NewString()
+		opts.ID = id
+	}
+
+	// The manifest key is a path on this host too, but unlike the keys
+	// above it is not needed to build: the Upload handler signs the
+	// SHA256SUMS the worker ships back.
+	opts.Signing.ManifestKey = ""
+
+	spec, err := b.sealSpec(opts)
+	if err != nil {
+		return nil, err
+	}
+	now := b.now()
+	if err := b.cfg.Jobs.Enqueue(ctx, &store.BuildJob{
+		ID:        id,
+		Arch:      arch,
+		Spec:      spec,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}); err != nil {
+		return nil, fmt.Errorf("queueing build %q: %w", id, err)
+	}
+	return &builder.BuildStatus{ID: id, Phase: builder.BuildPending}, nil
+}
+
+// checkRemoteBuildable rejects options that point at files on the AuroraBoot
+// host. A worker runs on another machine and only receives the serialized
+// options, so an overlay directory, a Dockerfile build context or SecureBoot
+// key paths would silently resolve to nothing (or to the wrong files) there.
+// Resuming is refused the other way round: the work of the failed run is on
+// the worker that ran it.
+func checkRemoteBuildable(opts builder.BuildOptions) error {
+	switch {
+	case opts.OverlayRootfs != "":
+		return fmt.Errorf("%w: rootfs overlays are not shipped to remote workers", builder.ErrNotSupported)
+	case opts.Resume:
+		return fmt.Errorf("%w: builds on remote workers cannot be resumed", builder.ErrNotSupported)
+	case opts.BuildContextDir != "":
+		return fmt.Errorf("%w: Dockerfile build contexts are not shipped to remote workers", builder.ErrNotSupported)
+	case opts.Signing.UKISecureBootKey != "", opts.Signing.UKISecureBootCert != "",
+		opts.Signing.UKITPMPCRKey != "", opts.Signing.UKIPublicKeysDir != "":
+		return fmt.Errorf("%w: SecureBoot signing keys are not shipped to remote workers", builder.ErrNotSupported)
+	}
+	return nil
+}
+
+// normalizeArch maps the request's architecture onto a queue. An empty arch
+// is amd64, matching what the local backend builds when no --platform is set.
+func normalizeArch(arch string) (string, error) {
+	switch arch {
+	case "":
+		return "amd64", nil
+	case "amd64", "arm64":
+		return arch, nil
+	default:
+		return "", fmt.Errorf("%w: source.arch must be 'amd64' or 'arm64', got %q", builder.ErrInvalidBuildOptions, arch)
+	}
+}
+
+func (b *Builder) sealSpec(opts builder.BuildOptions) (string, error) {
+	raw, err := json.Marshal(opts)
+	if err != nil {
+		return "", fmt.Errorf("encoding build spec: %w", err)
+	}
+	if b.cfg.Cipher == nil {
+		return string(raw), nil
+	}
+	sealed, err := b.cfg.Cipher.Encrypt(string(raw))
+	if err != nil {
+		return "", fmt.Errorf("encrypting build spec: %w", err)
+	}
+	return sealed, nil
+}
+
+func (b *Builder) openSpec(spec string) (builder.BuildOptions, error) {
+	var opts builder.BuildOptions
+	raw := spec
+	if b.cfg.Cipher != nil {
+		var err error
+		if raw, err = b.cfg.Cipher.Decrypt(spec); err != nil {
+			return opts, fmt.Errorf("decrypting build spec: %w", err)
+		}
+	}
+	if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+		return opts, fmt.Errorf("decoding build spec: %w", err)
+	}
+	return opts, nil
+}
+
+// Status reports a queued build from its job row and anything else from the
+// artifact record, which workers keep current through the lease protocol.
+func (b *Builder) Status(ctx context.Context, id string) (*builder.BuildStatus, error) {
+	if job, err := b.cfg.Jobs.GetByID(ctx, id); err == nil {
+		return statusFromJob(job), nil
+	}
+	rec, err := b.cfg.Artifacts.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	return &builder.BuildStatus{ID: rec.ID, Phase: rec.Phase, Message: rec.Message, Artifacts: rec.ArtifactFiles}, nil
+}
+
+// List returns every build still owned by the queue, leased or not.
+func (b *Builder) List(ctx context.Context) ([]*builder.BuildStatus, error) {
+	jobs, err := b.cfg.Jobs.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list build jobs: %w", err)
+	}
+	out := make([]*builder.BuildStatus, 0, len(jobs))
+	for _, job := range jobs {
+		out = append(out, statusFromJob(job))
+	}
+	return out, nil
+}
+
+func statusFromJob(job *store.BuildJob) *builder.BuildStatus {
+	if job.WorkerID != "" {
+		return &builder.BuildStatus{ID: job.ID, Phase: builder.BuildBuilding}
+	}
+	return &builder.BuildStatus{ID: job.ID, Phase: builder.BuildPending}
+}
+
+// Cancel drops the build from the queue. A worker currently running it learns
+// on its next heartbeat that the lease is gone and stops; its late log lines,
+// uploads and result are rejected because the job row and the upload token no
+// longer exist.
+func (b *Builder) Cancel(ctx context.Context, id string) error {
+	if err := b.cfg.Jobs.Delete(ctx, id); err != nil {
+		return fmt.Errorf("dequeue build %q: %w", id, err)
+	}
+	if err := b.cfg.Artifacts.UpdatePhaseMessage(ctx, id, builder.BuildError, "cancelled"); err != nil {
+		fmt.Fprintf(os.Stderr, "worker builder: cancel store update for %q failed: %v\n", id, err)
+	}
+	if err := b.cfg.Artifacts.ClearUploadToken(ctx, id); err != nil {
+		fmt.Fprintf(os.Stderr, "worker builder: clear upload token for %q failed: %v\n", id, err)
+	}
+	return nil
+}
+
+// Queued reports whether id is still owned by the build queue. runWeb uses it
+// to keep queued builds out of the startup orphan sweep: unlike a local build
+// goroutine, a queued or leased job survives a server restart.
+func (b *Builder) Queued(ctx context.Context, id string) bool {
+	_, err := b.cfg.Jobs.GetByID(ctx, id)
+	return err == nil
+}
+
+// Lease hands the oldest queued build for arch to workerID. It returns
+// (nil, nil) when nothing is queued. Every lease poll also counts as a
+// heartbeat, so an idle worker shows up as alive in the worker list.
+func (b *Builder) Lease(ctx context.Context, workerID, arch string) (*builder.WorkerLease, error) {
+	if err := b.cfg.Workers.Heartbeat(ctx, workerID, ""); err != nil {
+		return nil, fmt.Errorf("worker heartbeat: %w", err)
+	}
+	until := b.now().Add(b.cfg.LeaseDuration)
+	job, err := b.cfg.Jobs.LeaseNext(ctx, workerID, arch, until)
+	if err != nil {
+		return nil, fmt.Errorf("lease build: %w", err)
+	}
+	if job == nil {
+		return nil, nil
+	}
+
+	opts, err := b.openSpec(job.Spec)
+	if err != nil {
+		// The spec can never be replayed (e.g. the data key was rotated), so
+		// retrying on another worker is pointless. Fail the build instead of
+		// leaving it to bounce between workers forever.
+		_ = b.cfg.Jobs.Delete(ctx, job.ID)
+		_ = b.cfg.Artifacts.UpdatePhaseMessage(ctx, job.ID, builder.BuildError, "queued build spec is unreadable")
+		_ = b.cfg.Artifacts.ClearUploadToken(ctx, job.ID)
+		return nil, fmt.Errorf("build %q: %w", job.ID, err)
+	}
+
+	workerName := workerID
+	if w, err := b.cfg.Workers.GetByID(ctx, workerID); err == nil && w.Name != "" {
+		workerName = w.Name
+	}
+	_ = b.cfg.Workers.Heartbeat(ctx, workerID, job.ID)
+	_ = b.cfg.Artifacts.UpdatePhaseMessage(ctx, job.ID, builder.BuildBuilding, "building on worker "+workerName)
+	b.appendLog(job.ID, fmt.Sprintf("=== Leased by worker %s (%s, attempt %d) ===\n", workerName, job.Arch, job.Attempts))
+
+	return &builder.WorkerLease{BuildID: job.ID, Options: opts, LeaseExpiresAt: until}, nil
+}
+
+// Heartbeat records that workerID is alive and, when it reports a current
+// build, extends that build's lease. ErrLeaseLost tells the worker to abandon
+// the build.
+func (b *Builder) Heartbeat(ctx context.Context, workerID, buildID string) error {
+	if err := b.cfg.Workers.Heartbeat(ctx, workerID, buildID); err != nil {
+		return fmt.Errorf("worker heartbeat: %w", err)
+	}
+	if buildID == "" {
+		return nil
+	}
+	ok, err := b.cfg.Jobs.RenewLease(ctx, buildID, workerID, b.now().Add(b.cfg.LeaseDuration))
+	if err != nil {
+		return fmt.Errorf("renew lease: %w", err)
+	}
+	if !ok {
+		return builder.ErrLeaseLost
+	}
+	return nil
+}
+
+// AppendLog stores and broadcasts a log chunk streamed by the worker holding
+// buildID's lease. The worker already redacts LogRedactValues before sending,
+// as the local backend does before persisting.
+func (b *Builder) AppendLog(ctx context.Context, workerID, buildID, text string) error {
+	if err := b.checkHolder(ctx, workerID, buildID); err != nil {
+		return err
+	}
+	b.appendLog(buildID, text)
+	return nil
+}
+
+// Finish records the terminal result of a leased build and releases it from
+// the queue. On success the worker has already uploaded every output through
+// the upload endpoint, which populated the record's file list.
+func (b *Builder) Finish(ctx context.Context, workerID, buildID string, res builder.WorkerResult) error {
+	if err := b.checkHolder(ctx, workerID, buildID); err != nil {
+		return err
+	}
+	switch res.Phase {
+	case builder.BuildReady:
+		rec, err := b.cfg.Artifacts.GetByID(ctx, buildID)
+		if err != nil {
+			return fmt.Errorf("get artifact %q: %w", buildID, err)
+		}
+		rec.Phase = store.ArtifactReady
+		rec.Message = ""
+		rec.ContainerImage = res.ContainerImage
+		rec.BaseImageDigest = res.BaseImageDigest
+		rec.KairosInitImageDigest = res.KairosInitImageDigest
+		if rec.KairosInitImage == "" {
+			rec.KairosInitImage = res.KairosInitImage
+		}
+		rec.ContainerImageDigest = res.ContainerImageDigest
+		rec.Vulnerabilities = res.Vulnerabilities
+		if rec.Git != nil {
+			rec.Git.Commit = res.GitCommit
+		}
+		rec.UpdatedAt = b.now()
+		if err := b.cfg.Artifacts.Update(ctx, rec); err != nil {
+			return fmt.Errorf("update artifact %q: %w", buildID, err)
+		}
+	case builder.BuildError:
+		if err := b.cfg.Artifacts.UpdatePhaseMessage(ctx, buildID, store.ArtifactError, res.Message); err != nil {
+			return fmt.Errorf("update artifact %q: %w", buildID, err)
+		}
+	default:
+		return fmt.Errorf("%w: result phase must be %q or %q, got %q", builder.ErrInvalidBuildOptions, builder.BuildReady, builder.BuildError, res.Phase)
+	}
+
+	// Same reasoning as watchCRPhase in the operator backend: once the build
+	// is terminal a leaked upload token must not be able to overwrite it.
+	if err := b.cfg.Artifacts.ClearUploadToken(ctx, buildID); err != nil {
+		fmt.Fprintf(os.Stderr, "worker builder: clear upload token for %q failed: %v\n", buildID, err)
+	}
+	if err := b.cfg.Jobs.Delete(ctx, buildID); err != nil {
+		fmt.Fprintf(os.Stderr, "worker builder: dequeue finished build %q failed: %v\n", buildID, err)
+	}
+	_ = b.cfg.Workers.Heartbeat(ctx, workerID, "")
+	return nil
+}
+
+// checkHolder fails with ErrLeaseLost unless workerID currently holds the
+// lease on buildID.
+func (b *Builder) checkHolder(ctx context.Context, workerID, buildID string) error {
+	job, err := b.cfg.Jobs.GetByID(ctx, buildID)
+	if err != nil || job.WorkerID != workerID {
+		return builder.ErrLeaseLost
+	}
+	return nil
+}
+
+func (b *Builder) appendLog(id, text string) {
+	_ = b.cfg.Artifacts.AppendLog(context.Background(), id, text)
+	if b.logBroadcaster != nil {
+		b.logBroadcaster.BroadcastLogChunk(id, text)
+	}
+}
+
+// Run re-queues builds whose worker stopped heartbeating until ctx is
+// cancelled. The web server starts it once next to the HTTP listener.
+func (b *Builder) Run(ctx context.Context) {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.reapExpired(ctx)
+		}
+	}
+}
+
+// reapExpired hands every lapsed lease back to the queue and flips the record
+// back to Pending so the UI does not show a dead worker's build as running.
+func (b *Builder) reapExpired(ctx context.Context) {
+	ids, err := b.cfg.Jobs.RequeueExpired(ctx, b.now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "worker builder: requeue expired leases: %v\n", err)
+	}
+	for _, id := range ids {
+		_ = b.cfg.Artifacts.UpdatePhaseMessage(ctx, id, builder.BuildPending, "worker lease expired; waiting for another worker")
+		b.appendLog(id, "\n=== Worker lease expired; build re-queued ===\n")
+	}
+}
+
+var _ builder.ArtifactBuilder = (*Builder)(nil)
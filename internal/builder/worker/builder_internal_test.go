@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gormstore "github.com/kairos-io/AuroraBoot/internal/store/gorm"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+// TestReapExpiredRequeuesSilentWorkers drives the lease clock by hand: a
+// worker that stops heartbeating loses its build to the next worker, and its
+// own late heartbeat is told the lease is gone.
+func TestReapExpiredRequeuesSilentWorkers(t *testing.T) {
+	ctx := context.Background()
+	s, err := gormstore.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifacts := &gormstore.ArtifactStoreAdapter{S: s}
+	workers := &gormstore.BuildWorkerStoreAdapter{S: s}
+	b, err := New(Config{Jobs: &gormstore.BuildJobStoreAdapter{S: s}, Workers: workers, Artifacts: artifacts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	for _, w := range []*store.BuildWorker{
+		{ID: "w1", Name: "one", Arch: "amd64", APIKeyHash: "h1"},
+		{ID: "w2", Name: "two", Arch: "amd64", APIKeyHash: "h2"},
+	} {
+		if err := workers.Register(ctx, w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := artifacts.Create(ctx, &store.ArtifactRecord{ID: "a1", Phase: store.ArtifactPending}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Build(ctx, builder.BuildOptions{ID: "a1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lease, err := b.Lease(ctx, "w1", "amd64")
+	if err != nil || lease == nil {
+		t.Fatalf("lease by w1: %v, %v", lease, err)
+	}
+
+	now = now.Add(DefaultLeaseDuration + time.Second)
+	b.reapExpired(ctx)
+
+	rec, _ := artifacts.GetByID(ctx, "a1")
+	if rec.Phase != store.ArtifactPending {
+		t.Fatalf("phase after reap = %q, want Pending", rec.Phase)
+	}
+	if err := b.Heartbeat(ctx, "w1", "a1"); !errors.Is(err, builder.ErrLeaseLost) {
+		t.Fatalf("late heartbeat from w1 = %v, want ErrLeaseLost", err)
+	}
+
+	lease, err = b.Lease(ctx, "w2", "amd64")
+	if err != nil || lease == nil || lease.BuildID != "a1" {
+		t.Fatalf("lease by w2: %v, %v", lease, err)
+	}
+	if err := b.Finish(ctx, "w1", "a1", builder.WorkerResult{Phase: builder.BuildReady}); !errors.Is(err, builder.ErrLeaseLost) {
+		t.Fatalf("finish from the previous holder = %v, want ErrLeaseLost", err)
+	}
+}
@@ -0,0 +1,13 @@
+package worker_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkerBuilder(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Worker Builder Suite")
+}
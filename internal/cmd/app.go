@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 
@@ -10,6 +15,7 @@ import (
 	"github.com/kairos-io/AuroraBoot/internal"
 	"github.com/kairos-io/AuroraBoot/internal/config"
 	"github.com/kairos-io/kairos-sdk/types/logger"
+	"github.com/rs/zerolog"
 	"github.com/spectrocloud-labs/herd"
 	"github.com/urfave/cli/v2"
 )
@@ -30,8 +36,11 @@ func GetApp(version string) *cli.App {
 			&NetBootCmd,
 			&StartPixieCmd,
 			&WebCMD,
+			&WorkerCmd,
 			&RedFishDeployCmd,
 			&UnpackCmd,
+			&VerifyCmd,
+			&DiffCmd,
 		},
 		Flags: []cli.Flag{
 			&cli.StringSliceFlag{
@@ -43,6 +52,26 @@ func GetApp(version string) *cli.App {
 			&cli.BoolFlag{
 				Name: "debug",
 			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Print the steps the configuration runs and skips, with their inputs and outputs, without downloading or building anything",
+			},
+			&cli.StringFlag{
+				Name:  "dry-run-format",
+				Value: "text",
+				Usage: "Format of the --dry-run plan: 'text' or 'json'",
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Value: "text",
+				Usage: "Progress output: 'text' for the logs, or 'json' to print the step events as JSON lines on stdout, with the logs on stderr",
+			},
+			ResumeFlag,
+			&cli.StringFlag{
+				Name:    "board-profiles-dir",
+				Usage:   boardProfilesDirUsage,
+				EnvVars: []string{boardProfilesDirEnv},
+			},
 		},
 		Description: "Auroraboot is a tool that builds various Kairos artifacts suitable to run Kairos on Vms, bare metal, public cloud or single board computers (SBCs).\nIt also provides functionality like network booting to install Kairos. Read more in the docs: https://kairos.io/docs/reference/auroraboot/",
 		UsageText:   ``,
@@ -53,6 +82,15 @@ func GetApp(version string) *cli.App {
 			if ctx.Bool("debug") {
 				internal.Log.SetLevel("debug")
 			}
+			var onEvent func(deployer.Event)
+			switch ctx.String("output") {
+			case "text":
+			case "json":
+				internal.Log = stderrLogger(internal.Log)
+				onEvent = jsonEvents(ctx.App.Writer)
+			default:
+				return fmt.Errorf("--output must be 'text' or 'json', got %q", ctx.String("output"))
+			}
 			c, r, err := config.ReadConfig(ctx.Args().First(), ctx.String("cloud-config"), ctx.StringSlice("set"))
 			if err != nil {
 				return err
@@ -63,14 +101,31 @@ func GetApp(version string) *cli.App {
 
 			c.ISO.HandleDeprecations(internal.Log)
 
+			if err := loadBoardProfilesDir(ctx.String("board-profiles-dir")); err != nil {
+				return err
+			}
+
+			// Default the state dir before registering the steps, as some of them
+			// resolve their output paths as they are registered.
+			if c.State == "" {
+				c.State = "/tmp/auroraboot"
+			}
+			if ctx.Bool("resume") {
+				c.Resume = true
+			}
+
 			d := deployer.NewDeployer(*c, *r, herd.CollectOrphans)
+			d.OnEvent = onEvent
+			if ctx.Bool("dry-run") && !ctx.Bool("debug") {
+				d.Log = logger.NewNullLogger()
+			}
 			err = deployer.RegisterAll(d)
 			if err != nil {
 				return err
 			}
 
-			if d.Config.State == "" {
-				d.Config.State = "/tmp/auroraboot"
+			if ctx.Bool("dry-run") {
+				return writePlan(ctx.App.Writer, d.Plan(), ctx.String("dry-run-format"))
 			}
 
 			d.WriteDag()
@@ -79,8 +134,10 @@ func GetApp(version string) *cli.App {
 			}
 
 			err = d.CollectErrors()
-			errCleanup := d.CleanTmpDirs()
-			if errCleanup != nil {
+			if err != nil && d.Resumable() {
+				// Keep the unpacked rootfs and netboot files for --resume
+				internal.Log.Logger.Info().Str("state_dir", c.StateDir()).Msg("Keeping the work of the completed steps: rerun with --resume to continue from the failed step")
+			} else if errCleanup := d.CleanTmpDirs(); errCleanup != nil {
 				// Append the cleanup error to the main errors if any
 				err = multierror.Append(err, errCleanup)
 			}
@@ -95,6 +152,47 @@ func GetApp(version string) *cli.App {
 	}
 }
 
+// writePlan writes a deployer plan in the --dry-run-format.
+func writePlan(w io.Writer, plan deployer.Plan, format string) error {
+	switch format {
+	case "text":
+		return plan.WriteText(w)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	return fmt.Errorf("--dry-run-format must be 'text' or 'json', got %q", format)
+}
+
+// jsonEvents returns a deployer event handler writing each event to w as a
+// line of JSON.
+func jsonEvents(w io.Writer) func(deployer.Event) {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(e deployer.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(e)
+	}
+}
+
+// stderrLogger returns a logger at the level of base writing to stderr, so
+// that stdout only carries the --output json events.
+func stderrLogger(base logger.KairosLogger) logger.KairosLogger {
+	l := zerolog.New(zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) {
+		w.Out = os.Stderr
+		w.TimeFormat = time.RFC3339
+		w.FieldsExclude = []string{"SYSLOG_IDENTIFIER"}
+	})).
+		With().
+		Str("SYSLOG_IDENTIFIER", "kairos-aurora").
+		Timestamp().
+		Logger().
+		Level(base.Logger.GetLevel())
+	return logger.KairosLogger{Logger: l}
+}
+
 // CheckRoot is a helper which can add it to commands that require root
 func CheckRoot() error {
 	if os.Geteuid() != 0 {
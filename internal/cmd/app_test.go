@@ -2,7 +2,10 @@ package cmd_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"os"
 
+	"github.com/kairos-io/AuroraBoot/deployer"
 	cmdpkg "github.com/kairos-io/AuroraBoot/internal/cmd"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -26,4 +29,46 @@ var _ = Describe("root command", Label("cmd"), func() {
 
 		Expect(err).To(MatchError("no source defined: provide container_image or a complete release artifact configuration"))
 	})
+
+	It("prints the plan of a --dry-run as JSON without running it", func() {
+		app := cmdpkg.GetApp("v0.0.0")
+		out := new(bytes.Buffer)
+		app.Writer = out
+		state := GinkgoT().TempDir()
+
+		err := app.Run([]string{"auroraboot", "--dry-run", "--dry-run-format", "json",
+			"--set", "container_image=docker:alpine", "--set", "disk.efi=true", "--set", "state_dir=" + state})
+		Expect(err).ToNot(HaveOccurred())
+
+		var plan deployer.Plan
+		Expect(json.Unmarshal(out.Bytes(), &plan)).To(Succeed())
+		run := map[string]bool{}
+		for _, step := range plan.Steps {
+			run[step.Name] = step.Run
+		}
+		Expect(run).To(HaveKeyWithValue("gen-raw-efi-disk", true))
+		Expect(run).To(HaveKeyWithValue("gen-iso", false))
+		Expect(state).To(BeAnExistingFile())
+		entries, err := os.ReadDir(state)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("rejects an unknown --dry-run-format", func() {
+		app := cmdpkg.GetApp("v0.0.0")
+		app.Writer = new(bytes.Buffer)
+
+		err := app.Run([]string{"auroraboot", "--dry-run", "--dry-run-format", "yaml", "--set", "container_image=docker:alpine"})
+
+		Expect(err).To(MatchError(`--dry-run-format must be 'text' or 'json', got "yaml"`))
+	})
+
+	It("rejects an unknown --output", func() {
+		app := cmdpkg.GetApp("v0.0.0")
+		app.Writer = new(bytes.Buffer)
+
+		err := app.Run([]string{"auroraboot", "--output", "yaml", "--set", "container_image=docker:alpine"})
+
+		Expect(err).To(MatchError(`--output must be 'text' or 'json', got "yaml"`))
+	})
 })
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kairos-io/AuroraBoot/pkg/boards"
+)
+
+// boardProfilesDirEnv is the environment variable consulted for the operator
+// board-profile directory when --board-profiles-dir is not set.
+const boardProfilesDirEnv = "AURORABOOT_BOARD_PROFILES_DIR"
+
+// boardProfilesDirUsage documents --board-profiles-dir for every command that
+// builds raw disk images.
+const boardProfilesDirUsage = "Directory of operator-supplied *.yaml/*.yml board profiles for raw disk images, loaded once at start. A profile named the same as a built-in overrides it (logged). A malformed profile is skipped, not fatal"
+
+// loadBoardProfilesDir loads operator-supplied board profiles from dir (if any)
+// and installs the resulting registry as the process-wide default the raw disk
+// builder looks the board of a build up in. A bad profile disables only itself;
+// only a directory-read failure is fatal. An empty dir is a no-op.
+func loadBoardProfilesDir(dir string) error {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		dir = os.Getenv(boardProfilesDirEnv)
+	}
+	if strings.TrimSpace(dir) == "" {
+		return nil
+	}
+	registry, _, err := boards.LoadProfileDir(dir)
+	if err != nil {
+		return fmt.Errorf("loading board profiles: %w", err)
+	}
+	boards.SetDefaultRegistry(registry)
+	return nil
+}
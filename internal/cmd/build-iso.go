@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/kairos-io/AuroraBoot/internal"
@@ -68,11 +69,20 @@ var BuildISOCmd = cli.Command{
 			Name:  "live-console",
 			Usage: "Replace the console options used when booting from the live/installer ISO",
 		},
+		&cli.GenericFlag{
+			Name:  "extra-image",
+			Value: &isoImagesFlag{},
+			Usage: "Add an image to the ISO with its own boot menu entries, as 'image;name=<title>;cloud-config=<file>;cmdline=<options>' (everything but the image is optional). Repeat for more images.",
+		},
 		AllowInsecureRegistriesFlag,
+		ResumeFlag,
 	},
-	ArgsUsage: "<source>",
+	ArgsUsage: "<source> [<source>...]",
 	Action: func(ctx *cli.Context) error {
 		internal.Log = logger.NewKairosLogger("aurora", ctx.String("loglevel"), false)
+		imagesFlag := ctx.Generic("extra-image").(*isoImagesFlag)
+		// The flag value is shared by every run of the command
+		defer imagesFlag.reset()
 		source := ctx.Args().Get(0)
 		if source == "" {
 			// Hack to prevent ShowAppHelpAndExit from checking only subcommands.
@@ -123,6 +133,21 @@ var BuildISOCmd = cli.Command{
 			LiveConsole:       ctx.String("live-console"),
 		}
 
+		// The sources after the first one are more images on the ISO, with
+		// the defaults of --extra-image
+		for _, extra := range ctx.Args().Tail() {
+			isoOptions.Images = append(isoOptions.Images, schema.ISOImage{ContainerImage: extra})
+		}
+		for _, img := range imagesFlag.images {
+			if img.CloudConfig != "" {
+				img.CloudConfig, err = config.ReadCloudConfig(img.CloudConfig, map[string]interface{}{})
+				if err != nil {
+					return fmt.Errorf("reading cloud config of %s: %w", img.ContainerImage, err)
+				}
+			}
+			isoOptions.Images = append(isoOptions.Images, img)
+		}
+
 		if err := validateISOOptions(isoOptions); err != nil {
 			return err
 		}
@@ -134,6 +159,7 @@ var BuildISOCmd = cli.Command{
 			CloudConfig:             cloudConfig,
 			Arch:                    ctx.String("arch"),
 			AllowInsecureRegistries: &allowInsecure,
+			Resume:                  ctx.Bool("resume"),
 		}
 
 		if c.State == "" {
@@ -145,6 +171,7 @@ var BuildISOCmd = cli.Command{
 			d.PrepDirs,
 			d.StepCopyCloudConfig,
 			d.StepDumpSource,
+			d.StepDumpISOImages,
 			d.StepGenISO,
 		} {
 			if err := step(); err != nil {
@@ -157,9 +184,10 @@ var BuildISOCmd = cli.Command{
 		}
 
 		err = d.CollectErrors()
-
-		errCleanup := d.CleanTmpDirs()
-		if errCleanup != nil {
+		if err != nil && d.Resumable() {
+			// Keep the unpacked rootfs for --resume
+			internal.Log.Logger.Info().Str("output", c.StateDir()).Msg("Keeping the work of the completed steps: rerun with --resume to continue from the failed step")
+		} else if errCleanup := d.CleanTmpDirs(); errCleanup != nil {
 			// Append the cleanup error to the main errors if any
 			err = multierror.Append(err, errCleanup)
 		}
@@ -169,6 +197,9 @@ var BuildISOCmd = cli.Command{
 }
 
 func validateISOOptions(i schema.ISO) error {
+	if err := (schema.Config{ISO: i}).Validate(); err != nil {
+		return err
+	}
 	for _, path := range []string{i.OverlayISO, i.OverlayRootfs} {
 		if path == "" {
 			continue
@@ -185,3 +216,49 @@ func validateISOOptions(i schema.ISO) error {
 
 	return nil
 }
+
+// isoImagesFlag collects the --extra-image flags. Unlike a StringSliceFlag it
+// does not split values on commas, which kernel cmdlines use.
+type isoImagesFlag struct {
+	images []schema.ISOImage
+}
+
+// Set parses 'image;name=<title>;cloud-config=<file>;cmdline=<options>'. The
+// cloud config is kept as a path until the command reads it.
+func (f *isoImagesFlag) Set(value string) error {
+	fields := strings.Split(value, ";")
+	img := schema.ISOImage{ContainerImage: strings.TrimSpace(fields[0])}
+	if img.ContainerImage == "" {
+		return fmt.Errorf("no image in %q", value)
+	}
+	for _, field := range fields[1:] {
+		key, val, ok := strings.Cut(field, "=")
+		if !ok {
+			return fmt.Errorf("invalid option %q of image %s: expected key=value", field, img.ContainerImage)
+		}
+		switch strings.TrimSpace(key) {
+		case "name":
+			img.Name = strings.TrimSpace(val)
+		case "cloud-config":
+			img.CloudConfig = strings.TrimSpace(val)
+		case "cmdline":
+			img.ExtendLiveCmdline = strings.TrimSpace(val)
+		default:
+			return fmt.Errorf("unknown option %q of image %s: must be name, cloud-config or cmdline", key, img.ContainerImage)
+		}
+	}
+	f.images = append(f.images, img)
+	return nil
+}
+
+func (f *isoImagesFlag) reset() {
+	f.images = nil
+}
+
+func (f *isoImagesFlag) String() string {
+	var images []string
+	for _, img := range f.images {
+		images = append(images, img.ContainerImage)
+	}
+	return strings.Join(images, " ")
+}
@@ -109,4 +109,29 @@ var _ = Describe("build-iso", Label("iso", "cmd"), func() {
 		Expect(err).ToNot(BeNil())
 		Expect(err.Error()).ToNot(ContainSubstring("flag provided but not defined"))
 	})
+
+	It("Accepts more sources and images with options", Label("flags"), func() {
+		err = app.Run([]string{"", "build-iso",
+			"--extra-image", "/no/standard/reference;name=Standard;cmdline=console=ttyS0,115200",
+			"/no/image/reference", "/no/other/reference"})
+		// The images are accepted and fail on their references, before
+		// anything is pulled
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("invalid image reference"))
+	})
+
+	It("Errors out on an unknown image option", Label("flags"), func() {
+		err = app.Run([]string{"", "build-iso", "--extra-image", "some/standard:latest;label=Standard", "some/core:latest"})
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("unknown option \"label\""))
+	})
+
+	It("Errors out if two images get the same directory on the ISO", Label("flags"), func() {
+		err = app.Run([]string{"", "build-iso",
+			"--extra-image", "some/standard:latest;name=Ubuntu Standard",
+			"--extra-image", "other/standard:latest;name=ubuntu-standard",
+			"some/core:latest"})
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("is too close to"))
+	})
 })
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kairos-io/AuroraBoot/pkg/artifactdiff"
+	"github.com/kairos-io/AuroraBoot/pkg/client"
+	"github.com/urfave/cli/v2"
+)
+
+var DiffCmd = cli.Command{
+	Name:  "diff",
+	Usage: "Compare two builds: their inputs, packages, kernel, initrd and rootfs",
+	Description: `Diff shows what changed from build A to build B: the build inputs that
+differ (base image and digest, Dockerfile, cloud-config, Hadron layers, Git
+commit), and from the outputs the packages added, removed or upgraded
+according to the SBOMs, the kernel version, and the files added, removed or
+changed in the initrd and the rootfs squashfs.
+
+The arguments are either two artifact IDs, compared by the AuroraBoot server
+at --url, or two downloaded build directories, compared locally. A local
+comparison only knows the inputs the build manifest records.
+
+Examples:
+  # Compare two builds on the server
+  auroraboot diff --url https://auroraboot.lan --password secret 3f2a 9c1d
+
+  # Compare two downloaded builds, as JSON
+  auroraboot diff --json ./build-41 ./build-42
+`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "url", Usage: "AuroraBoot server to compare artifact IDs on", EnvVars: []string{"AURORABOOT_URL"}},
+		&cli.StringFlag{Name: "password", Usage: "Admin password of the server", EnvVars: []string{"AURORABOOT_ADMIN_PASSWORD"}},
+		&cli.BoolFlag{Name: "json", Usage: "Print the structured diff as JSON"},
+	},
+	ArgsUsage: "<a> <b>",
+	Action: func(ctx *cli.Context) error {
+		if ctx.NArg() != 2 {
+			cli.ShowCommandHelp(ctx, ctx.Command.Name)
+			fmt.Println("")
+			return errors.New("requires two arguments: artifact IDs or build directories")
+		}
+		a, b := ctx.Args().Get(0), ctx.Args().Get(1)
+
+		var d *artifactdiff.Diff
+		if isDir(a) && isDir(b) {
+			d = artifactdiff.Compare(
+				artifactdiff.Load(a, "", artifactdiff.Inputs{}),
+				artifactdiff.Load(b, "", artifactdiff.Inputs{}),
+			)
+		} else {
+			url := ctx.String("url")
+			if url == "" {
+				return errors.New("comparing artifact IDs needs --url (or pass two build directories)")
+			}
+			var err error
+			d, err = client.New(url, client.WithAdminPassword(ctx.String("password"))).Artifacts.Diff(ctx.Context, a, b)
+			if err != nil {
+				return err
+			}
+		}
+
+		if ctx.Bool("json") {
+			enc := json.NewEncoder(ctx.App.Writer)
+			enc.SetIndent("", "  ")
+			return enc.Encode(d)
+		}
+		return d.WriteText(ctx.App.Writer)
+	},
+}
+
+func isDir(p string) bool {
+	st, err := os.Stat(p)
+	return err == nil && st.IsDir()
+}
@@ -0,0 +1,54 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	cmdpkg "github.com/kairos-io/AuroraBoot/internal/cmd"
+	"github.com/kairos-io/AuroraBoot/pkg/artifactdiff"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("diff command", Label("cmd"), func() {
+	var out *bytes.Buffer
+
+	run := func(args ...string) error {
+		app := cmdpkg.GetApp("v0.0.0")
+		out = new(bytes.Buffer)
+		app.Writer = out
+		return app.Run(append([]string{"auroraboot", "diff"}, args...))
+	}
+
+	build := func(id, digest string) string {
+		dir := filepath.Join(GinkgoT().TempDir(), id)
+		Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+		m := builder.BuildManifest{ArtifactID: id}
+		m.Inputs.BaseImage = "ubuntu:24.04"
+		m.Inputs.BaseImageDigest = digest
+		data, err := json.Marshal(m)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, builder.ManifestFileName), data, 0o644)).To(Succeed())
+		return dir
+	}
+
+	It("compares two build directories", func() {
+		Expect(run(build("a", "sha256:aaa"), build("b", "sha256:bbb"))).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("baseImageDigest: sha256:aaa -> sha256:bbb"))
+	})
+
+	It("prints JSON with --json", func() {
+		Expect(run("--json", build("a", "sha256:aaa"), build("b", "sha256:aaa"))).To(Succeed())
+		var d artifactdiff.Diff
+		Expect(json.Unmarshal(out.Bytes(), &d)).To(Succeed())
+		Expect(d.A).To(Equal("a"))
+		Expect(d.Inputs).To(BeEmpty())
+	})
+
+	It("needs --url for artifact IDs", func() {
+		Expect(run("3f2a", "9c1d")).To(MatchError(ContainSubstring("--url")))
+	})
+})
@@ -17,3 +17,10 @@ var AllowInsecureRegistriesFlag = &cli.BoolFlag{
 	Aliases: []string{"insecure"},
 	Usage:   "Allow pulling container images from registries over plain HTTP or with untrusted/self-signed TLS certificates. The --insecure alias is deprecated; use --allow-insecure-registries instead",
 }
+
+// ResumeFlag is shared by the commands that run the deployer, so that a
+// failed build can be continued from the step it failed at.
+var ResumeFlag = &cli.BoolFlag{
+	Name:  "resume",
+	Usage: "Skip the steps a previous run with the same state dir completed, when their inputs are unchanged and their outputs are still there",
+}
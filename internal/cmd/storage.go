@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
+	"github.com/urfave/cli/v2"
+)
+
+// storageFlags configure where finished build outputs are kept for download.
+var storageFlags = []cli.Flag{
+	&cli.StringFlag{Name: "storage", Value: "local", Usage: "Where finished build outputs are kept for download: 'local' (--artifacts-dir) or 's3' (an S3-compatible bucket; downloads redirect to presigned URLs). Outputs are still written to --artifacts-dir, which netboot and the Redfish ISO-serve read from", EnvVars: []string{"AURORABOOT_STORAGE"}},
+	&cli.StringFlag{Name: "s3-endpoint", Usage: "Base URL of the S3-compatible service, e.g. https://minio.lan:9000. Used only when --storage=s3", EnvVars: []string{"AURORABOOT_S3_ENDPOINT"}},
+	&cli.StringFlag{Name: "s3-region", Value: "us-east-1", Usage: "Region the bucket lives in. Used only when --storage=s3", EnvVars: []string{"AURORABOOT_S3_REGION"}},
+	&cli.StringFlag{Name: "s3-bucket", Usage: "Bucket build outputs are stored in. Used only when --storage=s3", EnvVars: []string{"AURORABOOT_S3_BUCKET"}},
+	&cli.StringFlag{Name: "s3-prefix", Usage: "Key prefix for build outputs, to share a bucket. Used only when --storage=s3", EnvVars: []string{"AURORABOOT_S3_PREFIX"}},
+	&cli.StringFlag{Name: "s3-access-key", Usage: "S3 access key. Used only when --storage=s3", EnvVars: []string{"AURORABOOT_S3_ACCESS_KEY", "AWS_ACCESS_KEY_ID"}},
+	&cli.StringFlag{Name: "s3-secret-key", Usage: "S3 secret key. Used only when --storage=s3", EnvVars: []string{"AURORABOOT_S3_SECRET_KEY", "AWS_SECRET_ACCESS_KEY"}},
+	&cli.BoolFlag{Name: "s3-path-style", Value: true, Usage: "Address the bucket as <endpoint>/<bucket> (MinIO and most self-hosted services) rather than <bucket>.<endpoint>. Used only when --storage=s3", EnvVars: []string{"AURORABOOT_S3_PATH_STYLE"}},
+	&cli.DurationFlag{Name: "s3-presign-ttl", Value: storage.DefaultPresignTTL, Usage: "How long a download URL handed out by the server stays valid. Used only when --storage=s3", EnvVars: []string{"AURORABOOT_S3_PRESIGN_TTL"}},
+}
+
+// s3ConfigFromFlags collects the --s3-* flags.
+func s3ConfigFromFlags(c *cli.Context) storage.S3Config {
+	return storage.S3Config{
+		Endpoint:   c.String("s3-endpoint"),
+		Region:     c.String("s3-region"),
+		Bucket:     c.String("s3-bucket"),
+		Prefix:     c.String("s3-prefix"),
+		AccessKey:  c.String("s3-access-key"),
+		SecretKey:  c.String("s3-secret-key"),
+		PathStyle:  c.Bool("s3-path-style"),
+		PresignTTL: c.Duration("s3-presign-ttl"),
+	}
+}
+
+// NewArtifactStorage returns the --storage backend. An S3 bucket is checked
+// up front, so wrong credentials or a missing bucket fail the server start
+// instead of the first build that finishes.
+func NewArtifactStorage(ctx context.Context, kind, artifactsDir string, s3 storage.S3Config) (storage.Backend, error) {
+	switch kind {
+	case "", "local":
+		return storage.NewLocal(artifactsDir), nil
+	case "s3":
+		b, err := storage.NewS3(s3)
+		if err != nil {
+			return nil, err
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		if err := b.Check(checkCtx); err != nil {
+			return nil, fmt.Errorf("s3 bucket %q: %w", s3.Bucket, err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("--storage must be 'local' or 's3', got %q", kind)
+}
@@ -0,0 +1,46 @@
+package cmd_test
+
+import (
+	"context"
+
+	cmdpkg "github.com/kairos-io/AuroraBoot/internal/cmd"
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
+	"github.com/kairos-io/AuroraBoot/pkg/storage/s3fake"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewArtifactStorage", Label("cmd", "storage"), func() {
+	var fake *s3fake.Server
+
+	BeforeEach(func() {
+		fake = s3fake.New("artifacts", "minio")
+		DeferCleanup(fake.Close)
+	})
+
+	s3Config := func(bucket string) storage.S3Config {
+		return storage.S3Config{Endpoint: fake.URL, Bucket: bucket, AccessKey: "minio", SecretKey: "minio123", PathStyle: true}
+	}
+
+	It("defaults to the artifacts directory", func() {
+		b, err := cmdpkg.NewArtifactStorage(context.Background(), "local", GinkgoT().TempDir(), storage.S3Config{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(BeAssignableToTypeOf(&storage.Local{}))
+	})
+
+	It("returns an S3 backend once the bucket answers", func() {
+		b, err := cmdpkg.NewArtifactStorage(context.Background(), "s3", "", s3Config("artifacts"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(BeAssignableToTypeOf(&storage.S3{}))
+	})
+
+	It("fails the start when the bucket is missing", func() {
+		_, err := cmdpkg.NewArtifactStorage(context.Background(), "s3", "", s3Config("nope"))
+		Expect(err).To(MatchError(ContainSubstring("NoSuchBucket")))
+	})
+
+	It("rejects an unknown kind", func() {
+		_, err := cmdpkg.NewArtifactStorage(context.Background(), "gcs", "", storage.S3Config{})
+		Expect(err).To(MatchError(ContainSubstring("--storage")))
+	})
+})
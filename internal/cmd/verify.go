@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/urfave/cli/v2"
+)
+
+var VerifyCmd = cli.Command{
+	Name:  "verify",
+	Usage: "Verify the outputs of a build against its SHA256SUMS and signature",
+	Description: `Verify checks every file listed in the SHA256SUMS manifest of a downloaded
+build against its checksum and, with --key, the SHA256SUMS.sig signature
+against the given public key. The key is the public half of the server's
+--manifest-signing-key (e.g. cosign.pub), or the db.pem certificate of the
+SecureBoot key set the build was signed with.
+
+Examples:
+  # Check the checksums only
+  auroraboot verify ./build-42
+
+  # Check the checksums and the signature
+  auroraboot verify --key cosign.pub ./build-42
+`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "key",
+			Usage: "PEM public key or certificate to verify SHA256SUMS.sig with",
+		},
+	},
+	ArgsUsage: "<artifact-dir>",
+	Action: func(ctx *cli.Context) error {
+		if ctx.NArg() != 1 {
+			cli.ShowCommandHelp(ctx, ctx.Command.Name)
+			fmt.Println("")
+			return errors.New("requires an <artifact-dir> argument")
+		}
+		dir := ctx.Args().Get(0)
+		out := ctx.App.Writer
+
+		// Check the signature first: if the manifest itself is not
+		// authentic, the file checksums it lists prove nothing.
+		sigPath := filepath.Join(dir, checksums.SignatureFileName)
+		if keyPath := ctx.String("key"); keyPath != "" {
+			keyPEM, err := os.ReadFile(keyPath)
+			if err != nil {
+				return fmt.Errorf("reading key: %w", err)
+			}
+			pub, err := checksums.LoadPublicKey(keyPEM)
+			if err != nil {
+				return err
+			}
+			if err := checksums.VerifyDirSignature(dir, pub); err != nil {
+				return fmt.Errorf("%s: %w", checksums.SignatureFileName, err)
+			}
+			fmt.Fprintf(out, "%s: signature OK\n", checksums.FileName)
+		} else if _, err := os.Stat(sigPath); err == nil {
+			fmt.Fprintf(out, "%s: signature present but not checked (pass --key)\n", checksums.FileName)
+		}
+
+		results, err := checksums.VerifyDir(dir)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", checksums.FileName, err)
+		}
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+				fmt.Fprintf(out, "%s: FAILED (%v)\n", r.Name, r.Err)
+				continue
+			}
+			fmt.Fprintf(out, "%s: OK\n", r.Name)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d files failed verification", failed, len(results))
+		}
+		return nil
+	},
+}
@@ -0,0 +1,82 @@
+package cmd_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	cmdpkg "github.com/kairos-io/AuroraBoot/internal/cmd"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("verify command", Label("cmd"), func() {
+	var (
+		dir    string
+		pubKey string
+		out    *bytes.Buffer
+	)
+
+	run := func(args ...string) error {
+		app := cmdpkg.GetApp("v0.0.0")
+		out = new(bytes.Buffer)
+		app.Writer = out
+		return app.Run(append([]string{"auroraboot", "verify"}, args...))
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		iso := filepath.Join(dir, "kairos.iso")
+		Expect(os.WriteFile(iso, []byte("iso-bytes"), 0o644)).To(Succeed())
+		sum, err := checksums.HashFile(iso)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = checksums.Write(dir, []checksums.Entry{{Name: "kairos.iso", SHA256: sum}})
+		Expect(err).NotTo(HaveOccurred())
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		keys := GinkgoT().TempDir()
+		privKey := filepath.Join(keys, "cosign.key")
+		Expect(os.WriteFile(privKey, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)).To(Succeed())
+		pubDER, err := x509.MarshalPKIXPublicKey(key.Public())
+		Expect(err).NotTo(HaveOccurred())
+		pubKey = filepath.Join(keys, "cosign.pub")
+		Expect(os.WriteFile(pubKey, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644)).To(Succeed())
+		_, err = checksums.SignDir(dir, privKey)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("passes a signed, untouched build", func() {
+		Expect(run("--key", pubKey, dir)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("signature OK"))
+		Expect(out.String()).To(ContainSubstring("kairos.iso: OK"))
+	})
+
+	It("notes an unchecked signature when no key is given", func() {
+		Expect(run(dir)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("not checked"))
+	})
+
+	It("fails when a file was modified", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "kairos.iso"), []byte("tampered"), 0o644)).To(Succeed())
+		Expect(run("--key", pubKey, dir)).To(MatchError("1 of 1 files failed verification"))
+		Expect(out.String()).To(ContainSubstring("kairos.iso: FAILED"))
+	})
+
+	It("fails when the manifest was rewritten", func() {
+		Expect(os.WriteFile(filepath.Join(dir, checksums.FileName), []byte(""), 0o644)).To(Succeed())
+		Expect(run("--key", pubKey, dir)).To(MatchError(ContainSubstring("invalid signature")))
+	})
+
+	It("fails when there is no manifest", func() {
+		Expect(run(GinkgoT().TempDir())).To(MatchError(ContainSubstring(checksums.FileName)))
+	})
+})
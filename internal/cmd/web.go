@@ -5,10 +5,11 @@
 //	@version		0.1.0
 //	@description	HTTP API for the AuroraBoot Kairos node manager.
 //	@description
-//	@description	Three security schemes are used across the API:
+//	@description	Four security schemes are used across the API:
 //	@description	  * AdminBearer — Authorization: Bearer <admin-password>. Human/UI/automation callers.
 //	@description	  * NodeAPIKey — Authorization: Bearer <api-key>. Registered Kairos nodes calling their own endpoints. The api-key is returned from POST /api/v1/nodes/register.
 //	@description	  * RegistrationToken — shared secret carried inside the body of POST /api/v1/nodes/register.
+//	@description	  * WorkerAPIKey — Authorization: Bearer <api-key>. Remote build workers (`auroraboot worker`) calling the lease protocol. The api-key is returned from POST /api/v1/workers/register.
 //	@description
 //	@description	Two WebSocket endpoints also exist but are not described in this spec:
 //	@description	  * GET /api/v1/ws — agent command channel (auth via ?token=<api-key>).
@@ -31,6 +32,11 @@
 //	@name						Authorization
 //	@description				Supply as "Bearer <api-key>" returned from POST /api/v1/nodes/register.
 //
+//	@securityDefinitions.apikey	WorkerAPIKey
+//	@in							header
+//	@name						Authorization
+//	@description				Supply as "Bearer <api-key>" returned from POST /api/v1/workers/register.
+//
 //	@tag.name		Health
 //	@tag.description	Liveness and readiness probes.
 //	@tag.name		Agent bootstrap
@@ -45,6 +51,8 @@
 //	@tag.description	Queued remote operations (upgrade, reset, exec...).
 //	@tag.name		Artifacts
 //	@tag.description	Image builds produced by the embedded AuroraBoot.
+//	@tag.name		Workers
+//	@tag.description	Remote build workers and the build lease protocol (--builder=worker).
 //	@tag.name		SecureBoot
 //	@tag.description	UKI signing key sets.
 //	@tag.name		Settings
@@ -66,13 +74,16 @@ import (
 	_ "github.com/kairos-io/AuroraBoot/docs" // swag-generated; populates docs.SwaggerInfo
 	"github.com/kairos-io/AuroraBoot/internal/builder/auroraboot"
 	"github.com/kairos-io/AuroraBoot/internal/builder/operator"
+	"github.com/kairos-io/AuroraBoot/internal/builder/worker"
 	netbootmgr "github.com/kairos-io/AuroraBoot/internal/netbootmgr"
 	"github.com/kairos-io/AuroraBoot/internal/secrets"
 	gormstore "github.com/kairos-io/AuroraBoot/internal/store/gorm"
 	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
 	"github.com/kairos-io/AuroraBoot/pkg/handlers"
 	"github.com/kairos-io/AuroraBoot/pkg/isoserve"
 	"github.com/kairos-io/AuroraBoot/pkg/server"
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
 	"github.com/kairos-io/AuroraBoot/pkg/ws"
 
 	"github.com/labstack/echo/v4"
@@ -87,7 +98,7 @@ var WebCMD = cli.Command{
 	Name:    "web",
 	Aliases: []string{"w"},
 	Usage:   "Run the fleet management web UI and REST API",
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		&cli.StringFlag{Name: "listen", Value: ":8080", Usage: "HTTP listen address"},
 		&cli.StringFlag{Name: "data-dir", Value: "./data", Usage: "Base directory for all AuroraBoot data (db, artifacts, keys)"},
 		&cli.StringFlag{Name: "db", Usage: "Database DSN (default: <data-dir>/auroraboot.db)"},
@@ -102,20 +113,25 @@ var WebCMD = cli.Command{
 		&cli.StringFlag{Name: "redfish-serve-tls-cert", Usage: "TLS certificate for the Redfish ISO-serve (opt-in HTTPS; requires a BMC-trusted cert)"},
 		&cli.StringFlag{Name: "redfish-serve-tls-key", Usage: "TLS key for the Redfish ISO-serve"},
 		&cli.StringFlag{Name: "redfish-quirks-dir", Usage: "Directory of operator-supplied *.yaml/*.yml Redfish quirk profiles, loaded once at server start (not hot-reloaded). A BMCTarget's vendor resolves to a profile by name; an operator profile named the same as a built-in overrides it (logged). A malformed profile is skipped, not fatal", EnvVars: []string{redfishQuirksDirEnv}},
-		&cli.StringFlag{Name: "builder", Value: "local", Usage: "Which builder backend to use: 'local' or 'operator'"},
+		&cli.StringFlag{Name: "board-profiles-dir", Usage: boardProfilesDirUsage + ". Used only when --builder=local", EnvVars: []string{boardProfilesDirEnv}},
+		&cli.StringFlag{Name: "builder", Value: "local", Usage: "Which builder backend to use: 'local', 'operator' or 'worker'"},
 		&cli.StringFlag{Name: "kubeconfig", Usage: "Path to a kubeconfig file for the operator builder (single file). Empty means try in-cluster config first, then the default client-go loading rules (which honour a multi-file KUBECONFIG env)"},
 		&cli.StringFlag{Name: "builder-namespace", Value: "default", Usage: "Namespace in which OSArtifact CRs are created. Used only when --builder=operator"},
 		&cli.StringFlag{Name: "builder-upload-url", Usage: "URL exporter pods PUT built artifacts to. Only needs cluster-internal reachability, so a Service DNS name is appropriate. Defaults to --url. Used only when --builder=operator", EnvVars: []string{"AURORABOOT_BUILDER_UPLOAD_URL"}},
-	},
+		&cli.StringFlag{Name: "vuln-db", Usage: "Directory holding a local mirror of OSV advisories. Builds that request an SBOM are also matched against it for an offline vulnerability report. Used only when --builder=local", EnvVars: []string{"AURORABOOT_VULN_DB"}},
+		&cli.StringFlag{Name: "manifest-signing-key", Usage: "PEM private key (a cosign key, or any PKCS#8 key) the SHA256SUMS of every build is signed with, unless the build names a SecureBoot key set. An encrypted cosign key is unlocked with $COSIGN_PASSWORD", EnvVars: []string{"AURORABOOT_MANIFEST_SIGNING_KEY"}},
+		&cli.StringFlag{Name: "worker-token", Usage: "Registration token `auroraboot worker` processes enroll with (default: generated and saved to <data-dir>/secrets/worker-token). Used only when --builder=worker", EnvVars: []string{workerTokenEnv}},
+		&cli.StringFlag{Name: "git-webhook-secret", Usage: "Secret Git forges sign push webhooks to /api/v1/webhooks/git with (default: generated and saved to <data-dir>/secrets/git-webhook-secret)", EnvVars: []string{"AURORABOOT_GIT_WEBHOOK_SECRET"}},
+	}, storageFlags...),
 	Action: runWeb,
 }
 
 func runWeb(c *cli.Context) error {
 	builderKind := c.String("builder")
 	switch builderKind {
-	case "local", "operator":
+	case "local", "operator", "worker":
 	default:
-		return fmt.Errorf("--builder must be 'local' or 'operator', got %q", builderKind)
+		return fmt.Errorf("--builder must be 'local', 'operator' or 'worker', got %q", builderKind)
 	}
 
 	listenAddr := c.String("listen")
@@ -132,6 +148,7 @@ func runWeb(c *cli.Context) error {
 	redfishServeTLSCert := c.String("redfish-serve-tls-cert")
 	redfishServeTLSKey := c.String("redfish-serve-tls-key")
 	redfishQuirksDir := c.String("redfish-quirks-dir")
+	workerToken := c.String("worker-token")
 
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return fmt.Errorf("create data directory: %w", err)
@@ -166,6 +183,13 @@ func runWeb(c *cli.Context) error {
 		return fmt.Errorf("resolve registration token: %w", err)
 	}
 	regToken = resolvedRegToken
+	if builderKind == "worker" && workerToken == "" {
+		workerToken = loadOrGenerateSecret(filepath.Join(secretsDir, "worker-token"), "worker registration token")
+	}
+	gitWebhookSecret := c.String("git-webhook-secret")
+	if gitWebhookSecret == "" {
+		gitWebhookSecret = loadOrGenerateSecret(filepath.Join(secretsDir, "git-webhook-secret"), "Git webhook secret")
+	}
 	if externalURL == "" {
 		hostname, _ := os.Hostname()
 		if hostname == "" {
@@ -179,6 +203,10 @@ func runWeb(c *cli.Context) error {
 	if err := os.MkdirAll(artifactsDir, 0755); err != nil {
 		return fmt.Errorf("create artifacts directory: %w", err)
 	}
+	artifactStorage, err := NewArtifactStorage(context.Background(), c.String("storage"), artifactsDir, s3ConfigFromFlags(c))
+	if err != nil {
+		return fmt.Errorf("artifact storage: %w", err)
+	}
 
 	// Data encryption key for BMC credentials at rest (AES-256-GCM). Lives
 	// alongside the other secrets as a 0600 file; generated on first run.
@@ -202,13 +230,6 @@ func runWeb(c *cli.Context) error {
 
 	artifactStore := &gormstore.ArtifactStoreAdapter{S: store}
 
-	// Reconcile artifacts left Pending or Building by a previous process: a
-	// restart orphans their build goroutine, so they can never reach Ready on
-	// their own. Mark them Error so the UI reflects a terminal state.
-	if err := handlers.ReconcileOrphanedArtifacts(context.Background(), artifactStore); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: reconciling orphaned artifacts: %v\n", err)
-	}
-
 	// Create the WebSocket hub up front so the builder can broadcast log
 	// chunks to subscribed UI clients as they arrive. The same hub is
 	// handed to server.New below so the HTTP routes share it.
@@ -216,10 +237,14 @@ func runWeb(c *cli.Context) error {
 
 	var artifactBuilder builder.ArtifactBuilder
 	var systemInfo handlers.APISystemBuilder
+	var workerBuilder *worker.Builder
+	buildWorkerStore := &gormstore.BuildWorkerStoreAdapter{S: store}
 	switch builderKind {
 	case "local":
 		artifactBuilder = auroraboot.New(artifactsDir, nil, artifactStore).
-			WithLogBroadcaster(wsHub.UI)
+			WithLogBroadcaster(wsHub.UI).
+			WithVulnDB(c.String("vuln-db")).
+			WithStorage(artifactStorage)
 		systemInfo = handlers.APISystemBuilder{
 			Backend:           "local",
 			DownloadSupported: true,
@@ -267,10 +292,43 @@ func runWeb(c *cli.Context) error {
 			// download link by backend.
 			DownloadSupported: true,
 		}
+	case "worker":
+		// Builds are queued in the database and leased by `auroraboot worker`
+		// processes, which run the local pipeline on their own host and arch
+		// and upload the outputs through the same per-build upload token the
+		// operator exporter uses. The queue survives restarts; the reaper
+		// started below hands builds of silent workers back to the queue.
+		b, err := worker.New(worker.Config{
+			Jobs:      &gormstore.BuildJobStoreAdapter{S: store},
+			Workers:   buildWorkerStore,
+			Artifacts: artifactStore,
+			Cipher:    bmcCipher,
+		})
+		if err != nil {
+			return err
+		}
+		workerBuilder = b.WithLogBroadcaster(wsHub.UI)
+		artifactBuilder = workerBuilder
+		systemInfo = handlers.APISystemBuilder{
+			Backend:           "worker",
+			DownloadSupported: true,
+		}
 	default:
 		return fmt.Errorf("unreachable: --builder=%q survived validation", builderKind)
 	}
 
+	// Reconcile artifacts left Pending or Building by a previous process: a
+	// restart orphans their build goroutine, so they can never reach Ready on
+	// their own. Mark them Error so the UI reflects a terminal state. Builds
+	// still in the worker queue are not orphaned and keep their phase.
+	var keepInFlight func(id string) bool
+	if workerBuilder != nil {
+		keepInFlight = func(id string) bool { return workerBuilder.Queued(context.Background(), id) }
+	}
+	if err := handlers.ReconcileOrphanedArtifactsKeeping(context.Background(), artifactStore, keepInFlight); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: reconciling orphaned artifacts: %v\n", err)
+	}
+
 	nodeStore := &gormstore.NodeStoreAdapter{S: store}
 	commandStore := &gormstore.CommandStoreAdapter{S: store}
 	groupStore := &gormstore.GroupStoreAdapter{S: store}
@@ -321,12 +379,42 @@ func runWeb(c *cli.Context) error {
 	if err := loadRedfishQuirksDir(redfishQuirksDir); err != nil {
 		return err
 	}
+	// Board profiles are loaded the same way, for the raw disks local builds make.
+	if err := loadBoardProfilesDir(c.String("board-profiles-dir")); err != nil {
+		return err
+	}
 
 	// Root context for background deploy goroutines, cancelled when runWeb
 	// returns so in-flight Redfish deploys are aborted on shutdown.
 	baseCtx, baseCancel := context.WithCancel(context.Background())
 	defer baseCancel()
 
+	// Artifacts built before an object store was configured are only in
+	// the artifacts directory. Copy them over in the background; Download
+	// serves the local copy of anything not copied yet.
+	if _, isLocal := artifactStorage.(*storage.Local); !isLocal {
+		go func() {
+			if err := handlers.MigrateArtifactStorage(baseCtx, artifactStore, artifactsDir, artifactStorage); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: migrating artifacts to %s storage: %v\n", c.String("storage"), err)
+			}
+		}()
+	}
+
+	var workerQueue handlers.WorkerQueue
+	if workerBuilder != nil {
+		go workerBuilder.Run(baseCtx)
+		workerQueue = workerBuilder
+	}
+
+	// Signing happens at the end of a build, or when a remote build's
+	// manifest is uploaded; check the key now so a typo or a missing
+	// COSIGN_PASSWORD fails the server start instead of every build.
+	if key := c.String("manifest-signing-key"); key != "" {
+		if _, err := checksums.SignWithKeyFile(key, nil); err != nil {
+			return fmt.Errorf("--manifest-signing-key: %w", err)
+		}
+	}
+
 	e := server.New(server.Config{
 		BaseContext:           baseCtx,
 		NodeStore:             nodeStore,
@@ -339,6 +427,13 @@ func runWeb(c *cli.Context) error {
 		BMCTargetStore:        bmcTargetStore,
 		SettingsStore:         settingsStore,
 		Builder:               artifactBuilder,
+		BuildSetStore:         &gormstore.BuildSetStoreAdapter{S: store},
+		BuildTemplateStore:    &gormstore.BuildTemplateStoreAdapter{S: store},
+		RegistryStore:         &gormstore.RegistryStoreAdapter{S: store},
+		ChannelStore:          &gormstore.ChannelStoreAdapter{S: store},
+		SecretStore:           &gormstore.SecretStoreAdapter{S: store},
+		ArtifactSpecStore:     &gormstore.ArtifactSpecStoreAdapter{S: store},
+		GitWebhookSecret:      gitWebhookSecret,
 		SystemInfo:            systemInfo,
 		AdminPassword:         adminPassword,
 		RegToken:              regToken,
@@ -349,12 +444,20 @@ func runWeb(c *cli.Context) error {
 		Hub:                   wsHub,
 		ISOServe:              isoServe,
 		RedfishServeURL:       redfishServeURLSeed(isoServe, serveURL),
+		BuildWorkerStore:      buildWorkerStore,
+		WorkerQueue:           workerQueue,
+		WorkerToken:           workerToken,
+		ManifestSigningKey:    c.String("manifest-signing-key"),
+		Storage:               artifactStorage,
 	})
 
 	fmt.Fprintf(os.Stderr, "AuroraBoot fleet server starting on %s\n", listenAddr)
 	fmt.Fprintf(os.Stderr, "  UI:        %s\n", externalURL)
 	fmt.Fprintf(os.Stderr, "  DB:        %s\n", dbDSN)
 	fmt.Fprintf(os.Stderr, "  Artifacts: %s\n", artifactsDir)
+	if c.String("storage") == "s3" {
+		fmt.Fprintf(os.Stderr, "  Storage:   s3://%s/%s (%s)\n", c.String("s3-bucket"), c.String("s3-prefix"), c.String("s3-endpoint"))
+	}
 
 	return serve(e, listenAddr, tlsCert, tlsKey)
 }
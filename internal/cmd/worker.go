@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"github.com/kairos-io/AuroraBoot/internal/builder/worker"
+	"github.com/kairos-io/AuroraBoot/pkg/client"
+	"github.com/urfave/cli/v2"
+)
+
+// workerTokenEnv is shared by `web --worker-token` and `worker --token` so a
+// single env var configures both sides of a compose or helm deployment.
+const workerTokenEnv = "AURORABOOT_WORKER_TOKEN"
+
+// workerCredentialsFile holds the id and API key a worker was issued, under
+// --work-dir, so a restarted worker resumes its registration instead of
+// enrolling a duplicate.
+const workerCredentialsFile = "worker.json"
+
+type workerCredentials struct {
+	ID     string `json:"id"`
+	APIKey string `json:"apiKey"`
+}
+
+// WorkerCmd runs a remote build worker for an AuroraBoot server started with
+// --builder=worker.
+var WorkerCmd = cli.Command{
+	Name:  "worker",
+	Usage: "Run a remote build worker for an AuroraBoot server started with --builder=worker",
+	Description: `Worker registers with an AuroraBoot server, leases queued builds for its
+architecture, runs them with the local build pipeline, streams the build log
+back and uploads the finished artifacts.
+
+The host needs the same tooling as 'auroraboot web --builder=local' (docker,
+and the disk tools for raw images). Run one worker per architecture you want
+to build for.
+
+Examples:
+  # Register with the token printed by 'auroraboot web --builder=worker'
+  auroraboot worker --url https://auroraboot.example.com --token <worker-token>
+
+  # An arm64 build host with its own scratch directory
+  auroraboot worker --url https://auroraboot.example.com --token <worker-token> \
+    --name pi-builder --work-dir /var/lib/auroraboot-worker
+`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "url", Usage: "URL of the AuroraBoot server", EnvVars: []string{"AURORABOOT_URL"}, Required: true},
+		&cli.StringFlag{Name: "token", Usage: "Worker registration token of the server (--worker-token). Not needed once credentials are saved in --work-dir", EnvVars: []string{workerTokenEnv}},
+		&cli.StringFlag{Name: "name", Usage: "Name shown in the worker list (default: hostname)"},
+		&cli.StringFlag{Name: "arch", Value: runtime.GOARCH, Usage: "Architecture this worker builds for: 'amd64' or 'arm64'"},
+		&cli.StringFlag{Name: "work-dir", Value: "./auroraboot-worker", Usage: "Directory for build scratch space and the saved worker credentials"},
+		&cli.StringFlag{Name: "vuln-db", Usage: "Directory holding a local mirror of OSV advisories. Builds that request an SBOM are also matched against it for an offline vulnerability report", EnvVars: []string{"AURORABOOT_VULN_DB"}},
+		&cli.StringFlag{Name: "board-profiles-dir", Usage: boardProfilesDirUsage, EnvVars: []string{boardProfilesDirEnv}},
+		&cli.DurationFlag{Name: "poll-interval", Usage: "How often an idle worker asks for work (default 5s)"},
+	},
+	Action: runWorker,
+}
+
+func runWorker(c *cli.Context) error {
+	arch := c.String("arch")
+	if arch != "amd64" && arch != "arm64" {
+		return fmt.Errorf("--arch must be 'amd64' or 'arm64', got %q", arch)
+	}
+	name := c.String("name")
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	if name == "" {
+		return errors.New("--name is required when the hostname cannot be determined")
+	}
+	workDir, err := filepath.Abs(c.String("work-dir"))
+	if err != nil {
+		return fmt.Errorf("resolve work directory: %w", err)
+	}
+	if err := os.MkdirAll(workDir, 0700); err != nil {
+		return fmt.Errorf("create work directory: %w", err)
+	}
+
+	if err := loadBoardProfilesDir(c.String("board-profiles-dir")); err != nil {
+		return err
+	}
+
+	credsPath := filepath.Join(workDir, workerCredentialsFile)
+	creds, err := loadWorkerCredentials(credsPath)
+	if err != nil {
+		return err
+	}
+	if creds.APIKey == "" && c.String("token") == "" {
+		return errors.New("--token is required for the first registration")
+	}
+
+	agent := worker.NewAgent(worker.AgentConfig{
+		Client:            client.New(c.String("url")),
+		RegistrationToken: c.String("token"),
+		WorkerID:          creds.ID,
+		APIKey:            creds.APIKey,
+		Name:              name,
+		Arch:              arch,
+		Version:           c.App.Version,
+		WorkDir:           workDir,
+		PollInterval:      c.Duration("poll-interval"),
+		VulnDB:            c.String("vuln-db"),
+		OnRegister: func(id, apiKey string) error {
+			return saveWorkerCredentials(credsPath, workerCredentials{ID: id, APIKey: apiKey})
+		},
+	})
+
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "AuroraBoot worker %q (%s) polling %s\n", name, arch, c.String("url"))
+	return agent.Run(ctx)
+}
+
+func loadWorkerCredentials(path string) (workerCredentials, error) {
+	var creds workerCredentials
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return creds, nil
+	}
+	if err != nil {
+		return creds, fmt.Errorf("read worker credentials: %w", err)
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return creds, fmt.Errorf("parse worker credentials %s: %w", path, err)
+	}
+	return creds, nil
+}
+
+func saveWorkerCredentials(path string, creds workerCredentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/kairos-io/AuroraBoot/deployer"
 	"github.com/kairos-io/AuroraBoot/internal"
+	"github.com/kairos-io/AuroraBoot/pkg/cloudconfig"
 	"github.com/kairos-io/AuroraBoot/pkg/schema"
 	"github.com/kairos-io/kairos-sdk/unstructured"
 	"gopkg.in/yaml.v3"
@@ -169,5 +170,15 @@ func ReadCloudConfig(cloudConfig string, templateValues map[string]interface{})
 		return result, fmt.Errorf("cloud config set but contents are empty. Check that the content of the file is correct or the path is the proper one")
 	}
 
+	// Check the rendered config, so a typo is caught here rather than by
+	// a machine that fails to install.
+	report := cloudconfig.Lint(result)
+	for _, w := range report.Warnings {
+		internal.Log.Logger.Warn().Str("cloud-config", cloudConfig).Msg(w.String())
+	}
+	if err := report.Err(); err != nil {
+		return result, fmt.Errorf("%s: %w", cloudConfig, err)
+	}
+
 	return result, nil
 }
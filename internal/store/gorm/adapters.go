@@ -2,6 +2,7 @@ package gorm
 
 import (
 	"context"
+	"time"
 
 	"github.com/kairos-io/AuroraBoot/pkg/store"
 )
@@ -88,6 +89,9 @@ func (a *CommandStoreAdapter) UpdateStatusForNode(ctx context.Context, id string
 func (a *CommandStoreAdapter) ListByNode(ctx context.Context, nodeID string) ([]*store.NodeCommand, error) {
 	return a.S.ListByNode(ctx, nodeID)
 }
+func (a *CommandStoreAdapter) UpdateArgs(ctx context.Context, id string, args map[string]string) error {
+	return a.S.UpdateArgs(ctx, id, args)
+}
 func (a *CommandStoreAdapter) Delete(ctx context.Context, id string) error {
 	return a.S.CommandDelete(ctx, id)
 }
@@ -116,9 +120,15 @@ func (a *ArtifactStoreAdapter) UpdatePhaseMessage(ctx context.Context, id, phase
 func (a *ArtifactStoreAdapter) UpdateFiles(ctx context.Context, id string, files []string) error {
 	return a.S.ArtifactUpdateFiles(ctx, id, files)
 }
+func (a *ArtifactStoreAdapter) UpdateStep(ctx context.Context, id, step string, progress int) error {
+	return a.S.ArtifactUpdateStep(ctx, id, step, progress)
+}
 func (a *ArtifactStoreAdapter) ClearUploadToken(ctx context.Context, id string) error {
 	return a.S.ArtifactClearUploadToken(ctx, id)
 }
+func (a *ArtifactStoreAdapter) SetUploadToken(ctx context.Context, id, token string) error {
+	return a.S.ArtifactSetUploadToken(ctx, id, token)
+}
 func (a *ArtifactStoreAdapter) Delete(ctx context.Context, id string) error {
 	return a.S.ArtifactDelete(ctx, id)
 }
@@ -194,6 +204,50 @@ func (a *BMCTargetStoreAdapter) Delete(ctx context.Context, id string) error {
 	return a.S.BMCTargetDelete(ctx, id)
 }
 
+// ChannelStoreAdapter adapts Store to the store.ChannelStore interface.
+type ChannelStoreAdapter struct{ S *Store }
+
+func (a *ChannelStoreAdapter) Create(ctx context.Context, ch *store.Channel) error {
+	return a.S.ChannelCreate(ctx, ch)
+}
+func (a *ChannelStoreAdapter) GetByID(ctx context.Context, id string) (*store.Channel, error) {
+	return a.S.ChannelGetByID(ctx, id)
+}
+func (a *ChannelStoreAdapter) GetByName(ctx context.Context, name string) (*store.Channel, error) {
+	return a.S.ChannelGetByName(ctx, name)
+}
+func (a *ChannelStoreAdapter) List(ctx context.Context) ([]*store.Channel, error) {
+	return a.S.ChannelList(ctx)
+}
+func (a *ChannelStoreAdapter) Move(ctx context.Context, channelID, expect string, entry *store.ChannelEntry) error {
+	return a.S.ChannelMove(ctx, channelID, expect, entry)
+}
+func (a *ChannelStoreAdapter) History(ctx context.Context, channelID string) ([]*store.ChannelEntry, error) {
+	return a.S.ChannelHistory(ctx, channelID)
+}
+func (a *ChannelStoreAdapter) Delete(ctx context.Context, id string) error {
+	return a.S.ChannelDelete(ctx, id)
+}
+
+// RegistryStoreAdapter adapts Store to the store.RegistryStore interface.
+type RegistryStoreAdapter struct{ S *Store }
+
+func (a *RegistryStoreAdapter) Create(ctx context.Context, reg *store.Registry) error {
+	return a.S.RegistryCreate(ctx, reg)
+}
+func (a *RegistryStoreAdapter) GetByID(ctx context.Context, id string) (*store.Registry, error) {
+	return a.S.RegistryGetByID(ctx, id)
+}
+func (a *RegistryStoreAdapter) List(ctx context.Context) ([]*store.Registry, error) {
+	return a.S.RegistryList(ctx)
+}
+func (a *RegistryStoreAdapter) Update(ctx context.Context, reg *store.Registry) error {
+	return a.S.RegistryUpdate(ctx, reg)
+}
+func (a *RegistryStoreAdapter) Delete(ctx context.Context, id string) error {
+	return a.S.RegistryDelete(ctx, id)
+}
+
 // DeploymentStoreAdapter adapts Store to the store.DeploymentStore interface.
 type DeploymentStoreAdapter struct{ S *Store }
 
@@ -231,3 +285,123 @@ func (a *SettingsStoreAdapter) Set(ctx context.Context, key, value string) error
 func (a *SettingsStoreAdapter) GetAll(ctx context.Context) (map[string]string, error) {
 	return a.S.SettingGetAll(ctx)
 }
+
+// BuildWorkerStoreAdapter adapts Store to the store.BuildWorkerStore interface.
+type BuildWorkerStoreAdapter struct{ S *Store }
+
+func (a *BuildWorkerStoreAdapter) Register(ctx context.Context, w *store.BuildWorker) error {
+	return a.S.BuildWorkerRegister(ctx, w)
+}
+func (a *BuildWorkerStoreAdapter) GetByID(ctx context.Context, id string) (*store.BuildWorker, error) {
+	return a.S.BuildWorkerGetByID(ctx, id)
+}
+func (a *BuildWorkerStoreAdapter) GetByAPIKeyHash(ctx context.Context, hash string) (*store.BuildWorker, error) {
+	return a.S.BuildWorkerGetByAPIKeyHash(ctx, hash)
+}
+func (a *BuildWorkerStoreAdapter) List(ctx context.Context) ([]*store.BuildWorker, error) {
+	return a.S.BuildWorkerList(ctx)
+}
+func (a *BuildWorkerStoreAdapter) Heartbeat(ctx context.Context, id, currentBuildID string) error {
+	return a.S.BuildWorkerHeartbeat(ctx, id, currentBuildID)
+}
+func (a *BuildWorkerStoreAdapter) Delete(ctx context.Context, id string) error {
+	return a.S.BuildWorkerDelete(ctx, id)
+}
+
+// BuildJobStoreAdapter adapts Store to the store.BuildJobStore interface.
+type BuildJobStoreAdapter struct{ S *Store }
+
+func (a *BuildJobStoreAdapter) Enqueue(ctx context.Context, job *store.BuildJob) error {
+	return a.S.BuildJobEnqueue(ctx, job)
+}
+func (a *BuildJobStoreAdapter) GetByID(ctx context.Context, id string) (*store.BuildJob, error) {
+	return a.S.BuildJobGetByID(ctx, id)
+}
+func (a *BuildJobStoreAdapter) List(ctx context.Context) ([]*store.BuildJob, error) {
+	return a.S.BuildJobList(ctx)
+}
+func (a *BuildJobStoreAdapter) Delete(ctx context.Context, id string) error {
+	return a.S.BuildJobDelete(ctx, id)
+}
+func (a *BuildJobStoreAdapter) LeaseNext(ctx context.Context, workerID, arch string, until time.Time) (*store.BuildJob, error) {
+	return a.S.BuildJobLeaseNext(ctx, workerID, arch, until)
+}
+func (a *BuildJobStoreAdapter) RenewLease(ctx context.Context, id, workerID string, until time.Time) (bool, error) {
+	return a.S.BuildJobRenewLease(ctx, id, workerID, until)
+}
+func (a *BuildJobStoreAdapter) RequeueExpired(ctx context.Context, now time.Time) ([]string, error) {
+	return a.S.BuildJobRequeueExpired(ctx, now)
+}
+
+// BuildSetStoreAdapter adapts Store to the store.BuildSetStore interface.
+type BuildSetStoreAdapter struct{ S *Store }
+
+func (a *BuildSetStoreAdapter) Create(ctx context.Context, set *store.BuildSet) error {
+	return a.S.BuildSetCreate(ctx, set)
+}
+func (a *BuildSetStoreAdapter) GetByID(ctx context.Context, id string) (*store.BuildSet, error) {
+	return a.S.BuildSetGetByID(ctx, id)
+}
+func (a *BuildSetStoreAdapter) List(ctx context.Context) ([]*store.BuildSet, error) {
+	return a.S.BuildSetList(ctx)
+}
+func (a *BuildSetStoreAdapter) Delete(ctx context.Context, id string) error {
+	return a.S.BuildSetDelete(ctx, id)
+}
+func (a *BuildSetStoreAdapter) Attach(ctx context.Context, setID, artifactID string) error {
+	return a.S.BuildSetAttach(ctx, setID, artifactID)
+}
+func (a *BuildSetStoreAdapter) Children(ctx context.Context, setID string) ([]*store.ArtifactRecord, error) {
+	return a.S.BuildSetChildren(ctx, setID)
+}
+
+// ArtifactSpecStoreAdapter adapts Store to the store.ArtifactSpecStore interface.
+type ArtifactSpecStoreAdapter struct{ S *Store }
+
+func (a *ArtifactSpecStoreAdapter) SetSpec(ctx context.Context, id, spec string) error {
+	return a.S.ArtifactSetSpec(ctx, id, spec)
+}
+func (a *ArtifactSpecStoreAdapter) GetSpec(ctx context.Context, id string) (string, error) {
+	return a.S.ArtifactGetSpec(ctx, id)
+}
+
+// SecretStoreAdapter adapts Store to the store.SecretStore interface.
+type SecretStoreAdapter struct{ S *Store }
+
+func (a *SecretStoreAdapter) Set(ctx context.Context, secret *store.Secret) error {
+	return a.S.SecretSet(ctx, secret)
+}
+func (a *SecretStoreAdapter) Get(ctx context.Context, name string) (*store.Secret, error) {
+	return a.S.SecretGet(ctx, name)
+}
+func (a *SecretStoreAdapter) List(ctx context.Context) ([]*store.Secret, error) {
+	return a.S.SecretList(ctx)
+}
+func (a *SecretStoreAdapter) Delete(ctx context.Context, name string) error {
+	return a.S.SecretDelete(ctx, name)
+}
+
+// BuildTemplateStoreAdapter adapts Store to the store.BuildTemplateStore interface.
+type BuildTemplateStoreAdapter struct{ S *Store }
+
+func (a *BuildTemplateStoreAdapter) Create(ctx context.Context, tpl *store.BuildTemplate) error {
+	return a.S.BuildTemplateCreate(ctx, tpl)
+}
+func (a *BuildTemplateStoreAdapter) GetByID(ctx context.Context, id string) (*store.BuildTemplate, error) {
+	return a.S.BuildTemplateGetByID(ctx, id)
+}
+func (a *BuildTemplateStoreAdapter) GetByName(ctx context.Context, name string) (*store.BuildTemplate, error) {
+	return a.S.BuildTemplateGetByName(ctx, name)
+}
+func (a *BuildTemplateStoreAdapter) List(ctx context.Context) ([]*store.BuildTemplate, error) {
+	return a.S.BuildTemplateList(ctx)
+}
+func (a *BuildTemplateStoreAdapter) Update(ctx context.Context, tpl *store.BuildTemplate) error {
+	return a.S.BuildTemplateUpdate(ctx, tpl)
+}
+func (a *BuildTemplateStoreAdapter) Delete(ctx context.Context, id string) error {
+	return a.S.BuildTemplateDelete(ctx, id)
+}
+func (a *BuildTemplateStoreAdapter) Attach(ctx context.Context, templateID, artifactID string) error {
+	return a.S.BuildTemplateAttach(ctx, templateID, artifactID)
+}
@@ -0,0 +1,61 @@
+package gorm_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/internal/secrets"
+	gormstore "github.com/kairos-io/AuroraBoot/internal/store/gorm"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("ArtifactSpecStore", func() {
+	var (
+		ctx    context.Context
+		dbPath string
+		s      *gormstore.Store
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		dbPath = filepath.Join(GinkgoT().TempDir(), "specs.db")
+		c, err := secrets.NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+		Expect(err).NotTo(HaveOccurred())
+		s, err = gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		s = s.WithCipher(c)
+	})
+
+	It("keeps an artifact's spec across full-row updates", func() {
+		rec := &store.ArtifactRecord{ID: "a1", Phase: store.ArtifactPending}
+		Expect(s.ArtifactCreate(ctx, rec)).To(Succeed())
+		Expect(s.ArtifactSetSpec(ctx, "a1", `{"name":"edge"}`)).To(Succeed())
+
+		got, err := s.ArtifactGetByID(ctx, "a1")
+		Expect(err).NotTo(HaveOccurred())
+		got.Spec = ""
+		got.Phase = store.ArtifactReady
+		got.Git = &store.GitSource{URL: "https://example.com/r.git", Commit: "abc"}
+		Expect(s.ArtifactUpdate(ctx, got)).To(Succeed())
+
+		spec, err := s.ArtifactGetSpec(ctx, "a1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(spec).To(Equal(`{"name":"edge"}`))
+		got, err = s.ArtifactGetByID(ctx, "a1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Git.Commit).To(Equal("abc"))
+	})
+
+	It("fails to read a spec it cannot decrypt", func() {
+		raw, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(raw.ArtifactCreate(ctx, &store.ArtifactRecord{ID: "a1", Phase: store.ArtifactPending})).To(Succeed())
+		Expect(raw.ArtifactSetSpec(ctx, "a1", `{"name":"edge"}`)).To(Succeed())
+
+		_, err = s.ArtifactGetSpec(ctx, "a1")
+		Expect(err).To(MatchError(ContainSubstring("decrypting artifact spec")))
+	})
+})
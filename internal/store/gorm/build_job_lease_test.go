@@ -0,0 +1,126 @@
+package gorm_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	gormstore "github.com/kairos-io/AuroraBoot/internal/store/gorm"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("BuildJob leasing", func() {
+	var (
+		ctx context.Context
+		s   *gormstore.Store
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		s, err = gormstore.New(":memory:")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	enqueue := func(id, arch string, created time.Time) {
+		Expect(s.BuildJobEnqueue(ctx, &store.BuildJob{ID: id, Arch: arch, Spec: "spec-" + id, CreatedAt: created})).To(Succeed())
+	}
+
+	It("leases the oldest job for the worker's architecture only", func() {
+		base := time.Now()
+		enqueue("arm-1", "arm64", base)
+		enqueue("amd-2", "amd64", base.Add(2*time.Second))
+		enqueue("amd-1", "amd64", base.Add(time.Second))
+
+		until := time.Now().Add(time.Minute)
+		job, err := s.BuildJobLeaseNext(ctx, "w1", "amd64", until)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job).NotTo(BeNil())
+		Expect(job.ID).To(Equal("amd-1"))
+		Expect(job.WorkerID).To(Equal("w1"))
+		Expect(job.Attempts).To(Equal(1))
+		Expect(job.Spec).To(Equal("spec-amd-1"))
+		Expect(job.LeaseExpiresAt).NotTo(BeNil())
+
+		job, err = s.BuildJobLeaseNext(ctx, "w1", "amd64", until)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.ID).To(Equal("amd-2"))
+
+		job, err = s.BuildJobLeaseNext(ctx, "w1", "amd64", until)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job).To(BeNil(), "no amd64 work left; the arm64 job must not be handed out")
+	})
+
+	It("never hands the same job to two concurrent workers", func() {
+		for i := 0; i < 5; i++ {
+			enqueue("job-"+string(rune('a'+i)), "amd64", time.Now().Add(time.Duration(i)*time.Millisecond))
+		}
+		var (
+			mu  sync.Mutex
+			got = map[string]string{}
+			wg  sync.WaitGroup
+		)
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(worker string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				job, err := s.BuildJobLeaseNext(ctx, worker, "amd64", time.Now().Add(time.Minute))
+				Expect(err).NotTo(HaveOccurred())
+				if job == nil {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				Expect(got).NotTo(HaveKey(job.ID), "job leased twice")
+				got[job.ID] = worker
+			}("w" + string(rune('0'+w)))
+		}
+		wg.Wait()
+		Expect(len(got)).To(BeNumerically("<=", 5))
+	})
+
+	It("renews only for the lease holder", func() {
+		enqueue("j1", "amd64", time.Now())
+		_, err := s.BuildJobLeaseNext(ctx, "w1", "amd64", time.Now().Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+
+		ok, err := s.BuildJobRenewLease(ctx, "j1", "w2", time.Now().Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		ok, err = s.BuildJobRenewLease(ctx, "j1", "w1", time.Now().Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("requeues expired leases and leaves live ones alone", func() {
+		enqueue("stale", "amd64", time.Now())
+		enqueue("live", "amd64", time.Now().Add(time.Second))
+		_, err := s.BuildJobLeaseNext(ctx, "w1", "amd64", time.Now().Add(-time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = s.BuildJobLeaseNext(ctx, "w2", "amd64", time.Now().Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+
+		ids, err := s.BuildJobRequeueExpired(ctx, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids).To(ConsistOf("stale"))
+
+		stale, err := s.BuildJobGetByID(ctx, "stale")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stale.WorkerID).To(BeEmpty())
+		Expect(stale.LeaseExpiresAt).To(BeNil())
+
+		ok, err := s.BuildJobRenewLease(ctx, "stale", "w1", time.Now().Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse(), "the previous holder must not be able to resurrect a reaped lease")
+
+		job, err := s.BuildJobLeaseNext(ctx, "w3", "amd64", time.Now().Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.ID).To(Equal("stale"))
+		Expect(job.Attempts).To(Equal(2))
+	})
+})
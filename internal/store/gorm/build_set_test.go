@@ -0,0 +1,105 @@
+package gorm_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/internal/secrets"
+	gormstore "github.com/kairos-io/AuroraBoot/internal/store/gorm"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("BuildSet", func() {
+	var (
+		ctx context.Context
+		s   *gormstore.Store
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		s, err = gormstore.New(":memory:")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("keeps the child link when a builder saves the full record", func() {
+		set := &store.BuildSet{Name: "release"}
+		Expect(s.BuildSetCreate(ctx, set)).To(Succeed())
+		Expect(set.ID).NotTo(BeEmpty())
+
+		rec := &store.ArtifactRecord{ID: "child-1", Phase: store.ArtifactPending}
+		Expect(s.ArtifactCreate(ctx, rec)).To(Succeed())
+		Expect(s.BuildSetAttach(ctx, set.ID, "child-1")).To(Succeed())
+
+		// A builder that read the record before Attach writes it back.
+		rec.Phase = store.ArtifactReady
+		Expect(s.ArtifactUpdate(ctx, rec)).To(Succeed())
+
+		children, err := s.BuildSetChildren(ctx, set.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(children).To(HaveLen(1))
+		Expect(children[0].Phase).To(Equal(store.ArtifactReady))
+		Expect(children[0].BuildSetID).To(Equal(set.ID))
+	})
+
+	It("unlinks the children when the set is deleted", func() {
+		set := &store.BuildSet{Name: "release"}
+		Expect(s.BuildSetCreate(ctx, set)).To(Succeed())
+		Expect(s.ArtifactCreate(ctx, &store.ArtifactRecord{ID: "child-1", Phase: store.ArtifactReady})).To(Succeed())
+		Expect(s.BuildSetAttach(ctx, set.ID, "child-1")).To(Succeed())
+
+		Expect(s.BuildSetDelete(ctx, set.ID)).To(Succeed())
+		_, err := s.BuildSetGetByID(ctx, set.ID)
+		Expect(err).To(HaveOccurred())
+
+		rec, err := s.ArtifactGetByID(ctx, "child-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rec.BuildSetID).To(BeEmpty())
+	})
+
+	It("encrypts the spec at rest when a cipher is configured", func() {
+		dbPath := filepath.Join(GinkgoT().TempDir(), "sets.db")
+		c, err := secrets.NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+		Expect(err).NotTo(HaveOccurred())
+		enc, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		enc.WithCipher(c)
+
+		spec := `{"template":{"provisioning":{"password":"hunter2-hunter2"}}}`
+		set := &store.BuildSet{Name: "release", Spec: spec}
+		Expect(enc.BuildSetCreate(ctx, set)).To(Succeed())
+		Expect(set.Spec).To(Equal(spec), "the caller's struct keeps its plaintext")
+
+		got, err := enc.BuildSetGetByID(ctx, set.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Spec).To(Equal(spec))
+
+		raw, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		rawSet, err := raw.BuildSetGetByID(ctx, set.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rawSet.Spec).NotTo(ContainSubstring("hunter2"))
+	})
+
+	It("fails to read a spec it cannot decrypt", func() {
+		dbPath := filepath.Join(GinkgoT().TempDir(), "sets.db")
+		c, err := secrets.NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+		Expect(err).NotTo(HaveOccurred())
+		enc, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		enc.WithCipher(c)
+		set := &store.BuildSet{Name: "release", Spec: `{"template":{}}`}
+		Expect(enc.BuildSetCreate(ctx, set)).To(Succeed())
+
+		other, err := secrets.NewCipher([]byte("fedcba9876543210fedcba9876543210"))
+		Expect(err).NotTo(HaveOccurred())
+		wrong, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		wrong.WithCipher(other)
+		_, err = wrong.BuildSetGetByID(ctx, set.ID)
+		Expect(err).To(MatchError(ContainSubstring("decrypting build set spec")))
+	})
+})
@@ -0,0 +1,96 @@
+package gorm_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/internal/secrets"
+	gormstore "github.com/kairos-io/AuroraBoot/internal/store/gorm"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("BuildTemplateStore", func() {
+	var (
+		ctx    context.Context
+		dbPath string
+		s      *gormstore.Store
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		dbPath = filepath.Join(GinkgoT().TempDir(), "templates.db")
+		c, err := secrets.NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+		Expect(err).NotTo(HaveOccurred())
+		s, err = gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		s = s.WithCipher(c)
+	})
+
+	It("encrypts the spec and keeps names unique", func() {
+		tpl := &store.BuildTemplate{
+			Name:       "edge",
+			Parameters: []store.TemplateParameter{{Name: "version", Default: "v4.1.2"}},
+			Spec:       `{"provisioning":{"password":"hunter2"}}`,
+		}
+		Expect(s.BuildTemplateCreate(ctx, tpl)).To(Succeed())
+		Expect(tpl.ID).NotTo(BeEmpty())
+		Expect(s.BuildTemplateCreate(ctx, &store.BuildTemplate{Name: "edge"})).NotTo(Succeed())
+
+		got, err := s.BuildTemplateGetByName(ctx, "edge")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Spec).To(Equal(tpl.Spec))
+		Expect(got.Parameters).To(Equal(tpl.Parameters))
+
+		raw, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		rawTpl, err := raw.BuildTemplateGetByID(ctx, tpl.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rawTpl.Spec).NotTo(ContainSubstring("hunter2"))
+
+		got.Description = "vetted"
+		got.Spec = `{"baseImage":"ubuntu:24.04"}`
+		Expect(s.BuildTemplateUpdate(ctx, got)).To(Succeed())
+		got, err = s.BuildTemplateGetByID(ctx, tpl.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Description).To(Equal("vetted"))
+		Expect(got.Spec).To(Equal(`{"baseImage":"ubuntu:24.04"}`))
+
+		list, err := s.BuildTemplateList(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Spec).To(BeEmpty())
+	})
+
+	It("fails to read a spec it cannot decrypt", func() {
+		raw, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		tpl := &store.BuildTemplate{Name: "edge", Spec: `{}`}
+		Expect(raw.BuildTemplateCreate(ctx, tpl)).To(Succeed())
+
+		_, err = s.BuildTemplateGetByID(ctx, tpl.ID)
+		Expect(err).To(MatchError(ContainSubstring("decrypting build template spec")))
+		_, err = s.BuildTemplateGetByName(ctx, "edge")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("links builds that survive full-row updates and template deletion", func() {
+		tpl := &store.BuildTemplate{Name: "edge", Spec: `{}`}
+		Expect(s.BuildTemplateCreate(ctx, tpl)).To(Succeed())
+		Expect(s.ArtifactCreate(ctx, &store.ArtifactRecord{ID: "a1", Phase: store.ArtifactPending})).To(Succeed())
+		Expect(s.BuildTemplateAttach(ctx, tpl.ID, "a1")).To(Succeed())
+
+		Expect(s.ArtifactUpdate(ctx, &store.ArtifactRecord{ID: "a1", Phase: store.ArtifactReady})).To(Succeed())
+		got, err := s.ArtifactGetByID(ctx, "a1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.TemplateID).To(Equal(tpl.ID))
+
+		Expect(s.BuildTemplateDelete(ctx, tpl.ID)).To(Succeed())
+		got, err = s.ArtifactGetByID(ctx, "a1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.TemplateID).To(BeEmpty())
+		Expect(got.Phase).To(Equal(store.ArtifactReady))
+	})
+})
@@ -0,0 +1,71 @@
+package gorm_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	gormstore "github.com/kairos-io/AuroraBoot/internal/store/gorm"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("ChannelStore", func() {
+	var (
+		ctx context.Context
+		s   *gormstore.Store
+		ch  *store.Channel
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		s, err = gormstore.New(filepath.Join(GinkgoT().TempDir(), "channels.db"))
+		Expect(err).NotTo(HaveOccurred())
+		ch = &store.Channel{Name: "ubuntu-k3s/stable"}
+		Expect(s.ChannelCreate(ctx, ch)).To(Succeed())
+	})
+
+	It("moves the channel and records each move, newest first", func() {
+		Expect(s.ChannelMove(ctx, ch.ID, "", &store.ChannelEntry{Action: store.ChannelPromote, ArtifactID: "a1"})).To(Succeed())
+		Expect(s.ChannelMove(ctx, ch.ID, "a1", &store.ChannelEntry{Action: store.ChannelPromote, ArtifactID: "a2", Note: "cve fix"})).To(Succeed())
+
+		got, err := s.ChannelGetByName(ctx, "ubuntu-k3s/stable")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.ArtifactID).To(Equal("a2"))
+
+		history, err := s.ChannelHistory(ctx, ch.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history).To(HaveLen(2))
+		Expect(history[0].ArtifactID).To(Equal("a2"))
+		Expect(history[0].PreviousArtifactID).To(Equal("a1"))
+		Expect(history[0].Note).To(Equal("cve fix"))
+		Expect(history[1].PreviousArtifactID).To(BeEmpty())
+	})
+
+	It("refuses a move based on a stale current artifact", func() {
+		Expect(s.ChannelMove(ctx, ch.ID, "", &store.ChannelEntry{Action: store.ChannelPromote, ArtifactID: "a1"})).To(Succeed())
+		err := s.ChannelMove(ctx, ch.ID, "", &store.ChannelEntry{Action: store.ChannelPromote, ArtifactID: "a2"})
+		Expect(err).To(MatchError(store.ErrChannelMoved))
+
+		history, err := s.ChannelHistory(ctx, ch.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history).To(HaveLen(1))
+	})
+
+	It("stops groups following a deleted channel", func() {
+		g := &store.NodeGroup{Name: "edge", FollowChannelID: ch.ID}
+		Expect(s.Create(ctx, g)).To(Succeed())
+		Expect(s.ChannelMove(ctx, ch.ID, "", &store.ChannelEntry{Action: store.ChannelPromote, ArtifactID: "a1"})).To(Succeed())
+
+		Expect(s.ChannelDelete(ctx, ch.ID)).To(Succeed())
+
+		got, err := s.GetByID(ctx, g.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.FollowChannelID).To(BeEmpty())
+		history, err := s.ChannelHistory(ctx, ch.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history).To(BeEmpty())
+	})
+})
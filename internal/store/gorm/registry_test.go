@@ -0,0 +1,83 @@
+package gorm_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/internal/secrets"
+	gormstore "github.com/kairos-io/AuroraBoot/internal/store/gorm"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("RegistryStore", func() {
+	var (
+		ctx    context.Context
+		dbPath string
+		s      *gormstore.Store
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		dbPath = filepath.Join(GinkgoT().TempDir(), "registries.db")
+		c, err := secrets.NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+		Expect(err).NotTo(HaveOccurred())
+		s, err = gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		s = s.WithCipher(c)
+	})
+
+	It("encrypts the password at rest and decrypts it on read", func() {
+		reg := &store.Registry{Name: "ghcr", Host: "ghcr.io", Namespace: "acme", Username: "bot", Password: "ghp_token"}
+		Expect(s.RegistryCreate(ctx, reg)).To(Succeed())
+		Expect(reg.ID).NotTo(BeEmpty())
+		Expect(reg.Password).To(Equal("ghp_token"))
+
+		got, err := s.RegistryGetByID(ctx, reg.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Password).To(Equal("ghp_token"))
+		Expect(got.Namespace).To(Equal("acme"))
+
+		raw, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		rawReg, err := raw.RegistryGetByID(ctx, reg.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rawReg.Password).NotTo(BeEmpty())
+		Expect(rawReg.Password).NotTo(Equal("ghp_token"))
+	})
+
+	It("fails to read a password it cannot decrypt", func() {
+		raw, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		reg := &store.Registry{Name: "ghcr", Host: "ghcr.io", Password: "plaintext"}
+		Expect(raw.RegistryCreate(ctx, reg)).To(Succeed())
+
+		_, err = s.RegistryGetByID(ctx, reg.ID)
+		Expect(err).To(MatchError(ContainSubstring("decrypting registry password")))
+		_, err = s.RegistryList(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("lists, updates and deletes registries", func() {
+		a := &store.Registry{Name: "b-lab", Host: "registry.lan:5000", Insecure: true}
+		b := &store.Registry{Name: "a-hub", Host: "docker.io", Username: "u", Password: "p"}
+		Expect(s.RegistryCreate(ctx, a)).To(Succeed())
+		Expect(s.RegistryCreate(ctx, b)).To(Succeed())
+
+		b.Password = "rotated"
+		Expect(s.RegistryUpdate(ctx, b)).To(Succeed())
+
+		list, err := s.RegistryList(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(2))
+		Expect(list[0].Name).To(Equal("a-hub"))
+		Expect(list[0].Password).To(Equal("rotated"))
+		Expect(list[1].Insecure).To(BeTrue())
+
+		Expect(s.RegistryDelete(ctx, a.ID)).To(Succeed())
+		_, err = s.RegistryGetByID(ctx, a.ID)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,69 @@
+package gorm_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/internal/secrets"
+	gormstore "github.com/kairos-io/AuroraBoot/internal/store/gorm"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+var _ = Describe("SecretStore", func() {
+	var (
+		ctx    context.Context
+		dbPath string
+		s      *gormstore.Store
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		dbPath = filepath.Join(GinkgoT().TempDir(), "secrets.db")
+		c, err := secrets.NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+		Expect(err).NotTo(HaveOccurred())
+		s, err = gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		s = s.WithCipher(c)
+	})
+
+	It("encrypts secret values and upserts by name", func() {
+		Expect(s.SecretSet(ctx, &store.Secret{Name: "git-token", Value: "ghp_one", Description: "CI bot"})).To(Succeed())
+		first, err := s.SecretGet(ctx, "git-token")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.Value).To(Equal("ghp_one"))
+
+		Expect(s.SecretSet(ctx, &store.Secret{Name: "git-token", Value: "ghp_two"})).To(Succeed())
+		got, err := s.SecretGet(ctx, "git-token")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Value).To(Equal("ghp_two"))
+		Expect(got.CreatedAt).To(BeTemporally("==", first.CreatedAt))
+
+		raw, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		rawSecret, err := raw.SecretGet(ctx, "git-token")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rawSecret.Value).NotTo(BeEmpty())
+		Expect(rawSecret.Value).NotTo(Equal("ghp_two"))
+
+		list, err := s.SecretList(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Value).To(BeEmpty())
+
+		Expect(s.SecretDelete(ctx, "git-token")).To(Succeed())
+		_, err = s.SecretGet(ctx, "git-token")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails to read a value it cannot decrypt", func() {
+		raw, err := gormstore.New(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(raw.SecretSet(ctx, &store.Secret{Name: "git-token", Value: "plaintext"})).To(Succeed())
+
+		_, err = s.SecretGet(ctx, "git-token")
+		Expect(err).To(MatchError(ContainSubstring("decrypting secret")))
+	})
+})
@@ -72,7 +72,7 @@ func New(dsn string) (*Store, error) {
 		}
 	}
 
-	if err := db.AutoMigrate(&store.NodeGroup{}, &store.ManagedNode{}, &store.NodeCommand{}, &store.ArtifactRecord{}, &store.SecureBootKeySet{}, &store.BMCTarget{}, &store.Deployment{}, &store.Setting{}); err != nil {
+	if err := db.AutoMigrate(&store.NodeGroup{}, &store.ManagedNode{}, &store.NodeCommand{}, &store.ArtifactRecord{}, &store.SecureBootKeySet{}, &store.BMCTarget{}, &store.Deployment{}, &store.Setting{}, &store.BuildWorker{}, &store.BuildJob{}, &store.BuildSet{}, &store.Registry{}, &store.Channel{}, &store.ChannelEntry{}, &store.Secret{}, &store.BuildTemplate{}); err != nil {
 		return nil, fmt.Errorf("auto-migrating: %w", err)
 	}
 
@@ -552,6 +552,11 @@ func (s *Store) ListByNode(ctx context.Context, nodeID string) ([]*store.NodeCom
 	return cmds, nil
 }
 
+func (s *Store) UpdateArgs(ctx context.Context, id string, args map[string]string) error {
+	return s.db.WithContext(ctx).Model(&store.NodeCommand{ID: id}).Select("args").
+		Updates(&store.NodeCommand{Args: args}).Error
+}
+
 func (s *Store) CommandDelete(ctx context.Context, id string) error {
 	result := s.db.WithContext(ctx).Delete(&store.NodeCommand{}, "id = ?", id)
 	if result.RowsAffected == 0 {
@@ -586,7 +591,7 @@ func (s *Store) ArtifactGetByID(ctx context.Context, id string) (*store.Artifact
 
 func (s *Store) ArtifactList(ctx context.Context) ([]*store.ArtifactRecord, error) {
 	var records []*store.ArtifactRecord
-	if err := s.db.WithContext(ctx).Omit("Logs").Find(&records).Error; err != nil {
+	if err := s.db.WithContext(ctx).Omit("Logs", "Spec").Find(&records).Error; err != nil {
 		return nil, err
 	}
 	return records, nil
@@ -595,8 +600,10 @@ func (s *Store) ArtifactList(ctx context.Context) ([]*store.ArtifactRecord, erro
 func (s *Store) ArtifactUpdate(ctx context.Context, rec *store.ArtifactRecord) error {
 	// Omit the logs column so a caller's stale in-memory rec.Logs never
 	// clobbers concurrent AppendLog appends. AppendLog is the sole writer of
-	// that column; every other field remains updatable via this method.
-	return s.db.WithContext(ctx).Omit("logs").Save(rec).Error
+	// that column; build_set_id is likewise owned by BuildSetAttach, template_id
+	// by BuildTemplateAttach and spec by ArtifactSetSpec. Every other field
+	// remains updatable via this method.
+	return s.db.WithContext(ctx).Omit("logs", "build_set_id", "template_id", "spec").Save(rec).Error
 }
 
 // ArtifactUpdatePhaseMessage writes only the phase and message columns for the
@@ -622,6 +629,14 @@ func (s *Store) ArtifactUpdateFiles(ctx context.Context, id string, files []stri
 		Updates(store.ArtifactRecord{ArtifactFiles: files}).Error
 }
 
+// ArtifactUpdateStep writes only the step and step_progress columns for the
+// row with id. Select forces the write of a zero progress.
+func (s *Store) ArtifactUpdateStep(ctx context.Context, id, step string, progress int) error {
+	return s.db.WithContext(ctx).Model(&store.ArtifactRecord{}).Where("id = ?", id).
+		Select("Step", "StepProgress").
+		Updates(store.ArtifactRecord{Step: step, StepProgress: progress}).Error
+}
+
 // ArtifactClearUploadToken zeroes the upload_token column for the row with id.
 // watchCRPhase calls this on the terminal transition so a leaked token cannot
 // be replayed against Upload after the build has finished. Select forces the
@@ -632,6 +647,14 @@ func (s *Store) ArtifactClearUploadToken(ctx context.Context, id string) error {
 		Updates(store.ArtifactRecord{UploadToken: ""}).Error
 }
 
+// ArtifactSetUploadToken writes only the upload_token column for the row with
+// id, so a retried build's fresh token replaces the previous run's.
+func (s *Store) ArtifactSetUploadToken(ctx context.Context, id, token string) error {
+	return s.db.WithContext(ctx).Model(&store.ArtifactRecord{}).Where("id = ?", id).
+		Select("UploadToken").
+		Updates(store.ArtifactRecord{UploadToken: token}).Error
+}
+
 func (s *Store) ArtifactDelete(ctx context.Context, id string) error {
 	return s.db.WithContext(ctx).Delete(&store.ArtifactRecord{}, "id = ?", id).Error
 }
@@ -767,6 +790,243 @@ func (s *Store) BMCTargetDelete(ctx context.Context, id string) error {
 	return s.db.WithContext(ctx).Delete(&store.BMCTarget{}, "id = ?", id).Error
 }
 
+// --- ChannelStore ---
+
+func (s *Store) ChannelCreate(ctx context.Context, ch *store.Channel) error {
+	ch.ID = uuid.New().String()
+	return s.db.WithContext(ctx).Create(ch).Error
+}
+
+func (s *Store) ChannelGetByID(ctx context.Context, id string) (*store.Channel, error) {
+	var ch store.Channel
+	if err := s.db.WithContext(ctx).First(&ch, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+func (s *Store) ChannelGetByName(ctx context.Context, name string) (*store.Channel, error) {
+	var ch store.Channel
+	if err := s.db.WithContext(ctx).First(&ch, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+func (s *Store) ChannelList(ctx context.Context) ([]*store.Channel, error) {
+	var chans []*store.Channel
+	if err := s.db.WithContext(ctx).Order("name ASC").Find(&chans).Error; err != nil {
+		return nil, err
+	}
+	return chans, nil
+}
+
+// ChannelMove compares-and-sets the channel's artifact_id and appends the
+// history entry in the same transaction.
+func (s *Store) ChannelMove(ctx context.Context, channelID, expect string, entry *store.ChannelEntry) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&store.Channel{}).
+			Where("id = ? AND artifact_id = ?", channelID, expect).
+			Updates(map[string]any{"artifact_id": entry.ArtifactID, "updated_at": time.Now()})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return store.ErrChannelMoved
+		}
+		entry.ID = uuid.New().String()
+		entry.ChannelID = channelID
+		entry.PreviousArtifactID = expect
+		return tx.Create(entry).Error
+	})
+}
+
+func (s *Store) ChannelHistory(ctx context.Context, channelID string) ([]*store.ChannelEntry, error) {
+	var entries []*store.ChannelEntry
+	// Moves of one channel are serialised by ChannelMove's compare-and-set,
+	// so created_at orders them.
+	if err := s.db.WithContext(ctx).Where("channel_id = ?", channelID).
+		Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *Store) ChannelDelete(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&store.NodeGroup{}).Where("follow_channel_id = ?", id).
+			Update("follow_channel_id", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&store.ChannelEntry{}, "channel_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&store.Channel{}, "id = ?", id).Error
+	})
+}
+
+// --- RegistryStore ---
+
+func (s *Store) RegistryCreate(ctx context.Context, reg *store.Registry) error {
+	reg.ID = uuid.New().String()
+	row := *reg
+	if err := s.encryptRegistryPassword(&row); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Create(&row).Error
+}
+
+func (s *Store) RegistryGetByID(ctx context.Context, id string) (*store.Registry, error) {
+	var r store.Registry
+	if err := s.db.WithContext(ctx).First(&r, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if err := s.decryptRegistryPassword(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *Store) RegistryList(ctx context.Context) ([]*store.Registry, error) {
+	var regs []*store.Registry
+	if err := s.db.WithContext(ctx).Order("name ASC").Find(&regs).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range regs {
+		if err := s.decryptRegistryPassword(r); err != nil {
+			return nil, err
+		}
+	}
+	return regs, nil
+}
+
+func (s *Store) RegistryUpdate(ctx context.Context, reg *store.Registry) error {
+	row := *reg
+	if err := s.encryptRegistryPassword(&row); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Save(&row).Error
+}
+
+func (s *Store) RegistryDelete(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Delete(&store.Registry{}, "id = ?", id).Error
+}
+
+// encryptRegistryPassword and decryptRegistryPassword keep registry
+// credentials encrypted at rest. A value that does not decrypt is an error:
+// handing ciphertext to docker login as a password would only fail later.
+func (s *Store) encryptRegistryPassword(reg *store.Registry) error {
+	if s.cipher == nil || reg.Password == "" {
+		return nil
+	}
+	enc, err := s.cipher.Encrypt(reg.Password)
+	if err != nil {
+		return fmt.Errorf("encrypting registry password: %w", err)
+	}
+	reg.Password = enc
+	return nil
+}
+
+func (s *Store) decryptRegistryPassword(reg *store.Registry) error {
+	if s.cipher == nil || reg.Password == "" {
+		return nil
+	}
+	plain, err := s.cipher.Decrypt(reg.Password)
+	if err != nil {
+		return fmt.Errorf("decrypting registry password: %w", err)
+	}
+	reg.Password = plain
+	return nil
+}
+
+// --- ArtifactSpecStore ---
+
+// ArtifactSetSpec writes only the spec column, encrypted like BuildSet.Spec.
+func (s *Store) ArtifactSetSpec(ctx context.Context, id, spec string) error {
+	if s.cipher != nil && spec != "" {
+		enc, err := s.cipher.Encrypt(spec)
+		if err != nil {
+			return fmt.Errorf("encrypting artifact spec: %w", err)
+		}
+		spec = enc
+	}
+	return s.db.WithContext(ctx).Model(&store.ArtifactRecord{}).Where("id = ?", id).
+		Update("spec", spec).Error
+}
+
+func (s *Store) ArtifactGetSpec(ctx context.Context, id string) (string, error) {
+	var rec store.ArtifactRecord
+	if err := s.db.WithContext(ctx).Select("spec").First(&rec, "id = ?", id).Error; err != nil {
+		return "", err
+	}
+	if s.cipher == nil || rec.Spec == "" {
+		return rec.Spec, nil
+	}
+	plain, err := s.cipher.Decrypt(rec.Spec)
+	if err != nil {
+		return "", fmt.Errorf("decrypting artifact spec: %w", err)
+	}
+	return plain, nil
+}
+
+// --- SecretStore ---
+
+// SecretSet upserts the secret, keeping the original CreatedAt on update.
+func (s *Store) SecretSet(ctx context.Context, secret *store.Secret) error {
+	row := *secret
+	if s.cipher != nil && row.Value != "" {
+		enc, err := s.cipher.Encrypt(row.Value)
+		if err != nil {
+			return fmt.Errorf("encrypting secret: %w", err)
+		}
+		row.Value = enc
+	}
+	now := time.Now()
+	row.UpdatedAt = now
+	var existing store.Secret
+	switch err := s.db.WithContext(ctx).Select("created_at").First(&existing, "name = ?", row.Name).Error; {
+	case err == nil:
+		row.CreatedAt = existing.CreatedAt
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		row.CreatedAt = now
+	default:
+		return err
+	}
+	if err := s.db.WithContext(ctx).Save(&row).Error; err != nil {
+		return err
+	}
+	secret.CreatedAt, secret.UpdatedAt = row.CreatedAt, row.UpdatedAt
+	return nil
+}
+
+func (s *Store) SecretGet(ctx context.Context, name string) (*store.Secret, error) {
+	var secret store.Secret
+	if err := s.db.WithContext(ctx).First(&secret, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	if s.cipher != nil && secret.Value != "" {
+		plain, err := s.cipher.Decrypt(secret.Value)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting secret %q: %w", name, err)
+		}
+		secret.Value = plain
+	}
+	return &secret, nil
+}
+
+// SecretList returns every secret without its value, by name.
+func (s *Store) SecretList(ctx context.Context) ([]*store.Secret, error) {
+	var secrets []*store.Secret
+	if err := s.db.WithContext(ctx).Omit("Value").Order("name ASC").Find(&secrets).Error; err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+func (s *Store) SecretDelete(ctx context.Context, name string) error {
+	return s.db.WithContext(ctx).Delete(&store.Secret{}, "name = ?", name).Error
+}
+
 // --- DeploymentStore ---
 
 func (s *Store) DeploymentCreate(ctx context.Context, dep *store.Deployment) error {
@@ -858,6 +1118,151 @@ func (s *Store) SettingGetAll(ctx context.Context) (map[string]string, error) {
 	return out, nil
 }
 
+// --- BuildWorkerStore ---
+
+func (s *Store) BuildWorkerRegister(ctx context.Context, w *store.BuildWorker) error {
+	if w.ID == "" {
+		w.ID = uuid.New().String()
+	}
+	return s.db.WithContext(ctx).Create(w).Error
+}
+
+func (s *Store) BuildWorkerGetByID(ctx context.Context, id string) (*store.BuildWorker, error) {
+	var w store.BuildWorker
+	if err := s.db.WithContext(ctx).First(&w, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (s *Store) BuildWorkerGetByAPIKeyHash(ctx context.Context, hash string) (*store.BuildWorker, error) {
+	var w store.BuildWorker
+	if err := s.db.WithContext(ctx).First(&w, "api_key_hash = ?", hash).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (s *Store) BuildWorkerList(ctx context.Context) ([]*store.BuildWorker, error) {
+	var workers []*store.BuildWorker
+	if err := s.db.WithContext(ctx).Order("name").Find(&workers).Error; err != nil {
+		return nil, err
+	}
+	return workers, nil
+}
+
+// BuildWorkerHeartbeat writes only last_heartbeat and current_build_id so a
+// heartbeat never races an admin edit of the worker row.
+func (s *Store) BuildWorkerHeartbeat(ctx context.Context, id, currentBuildID string) error {
+	now := time.Now()
+	return s.db.WithContext(ctx).Model(&store.BuildWorker{}).Where("id = ?", id).
+		Updates(map[string]any{"last_heartbeat": &now, "current_build_id": currentBuildID}).Error
+}
+
+func (s *Store) BuildWorkerDelete(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Delete(&store.BuildWorker{}, "id = ?", id).Error
+}
+
+// --- BuildJobStore ---
+
+func (s *Store) BuildJobEnqueue(ctx context.Context, job *store.BuildJob) error {
+	return s.db.WithContext(ctx).Create(job).Error
+}
+
+func (s *Store) BuildJobGetByID(ctx context.Context, id string) (*store.BuildJob, error) {
+	var job store.BuildJob
+	if err := s.db.WithContext(ctx).First(&job, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *Store) BuildJobList(ctx context.Context) ([]*store.BuildJob, error) {
+	var jobs []*store.BuildJob
+	if err := s.db.WithContext(ctx).Omit("Spec").Order("created_at").Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (s *Store) BuildJobDelete(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Delete(&store.BuildJob{}, "id = ?", id).Error
+}
+
+// buildJobLeaseBatch bounds how many queued candidates one LeaseNext call
+// tries before giving up. Losing a candidate means another worker claimed it
+// in the meantime, so a handful of attempts is plenty; the worker polls again.
+const buildJobLeaseBatch = 5
+
+// BuildJobLeaseNext claims the oldest queued job for arch. Candidates are read
+// first and then claimed with a conditional UPDATE on an empty worker_id
+// plus a RowsAffected check, so two workers polling at once can never both
+// win the same row — the loser moves on to the next candidate. Mirrors
+// ClaimForDelivery.
+func (s *Store) BuildJobLeaseNext(ctx context.Context, workerID, arch string, until time.Time) (*store.BuildJob, error) {
+	var candidates []*store.BuildJob
+	if err := s.db.WithContext(ctx).Select("id").
+		Where("worker_id = ? AND arch = ?", "", arch).
+		Order("created_at").Limit(buildJobLeaseBatch).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	for _, cand := range candidates {
+		res := s.db.WithContext(ctx).Model(&store.BuildJob{}).
+			Where("id = ? AND worker_id = ?", cand.ID, "").
+			Updates(map[string]any{
+				"worker_id":        workerID,
+				"lease_expires_at": &until,
+				"attempts":         gorm.Expr("attempts + 1"),
+			})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			return s.BuildJobGetByID(ctx, cand.ID)
+		}
+	}
+	return nil, nil
+}
+
+// BuildJobRenewLease extends the deadline only while workerID still holds the
+// lease. A reaped or cancelled job matches no row, which the worker reads as
+// "stop building".
+func (s *Store) BuildJobRenewLease(ctx context.Context, id, workerID string, until time.Time) (bool, error) {
+	res := s.db.WithContext(ctx).Model(&store.BuildJob{}).
+		Where("id = ? AND worker_id = ?", id, workerID).
+		Update("lease_expires_at", &until)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// BuildJobRequeueExpired releases every lease whose deadline has passed. Each
+// release is a compare-and-set on (id, worker_id, lease_expires_at < now) so a
+// renewal that lands between the scan and the update keeps its lease.
+func (s *Store) BuildJobRequeueExpired(ctx context.Context, now time.Time) ([]string, error) {
+	var expired []*store.BuildJob
+	if err := s.db.WithContext(ctx).Select("id", "worker_id").
+		Where("worker_id <> ? AND lease_expires_at < ?", "", now).
+		Find(&expired).Error; err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, job := range expired {
+		res := s.db.WithContext(ctx).Model(&store.BuildJob{}).
+			Where("id = ? AND worker_id = ? AND lease_expires_at < ?", job.ID, job.WorkerID, now).
+			Updates(map[string]any{"worker_id": "", "lease_expires_at": nil})
+		if res.Error != nil {
+			return ids, res.Error
+		}
+		if res.RowsAffected == 1 {
+			ids = append(ids, job.ID)
+		}
+	}
+	return ids, nil
+}
+
 // Close closes the underlying database connection.
 func (s *Store) Close() error {
 	sqlDB, err := s.db.DB()
@@ -866,3 +1271,196 @@ func (s *Store) Close() error {
 	}
 	return sqlDB.Close()
 }
+
+// --- BuildSetStore ---
+
+func (s *Store) BuildSetCreate(ctx context.Context, set *store.BuildSet) error {
+	if set.ID == "" {
+		set.ID = uuid.New().String()
+	}
+	row := *set
+	if err := s.encryptSpec(&row); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return err
+	}
+	set.CreatedAt, set.UpdatedAt = row.CreatedAt, row.UpdatedAt
+	return nil
+}
+
+func (s *Store) BuildSetGetByID(ctx context.Context, id string) (*store.BuildSet, error) {
+	var set store.BuildSet
+	if err := s.db.WithContext(ctx).First(&set, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if err := s.decryptSpec(&set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// BuildSetList returns every set, newest first. Specs are not loaded; use
+// BuildSetGetByID when the spec is needed.
+func (s *Store) BuildSetList(ctx context.Context) ([]*store.BuildSet, error) {
+	var sets []*store.BuildSet
+	if err := s.db.WithContext(ctx).Omit("Spec").Order("created_at DESC").Find(&sets).Error; err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+// BuildSetDelete removes the set and unlinks its children, which stay behind
+// as ordinary artifacts.
+func (s *Store) BuildSetDelete(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&store.ArtifactRecord{}).Where("build_set_id = ?", id).
+			Update("build_set_id", "").Error; err != nil {
+			return err
+		}
+		return tx.Delete(&store.BuildSet{}, "id = ?", id).Error
+	})
+}
+
+func (s *Store) BuildSetAttach(ctx context.Context, setID, artifactID string) error {
+	return s.db.WithContext(ctx).Model(&store.ArtifactRecord{}).Where("id = ?", artifactID).
+		Update("build_set_id", setID).Error
+}
+
+func (s *Store) BuildSetChildren(ctx context.Context, setID string) ([]*store.ArtifactRecord, error) {
+	var records []*store.ArtifactRecord
+	if err := s.db.WithContext(ctx).Omit("Logs").Where("build_set_id = ?", setID).
+		Order("created_at ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// --- BuildTemplateStore ---
+
+func (s *Store) BuildTemplateCreate(ctx context.Context, tpl *store.BuildTemplate) error {
+	if tpl.ID == "" {
+		tpl.ID = uuid.New().String()
+	}
+	row := *tpl
+	if err := s.encryptTemplateSpec(&row); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return err
+	}
+	tpl.CreatedAt, tpl.UpdatedAt = row.CreatedAt, row.UpdatedAt
+	return nil
+}
+
+func (s *Store) BuildTemplateGetByID(ctx context.Context, id string) (*store.BuildTemplate, error) {
+	var tpl store.BuildTemplate
+	if err := s.db.WithContext(ctx).First(&tpl, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if err := s.decryptTemplateSpec(&tpl); err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+func (s *Store) BuildTemplateGetByName(ctx context.Context, name string) (*store.BuildTemplate, error) {
+	var tpl store.BuildTemplate
+	if err := s.db.WithContext(ctx).First(&tpl, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	if err := s.decryptTemplateSpec(&tpl); err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// BuildTemplateList returns every template by name. Specs are not loaded; use
+// BuildTemplateGetByID when the spec is needed.
+func (s *Store) BuildTemplateList(ctx context.Context) ([]*store.BuildTemplate, error) {
+	var tpls []*store.BuildTemplate
+	if err := s.db.WithContext(ctx).Omit("Spec").Order("name ASC").Find(&tpls).Error; err != nil {
+		return nil, err
+	}
+	return tpls, nil
+}
+
+func (s *Store) BuildTemplateUpdate(ctx context.Context, tpl *store.BuildTemplate) error {
+	row := *tpl
+	if err := s.encryptTemplateSpec(&row); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Save(&row).Error; err != nil {
+		return err
+	}
+	tpl.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+// BuildTemplateDelete removes the template and unlinks the artifacts built
+// from it.
+func (s *Store) BuildTemplateDelete(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&store.ArtifactRecord{}).Where("template_id = ?", id).
+			Update("template_id", "").Error; err != nil {
+			return err
+		}
+		return tx.Delete(&store.BuildTemplate{}, "id = ?", id).Error
+	})
+}
+
+func (s *Store) BuildTemplateAttach(ctx context.Context, templateID, artifactID string) error {
+	return s.db.WithContext(ctx).Model(&store.ArtifactRecord{}).Where("id = ?", artifactID).
+		Update("template_id", templateID).Error
+}
+
+func (s *Store) encryptTemplateSpec(tpl *store.BuildTemplate) error {
+	if s.cipher == nil || tpl.Spec == "" {
+		return nil
+	}
+	enc, err := s.cipher.Encrypt(tpl.Spec)
+	if err != nil {
+		return fmt.Errorf("encrypting build template spec: %w", err)
+	}
+	tpl.Spec = enc
+	return nil
+}
+
+func (s *Store) decryptTemplateSpec(tpl *store.BuildTemplate) error {
+	if s.cipher == nil || tpl.Spec == "" {
+		return nil
+	}
+	plain, err := s.cipher.Decrypt(tpl.Spec)
+	if err != nil {
+		return fmt.Errorf("decrypting build template spec: %w", err)
+	}
+	tpl.Spec = plain
+	return nil
+}
+
+// encryptSpec and decryptSpec encrypt the build-set spec, which may embed a
+// provisioning password. Build sets postdate the cipher, so unlike BMC
+// passwords there is no plaintext legacy row to fall back to.
+func (s *Store) encryptSpec(set *store.BuildSet) error {
+	if s.cipher == nil || set.Spec == "" {
+		return nil
+	}
+	enc, err := s.cipher.Encrypt(set.Spec)
+	if err != nil {
+		return fmt.Errorf("encrypting build set spec: %w", err)
+	}
+	set.Spec = enc
+	return nil
+}
+
+func (s *Store) decryptSpec(set *store.BuildSet) error {
+	if s.cipher == nil || set.Spec == "" {
+		return nil
+	}
+	plain, err := s.cipher.Decrypt(set.Spec)
+	if err != nil {
+		return fmt.Errorf("decrypting build set spec: %w", err)
+	}
+	set.Spec = plain
+	return nil
+}
@@ -729,6 +729,24 @@ var _ = Describe("Gorm Store", func() {
 		})
 	})
 
+	Describe("ArtifactUpdateStep", func() {
+		It("updates only step and step_progress, back to zero too", func() {
+			rec := &store.ArtifactRecord{ID: "art-step", Phase: store.ArtifactBuilding, BaseImage: "img"}
+			Expect(s.ArtifactCreate(ctx, rec)).To(Succeed())
+			Expect(s.ArtifactAppendLog(ctx, "art-step", "pulling\n")).To(Succeed())
+
+			Expect(s.ArtifactUpdateStep(ctx, "art-step", "dump-source", 40)).To(Succeed())
+			Expect(s.ArtifactUpdateStep(ctx, "art-step", "gen-iso", 0)).To(Succeed())
+
+			refreshed, err := s.ArtifactGetByID(ctx, "art-step")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refreshed.Step).To(Equal("gen-iso"))
+			Expect(refreshed.StepProgress).To(BeZero())
+			Expect(refreshed.Phase).To(Equal(store.ArtifactBuilding))
+			Expect(refreshed.Logs).To(Equal("pulling\n"))
+		})
+	})
+
 	Describe("ArtifactClearUploadToken", func() {
 		It("zeroes upload_token and leaves everything else alone", func() {
 			rec := &store.ArtifactRecord{
@@ -749,4 +767,24 @@ var _ = Describe("Gorm Store", func() {
 			Expect(refreshed.BaseImage).To(Equal("img"))
 		})
 	})
+
+	Describe("ArtifactSetUploadToken", func() {
+		It("replaces upload_token and leaves everything else alone", func() {
+			rec := &store.ArtifactRecord{
+				ID: "art-set", Phase: store.ArtifactPending, BaseImage: "img",
+				UploadToken: "old", Message: "retrying",
+			}
+			Expect(s.ArtifactCreate(ctx, rec)).To(Succeed())
+			Expect(s.ArtifactAppendLog(ctx, "art-set", "line\n")).To(Succeed())
+
+			Expect(s.ArtifactSetUploadToken(ctx, "art-set", "new")).To(Succeed())
+
+			refreshed, err := s.ArtifactGetByID(ctx, "art-set")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refreshed.UploadToken).To(Equal("new"))
+			Expect(refreshed.Phase).To(Equal(store.ArtifactPending))
+			Expect(refreshed.Message).To(Equal("retrying"))
+			Expect(refreshed.Logs).To(Equal("line\n"))
+		})
+	})
 })
@@ -0,0 +1,269 @@
+package artifactdiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/diskfs/go-diskfs/backend/file"
+	"github.com/diskfs/go-diskfs/filesystem/squashfs"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
+	"github.com/klauspost/compress/zstd"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type cpioEntry struct {
+	name string
+	mode int64
+	data string
+}
+
+// newc writes a newc cpio archive holding entries, with the trailer.
+func newc(entries ...cpioEntry) []byte {
+	var buf bytes.Buffer
+	write := func(e cpioEntry) {
+		name := e.name + "\x00"
+		fmt.Fprintf(&buf, "070701%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X",
+			0, e.mode, 0, 0, 1, 0, len(e.data), 0, 0, 0, 0, len(name), 0)
+		buf.WriteString(name)
+		buf.Write(make([]byte, pad4(int64(cpioHeaderSize+len(name)))))
+		buf.WriteString(e.data)
+		buf.Write(make([]byte, pad4(int64(len(e.data)))))
+	}
+	for _, e := range entries {
+		write(e)
+	}
+	write(cpioEntry{name: cpioTrailer})
+	return buf.Bytes()
+}
+
+// bzImage returns the start of an x86 kernel image whose setup header
+// names version.
+func bzImage(version string) []byte {
+	img := make([]byte, 0x600)
+	copy(img[0x202:], "HdrS")
+	binary.LittleEndian.PutUint16(img[0x20e:], 0x300)
+	copy(img[0x500:], version+" (builder@kairos) #1 SMP\x00")
+	return img
+}
+
+// writeSquashfs creates a squashfs image at p holding dirs and files. The
+// tree is laid out in the filesystem's workspace, which Finalize packs.
+func writeSquashfs(p string, dirs []string, files map[string]string) {
+	f, err := os.Create(p)
+	Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+	sq, err := squashfs.Create(file.New(f, false), 0, 0, 4096)
+	Expect(err).ToNot(HaveOccurred())
+	ws := sq.Workspace()
+	for _, d := range dirs {
+		Expect(os.MkdirAll(filepath.Join(ws, d), 0o755)).To(Succeed())
+	}
+	for name, data := range files {
+		Expect(os.WriteFile(filepath.Join(ws, name), []byte(data), 0o644)).To(Succeed())
+	}
+	Expect(sq.Finalize(squashfs.FinalizeOptions{})).To(Succeed())
+}
+
+var _ = Describe("LineDiff", func() {
+	It("returns nil for equal texts", func() {
+		Expect(LineDiff("a\nb\n", "a\nb\n")).To(BeNil())
+	})
+
+	It("marks removed and added lines and trims distant context", func() {
+		a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+		b := "1\n2\n3\n4\n5\n6\n7\n8\nnine\n10\n"
+		Expect(LineDiff(a, b)).To(Equal([]string{
+			"@@ 5 unchanged lines",
+			" 6", " 7", " 8", "-9", "+nine", " 10",
+		}))
+	})
+
+	It("diffs against an empty text", func() {
+		Expect(LineDiff("", "FROM alpine\n")).To(Equal([]string{"+FROM alpine"}))
+	})
+})
+
+var _ = Describe("Compare", func() {
+	It("reports differing inputs, packages and files", func() {
+		a := &Snapshot{
+			ID: "a",
+			Inputs: Inputs{
+				BaseImage: "ubuntu:24.04", BaseImageDigest: "sha256:aaa",
+				Dockerfile: "FROM x\nRUN a\n", HadronLayers: []string{"base"},
+			},
+			Packages: &sbom.Inventory{Packages: []sbom.Package{
+				{Type: "deb", Name: "curl", Version: "8.5.0-1", Arch: "amd64"},
+				{Type: "deb", Name: "vim", Version: "9.1", Arch: "amd64"},
+			}},
+			Kernel: "6.8.0-45-generic",
+			Rootfs: []File{
+				{Path: "/etc", Mode: "drwxr-xr-x", Size: 100},
+				{Path: "/etc/hostname", Mode: "-rw-r--r--", Size: 6},
+				{Path: "/etc/old", Mode: "-rw-r--r--", Size: 1},
+			},
+		}
+		b := &Snapshot{
+			ID: "b",
+			Inputs: Inputs{
+				BaseImage: "ubuntu:24.04", BaseImageDigest: "sha256:bbb",
+				Dockerfile: "FROM x\nRUN b\n", HadronLayers: []string{"base", "k3s"},
+			},
+			Packages: &sbom.Inventory{Packages: []sbom.Package{
+				{Type: "deb", Name: "curl", Version: "8.5.0-2", Arch: "amd64"},
+				{Type: "deb", Name: "htop", Version: "3.3", Arch: "amd64"},
+			}},
+			Kernel: "6.8.0-47-generic",
+			Rootfs: []File{
+				{Path: "/etc", Mode: "drwxr-xr-x", Size: 120},
+				{Path: "/etc/hostname", Mode: "-rw-r--r--", Size: 9},
+				{Path: "/etc/new", Mode: "-rw-r--r--", Size: 1},
+			},
+			Notes: []string{"initrd: missing"},
+		}
+
+		d := Compare(a, b)
+		Expect(d.Inputs).To(ConsistOf(
+			Change{Field: "baseImageDigest", A: "sha256:aaa", B: "sha256:bbb"},
+			Change{Field: "hadronLayers", A: "base", B: "base,k3s"},
+		))
+		Expect(d.Dockerfile).To(Equal([]string{" FROM x", "-RUN a", "+RUN b"}))
+		Expect(d.CloudConfig).To(BeEmpty())
+		Expect(d.Kernel).To(Equal(&Change{Field: "kernel", A: "6.8.0-45-generic", B: "6.8.0-47-generic"}))
+
+		Expect(d.Packages.Added).To(HaveLen(1))
+		Expect(d.Packages.Added[0].Name).To(Equal("htop"))
+		Expect(d.Packages.Removed).To(HaveLen(1))
+		Expect(d.Packages.Removed[0].Name).To(Equal("vim"))
+		Expect(d.Packages.Changed).To(Equal([]PackageChange{{Name: "curl", Arch: "amd64", A: "8.5.0-1", B: "8.5.0-2"}}))
+
+		Expect(d.Rootfs.Counts).To(Equal(FileCounts{Added: 1, Removed: 1, Changed: 1}))
+		Expect(d.Rootfs.Changed[0].Path).To(Equal("/etc/hostname"))
+		Expect(d.Initrd).To(BeNil())
+		Expect(d.Notes).To(Equal([]string{"B: initrd: missing"}))
+	})
+
+	It("caps long file lists but keeps exact counts", func() {
+		var b []File
+		for i := range maxFileChanges + 5 {
+			b = append(b, File{Path: fmt.Sprintf("/f%05d", i), Mode: "-rw-r--r--"})
+		}
+		d := compareFiles(nil, b)
+		Expect(d.Added).To(HaveLen(maxFileChanges))
+		Expect(d.Counts.Added).To(Equal(maxFileChanges + 5))
+		Expect(d.Truncated).To(BeTrue())
+	})
+})
+
+var _ = Describe("readInitrd", func() {
+	It("reads an uncompressed archive followed by a zstd one", func() {
+		early := newc(
+			cpioEntry{name: "kernel", mode: 0o40755},
+			cpioEntry{name: "kernel/x86/microcode/GenuineIntel.bin", mode: 0o100644, data: "ucode"},
+		)
+		main := newc(
+			cpioEntry{name: "init", mode: 0o120777, data: "usr/lib/systemd/systemd"},
+			cpioEntry{name: "etc/os-release", mode: 0o100644, data: "ID=kairos\n"},
+		)
+		var z bytes.Buffer
+		zw, err := zstd.NewWriter(&z)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = zw.Write(main)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(zw.Close()).To(Succeed())
+
+		img := append(early, make([]byte, 512-len(early)%512)...)
+		img = append(img, z.Bytes()...)
+		files, err := readInitrd(bytes.NewReader(img))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(4))
+		Expect(files[0].Path).To(Equal("/etc/os-release"))
+		Expect(files[0].SHA256).To(HaveLen(64))
+		Expect(files[1]).To(Equal(File{Path: "/init", Mode: "Lrwxrwxrwx", Size: 23, Link: "usr/lib/systemd/systemd"}))
+		Expect(files[2].Mode).To(Equal("drwxr-xr-x"))
+	})
+
+	It("rejects an unknown format", func() {
+		_, err := readInitrd(bytes.NewReader([]byte("not an initrd")))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("kernelVersion", func() {
+	It("reads the version from a bzImage setup header", func() {
+		v, err := kernelVersion(bytes.NewReader(bzImage("6.8.0-45-generic")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(v).To(Equal("6.8.0-45-generic"))
+	})
+
+	It("falls back to the Linux version banner", func() {
+		img := append(make([]byte, 4096), []byte("Linux version 6.6.31-v8+ (gcc) #1\x00")...)
+		v, err := kernelVersion(bytes.NewReader(img))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(v).To(Equal("6.6.31-v8+"))
+	})
+})
+
+var _ = Describe("Load", func() {
+	It("reads the manifest and netboot outputs of a build directory", func() {
+		dir := GinkgoT().TempDir()
+		writeSquashfs(filepath.Join(dir, "kairos.squashfs"),
+			[]string{"/etc", "/usr/lib/modules/6.8.0-45-generic"},
+			map[string]string{"/etc/hostname": "kairos\n"},
+		)
+		Expect(os.WriteFile(filepath.Join(dir, "kairos-kernel"), bzImage("6.8.0-45-generic"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "kairos-initrd"),
+			newc(cpioEntry{name: "init", mode: 0o100755, data: "#!/bin/sh\n"}), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, builder.ManifestFileName),
+			[]byte(`{"artifactId":"abc","inputs":{"baseImage":"ubuntu:24.04","baseImageDigest":"sha256:aaa"}}`), 0o644)).To(Succeed())
+
+		s := Load(dir, "", Inputs{BaseImage: "from-record"})
+		Expect(s.Notes).To(BeEmpty())
+		Expect(s.ID).To(Equal("abc"))
+		Expect(s.Inputs.BaseImage).To(Equal("from-record"))
+		Expect(s.Inputs.BaseImageDigest).To(Equal("sha256:aaa"))
+		Expect(s.Kernel).To(Equal("6.8.0-45-generic"))
+		Expect(s.Initrd).To(HaveLen(1))
+		Expect(s.Rootfs).To(ContainElement(File{Path: "/etc/hostname", Mode: "-rw-r--r--", Size: 7}))
+		Expect(modulesKernel(s.Rootfs)).To(Equal("6.8.0-45-generic"))
+	})
+
+	It("notes what a directory does not have", func() {
+		s := Load(GinkgoT().TempDir(), "x", Inputs{})
+		Expect(s.ID).To(Equal("x"))
+		Expect(s.Rootfs).To(BeNil())
+		Expect(s.Notes).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("WriteText", func() {
+	It("prints one section per difference", func() {
+		d := &Diff{
+			A: "a", B: "b",
+			Inputs:   []Change{{Field: "gitCommit", A: "", B: "abc123"}},
+			Packages: &PackageDiff{Changed: []PackageChange{{Name: "curl", A: "8.5.0-1", B: "8.5.0-2"}}},
+			Rootfs: &FileDiff{
+				Changed: []FileChange{{Path: "/etc/hostname", A: File{Size: 6}, B: File{Size: 9}}},
+				Counts:  FileCounts{Changed: 1},
+			},
+			Notes: []string{"A: initrd: missing"},
+		}
+		var buf bytes.Buffer
+		Expect(d.WriteText(&buf)).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring("gitCommit: (none) -> abc123"))
+		Expect(buf.String()).To(ContainSubstring("~ curl 8.5.0-1 -> 8.5.0-2"))
+		Expect(buf.String()).To(ContainSubstring("Rootfs (0 added, 0 removed, 1 changed)"))
+		Expect(buf.String()).To(ContainSubstring("~ /etc/hostname (6 -> 9 bytes)"))
+		Expect(buf.String()).NotTo(ContainSubstring("No differences"))
+	})
+
+	It("says so when nothing differs", func() {
+		var buf bytes.Buffer
+		Expect((&Diff{A: "a", B: "b"}).WriteText(&buf)).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring("No differences found."))
+	})
+})
@@ -0,0 +1,223 @@
+// Package artifactdiff compares two builds: what went in (base image and
+// its digest, Dockerfile, cloud-config, Hadron layers) and what came out
+// (installed packages from the SBOM, kernel version, initrd contents and the
+// files of the rootfs squashfs). It answers "what changed between the build
+// that works and the one that does not".
+//
+// Load reads what a build directory holds into a Snapshot; Compare turns
+// two snapshots into a Diff. Both work on a downloaded build as well as on
+// the server's artifacts directory.
+package artifactdiff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
+)
+
+// maxFileChanges caps each list of a FileDiff. A rootfs rebuilt on a new
+// base can differ in tens of thousands of files; the counts stay exact.
+const maxFileChanges = 2000
+
+// Diff is the structured difference from build A to build B. Added means
+// present in B only, removed in A only. An output section is nil when it
+// could not be compared (neither build has it, or reading it failed); Notes
+// says why.
+type Diff struct {
+	A string `json:"a"`
+	B string `json:"b"`
+	// Inputs lists the build inputs that differ.
+	Inputs []Change `json:"inputs"`
+	// Dockerfile and CloudConfig are line diffs, each line prefixed with
+	// "+", "-" or " " and runs of unchanged lines cut to a few lines of
+	// context around "@@" markers. Empty when the text is the same.
+	Dockerfile  []string     `json:"dockerfile,omitempty"`
+	CloudConfig []string     `json:"cloudConfig,omitempty"`
+	Packages    *PackageDiff `json:"packages,omitempty"`
+	Kernel      *Change      `json:"kernel,omitempty"`
+	Initrd      *FileDiff    `json:"initrd,omitempty"`
+	Rootfs      *FileDiff    `json:"rootfs,omitempty"`
+	Notes       []string     `json:"notes,omitempty"`
+}
+
+// Change is one value that differs between A and B.
+type Change struct {
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+// PackageDiff compares the package inventories of two SBOMs.
+type PackageDiff struct {
+	Added   []sbom.Package  `json:"added"`
+	Removed []sbom.Package  `json:"removed"`
+	Changed []PackageChange `json:"changed"`
+}
+
+// PackageChange is a package installed in both builds at different versions.
+type PackageChange struct {
+	Name string `json:"name"`
+	Arch string `json:"arch,omitempty"`
+	A    string `json:"a"`
+	B    string `json:"b"`
+}
+
+// FileDiff compares two file listings. The lists are sorted by path and
+// capped at maxFileChanges entries each; Counts are always complete.
+type FileDiff struct {
+	Added     []File       `json:"added"`
+	Removed   []File       `json:"removed"`
+	Changed   []FileChange `json:"changed"`
+	Counts    FileCounts   `json:"counts"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+// FileCounts are the sizes of a FileDiff's lists before capping.
+type FileCounts struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+}
+
+// FileChange is a path present in both builds whose type, mode, size, link
+// target or (for the initrd) content differs.
+type FileChange struct {
+	Path string `json:"path"`
+	A    File   `json:"a"`
+	B    File   `json:"b"`
+}
+
+// Compare returns the difference from a to b. Neither is modified.
+func Compare(a, b *Snapshot) *Diff {
+	d := &Diff{A: a.ID, B: b.ID, Inputs: []Change{}}
+	for _, n := range a.Notes {
+		d.Notes = append(d.Notes, "A: "+n)
+	}
+	for _, n := range b.Notes {
+		d.Notes = append(d.Notes, "B: "+n)
+	}
+
+	ia, ib := a.Inputs, b.Inputs
+	for _, f := range []struct {
+		name string
+		a, b string
+	}{
+		{"baseImage", ia.BaseImage, ib.BaseImage},
+		{"baseImageDigest", ia.BaseImageDigest, ib.BaseImageDigest},
+		{"kairosInitImage", ia.KairosInitImage, ib.KairosInitImage},
+		{"kairosInitImageDigest", ia.KairosInitImageDigest, ib.KairosInitImageDigest},
+		{"kairosVersion", ia.KairosVersion, ib.KairosVersion},
+		{"arch", ia.Arch, ib.Arch},
+		{"variant", ia.Variant, ib.Variant},
+		{"model", ia.Model, ib.Model},
+		{"kubernetesDistro", ia.KubernetesDistro, ib.KubernetesDistro},
+		{"kubernetesVersion", ia.KubernetesVersion, ib.KubernetesVersion},
+		{"hadronBase", ia.HadronBase, ib.HadronBase},
+		{"hadronLayers", strings.Join(ia.HadronLayers, ","), strings.Join(ib.HadronLayers, ",")},
+		{"hadronFirmware", strings.Join(ia.HadronFirmware, ","), strings.Join(ib.HadronFirmware, ",")},
+		{"dockerfileSha256", ia.DockerfileSHA256, ib.DockerfileSHA256},
+		{"gitUrl", ia.GitURL, ib.GitURL},
+		{"gitCommit", ia.GitCommit, ib.GitCommit},
+	} {
+		if f.a != f.b {
+			d.Inputs = append(d.Inputs, Change{Field: f.name, A: f.a, B: f.b})
+		}
+	}
+	d.Dockerfile = LineDiff(ia.Dockerfile, ib.Dockerfile)
+	d.CloudConfig = LineDiff(ia.CloudConfig, ib.CloudConfig)
+
+	if a.Packages != nil && b.Packages != nil {
+		d.Packages = comparePackages(a.Packages.Packages, b.Packages.Packages)
+	} else if a.Packages != nil || b.Packages != nil {
+		d.Notes = append(d.Notes, "packages: only one build has an SBOM")
+	}
+	if a.Kernel != "" && b.Kernel != "" {
+		if a.Kernel != b.Kernel {
+			d.Kernel = &Change{Field: "kernel", A: a.Kernel, B: b.Kernel}
+		}
+	} else if a.Kernel != "" || b.Kernel != "" {
+		d.Notes = append(d.Notes, "kernel: the version of only one build is known")
+	}
+	if a.Initrd != nil && b.Initrd != nil {
+		d.Initrd = compareFiles(a.Initrd, b.Initrd)
+	}
+	if a.Rootfs != nil && b.Rootfs != nil {
+		d.Rootfs = compareFiles(a.Rootfs, b.Rootfs)
+	}
+	return d
+}
+
+func comparePackages(a, b []sbom.Package) *PackageDiff {
+	key := func(p sbom.Package) string { return p.Type + "/" + p.Name + "/" + p.Arch }
+	inA := make(map[string]sbom.Package, len(a))
+	for _, p := range a {
+		inA[key(p)] = p
+	}
+	d := &PackageDiff{Added: []sbom.Package{}, Removed: []sbom.Package{}, Changed: []PackageChange{}}
+	seen := make(map[string]bool, len(b))
+	for _, p := range b {
+		k := key(p)
+		seen[k] = true
+		old, ok := inA[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, p)
+		case old.Version != p.Version:
+			d.Changed = append(d.Changed, PackageChange{Name: p.Name, Arch: p.Arch, A: old.Version, B: p.Version})
+		}
+	}
+	for _, p := range a {
+		if !seen[key(p)] {
+			d.Removed = append(d.Removed, p)
+		}
+	}
+	sort.Slice(d.Added, func(i, j int) bool { return d.Added[i].Name < d.Added[j].Name })
+	sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].Name < d.Removed[j].Name })
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].Name < d.Changed[j].Name })
+	return d
+}
+
+func compareFiles(a, b []File) *FileDiff {
+	inA := make(map[string]File, len(a))
+	for _, f := range a {
+		inA[f.Path] = f
+	}
+	d := &FileDiff{Added: []File{}, Removed: []File{}, Changed: []FileChange{}}
+	seen := make(map[string]bool, len(b))
+	for _, f := range b {
+		seen[f.Path] = true
+		old, ok := inA[f.Path]
+		switch {
+		case !ok:
+			d.Counts.Added++
+			if len(d.Added) < maxFileChanges {
+				d.Added = append(d.Added, f)
+			}
+		case !old.same(f):
+			d.Counts.Changed++
+			if len(d.Changed) < maxFileChanges {
+				d.Changed = append(d.Changed, FileChange{Path: f.Path, A: old, B: f})
+			}
+		}
+	}
+	for _, f := range a {
+		if !seen[f.Path] {
+			d.Counts.Removed++
+			if len(d.Removed) < maxFileChanges {
+				d.Removed = append(d.Removed, f)
+			}
+		}
+	}
+	d.Truncated = d.Counts.Added > len(d.Added) || d.Counts.Removed > len(d.Removed) || d.Counts.Changed > len(d.Changed)
+	return d
+}
+
+// same reports whether f and o look like the same file. Directory sizes
+// depend on how the filesystem was packed and are ignored.
+func (f File) same(o File) bool {
+	if f.Mode != o.Mode || f.Link != o.Link || f.SHA256 != o.SHA256 {
+		return false
+	}
+	return strings.HasPrefix(f.Mode, "d") || f.Size == o.Size
+}
@@ -0,0 +1,197 @@
+package artifactdiff
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// cpioTrailer names the last entry of a cpio archive.
+const cpioTrailer = "TRAILER!!!"
+
+// cpioHeaderSize is the size of a newc header: a 6 byte magic and 13
+// 8-digit hex fields.
+const cpioHeaderSize = 110
+
+// readInitrd lists the files of an initramfs. As the kernel does, it accepts
+// several cpio archives back to back (an uncompressed early microcode
+// archive is commonly prepended), the last of which may be gzip, zstd or xz
+// compressed. Regular files are hashed, so a changed module or script shows
+// up even when its size does not change.
+func readInitrd(r io.Reader) ([]File, error) {
+	files := map[string]File{}
+	if err := readArchives(bufio.NewReader(r), files); err != nil {
+		return nil, err
+	}
+	return sortedFiles(files), nil
+}
+
+func readArchives(br *bufio.Reader, files map[string]File) error {
+	for {
+		// Archives are padded to a 512 byte (or larger) boundary with NULs.
+		for {
+			b, err := br.ReadByte()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if b != 0 {
+				_ = br.UnreadByte()
+				break
+			}
+		}
+		magic, err := br.Peek(6)
+		if err != nil && len(magic) < 4 {
+			return fmt.Errorf("reading archive header: %w", err)
+		}
+		var dec io.Reader
+		switch {
+		case bytes.HasPrefix(magic, []byte("07070")):
+			if err := readCpio(br, files); err != nil {
+				return err
+			}
+			continue
+		case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+			gz, err := gzip.NewReader(br)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			dec = gz
+		case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+			zr, err := zstd.NewReader(br)
+			if err != nil {
+				return err
+			}
+			defer zr.Close()
+			dec = zr
+		case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0}):
+			xr, err := xz.NewReader(br)
+			if err != nil {
+				return err
+			}
+			dec = xr
+		default:
+			return fmt.Errorf("unsupported initramfs compression (magic %x)", magic[:4])
+		}
+		// A compressed archive is the last one; what it decompresses to can
+		// itself be several archives.
+		return readArchives(bufio.NewReader(dec), files)
+	}
+}
+
+// readCpio reads one newc archive up to and including its trailer.
+func readCpio(br *bufio.Reader, files map[string]File) error {
+	hdr := make([]byte, cpioHeaderSize)
+	for {
+		if _, err := io.ReadFull(br, hdr); err != nil {
+			return fmt.Errorf("reading cpio header: %w", err)
+		}
+		if m := string(hdr[:6]); m != "070701" && m != "070702" {
+			return fmt.Errorf("unsupported cpio format %q", m)
+		}
+		field := func(i int) (int64, error) {
+			return strconv.ParseInt(string(hdr[6+8*i:14+8*i]), 16, 64)
+		}
+		mode, err := field(1)
+		if err != nil {
+			return fmt.Errorf("cpio mode: %w", err)
+		}
+		size, err := field(6)
+		if err != nil {
+			return fmt.Errorf("cpio file size: %w", err)
+		}
+		nameSize, err := field(11)
+		if err != nil || nameSize <= 0 || nameSize > 4096 {
+			return errors.New("invalid cpio name size")
+		}
+		name := make([]byte, nameSize)
+		if _, err := io.ReadFull(br, name); err != nil {
+			return fmt.Errorf("reading cpio name: %w", err)
+		}
+		if err := skip(br, pad4(cpioHeaderSize+nameSize)); err != nil {
+			return err
+		}
+		entry := string(bytes.TrimRight(name, "\x00"))
+		if entry == cpioTrailer {
+			return nil
+		}
+
+		f := File{Path: path.Clean("/" + entry), Mode: cpioMode(mode).String(), Size: size}
+		switch mode & 0o170000 {
+		case 0o100000:
+			h := sha256.New()
+			if _, err := io.CopyN(h, br, size); err != nil {
+				return fmt.Errorf("reading %s: %w", entry, err)
+			}
+			f.SHA256 = hex.EncodeToString(h.Sum(nil))
+		case 0o120000:
+			target := make([]byte, size)
+			if _, err := io.ReadFull(br, target); err != nil {
+				return fmt.Errorf("reading %s: %w", entry, err)
+			}
+			f.Link = string(target)
+		default:
+			if err := skip(br, size); err != nil {
+				return err
+			}
+		}
+		if err := skip(br, pad4(size)); err != nil {
+			return err
+		}
+		// A later archive overrides an earlier one, as when the kernel
+		// unpacks them.
+		files[f.Path] = f
+	}
+}
+
+func pad4(n int64) int64 {
+	return (4 - n%4) % 4
+}
+
+func skip(r io.Reader, n int64) error {
+	_, err := io.CopyN(io.Discard, r, n)
+	return err
+}
+
+// cpioMode converts a Unix st_mode to an fs.FileMode.
+func cpioMode(m int64) fs.FileMode {
+	mode := fs.FileMode(m & 0o777)
+	switch m & 0o170000 {
+	case 0o040000:
+		mode |= fs.ModeDir
+	case 0o120000:
+		mode |= fs.ModeSymlink
+	case 0o020000:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	case 0o060000:
+		mode |= fs.ModeDevice
+	case 0o010000:
+		mode |= fs.ModeNamedPipe
+	case 0o140000:
+		mode |= fs.ModeSocket
+	}
+	if m&0o4000 != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if m&0o2000 != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if m&0o1000 != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode
+}
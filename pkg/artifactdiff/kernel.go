@@ -0,0 +1,80 @@
+package artifactdiff
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+// kernelScanLimit bounds how much of a kernel image without a version in
+// its header is searched for the banner.
+const kernelScanLimit = 128 << 20
+
+var linuxBanner = []byte("Linux version ")
+
+// kernelVersion reads the release of a kernel image, e.g. "6.8.0-45-generic".
+// An x86 bzImage names it in its setup header; other images (arm64 Image,
+// possibly gzipped) carry it in the "Linux version" banner, which is searched
+// for instead. It returns "" when neither is found.
+func kernelVersion(r io.Reader) (string, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	head, err := br.Peek(64 << 10)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", err
+	}
+	// bzImage: "HdrS" at 0x202, and at 0x20e the offset (from 0x200) of
+	// the NUL-terminated version string.
+	if len(head) > 0x210 && string(head[0x202:0x206]) == "HdrS" {
+		off := int(binary.LittleEndian.Uint16(head[0x20e:])) + 0x200
+		if off < len(head) {
+			s := head[off:]
+			if i := bytes.IndexByte(s, 0); i >= 0 {
+				s = s[:i]
+			}
+			if fields := strings.Fields(string(s)); len(fields) > 0 {
+				return fields[0], nil
+			}
+		}
+	}
+	var src io.Reader = br
+	if bytes.HasPrefix(head, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return "", err
+		}
+		defer gz.Close()
+		src = gz
+	}
+	return scanBanner(io.LimitReader(src, kernelScanLimit))
+}
+
+// scanBanner finds "Linux version <release>" in r.
+func scanBanner(r io.Reader) (string, error) {
+	buf := make([]byte, 1<<20)
+	keep := 0
+	for {
+		n, err := r.Read(buf[keep:])
+		data := buf[:keep+n]
+		if i := bytes.Index(data, linuxBanner); i >= 0 {
+			rest := data[i+len(linuxBanner):]
+			if end := bytes.IndexAny(rest, " \x00"); end > 0 {
+				return string(rest[:end]), nil
+			}
+			if err != nil {
+				return string(rest), nil
+			}
+		}
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		// Keep a tail long enough to hold a banner split across reads.
+		keep = min(len(data), 256)
+		copy(buf, data[len(data)-keep:])
+	}
+}
@@ -0,0 +1,143 @@
+package artifactdiff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/diskfs/go-diskfs/backend"
+	"github.com/diskfs/go-diskfs/backend/file"
+	"github.com/diskfs/go-diskfs/filesystem/iso9660"
+	"github.com/diskfs/go-diskfs/filesystem/squashfs"
+)
+
+// isoBlockSize is the logical block size of an ISO 9660 image.
+const isoBlockSize = 2048
+
+// File is one entry of a rootfs or initrd listing. Mode is in ls -l form
+// ("drwxr-xr-x", "Lrwxrwxrwx"). SHA256 is only filled for initrd files;
+// hashing a whole rootfs would mean decompressing all of it.
+type File struct {
+	Path   string `json:"path"`
+	Mode   string `json:"mode"`
+	Size   int64  `json:"size"`
+	Link   string `json:"link,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// readSquashfs lists the files of the squashfs image that starts at offset
+// start in b and is size bytes long.
+func readSquashfs(b backend.Storage, size, start int64) ([]File, error) {
+	sq, err := squashfs.Read(b, size, start, 0)
+	if err != nil {
+		return nil, fmt.Errorf("reading squashfs: %w", err)
+	}
+	var files []File
+	err = fs.WalkDir(sq, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f := File{Path: path.Clean("/" + p), Mode: info.Mode().String(), Size: info.Size()}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if l, ok := d.(interface{ Readlink() (string, error) }); ok {
+				f.Link, _ = l.Readlink()
+			}
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing squashfs: %w", err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// isoImage is a Kairos live ISO opened for reading its rootfs, kernel and
+// initrd in place.
+type isoImage struct {
+	f  *os.File
+	b  backend.Storage
+	fs *iso9660.FileSystem
+}
+
+func openISO(p string) (*isoImage, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	b := file.New(f, true)
+	isofs, err := iso9660.Read(b, st.Size(), 0, isoBlockSize)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("reading ISO: %w", err)
+	}
+	return &isoImage{f: f, b: b, fs: isofs}, nil
+}
+
+func (im *isoImage) Close() error {
+	return im.f.Close()
+}
+
+func (im *isoImage) open(p string) (io.ReadCloser, error) {
+	return im.fs.OpenFile(p, os.O_RDONLY)
+}
+
+// rootfs lists the /rootfs.squashfs of the ISO. ISO 9660 stores a file in
+// one contiguous extent, so the squashfs is read where it lies.
+func (im *isoImage) rootfs() ([]File, error) {
+	f, err := im.fs.OpenFile("/rootfs.squashfs", os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	isoFile, ok := f.(*iso9660.File)
+	if !ok {
+		return nil, errors.New("unexpected ISO file type")
+	}
+	st, err := isoFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return readSquashfs(im.b, st.Size(), int64(isoFile.Location())*isoBlockSize)
+}
+
+// modulesKernel returns the kernel release(s) a rootfs has modules for,
+// which stands in for the version when the kernel image is not at hand.
+func modulesKernel(files []File) string {
+	var versions []string
+	for _, f := range files {
+		for _, dir := range []string{"/lib/modules/", "/usr/lib/modules/"} {
+			rel, ok := strings.CutPrefix(f.Path, dir)
+			if ok && rel != "" && !strings.Contains(rel, "/") && strings.HasPrefix(f.Mode, "d") {
+				versions = append(versions, rel)
+			}
+		}
+	}
+	sort.Strings(versions)
+	return strings.Join(slices.Compact(versions), ", ")
+}
+
+func sortedFiles(m map[string]File) []File {
+	out := make([]File, 0, len(m))
+	for _, f := range m {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
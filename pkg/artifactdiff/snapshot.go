@@ -0,0 +1,233 @@
+package artifactdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/diskfs/go-diskfs/backend/file"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
+)
+
+// Inputs is what a build was asked to do. The server fills it from the
+// artifact record; for a downloaded build Load recovers what the build
+// manifest records.
+type Inputs struct {
+	BaseImage             string   `json:"baseImage,omitempty"`
+	BaseImageDigest       string   `json:"baseImageDigest,omitempty"`
+	KairosInitImage       string   `json:"kairosInitImage,omitempty"`
+	KairosInitImageDigest string   `json:"kairosInitImageDigest,omitempty"`
+	KairosVersion         string   `json:"kairosVersion,omitempty"`
+	Arch                  string   `json:"arch,omitempty"`
+	Variant               string   `json:"variant,omitempty"`
+	Model                 string   `json:"model,omitempty"`
+	KubernetesDistro      string   `json:"kubernetesDistro,omitempty"`
+	KubernetesVersion     string   `json:"kubernetesVersion,omitempty"`
+	HadronBase            string   `json:"hadronBase,omitempty"`
+	HadronLayers          []string `json:"hadronLayers,omitempty"`
+	HadronFirmware        []string `json:"hadronFirmware,omitempty"`
+	Dockerfile            string   `json:"dockerfile,omitempty"`
+	DockerfileSHA256      string   `json:"dockerfileSha256,omitempty"`
+	CloudConfig           string   `json:"cloudConfig,omitempty"`
+	GitURL                string   `json:"gitUrl,omitempty"`
+	GitCommit             string   `json:"gitCommit,omitempty"`
+}
+
+// Snapshot is everything Compare looks at for one build. The output fields
+// are nil or empty when the build directory does not have them; Notes
+// records what could not be read and why.
+type Snapshot struct {
+	ID       string
+	Inputs   Inputs
+	Packages *sbom.Inventory
+	Kernel   string
+	Initrd   []File
+	Rootfs   []File
+	Notes    []string
+}
+
+// Load reads the build in dir. in holds the inputs already known (from the
+// artifact record); empty fields are filled from the build manifest. The
+// rootfs, kernel and initrd are taken from the netboot outputs when the
+// build has them, otherwise from inside its ISO. Load does not fail: a part
+// that cannot be read is left out and noted.
+func Load(dir, id string, in Inputs) *Snapshot {
+	s := &Snapshot{ID: id, Inputs: in}
+	s.loadManifest(dir)
+	if s.ID == "" {
+		s.ID = filepath.Base(dir)
+	}
+	s.loadSBOM(dir)
+	s.loadOutputs(dir)
+	return s
+}
+
+func (s *Snapshot) note(format string, args ...any) {
+	s.Notes = append(s.Notes, fmt.Sprintf(format, args...))
+}
+
+func (s *Snapshot) loadManifest(dir string) {
+	data, err := os.ReadFile(filepath.Join(dir, builder.ManifestFileName))
+	if err != nil {
+		return
+	}
+	var m builder.BuildManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		s.note("%s: %v", builder.ManifestFileName, err)
+		return
+	}
+	if s.ID == "" {
+		s.ID = m.ArtifactID
+	}
+	in := &s.Inputs
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&in.BaseImage, m.Inputs.BaseImage},
+		{&in.BaseImageDigest, m.Inputs.BaseImageDigest},
+		{&in.KairosInitImage, m.Inputs.KairosInitImage},
+		{&in.KairosInitImageDigest, m.Inputs.KairosInitImageDigest},
+		{&in.DockerfileSHA256, m.Inputs.DockerfileSHA256},
+		{&in.GitURL, m.Inputs.GitURL},
+		{&in.GitCommit, m.Inputs.GitCommit},
+		{&in.Arch, m.Inputs.Arch},
+	} {
+		if *f.dst == "" {
+			*f.dst = f.src
+		}
+	}
+}
+
+func (s *Snapshot) loadSBOM(dir string) {
+	for _, format := range []string{sbom.FormatSPDX, sbom.FormatCycloneDX} {
+		name := sbom.FileName(format)
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		inv, err := sbom.Read(f)
+		f.Close()
+		if err != nil {
+			s.note("%s: %v", name, err)
+			return
+		}
+		s.Packages = inv
+		return
+	}
+}
+
+func (s *Snapshot) loadOutputs(dir string) {
+	squash, kernel, initrd := first(dir, "*.squashfs"), first(dir, "*-kernel"), first(dir, "*-initrd")
+	if iso := first(dir, "*.iso"); iso != "" && (squash == "" || kernel == "" || initrd == "") {
+		s.loadISO(iso, squash == "", kernel == "", initrd == "")
+	}
+	if squash != "" {
+		if files, err := readSquashfsFile(squash); err != nil {
+			s.note("rootfs: %v", err)
+		} else {
+			s.Rootfs = files
+		}
+	}
+	if kernel != "" {
+		if f, err := os.Open(kernel); err != nil {
+			s.note("kernel: %v", err)
+		} else {
+			s.setKernel(kernelVersion(f))
+			f.Close()
+		}
+	}
+	if initrd != "" {
+		if f, err := os.Open(initrd); err != nil {
+			s.note("initrd: %v", err)
+		} else {
+			s.setInitrd(readInitrd(f))
+			f.Close()
+		}
+	}
+	if s.Rootfs == nil {
+		s.note("rootfs: the build has neither a netboot squashfs nor an ISO to read it from")
+	}
+	if s.Kernel == "" && s.Rootfs != nil {
+		s.Kernel = modulesKernel(s.Rootfs)
+	}
+}
+
+// loadISO reads from the ISO what the netboot outputs did not provide.
+func (s *Snapshot) loadISO(p string, rootfs, kernel, initrd bool) {
+	im, err := openISO(p)
+	if err != nil {
+		s.note("%s: %v", filepath.Base(p), err)
+		return
+	}
+	defer im.Close()
+	if rootfs {
+		if files, err := im.rootfs(); err != nil {
+			s.note("rootfs: %v", err)
+		} else {
+			s.Rootfs = files
+		}
+	}
+	if kernel {
+		if f, err := im.open("/boot/kernel"); err != nil {
+			s.note("kernel: %v", err)
+		} else {
+			s.setKernel(kernelVersion(f))
+			f.Close()
+		}
+	}
+	if initrd {
+		if f, err := im.open("/boot/initrd"); err != nil {
+			s.note("initrd: %v", err)
+		} else {
+			s.setInitrd(readInitrd(f))
+			f.Close()
+		}
+	}
+}
+
+func (s *Snapshot) setKernel(v string, err error) {
+	if err != nil {
+		s.note("kernel: %v", err)
+		return
+	}
+	s.Kernel = v
+}
+
+func (s *Snapshot) setInitrd(files []File, err error) {
+	if err != nil {
+		s.note("initrd: %v", err)
+		return
+	}
+	s.Initrd = files
+}
+
+func readSquashfsFile(p string) ([]File, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return readSquashfs(file.New(f, true), st.Size(), 0)
+}
+
+// first returns the first file in dir matching pattern, skipping the
+// partial files of an upload in progress.
+func first(dir, pattern string) string {
+	matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+	sort.Strings(matches)
+	for _, m := range matches {
+		if !strings.HasPrefix(filepath.Base(m), ".") {
+			return m
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package artifactdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is how many unchanged lines LineDiff keeps around a change.
+const diffContext = 3
+
+// maxDiffLines bounds the quadratic line matching. Longer texts that differ
+// are reported as replaced wholesale.
+const maxDiffLines = 4000
+
+// LineDiff returns the lines of a and b as a diff: "-" for lines only in a,
+// "+" for lines only in b and " " for the context kept around each change.
+// Skipped unchanged lines are replaced by a single "@@" line. It returns nil
+// when a and b are equal.
+func LineDiff(a, b string) []string {
+	if a == b {
+		return nil
+	}
+	la, lb := splitLines(a), splitLines(b)
+	var ops []string
+	if len(la) > maxDiffLines || len(lb) > maxDiffLines {
+		for _, l := range la {
+			ops = append(ops, "-"+l)
+		}
+		for _, l := range lb {
+			ops = append(ops, "+"+l)
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of la[i:]
+	// and lb[j:].
+	lcs := make([][]int, len(la)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(lb)+1)
+	}
+	for i := len(la) - 1; i >= 0; i-- {
+		for j := len(lb) - 1; j >= 0; j-- {
+			if la[i] == lb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(la) || j < len(lb) {
+		switch {
+		case i < len(la) && j < len(lb) && la[i] == lb[j]:
+			ops = append(ops, " "+la[i])
+			i++
+			j++
+		case i < len(la) && (j == len(lb) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, "-"+la[i])
+			i++
+		default:
+			ops = append(ops, "+"+lb[j])
+			j++
+		}
+	}
+	return trimContext(ops)
+}
+
+// trimContext drops unchanged lines further than diffContext from any
+// change, marking each gap with "@@ n unchanged lines".
+func trimContext(ops []string) []string {
+	keep := make([]bool, len(ops))
+	changed := false
+	for i, op := range ops {
+		if op[0] == ' ' {
+			continue
+		}
+		changed = true
+		for k := max(0, i-diffContext); k <= min(len(ops)-1, i+diffContext); k++ {
+			keep[k] = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	var out []string
+	skipped := 0
+	for i, op := range ops {
+		if keep[i] {
+			if skipped > 0 {
+				out = append(out, fmt.Sprintf("@@ %d unchanged lines", skipped))
+				skipped = 0
+			}
+			out = append(out, op)
+			continue
+		}
+		skipped++
+	}
+	if skipped > 0 {
+		out = append(out, fmt.Sprintf("@@ %d unchanged lines", skipped))
+	}
+	return out
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,107 @@
+package artifactdiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WriteText writes d for a terminal: one section per part that differs,
+// "+" for what B adds and "-" for what it drops.
+func (d *Diff) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "A: %s\nB: %s\n", d.A, d.B)
+	same := true
+	section := func(title string) {
+		same = false
+		fmt.Fprintf(bw, "\n%s\n", title)
+	}
+
+	if len(d.Inputs) > 0 {
+		section("Inputs")
+		for _, c := range d.Inputs {
+			fmt.Fprintf(bw, "  %s: %s -> %s\n", c.Field, orNone(c.A), orNone(c.B))
+		}
+	}
+	for _, t := range []struct {
+		title string
+		lines []string
+	}{{"Dockerfile", d.Dockerfile}, {"Cloud config", d.CloudConfig}} {
+		if len(t.lines) == 0 {
+			continue
+		}
+		section(t.title)
+		for _, l := range t.lines {
+			fmt.Fprintf(bw, "  %s\n", l)
+		}
+	}
+	if p := d.Packages; p != nil && len(p.Added)+len(p.Removed)+len(p.Changed) > 0 {
+		section(fmt.Sprintf("Packages (%d added, %d removed, %d changed)", len(p.Added), len(p.Removed), len(p.Changed)))
+		for _, c := range p.Changed {
+			fmt.Fprintf(bw, "  ~ %s %s -> %s\n", c.Name, c.A, c.B)
+		}
+		for _, a := range p.Added {
+			fmt.Fprintf(bw, "  + %s %s\n", a.Name, a.Version)
+		}
+		for _, r := range p.Removed {
+			fmt.Fprintf(bw, "  - %s %s\n", r.Name, r.Version)
+		}
+	}
+	if d.Kernel != nil {
+		section("Kernel")
+		fmt.Fprintf(bw, "  %s -> %s\n", d.Kernel.A, d.Kernel.B)
+	}
+	for _, t := range []struct {
+		title string
+		files *FileDiff
+	}{{"Initrd", d.Initrd}, {"Rootfs", d.Rootfs}} {
+		f := t.files
+		if f == nil || f.Counts == (FileCounts{}) {
+			continue
+		}
+		section(fmt.Sprintf("%s (%d added, %d removed, %d changed)", t.title, f.Counts.Added, f.Counts.Removed, f.Counts.Changed))
+		for _, c := range f.Changed {
+			fmt.Fprintf(bw, "  ~ %s (%s)\n", c.Path, describeChange(c.A, c.B))
+		}
+		for _, a := range f.Added {
+			fmt.Fprintf(bw, "  + %s\n", a.Path)
+		}
+		for _, r := range f.Removed {
+			fmt.Fprintf(bw, "  - %s\n", r.Path)
+		}
+		if f.Truncated {
+			fmt.Fprintf(bw, "  ... lists cut at %d entries each\n", maxFileChanges)
+		}
+	}
+	if same {
+		fmt.Fprintln(bw, "\nNo differences found.")
+	}
+	if len(d.Notes) > 0 {
+		fmt.Fprintln(bw, "\nNotes")
+		for _, n := range d.Notes {
+			fmt.Fprintf(bw, "  %s\n", n)
+		}
+	}
+	return bw.Flush()
+}
+
+// describeChange says briefly how a file changed.
+func describeChange(a, b File) string {
+	switch {
+	case a.Mode != b.Mode:
+		return a.Mode + " -> " + b.Mode
+	case a.Link != b.Link:
+		return "-> " + b.Link
+	case a.Size != b.Size:
+		return fmt.Sprintf("%d -> %d bytes", a.Size, b.Size)
+	default:
+		return "content"
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -23,6 +25,28 @@ func AuthNodeID(c echo.Context) string {
 	return id
 }
 
+// ContextKeyWorkerID is the key used to store the authenticated build worker ID
+// in the echo context.
+const ContextKeyWorkerID = "workerID"
+
+// AuthWorkerID returns the authenticated build worker ID set by
+// WorkerAPIKeyMiddleware, or "" when the request was not authenticated as a
+// worker.
+func AuthWorkerID(c echo.Context) string {
+	id, _ := c.Get(ContextKeyWorkerID).(string)
+	return id
+}
+
+// HashWorkerAPIKey is the digest stored in place of a build worker's API key.
+// The plaintext is returned once from registration and never persisted, so
+// the middleware hashes the presented bearer and looks the worker up by
+// digest. sha256 is sufficient because the key is 32 random bytes, not a
+// human-chosen password.
+func HashWorkerAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
 // RequireNodeMatch enforces that the authenticated node (set by
 // NodeAPIKeyMiddleware) matches the nodeID path parameter. It is meant to wrap
 // agent-group routes carrying a :nodeID segment so a node can only act on its
@@ -79,6 +103,26 @@ func NodeAPIKeyMiddleware(nodeStore store.NodeStore) echo.MiddlewareFunc {
 	}
 }
 
+// WorkerAPIKeyMiddleware returns an Echo middleware that checks the
+// Authorization header for a Bearer token matching a build worker's API key.
+// On success it sets the worker ID in the context.
+func WorkerAPIKeyMiddleware(workers store.BuildWorkerStore) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := extractBearer(c.Request().Header.Get("Authorization"))
+			if token == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			}
+			worker, err := workers.GetByAPIKeyHash(c.Request().Context(), HashWorkerAPIKey(token))
+			if err != nil || worker == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			}
+			c.Set(ContextKeyWorkerID, worker.ID)
+			return next(c)
+		}
+	}
+}
+
 // AgentOrAdminMiddleware authenticates a request as EITHER an admin (bearer
 // token == admin password) OR a node (bearer token == a node API key). It is
 // used for the two routes that are legitimately shared between the agent and
@@ -0,0 +1,149 @@
+// Package boards holds the board profiles the raw disk builder uses for single
+// board computers: the firmware and boot files a board reads from its boot
+// partition, the bootloader blobs its boot ROM loads from raw disk offsets and
+// the partition offsets those blobs need.
+//
+// Profiles are YAML. The built-in ones live in profiles/ and operators can load
+// their own from a directory with LoadProfileDir, as with the Redfish quirk
+// profiles.
+package boards
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Profile describes how the raw disk of a board is built.
+type Profile struct {
+	// Name identifies the profile (required).
+	Name string `yaml:"name"`
+	// Models are the KAIROS_MODEL values of the source's /etc/kairos-release
+	// the profile is used for, as path.Match patterns such as "rpi*". It
+	// defaults to the name.
+	Models []string `yaml:"models,omitempty"`
+	// Firmware is copied into the boot partition.
+	Firmware []Firmware `yaml:"firmware,omitempty"`
+	// Files are written into the boot partition after the firmware, replacing
+	// any firmware file at the same path: config.txt, extlinux/extlinux.conf,
+	// a u-boot script and the like.
+	Files []File `yaml:"files,omitempty"`
+	// Bootloader blobs are written at raw offsets of the disk once it is
+	// partitioned.
+	Bootloader []Blob `yaml:"bootloader,omitempty"`
+	// Layout is the partition layout the board needs, for instance to keep
+	// the partitions clear of its bootloader blobs. disk.layout, when set,
+	// takes precedence.
+	Layout *schema.Layout `yaml:"layout,omitempty"`
+}
+
+// Firmware is a file or directory of the build host copied into the boot
+// partition.
+type Firmware struct {
+	// Source is an absolute path. The contents of a directory are copied.
+	Source string `yaml:"source"`
+	// Target is where the source goes, relative to the root of the boot
+	// partition, which is the default.
+	Target string `yaml:"target,omitempty"`
+}
+
+// File is a file written into the boot partition.
+type File struct {
+	// Path is relative to the root of the boot partition.
+	Path    string `yaml:"path"`
+	Content string `yaml:"content"`
+}
+
+// Blob is a bootloader file written at a raw offset of the disk, as dd does.
+type Blob struct {
+	// Source is the absolute path of the file on the build host.
+	Source string `yaml:"source"`
+	// Offset is where the blob is written, in bytes from the start of the disk.
+	Offset uint64 `yaml:"offset,omitempty"`
+	// Skip is the number of bytes of the source left out from its start.
+	Skip uint64 `yaml:"skip,omitempty"`
+	// Length is the number of bytes written, the rest of the source when zero.
+	Length uint64 `yaml:"length,omitempty"`
+}
+
+// ParseProfile decodes and strictly validates a YAML board profile. Unknown
+// keys are rejected, as are relative sources and paths leaving the boot
+// partition.
+func ParseProfile(data []byte) (*Profile, error) {
+	var p Profile
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parsing board profile: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("validating board profile %q: %w", p.Name, err)
+	}
+	return &p, nil
+}
+
+func (p *Profile) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	for i, m := range p.Models {
+		if _, err := path.Match(m, ""); err != nil || m == "" {
+			return fmt.Errorf("models[%d]: %q is not a valid pattern", i, m)
+		}
+	}
+	for i, f := range p.Firmware {
+		if !filepath.IsAbs(f.Source) {
+			return fmt.Errorf("firmware[%d]: source %q must be an absolute path", i, f.Source)
+		}
+		if f.Target != "" && !insideBootPartition(f.Target) {
+			return fmt.Errorf("firmware[%d]: target %q must be a relative path inside the boot partition", i, f.Target)
+		}
+	}
+	for i, f := range p.Files {
+		if f.Path == "" || !insideBootPartition(f.Path) {
+			return fmt.Errorf("files[%d]: path %q must be a relative path inside the boot partition", i, f.Path)
+		}
+	}
+	for i, b := range p.Bootloader {
+		if !filepath.IsAbs(b.Source) {
+			return fmt.Errorf("bootloader[%d]: source %q must be an absolute path", i, b.Source)
+		}
+	}
+	if p.Layout != nil {
+		if err := p.Layout.Validate(); err != nil {
+			return fmt.Errorf("layout: %w", err)
+		}
+	}
+	return nil
+}
+
+// insideBootPartition reports whether p is a relative path that stays inside
+// the directory it is joined to.
+func insideBootPartition(p string) bool {
+	if filepath.IsAbs(p) {
+		return false
+	}
+	clean := filepath.Clean(p)
+	return clean != "." && clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// Matches reports whether the profile is for model.
+func (p *Profile) Matches(model string) bool {
+	for _, pattern := range p.patterns() {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Profile) patterns() []string {
+	if len(p.Models) == 0 {
+		return []string{p.Name}
+	}
+	return p.Models
+}
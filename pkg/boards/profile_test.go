@@ -0,0 +1,64 @@
+package boards
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProfile(t *testing.T) {
+	p, err := ParseProfile([]byte(`
+name: board
+models: ["board-*"]
+firmware:
+  - source: /firmware/board/
+    target: overlays
+files:
+  - path: extlinux/extlinux.conf
+    content: "default kairos\n"
+bootloader:
+  - source: /firmware/board/u-boot.bin
+    offset: 8192
+layout:
+  partitions:
+    - role: boot
+      offset: 4
+    - role: oem
+    - role: recovery
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Firmware[0].Target != "overlays" || p.Files[0].Path != "extlinux/extlinux.conf" || p.Bootloader[0].Offset != 8192 {
+		t.Errorf("profile %+v", p)
+	}
+	if p.Layout == nil || p.Layout.Partitions[0].Offset != 4 {
+		t.Errorf("layout %+v", p.Layout)
+	}
+	if !p.Matches("board-v2") || p.Matches("board") {
+		t.Error("models should be matched as patterns")
+	}
+}
+
+func TestParseProfileRejects(t *testing.T) {
+	cases := map[string]string{
+		"name is required":          "firmware:\n  - source: /fw\n",
+		"field firmwares not found": "name: b\nfirmwares: []\n",
+		"not a valid pattern":       "name: b\nmodels: [\"[\"]\n",
+		"must be an absolute path":  "name: b\nbootloader:\n  - source: u-boot.bin\n",
+		"inside the boot partition": "name: b\nfiles:\n  - path: ../etc/passwd\n    content: x\n",
+		"layout:":                   "name: b\nlayout:\n  partitions:\n    - role: boot\n",
+	}
+	for want, data := range cases {
+		if _, err := ParseProfile([]byte(data)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want an error mentioning %q", data, err, want)
+		}
+	}
+}
+
+// A profile without models is for the model of its name.
+func TestProfileMatchesItsName(t *testing.T) {
+	p := &Profile{Name: "odroid-c2"}
+	if !p.Matches("odroid-c2") || p.Matches("odroid-c4") {
+		t.Error("a profile without models should match its name only")
+	}
+}
@@ -0,0 +1,185 @@
+package boards
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed profiles/*.yaml
+var builtinProfiles embed.FS
+
+// ProfileLoadResult records the outcome of loading one operator profile file.
+// A malformed profile disables only itself: Err is set and the rest of the
+// directory still loads.
+type ProfileLoadResult struct {
+	// Path is the file the result is for.
+	Path string
+	// Name is the profile's declared name, empty when it could not be parsed.
+	Name string
+	// Overrode is true when the profile replaced a built-in of the same name.
+	Overrode bool
+	// Err is non-nil when the file could not be read or parsed.
+	Err error
+}
+
+// Registry holds the board profiles: the built-in ones plus any loaded from an
+// operator directory. It is built once at start and read-only after that.
+type Registry struct {
+	byName  map[string]*Profile
+	builtin map[string]bool
+}
+
+// newRegistry builds a Registry holding the built-in profiles.
+func newRegistry() *Registry {
+	r := &Registry{byName: map[string]*Profile{}, builtin: map[string]bool{}}
+	entries, err := builtinProfiles.ReadDir("profiles")
+	if err != nil {
+		panic(err)
+	}
+	for _, ent := range entries {
+		data, err := builtinProfiles.ReadFile("profiles/" + ent.Name())
+		if err != nil {
+			panic(err)
+		}
+		p, err := ParseProfile(data)
+		if err != nil {
+			panic(fmt.Sprintf("built-in board profile %s: %v", ent.Name(), err))
+		}
+		r.byName[p.Name] = p
+		r.builtin[p.Name] = true
+	}
+	return r
+}
+
+// LoadProfileDir reads every *.yaml / *.yml file in dir into a registry of the
+// built-in profiles, returning one ProfileLoadResult per file. A bad profile is
+// logged and skipped; it never fails the whole load. An operator profile named
+// like a built-in replaces it. An empty or missing dir is not an error, the
+// returned error is reserved for a directory that cannot be read.
+func LoadProfileDir(dir string) (*Registry, []ProfileLoadResult, error) {
+	r := newRegistry()
+
+	if strings.TrimSpace(dir) == "" {
+		return r, nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("boards: profiles dir %q does not exist; using built-in profiles only", dir)
+			return r, nil, nil
+		}
+		return r, nil, fmt.Errorf("reading board profiles dir %q: %w", dir, err)
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, ent := range entries {
+		if ent.IsDir() || !isProfileFile(ent.Name()) {
+			continue
+		}
+		files = append(files, ent.Name())
+	}
+	sort.Strings(files)
+
+	var results []ProfileLoadResult
+	for _, fname := range files {
+		path := filepath.Join(dir, fname)
+		res := ProfileLoadResult{Path: path}
+
+		data, err := os.ReadFile(path)
+		if err == nil {
+			var p *Profile
+			if p, err = ParseProfile(data); err == nil {
+				res.Name = p.Name
+				res.Overrode = r.add(p)
+			}
+		}
+		if err != nil {
+			res.Err = fmt.Errorf("loading board profile %q: %w", path, err)
+			log.Printf("boards: skipping board profile %q: %v", path, err)
+		}
+		results = append(results, res)
+	}
+
+	return r, results, nil
+}
+
+// add inserts an operator profile, logging when it replaces a built-in.
+func (r *Registry) add(p *Profile) (overrode bool) {
+	if r.builtin[p.Name] {
+		overrode = true
+		delete(r.builtin, p.Name)
+		log.Printf("boards: operator board profile %q overrides the built-in", p.Name)
+	}
+	r.byName[p.Name] = p
+	log.Printf("boards: loaded board profile %q", p.Name)
+	return overrode
+}
+
+// isProfileFile reports whether a filename is a YAML board profile by extension.
+func isProfileFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Lookup returns the profile for a board model. A profile listing the model
+// itself wins over patterns; otherwise the first matching profile in name
+// order is used. The bool is false when no profile is for the model.
+func (r *Registry) Lookup(model string) (*Profile, bool) {
+	names := r.Names()
+	for _, name := range names {
+		for _, pattern := range r.byName[name].patterns() {
+			if pattern == model {
+				return r.byName[name], true
+			}
+		}
+	}
+	for _, name := range names {
+		if r.byName[name].Matches(model) {
+			return r.byName[name], true
+		}
+	}
+	return nil, false
+}
+
+// Names returns the names of the registered profiles, sorted.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.byName))
+	for name := range r.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defaultRegistry is the process-wide registry the raw disk builder looks
+// profiles up in. It holds the built-in profiles until SetDefaultRegistry
+// installs one loaded at start.
+var (
+	defaultRegistryMu sync.RWMutex
+	defaultRegistry   = newRegistry()
+)
+
+// SetDefaultRegistry installs the process-wide registry. The CLI calls it once,
+// after LoadProfileDir. A nil argument is ignored.
+func SetDefaultRegistry(r *Registry) {
+	if r == nil {
+		return
+	}
+	defaultRegistryMu.Lock()
+	defaultRegistry = r
+	defaultRegistryMu.Unlock()
+}
+
+// DefaultRegistry returns the process-wide registry.
+func DefaultRegistry() *Registry {
+	defaultRegistryMu.RLock()
+	defer defaultRegistryMu.RUnlock()
+	return defaultRegistry
+}
@@ -0,0 +1,121 @@
+package boards
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeProfile(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o600); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+}
+
+// The built-in profiles cover the boards the raw disk builder always knew,
+// with the Raspberry Pi 4 one standing in for every other Raspberry Pi.
+func TestBuiltinLookup(t *testing.T) {
+	r := newRegistry()
+	for model, want := range map[string]string{
+		"rpi3":        "rpi4",
+		"rpi4":        "rpi4",
+		"rpi5":        "rpi5",
+		"odroid-c2":   "odroid-c2",
+		"pinebookpro": "pinebookpro",
+	} {
+		p, ok := r.Lookup(model)
+		if !ok || p.Name != want {
+			t.Errorf("model %q: got %v, want the %s profile", model, p, want)
+		}
+	}
+	if p, ok := r.Lookup("generic"); ok {
+		t.Errorf("generic should have no profile, got %s", p.Name)
+	}
+}
+
+// The built-in bootloader offsets are where each board's boot ROM reads
+// from. The Pinebook Pro ones moved from the start of the disk when the
+// profiles replaced the hard-coded writes; this pins the new layout.
+func TestBuiltinBootloaderOffsets(t *testing.T) {
+	r := newRegistry()
+	for model, want := range map[string][]Blob{
+		"odroid-c2": {
+			{Source: "/arm/odroid-c2/bl1.bin.hardkernel", Length: 442},
+			{Source: "/arm/odroid-c2/bl1.bin.hardkernel", Offset: 512, Skip: 512},
+			{Source: "/arm/odroid-c2/u-boot.odroidc2", Offset: 97 * 512},
+		},
+		"pinebookpro": {
+			{Source: "/arm/pinebookpro/idbloader.img", Offset: 64 * 512},
+			{Source: "/arm/pinebookpro/u-boot.itb", Offset: 8 << 20},
+		},
+	} {
+		p, _ := r.Lookup(model)
+		if !reflect.DeepEqual(p.Bootloader, want) {
+			t.Errorf("%s bootloader is %+v, want %+v", model, p.Bootloader, want)
+		}
+	}
+	p, _ := r.Lookup("pinebookpro")
+	if p.Layout == nil || p.Layout.Partitions[0].Offset != 16 {
+		t.Errorf("the first pinebookpro partition should start at 16 MiB, layout %+v", p.Layout)
+	}
+}
+
+func TestLoadProfileDirEmptyAndMissing(t *testing.T) {
+	for _, dir := range []string{"", filepath.Join(t.TempDir(), "does-not-exist"), t.TempDir()} {
+		r, results, err := LoadProfileDir(dir)
+		if err != nil || len(results) != 0 {
+			t.Fatalf("dir %q: err=%v results=%v", dir, err, results)
+		}
+		if _, ok := r.Lookup("rpi4"); !ok {
+			t.Fatalf("dir %q: the built-in profiles should be loaded", dir)
+		}
+	}
+}
+
+func TestLoadProfileDirLoadsAndSkips(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile(t, dir, "good.yaml", "name: good\nmodels: [\"good-*\"]\n")
+	writeProfile(t, dir, "bad.yml", "name: bad\nbootloader:\n  - source: relative.bin\n")
+	writeProfile(t, dir, "notes.txt", "not a profile")
+
+	r, results, err := LoadProfileDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("want results for the two YAML files, got %+v", results)
+	}
+	if results[0].Err == nil || results[1].Err != nil || results[1].Name != "good" {
+		t.Errorf("results %+v", results)
+	}
+	if _, ok := r.Lookup("good-1"); !ok {
+		t.Error("the good profile should be loaded")
+	}
+	if _, ok := r.Lookup("bad"); ok {
+		t.Error("the bad profile should be skipped")
+	}
+}
+
+// An operator profile replaces the built-in of the same name, and one listing
+// a model outright wins over a built-in pattern.
+func TestLoadProfileDirOverrides(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile(t, dir, "rpi5.yaml", "name: rpi5\nfirmware:\n  - source: /opt/rpi5/\n")
+	writeProfile(t, dir, "cm4.yaml", "name: cm4\nmodels: [rpi-cm4]\n")
+
+	r, results, err := LoadProfileDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Overrode || !results[1].Overrode {
+		t.Errorf("results %+v", results)
+	}
+	if p, _ := r.Lookup("rpi5"); p.Firmware[0].Source != "/opt/rpi5/" {
+		t.Errorf("rpi5 resolves to %+v", p)
+	}
+	if p, _ := r.Lookup("rpi-cm4"); p.Name != "cm4" {
+		t.Errorf("rpi-cm4 resolves to %s", p.Name)
+	}
+}
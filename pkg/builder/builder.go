@@ -3,6 +3,8 @@ package builder
 import (
 	"context"
 	"errors"
+
+	"github.com/kairos-io/AuroraBoot/pkg/gitsource"
 )
 
 // ErrInvalidBuildOptions marks a build failure caused by invalid admin-supplied
@@ -46,6 +48,33 @@ type OutputOptions struct {
 	UKI         bool
 	FIPS        bool
 	TrustedBoot bool
+	// QCOW2 writes a qcow2 image of the raw disk for KVM, Proxmox and
+	// OpenStack; QCOW2Compress deflates its clusters.
+	QCOW2         bool
+	QCOW2Compress bool
+	// VHDDynamic makes the VHD output a sparse, dynamic VHD (Azure only
+	// takes fixed ones). VHDX writes a VHDX for Hyper-V generation 2
+	// virtual machines.
+	VHDDynamic bool
+	VHDX       bool
+	// VMDK writes a stream-optimized VMDK of the raw disk for vSphere; OVA
+	// packs one with an OVF descriptor of an EFI virtual machine with
+	// OVACPUs and OVAMemoryMB (2 and 4096 when zero), and a user-data vApp
+	// property with OVAUserDataProperty.
+	VMDK                bool
+	OVA                 bool
+	OVACPUs             int
+	OVAMemoryMB         int
+	OVAUserDataProperty bool
+	// SBOM writes a software bill of materials of the packages installed in
+	// the built image next to the other outputs, in SBOMFormat
+	// (sbom.FormatSPDX when empty).
+	SBOM       bool
+	SBOMFormat string
+	// Compression replaces the raw disk and VHD outputs with copies
+	// compressed as "zstd", "xz" or "gzip" and adds one next to the ISO,
+	// which is kept for booting. Empty leaves them uncompressed.
+	Compression string
 }
 
 // SigningOptions holds SecureBoot / UKI signing paths.
@@ -55,6 +84,14 @@ type SigningOptions struct {
 	UKITPMPCRKey        string
 	UKIPublicKeysDir    string
 	UKISecureBootEnroll string
+	// ManifestKey is the PEM private key the SHA256SUMS manifest of the
+	// outputs is signed with (see pkg/checksums). Empty leaves it unsigned.
+	// It is a path on the host running the builder, so backends that build
+	// elsewhere leave signing to the server as the files are uploaded.
+	ManifestKey string
+	// ManifestKeySetID names the SecureBoot key set ManifestKey came from,
+	// recorded on the artifact; empty for the server's own manifest key.
+	ManifestKeySetID string
 }
 
 // ProvisioningOptions controls post-build provisioning behaviour.
@@ -80,6 +117,12 @@ type BuildOptions struct {
 	ID   string // unique build ID
 	Name string // optional friendly name
 
+	// Resume reruns the failed build ID in place, skipping the steps it
+	// completed whose inputs are unchanged and whose outputs are still in
+	// its output dir. Only the local backend keeps those between runs; the
+	// others return ErrNotSupported.
+	Resume bool
+
 	// UploadToken is the per-build bearer the operator backend's exporter
 	// Job uses to PUT finished artifacts back to AuroraBoot's upload
 	// endpoint. Populated by the Create handler on every build regardless
@@ -121,12 +164,24 @@ type BuildOptions struct {
 	CloudConfig string // YAML cloud-config to bake in
 	OutputDir   string // where to write artifacts
 
+	// Secrets are the values of the secrets CloudConfig references as
+	// {{ secret "name" }}, by name. The handler reads them from the secret
+	// store and the builder substitutes them only into the config it bakes
+	// in, so the artifact record and the build manifest keep the reference.
+	Secrets map[string]string
+
 	// Customization options:
 	OverlayRootfs   string // path to overlay dir (files copied on top of rootfs)
 	Dockerfile      string // optional Dockerfile content (builds image via docker before ISO)
 	BuildContextDir string // directory with files available to COPY in Dockerfile
 	KairosInitImage string
 
+	// Git, when set, is cloned into the build context before the build. Its
+	// Dockerfile and overlay/ are used unless Dockerfile or OverlayRootfs are
+	// set, and the commit it resolved to is recorded on the artifact.
+	// Git.Password is filled in by the handler from the secret store.
+	Git *gitsource.Source
+
 	// Hadron composition (metadata only — not consumed by the build; the
 	// rendered Dockerfile is what actually runs). Persisted on the artifact
 	// record so the frontend can rehydrate the composer when cloning.
@@ -0,0 +1,105 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+)
+
+// ManifestFileName is the file a build writes next to its outputs recording
+// what went in (image references and the digests they resolved to) and what
+// came out (a checksum per output file). It is listed among the artifact
+// files, so it is downloaded, uploaded by remote workers and deleted with the
+// artifact like any other output.
+const ManifestFileName = "build-manifest.json"
+
+// BuildManifest is the reproducibility record of one build. Inputs pins the
+// images the build started from; rebuilding from the pinned references (see
+// PinnedImageRef) produces the same OS image, and Outputs lets anyone holding
+// the files verify they are the ones this build produced.
+type BuildManifest struct {
+	ArtifactID string         `json:"artifactId"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	Inputs     ManifestInputs `json:"inputs"`
+	Image      ManifestImage  `json:"image"`
+	Outputs    []ManifestFile `json:"outputs"`
+}
+
+// ManifestInputs are the images a build was derived from. A digest is empty
+// when the builder could not resolve it (an image that only exists in the
+// local daemon, an unreachable registry) or when the input was not used, e.g.
+// BaseImage on a Dockerfile build.
+type ManifestInputs struct {
+	BaseImage             string `json:"baseImage,omitempty"`
+	BaseImageDigest       string `json:"baseImageDigest,omitempty"`
+	KairosInitImage       string `json:"kairosInitImage,omitempty"`
+	KairosInitImageDigest string `json:"kairosInitImageDigest,omitempty"`
+	// DockerfileSHA256 is the checksum of the Dockerfile exactly as it was
+	// handed to docker build, including the FROM line prepended for Hadron.
+	DockerfileSHA256 string `json:"dockerfileSha256,omitempty"`
+	// GitURL and GitCommit name the repository revision the Dockerfile
+	// and overlay were checked out from.
+	GitURL    string `json:"gitUrl,omitempty"`
+	GitCommit string `json:"gitCommit,omitempty"`
+	Arch      string `json:"arch,omitempty"`
+}
+
+// ManifestImage is the OS image the outputs were generated from.
+type ManifestImage struct {
+	Ref    string `json:"ref"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// ManifestFile is the checksum of one build output.
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ChecksumFile hashes the file at path into a ManifestFile named after its
+// base name. The hash is the one SHA256SUMS lists, so the manifest and the
+// signed checksums always agree.
+func ChecksumFile(path string) (ManifestFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	sum, err := checksums.HashFile(path)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	return ManifestFile{Name: filepath.Base(path), Size: info.Size(), SHA256: sum}, nil
+}
+
+// PinnedImageRef rewrites ref to address digest instead of a tag, e.g.
+// "quay.io/kairos/ubuntu:24.04" with "sha256:ab…" becomes
+// "quay.io/kairos/ubuntu@sha256:ab…". An existing digest on ref is replaced.
+// It returns ref unchanged when digest is empty, so callers can pin
+// unconditionally.
+func PinnedImageRef(ref, digest string) string {
+	if digest == "" || ref == "" {
+		return ref
+	}
+	repo := ref
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo = repo[:i]
+	}
+	// A ":" after the last "/" is a tag; one before it is a registry port.
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	return repo + "@" + digest
+}
+
+// ImageRefDigest returns the digest ref already pins, or "" for a tag
+// reference.
+func ImageRefDigest(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[i+1:]
+	}
+	return ""
+}
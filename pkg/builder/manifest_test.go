@@ -0,0 +1,50 @@
+package builder_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+)
+
+var _ = Describe("PinnedImageRef", func() {
+	const digest = "sha256:0123456789abcdef"
+
+	DescribeTable("rewrites the reference to address the digest",
+		func(ref, want string) {
+			Expect(builder.PinnedImageRef(ref, digest)).To(Equal(want))
+		},
+		Entry("tagged", "quay.io/kairos/ubuntu:24.04", "quay.io/kairos/ubuntu@"+digest),
+		Entry("untagged", "ubuntu", "ubuntu@"+digest),
+		Entry("registry with a port", "localhost:5000/kairos/ubuntu:24.04", "localhost:5000/kairos/ubuntu@"+digest),
+		Entry("registry with a port, untagged", "localhost:5000/kairos/ubuntu", "localhost:5000/kairos/ubuntu@"+digest),
+		Entry("already pinned", "quay.io/kairos/ubuntu:24.04@sha256:old", "quay.io/kairos/ubuntu@"+digest),
+	)
+
+	It("leaves the reference alone without a digest", func() {
+		Expect(builder.PinnedImageRef("quay.io/kairos/ubuntu:24.04", "")).To(Equal("quay.io/kairos/ubuntu:24.04"))
+	})
+
+	It("reads the digest back", func() {
+		Expect(builder.ImageRefDigest("quay.io/kairos/ubuntu@" + digest)).To(Equal(digest))
+		Expect(builder.ImageRefDigest("quay.io/kairos/ubuntu:24.04")).To(BeEmpty())
+	})
+})
+
+var _ = Describe("ChecksumFile", func() {
+	It("hashes the file under its base name", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "kairos.iso")
+		Expect(os.WriteFile(path, []byte("hello\n"), 0o644)).To(Succeed())
+
+		f, err := builder.ChecksumFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f).To(Equal(builder.ManifestFile{
+			Name:   "kairos.iso",
+			Size:   6,
+			SHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		}))
+	})
+})
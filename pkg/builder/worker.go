@@ -0,0 +1,45 @@
+package builder
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+// ErrLeaseLost is returned by the remote worker backend when a worker acts on
+// a build it no longer holds: the lease expired and the build was handed back
+// to the queue, or the build was cancelled. The worker must abandon the build
+// rather than keep uploading or reporting for it. Handlers map it to 409.
+var ErrLeaseLost = errors.New("build lease lost")
+
+// WorkerLease is a queued build handed to a remote build worker. Options is
+// the exact BuildOptions the Create handler produced — including UploadToken,
+// which the worker presents to PUT /api/v1/artifacts/:id/upload/* exactly like
+// the operator backend's exporter Job does. The worker must heartbeat before
+// LeaseExpiresAt or the build is re-queued for another worker.
+type WorkerLease struct {
+	BuildID        string       `json:"buildId"`
+	Options        BuildOptions `json:"options"`
+	LeaseExpiresAt time.Time    `json:"leaseExpiresAt"`
+}
+
+// WorkerResult is the terminal report a worker sends once a leased build has
+// finished and, on success, every output file has been uploaded. Phase is
+// BuildReady or BuildError. The digests are what the worker's local build
+// pinned, copied onto the artifact record as-is.
+type WorkerResult struct {
+	Phase                 string `json:"phase"`
+	Message               string `json:"message,omitempty"`
+	ContainerImage        string `json:"containerImage,omitempty"`
+	KairosInitImage       string `json:"kairosInitImage,omitempty"`
+	BaseImageDigest       string `json:"baseImageDigest,omitempty"`
+	KairosInitImageDigest string `json:"kairosInitImageDigest,omitempty"`
+	ContainerImageDigest  string `json:"containerImageDigest,omitempty"`
+	// GitCommit is what the build's Git ref resolved to, for builds with a
+	// Git source.
+	GitCommit string `json:"gitCommit,omitempty"`
+	// Vulnerabilities is the summary of the worker's offline advisory match,
+	// nil when the build had no SBOM or the worker has no advisory database.
+	Vulnerabilities *store.VulnerabilitySummary `json:"vulnerabilities,omitempty"`
+}
@@ -0,0 +1,185 @@
+// Package checksums writes and verifies the SHA256SUMS manifest that lists
+// every output of a build, and the detached signature over it.
+//
+// The manifest uses the format coreutils' sha256sum prints, so a downloaded
+// artifact directory can be checked with nothing more than `sha256sum -c
+// SHA256SUMS`. The signature is the base64 encoding of a signature over the
+// manifest bytes, which is what `cosign sign-blob` produces, so `cosign
+// verify-blob --key <pub> --signature SHA256SUMS.sig SHA256SUMS` accepts it
+// as well as `auroraboot verify`.
+package checksums
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// FileName is the manifest a build writes next to its outputs.
+	FileName = "SHA256SUMS"
+	// SignatureFileName is the detached signature over FileName.
+	SignatureFileName = FileName + ".sig"
+	// Header carries the hex SHA-256 of an uploaded file, so the server can
+	// reject a body that was truncated or altered in transit before it
+	// replaces anything on disk.
+	Header = "X-Checksum-Sha256"
+)
+
+// Entry is one line of the manifest.
+type Entry struct {
+	Name   string
+	SHA256 string
+}
+
+// HashFile returns the hex SHA-256 of the file at path.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Format renders entries in sha256sum's text format, sorted by name so the
+// same set of files always produces the same bytes (and so the same
+// signature).
+func Format(entries []Entry) []byte {
+	sorted := append([]Entry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	var buf bytes.Buffer
+	for _, e := range sorted {
+		fmt.Fprintf(&buf, "%s  %s\n", e.SHA256, e.Name)
+	}
+	return buf.Bytes()
+}
+
+// Parse reads a manifest in sha256sum's format. Both the text ("  ") and
+// binary (" *") separators are accepted; names must be bare file names, as a
+// manifest only ever describes the directory it sits in.
+func Parse(data []byte) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok || len(name) < 2 || (name[0] != ' ' && name[0] != '*') {
+			return nil, fmt.Errorf("line %d: expected \"<sha256>  <name>\"", n)
+		}
+		name = name[1:]
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("line %d: invalid SHA-256 %q", n, sum)
+		}
+		if name != filepath.Base(name) || name == "." || name == ".." || strings.Contains(name, `\`) {
+			return nil, fmt.Errorf("line %d: %q is not a plain file name", n, name)
+		}
+		entries = append(entries, Entry{Name: name, SHA256: strings.ToLower(sum)})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Write writes entries as FileName in dir and returns its path.
+func Write(dir string, entries []Entry) (string, error) {
+	path := filepath.Join(dir, FileName)
+	if err := os.WriteFile(path, Format(entries), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Read parses FileName in dir.
+func Read(dir string) ([]Entry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Add hashes the named files in dir into dir's FileName, creating it if
+// needed. Entries for the same names are replaced and entries whose file is
+// gone are dropped, so a step that replaces an output with another keeps
+// the manifest in step with the directory.
+func Add(dir string, names ...string) (string, error) {
+	entries, err := Read(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	var kept []Entry
+	for _, e := range entries {
+		if _, err := os.Stat(filepath.Join(dir, e.Name)); err == nil {
+			kept = append(kept, e)
+		}
+	}
+	for _, name := range names {
+		sum, err := HashFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+		kept = slices.DeleteFunc(kept, func(e Entry) bool { return e.Name == name })
+		kept = append(kept, Entry{Name: name, SHA256: sum})
+	}
+	return Write(dir, kept)
+}
+
+// Lookup returns the checksum recorded for name in entries.
+func Lookup(entries []Entry, name string) (string, bool) {
+	for _, e := range entries {
+		if e.Name == name {
+			return e.SHA256, true
+		}
+	}
+	return "", false
+}
+
+// Result is the outcome of checking one manifest entry.
+type Result struct {
+	Name string
+	// Err is nil when the file exists and matches.
+	Err error
+}
+
+// VerifyDir checks every file FileName in dir lists. It returns an error only
+// when the manifest itself cannot be read; per-file failures are reported in
+// the results so a caller can list all of them rather than stop at the first.
+func VerifyDir(dir string) ([]Result, error) {
+	entries, err := Read(dir)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, 0, len(entries))
+	for _, e := range entries {
+		results = append(results, Result{Name: e.Name, Err: verifyFile(filepath.Join(dir, e.Name), e.SHA256)})
+	}
+	return results, nil
+}
+
+func verifyFile(path, want string) error {
+	got, err := HashFile(path)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("checksum mismatch: got %s, want %s", got, want)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package checksums_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// sha256 of "hello\n" and "world\n", as printed by sha256sum.
+const (
+	helloSum = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	worldSum = "e258d248fda94c63753607f7c4494ee0fcbe92f1a76bfdac795c9d84101eb317"
+)
+
+var _ = Describe("SHA256SUMS", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "b.iso"), []byte("hello\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "a.raw"), []byte("world\n"), 0o644)).To(Succeed())
+	})
+
+	It("writes sha256sum's format sorted by name", func() {
+		_, err := checksums.Write(dir, []checksums.Entry{
+			{Name: "b.iso", SHA256: helloSum},
+			{Name: "a.raw", SHA256: worldSum},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		data, err := os.ReadFile(filepath.Join(dir, checksums.FileName))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(worldSum + "  a.raw\n" + helloSum + "  b.iso\n"))
+	})
+
+	It("adds files to an existing manifest and drops the ones that are gone", func() {
+		_, err := checksums.Write(dir, []checksums.Entry{
+			{Name: "a.raw", SHA256: worldSum},
+			{Name: "b.iso", SHA256: worldSum},
+			{Name: "gone.img", SHA256: helloSum},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, "c.vhd"), []byte("hello\n"), 0o644)).To(Succeed())
+
+		_, err = checksums.Add(dir, "b.iso", "c.vhd")
+		Expect(err).ToNot(HaveOccurred())
+		entries, err := checksums.Read(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(Equal([]checksums.Entry{
+			{Name: "a.raw", SHA256: worldSum},
+			{Name: "b.iso", SHA256: helloSum},
+			{Name: "c.vhd", SHA256: helloSum},
+		}))
+	})
+
+	It("parses text and binary mode lines", func() {
+		entries, err := checksums.Parse([]byte(helloSum + "  b.iso\n" + worldSum + " *a.raw\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(Equal([]checksums.Entry{{Name: "b.iso", SHA256: helloSum}, {Name: "a.raw", SHA256: worldSum}}))
+	})
+
+	DescribeTable("rejects malformed manifests",
+		func(line string) {
+			_, err := checksums.Parse([]byte(line))
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("missing name", helloSum+"\n"),
+		Entry("short sum", "abcd  b.iso\n"),
+		Entry("path in name", helloSum+"  ../b.iso\n"),
+		Entry("nested name", helloSum+"  sub/b.iso\n"),
+	)
+
+	It("verifies every listed file and reports each mismatch", func() {
+		_, err := checksums.Write(dir, []checksums.Entry{
+			{Name: "b.iso", SHA256: helloSum},
+			{Name: "a.raw", SHA256: helloSum},
+			{Name: "gone.img", SHA256: helloSum},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		results, err := checksums.VerifyDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		// Results follow the manifest, which Write sorted by name.
+		Expect(results).To(HaveLen(3))
+		Expect(results[0].Name).To(Equal("a.raw"))
+		Expect(results[0].Err).To(MatchError(ContainSubstring("checksum mismatch")))
+		Expect(results[1].Name).To(Equal("b.iso"))
+		Expect(results[1].Err).ToNot(HaveOccurred())
+		Expect(results[2].Err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,227 @@
+package checksums
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// PasswordEnv names the environment variable the password of an encrypted
+// cosign key is read from, the same one cosign itself uses.
+const PasswordEnv = "COSIGN_PASSWORD"
+
+// PEM block types cosign writes for its encrypted private keys. The second
+// is what releases before the sigstore rename produced.
+var encryptedKeyTypes = map[string]bool{
+	"ENCRYPTED SIGSTORE PRIVATE KEY": true,
+	"ENCRYPTED COSIGN PRIVATE KEY":   true,
+}
+
+// LoadSigner parses a PEM private key. It accepts the keys `cosign
+// generate-key-pair` writes (decrypted with password), and unencrypted
+// PKCS#8, PKCS#1 RSA and SEC 1 EC keys, which covers the db.key of a
+// SecureBoot key set.
+func LoadSigner(keyPEM, password []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM block found in signing key")
+	}
+	der := block.Bytes
+	if encryptedKeyTypes[block.Type] {
+		var err error
+		if der, err = decryptCosignKey(block.Bytes, password); err != nil {
+			return nil, err
+		}
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(der)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(der)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(der)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing key: %w", err)
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return k.(crypto.Signer), nil
+	}
+	return nil, fmt.Errorf("unsupported signing key type %T", key)
+}
+
+// cosignKey is the JSON envelope inside an encrypted cosign key: the PKCS#8
+// key sealed with NaCl secretbox under a scrypt-derived key.
+type cosignKey struct {
+	KDF struct {
+		Name   string `json:"name"`
+		Params struct {
+			N int `json:"N"`
+			R int `json:"r"`
+			P int `json:"p"`
+		} `json:"params"`
+		Salt []byte `json:"salt"`
+	} `json:"kdf"`
+	Cipher struct {
+		Name  string `json:"name"`
+		Nonce []byte `json:"nonce"`
+	} `json:"cipher"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func decryptCosignKey(data, password []byte) ([]byte, error) {
+	var k cosignKey
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, fmt.Errorf("parsing encrypted signing key: %w", err)
+	}
+	if k.KDF.Name != "scrypt" || k.Cipher.Name != "nacl/secretbox" || len(k.Cipher.Nonce) != 24 {
+		return nil, fmt.Errorf("unsupported key encryption %s/%s", k.KDF.Name, k.Cipher.Name)
+	}
+	derived, err := scrypt.Key(password, k.KDF.Salt, k.KDF.Params.N, k.KDF.Params.R, k.KDF.Params.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	var key [32]byte
+	var nonce [24]byte
+	copy(key[:], derived)
+	copy(nonce[:], k.Cipher.Nonce)
+	out, ok := secretbox.Open(nil, k.Ciphertext, &nonce, &key)
+	if !ok {
+		return nil, fmt.Errorf("decrypting signing key: wrong password (set %s)", PasswordEnv)
+	}
+	return out, nil
+}
+
+// Sign signs data the way cosign sign-blob does (ECDSA and RSA over the
+// SHA-256 of data, Ed25519 over data itself) and returns the base64 text
+// written to SignatureFileName.
+func Sign(signer crypto.Signer, data []byte) ([]byte, error) {
+	var sig []byte
+	var err error
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		sig, err = signer.Sign(rand.Reader, data, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(data)
+		sig, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(sig)), nil
+}
+
+// SignWithKeyFile signs data with the PEM private key at keyPath, returning
+// what Sign does. An encrypted cosign key is decrypted with the password in
+// PasswordEnv.
+func SignWithKeyFile(keyPath string, data []byte) ([]byte, error) {
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	signer, err := LoadSigner(keyPEM, []byte(os.Getenv(PasswordEnv)))
+	if err != nil {
+		return nil, err
+	}
+	return Sign(signer, data)
+}
+
+// SignDir signs FileName in dir with the PEM private key at keyPath and
+// writes SignatureFileName next to it, returning its path.
+func SignDir(dir, keyPath string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return "", err
+	}
+	sig, err := SignWithKeyFile(keyPath, data)
+	if err != nil {
+		return "", fmt.Errorf("signing %s: %w", FileName, err)
+	}
+	path := filepath.Join(dir, SignatureFileName)
+	if err := os.WriteFile(path, sig, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// LoadPublicKey parses a PEM public key (`cosign.pub`), PKCS#1 RSA public
+// key, or certificate (the db.pem of a SecureBoot key set).
+func LoadPublicKey(keyPEM []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM block found in public key")
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate: %w", err)
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+	return pub, nil
+}
+
+// VerifySignature checks sig, as written by Sign, over data. A raw binary
+// signature is accepted too, as long as it is not also valid base64.
+func VerifySignature(pub crypto.PublicKey, data, sig []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		raw = sig
+	}
+	digest := sha256.Sum256(data)
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], raw) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], raw); err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, raw) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported public key type %T", pub)
+}
+
+// VerifyDirSignature checks SignatureFileName in dir against FileName.
+func VerifyDirSignature(dir string, pub crypto.PublicKey) error {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return err
+	}
+	sig, err := os.ReadFile(filepath.Join(dir, SignatureFileName))
+	if err != nil {
+		return err
+	}
+	return VerifySignature(pub, data, sig)
+}
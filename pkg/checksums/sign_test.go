@@ -0,0 +1,135 @@
+package checksums_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+func pkcs8PEM(key crypto.Signer) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicPEM(key crypto.Signer) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	Expect(err).ToNot(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// cosignEncrypted seals key the way cosign generate-key-pair does, with a
+// cheap scrypt cost so the test stays fast.
+func cosignEncrypted(key crypto.Signer, password string) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+	salt := make([]byte, 32)
+	var nonce [24]byte
+	_, _ = rand.Read(salt)
+	_, _ = rand.Read(nonce[:])
+	derived, err := scrypt.Key([]byte(password), salt, 1024, 8, 1, 32)
+	Expect(err).ToNot(HaveOccurred())
+	var k [32]byte
+	copy(k[:], derived)
+	env := map[string]any{
+		"kdf":        map[string]any{"name": "scrypt", "params": map[string]int{"N": 1024, "r": 8, "p": 1}, "salt": salt},
+		"cipher":     map[string]any{"name": "nacl/secretbox", "nonce": nonce[:]},
+		"ciphertext": secretbox.Seal(nil, der, &nonce, &k),
+	}
+	data, err := json.Marshal(env)
+	Expect(err).ToNot(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED SIGSTORE PRIVATE KEY", Bytes: data})
+}
+
+var _ = Describe("signing", func() {
+	data := []byte(helloSum + "  b.iso\n")
+
+	DescribeTable("round-trips a signature",
+		func(newKey func() crypto.Signer) {
+			key := newKey()
+			signer, err := checksums.LoadSigner(pkcs8PEM(key), nil)
+			Expect(err).ToNot(HaveOccurred())
+			sig, err := checksums.Sign(signer, data)
+			Expect(err).ToNot(HaveOccurred())
+
+			pub, err := checksums.LoadPublicKey(publicPEM(key))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checksums.VerifySignature(pub, data, sig)).To(Succeed())
+			Expect(checksums.VerifySignature(pub, append(data, 'x'), sig)).ToNot(Succeed())
+		},
+		Entry("ECDSA P-256", func() crypto.Signer {
+			k, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			return k
+		}),
+		Entry("RSA", func() crypto.Signer {
+			k, _ := rsa.GenerateKey(rand.Reader, 2048)
+			return k
+		}),
+		Entry("Ed25519", func() crypto.Signer {
+			_, k, _ := ed25519.GenerateKey(rand.Reader)
+			return k
+		}),
+	)
+
+	It("decrypts cosign keys with the password", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		enc := cosignEncrypted(key, "s3cret")
+
+		_, err = checksums.LoadSigner(enc, []byte("wrong"))
+		Expect(err).To(MatchError(ContainSubstring("wrong password")))
+
+		signer, err := checksums.LoadSigner(enc, []byte("s3cret"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signer.Public()).To(Equal(key.Public()))
+	})
+
+	It("signs a directory with a SecureBoot db key and verifies against its certificate", func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "db"},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+		Expect(err).ToNot(HaveOccurred())
+
+		dir := GinkgoT().TempDir()
+		keyPath := filepath.Join(dir, "db.key")
+		Expect(os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600)).To(Succeed())
+		out := filepath.Join(dir, "out")
+		Expect(os.Mkdir(out, 0o755)).To(Succeed())
+		_, err = checksums.Write(out, []checksums.Entry{{Name: "b.iso", SHA256: helloSum}})
+		Expect(err).ToNot(HaveOccurred())
+
+		sigPath, err := checksums.SignDir(out, keyPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sigPath).To(Equal(filepath.Join(out, checksums.SignatureFileName)))
+
+		pub, err := checksums.LoadPublicKey(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(checksums.VerifyDirSignature(out, pub)).To(Succeed())
+
+		other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(checksums.VerifyDirSignature(out, other.Public())).ToNot(Succeed())
+	})
+})
@@ -4,6 +4,9 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/url"
+
+	"github.com/kairos-io/AuroraBoot/pkg/artifactdiff"
 )
 
 // ArtifactsService groups the image-build endpoints.
@@ -53,6 +56,29 @@ func (s *ArtifactsService) Logs(ctx context.Context, id string) (string, error)
 	return string(b), nil
 }
 
+// SBOM names an artifact's SBOM document and returns its vulnerability
+// findings, if the build was matched against an advisory database. Fetch
+// the document itself with Download.
+func (s *ArtifactsService) SBOM(ctx context.Context, id string) (*ArtifactSBOM, error) {
+	var out ArtifactSBOM
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/artifacts/"+id+"/sbom", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Diff compares build from (A) with build to (B): the inputs that differ
+// and, read from their outputs, the package, kernel, initrd and rootfs
+// changes. Diff.WriteText renders it the way the CLI prints it.
+func (s *ArtifactsService) Diff(ctx context.Context, from, to string) (*artifactdiff.Diff, error) {
+	q := url.Values{"from": {from}, "to": {to}}
+	var out artifactdiff.Diff
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/artifacts/diff", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 // Cancel aborts a running build.
 func (s *ArtifactsService) Cancel(ctx context.Context, id string) error {
 	return s.c.do(ctx, http.MethodPost, "/api/v1/artifacts/"+id+"/cancel", nil, nil, nil)
@@ -77,6 +103,17 @@ func (s *ArtifactsService) Update(ctx context.Context, id string, req UpdateArti
 	return &out, nil
 }
 
+// Publish pushes the build's OS image and/or output files to a registry
+// and returns the resulting digest references. It blocks until the push
+// completes.
+func (s *ArtifactsService) Publish(ctx context.Context, id string, req PublishRequest) (*Publication, error) {
+	var out Publication
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/artifacts/"+id+"/publish", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 // Download streams a produced artifact file (an ISO, UKI, raw disk,
 // netboot file...). The caller owns the returned ReadCloser and must
 // close it.
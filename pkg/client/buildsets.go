@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// BuildSetsService groups the build-matrix endpoints.
+type BuildSetsService struct{ c *Client }
+
+// Create expands the matrix and starts one build per combination.
+func (s *BuildSetsService) Create(ctx context.Context, req CreateBuildSetRequest) (*BuildSet, error) {
+	var out BuildSet
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/build-sets", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// List returns every build set with its aggregated status.
+func (s *BuildSetsService) List(ctx context.Context) ([]BuildSet, error) {
+	var out []BuildSet
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/build-sets", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Get fetches one build set including its child artifacts.
+func (s *BuildSetsService) Get(ctx context.Context, id string) (*BuildSet, error) {
+	var out BuildSet
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/build-sets/"+id, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Cancel aborts every child that has not finished yet.
+func (s *BuildSetsService) Cancel(ctx context.Context, id string) (*BuildSet, error) {
+	var out BuildSet
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/build-sets/"+id+"/cancel", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Clone starts a new build set from the request of an existing one.
+func (s *BuildSetsService) Clone(ctx context.Context, id string) (*BuildSet, error) {
+	var out BuildSet
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/build-sets/"+id+"/clone", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Delete removes the grouping; the child artifacts are kept.
+func (s *BuildSetsService) Delete(ctx context.Context, id string) error {
+	return s.c.do(ctx, http.MethodDelete, "/api/v1/build-sets/"+id, nil, nil, nil)
+}
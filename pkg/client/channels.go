@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// ChannelsService groups the artifact channel endpoints.
+type ChannelsService struct{ c *Client }
+
+// Create adds an empty channel.
+func (s *ChannelsService) Create(ctx context.Context, name, description string) (*Channel, error) {
+	body := map[string]string{"name": name, "description": description}
+	var out Channel
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/channels", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// List returns every channel.
+func (s *ChannelsService) List(ctx context.Context) ([]Channel, error) {
+	var out []Channel
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/channels", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// History returns a channel's promotions and rollbacks, newest first.
+func (s *ChannelsService) History(ctx context.Context, channelID string) ([]ChannelEntry, error) {
+	var out []ChannelEntry
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/channels/"+channelID+"/history", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Promote points a channel at an artifact, or at what another channel
+// points at.
+func (s *ChannelsService) Promote(ctx context.Context, channelID string, req MoveChannelRequest) (*ChannelMove, error) {
+	var out ChannelMove
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/channels/"+channelID+"/promote", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Rollback points a channel back at an earlier artifact.
+func (s *ChannelsService) Rollback(ctx context.Context, channelID string, req MoveChannelRequest) (*ChannelMove, error) {
+	var out ChannelMove
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/channels/"+channelID+"/rollback", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Delete removes a channel and its history; groups following it stop.
+func (s *ChannelsService) Delete(ctx context.Context, channelID string) error {
+	return s.c.do(ctx, http.MethodDelete, "/api/v1/channels/"+channelID, nil, nil, nil)
+}
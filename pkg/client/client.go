@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -28,15 +29,25 @@ type Client struct {
 
 	adminPassword string
 	nodeAPIKey    string
+	workerAPIKey  string
 
 	// Service handles. Populated once in New so downstream users can
 	// write `cli.Nodes.List(ctx, nil)` etc.
-	Nodes      *NodesService
-	Groups     *GroupsService
-	Artifacts  *ArtifactsService
-	Commands   *CommandsService
-	SecureBoot *SecureBootService
-	Settings   *SettingsService
+	Nodes       *NodesService
+	Groups      *GroupsService
+	Artifacts   *ArtifactsService
+	Commands    *CommandsService
+	SecureBoot  *SecureBootService
+	Settings    *SettingsService
+	Workers     *WorkersService
+	BuildSets   *BuildSetsService
+	Registries  *RegistriesService
+	Channels    *ChannelsService
+	Retention   *RetentionService
+	Secrets     *SecretsService
+	Templates   *BuildTemplatesService
+	ImageWatch  *ImageWatchService
+	CloudConfig *CloudConfigService
 }
 
 // New constructs a client pointed at baseURL. baseURL should NOT
@@ -62,6 +73,15 @@ func New(baseURL string, opts ...Option) *Client {
 	c.Commands = &CommandsService{c: c}
 	c.SecureBoot = &SecureBootService{c: c}
 	c.Settings = &SettingsService{c: c}
+	c.Workers = &WorkersService{c: c}
+	c.BuildSets = &BuildSetsService{c: c}
+	c.Registries = &RegistriesService{c: c}
+	c.Channels = &ChannelsService{c: c}
+	c.Retention = &RetentionService{c: c}
+	c.Secrets = &SecretsService{c: c}
+	c.Templates = &BuildTemplatesService{c: c}
+	c.ImageWatch = &ImageWatchService{c: c}
+	c.CloudConfig = &CloudConfigService{c: c}
 	return c
 }
 
@@ -77,6 +97,12 @@ func WithNodeAPIKey(apiKey string) Option {
 	return func(c *Client) { c.nodeAPIKey = apiKey }
 }
 
+// WithWorkerAPIKey authenticates subsequent calls with a build worker's
+// API key. Used by `auroraboot worker` after registration.
+func WithWorkerAPIKey(apiKey string) Option {
+	return func(c *Client) { c.workerAPIKey = apiKey }
+}
+
 // WithHTTPClient overrides the underlying http.Client. Use this to
 // configure timeouts, a custom transport, retries etc.
 func WithHTTPClient(h *http.Client) Option {
@@ -116,6 +142,39 @@ func (c *Client) WithNodeAPIKey(apiKey string) *Client {
 	cpy.Commands = &CommandsService{c: &cpy}
 	cpy.SecureBoot = &SecureBootService{c: &cpy}
 	cpy.Settings = &SettingsService{c: &cpy}
+	cpy.Workers = &WorkersService{c: &cpy}
+	cpy.BuildSets = &BuildSetsService{c: &cpy}
+	cpy.Registries = &RegistriesService{c: &cpy}
+	cpy.Channels = &ChannelsService{c: &cpy}
+	cpy.Retention = &RetentionService{c: &cpy}
+	cpy.Secrets = &SecretsService{c: &cpy}
+	cpy.Templates = &BuildTemplatesService{c: &cpy}
+	cpy.ImageWatch = &ImageWatchService{c: &cpy}
+	cpy.CloudConfig = &CloudConfigService{c: &cpy}
+	return &cpy
+}
+
+// WithWorkerAPIKey returns a shallow copy of the client bound to a build
+// worker's API key, mirroring WithNodeAPIKey for the worker registration
+// flow.
+func (c *Client) WithWorkerAPIKey(apiKey string) *Client {
+	cpy := *c
+	cpy.workerAPIKey = apiKey
+	cpy.Nodes = &NodesService{c: &cpy}
+	cpy.Groups = &GroupsService{c: &cpy}
+	cpy.Artifacts = &ArtifactsService{c: &cpy}
+	cpy.Commands = &CommandsService{c: &cpy}
+	cpy.SecureBoot = &SecureBootService{c: &cpy}
+	cpy.Settings = &SettingsService{c: &cpy}
+	cpy.Workers = &WorkersService{c: &cpy}
+	cpy.BuildSets = &BuildSetsService{c: &cpy}
+	cpy.Registries = &RegistriesService{c: &cpy}
+	cpy.Channels = &ChannelsService{c: &cpy}
+	cpy.Retention = &RetentionService{c: &cpy}
+	cpy.Secrets = &SecretsService{c: &cpy}
+	cpy.Templates = &BuildTemplatesService{c: &cpy}
+	cpy.ImageWatch = &ImageWatchService{c: &cpy}
+	cpy.CloudConfig = &CloudConfigService{c: &cpy}
 	return &cpy
 }
 
@@ -223,6 +282,8 @@ func (c *Client) do(ctx context.Context, method, path string, query url.Values,
 		req.Header.Set("Authorization", "Bearer "+c.adminPassword)
 	} else if c.nodeAPIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+c.nodeAPIKey)
+	} else if c.workerAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.workerAPIKey)
 	}
 
 	resp, err := c.httpClient.Do(req)
@@ -268,8 +329,10 @@ func (c *Client) doRaw(ctx context.Context, method, path string, query url.Value
 		req.Header.Set("Authorization", "Bearer "+c.adminPassword)
 	} else if c.nodeAPIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+c.nodeAPIKey)
+	} else if c.workerAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.workerAPIKey)
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.rawClient().Do(req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -281,6 +344,31 @@ func (c *Client) doRaw(ctx context.Context, method, path string, query url.Value
 	return resp.Body, resp, nil
 }
 
+// rawClient is httpClient with redirects to another host:port stripped of
+// the bearer. A server keeping artifacts in object storage answers downloads
+// with a redirect to a presigned URL, which carries its own credentials;
+// net/http only compares hostnames before forwarding Authorization, so an
+// object store on the server's own host (MinIO on another port) would
+// otherwise receive the admin password, and refuse the request for carrying
+// two credentials.
+func (c *Client) rawClient() *http.Client {
+	hc := *c.httpClient
+	next := hc.CheckRedirect
+	hc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Host != via[0].URL.Host {
+			req.Header.Del("Authorization")
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &hc
+}
+
 // decodeJSON decodes r into out. Small helper used by service methods
 // that need to parse a response body from doRaw (which returns a raw
 // ReadCloser rather than decoding into a target struct).
@@ -0,0 +1,21 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CloudConfigService groups the cloud-config endpoints.
+type CloudConfigService struct{ c *Client }
+
+// Validate lints a cloud-config with the checks a build runs. Set fragment
+// for the extra YAML of a build request, which needs no header. A config
+// with errors is not an error here: check CloudConfigReport.Valid.
+func (s *CloudConfigService) Validate(ctx context.Context, cloudConfig string, fragment bool) (*CloudConfigReport, error) {
+	var out CloudConfigReport
+	body := map[string]any{"cloudConfig": cloudConfig, "fragment": fragment}
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/cloud-config/validate", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
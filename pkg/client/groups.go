@@ -46,6 +46,18 @@ func (s *GroupsService) Update(ctx context.Context, groupID, name, description s
 	return &out, nil
 }
 
+// Follow makes the group follow a channel: every promotion or rollback of
+// it queues an upgrade on the group's nodes. An empty channelID stops
+// following.
+func (s *GroupsService) Follow(ctx context.Context, groupID, channelID string) (*Group, error) {
+	body := map[string]string{"followChannelId": channelID}
+	var out Group
+	if err := s.c.do(ctx, http.MethodPut, "/api/v1/groups/"+groupID, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 // Delete removes a group. Member nodes are detached (their groupID is
 // cleared) in the same transaction; nodes themselves are never deleted.
 func (s *GroupsService) Delete(ctx context.Context, groupID string) error {
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// ImageWatchService groups the base image watcher endpoints.
+type ImageWatchService struct{ c *Client }
+
+// Get reads the watcher's schedule and the result of its last run.
+func (s *ImageWatchService) Get(ctx context.Context) (*ImageWatchStatus, error) {
+	var out ImageWatchStatus
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/image-watch", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetSchedule sets the cron schedule the watcher checks on. An empty
+// schedule turns it off.
+func (s *ImageWatchService) SetSchedule(ctx context.Context, schedule string) (*ImageWatchStatus, error) {
+	var out ImageWatchStatus
+	body := map[string]string{"schedule": schedule}
+	if err := s.c.do(ctx, http.MethodPut, "/api/v1/image-watch", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Run checks the watched base images now and rebuilds those that moved.
+func (s *ImageWatchService) Run(ctx context.Context) (*ImageWatchRun, error) {
+	var out ImageWatchRun
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/image-watch/run", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
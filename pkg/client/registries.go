@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// RegistriesService groups the publishing-registry endpoints.
+type RegistriesService struct{ c *Client }
+
+// Create saves a registry and its push credentials.
+func (s *RegistriesService) Create(ctx context.Context, req RegistryRequest) (*Registry, error) {
+	var out Registry
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/registries", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// List returns every configured registry.
+func (s *RegistriesService) List(ctx context.Context) ([]Registry, error) {
+	var out []Registry
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/registries", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Update replaces a registry's settings; an empty password keeps the
+// stored one.
+func (s *RegistriesService) Update(ctx context.Context, id string, req RegistryRequest) (*Registry, error) {
+	var out Registry
+	if err := s.c.do(ctx, http.MethodPut, "/api/v1/registries/"+id, nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Delete removes a registry. Past publications keep their references.
+func (s *RegistriesService) Delete(ctx context.Context, id string) error {
+	return s.c.do(ctx, http.MethodDelete, "/api/v1/registries/"+id, nil, nil, nil)
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// RetentionService groups the artifact retention endpoints.
+type RetentionService struct{ c *Client }
+
+// GetPolicy reads the retention policy.
+func (s *RetentionService) GetPolicy(ctx context.Context) (*RetentionPolicy, error) {
+	var out RetentionPolicy
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/retention", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetPolicy replaces the retention policy.
+func (s *RetentionService) SetPolicy(ctx context.Context, policy RetentionPolicy) (*RetentionPolicy, error) {
+	var out RetentionPolicy
+	if err := s.c.do(ctx, http.MethodPut, "/api/v1/retention", nil, policy, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Plan lists what the policy would remove now, without removing it.
+func (s *RetentionService) Plan(ctx context.Context) (*RetentionPlan, error) {
+	var out RetentionPlan
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/retention/plan", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Run applies the policy now and returns what it removed.
+func (s *RetentionService) Run(ctx context.Context) (*RetentionPlan, error) {
+	var out RetentionPlan
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/retention/run", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// SecretsService groups the secret endpoints. Secrets hold credentials,
+// such as the token a Git source is cloned with; values are write-only.
+type SecretsService struct{ c *Client }
+
+// List returns every secret, without values.
+func (s *SecretsService) List(ctx context.Context) ([]Secret, error) {
+	var out []Secret
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/secrets", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Set creates the secret or replaces its value.
+func (s *SecretsService) Set(ctx context.Context, name string, req SecretRequest) (*Secret, error) {
+	var out Secret
+	if err := s.c.do(ctx, http.MethodPut, "/api/v1/secrets/"+name, nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Delete removes a secret.
+func (s *SecretsService) Delete(ctx context.Context, name string) error {
+	return s.c.do(ctx, http.MethodDelete, "/api/v1/secrets/"+name, nil, nil, nil)
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// BuildTemplatesService groups the build template endpoints.
+type BuildTemplatesService struct{ c *Client }
+
+// List returns every template, without specs.
+func (s *BuildTemplatesService) List(ctx context.Context) ([]BuildTemplate, error) {
+	var out []BuildTemplate
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/build-templates", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Get fetches one template including its spec.
+func (s *BuildTemplatesService) Get(ctx context.Context, id string) (*BuildTemplate, error) {
+	var out BuildTemplate
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/build-templates/"+id, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Create stores a new template. Names are unique; a duplicate is a 409.
+func (s *BuildTemplatesService) Create(ctx context.Context, req BuildTemplateRequest) (*BuildTemplate, error) {
+	var out BuildTemplate
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/build-templates", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Update replaces a template.
+func (s *BuildTemplatesService) Update(ctx context.Context, id string, req BuildTemplateRequest) (*BuildTemplate, error) {
+	var out BuildTemplate
+	if err := s.c.do(ctx, http.MethodPut, "/api/v1/build-templates/"+id, nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Delete removes a template. Artifacts built from it are kept.
+func (s *BuildTemplatesService) Delete(ctx context.Context, id string) error {
+	return s.c.do(ctx, http.MethodDelete, "/api/v1/build-templates/"+id, nil, nil, nil)
+}
+
+// Build renders the template with the given parameters and overrides and
+// starts a build from it.
+func (s *BuildTemplatesService) Build(ctx context.Context, id string, req BuildFromTemplateRequest) (*Artifact, error) {
+	var out Artifact
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/build-templates/"+id+"/build", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Export returns the template as a YAML document Import accepts.
+func (s *BuildTemplatesService) Export(ctx context.Context, id string) ([]byte, error) {
+	body, _, err := s.c.doRaw(ctx, http.MethodGet, "/api/v1/build-templates/"+id+"/export", nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// Import creates the template in a YAML document, or replaces the one with
+// the same name.
+func (s *BuildTemplatesService) Import(ctx context.Context, r io.Reader) (*BuildTemplate, error) {
+	body, _, err := s.c.doRaw(ctx, http.MethodPost, "/api/v1/build-templates/import", nil, r, "application/yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	var out BuildTemplate
+	if err := decodeJSON(body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -42,12 +42,13 @@ type Node struct {
 
 // Group is a logical bucket of nodes (environment, cluster, role...).
 type Group struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description,omitempty"`
-	NodeCount   int       `json:"node_count,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description,omitempty"`
+	NodeCount       int       `json:"node_count,omitempty"`
+	FollowChannelID string    `json:"followChannelId,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
 }
 
 // NodeRegisterRequest is the body of POST /api/v1/nodes/register.
@@ -171,6 +172,16 @@ type Artifact struct {
 	GCE                     bool          `json:"gce"`
 	VHD                     bool          `json:"vhd"`
 	MAAS                    bool          `json:"maas"`
+	QCOW2                   bool          `json:"qcow2"`
+	QCOW2Compress           bool          `json:"qcow2Compress,omitempty"`
+	VHDDynamic              bool          `json:"vhdDynamic,omitempty"`
+	VHDX                    bool          `json:"vhdx"`
+	VMDK                    bool          `json:"vmdk"`
+	OVA                     bool          `json:"ova"`
+	OVACPUs                 int           `json:"ovaCpus,omitempty"`
+	OVAMemoryMB             int           `json:"ovaMemoryMB,omitempty"`
+	OVAUserDataProperty     bool          `json:"ovaUserDataProperty,omitempty"`
+	Compression             string        `json:"compression,omitempty"`
 	UKI                     bool          `json:"uki"`
 	FIPS                    bool          `json:"fips"`
 	TrustedBoot             bool          `json:"trustedBoot"`
@@ -184,9 +195,207 @@ type Artifact struct {
 	CloudConfig             string        `json:"cloudConfig,omitempty"`
 	TargetGroupID           string        `json:"targetGroupId,omitempty"`
 	ContainerImage          string        `json:"containerImage,omitempty"`
+	BaseImageDigest         string        `json:"baseImageDigest,omitempty"`
+	KairosInitImageDigest   string        `json:"kairosInitImageDigest,omitempty"`
+	ContainerImageDigest    string        `json:"containerImageDigest,omitempty"`
 	Artifacts               []string      `json:"artifacts,omitempty"`
+	BuildSetID              string        `json:"buildSetId,omitempty"`
+	TemplateID              string        `json:"templateId,omitempty"`
 	CreatedAt               time.Time     `json:"createdAt"`
 	UpdatedAt               time.Time     `json:"updatedAt"`
+	// Vulnerabilities is set once an SBOM build has been matched against
+	// the builder's advisory database.
+	SBOM            bool                  `json:"sbom,omitempty"`
+	SBOMFormat      string                `json:"sbomFormat,omitempty"`
+	Vulnerabilities *VulnerabilitySummary `json:"vulnerabilities,omitempty"`
+	// ManifestKeySetID is the SecureBoot key set the SHA256SUMS of the
+	// outputs is signed with; empty means the server's manifest key, if any.
+	ManifestKeySetID string `json:"manifestKeySetId,omitempty"`
+	// Publications lists every push of the build to a registry.
+	Publications []Publication `json:"publications,omitempty"`
+	// Git is the repository the build's inputs came from, with the commit
+	// it was built from once it finishes.
+	Git *GitSource `json:"git,omitempty"`
+}
+
+// GitSource is a repository a build takes its Dockerfile and overlay/
+// from. Secret names the secret holding the password or token; Commit is
+// set on artifacts only.
+type GitSource struct {
+	URL      string `json:"url"`
+	Ref      string `json:"ref,omitempty"`
+	Subpath  string `json:"subpath,omitempty"`
+	Username string `json:"username,omitempty"`
+	Secret   string `json:"secret,omitempty"`
+	Commit   string `json:"commit,omitempty"`
+}
+
+// Secret is a named credential. The value is never returned.
+type Secret struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SecretRequest is the body of PUT /api/v1/secrets/:name.
+type SecretRequest struct {
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// Publication records one push of an artifact to a registry. Image and
+// Files are digest references.
+type Publication struct {
+	RegistryID  string    `json:"registryId"`
+	Image       string    `json:"image,omitempty"`
+	Files       string    `json:"files,omitempty"`
+	Tags        []string  `json:"tags"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+// PublishRequest is the body of POST /api/v1/artifacts/:id/publish. Image
+// pushes the OS container image under Tags; Outputs pushes the output
+// files (all, or only Files) as an OCI artifact tagged <tag>-artifacts.
+type PublishRequest struct {
+	RegistryID string   `json:"registryId"`
+	Repository string   `json:"repository"`
+	Tags       []string `json:"tags"`
+	Image      bool     `json:"image,omitempty"`
+	Outputs    bool     `json:"outputs,omitempty"`
+	Files      []string `json:"files,omitempty"`
+}
+
+// Registry is an OCI registry artifacts can be published to. The password
+// is write-only and never returned.
+type Registry struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Host      string    `json:"host"`
+	Namespace string    `json:"namespace,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	Insecure  bool      `json:"insecure,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// RegistryRequest is the body of POST /api/v1/registries and
+// PUT /api/v1/registries/:id. On update an empty Password keeps the
+// stored one.
+type RegistryRequest struct {
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Namespace string `json:"namespace,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+	Insecure  bool   `json:"insecure,omitempty"`
+}
+
+// Channel is a named pointer to an artifact, such as "ubuntu-k3s/stable".
+// Upgrades with source "channel:<name>" install whatever it points at when
+// the node receives the command.
+type Channel struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	ArtifactID  string    `json:"artifactId,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// ChannelEntry is one promotion or rollback of a channel.
+type ChannelEntry struct {
+	ID                 string    `json:"id"`
+	ChannelID          string    `json:"channelId"`
+	Action             string    `json:"action"`
+	ArtifactID         string    `json:"artifactId"`
+	PreviousArtifactID string    `json:"previousArtifactId,omitempty"`
+	FromChannel        string    `json:"fromChannel,omitempty"`
+	Note               string    `json:"note,omitempty"`
+	CreatedAt          time.Time `json:"createdAt"`
+}
+
+// MoveChannelRequest is the body of the promote and rollback endpoints.
+// Promote takes exactly one of ArtifactID and FromChannel; rollback takes
+// an optional ArtifactID and defaults to the previous artifact.
+type MoveChannelRequest struct {
+	ArtifactID  string `json:"artifactId,omitempty"`
+	FromChannel string `json:"fromChannel,omitempty"`
+	Note        string `json:"note,omitempty"`
+}
+
+// ChannelMove is returned by promote and rollback. Queued counts the
+// upgrades queued for nodes in groups following the channel.
+type ChannelMove struct {
+	Channel Channel      `json:"channel"`
+	Entry   ChannelEntry `json:"entry"`
+	Queued  int          `json:"queued"`
+}
+
+// RetentionPolicy holds the artifact retention rules. Zero disables a rule.
+type RetentionPolicy struct {
+	KeepLastPerBase int   `json:"keepLastPerBase"`
+	MaxAgeDays      int   `json:"maxAgeDays"`
+	MaxTotalBytes   int64 `json:"maxTotalBytes"`
+}
+
+// RetentionRemoval is an artifact the retention policy removes, and why
+// ("keep-last", "max-age" or "max-total-bytes").
+type RetentionRemoval struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Base      string    `json:"base,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Bytes     int64     `json:"bytes"`
+	Reason    string    `json:"reason"`
+}
+
+// RetentionKept is an artifact a rule matched that stays because a
+// deployment, a channel or the netboot server still uses it.
+type RetentionKept struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Reason    string `json:"reason"`
+	Protected string `json:"protected"`
+}
+
+// RetentionPlan is what the retention policy removes. Failed is only set
+// by Run, keyed by artifact ID.
+type RetentionPlan struct {
+	Remove     []RetentionRemoval `json:"remove"`
+	Kept       []RetentionKept    `json:"kept,omitempty"`
+	TotalBytes int64              `json:"totalBytes"`
+	FreedBytes int64              `json:"freedBytes"`
+	OverBudget bool               `json:"overBudget,omitempty"`
+	Failed     map[string]string  `json:"failed,omitempty"`
+}
+
+// ImageWatchStatus is the base image watcher's schedule, when it runs next
+// and what its last run found.
+type ImageWatchStatus struct {
+	Schedule string         `json:"schedule"`
+	NextRun  *time.Time     `json:"nextRun,omitempty"`
+	LastRun  *ImageWatchRun `json:"lastRun,omitempty"`
+}
+
+// ImageWatchRun is one pass of the watcher over the watched base images.
+type ImageWatchRun struct {
+	StartedAt  time.Time    `json:"startedAt"`
+	FinishedAt time.Time    `json:"finishedAt"`
+	Checks     []ImageCheck `json:"checks"`
+}
+
+// ImageCheck is what the watcher found for one watched build. RebuildID is
+// set when the base image moved and a rebuild was started.
+type ImageCheck struct {
+	Image          string `json:"image"`
+	ArtifactID     string `json:"artifactId"`
+	TemplateID     string `json:"templateId,omitempty"`
+	PreviousDigest string `json:"previousDigest"`
+	Digest         string `json:"digest,omitempty"`
+	Changed        bool   `json:"changed"`
+	RebuildID      string `json:"rebuildId,omitempty"`
+	Error          string `json:"error,omitempty"`
 }
 
 // CreateArtifactRequest is the body of POST /api/v1/artifacts.
@@ -210,6 +419,7 @@ type CreateArtifactRequest struct {
 	HadronExtra             string                 `json:"hadronExtra,omitempty"`
 	OverlayRootfs           string                 `json:"overlayRootfs,omitempty"`
 	KairosInitImage         string                 `json:"kairosInitImage,omitempty"`
+	Git                     *GitSource             `json:"git,omitempty"`
 	Outputs                 ArtifactOutputs        `json:"outputs"`
 	Signing                 ArtifactSigning        `json:"signing"`
 	Provisioning            ArtifactProvisioning   `json:"provisioning"`
@@ -230,9 +440,63 @@ type ArtifactOutputs struct {
 	UKI         bool `json:"uki,omitempty"`
 	FIPS        bool `json:"fips,omitempty"`
 	TrustedBoot bool `json:"trustedBoot,omitempty"`
+	// QCOW2Compress deflates the clusters of the qcow2 image.
+	QCOW2         bool `json:"qcow2,omitempty"`
+	QCOW2Compress bool `json:"qcow2Compress,omitempty"`
+	// VHDDynamic makes the VHD a sparse, dynamic one instead of the fixed
+	// one Azure needs.
+	VHDDynamic bool `json:"vhdDynamic,omitempty"`
+	VHDX       bool `json:"vhdx,omitempty"`
+	// OVACPUs and OVAMemoryMB size the virtual machine of the OVA (2 CPUs
+	// and 4096 MiB when zero).
+	VMDK                bool `json:"vmdk,omitempty"`
+	OVA                 bool `json:"ova,omitempty"`
+	OVACPUs             int  `json:"ovaCpus,omitempty"`
+	OVAMemoryMB         int  `json:"ovaMemoryMB,omitempty"`
+	OVAUserDataProperty bool `json:"ovaUserDataProperty,omitempty"`
+	// SBOMFormat is "spdx-json" (the default) or "cyclonedx-json".
+	SBOM       bool   `json:"sbom,omitempty"`
+	SBOMFormat string `json:"sbomFormat,omitempty"`
+	// Compression is "zstd", "xz" or "gzip" to compress the raw disk, ISO
+	// and VHD outputs.
+	Compression string `json:"compression,omitempty"`
+}
+
+// VulnerabilitySummary counts the advisories matching an artifact's
+// installed packages by severity.
+type VulnerabilitySummary struct {
+	Database  string    `json:"database"`
+	Critical  int       `json:"critical"`
+	High      int       `json:"high"`
+	Medium    int       `json:"medium"`
+	Low       int       `json:"low"`
+	Unknown   int       `json:"unknown"`
+	Total     int       `json:"total"`
+	ScannedAt time.Time `json:"scannedAt"`
+}
+
+// VulnerabilityFinding is one advisory affecting one installed package.
+type VulnerabilityFinding struct {
+	ID           string   `json:"id"`
+	Aliases      []string `json:"aliases,omitempty"`
+	Package      string   `json:"package"`
+	Version      string   `json:"version"`
+	FixedVersion string   `json:"fixedVersion,omitempty"`
+	Severity     string   `json:"severity"`
+	Summary      string   `json:"summary,omitempty"`
+}
+
+// ArtifactSBOM is the response of GET /api/v1/artifacts/:id/sbom. File is
+// the SBOM document's name for Download.
+type ArtifactSBOM struct {
+	Format          string                 `json:"format"`
+	File            string                 `json:"file"`
+	Vulnerabilities *VulnerabilitySummary  `json:"vulnerabilities,omitempty"`
+	Findings        []VulnerabilityFinding `json:"findings,omitempty"`
 }
 
-// ArtifactSigning describes UKI SecureBoot signing options.
+// ArtifactSigning describes UKI SecureBoot signing options, and the key
+// set the SHA256SUMS of the outputs is signed with.
 type ArtifactSigning struct {
 	UKIKeySetID         string `json:"ukiKeySetId,omitempty"`
 	UKISecureBootKey    string `json:"ukiSecureBootKey,omitempty"`
@@ -240,6 +504,7 @@ type ArtifactSigning struct {
 	UKITPMPCRKey        string `json:"ukiTpmPcrKey,omitempty"`
 	UKIPublicKeysDir    string `json:"ukiPublicKeysDir,omitempty"`
 	UKISecureBootEnroll string `json:"ukiSecureBootEnroll,omitempty"`
+	ManifestKeySetID    string `json:"manifestKeySetId,omitempty"`
 }
 
 // ArtifactProvisioning describes cloud-config injection options.
@@ -259,6 +524,90 @@ type UpdateArtifactRequest struct {
 	Saved *bool  `json:"saved,omitempty"`
 }
 
+// CreateBuildSetRequest is the body of POST /api/v1/build-sets. Every
+// child is Template with one combination of the Matrix axes applied.
+type CreateBuildSetRequest struct {
+	Name     string                `json:"name,omitempty"`
+	Template CreateArtifactRequest `json:"template"`
+	Matrix   BuildMatrix           `json:"matrix"`
+}
+
+// BuildMatrix lists the values to vary across a build set. An empty axis
+// keeps the template's value.
+type BuildMatrix struct {
+	BaseImages []string           `json:"baseImages,omitempty"`
+	Arches     []string           `json:"arches,omitempty"`
+	Variants   []string           `json:"variants,omitempty"`
+	Kubernetes []MatrixKubernetes `json:"kubernetes,omitempty"`
+	Outputs    []ArtifactOutputs  `json:"outputs,omitempty"`
+}
+
+// MatrixKubernetes is one Kubernetes distro/version value of a matrix.
+type MatrixKubernetes struct {
+	Distro  string `json:"distro"`
+	Version string `json:"version,omitempty"`
+}
+
+// BuildSet is a build matrix with its status aggregated from its child
+// artifacts. Children is only populated by Get and the create/clone/cancel
+// responses, not by List.
+type BuildSet struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Phase     ArtifactPhase  `json:"phase"`
+	Counts    BuildSetCounts `json:"counts"`
+	Children  []Artifact     `json:"children,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// BuildSetCounts tallies a build set's children by phase.
+type BuildSetCounts struct {
+	Total    int `json:"total"`
+	Pending  int `json:"pending"`
+	Building int `json:"building"`
+	Ready    int `json:"ready"`
+	Error    int `json:"error"`
+}
+
+// BuildTemplate is a stored, parameterised build recipe. Spec is a partial
+// CreateArtifactRequest whose string values may reference Parameters as
+// ${{ name }}; List leaves it out.
+type BuildTemplate struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  []TemplateParameter    `json:"parameters,omitempty"`
+	Spec        map[string]interface{} `json:"spec,omitempty"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	UpdatedAt   time.Time              `json:"updatedAt"`
+}
+
+// TemplateParameter is one placeholder a template's spec may reference.
+type TemplateParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// BuildTemplateRequest is the body of creating or replacing a template.
+// Spec is kept loosely typed so a template can leave any field unset.
+type BuildTemplateRequest struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  []TemplateParameter    `json:"parameters,omitempty"`
+	Spec        map[string]interface{} `json:"spec"`
+}
+
+// BuildFromTemplateRequest is the body of POST
+// /api/v1/build-templates/{id}/build. Overrides is a partial
+// CreateArtifactRequest merged over the template's spec.
+type BuildFromTemplateRequest struct {
+	Name       string                 `json:"name,omitempty"`
+	Parameters map[string]string      `json:"parameters,omitempty"`
+	Overrides  map[string]interface{} `json:"overrides,omitempty"`
+}
+
 // SecureBootKeySet is a generated or imported UKI signing key set.
 type SecureBootKeySet struct {
 	ID               string    `json:"id"`
@@ -280,3 +629,48 @@ type GenerateKeySetRequest struct {
 type RegistrationTokenResponse struct {
 	RegistrationToken string `json:"registrationToken"`
 }
+
+// BuildWorker is a remote build host registered with `auroraboot worker`.
+type BuildWorker struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Arch           string     `json:"arch"`
+	Version        string     `json:"version,omitempty"`
+	CurrentBuildID string     `json:"currentBuildId,omitempty"`
+	LastHeartbeat  *time.Time `json:"lastHeartbeat,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// WorkerRegisterRequest is the body of POST /api/v1/workers/register.
+type WorkerRegisterRequest struct {
+	RegistrationToken string `json:"registrationToken"`
+	Name              string `json:"name"`
+	Arch              string `json:"arch"`
+	Version           string `json:"version,omitempty"`
+}
+
+// WorkerRegisterResponse carries the new worker's id and the API key it
+// authenticates every later worker call with.
+type WorkerRegisterResponse struct {
+	ID     string `json:"id"`
+	APIKey string `json:"apiKey"`
+}
+
+// CloudConfigReport is the response of POST /api/v1/cloud-config/validate.
+// A build is refused when Errors is not empty.
+type CloudConfigReport struct {
+	Valid    bool               `json:"valid"`
+	Errors   []CloudConfigIssue `json:"errors"`
+	Warnings []CloudConfigIssue `json:"warnings"`
+}
+
+// CloudConfigIssue is one lint finding. Line and Column are 1-based and
+// zero when unknown; Path is the key it is about, e.g. "install.device".
+type CloudConfigIssue struct {
+	Severity string `json:"severity"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+)
+
+// WorkersService groups the remote build-worker endpoints. The admin
+// calls (List, Delete) use the client's admin password; the rest are the
+// protocol `auroraboot worker` speaks and need a client bound to the
+// worker's API key via WithWorkerAPIKey.
+type WorkersService struct{ c *Client }
+
+// Register enrolls a build worker. Authentication is the worker
+// registration token carried inside the request body, as for node
+// registration.
+func (s *WorkersService) Register(ctx context.Context, req WorkerRegisterRequest) (*WorkerRegisterResponse, error) {
+	var out WorkerRegisterResponse
+	if err := s.c.do(ctx, http.MethodPost, "/api/v1/workers/register", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// List returns every registered build worker.
+func (s *WorkersService) List(ctx context.Context) ([]BuildWorker, error) {
+	var out []BuildWorker
+	if err := s.c.do(ctx, http.MethodGet, "/api/v1/workers", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Delete removes a build worker. Its API key stops working immediately;
+// a build it was running is re-queued once the lease lapses.
+func (s *WorkersService) Delete(ctx context.Context, id string) error {
+	return s.c.do(ctx, http.MethodDelete, "/api/v1/workers/"+id, nil, nil, nil)
+}
+
+// Lease asks for the next queued build for the worker's architecture. A
+// nil lease with a nil error means the queue is empty.
+func (s *WorkersService) Lease(ctx context.Context) (*builder.WorkerLease, error) {
+	body, resp, err := s.c.doRaw(ctx, http.MethodPost, "/api/v1/worker/lease", nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	var out builder.WorkerLease
+	if err := decodeJSON(body, &out); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &out, nil
+}
+
+// Heartbeat reports the worker alive and, when buildID is set, renews that
+// build's lease. An error satisfying IsConflict means the lease was lost
+// and the build must be abandoned.
+func (s *WorkersService) Heartbeat(ctx context.Context, buildID string) error {
+	body := map[string]string{"buildId": buildID}
+	return s.c.do(ctx, http.MethodPost, "/api/v1/worker/heartbeat", nil, body, nil)
+}
+
+// AppendLog streams a chunk of build output for a leased build.
+func (s *WorkersService) AppendLog(ctx context.Context, buildID, text string) error {
+	body, _, err := s.c.doRaw(ctx, http.MethodPost, "/api/v1/worker/builds/"+buildID+"/logs", nil, strings.NewReader(text), "text/plain")
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, body)
+	return body.Close()
+}
+
+// Finish reports the terminal result of a leased build.
+func (s *WorkersService) Finish(ctx context.Context, buildID string, res builder.WorkerResult) error {
+	return s.c.do(ctx, http.MethodPost, "/api/v1/worker/builds/"+buildID+"/finish", nil, res, nil)
+}
+
+// Upload PUTs one output file of a leased build to the per-build upload
+// endpoint. It authenticates with the build's upload token from the lease,
+// not with the client's own credentials, exactly like the operator
+// backend's exporter Job. A non-empty sha256 (hex) is sent along so the
+// server refuses a body that does not match it.
+func (s *WorkersService) Upload(ctx context.Context, buildID, uploadToken, filename, sha256 string, r io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut,
+		s.c.baseURL+"/api/v1/artifacts/"+buildID+"/upload/"+filename, r)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "Bearer "+uploadToken)
+	if sha256 != "" {
+		req.Header.Set(checksums.Header, sha256)
+	}
+	if s.c.userAgent != "" {
+		req.Header.Set("User-Agent", s.c.userAgent)
+	}
+	resp, err := s.c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
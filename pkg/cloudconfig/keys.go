@@ -0,0 +1,183 @@
+package cloudconfig
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	kschema "github.com/kairos-io/kairos-sdk/schema"
+	yip "github.com/mudler/yip/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// topLevelKeys are the top-level keys of a Kairos cloud-config: those of
+// the Kairos config schema plus the blocks it does not describe (reset,
+// upgrade, the Kubernetes providers, phonehome). Providers may add keys of
+// their own, so an unknown top-level key is only reported when it looks
+// like a misspelt known one.
+var topLevelKeys = union(jsonKeys(reflect.TypeOf(kschema.RootSchema{})), []string{
+	"install", "reset", "upgrade", "name",
+	"k3s", "k3s-agent", "k0s", "k0s-worker", "kubevip",
+	"phonehome", "kcrypt",
+})
+
+// installKeys are the options of the install block. The agent owns the
+// whole block, so any other key there is reported.
+var installKeys = union(jsonKeys(reflect.TypeOf(kschema.InstallSchema{})), []string{
+	"reboot", "poweroff", "source",
+})
+
+// stepKeys are the options of a yip stage step.
+var stepKeys = yamlKeys(reflect.TypeOf(yip.Stage{}))
+
+// stages are the stages the Kairos agent runs by itself. Each also has a
+// ".before" and ".after" form.
+var stages = []string{
+	"rootfs", "initramfs", "boot", "fs", "network", "reconcile",
+	"before-install", "after-install", "after-install-chroot",
+	"before-reset", "after-reset", "after-reset-chroot",
+	"before-upgrade", "after-upgrade", "after-upgrade-chroot",
+	"kairos-install.pre", "kairos-install.after",
+	"kairos-uki-install.pre", "kairos-uki-install.after",
+}
+
+// checkKeys warns about keys the agent would ignore.
+func checkKeys(top *yaml.Node, r *Report) {
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		k, v := top.Content[i], top.Content[i+1]
+		if !slices.Contains(topLevelKeys, k.Value) {
+			if s := suggest(k.Value, topLevelKeys); s != "" {
+				r.add(SeverityWarning, k.Line, k.Column, k.Value, "unknown key; did you mean %q?", s)
+			}
+			continue
+		}
+		switch k.Value {
+		case "install":
+			checkMapping(v, "install", installKeys, "install option", r)
+		case "stages":
+			checkStages(v, r)
+		}
+	}
+}
+
+// checkMapping reports every key of n that is not in known.
+func checkMapping(n *yaml.Node, path string, known []string, what string, r *Report) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k := n.Content[i]
+		if slices.Contains(known, k.Value) {
+			continue
+		}
+		msg := "unknown " + what + "; it is ignored"
+		if s := suggest(k.Value, known); s != "" {
+			msg = fmt.Sprintf("unknown %s; did you mean %q?", what, s)
+		}
+		r.add(SeverityWarning, k.Line, k.Column, joinPath(path, k.Value), "%s", msg)
+	}
+}
+
+func checkStages(n *yaml.Node, r *Report) {
+	if n.Kind != yaml.MappingNode {
+		// The schema reports the wrong type.
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		path := "stages." + k.Value
+		// Any stage name is valid, as one can be run by hand with
+		// kairos-agent run-stage; only a near miss of one the agent runs
+		// by itself is worth a warning.
+		if base := strings.TrimSuffix(strings.TrimSuffix(k.Value, ".before"), ".after"); !slices.Contains(stages, base) {
+			if s := suggest(base, stages); s != "" {
+				r.add(SeverityWarning, k.Line, k.Column, path, "unknown stage, never run by the agent; did you mean %q?", s)
+			}
+		}
+		if v.Kind != yaml.SequenceNode {
+			continue
+		}
+		for j, step := range v.Content {
+			checkMapping(step, fmt.Sprintf("%s[%d]", path, j), stepKeys, "step option", r)
+		}
+	}
+}
+
+// suggest returns the known key closest to key, if it is close enough to
+// be a typo of it.
+func suggest(key string, known []string) string {
+	best, bestDist := "", 0
+	for _, k := range known {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+		d := levenshtein(strings.ToLower(key), k)
+		if best == "" || d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	// One edit for short keys, two for longer ones: "instal" is a typo of
+	// "install", "env" is not one of "eject-cd".
+	if limit := 1 + min(len(key), len(best))/6; bestDist <= limit {
+		return best
+	}
+	return ""
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// jsonKeys lists the JSON names of t's fields, descending into embedded
+// structs without a name of their own.
+func jsonKeys(t reflect.Type) []string {
+	return tagKeys(t, "json")
+}
+
+// yamlKeys lists the YAML names of t's fields.
+func yamlKeys(t reflect.Type) []string {
+	return tagKeys(t, "yaml")
+}
+
+func tagKeys(t reflect.Type, tag string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		switch {
+		case name == "-" || f.Name == "_":
+		case name != "":
+			keys = append(keys, name)
+		case f.Anonymous && f.Type.Kind() == reflect.Struct:
+			keys = append(keys, tagKeys(f.Type, tag)...)
+		}
+	}
+	return keys
+}
+
+func union(a, b []string) []string {
+	out := append([]string(nil), a...)
+	for _, k := range b {
+		if !slices.Contains(out, k) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
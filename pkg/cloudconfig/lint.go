@@ -0,0 +1,231 @@
+// Package cloudconfig checks a Kairos cloud-config before it is baked into
+// an image, so that a typo like "instal:" or a malformed stage is reported
+// at build time instead of by a machine that fails to install.
+//
+// Lint reports two kinds of issues. Errors are what the Kairos agent would
+// reject or misread: YAML that does not parse, duplicate keys, and values
+// the Kairos config schema does not allow. Warnings are what it would
+// silently ignore: keys that look like a misspelt known key, unknown stage
+// step options, a missing header. Every issue carries the line and column
+// it was found at when they are known.
+//
+// A cloud-config may reference secrets of the server's secret store as
+// {{ secret "name" }}; ResolveSecrets substitutes them when the config is
+// baked into an image.
+package cloudconfig
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	goyaml "github.com/goccy/go-yaml"
+	goparser "github.com/goccy/go-yaml/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity classifies an Issue.
+type Severity string
+
+const (
+	// SeverityError marks a problem that makes the cloud-config unusable.
+	SeverityError Severity = "error"
+	// SeverityWarning marks something the agent would accept but most
+	// likely not do what was meant.
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is one problem found in a cloud-config. Line and Column are 1-based
+// and zero when the problem has no single position. Path names the key the
+// issue is about, e.g. "install.device" or "stages.boot[0].commands".
+type Issue struct {
+	Severity Severity `json:"severity" enums:"error,warning"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Path     string   `json:"path,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", i.Line)
+	}
+	if i.Path != "" {
+		b.WriteString(i.Path + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// Report is the outcome of linting one cloud-config. Both lists are sorted
+// by line.
+type Report struct {
+	Valid    bool    `json:"valid"`
+	Errors   []Issue `json:"errors"`
+	Warnings []Issue `json:"warnings"`
+}
+
+// Err returns nil when the report has no errors, otherwise an error listing
+// them.
+func (r *Report) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(r.Errors))
+	for i, e := range r.Errors {
+		msgs[i] = e.String()
+	}
+	return errors.New("invalid cloud-config: " + strings.Join(msgs, "; "))
+}
+
+func (r *Report) add(sev Severity, line, col int, path, format string, args ...any) {
+	issue := Issue{Severity: sev, Line: line, Column: col, Path: path, Message: fmt.Sprintf(format, args...)}
+	if sev == SeverityError {
+		r.Errors = append(r.Errors, issue)
+	} else {
+		r.Warnings = append(r.Warnings, issue)
+	}
+}
+
+// headers are the first lines the Kairos agent recognises a config by.
+var headers = []string{"#cloud-config", "#kairos-config", "#node-config"}
+
+// Lint checks a complete cloud-config document.
+func Lint(doc string) *Report {
+	r := lint(doc)
+	if strings.TrimSpace(doc) != "" && !hasHeader(doc) {
+		r.add(SeverityWarning, 1, 1, "", "missing #cloud-config header; the Kairos agent ignores a config file without one")
+	}
+	sortIssues(r)
+	return r
+}
+
+// LintFragment checks YAML that is merged into a generated cloud-config,
+// like the extra YAML of a build request. The header is optional there.
+func LintFragment(doc string) *Report {
+	r := lint(doc)
+	sortIssues(r)
+	return r
+}
+
+func lint(doc string) *Report {
+	r := &Report{Errors: []Issue{}, Warnings: []Issue{}}
+	defer func() { r.Valid = len(r.Errors) == 0 }()
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+		line, col, msg := syntaxError(doc, err)
+		r.add(SeverityError, line, col, "", "%s", msg)
+		return r
+	}
+	if len(root.Content) == 0 {
+		// Empty, or nothing but comments.
+		return r
+	}
+	top := root.Content[0]
+	if top.Kind != yaml.MappingNode {
+		if top.Tag == "!!null" {
+			return r
+		}
+		r.add(SeverityError, top.Line, top.Column, "", "a cloud-config must be a mapping of keys, not a %s", kindName(top))
+		return r
+	}
+	if duplicateKeys(top, "", r) {
+		// The agent fails to parse the document at all; what the schema
+		// says about either copy of the key would only add noise.
+		return r
+	}
+	if n := findUnquotedSecretRef(strings.Split(doc, "\n"), top); n != nil {
+		// The schema would only complain that a map is not a string.
+		r.add(SeverityError, n.Line, n.Column, "", "%s", unquotedSecretRefMsg)
+		return r
+	}
+	checkKeys(top, r)
+	checkSchema(doc, top, r)
+	return r
+}
+
+func hasHeader(doc string) bool {
+	for _, h := range headers {
+		if strings.HasPrefix(doc, h) {
+			return true
+		}
+	}
+	return false
+}
+
+var yamlLineRe = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// syntaxError positions a parse error. The agent parses with yaml.v3, so
+// its verdict stands, but yaml.v3 often names the line before the problem;
+// goccy/go-yaml points at the offending token, and is asked where it is.
+func syntaxError(doc string, err error) (line, col int, msg string) {
+	var se *goyaml.SyntaxError
+	if _, perr := goparser.ParseBytes([]byte(doc), 0); errors.As(perr, &se) && se.Token != nil {
+		return se.Token.Position.Line, se.Token.Position.Column, se.Message
+	}
+	msg = err.Error()
+	if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+		line, _ = strconv.Atoi(m[1])
+		msg = strings.TrimPrefix(msg, m[0])
+	}
+	return line, 0, strings.TrimPrefix(msg, "yaml: ")
+}
+
+// duplicateKeys reports every key defined twice in the same mapping,
+// anywhere under n. It returns whether it found one.
+func duplicateKeys(n *yaml.Node, path string, r *Report) bool {
+	found := false
+	switch n.Kind {
+	case yaml.MappingNode:
+		seen := map[string]*yaml.Node{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			p := joinPath(path, k.Value)
+			if first, ok := seen[k.Value]; ok {
+				r.add(SeverityError, k.Line, k.Column, p, "key is already defined at line %d", first.Line)
+				found = true
+			} else {
+				seen[k.Value] = k
+			}
+			if duplicateKeys(v, p, r) {
+				found = true
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			if duplicateKeys(item, fmt.Sprintf("%s[%d]", path, i), r) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func kindName(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		return "list"
+	case yaml.ScalarNode:
+		return "scalar value"
+	default:
+		return "document"
+	}
+}
+
+func sortIssues(r *Report) {
+	for _, list := range [][]Issue{r.Errors, r.Warnings} {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Line < list[j].Line })
+	}
+}
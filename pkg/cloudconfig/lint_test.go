@@ -0,0 +1,136 @@
+package cloudconfig_test
+
+import (
+	"github.com/kairos-io/AuroraBoot/pkg/cloudconfig"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lint", func() {
+	It("accepts a typical cloud-config", func() {
+		r := cloudconfig.Lint(`#cloud-config
+install:
+  auto: true
+  device: auto
+  reboot: true
+k3s:
+  enabled: true
+phonehome:
+  url: https://auroraboot.lan
+  registration_token: abc
+  allowed_commands: [upgrade, reboot]
+stages:
+  initramfs:
+  - name: user
+    users:
+      kairos:
+        passwd: kairos
+        groups: [admin]
+  boot.after:
+  - commands:
+    - echo hi
+`)
+		Expect(r.Valid).To(BeTrue())
+		Expect(r.Errors).To(BeEmpty())
+		Expect(r.Warnings).To(BeEmpty())
+		Expect(r.Err()).ToNot(HaveOccurred())
+	})
+
+	It("reports YAML syntax errors with their line", func() {
+		r := cloudconfig.Lint("#cloud-config\ninstall:\n  auto: true\n device: auto\n")
+		Expect(r.Valid).To(BeFalse())
+		Expect(r.Errors).To(HaveLen(1))
+		Expect(r.Errors[0].Line).To(Equal(4))
+		Expect(r.Errors[0].Column).To(Equal(2))
+	})
+
+	It("points at a tab used for indentation", func() {
+		r := cloudconfig.Lint("#cloud-config\ninstall:\n  auto: true\n\tdevice: auto\n")
+		Expect(r.Errors).To(HaveLen(1))
+		Expect(r.Errors[0].Line).To(Equal(4))
+	})
+
+	It("reports duplicate keys at the second definition", func() {
+		r := cloudconfig.Lint("#cloud-config\ninstall:\n  auto: true\ninstall:\n  device: auto\n")
+		Expect(r.Errors).To(ConsistOf(cloudconfig.Issue{
+			Severity: cloudconfig.SeverityError, Line: 4, Column: 1, Path: "install",
+			Message: "key is already defined at line 2",
+		}))
+	})
+
+	It("maps schema violations to the offending key", func() {
+		r := cloudconfig.Lint(`#cloud-config
+install:
+  device: sda
+stages:
+  boot:
+  - commands: echo hi
+`)
+		Expect(r.Errors).To(HaveLen(2))
+		Expect(r.Errors[0].Line).To(Equal(3))
+		Expect(r.Errors[0].Path).To(Equal("install.device"))
+		Expect(r.Errors[0].Message).To(ContainSubstring("does not match pattern"))
+		Expect(r.Errors[1].Line).To(Equal(6))
+		Expect(r.Errors[1].Path).To(Equal("stages.boot[0].commands"))
+		Expect(r.Errors[1].Message).To(Equal("expected array, but got string"))
+		Expect(r.Err()).To(MatchError(ContainSubstring("line 3: install.device: does not match pattern")))
+	})
+
+	It("summarises a oneOf that no branch matches", func() {
+		r := cloudconfig.Lint("#cloud-config\ninstall:\n  reboot: true\n  poweroff: true\n")
+		Expect(r.Errors).To(HaveLen(1))
+		Expect(r.Errors[0].Path).To(Equal("install"))
+		Expect(r.Errors[0].Line).To(Equal(2))
+		Expect(r.Errors[0].Message).To(ContainSubstring("matches none of the allowed combinations"))
+	})
+
+	It("warns about misspelt keys, install options, stages and step options", func() {
+		r := cloudconfig.Lint(`#cloud-config
+instal:
+  auto: true
+install:
+  devise: auto
+stages:
+  bot:
+  - comands:
+    - echo hi
+my-provider:
+  enabled: true
+`)
+		Expect(r.Valid).To(BeTrue())
+		Expect(r.Warnings).To(HaveLen(4))
+		Expect(r.Warnings[0]).To(Equal(cloudconfig.Issue{
+			Severity: cloudconfig.SeverityWarning, Line: 2, Column: 1, Path: "instal",
+			Message: `unknown key; did you mean "install"?`,
+		}))
+		Expect(r.Warnings[1].Path).To(Equal("install.devise"))
+		Expect(r.Warnings[1].Message).To(ContainSubstring(`"device"`))
+		Expect(r.Warnings[2].Path).To(Equal("stages.bot"))
+		Expect(r.Warnings[2].Message).To(ContainSubstring(`"boot"`))
+		Expect(r.Warnings[3].Path).To(Equal("stages.bot[0].comands"))
+		Expect(r.Warnings[3].Message).To(ContainSubstring(`"commands"`))
+	})
+
+	It("passes on the agent's semantic checks", func() {
+		r := cloudconfig.Lint("#cloud-config\ninstall:\n  ssh_hardening: true\n")
+		Expect(r.Errors).To(HaveLen(1))
+		Expect(r.Errors[0].Path).To(Equal("install.ssh_hardening"))
+		Expect(r.Errors[0].Line).To(Equal(3))
+	})
+
+	It("warns about a missing header, unless linting a fragment", func() {
+		Expect(cloudconfig.Lint("install:\n  auto: true\n").Warnings).To(HaveLen(1))
+		Expect(cloudconfig.LintFragment("install:\n  auto: true\n").Warnings).To(BeEmpty())
+	})
+
+	It("rejects a document that is not a mapping", func() {
+		r := cloudconfig.LintFragment("- a\n- b\n")
+		Expect(r.Valid).To(BeFalse())
+		Expect(r.Errors[0].Message).To(ContainSubstring("mapping"))
+	})
+
+	It("accepts an empty fragment", func() {
+		Expect(cloudconfig.LintFragment("").Valid).To(BeTrue())
+		Expect(cloudconfig.LintFragment("# nothing yet\n").Valid).To(BeTrue())
+	})
+})
@@ -0,0 +1,164 @@
+package cloudconfig
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	kschema "github.com/kairos-io/kairos-sdk/schema"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// schemaLeaf is one concrete schema violation: where, and what.
+type schemaLeaf struct {
+	loc string // JSON pointer into the document
+	msg string
+}
+
+// checkSchema validates the document against the Kairos config schema and
+// runs the agent's own semantic checks.
+func checkSchema(doc string, top *yaml.Node, r *Report) {
+	kc, err := kschema.NewConfigFromYAML(doc, kschema.RootSchema{})
+	if err != nil {
+		// lint parsed it already; this cannot fail differently.
+		return
+	}
+	if !kc.IsValid() {
+		var ve *jsonschema.ValidationError
+		if !errors.As(kc.ValidationError, &ve) {
+			r.add(SeverityError, 0, 0, "", "schema: %v", kc.ValidationError)
+			return
+		}
+		seen := map[schemaLeaf]bool{}
+		for _, l := range leaves(ve) {
+			// The schema still requires the legacy top-level users list;
+			// users are normally declared in a stage instead.
+			if l.loc == "" && l.msg == "missing properties: 'users'" {
+				continue
+			}
+			if seen[l] {
+				continue
+			}
+			seen[l] = true
+			line, col, path := locate(top, l.loc)
+			r.add(SeverityError, line, col, path, "%s", l.msg)
+		}
+	}
+
+	warnings, err := kc.ValidateSemantics()
+	for _, w := range warnings {
+		path, msg, _ := strings.Cut(w, ": ")
+		line, col, path := locate(top, "/"+strings.ReplaceAll(path, ".", "/"))
+		r.add(SeverityWarning, line, col, path, "%s", msg)
+	}
+	if err != nil {
+		path, msg, _ := strings.Cut(err.Error(), ": ")
+		line, col, path := locate(top, "/"+strings.ReplaceAll(path, ".", "/"))
+		r.add(SeverityError, line, col, path, "%s", msg)
+	}
+}
+
+// leaves flattens a validation error to the violations at its leaves. The
+// branches of a oneOf each explain why they did not match, which mostly
+// repeats; only the violations common to all branches are kept, or a
+// single summary when there are none.
+func leaves(e *jsonschema.ValidationError) []schemaLeaf {
+	if len(e.Causes) == 0 {
+		return []schemaLeaf{{loc: e.InstanceLocation, msg: e.Message}}
+	}
+	if strings.HasSuffix(e.KeywordLocation, "/oneOf") || strings.HasSuffix(e.KeywordLocation, "/anyOf") {
+		var branches [][]schemaLeaf
+		for _, c := range e.Causes {
+			branches = append(branches, leaves(c))
+		}
+		var common []schemaLeaf
+		for _, l := range branches[0] {
+			inAll := true
+			for _, b := range branches[1:] {
+				if !containsLeaf(b, l) {
+					inAll = false
+					break
+				}
+			}
+			if inAll {
+				common = append(common, l)
+			}
+		}
+		if len(common) > 0 {
+			return common
+		}
+		var why []string
+		for _, b := range branches {
+			var parts []string
+			for _, l := range b {
+				parts = append(parts, fmt.Sprintf("%s %s", pointerPath(strings.TrimPrefix(l.loc, e.InstanceLocation)), l.msg))
+			}
+			why = append(why, strings.Join(parts, " and "))
+		}
+		return []schemaLeaf{{loc: e.InstanceLocation, msg: "matches none of the allowed combinations (" + strings.Join(why, "; or ") + ")"}}
+	}
+	var out []schemaLeaf
+	for _, c := range e.Causes {
+		out = append(out, leaves(c)...)
+	}
+	return out
+}
+
+func containsLeaf(list []schemaLeaf, l schemaLeaf) bool {
+	for _, x := range list {
+		if x == l {
+			return true
+		}
+	}
+	return false
+}
+
+// pointerPath turns a relative JSON pointer into a key path for messages.
+func pointerPath(p string) string {
+	return strings.TrimPrefix(strings.ReplaceAll(p, "/", "."), ".")
+}
+
+// locate finds the node a JSON pointer refers to and returns its position
+// and the pointer as a key path ("stages.boot[0].commands"). For a mapping
+// key the position is that of the key. When the pointer goes further than
+// the document, the deepest node found is used.
+func locate(top *yaml.Node, pointer string) (line, col int, path string) {
+	n := top
+	line, col = n.Line, n.Column
+	if pointer == "" || pointer == "/" {
+		return line, col, ""
+	}
+	for _, seg := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		seg = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+		switch n.Kind {
+		case yaml.MappingNode:
+			path = joinPath(path, seg)
+			var next *yaml.Node
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == seg {
+					line, col = n.Content[i].Line, n.Content[i].Column
+					next = n.Content[i+1]
+					break
+				}
+			}
+			if next == nil {
+				return line, col, path
+			}
+			n = next
+		case yaml.SequenceNode:
+			path = fmt.Sprintf("%s[%s]", path, seg)
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(n.Content) {
+				return line, col, path
+			}
+			n = n.Content[i]
+			line, col = n.Line, n.Column
+		default:
+			path = joinPath(path, seg)
+			return line, col, path
+		}
+	}
+	return line, col, path
+}
@@ -0,0 +1,145 @@
+package cloudconfig
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretRefRe matches a secret reference, {{ secret "name" }}. Other
+// {{ ... }} expressions are yip templates rendered on the node and are left
+// alone.
+var secretRefRe = regexp.MustCompile(`\{\{-?\s*secret\s+"([^"]*)"\s*-?\}\}`)
+
+// SecretRefs returns the names of the secrets doc references, each once, in
+// the order they first appear. References are looked for in the parsed
+// strings, so one written with escaped quotes in a double-quoted string
+// counts; a document that does not parse is searched as text.
+func SecretRefs(doc string) []string {
+	var texts []string
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+		texts = []string{doc}
+	} else {
+		texts = scalars(&root, nil)
+	}
+	var names []string
+	seen := map[string]bool{}
+	for _, t := range texts {
+		for _, m := range secretRefRe.FindAllStringSubmatch(t, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+func scalars(n *yaml.Node, out []string) []string {
+	if n.Kind == yaml.ScalarNode {
+		return append(out, n.Value)
+	}
+	for _, c := range n.Content {
+		out = scalars(c, out)
+	}
+	return out
+}
+
+// ResolveSecrets replaces every secret reference in doc with the value of
+// the secret it names. The value is substituted in the parsed string it is
+// part of and the document encoded again, so a value with quotes, colons or
+// newlines (an SSH key) stays one string. A document without references is
+// returned as is; one naming a secret missing from values is an error.
+// Only strings are searched: a reference in a comment stays as written.
+func ResolveSecrets(doc string, values map[string]string) (string, error) {
+	if !strings.Contains(doc, "secret") {
+		return doc, nil
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+		return "", fmt.Errorf("parsing cloud-config: %w", err)
+	}
+	if err := unquotedSecretRef(doc, &root); err != nil {
+		return "", err
+	}
+	changed, err := resolveNode(&root, values)
+	if err != nil || !changed {
+		return doc, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return "", fmt.Errorf("encoding cloud-config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("encoding cloud-config: %w", err)
+	}
+	out := buf.String()
+	// The header is a comment, and yaml.v3 may attach it to the first key
+	// and move it; the agent only reads a config that starts with it.
+	if first, _, _ := strings.Cut(doc, "\n"); hasHeader(doc) && !strings.HasPrefix(out, first) {
+		out = first + "\n" + out
+	}
+	return out, nil
+}
+
+func resolveNode(n *yaml.Node, values map[string]string) (changed bool, err error) {
+	if n.Kind == yaml.ScalarNode {
+		if !secretRefRe.MatchString(n.Value) {
+			return false, nil
+		}
+		n.Value = secretRefRe.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			name := secretRefRe.FindStringSubmatch(ref)[1]
+			v, ok := values[name]
+			if !ok && err == nil {
+				err = fmt.Errorf("secret %q is not defined", name)
+			}
+			return v
+		})
+		// Let the encoder pick a style that can hold the value.
+		n.Tag, n.Style = "!!str", 0
+		return true, err
+	}
+	for _, c := range n.Content {
+		ch, err := resolveNode(c, values)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || ch
+	}
+	return changed, nil
+}
+
+// unquotedSecretRef returns an error for the first reference written as a
+// plain value: YAML reads {{ secret "x" }} as a nested flow mapping, not a
+// string, so it cannot be substituted.
+func unquotedSecretRef(doc string, root *yaml.Node) error {
+	if n := findUnquotedSecretRef(strings.Split(doc, "\n"), root); n != nil {
+		return fmt.Errorf("line %d: %s", n.Line, unquotedSecretRefMsg)
+	}
+	return nil
+}
+
+const unquotedSecretRefMsg = `a secret reference must be quoted, e.g. '{{ secret "name" }}'`
+
+func findUnquotedSecretRef(lines []string, n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.MappingNode && n.Style&yaml.FlowStyle != 0 && n.Line > 0 && n.Line <= len(lines) {
+		if line := lines[n.Line-1]; n.Column > 0 && n.Column <= len(line) {
+			if loc := secretRefRe.FindStringIndex(line[n.Column-1:]); loc != nil && loc[0] == 0 {
+				return n
+			}
+		}
+	}
+	for _, c := range n.Content {
+		if found := findUnquotedSecretRef(lines, c); found != nil {
+			return found
+		}
+	}
+	return nil
+}
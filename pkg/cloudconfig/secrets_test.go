@@ -0,0 +1,87 @@
+package cloudconfig_test
+
+import (
+	"github.com/kairos-io/AuroraBoot/pkg/cloudconfig"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+var _ = Describe("Secret references", func() {
+	const doc = `#cloud-config
+stages:
+  network:
+  - name: wifi
+    files:
+    - path: /etc/wpa.conf
+      content: 'psk={{ secret "wifi-psk" }}'
+  initramfs:
+  - users:
+      kairos:
+        ssh_authorized_keys:
+        - "{{ secret \"ssh-key\" }}"
+        - '{{secret "wifi-psk"}}'
+    commands:
+    - echo {{ .Values.node.hostname }}
+`
+
+	It("lists the secrets a document references, once each", func() {
+		Expect(cloudconfig.SecretRefs(doc)).To(Equal([]string{"wifi-psk", "ssh-key"}))
+		Expect(cloudconfig.SecretRefs("#cloud-config\nhostname: a\n")).To(BeEmpty())
+	})
+
+	It("substitutes values inside the strings that reference them", func() {
+		out, err := cloudconfig.ResolveSecrets(doc, map[string]string{
+			"wifi-psk": `it's: "quoted"`,
+			"ssh-key":  "ssh-ed25519 AAAA\nsecond line",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(HavePrefix("#cloud-config\n"))
+		Expect(out).To(ContainSubstring("{{ .Values.node.hostname }}"))
+		Expect(out).ToNot(ContainSubstring("secret"))
+
+		var parsed struct {
+			Stages map[string][]struct {
+				Files []struct {
+					Content string `yaml:"content"`
+				} `yaml:"files"`
+				Users map[string]struct {
+					Keys []string `yaml:"ssh_authorized_keys"`
+				} `yaml:"users"`
+			} `yaml:"stages"`
+		}
+		Expect(yaml.Unmarshal([]byte(out), &parsed)).To(Succeed())
+		Expect(parsed.Stages["network"][0].Files[0].Content).To(Equal(`psk=it's: "quoted"`))
+		Expect(parsed.Stages["initramfs"][0].Users["kairos"].Keys).To(Equal([]string{
+			"ssh-ed25519 AAAA\nsecond line",
+			`it's: "quoted"`,
+		}))
+	})
+
+	It("leaves a document without references untouched", func() {
+		in := "#cloud-config\n# keep me\nhostname:   a\n"
+		Expect(cloudconfig.ResolveSecrets(in, nil)).To(Equal(in))
+	})
+
+	It("fails on a secret it has no value for", func() {
+		_, err := cloudconfig.ResolveSecrets(doc, map[string]string{"wifi-psk": "x"})
+		Expect(err).To(MatchError(ContainSubstring(`secret "ssh-key" is not defined`)))
+	})
+
+	It("rejects an unquoted reference, which YAML reads as a map", func() {
+		in := "#cloud-config\nwifi:\n  psk: {{ secret \"wifi-psk\" }}\n"
+		_, err := cloudconfig.ResolveSecrets(in, map[string]string{"wifi-psk": "x"})
+		Expect(err).To(MatchError(ContainSubstring("line 3: a secret reference must be quoted")))
+
+		r := cloudconfig.Lint(in)
+		Expect(r.Errors).To(HaveLen(1))
+		Expect(r.Errors[0].Line).To(Equal(3))
+		Expect(r.Errors[0].Column).To(Equal(8))
+		Expect(r.Errors[0].Message).To(ContainSubstring("must be quoted"))
+	})
+
+	It("lints a quoted reference like any other string", func() {
+		r := cloudconfig.Lint(doc)
+		Expect(r.Errors).To(BeEmpty())
+	})
+})
@@ -0,0 +1,13 @@
+package cloudconfig_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCloudConfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "pkg/cloudconfig suite")
+}
@@ -37,6 +37,12 @@ var BootHybrid []byte
 //go:embed grub_live_bios.cfg
 var GrubLiveBiosCfg []byte
 
+// GrubLiveImageCfg is the livecd config of an additional image of a
+// multi-image ISO, appended to GrubLiveBiosCfg once per image
+//
+//go:embed grub_live_image.cfg
+var GrubLiveImageCfg []byte
+
 type UkiOutput string
 
 const (
@@ -75,6 +81,11 @@ const (
 	IsoKernelPath = "/boot/kernel"
 	IsoInitrdPath = "/boot/initrd"
 
+	// IsoImageCloudConfig is where the cloud config of an additional image
+	// of a multi-image ISO is written in its rootfs, in one of the
+	// directories the agent reads its config from
+	IsoImageCloudConfig = "/usr/local/cloud-config/90_iso_image.yaml"
+
 	// Default directory and file fileModes
 	DirPerm        = os.ModeDir | os.ModePerm
 	FilePerm       = 0666
@@ -176,13 +187,22 @@ const (
 	OpStartNetboot    = "start-netboot"
 
 	OpDumpSource     = "dump-source"
+	OpDumpISOImages  = "dump-iso-images"
 	OpGenISO         = "gen-iso"
 	OpExtractNetboot = "extract-netboot"
 
 	OpGenEFIRawDisk  = "gen-raw-efi-disk"
 	OpGenBIOSRawDisk = "gen-raw-bios-disk"
 
-	OpConvertGCE  = "convert-gce"
-	OpConvertVHD  = "convert-vhd"
-	OpConvertMAAS = "convert-maas"
+	OpConvertGCE   = "convert-gce"
+	OpConvertVHD   = "convert-vhd"
+	OpConvertMAAS  = "convert-maas"
+	OpConvertQCOW2 = "convert-qcow2"
+	OpConvertVMDK  = "convert-vmdk"
+	OpConvertOVA   = "convert-ova"
+	OpConvertVHDX  = "convert-vhdx"
+
+	OpCompressOutputs = "compress-outputs"
+
+	OpGenSBOM = "gen-sbom"
 )
@@ -0,0 +1,11 @@
+package gitsource
+
+// This file lets the external gitsource_test package clone from local bare
+// repositories. It is compiled only under `go test`.
+
+// AllowLocalForTest makes Validate accept local paths until the returned
+// function is called.
+func AllowLocalForTest() func() {
+	allowLocal = true
+	return func() { allowLocal = false }
+}
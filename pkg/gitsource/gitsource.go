@@ -0,0 +1,193 @@
+// Package gitsource fetches the build inputs of an artifact from a Git
+// repository.
+//
+// A build with a Git source clones the repository at the requested ref into
+// its build context before anything else runs. Within the checked-out
+// subpath, a Dockerfile is used as the build's Dockerfile and an overlay/
+// directory as its rootfs overlay, unless the request sets its own. The
+// commit the ref resolved to is recorded on the artifact, so a build can be
+// traced back to (and rebuilt from) an exact revision.
+package gitsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// Conventional paths inside the checked-out subpath.
+const (
+	DockerfileName = "Dockerfile"
+	OverlayDirName = "overlay"
+)
+
+// Source is where to fetch build inputs from.
+type Source struct {
+	// URL is an http(s) clone URL.
+	URL string `json:"url"`
+	// Ref is a branch, tag or commit. Empty means the default branch.
+	Ref string `json:"ref,omitempty"`
+	// Subpath is the directory inside the repository holding the
+	// Dockerfile and overlay. Empty means the repository root.
+	Subpath string `json:"subpath,omitempty"`
+	// Username and Password authenticate over HTTP. Password is usually a
+	// token; most forges accept any non-empty username with one.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Secret names the secret store entry Password was read from. Clone
+	// ignores it; builders record it on the artifact.
+	Secret string `json:"secret,omitempty"`
+}
+
+// allowLocal lets Validate accept local repository paths. Only the package
+// tests set it: a server cloning paths it was handed would expose whatever
+// repositories its own filesystem holds.
+var allowLocal bool
+
+// Validate rejects sources that cannot be cloned or whose subpath escapes
+// the checkout. Only http(s) URLs are accepted.
+func (s Source) Validate() error {
+	if s.URL == "" {
+		return errors.New("git url is required")
+	}
+	u, err := url.Parse(s.URL)
+	switch {
+	case err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "":
+		if u.User != nil {
+			return errors.New("git url must not carry credentials, store them as a secret")
+		}
+	case allowLocal && filepath.IsAbs(s.URL):
+	default:
+		return fmt.Errorf("git url %q is not supported, use http(s)", s.URL)
+	}
+	if strings.HasPrefix(s.Ref, "-") {
+		return fmt.Errorf("invalid git ref %q", s.Ref)
+	}
+	if s.Subpath != "" {
+		clean := path.Clean(s.Subpath)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("git subpath %q must stay inside the repository", s.Subpath)
+		}
+	}
+	return nil
+}
+
+// Dir is the directory of the checkout in dir that Subpath points at.
+func (s Source) Dir(dir string) string {
+	if s.Subpath == "" {
+		return dir
+	}
+	return filepath.Join(dir, filepath.FromSlash(path.Clean(s.Subpath)))
+}
+
+// InCheckout resolves the symlinks of p, a path in the checkout dir, and
+// returns where it points. A repository can commit a symlink to any path,
+// so one resolving outside dir is refused: the build would read the
+// server's files through it. A missing p returns an fs.ErrNotExist error.
+func InCheckout(dir, p string) (string, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		name, _ := filepath.Rel(dir, p)
+		return "", fmt.Errorf("%s in the repository points outside of it", filepath.ToSlash(name))
+	}
+	return resolved, nil
+}
+
+// Clone clones src into dir, which must not exist or be empty, checks out
+// src.Ref and returns the commit it resolved to. Progress goes to progress
+// when it is non-nil.
+func Clone(ctx context.Context, src Source, dir string, progress io.Writer) (string, error) {
+	if err := src.Validate(); err != nil {
+		return "", err
+	}
+	opts := &git.CloneOptions{URL: src.URL, Progress: progress, Tags: git.AllTags}
+	if src.Password != "" {
+		user := src.Username
+		if user == "" {
+			user = "git"
+		}
+		opts.Auth = &githttp.BasicAuth{Username: user, Password: src.Password}
+	}
+	repo, err := git.PlainCloneContext(ctx, dir, false, opts)
+	if err != nil {
+		if errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed) {
+			return "", fmt.Errorf("cloning %s: %w (check the credentials secret)", src.URL, err)
+		}
+		return "", fmt.Errorf("cloning %s: %w", src.URL, err)
+	}
+
+	var hash *plumbing.Hash
+	if src.Ref == "" {
+		head, err := repo.Head()
+		if err != nil {
+			return "", fmt.Errorf("resolving HEAD: %w", err)
+		}
+		h := head.Hash()
+		hash = &h
+	} else {
+		// Branches other than the default only exist as remote-tracking
+		// refs after a clone, so try those too.
+		for _, rev := range []string{"refs/remotes/origin/" + src.Ref, src.Ref} {
+			if hash, err = repo.ResolveRevision(plumbing.Revision(rev)); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("ref %q not found in %s", src.Ref, src.URL)
+		}
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true}); err != nil {
+		return "", fmt.Errorf("checking out %s: %w", hash, err)
+	}
+	return hash.String(), nil
+}
+
+// SameRepository reports whether two clone URLs name the same repository,
+// ignoring the scheme, a trailing ".git" or slash, credentials and the case
+// of the host. A push webhook reports the repository under several URLs and
+// none has to match what a build was created with byte for byte.
+func SameRepository(a, b string) bool {
+	na, nb := normalizeURL(a), normalizeURL(b)
+	return na != "" && na == nb
+}
+
+func normalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	host, p := "", raw
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		host, p = strings.ToLower(u.Hostname()), u.Path
+	} else if at := strings.Index(raw, "@"); at >= 0 && strings.Contains(raw[at:], ":") {
+		// scp-like SSH form: git@host:org/repo.git
+		rest := raw[at+1:]
+		colon := strings.Index(rest, ":")
+		host, p = strings.ToLower(rest[:colon]), rest[colon+1:]
+	}
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	if p == "" {
+		return ""
+	}
+	return host + "/" + p
+}
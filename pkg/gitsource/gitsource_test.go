@@ -0,0 +1,189 @@
+package gitsource_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/gitsource"
+)
+
+// newBareRepo creates a bare repository with two commits on main, the first
+// tagged v1, and a "feature" branch with a third, and returns its path and
+// the commit hashes.
+func newBareRepo() (string, map[string]string) {
+	work, bare := GinkgoT().TempDir(), GinkgoT().TempDir()
+	_, err := git.PlainInit(bare, true)
+	Expect(err).NotTo(HaveOccurred())
+	repo, err := git.PlainInitWithOptions(work, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	wt, err := repo.Worktree()
+	Expect(err).NotTo(HaveOccurred())
+
+	commits := map[string]string{}
+	commit := func(name, file, content string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(work, file)), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(work, file), []byte(content), 0o644)).To(Succeed())
+		_, err := wt.Add(file)
+		Expect(err).NotTo(HaveOccurred())
+		h, err := wt.Commit(name, &git.CommitOptions{Author: &object.Signature{Name: "t", Email: "t@example.com", When: time.Now()}})
+		Expect(err).NotTo(HaveOccurred())
+		commits[name] = h.String()
+	}
+	commit("first", "images/edge/Dockerfile", "FROM scratch\n")
+	_, err = repo.CreateTag("v1", plumbing.NewHash(commits["first"]), nil)
+	Expect(err).NotTo(HaveOccurred())
+	commit("second", "images/edge/overlay/etc/motd", "hello\n")
+	Expect(wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true})).To(Succeed())
+	commit("feature", "images/edge/Dockerfile", "FROM busybox\n")
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{bare}})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(repo.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{
+		"refs/heads/*:refs/heads/*", "refs/tags/*:refs/tags/*",
+	}})).To(Succeed())
+	// Point the bare repository's HEAD at main, as a forge would.
+	bareRepo, err := git.PlainOpen(bare)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(bareRepo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main")))).To(Succeed())
+	return bare, commits
+}
+
+var _ = Describe("Clone", func() {
+	var (
+		bare    string
+		commits map[string]string
+	)
+
+	BeforeEach(func() {
+		DeferCleanup(gitsource.AllowLocalForTest())
+		bare, commits = newBareRepo()
+	})
+
+	clone := func(src gitsource.Source) (string, string, error) {
+		dir := filepath.Join(GinkgoT().TempDir(), "source")
+		commit, err := gitsource.Clone(context.Background(), src, dir, nil)
+		return dir, commit, err
+	}
+
+	It("checks out the default branch when no ref is given", func() {
+		dir, commit, err := clone(gitsource.Source{URL: bare, Subpath: "images/edge"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commit).To(Equal(commits["second"]))
+		sub := gitsource.Source{Subpath: "images/edge"}.Dir(dir)
+		Expect(filepath.Join(sub, gitsource.OverlayDirName, "etc", "motd")).To(BeARegularFile())
+	})
+
+	It("resolves branches, tags and commits", func() {
+		for ref, want := range map[string]string{
+			"feature":         commits["feature"],
+			"v1":              commits["first"],
+			commits["second"]: commits["second"],
+		} {
+			dir, commit, err := clone(gitsource.Source{URL: bare, Ref: ref})
+			Expect(err).NotTo(HaveOccurred(), ref)
+			Expect(commit).To(Equal(want), ref)
+			if ref == "feature" {
+				Expect(os.ReadFile(filepath.Join(dir, "images/edge/Dockerfile"))).To(BeEquivalentTo("FROM busybox\n"))
+			}
+		}
+	})
+
+	It("fails on an unknown ref", func() {
+		_, _, err := clone(gitsource.Source{URL: bare, Ref: "nope"})
+		Expect(err).To(MatchError(ContainSubstring(`ref "nope" not found`)))
+	})
+})
+
+var _ = Describe("InCheckout", func() {
+	// clone checks out a repository whose images/edge holds a files
+	// directory, a link to it as inner and a link to target as overlay.
+	clone := func(target string) string {
+		DeferCleanup(gitsource.AllowLocalForTest())
+		work := GinkgoT().TempDir()
+		repo, err := git.PlainInit(work, false)
+		Expect(err).NotTo(HaveOccurred())
+		wt, err := repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		edge := filepath.Join(work, "images", "edge")
+		Expect(os.MkdirAll(filepath.Join(edge, "files"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(edge, "files", "motd"), []byte("hello\n"), 0o644)).To(Succeed())
+		Expect(os.Symlink(target, filepath.Join(edge, gitsource.OverlayDirName))).To(Succeed())
+		Expect(os.Symlink("files", filepath.Join(edge, "inner"))).To(Succeed())
+		for _, f := range []string{"images/edge/files/motd", "images/edge/overlay", "images/edge/inner"} {
+			_, err := wt.Add(f)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err = wt.Commit("links", &git.CommitOptions{Author: &object.Signature{Name: "t", Email: "t@example.com", When: time.Now()}})
+		Expect(err).NotTo(HaveOccurred())
+
+		dir := filepath.Join(GinkgoT().TempDir(), "source")
+		_, err = gitsource.Clone(context.Background(), gitsource.Source{URL: work}, dir, nil)
+		Expect(err).NotTo(HaveOccurred())
+		return dir
+	}
+
+	It("refuses an overlay linking outside the checkout", func() {
+		// A checkout keeps absolute links inside the worktree, so climb out
+		// with a relative one.
+		secrets := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(secrets, "key"), []byte("secret\n"), 0o600)).To(Succeed())
+		dir := clone(strings.Repeat("../", 64) + strings.TrimPrefix(filepath.ToSlash(secrets), "/"))
+		overlay := filepath.Join(dir, "images", "edge", gitsource.OverlayDirName)
+		Expect(filepath.Join(overlay, "key")).To(BeARegularFile())
+
+		_, err := gitsource.InCheckout(dir, overlay)
+		Expect(err).To(MatchError("images/edge/overlay in the repository points outside of it"))
+	})
+
+	It("resolves links inside the checkout", func() {
+		dir := clone("files")
+		overlay, err := gitsource.InCheckout(dir, filepath.Join(dir, "images", "edge", gitsource.OverlayDirName))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Join(overlay, "motd")).To(BeARegularFile())
+
+		inner, err := gitsource.InCheckout(dir, filepath.Join(dir, "images", "edge", "inner"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner).To(Equal(overlay))
+	})
+
+	It("returns fs.ErrNotExist for a missing path", func() {
+		dir := clone("files")
+		_, err := gitsource.InCheckout(dir, filepath.Join(dir, gitsource.DockerfileName))
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+})
+
+var _ = Describe("Source", func() {
+	It("rejects unsafe sources", func() {
+		Expect(gitsource.Source{URL: "https://example.com/r.git", Subpath: "a/b"}.Validate()).To(Succeed())
+		Expect(gitsource.Source{}.Validate()).NotTo(Succeed())
+		Expect(gitsource.Source{URL: "ssh://git@example.com/r.git"}.Validate()).NotTo(Succeed())
+		Expect(gitsource.Source{URL: "git@example.com:r.git"}.Validate()).NotTo(Succeed())
+		Expect(gitsource.Source{URL: "file:///srv/git/r.git"}.Validate()).NotTo(Succeed())
+		Expect(gitsource.Source{URL: "/srv/git/r.git"}.Validate()).NotTo(Succeed())
+		Expect(gitsource.Source{URL: "https:///r.git"}.Validate()).NotTo(Succeed())
+		Expect(gitsource.Source{URL: "https://u:p@example.com/r.git"}.Validate()).NotTo(Succeed())
+		Expect(gitsource.Source{URL: "https://example.com/r.git", Subpath: "../x"}.Validate()).NotTo(Succeed())
+		Expect(gitsource.Source{URL: "https://example.com/r.git", Ref: "--upload-pack=x"}.Validate()).NotTo(Succeed())
+	})
+
+	It("matches the URLs a forge reports for one repository", func() {
+		Expect(gitsource.SameRepository("https://GitHub.com/org/repo.git", "https://github.com/org/repo")).To(BeTrue())
+		Expect(gitsource.SameRepository("https://github.com/org/repo", "git@github.com:org/repo.git")).To(BeTrue())
+		Expect(gitsource.SameRepository("https://github.com/org/repo", "https://github.com/org/other")).To(BeFalse())
+		Expect(gitsource.SameRepository("", "")).To(BeFalse())
+	})
+})
@@ -0,0 +1,13 @@
+package gitsource_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGitSource(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "pkg/gitsource suite")
+}
@@ -13,6 +13,8 @@ package handlers
 import (
 	"time"
 
+	"github.com/kairos-io/AuroraBoot/pkg/retention"
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
 	"github.com/kairos-io/AuroraBoot/pkg/store"
 )
 
@@ -110,18 +112,26 @@ type APISetGroupRequest struct {
 type APICreateGroupRequest struct {
 	Name        string `json:"name" example:"production"`
 	Description string `json:"description" example:"Production fleet nodes"`
+	// FollowChannelID makes every promotion or rollback of the channel queue
+	// an upgrade on the group's nodes.
+	FollowChannelID string `json:"followChannelId,omitempty"`
 }
 
 // APIUpdateGroupRequest is the JSON body of PUT /api/v1/groups/:id.
 type APIUpdateGroupRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// FollowChannelID is left unchanged when omitted; "" stops following.
+	FollowChannelID *string `json:"followChannelId,omitempty"`
 }
 
 // --- Commands ---
 
 // APICreateCommandRequest is the JSON body of
 // POST /api/v1/nodes/:nodeID/commands and POST /api/v1/groups/:id/commands.
+// An upgrade source is "oci:<image>", "artifact:<id>" or "channel:<name>";
+// a channel resolves to the artifact it points at when the node receives
+// the command.
 type APICreateCommandRequest struct {
 	Command string            `json:"command" example:"upgrade" enums:"upgrade,upgrade-recovery,reset,apply-cloud-config,reboot,exec"`
 	Args    map[string]string `json:"args"`
@@ -164,12 +174,25 @@ type APICreateArtifactRequest struct {
 	HadronExtra             string                  `json:"hadronExtra"`
 	OverlayRootfs           string                  `json:"overlayRootfs"`
 	KairosInitImage         string                  `json:"kairosInitImage"`
+	Git                     *APIGitSource           `json:"git,omitempty"`
 	Outputs                 APIArtifactOutputs      `json:"outputs"`
 	Signing                 APIArtifactSigning      `json:"signing"`
 	Provisioning            APIArtifactProvisioning `json:"provisioning"`
 	CloudConfig             string                  `json:"cloudConfig"`
 }
 
+// APIGitSource builds from a Git repository: it is cloned at Ref (the
+// default branch when empty), and the Dockerfile and overlay/ directory in
+// Subpath are used unless the request sets dockerfile or overlayRootfs.
+// Secret names the secret holding the password or token for HTTP auth.
+type APIGitSource struct {
+	URL      string `json:"url" example:"https://github.com/acme/edge-images.git"`
+	Ref      string `json:"ref,omitempty" example:"main"`
+	Subpath  string `json:"subpath,omitempty" example:"images/edge"`
+	Username string `json:"username,omitempty"`
+	Secret   string `json:"secret,omitempty" example:"github-token"`
+}
+
 // APIArtifactOutputs toggles the build's output formats.
 type APIArtifactOutputs struct {
 	ISO         bool `json:"iso"`
@@ -183,6 +206,32 @@ type APIArtifactOutputs struct {
 	UKI         bool `json:"uki"`
 	FIPS        bool `json:"fips"`
 	TrustedBoot bool `json:"trustedBoot"`
+	// QCOW2 adds a qcow2 image of the raw disk for KVM, Proxmox and
+	// OpenStack; QCOW2Compress deflates its clusters.
+	QCOW2         bool `json:"qcow2"`
+	QCOW2Compress bool `json:"qcow2Compress"`
+	// VHDDynamic makes the VHD output a sparse, dynamic VHD instead of the
+	// fixed one Azure requires. VHDX adds a VHDX for Hyper-V generation 2
+	// virtual machines.
+	VHDDynamic bool `json:"vhdDynamic"`
+	VHDX       bool `json:"vhdx"`
+	// VMDK adds a stream-optimized VMDK for vSphere. OVA packs one with an
+	// OVF descriptor of an EFI virtual machine of OVACPUs CPUs and
+	// OVAMemoryMB MiB (2 and 4096 when zero); OVAUserDataProperty adds a
+	// user-data vApp property taking a base64 cloud-config at deployment.
+	VMDK                bool `json:"vmdk"`
+	OVA                 bool `json:"ova"`
+	OVACPUs             int  `json:"ovaCpus"`
+	OVAMemoryMB         int  `json:"ovaMemoryMB"`
+	OVAUserDataProperty bool `json:"ovaUserDataProperty"`
+	// SBOM adds a software bill of materials of the image's installed
+	// packages to the outputs. SBOMFormat defaults to spdx-json.
+	SBOM       bool   `json:"sbom"`
+	SBOMFormat string `json:"sbomFormat" enums:"spdx-json,cyclonedx-json"`
+	// Compression replaces the raw disk and VHD outputs with compressed
+	// copies and adds one next to the ISO, which is kept for booting.
+	// Empty leaves them uncompressed.
+	Compression string `json:"compression" enums:"zstd,xz,gzip"`
 }
 
 // APIArtifactSigning holds SecureBoot signing options for UKI builds.
@@ -216,6 +265,170 @@ type APIUpdateArtifactRequest struct {
 	Saved *bool  `json:"saved"`
 }
 
+// APIArtifactSBOM is the JSON body of GET /api/v1/artifacts/:id/sbom. File is
+// the SBOM document, downloadable from /api/v1/artifacts/:id/download/:file.
+// Vulnerabilities and Findings are only present when the build was matched
+// against an advisory database.
+type APIArtifactSBOM struct {
+	Format          string                      `json:"format" example:"spdx-json"`
+	File            string                      `json:"file" example:"sbom.spdx.json"`
+	Vulnerabilities *store.VulnerabilitySummary `json:"vulnerabilities,omitempty"`
+	Findings        []sbom.Finding              `json:"findings,omitempty"`
+}
+
+// APIPublishRequest is the JSON body of POST /api/v1/artifacts/:id/publish.
+// Repository is appended to the registry's host and namespace. Image pushes
+// the OS container image under Tags; Outputs pushes the output files (all of
+// them, or only Files) as an OCI artifact under each tag plus "-artifacts".
+type APIPublishRequest struct {
+	RegistryID string   `json:"registryId"`
+	Repository string   `json:"repository" example:"kairos/edge-os"`
+	Tags       []string `json:"tags" example:"v1.2.0,latest"`
+	Image      bool     `json:"image"`
+	Outputs    bool     `json:"outputs"`
+	Files      []string `json:"files,omitempty" example:"kairos.iso"`
+}
+
+// APIValidateCloudConfigRequest is the JSON body of
+// POST /api/v1/cloud-config/validate. Fragment marks YAML that is merged
+// into a generated cloud-config (the cloudConfig of a build request) rather
+// than a complete document.
+type APIValidateCloudConfigRequest struct {
+	CloudConfig string `json:"cloudConfig" example:"#cloud-config\ninstall:\n  auto: true\n"`
+	Fragment    bool   `json:"fragment"`
+}
+
+// APIPlanRequest is the JSON body of POST /api/v1/plan. Config is the
+// AuroraBoot configuration YAML, as given to the CLI with --config.
+type APIPlanRequest struct {
+	Config string `json:"config" example:"container_image: quay.io/kairos/ubuntu:24.04-core-amd64-generic-v3.5.0\ndisk:\n  efi: true\n"`
+}
+
+// --- Registries ---
+
+// APIRegistryRequest is the JSON body of POST /api/v1/registries and
+// PUT /api/v1/registries/:id. Host carries no scheme; Insecure allows plain
+// HTTP. On update an empty Password keeps the stored one.
+type APIRegistryRequest struct {
+	Name      string `json:"name" example:"ghcr"`
+	Host      string `json:"host" example:"ghcr.io"`
+	Namespace string `json:"namespace,omitempty" example:"acme"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+	Insecure  bool   `json:"insecure,omitempty"`
+}
+
+// --- Secrets and Git webhooks ---
+
+// APISecretRequest is the JSON body of PUT /api/v1/secrets/:name.
+type APISecretRequest struct {
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// APIGitPushResult is returned by POST /api/v1/webhooks/git. Builds lists
+// the IDs of the rebuilds it started; Failed the artifacts it could not
+// rebuild and why.
+type APIGitPushResult struct {
+	Ref    string              `json:"ref,omitempty" example:"refs/heads/main"`
+	Commit string              `json:"commit,omitempty"`
+	Builds []string            `json:"builds,omitempty"`
+	Failed []APIGitPushFailure `json:"failed,omitempty"`
+}
+
+// APIGitPushFailure is one artifact a push could not rebuild.
+type APIGitPushFailure struct {
+	ArtifactID string `json:"artifactId"`
+	Error      string `json:"error"`
+}
+
+// --- Build sets ---
+
+// APICreateBuildSetRequest is the JSON body of POST /api/v1/build-sets.
+// Template is an ordinary create-artifact body; Matrix lists the values to
+// vary. The set expands to the cross product of every non-empty axis, each
+// child being Template with that combination applied. An empty axis keeps
+// Template's value, so {"arches":["amd64","arm64"]} alone builds the same
+// image for both architectures.
+type APICreateBuildSetRequest struct {
+	Name     string                   `json:"name" example:"release v3.6.0"`
+	Template APICreateArtifactRequest `json:"template"`
+	Matrix   APIBuildMatrix           `json:"matrix"`
+}
+
+// APIBuildMatrix holds the axes of a build set: ImageSource values crossed
+// with output selections.
+type APIBuildMatrix struct {
+	BaseImages []string              `json:"baseImages,omitempty" example:"quay.io/kairos/ubuntu:24.04"`
+	Arches     []string              `json:"arches,omitempty" example:"amd64"`
+	Variants   []string              `json:"variants,omitempty" example:"core"`
+	Kubernetes []APIMatrixKubernetes `json:"kubernetes,omitempty"`
+	Outputs    []APIArtifactOutputs  `json:"outputs,omitempty"`
+}
+
+// APIMatrixKubernetes is one Kubernetes distro/version value of the matrix.
+// Distro and version travel together because not every version exists for
+// every distro.
+type APIMatrixKubernetes struct {
+	Distro  string `json:"distro" enums:"k3s,k0s"`
+	Version string `json:"version"`
+}
+
+// APIBuildSet is a build set with its status aggregated from the children:
+// Pending until a child starts, Building while any child is still running,
+// Ready once every child is Ready and Error once all are terminal and at
+// least one failed.
+type APIBuildSet struct {
+	ID        string                  `json:"id"`
+	Name      string                  `json:"name"`
+	Phase     string                  `json:"phase" enums:"Pending,Building,Ready,Error"`
+	Counts    APIBuildSetCounts       `json:"counts"`
+	Children  []*store.ArtifactRecord `json:"children,omitempty"`
+	CreatedAt time.Time               `json:"createdAt"`
+}
+
+// APIBuildSetCounts tallies a build set's children by phase.
+type APIBuildSetCounts struct {
+	Total    int `json:"total"`
+	Pending  int `json:"pending"`
+	Building int `json:"building"`
+	Ready    int `json:"ready"`
+	Error    int `json:"error"`
+}
+
+// --- Build templates ---
+
+// APIBuildTemplateRequest is the JSON body of POST and PUT
+// /api/v1/build-templates, and the shape of the YAML document import and
+// export exchange. Spec is a partial APICreateArtifactRequest whose string
+// values may reference Parameters as ${{ name }}.
+type APIBuildTemplateRequest struct {
+	Name        string                    `json:"name" example:"edge-ubuntu"`
+	Description string                    `json:"description,omitempty"`
+	Parameters  []store.TemplateParameter `json:"parameters,omitempty"`
+	Spec        APICreateArtifactRequest  `json:"spec"`
+}
+
+// APIBuildTemplate is a stored build template. List leaves Spec out.
+type APIBuildTemplate struct {
+	ID          string                    `json:"id"`
+	Name        string                    `json:"name"`
+	Description string                    `json:"description,omitempty"`
+	Parameters  []store.TemplateParameter `json:"parameters,omitempty"`
+	Spec        map[string]interface{}    `json:"spec,omitempty" swaggertype:"object"`
+	CreatedAt   time.Time                 `json:"createdAt"`
+	UpdatedAt   time.Time                 `json:"updatedAt"`
+}
+
+// APIBuildFromTemplateRequest is the JSON body of
+// POST /api/v1/build-templates/:id/build. Name defaults to the spec's name,
+// then to the template's.
+type APIBuildFromTemplateRequest struct {
+	Name       string                 `json:"name,omitempty"`
+	Parameters map[string]string      `json:"parameters,omitempty" example:"version:v4.1.2"`
+	Overrides  map[string]interface{} `json:"overrides,omitempty" swaggertype:"object"`
+}
+
 // --- SecureBoot keys ---
 
 // APIGenerateKeySetRequest is the JSON body of
@@ -240,11 +453,109 @@ type APIRegistrationTokenResponse struct {
 // render a "Builds via cluster X" badge and decide whether to expose the
 // download UI. `cluster` and `namespace` are omitted for the local backend.
 type APISystemBuilder struct {
-	Backend           string `json:"backend" example:"operator" enums:"local,operator"`
+	Backend           string `json:"backend" example:"operator" enums:"local,operator,worker"`
 	Cluster           string `json:"cluster,omitempty" example:"https://kind.example"`
 	Namespace         string `json:"namespace,omitempty" example:"kairos-builds"`
 	DownloadSupported bool   `json:"downloadSupported"`
 }
 
+// --- Remote build workers ---
+
+// APIWorkerRegisterRequest is the JSON body of POST /api/v1/workers/register.
+// RegistrationToken is the worker registration token (not the node one): a
+// worker receives full build specs, including the injected cloud-config, so
+// enrolling one must not be possible with the token baked into every image.
+type APIWorkerRegisterRequest struct {
+	RegistrationToken string `json:"registrationToken"`
+	Name              string `json:"name" example:"builder-arm64-01"`
+	Arch              string `json:"arch" example:"arm64" enums:"amd64,arm64"`
+	Version           string `json:"version,omitempty"`
+}
+
+// APIWorkerRegisterResponse is returned on successful worker registration.
+// APIKey is shown exactly once; only its digest is stored.
+type APIWorkerRegisterResponse struct {
+	ID     string `json:"id"`
+	APIKey string `json:"apiKey"`
+}
+
+// APIWorkerHeartbeatRequest is the JSON body of POST /api/v1/worker/heartbeat.
+// BuildID, when set, is the leased build whose lease the heartbeat renews.
+type APIWorkerHeartbeatRequest struct {
+	BuildID string `json:"buildId,omitempty"`
+}
+
 // Verify time is imported — otherwise unused-import error.
 var _ = time.Time{}
+
+// --- Channels ---
+
+// APICreateChannelRequest is the JSON body of POST /api/v1/channels.
+type APICreateChannelRequest struct {
+	Name        string `json:"name" example:"ubuntu-k3s/stable"`
+	Description string `json:"description,omitempty"`
+}
+
+// APIMoveChannelRequest is the JSON body of POST /api/v1/channels/:id/promote
+// and POST /api/v1/channels/:id/rollback. Promote takes exactly one of
+// ArtifactID and FromChannel; rollback takes an optional ArtifactID.
+type APIMoveChannelRequest struct {
+	ArtifactID  string `json:"artifactId,omitempty"`
+	FromChannel string `json:"fromChannel,omitempty" example:"ubuntu-k3s/staging"`
+	Note        string `json:"note,omitempty"`
+}
+
+// APIChannelMove is returned by promote and rollback. Queued counts the
+// upgrades queued for nodes in groups following the channel.
+type APIChannelMove struct {
+	Channel store.Channel      `json:"channel"`
+	Entry   store.ChannelEntry `json:"entry"`
+	Queued  int                `json:"queued"`
+}
+
+// --- Retention ---
+
+// APIRetentionRun is returned by POST /api/v1/retention/run: the plan that
+// was applied, and the removals that failed keyed by artifact ID.
+type APIRetentionRun struct {
+	retention.Plan
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// --- Image watch ---
+
+// APIImageWatchSettings is the JSON body of PUT /api/v1/image-watch. An
+// empty schedule turns the watcher off.
+type APIImageWatchSettings struct {
+	Schedule string `json:"schedule" example:"0 3 * * *"`
+}
+
+// APIImageWatchStatus is returned by GET and PUT /api/v1/image-watch.
+type APIImageWatchStatus struct {
+	Schedule string            `json:"schedule"`
+	NextRun  *time.Time        `json:"nextRun,omitempty"`
+	LastRun  *APIImageWatchRun `json:"lastRun,omitempty"`
+}
+
+// APIImageWatchRun is one pass of the watcher over the watched base images.
+type APIImageWatchRun struct {
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	Checks     []APIImageCheck `json:"checks"`
+}
+
+// APIImageCheck is the outcome for one watched build: the digest its base
+// image was built from and the one the tag points at now. When they differ
+// the build is started again and RebuildID names the new build. The UI
+// WebSocket receives the checks that found a change as "base-image-changed"
+// messages.
+type APIImageCheck struct {
+	Image          string `json:"image"`
+	ArtifactID     string `json:"artifactId"`
+	TemplateID     string `json:"templateId,omitempty"`
+	PreviousDigest string `json:"previousDigest"`
+	Digest         string `json:"digest,omitempty"`
+	Changed        bool   `json:"changed"`
+	RebuildID      string `json:"rebuildId,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
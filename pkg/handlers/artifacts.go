@@ -7,19 +7,29 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/kairos-io/AuroraBoot/pkg/cloudconfig"
+	"github.com/kairos-io/AuroraBoot/pkg/gitsource"
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
 	"github.com/kairos-io/AuroraBoot/pkg/store"
 	"github.com/labstack/echo/v4"
 	"gopkg.in/yaml.v3"
@@ -63,6 +73,16 @@ type ArtifactHandler struct {
 	regToken       string
 	aurorabootURL  string
 	artifactsDir   string
+	// manifestKey signs the SHA256SUMS of builds that do not name a key
+	// set of their own. Empty leaves them unsigned.
+	manifestKey string
+	// storage is where finished outputs are kept for download. It
+	// defaults to artifactsDir itself.
+	storage storage.Backend
+	// secrets resolves the credentials of Git sources; specs keeps each
+	// build's request so a Git push can rebuild it. Both optional.
+	secrets store.SecretStore
+	specs   store.ArtifactSpecStore
 }
 
 // NewArtifactHandler creates a new ArtifactHandler.
@@ -75,9 +95,39 @@ func NewArtifactHandler(b builder.ArtifactBuilder, artifactStore store.ArtifactS
 		regToken:       regToken,
 		aurorabootURL:  aurorabootURL,
 		artifactsDir:   artifactsDir,
+		storage:        storage.NewLocal(artifactsDir),
 	}
 }
 
+// WithManifestSigningKey sets the PEM private key (a cosign key, or any
+// PKCS#8 key) the SHA256SUMS of every build is signed with unless the
+// request names a SecureBoot key set instead.
+func (h *ArtifactHandler) WithManifestSigningKey(path string) *ArtifactHandler {
+	h.manifestKey = path
+	return h
+}
+
+// WithStorage replaces the default on-disk artifact storage. Uploaded files
+// are still written to the artifacts directory first, then pushed to b, and
+// Download redirects to b when it can hand out a URL.
+func (h *ArtifactHandler) WithStorage(b storage.Backend) *ArtifactHandler {
+	h.storage = b
+	return h
+}
+
+// WithSecrets lets Git sources name a secret holding their credentials.
+func (h *ArtifactHandler) WithSecrets(s store.SecretStore) *ArtifactHandler {
+	h.secrets = s
+	return h
+}
+
+// WithSpecs keeps the request of every build so it can be started again,
+// as GitWebhookHandler does when a build's branch moves.
+func (h *ArtifactHandler) WithSpecs(s store.ArtifactSpecStore) *ArtifactHandler {
+	h.specs = s
+	return h
+}
+
 // createArtifactRequest is the expected body for creating an artifact build.
 type createArtifactRequest struct {
 	Name                    string `json:"name"`
@@ -99,6 +149,9 @@ type createArtifactRequest struct {
 	OverlayRootfs           string   `json:"overlayRootfs"`
 	KairosInitImage         string `json:"kairosInitImage"`
 
+	// Git fetches the Dockerfile and overlay from a repository instead.
+	Git *gitSourceRequest `json:"git,omitempty"`
+
 	Outputs      artifactOutputs    `json:"outputs"`
 	Signing      *signingConfig     `json:"signing,omitempty"`
 	Provisioning provisioningConfig `json:"provisioning"`
@@ -119,6 +172,38 @@ type artifactOutputs struct {
 	UKI         bool `json:"uki"`
 	FIPS        bool `json:"fips"`
 	TrustedBoot bool `json:"trustedBoot"`
+	// QCOW2 is converted from the raw disk; QCOW2Compress deflates its
+	// clusters.
+	QCOW2         bool `json:"qcow2"`
+	QCOW2Compress bool `json:"qcow2Compress"`
+	// VHDDynamic picks a dynamic VHD over a fixed one; VHDX is for Hyper-V
+	// generation 2 virtual machines.
+	VHDDynamic bool `json:"vhdDynamic"`
+	VHDX       bool `json:"vhdx"`
+	// VMDK and OVA are converted from the raw disk; the OVA* settings
+	// describe the OVA's virtual machine.
+	VMDK                bool `json:"vmdk"`
+	OVA                 bool `json:"ova"`
+	OVACPUs             int  `json:"ovaCpus"`
+	OVAMemoryMB         int  `json:"ovaMemoryMB"`
+	OVAUserDataProperty bool `json:"ovaUserDataProperty"`
+	// SBOM is an add-on to the image outputs rather than an image format of
+	// its own; SBOMFormat picks the document format (spdx-json by default).
+	SBOM       bool   `json:"sbom"`
+	SBOMFormat string `json:"sbomFormat"`
+	// Compression is applied to the raw disk, ISO and VHD outputs.
+	Compression string `json:"compression"`
+}
+
+// gitSourceRequest names the repository a build takes its inputs from. The
+// password is never part of the request: Secret names the secret store
+// entry holding it.
+type gitSourceRequest struct {
+	URL      string `json:"url"`
+	Ref      string `json:"ref"`
+	Subpath  string `json:"subpath"`
+	Username string `json:"username"`
+	Secret   string `json:"secret"`
 }
 
 type signingConfig struct {
@@ -128,6 +213,7 @@ type signingConfig struct {
 	UKITPMPCRKey        string `json:"ukiTpmPcrKey"`
 	UKIPublicKeysDir    string `json:"ukiPublicKeysDir"`
 	UKISecureBootEnroll string `json:"ukiSecureBootEnroll"`
+	ManifestKeySetID    string `json:"manifestKeySetId"`
 }
 
 type provisioningConfig struct {
@@ -172,8 +258,42 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
 	}
 
-	ctx := c.Request().Context()
+	status, err := h.startBuild(c.Request().Context(), req)
+	if err != nil {
+		return startBuildError(c, err)
+	}
+	return c.JSON(http.StatusCreated, status)
+}
+
+// buildStartFailure is a startBuild error already classified into the status
+// code and client-safe message the handler responds with.
+type buildStartFailure struct {
+	code int
+	msg  string
+}
+
+func (f *buildStartFailure) Error() string { return f.msg }
+
+// startBuildError writes the response for a startBuild failure.
+func startBuildError(c echo.Context, err error) error {
+	var f *buildStartFailure
+	if errors.As(err, &f) {
+		return c.JSON(f.code, map[string]string{"error": f.msg})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to start build"})
+}
+
+// startBuild turns one create request into BuildOptions, hands it to the
+// builder and persists the record. Create and the build-set expansion both go
+// through here so a matrix child is built exactly like a single artifact.
+// Errors are *buildStartFailure.
+func (h *ArtifactHandler) startBuild(ctx context.Context, req createArtifactRequest) (*builder.BuildStatus, error) {
+	return h.launchBuild(ctx, req, "")
+}
 
+// launchBuild is startBuild, rerunning the failed build resumeID in place
+// when it is set.
+func (h *ArtifactHandler) launchBuild(ctx context.Context, req createArtifactRequest, resumeID string) (*builder.BuildStatus, error) {
 	// Provisioning defaults: nil means default true.
 	autoInstall := true
 	if req.Provisioning.AutoInstall != nil {
@@ -194,7 +314,7 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 		if req.Signing.UKIKeySetID != "" && h.secureBootKeys != nil {
 			ks, err := h.secureBootKeys.GetByID(ctx, req.Signing.UKIKeySetID)
 			if err != nil {
-				return c.JSON(http.StatusBadRequest, map[string]string{"error": "key set not found"})
+				return nil, &buildStartFailure{http.StatusBadRequest, "key set not found"}
 			}
 			ukiSBKey = filepath.Join(ks.KeysDir, "db.key")
 			ukiSBCert = filepath.Join(ks.KeysDir, "db.pem")
@@ -203,17 +323,56 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 		}
 	}
 
+	// Manifest signing key: the db.key of the named SecureBoot key set, or
+	// the server's own key when the request does not name one.
+	manifestKey, manifestKeySetID := h.manifestKey, ""
+	if req.Signing != nil && req.Signing.ManifestKeySetID != "" {
+		if h.secureBootKeys == nil {
+			return nil, &buildStartFailure{http.StatusBadRequest, "manifest key set not found"}
+		}
+		ks, err := h.secureBootKeys.GetByID(ctx, req.Signing.ManifestKeySetID)
+		if err != nil {
+			return nil, &buildStartFailure{http.StatusBadRequest, "manifest key set not found"}
+		}
+		manifestKey = manifestKeySetKey(ks)
+		manifestKeySetID = ks.ID
+	}
+
+	sbomFormat := ""
+	if req.Outputs.SBOM {
+		sbomFormat = req.Outputs.SBOMFormat
+		if sbomFormat == "" {
+			sbomFormat = sbom.FormatSPDX
+		}
+		if !sbom.ValidFormat(sbomFormat) {
+			return nil, &buildStartFailure{http.StatusBadRequest, fmt.Sprintf("outputs.sbomFormat must be %q or %q", sbom.FormatSPDX, sbom.FormatCycloneDX)}
+		}
+	}
+	if c := (schema.Compression{Format: req.Outputs.Compression}); c.Format != "" && c.Extension() == "" {
+		return nil, &buildStartFailure{http.StatusBadRequest, fmt.Sprintf("outputs.compression must be %q, %q or %q", schema.CompressionZstd, schema.CompressionXZ, schema.CompressionGzip)}
+	}
+
+	var gitSrc *gitsource.Source
+	if req.Git != nil {
+		src, err := h.resolveGitSource(ctx, *req.Git)
+		if err != nil {
+			return nil, err
+		}
+		gitSrc = src
+	}
+
 	// Mint the per-build upload token before we hand opts to the builder so
 	// the operator backend's exporter Secret carries a fresh token for every
 	// build, and the store record can validate the incoming PUT /upload.
 	uploadToken, err := mintUploadToken()
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to prepare build"})
+		return nil, &buildStartFailure{http.StatusInternalServerError, "failed to prepare build"}
 	}
 
 	// Build opts — set both flat fields and grouped sub-structs.
 	opts := builder.BuildOptions{
 		ID:                uuid.New().String(),
+		Resume:            resumeID != "",
 		Name:              req.Name,
 		UploadToken:       uploadToken,
 		LogRedactValues:   []string{h.regToken, req.Provisioning.Password},
@@ -237,6 +396,13 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 		HadronFirmware:    req.HadronFirmware,
 		HadronLayers:      req.HadronLayers,
 		HadronExtra:       req.HadronExtra,
+		Git:               gitSrc,
+	}
+	if opts.Resume {
+		opts.ID = resumeID
+	}
+	if gitSrc != nil && gitSrc.Password != "" {
+		opts.LogRedactValues = append(opts.LogRedactValues, gitSrc.Password)
 	}
 	// Set grouped fields.
 	opts.Source = builder.ImageSource{
@@ -250,23 +416,37 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 		AllowInsecureRegistries: req.AllowInsecureRegistries,
 	}
 	opts.Outputs = builder.OutputOptions{
-		ISO:         req.Outputs.ISO,
-		CloudImage:  req.Outputs.CloudImage,
-		Netboot:     req.Outputs.Netboot,
-		RawDisk:     req.Outputs.RawDisk,
-		Tar:         req.Outputs.Tar,
-		GCE:         req.Outputs.GCE,
-		VHD:         req.Outputs.VHD,
-		MAAS:        req.Outputs.MAAS,
-		UKI:         req.Outputs.UKI,
-		FIPS:        req.Outputs.FIPS,
-		TrustedBoot: req.Outputs.TrustedBoot,
+		ISO:                 req.Outputs.ISO,
+		CloudImage:          req.Outputs.CloudImage,
+		Netboot:             req.Outputs.Netboot,
+		RawDisk:             req.Outputs.RawDisk,
+		Tar:                 req.Outputs.Tar,
+		GCE:                 req.Outputs.GCE,
+		VHD:                 req.Outputs.VHD,
+		MAAS:                req.Outputs.MAAS,
+		UKI:                 req.Outputs.UKI,
+		FIPS:                req.Outputs.FIPS,
+		TrustedBoot:         req.Outputs.TrustedBoot,
+		QCOW2:               req.Outputs.QCOW2,
+		QCOW2Compress:       req.Outputs.QCOW2Compress,
+		VHDDynamic:          req.Outputs.VHDDynamic,
+		VHDX:                req.Outputs.VHDX,
+		VMDK:                req.Outputs.VMDK,
+		OVA:                 req.Outputs.OVA,
+		OVACPUs:             req.Outputs.OVACPUs,
+		OVAMemoryMB:         req.Outputs.OVAMemoryMB,
+		OVAUserDataProperty: req.Outputs.OVAUserDataProperty,
+		SBOM:                req.Outputs.SBOM,
+		SBOMFormat:          sbomFormat,
+		Compression:         req.Outputs.Compression,
 	}
 	opts.Signing = builder.SigningOptions{
 		UKISecureBootKey:  ukiSBKey,
 		UKISecureBootCert: ukiSBCert,
 		UKITPMPCRKey:      ukiTPMKey,
 		UKIPublicKeysDir:  ukiPubKeysDir,
+		ManifestKey:       manifestKey,
+		ManifestKeySetID:  manifestKeySetID,
 	}
 	if req.Signing != nil {
 		opts.Signing.UKISecureBootEnroll = req.Signing.UKISecureBootEnroll
@@ -300,6 +480,13 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 		}
 	}
 
+	// Lint the extra YAML on its own first, so its issues carry the line
+	// numbers the user typed; buildCloudConfig would drop YAML that does
+	// not parse.
+	if err := cloudconfig.LintFragment(req.CloudConfig).Err(); err != nil {
+		return nil, &buildStartFailure{http.StatusBadRequest, err.Error()}
+	}
+
 	// Build the canonical cloud-config from structured provisioning fields.
 	// req.CloudConfig is treated as "extra YAML" appended at the end (the
 	// Advanced field), NOT a full document — this prevents duplicate top-level
@@ -320,6 +507,23 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 		sshKeys:            req.Provisioning.SSHKeys,
 		extraYAML:          req.CloudConfig,
 	})
+	// The merge can make valid parts invalid together, e.g. an extra
+	// install.poweroff next to the generated install.reboot.
+	if err := cloudconfig.Lint(opts.CloudConfig).Err(); err != nil {
+		return nil, &buildStartFailure{http.StatusBadRequest, "generated " + err.Error()}
+	}
+	if err := h.resolveSecretRefs(ctx, &opts); err != nil {
+		return nil, err
+	}
+
+	// A retried build keeps its record, so the Create below is skipped and
+	// the record would keep the digest of the failed run's token. Replace
+	// it before the build starts and can upload anything.
+	if resumeID != "" && h.store != nil {
+		if err := h.store.SetUploadToken(ctx, resumeID, hashUploadToken(uploadToken)); err != nil {
+			return nil, &buildStartFailure{http.StatusInternalServerError, "failed to prepare build"}
+		}
+	}
 
 	status, err := h.builder.Build(ctx, opts)
 	if err != nil {
@@ -327,14 +531,14 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 		// server fault (500). The validation detail (field + "invalid") is safe
 		// to surface; it carries no secrets.
 		if errors.Is(err, builder.ErrInvalidBuildOptions) {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return nil, &buildStartFailure{http.StatusBadRequest, err.Error()}
 		}
 		// A builder that cannot service this request (e.g. a scaffolded backend
 		// or one that does not produce a requested output) is 501, not 500.
 		if errors.Is(err, builder.ErrNotSupported) {
-			return c.JSON(http.StatusNotImplemented, map[string]string{"error": err.Error()})
+			return nil, &buildStartFailure{http.StatusNotImplemented, err.Error()}
 		}
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to start build"})
+		return nil, &buildStartFailure{http.StatusInternalServerError, "failed to start build"}
 	}
 
 	// Persist the artifact record in the store if available.
@@ -368,7 +572,19 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 			GCE:                     req.Outputs.GCE,
 			VHD:                     req.Outputs.VHD,
 			MAAS:                    req.Outputs.MAAS,
+			QCOW2:                   req.Outputs.QCOW2,
+			QCOW2Compress:           req.Outputs.QCOW2Compress,
+			VHDDynamic:              req.Outputs.VHDDynamic,
+			VHDX:                    req.Outputs.VHDX,
+			VMDK:                    req.Outputs.VMDK,
+			OVA:                     req.Outputs.OVA,
+			OVACPUs:                 req.Outputs.OVACPUs,
+			OVAMemoryMB:             req.Outputs.OVAMemoryMB,
+			OVAUserDataProperty:     req.Outputs.OVAUserDataProperty,
+			Compression:             req.Outputs.Compression,
 			UKI:                     req.Outputs.UKI,
+			SBOM:                    req.Outputs.SBOM,
+			SBOMFormat:              sbomFormat,
 			KairosInitImage:         req.KairosInitImage,
 			AutoInstall:             autoInstall,
 			RegisterAuroraBoot:      registerAuroraBoot,
@@ -383,6 +599,10 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 			KubernetesEnabled:       boolPtr(kubernetesEnabled),
 			TargetGroupID:           req.Provisioning.TargetGroupId,
 			OverlayRootfs:           req.OverlayRootfs,
+			ManifestKeySetID:        manifestKeySetID,
+		}
+		if gitSrc != nil {
+			rec.Git = &store.GitSource{URL: gitSrc.URL, Ref: gitSrc.Ref, Subpath: gitSrc.Subpath, Username: gitSrc.Username, Secret: gitSrc.Secret}
 		}
 		// A builder that persists on its own (the local backend) will have
 		// already written the row before Build returned; a builder that does
@@ -401,12 +621,81 @@ func (h *ArtifactHandler) Create(c echo.Context) error {
 				if cancelErr := h.builder.Cancel(ctx, status.ID); cancelErr != nil && !errors.Is(cancelErr, builder.ErrNotSupported) {
 					fmt.Fprintf(os.Stderr, "create: reap phantom CR %q after store.Create failed: %v\n", status.ID, cancelErr)
 				}
-				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to persist build"})
+				return nil, &buildStartFailure{http.StatusInternalServerError, "failed to persist build"}
 			}
 		}
 	}
 
-	return c.JSON(http.StatusCreated, status)
+	// Keep the request for rebuilds. The build is already running, so a
+	// failure here only costs the rebuild and is not worth failing it for.
+	if h.specs != nil {
+		spec, err := json.Marshal(req)
+		if err == nil {
+			err = h.specs.SetSpec(ctx, status.ID, string(spec))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "create: saving request of build %q: %v\n", status.ID, err)
+		}
+	}
+
+	return status, nil
+}
+
+// resolveGitSource validates a request's Git source and reads its password
+// from the secret store.
+func (h *ArtifactHandler) resolveGitSource(ctx context.Context, req gitSourceRequest) (*gitsource.Source, error) {
+	src := &gitsource.Source{
+		URL:      strings.TrimSpace(req.URL),
+		Ref:      strings.TrimSpace(req.Ref),
+		Subpath:  strings.Trim(req.Subpath, "/"),
+		Username: req.Username,
+		Secret:   req.Secret,
+	}
+	if err := src.Validate(); err != nil {
+		return nil, &buildStartFailure{http.StatusBadRequest, err.Error()}
+	}
+	if src.Secret != "" {
+		if h.secrets == nil {
+			return nil, &buildStartFailure{http.StatusBadRequest, "git secrets are not available on this server"}
+		}
+		secret, err := h.secrets.Get(ctx, src.Secret)
+		if err != nil {
+			return nil, &buildStartFailure{http.StatusBadRequest, fmt.Sprintf("secret %q not found", src.Secret)}
+		}
+		src.Password = secret.Value
+	}
+	return src, nil
+}
+
+// resolveSecretRefs reads the secrets the cloud-config references as
+// {{ secret "name" }} into opts.Secrets, for the builder to substitute, and
+// has their values redacted from the build log. opts.CloudConfig keeps the
+// references, so neither the artifact record nor the saved request ever
+// holds a value.
+func (h *ArtifactHandler) resolveSecretRefs(ctx context.Context, opts *builder.BuildOptions) error {
+	names := cloudconfig.SecretRefs(opts.CloudConfig)
+	if len(names) == 0 {
+		return nil
+	}
+	if h.secrets == nil {
+		return &buildStartFailure{http.StatusBadRequest, "secret references are not available on this server"}
+	}
+	opts.Secrets = make(map[string]string, len(names))
+	for _, name := range names {
+		secret, err := h.secrets.Get(ctx, name)
+		if err != nil {
+			return &buildStartFailure{http.StatusBadRequest, fmt.Sprintf("cloud-config references secret %q, which does not exist", name)}
+		}
+		opts.Secrets[name] = secret.Value
+		// The log is redacted line by line; a key file is hidden a line
+		// at a time.
+		for _, line := range strings.Split(secret.Value, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				opts.LogRedactValues = append(opts.LogRedactValues, line)
+			}
+		}
+	}
+	return nil
 }
 
 // List handles GET /api/v1/artifacts.
@@ -502,6 +791,58 @@ func (h *ArtifactHandler) GetLogs(c echo.Context) error {
 	return c.String(http.StatusOK, logs)
 }
 
+// GetSBOM handles GET /api/v1/artifacts/:id/sbom.
+//
+//	@Summary		Get an artifact's SBOM and vulnerability report
+//	@Description	Names the SBOM document the build produced and, when the builder matched it against a local advisory database, returns the vulnerability summary and findings. The document itself is served by the download endpoint. 404 until the build has written it.
+//	@Tags			Artifacts
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			id	path		string	true	"Artifact ID"
+//	@Success		200	{object}	APIArtifactSBOM
+//	@Failure		400	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Router			/api/v1/artifacts/{id}/sbom [get]
+func (h *ArtifactHandler) GetSBOM(c echo.Context) error {
+	id := c.Param("id")
+	if err := safePathSegment(id); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid artifact id"})
+	}
+	if h.store == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "artifact not found"})
+	}
+	rec, err := h.store.GetByID(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "artifact not found"})
+	}
+	if !rec.SBOM {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "no SBOM was requested for this artifact"})
+	}
+
+	format := rec.SBOMFormat
+	if format == "" {
+		format = sbom.FormatSPDX
+	}
+	resp := APIArtifactSBOM{Format: format, File: sbom.FileName(format)}
+	buildDir := filepath.Join(h.artifactsDir, id)
+	if _, err := os.Stat(filepath.Join(buildDir, resp.File)); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "SBOM not available yet"})
+	}
+
+	// The summary on the record is enough for the UI badge; the findings
+	// only live in the report file, which can be large, so they are read
+	// on demand here.
+	resp.Vulnerabilities = rec.Vulnerabilities
+	if data, err := os.ReadFile(filepath.Join(buildDir, sbom.ReportFileName)); err == nil {
+		var report sbom.VulnReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "vulnerability report is unreadable"})
+		}
+		resp.Findings = report.Findings
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
 // Upload handles PUT /api/v1/artifacts/:id/upload/*.
 //
 // The operator backend's exporter Job hits this endpoint once per artifact
@@ -521,6 +862,7 @@ func (h *ArtifactHandler) GetLogs(c echo.Context) error {
 //	@Failure	401	{object}	APIError
 //	@Failure	404	{object}	APIError
 //	@Failure	413	{object}	APIError
+//	@Failure	502	{object}	APIError
 //	@Router		/api/v1/artifacts/{id}/upload/{filename} [put]
 func (h *ArtifactHandler) Upload(c echo.Context) error {
 	id := c.Param("id")
@@ -578,8 +920,9 @@ func (h *ArtifactHandler) Upload(c echo.Context) error {
 	tmpPath := tmp.Name()
 	defer os.Remove(tmpPath)
 
+	hash := sha256.New()
 	limited := http.MaxBytesReader(c.Response().Writer, c.Request().Body, maxUploadBytes)
-	if _, err := io.Copy(tmp, limited); err != nil {
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), limited); err != nil {
 		_ = tmp.Close()
 		var maxErr *http.MaxBytesError
 		if errors.As(err, &maxErr) {
@@ -591,10 +934,50 @@ func (h *ArtifactHandler) Upload(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "upload close failed"})
 	}
 
+	// Check the body against the checksum the uploader sent and against
+	// the build's SHA256SUMS, whichever of the two arrived first, before
+	// it replaces anything on disk.
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if want := c.Request().Header.Get(checksums.Header); want != "" && !strings.EqualFold(want, sum) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "checksum mismatch"})
+	}
+	if err := checkUploadAgainstManifest(buildDir, clean, tmpPath, sum); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// The manifest is signed here rather than by the remote builder, which
+	// never holds the key. Signing before the rename means a key problem
+	// leaves nothing half-published for the exporter's retry to trip over.
+	var sig []byte
+	if clean == checksums.FileName {
+		if sig, err = h.signManifest(ctx, rec, tmpPath); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to sign " + checksums.FileName})
+		}
+	}
+
 	dst := filepath.Join(buildDir, clean)
 	if err := os.Rename(tmpPath, dst); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "upload finalize failed"})
 	}
+	if sig != nil {
+		if err := os.WriteFile(filepath.Join(buildDir, checksums.SignatureFileName), sig, 0o644); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to write " + checksums.SignatureFileName})
+		}
+	}
+
+	// Push to the artifact storage before the file is listed, so a listed
+	// file is always downloadable. A failure here is worth the exporter's
+	// retry: the next attempt rewrites the local copy and pushes again.
+	stored := []string{clean}
+	if sig != nil {
+		stored = append(stored, checksums.SignatureFileName)
+	}
+	for _, name := range stored {
+		if err := h.storage.Put(ctx, storage.Key(id, name), filepath.Join(buildDir, name)); err != nil {
+			fmt.Fprintf(os.Stderr, "upload: storing %s/%s failed: %v\n", id, name, err)
+			return c.JSON(http.StatusBadGateway, map[string]string{"error": "failed to store artifact"})
+		}
+	}
 
 	// Reflect the newly-uploaded file in the store record so the UI's
 	// existing artifact list (which reads ArtifactFiles verbatim) surfaces
@@ -606,11 +989,90 @@ func (h *ArtifactHandler) Upload(c echo.Context) error {
 	// disk, the store row can be reconciled later, and returning 500 here
 	// would mislead the exporter into retrying an already-durable upload.
 	appendArtifactFile(rec, clean)
+	if sig != nil {
+		appendArtifactFile(rec, checksums.SignatureFileName)
+	}
 	_ = h.store.UpdateFiles(ctx, id, rec.ArtifactFiles)
 
 	return c.NoContent(http.StatusCreated)
 }
 
+// checkUploadAgainstManifest cross-checks an upload with the build's
+// SHA256SUMS. A file must match the entry the manifest already on disk has
+// for it; a manifest must match every file it lists that is already on disk.
+// Together they catch a mismatch whichever order the uploader sends them in.
+// The signature is produced here, so an uploaded one is refused outright.
+func checkUploadAgainstManifest(buildDir, name, tmpPath, sum string) error {
+	switch name {
+	case checksums.SignatureFileName:
+		return fmt.Errorf("%s is produced by the server", checksums.SignatureFileName)
+	case checksums.FileName:
+		data, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return err
+		}
+		entries, err := checksums.Parse(data)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", checksums.FileName, err)
+		}
+		for _, e := range entries {
+			got, err := checksums.HashFile(filepath.Join(buildDir, e.Name))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if got != e.SHA256 {
+				return fmt.Errorf("checksum mismatch for %s", e.Name)
+			}
+		}
+		return nil
+	}
+	entries, err := checksums.Read(buildDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if want, ok := checksums.Lookup(entries, name); ok && want != sum {
+		return fmt.Errorf("checksum mismatch for %s", name)
+	}
+	return nil
+}
+
+// signManifest signs the manifest at path with the key the build was
+// created with, returning nil when it has none.
+func (h *ArtifactHandler) signManifest(ctx context.Context, rec *store.ArtifactRecord, path string) ([]byte, error) {
+	key := h.manifestKey
+	if rec.ManifestKeySetID != "" {
+		if h.secureBootKeys == nil {
+			return nil, errors.New("no key set store")
+		}
+		ks, err := h.secureBootKeys.GetByID(ctx, rec.ManifestKeySetID)
+		if err != nil {
+			return nil, err
+		}
+		key = manifestKeySetKey(ks)
+	}
+	if key == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return checksums.SignWithKeyFile(key, data)
+}
+
+// manifestKeySetKey is the private key of a SecureBoot key set used for
+// manifests: the same db key UKIs are signed with, so the db.pem already
+// distributed to verify boot images verifies the outputs too.
+func manifestKeySetKey(ks *store.SecureBootKeySet) string {
+	return filepath.Join(ks.KeysDir, "db.key")
+}
+
 // appendArtifactFile adds name to rec.ArtifactFiles if it is not already
 // present. Kept as a small helper so tests can reason about it in isolation
 // from the Upload handler.
@@ -639,6 +1101,42 @@ func safePathSegment(s string) error {
 	return nil
 }
 
+// Retry handles POST /api/v1/artifacts/:id/retry.
+//
+//	@Summary		Retry a failed build from the failed step
+//	@Description	Reruns a failed build in place from its stored request. The steps it completed are skipped as long as their inputs are unchanged and their outputs are still there, so a build that failed late does not start from zero. Only the local backend supports it.
+//	@Tags			Artifacts
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			id	path		string	true	"Artifact ID"
+//	@Success		202	{object}	builder.BuildStatus
+//	@Failure		404	{object}	APIError
+//	@Failure		409	{object}	APIError
+//	@Failure		501	{object}	APIError
+//	@Router			/api/v1/artifacts/{id}/retry [post]
+func (h *ArtifactHandler) Retry(c echo.Context) error {
+	if h.store == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "artifact not found"})
+	}
+	ctx := c.Request().Context()
+	rec, err := h.store.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "artifact not found"})
+	}
+	if rec.Phase != store.ArtifactError {
+		return c.JSON(http.StatusConflict, map[string]string{"error": fmt.Sprintf("only failed builds can be retried, this one is %s", rec.Phase)})
+	}
+	req, err := h.storedRequest(ctx, rec)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+	status, err := h.launchBuild(ctx, req, rec.ID)
+	if err != nil {
+		return startBuildError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, status)
+}
+
 // Cancel handles POST /api/v1/artifacts/:id/cancel.
 // Cancel handles POST /api/v1/artifacts/:id/cancel.
 //
@@ -690,7 +1188,17 @@ func (h *ArtifactHandler) Cancel(c echo.Context) error {
 	return c.JSON(http.StatusOK, rec)
 }
 
-// Download handles GET /api/v1/artifacts/:id/download/*.
+// Download handles GET and HEAD /api/v1/artifacts/:id/download/*.
+//
+// When the artifact storage hands out URLs (S3), the client is redirected
+// to a presigned one so the bytes come straight from the object store. A
+// file the storage does not hold yet (an artifact built before the storage
+// was configured and not reached by the migration) is served from the
+// local copy instead.
+//
+// Either way the response advertises the file's size and the checksum the
+// build's SHA256SUMS lists for it, so a client can tell how much a
+// compressed output will take and check it without a second request.
 func (h *ArtifactHandler) Download(c echo.Context) error {
 	id := c.Param("id")
 	// Echo uses * for catch-all params; the param name is "*".
@@ -726,9 +1234,43 @@ func (h *ArtifactHandler) Download(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid path"})
 	}
 
+	setDownloadHeaders(c, filepath.Join(h.artifactsDir, id), filename)
+
+	ctx := c.Request().Context()
+	key := storage.Key(id, filename)
+	if u, err := h.storage.URL(ctx, key); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to resolve download URL"})
+	} else if u != "" {
+		if ok, err := h.storage.Exists(ctx, key); err == nil && ok {
+			return c.Redirect(http.StatusFound, u)
+		}
+	}
+
 	return c.File(filePath)
 }
 
+// setDownloadHeaders sets X-Artifact-Size to the size of filename in the
+// build's output directory dir, and X-Checksum-Sha256 and Digest to its
+// checksum in the SHA256SUMS there. Whatever the local copy does not tell is
+// left out.
+func setDownloadHeaders(c echo.Context, dir, filename string) {
+	if st, err := os.Stat(filepath.Join(dir, filename)); err == nil {
+		c.Response().Header().Set("X-Artifact-Size", strconv.FormatInt(st.Size(), 10))
+	}
+	entries, err := checksums.Read(dir)
+	if err != nil {
+		return
+	}
+	sum, ok := checksums.Lookup(entries, filename)
+	if !ok {
+		return
+	}
+	c.Response().Header().Set("X-Checksum-Sha256", sum)
+	if b, err := hex.DecodeString(sum); err == nil {
+		c.Response().Header().Set("Digest", "sha-256="+base64.StdEncoding.EncodeToString(b))
+	}
+}
+
 // ClearFailed handles DELETE /api/v1/artifacts/failed.
 func (h *ArtifactHandler) ClearFailed(c echo.Context) error {
 	ctx := c.Request().Context()
@@ -746,6 +1288,9 @@ func (h *ArtifactHandler) ClearFailed(c echo.Context) error {
 					fmt.Fprintf(os.Stderr, "clear-failed: builder.Cancel(%q) failed: %v\n", r.ID, cancelErr)
 				}
 				os.RemoveAll(filepath.Join(h.artifactsDir, r.ID))
+				if err := h.storage.Delete(ctx, r.ID); err != nil {
+					fmt.Fprintf(os.Stderr, "clear-failed: removing %q from artifact storage failed: %v\n", r.ID, err)
+				}
 				if r.OverlayRootfs != "" && strings.HasPrefix(r.OverlayRootfs, h.artifactsDir) {
 					os.RemoveAll(r.OverlayRootfs)
 				}
@@ -776,6 +1321,17 @@ func (h *ArtifactHandler) Delete(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "artifact not found"})
 	}
+	if err := h.remove(ctx, rec); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to delete"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// remove deletes an artifact's record and everything it left behind: the
+// build, its output directory and stored copy, the uploaded overlay and the
+// local container image. Delete and the retention GC share it.
+func (h *ArtifactHandler) remove(ctx context.Context, rec *store.ArtifactRecord) error {
+	id := rec.ID
 
 	// Cancel is best-effort AND unconditional. Delete's job is to reclaim
 	// state, and refusing here would strand the row and its on-disk files
@@ -790,9 +1346,13 @@ func (h *ArtifactHandler) Delete(c echo.Context) error {
 		fmt.Fprintf(os.Stderr, "delete: builder.Cancel(%q) failed: %v\n", id, cancelErr)
 	}
 
-	// Remove build output directory.
+	// Remove build output directory, and the stored copy when the artifact
+	// storage is not that directory.
 	outputDir := filepath.Join(h.artifactsDir, id)
 	os.RemoveAll(outputDir)
+	if err := h.storage.Delete(ctx, id); err != nil {
+		fmt.Fprintf(os.Stderr, "delete: removing %q from artifact storage failed: %v\n", id, err)
+	}
 
 	// Remove uploaded overlay directory if present.
 	if rec.OverlayRootfs != "" && strings.HasPrefix(rec.OverlayRootfs, h.artifactsDir) {
@@ -805,10 +1365,7 @@ func (h *ArtifactHandler) Delete(c echo.Context) error {
 	}
 
 	// Delete DB record.
-	if err := h.store.Delete(ctx, id); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to delete"})
-	}
-	return c.NoContent(http.StatusNoContent)
+	return h.store.Delete(ctx, id)
 }
 
 // Update handles PATCH /api/v1/artifacts/:id.
@@ -1234,6 +1791,15 @@ func boolPtr(v bool) *bool { return &v }
 // Per-row Update failures are logged and skipped so a single bad row does not
 // block the rest of the sweep; only a failure listing artifacts is fatal.
 func ReconcileOrphanedArtifacts(ctx context.Context, artifacts store.ArtifactStore) error {
+	return ReconcileOrphanedArtifactsKeeping(ctx, artifacts, nil)
+}
+
+// ReconcileOrphanedArtifactsKeeping is ReconcileOrphanedArtifacts for backends
+// whose in-flight builds outlive the server process. keep reports whether a
+// Pending/Building record is still owned by something that will drive it to
+// a terminal state (the remote-worker backend's persistent queue); those rows
+// are left untouched. A nil keep fails every in-flight row.
+func ReconcileOrphanedArtifactsKeeping(ctx context.Context, artifacts store.ArtifactStore, keep func(id string) bool) error {
 	recs, err := artifacts.List(ctx)
 	if err != nil {
 		return fmt.Errorf("listing artifacts: %w", err)
@@ -1242,6 +1808,9 @@ func ReconcileOrphanedArtifacts(ctx context.Context, artifacts store.ArtifactSto
 		if rec.Phase != store.ArtifactPending && rec.Phase != store.ArtifactBuilding {
 			continue
 		}
+		if keep != nil && keep(rec.ID) {
+			continue
+		}
 		prevPhase := rec.Phase
 		rec.Phase = store.ArtifactError
 		rec.Message = "interrupted by server restart"
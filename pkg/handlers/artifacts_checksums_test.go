@@ -0,0 +1,174 @@
+package handlers_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("ArtifactHandler checksums", func() {
+	const (
+		buildID = "build-sums"
+		token   = "abcdef0123456789"
+	)
+
+	var (
+		e            *echo.Echo
+		fb           *fakeBuilder
+		as           *fakeArtifactStore
+		keys         *fakeSecureBootKeySetStore
+		artifactsDir string
+		keyPath      string
+		key          *ecdsa.PrivateKey
+		handler      *handlers.ArtifactHandler
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		fb = &fakeBuilder{}
+		as = &fakeArtifactStore{
+			records: []*store.ArtifactRecord{
+				{ID: buildID, Phase: store.ArtifactBuilding, UploadToken: sha256Hex(token)},
+			},
+		}
+		artifactsDir = GinkgoT().TempDir()
+
+		var err error
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		keysDir := GinkgoT().TempDir()
+		keyPath = filepath.Join(keysDir, "db.key")
+		Expect(os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)).To(Succeed())
+		keys = &fakeSecureBootKeySetStore{keySets: []*store.SecureBootKeySet{{ID: "ks-1", Name: "prod", KeysDir: keysDir}}}
+
+		handler = handlers.NewArtifactHandler(fb, as, nil, keys, artifactsDir, "reg-token", "http://localhost:8080")
+	})
+
+	upload := func(filename string, body []byte, sum string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/artifacts/"+buildID+"/upload/"+filename, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if sum != "" {
+			req.Header.Set(checksums.Header, sum)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id", "*")
+		c.SetParamValues(buildID, filename)
+		Expect(handler.Upload(c)).To(Succeed())
+		return rec
+	}
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		Expect(handler.Create(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	isoSum := sha256Hex("iso-bytes")
+	manifest := []byte(isoSum + "  kairos.iso\n")
+
+	Describe("Upload", func() {
+		It("accepts a body matching its checksum header", func() {
+			Expect(upload("kairos.iso", []byte("iso-bytes"), isoSum).Code).To(Equal(http.StatusCreated))
+		})
+
+		It("rejects a body that does not match its checksum header and keeps nothing", func() {
+			rec := upload("kairos.iso", []byte("truncated"), isoSum)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("checksum mismatch"))
+			Expect(filepath.Join(artifactsDir, buildID, "kairos.iso")).NotTo(BeAnExistingFile())
+		})
+
+		It("rejects a file that disagrees with the SHA256SUMS already received", func() {
+			Expect(upload(checksums.FileName, manifest, "").Code).To(Equal(http.StatusCreated))
+			Expect(upload("kairos.iso", []byte("tampered"), "").Code).To(Equal(http.StatusBadRequest))
+			Expect(upload("kairos.iso", []byte("iso-bytes"), "").Code).To(Equal(http.StatusCreated))
+		})
+
+		It("rejects a SHA256SUMS that disagrees with the files already received", func() {
+			Expect(upload("kairos.iso", []byte("tampered"), "").Code).To(Equal(http.StatusCreated))
+			rec := upload(checksums.FileName, manifest, "")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("kairos.iso"))
+		})
+
+		It("rejects a malformed SHA256SUMS", func() {
+			Expect(upload(checksums.FileName, []byte("not a manifest\n"), "").Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("refuses an uploaded signature", func() {
+			Expect(upload(checksums.SignatureFileName, []byte("c2ln"), "").Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("leaves SHA256SUMS unsigned when no key is configured", func() {
+			Expect(upload(checksums.FileName, manifest, "").Code).To(Equal(http.StatusCreated))
+			Expect(filepath.Join(artifactsDir, buildID, checksums.SignatureFileName)).NotTo(BeAnExistingFile())
+		})
+
+		It("signs SHA256SUMS with the server key and lists the signature", func() {
+			handler.WithManifestSigningKey(keyPath)
+			Expect(upload("kairos.iso", []byte("iso-bytes"), isoSum).Code).To(Equal(http.StatusCreated))
+			Expect(upload(checksums.FileName, manifest, "").Code).To(Equal(http.StatusCreated))
+
+			Expect(checksums.VerifyDirSignature(filepath.Join(artifactsDir, buildID), key.Public())).To(Succeed())
+			rec, err := as.GetByID(nil, buildID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rec.ArtifactFiles).To(ConsistOf("kairos.iso", checksums.FileName, checksums.SignatureFileName))
+		})
+
+		It("signs SHA256SUMS with the key set recorded on the build", func() {
+			as.records[0].ManifestKeySetID = "ks-1"
+			Expect(upload(checksums.FileName, manifest, "").Code).To(Equal(http.StatusCreated))
+			Expect(checksums.VerifyDirSignature(filepath.Join(artifactsDir, buildID), key.Public())).To(Succeed())
+		})
+
+		It("fails the upload, and publishes nothing, when the key cannot be used", func() {
+			handler.WithManifestSigningKey(filepath.Join(artifactsDir, "missing.key"))
+			Expect(upload(checksums.FileName, manifest, "").Code).To(Equal(http.StatusInternalServerError))
+			Expect(filepath.Join(artifactsDir, buildID, checksums.FileName)).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Describe("Create", func() {
+		It("passes the server key to the builder by default", func() {
+			handler.WithManifestSigningKey(keyPath)
+			Expect(create(`{"baseImage":"quay.io/kairos/ubuntu:24.04","outputs":{"iso":true}}`).Code).To(Equal(http.StatusCreated))
+			Expect(fb.lastOpts.Signing.ManifestKey).To(Equal(keyPath))
+			Expect(fb.lastOpts.Signing.ManifestKeySetID).To(BeEmpty())
+		})
+
+		It("resolves a manifest key set to its db.key and records it", func() {
+			rec := create(`{"baseImage":"quay.io/kairos/ubuntu:24.04","outputs":{"iso":true},"signing":{"manifestKeySetId":"ks-1"}}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(fb.lastOpts.Signing.ManifestKey).To(Equal(keyPath))
+			Expect(fb.lastOpts.Signing.ManifestKeySetID).To(Equal("ks-1"))
+			Expect(as.records[len(as.records)-1].ManifestKeySetID).To(Equal("ks-1"))
+		})
+
+		It("rejects an unknown manifest key set with 400", func() {
+			rec := create(`{"baseImage":"quay.io/kairos/ubuntu:24.04","outputs":{"iso":true},"signing":{"manifestKeySetId":"nope"}}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(fb.builds).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/kairos-io/AuroraBoot/pkg/artifactdiff"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+// Diff handles GET /api/v1/artifacts/diff?from=:a&to=:b.
+//
+// The inputs come from the two artifact records, the outputs from their
+// build directories. Reading the rootfs of a large image takes a few
+// seconds; nothing is cached, as builds are compared rarely and on demand.
+//
+//	@Summary		Compare two artifacts
+//	@Description	Returns what changed from build `from` to build `to`: the build inputs that differ (base image and digest, Hadron layers, Git commit, ...), line diffs of the Dockerfile and cloud-config, and, read from the outputs, the packages added, removed or upgraded according to the SBOMs, the kernel version, and the files added, removed or changed in the initrd and rootfs. A section is left out when one of the builds does not have it; `notes` says why.
+//	@Tags			Artifacts
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			from	query		string	true	"Artifact ID of build A"
+//	@Param			to		query		string	true	"Artifact ID of build B"
+//	@Success		200		{object}	artifactdiff.Diff
+//	@Failure		400		{object}	APIError
+//	@Failure		404		{object}	APIError
+//	@Router			/api/v1/artifacts/diff [get]
+func (h *ArtifactHandler) Diff(c echo.Context) error {
+	from, to := c.QueryParam("from"), c.QueryParam("to")
+	if from == "" || to == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from and to are required"})
+	}
+	if safePathSegment(from) != nil || safePathSegment(to) != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid artifact id"})
+	}
+	if h.store == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "artifact not found"})
+	}
+	ctx := c.Request().Context()
+	var snaps [2]*artifactdiff.Snapshot
+	for i, id := range []string{from, to} {
+		rec, err := h.store.GetByID(ctx, id)
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "artifact " + id + " not found"})
+		}
+		snaps[i] = artifactdiff.Load(filepath.Join(h.artifactsDir, id), id, diffInputs(rec))
+	}
+	return c.JSON(http.StatusOK, artifactdiff.Compare(snaps[0], snaps[1]))
+}
+
+// diffInputs is what the record says the build was asked to do. The digests
+// and Git commit are only set once the build ran; Load falls back to the
+// build manifest for anything left empty.
+func diffInputs(rec *store.ArtifactRecord) artifactdiff.Inputs {
+	in := artifactdiff.Inputs{
+		BaseImage:             rec.BaseImage,
+		BaseImageDigest:       rec.BaseImageDigest,
+		KairosInitImage:       rec.KairosInitImage,
+		KairosInitImageDigest: rec.KairosInitImageDigest,
+		KairosVersion:         rec.KairosVersion,
+		Arch:                  rec.Arch,
+		Variant:               rec.Variant,
+		Model:                 rec.Model,
+		KubernetesDistro:      rec.KubernetesDistro,
+		KubernetesVersion:     rec.KubernetesVersion,
+		HadronBase:            rec.HadronBase,
+		HadronLayers:          rec.HadronLayers,
+		HadronFirmware:        rec.HadronFirmware,
+		Dockerfile:            rec.Dockerfile,
+		CloudConfig:           rec.CloudConfig,
+	}
+	if rec.Git != nil {
+		in.GitURL = rec.Git.URL
+		in.GitCommit = rec.Git.Commit
+	}
+	return in
+}
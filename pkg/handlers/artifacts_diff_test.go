@@ -0,0 +1,77 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/artifactdiff"
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("ArtifactHandler Diff", func() {
+	var (
+		e            *echo.Echo
+		as           *fakeArtifactStore
+		artifactsDir string
+		handler      *handlers.ArtifactHandler
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		as = &fakeArtifactStore{}
+		artifactsDir = GinkgoT().TempDir()
+		handler = handlers.NewArtifactHandler(&fakeBuilder{}, as, nil, nil, artifactsDir, "reg-token", "http://localhost:8080")
+	})
+
+	diff := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/artifacts/diff?"+query, nil)
+		rec := httptest.NewRecorder()
+		Expect(handler.Diff(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	It("compares the inputs of the two records", func() {
+		as.records = []*store.ArtifactRecord{
+			{ID: "a1", Phase: store.ArtifactReady, BaseImage: "ubuntu:24.04", BaseImageDigest: "sha256:aaa", Dockerfile: "FROM x\nRUN a\n"},
+			{ID: "b1", Phase: store.ArtifactReady, BaseImage: "ubuntu:24.04", BaseImageDigest: "sha256:bbb", Dockerfile: "FROM x\nRUN b\n",
+				Git: &store.GitSource{URL: "https://example.com/os.git", Commit: "abc123"}},
+		}
+		Expect(os.MkdirAll(filepath.Join(artifactsDir, "a1"), 0o755)).To(Succeed())
+
+		rec := diff("from=a1&to=b1")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var d artifactdiff.Diff
+		Expect(json.Unmarshal(rec.Body.Bytes(), &d)).To(Succeed())
+		Expect(d.A).To(Equal("a1"))
+		Expect(d.B).To(Equal("b1"))
+		Expect(d.Inputs).To(ConsistOf(
+			artifactdiff.Change{Field: "baseImageDigest", A: "sha256:aaa", B: "sha256:bbb"},
+			artifactdiff.Change{Field: "gitUrl", B: "https://example.com/os.git"},
+			artifactdiff.Change{Field: "gitCommit", B: "abc123"},
+		))
+		Expect(d.Dockerfile).To(Equal([]string{" FROM x", "-RUN a", "+RUN b"}))
+		Expect(d.Rootfs).To(BeNil())
+		Expect(d.Notes).NotTo(BeEmpty())
+	})
+
+	It("returns 400 without both IDs", func() {
+		Expect(diff("from=a1").Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns 400 for an ID that is not a path segment", func() {
+		Expect(diff("from=a1&to=..").Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns 404 for an unknown artifact", func() {
+		as.records = []*store.ArtifactRecord{{ID: "a1", Phase: store.ArtifactReady}}
+		Expect(diff("from=a1&to=nope").Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -59,4 +59,21 @@ var _ = Describe("ReconcileOrphanedArtifacts", func() {
 		Expect(ready.Message).To(Equal("done"))
 		Expect(ready.UpdatedAt).To(Equal(originalUpdatedAt))
 	})
+
+	It("leaves rows the keep predicate still owns in flight", func() {
+		ctx := context.Background()
+		Expect(artifacts.Create(ctx, &store.ArtifactRecord{ID: "art-queued", Phase: store.ArtifactPending})).To(Succeed())
+		Expect(artifacts.Create(ctx, &store.ArtifactRecord{ID: "art-lost", Phase: store.ArtifactBuilding})).To(Succeed())
+
+		keep := func(id string) bool { return id == "art-queued" }
+		Expect(handlers.ReconcileOrphanedArtifactsKeeping(ctx, artifacts, keep)).To(Succeed())
+
+		queued, err := artifacts.GetByID(ctx, "art-queued")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(queued.Phase).To(Equal(store.ArtifactPending))
+
+		lost, err := artifacts.GetByID(ctx, "art-lost")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lost.Phase).To(Equal(store.ArtifactError))
+	})
 })
@@ -0,0 +1,113 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("ArtifactHandler SBOM", func() {
+	var (
+		e            *echo.Echo
+		fb           *fakeBuilder
+		as           *fakeArtifactStore
+		artifactsDir string
+		handler      *handlers.ArtifactHandler
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		fb = &fakeBuilder{}
+		as = &fakeArtifactStore{}
+		artifactsDir = GinkgoT().TempDir()
+		handler = handlers.NewArtifactHandler(fb, as, nil, nil, artifactsDir, "reg-token", "http://localhost:8080")
+	})
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		Expect(handler.Create(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	getSBOM := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/artifacts/"+id+"/sbom", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		Expect(handler.GetSBOM(c)).To(Succeed())
+		return rec
+	}
+
+	Describe("Create", func() {
+		It("defaults the SBOM format to SPDX and records it", func() {
+			rec := create(`{"baseImage":"quay.io/kairos/ubuntu:24.04","outputs":{"iso":true,"sbom":true}}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(fb.lastOpts.Outputs.SBOM).To(BeTrue())
+			Expect(fb.lastOpts.Outputs.SBOMFormat).To(Equal(sbom.FormatSPDX))
+			Expect(as.records).To(HaveLen(1))
+			Expect(as.records[0].SBOM).To(BeTrue())
+			Expect(as.records[0].SBOMFormat).To(Equal(sbom.FormatSPDX))
+		})
+
+		It("rejects an unknown SBOM format with 400", func() {
+			rec := create(`{"baseImage":"quay.io/kairos/ubuntu:24.04","outputs":{"iso":true,"sbom":true,"sbomFormat":"syft-json"}}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(fb.builds).To(BeEmpty())
+		})
+	})
+
+	Describe("GetSBOM", func() {
+		It("returns 404 when the artifact did not ask for an SBOM", func() {
+			as.records = []*store.ArtifactRecord{{ID: "a1", Phase: store.ArtifactReady}}
+			Expect(getSBOM("a1").Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("returns 404 while the document has not been written", func() {
+			as.records = []*store.ArtifactRecord{{ID: "a1", Phase: store.ArtifactBuilding, SBOM: true, SBOMFormat: sbom.FormatCycloneDX}}
+			Expect(getSBOM("a1").Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("returns the document name, summary and findings", func() {
+			summary := &store.VulnerabilitySummary{Database: "/mirror", High: 1, Total: 1}
+			as.records = []*store.ArtifactRecord{{
+				ID: "a1", Phase: store.ArtifactReady, SBOM: true, SBOMFormat: sbom.FormatCycloneDX, Vulnerabilities: summary,
+			}}
+			dir := filepath.Join(artifactsDir, "a1")
+			Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "sbom.cdx.json"), []byte("{}"), 0o644)).To(Succeed())
+			report, err := json.Marshal(sbom.VulnReport{Findings: []sbom.Finding{
+				{ID: "DSA-1", Package: "libssl3", Version: "3.0.11-1", FixedVersion: "3.0.11-2", Severity: sbom.SeverityHigh},
+			}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(dir, sbom.ReportFileName), report, 0o644)).To(Succeed())
+
+			rec := getSBOM("a1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var got handlers.APIArtifactSBOM
+			Expect(json.Unmarshal(rec.Body.Bytes(), &got)).To(Succeed())
+			Expect(got.Format).To(Equal(sbom.FormatCycloneDX))
+			Expect(got.File).To(Equal("sbom.cdx.json"))
+			Expect(got.Vulnerabilities).To(Equal(summary))
+			Expect(got.Findings).To(HaveLen(1))
+			Expect(got.Findings[0].FixedVersion).To(Equal("3.0.11-2"))
+		})
+
+		It("rejects a traversal id", func() {
+			Expect(getSBOM("..").Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
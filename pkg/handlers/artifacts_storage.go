@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+)
+
+// MigrateArtifactStorage copies the outputs of every Ready artifact that
+// backend does not hold yet from their directory under artifactsDir. It is
+// the migration path for artifacts built before an object store was
+// configured: runWeb starts it in the background, Download keeps serving
+// the local copy of a file until it has been copied, and since files already
+// stored are skipped it is safe to interrupt and run again on the next start.
+//
+// A storage that cannot be reached stops the migration; a file that fails to
+// copy is reported and the rest carry on.
+func MigrateArtifactStorage(ctx context.Context, artifacts store.ArtifactStore, artifactsDir string, backend storage.Backend) error {
+	recs, err := artifacts.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing artifacts: %w", err)
+	}
+	failed := 0
+	for _, rec := range recs {
+		if rec.Phase != store.ArtifactReady {
+			continue
+		}
+		for _, f := range rec.ArtifactFiles {
+			key := storage.Key(rec.ID, f)
+			ok, err := backend.Exists(ctx, key)
+			if err != nil {
+				return fmt.Errorf("checking %s: %w", key, err)
+			}
+			if ok {
+				continue
+			}
+			local := filepath.Join(artifactsDir, rec.ID, filepath.Base(f))
+			if _, err := os.Stat(local); err != nil {
+				fmt.Fprintf(os.Stderr, "storage migration: skipping %s: %v\n", key, err)
+				continue
+			}
+			if err := backend.Put(ctx, key, local); err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "storage migration: copying %s failed: %v\n", key, err)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "storage migration: copied %s\n", key)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d files could not be copied", failed)
+	}
+	return nil
+}
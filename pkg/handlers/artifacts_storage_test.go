@@ -0,0 +1,175 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
+	"github.com/kairos-io/AuroraBoot/pkg/storage/s3fake"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("ArtifactHandler with S3 storage", func() {
+	var (
+		e            *echo.Echo
+		as           *fakeArtifactStore
+		artifactsDir string
+		fake         *s3fake.Server
+		backend      *storage.S3
+		handler      *handlers.ArtifactHandler
+	)
+
+	const (
+		buildID = "build-s3"
+		token   = "abcdef0123456789"
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		artifactsDir = GinkgoT().TempDir()
+		fake = s3fake.New("artifacts", "minio")
+		DeferCleanup(fake.Close)
+		var err error
+		backend, err = storage.NewS3(storage.S3Config{
+			Endpoint:  fake.URL,
+			Bucket:    "artifacts",
+			AccessKey: "minio",
+			SecretKey: "minio123",
+			PathStyle: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		as = &fakeArtifactStore{
+			records: []*store.ArtifactRecord{
+				{ID: buildID, Phase: store.ArtifactBuilding, UploadToken: sha256Hex(token)},
+			},
+		}
+		handler = handlers.NewArtifactHandler(&fakeBuilder{}, as, nil, nil, artifactsDir, "reg-token", "http://localhost:8080").
+			WithStorage(backend)
+	})
+
+	upload := func(filename string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/artifacts/"+buildID+"/upload/"+filename, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id", "*")
+		c.SetParamValues(buildID, filename)
+		Expect(handler.Upload(c)).To(Succeed())
+		return rec
+	}
+
+	object := func(key string) []byte {
+		data, _ := fake.Object(key)
+		return data
+	}
+
+	download := func(filename string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/artifacts/"+buildID+"/download/"+filename, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id", "*")
+		c.SetParamValues(buildID, filename)
+		Expect(handler.Download(c)).To(Succeed())
+		return rec
+	}
+
+	It("pushes uploads to the bucket and redirects downloads to a presigned URL", func() {
+		Expect(upload("kairos.iso", []byte("iso-bytes")).Code).To(Equal(http.StatusCreated))
+		Expect(object(buildID + "/kairos.iso")).To(Equal([]byte("iso-bytes")))
+
+		rec := download("kairos.iso")
+		Expect(rec.Code).To(Equal(http.StatusFound))
+		loc := rec.Header().Get("Location")
+		Expect(loc).To(HavePrefix(fake.URL + "/artifacts/" + buildID + "/kairos.iso?"))
+		Expect(loc).To(ContainSubstring("X-Amz-Signature="))
+	})
+
+	It("fails an upload the bucket refuses, leaving the file unlisted", func() {
+		fake.Close()
+		rec := upload("kairos.iso", []byte("iso-bytes"))
+		Expect(rec.Code).To(Equal(http.StatusBadGateway))
+		Expect(as.records[0].ArtifactFiles).To(BeEmpty())
+	})
+
+	It("serves the local copy of a file the bucket does not hold yet", func() {
+		Expect(os.MkdirAll(filepath.Join(artifactsDir, buildID), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(artifactsDir, buildID, "old.iso"), []byte("old"), 0o644)).To(Succeed())
+
+		rec := download("old.iso")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(Equal("old"))
+	})
+
+	It("advertises the size and checksum of a download", func() {
+		dir := filepath.Join(artifactsDir, buildID)
+		Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "kairos.raw.zst"), []byte("hello\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "SHA256SUMS"), []byte("5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  kairos.raw.zst\n"), 0o644)).To(Succeed())
+		fake.PutObject(buildID+"/kairos.raw.zst", []byte("hello\n"))
+
+		rec := download("kairos.raw.zst")
+		Expect(rec.Code).To(Equal(http.StatusFound))
+		Expect(rec.Header().Get("X-Artifact-Size")).To(Equal("6"))
+		Expect(rec.Header().Get("X-Checksum-Sha256")).To(Equal("5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"))
+		Expect(rec.Header().Get("Digest")).To(Equal("sha-256=WJG1tSLV3whtD/CxEPvZ0hu0/HFjrzTQgoai6Eb2vgM="))
+
+		// Files the manifest does not list go without a checksum.
+		Expect(os.WriteFile(filepath.Join(dir, "other.iso"), []byte("iso"), 0o644)).To(Succeed())
+		rec = download("other.iso")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("X-Artifact-Size")).To(Equal("3"))
+		Expect(rec.Header().Get("X-Checksum-Sha256")).To(BeEmpty())
+	})
+
+	It("removes the stored objects on delete", func() {
+		fake.PutObject(buildID+"/kairos.iso", []byte("iso"))
+		fake.PutObject("other/kairos.iso", []byte("iso"))
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/artifacts/"+buildID, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(buildID)
+		Expect(handler.Delete(c)).To(Succeed())
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(fake.Keys()).To(Equal([]string{"other/kairos.iso"}))
+	})
+
+	Describe("MigrateArtifactStorage", func() {
+		It("copies the files of Ready artifacts the bucket is missing", func() {
+			dir := filepath.Join(artifactsDir, "old-build")
+			Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "kairos.iso"), []byte("iso"), 0o644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "SHA256SUMS"), []byte("sums"), 0o644)).To(Succeed())
+			fake.PutObject("old-build/SHA256SUMS", []byte("already there"))
+			as.records = append(as.records,
+				// Local builds record full paths, uploads bare names.
+				&store.ArtifactRecord{ID: "old-build", Phase: store.ArtifactReady, ArtifactFiles: []string{filepath.Join(dir, "kairos.iso"), "SHA256SUMS", "gone.raw"}},
+				&store.ArtifactRecord{ID: "failed-build", Phase: store.ArtifactError, ArtifactFiles: []string{"kairos.iso"}},
+			)
+
+			Expect(handlers.MigrateArtifactStorage(context.Background(), as, artifactsDir, backend)).To(Succeed())
+			Expect(object("old-build/kairos.iso")).To(Equal([]byte("iso")))
+			Expect(object("old-build/SHA256SUMS")).To(Equal([]byte("already there")))
+			for _, k := range fake.Keys() {
+				Expect(strings.HasPrefix(k, "failed-build/")).To(BeFalse())
+			}
+		})
+
+		It("stops when the storage is unreachable", func() {
+			as.records[0].Phase = store.ArtifactReady
+			as.records[0].ArtifactFiles = []string{"kairos.iso"}
+			fake.Close()
+			Expect(handlers.MigrateArtifactStorage(context.Background(), as, artifactsDir, backend)).To(MatchError(ContainSubstring("checking")))
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package handlers_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -47,6 +48,65 @@ var _ = Describe("ArtifactHandler", func() {
 			Expect(status.ID).NotTo(BeEmpty())
 		})
 
+		It("passes the qcow2 output and its compression to the builder", func() {
+			body := `{"baseImage":"quay.io/kairos/ubuntu:24.04","outputs":{"rawDisk":true,"qcow2":true,"qcow2Compress":true}}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			Expect(handler.Create(e.NewContext(req, rec))).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(fb.lastOpts.Outputs.QCOW2).To(BeTrue())
+			Expect(fb.lastOpts.Outputs.QCOW2Compress).To(BeTrue())
+		})
+
+		It("passes the dynamic VHD and VHDX outputs to the builder", func() {
+			body := `{"baseImage":"quay.io/kairos/ubuntu:24.04","outputs":{"vhd":true,"vhdDynamic":true,"vhdx":true}}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			Expect(handler.Create(e.NewContext(req, rec))).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(fb.lastOpts.Outputs.VHDDynamic).To(BeTrue())
+			Expect(fb.lastOpts.Outputs.VHDX).To(BeTrue())
+		})
+
+		It("passes the VMDK and OVA outputs and the OVA's virtual machine to the builder", func() {
+			body := `{"baseImage":"quay.io/kairos/ubuntu:24.04","outputs":{"vmdk":true,"ova":true,"ovaCpus":4,"ovaMemoryMB":8192,"ovaUserDataProperty":true}}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			Expect(handler.Create(e.NewContext(req, rec))).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(fb.lastOpts.Outputs.VMDK).To(BeTrue())
+			Expect(fb.lastOpts.Outputs.OVA).To(BeTrue())
+			Expect(fb.lastOpts.Outputs.OVACPUs).To(Equal(4))
+			Expect(fb.lastOpts.Outputs.OVAMemoryMB).To(Equal(8192))
+			Expect(fb.lastOpts.Outputs.OVAUserDataProperty).To(BeTrue())
+		})
+
+		It("passes the output compression to the builder and rejects unknown formats", func() {
+			body := `{"baseImage":"quay.io/kairos/ubuntu:24.04","outputs":{"rawDisk":true,"compression":"zstd"}}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			Expect(handler.Create(e.NewContext(req, rec))).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(fb.lastOpts.Outputs.Compression).To(Equal("zstd"))
+
+			body = `{"baseImage":"quay.io/kairos/ubuntu:24.04","outputs":{"rawDisk":true,"compression":"bzip2"}}`
+			req = httptest.NewRequest(http.MethodPost, "/api/v1/artifacts", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec = httptest.NewRecorder()
+
+			Expect(handler.Create(e.NewContext(req, rec))).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("outputs.compression"))
+		})
+
 		It("returns 400 (not 500) when build inputs fail validation", func() {
 			// The real builder rejects shell-metacharacter values like this
 			// before any build starts and returns an ErrInvalidBuildOptions-wrapped
@@ -200,6 +260,97 @@ var _ = Describe("ArtifactHandler", func() {
 		})
 	})
 
+	Describe("Create — cloud-config validation", func() {
+		post := func(body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			Expect(handler.Create(e.NewContext(req, rec))).To(Succeed())
+			return rec
+		}
+
+		It("rejects extra YAML that does not parse, naming the line", func() {
+			rec := post(`{"baseImage":"ubuntu:24.04","outputs":{"iso":true},"cloudConfig":"install:\n  auto: true\n device: auto\n"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("line 3"))
+			Expect(fb.builds).To(BeEmpty())
+		})
+
+		It("rejects extra YAML the schema does not allow", func() {
+			rec := post(`{"baseImage":"ubuntu:24.04","outputs":{"iso":true},"cloudConfig":"stages:\n  boot:\n  - commands: echo hi\n"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("stages.boot[0].commands"))
+		})
+
+		It("rejects extra YAML that conflicts with the generated config", func() {
+			rec := post(`{"baseImage":"ubuntu:24.04","outputs":{"iso":true},"cloudConfig":"install:\n  poweroff: true\n"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("generated invalid cloud-config"))
+		})
+
+		It("accepts extra YAML with only warnings", func() {
+			rec := post(`{"baseImage":"ubuntu:24.04","outputs":{"iso":true},"cloudConfig":"instal:\n  auto: true\n"}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+		})
+	})
+
+	Describe("Create — secret references", func() {
+		var (
+			secrets *fakeSecretStore
+			records *fakeArtifactStore
+		)
+
+		BeforeEach(func() {
+			secrets = newFakeSecretStore()
+			Expect(secrets.Set(context.Background(), &store.Secret{Name: "wifi-psk", Value: "correct-horse-battery"})).To(Succeed())
+			records = &fakeArtifactStore{}
+			handler = handlers.NewArtifactHandler(fb, records, nil, nil, "", "reg-token", "http://localhost:8080").WithSecrets(secrets)
+		})
+
+		post := func(body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			Expect(handler.Create(e.NewContext(req, rec))).To(Succeed())
+			return rec
+		}
+
+		It("hands the values to the builder and keeps only the reference", func() {
+			rec := post(`{"baseImage":"ubuntu:24.04","outputs":{"iso":true},"cloudConfig":"wifi:\n  psk: '{{ secret \"wifi-psk\" }}'\n"}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+
+			Expect(fb.lastOpts.CloudConfig).To(ContainSubstring(`psk: '{{ secret "wifi-psk" }}'`))
+			Expect(fb.lastOpts.CloudConfig).ToNot(ContainSubstring("correct-horse-battery"))
+			Expect(fb.lastOpts.Secrets).To(Equal(map[string]string{"wifi-psk": "correct-horse-battery"}))
+			Expect(fb.lastOpts.LogRedactValues).To(ContainElement("correct-horse-battery"))
+
+			stored, err := records.GetByID(context.Background(), fb.lastOpts.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored.CloudConfig).To(ContainSubstring(`{{ secret "wifi-psk" }}`))
+			Expect(stored.CloudConfig).ToNot(ContainSubstring("correct-horse-battery"))
+		})
+
+		It("rejects a reference to a secret that does not exist", func() {
+			rec := post(`{"baseImage":"ubuntu:24.04","outputs":{"iso":true},"cloudConfig":"wifi:\n  psk: '{{ secret \"nope\" }}'\n"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring(`secret \"nope\"`))
+			Expect(fb.builds).To(BeEmpty())
+		})
+
+		It("rejects an unquoted reference, naming the line", func() {
+			rec := post(`{"baseImage":"ubuntu:24.04","outputs":{"iso":true},"cloudConfig":"wifi:\n  psk: {{ secret \"wifi-psk\" }}\n"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("line 2"))
+			Expect(rec.Body.String()).To(ContainSubstring("must be quoted"))
+		})
+
+		It("rejects references on a server without a secret store", func() {
+			handler = handlers.NewArtifactHandler(fb, nil, nil, nil, "", "reg-token", "http://localhost:8080")
+			rec := post(`{"baseImage":"ubuntu:24.04","outputs":{"iso":true},"cloudConfig":"wifi:\n  psk: '{{ secret \"wifi-psk\" }}'\n"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
 	Describe("List", func() {
 		It("should list all builds", func() {
 			fb.builds = []*builder.BuildStatus{
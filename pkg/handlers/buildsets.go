@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+// maxBuildSetChildren caps how many artifacts one matrix may expand to. Every
+// child is a full image build; a typo'd axis should fail fast rather than
+// queue hundreds of them.
+const maxBuildSetChildren = 64
+
+// createBuildSetRequest is the expected body for creating a build set. It is
+// also what BuildSet.Spec stores, so Clone replays exactly the same request.
+type createBuildSetRequest struct {
+	Name     string                `json:"name"`
+	Template createArtifactRequest `json:"template"`
+	Matrix   buildMatrix           `json:"matrix"`
+}
+
+type buildMatrix struct {
+	BaseImages []string           `json:"baseImages,omitempty"`
+	Arches     []string           `json:"arches,omitempty"`
+	Variants   []string           `json:"variants,omitempty"`
+	Kubernetes []matrixKubernetes `json:"kubernetes,omitempty"`
+	Outputs    []artifactOutputs  `json:"outputs,omitempty"`
+}
+
+type matrixKubernetes struct {
+	Distro  string `json:"distro"`
+	Version string `json:"version"`
+}
+
+// BuildSetHandler serves build sets: one request expanded into a matrix of
+// artifact builds that are tracked, cancelled and cloned together.
+type BuildSetHandler struct {
+	artifacts *ArtifactHandler
+	sets      store.BuildSetStore
+}
+
+// NewBuildSetHandler creates a new BuildSetHandler. Children are started
+// through artifacts, so they get the same cloud-config, upload token and
+// error mapping as a single POST /api/v1/artifacts.
+func NewBuildSetHandler(artifacts *ArtifactHandler, sets store.BuildSetStore) *BuildSetHandler {
+	return &BuildSetHandler{artifacts: artifacts, sets: sets}
+}
+
+// Create handles POST /api/v1/build-sets.
+//
+//	@Summary		Start a build matrix
+//	@Description	Expands the matrix into one artifact build per combination and starts them all. If any child fails to start, the children already started are cancelled and the set is discarded; the error names the failing combination.
+//	@Tags			Artifacts
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			body	body		APICreateBuildSetRequest	true	"Build matrix"
+//	@Success		201		{object}	APIBuildSet
+//	@Failure		400		{object}	APIError
+//	@Failure		501		{object}	APIError
+//	@Router			/api/v1/build-sets [post]
+func (h *BuildSetHandler) Create(c echo.Context) error {
+	var req createBuildSetRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	return h.start(c, req)
+}
+
+// Clone handles POST /api/v1/build-sets/:id/clone.
+//
+//	@Summary		Rebuild a whole build matrix
+//	@Description	Starts a new build set from the stored request of an existing one.
+//	@Tags			Artifacts
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			id	path		string	true	"Build set ID"
+//	@Success		201	{object}	APIBuildSet
+//	@Failure		404	{object}	APIError
+//	@Router			/api/v1/build-sets/{id}/clone [post]
+func (h *BuildSetHandler) Clone(c echo.Context) error {
+	set, err := h.sets.GetByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "build set not found"})
+	}
+	var req createBuildSetRequest
+	if err := json.Unmarshal([]byte(set.Spec), &req); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "stored build set request is unreadable"})
+	}
+	return h.start(c, req)
+}
+
+func (h *BuildSetHandler) start(c echo.Context, req createBuildSetRequest) error {
+	ctx := c.Request().Context()
+	children, err := expandBuildMatrix(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	spec, err := json.Marshal(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to encode build set"})
+	}
+	set := &store.BuildSet{Name: req.Name, Spec: string(spec)}
+	if err := h.sets.Create(ctx, set); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create build set"})
+	}
+
+	var started []string
+	for _, child := range children {
+		status, err := h.artifacts.startBuild(ctx, child)
+		if err == nil {
+			started = append(started, status.ID)
+			err = h.sets.Attach(ctx, set.ID, status.ID)
+		}
+		if err != nil {
+			h.abandon(ctx, set.ID, started)
+			var f *buildStartFailure
+			if errors.As(err, &f) {
+				return c.JSON(f.code, map[string]string{"error": fmt.Sprintf("%s: %s", child.Name, f.msg)})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to start build set"})
+		}
+	}
+
+	resp, err := h.view(ctx, set)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to load build set"})
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// abandon cancels the children a failed Create already started and drops the
+// set. The cancelled children stay visible as ordinary artifacts so the
+// admin can see what ran.
+func (h *BuildSetHandler) abandon(ctx context.Context, setID string, started []string) {
+	for _, id := range started {
+		if err := h.artifacts.builder.Cancel(ctx, id); err != nil && !errors.Is(err, builder.ErrNotSupported) {
+			fmt.Fprintf(os.Stderr, "build set %s: cancel child %s: %v\n", setID, id, err)
+		}
+	}
+	if err := h.sets.Delete(ctx, setID); err != nil {
+		fmt.Fprintf(os.Stderr, "build set %s: discard after failed start: %v\n", setID, err)
+	}
+}
+
+// List handles GET /api/v1/build-sets.
+//
+//	@Summary	List build sets
+//	@Tags		Artifacts
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Success	200	{array}	APIBuildSet
+//	@Router		/api/v1/build-sets [get]
+func (h *BuildSetHandler) List(c echo.Context) error {
+	ctx := c.Request().Context()
+	sets, err := h.sets.List(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to list build sets"})
+	}
+	out := make([]APIBuildSet, 0, len(sets))
+	for _, set := range sets {
+		v, err := h.view(ctx, set)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to list build sets"})
+		}
+		// The list view carries the counts only; GET the set for children.
+		v.Children = nil
+		out = append(out, *v)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// Get handles GET /api/v1/build-sets/:id.
+//
+//	@Summary	Get a build set with its children
+//	@Tags		Artifacts
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Param		id	path		string	true	"Build set ID"
+//	@Success	200	{object}	APIBuildSet
+//	@Failure	404	{object}	APIError
+//	@Router		/api/v1/build-sets/{id} [get]
+func (h *BuildSetHandler) Get(c echo.Context) error {
+	ctx := c.Request().Context()
+	set, err := h.sets.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "build set not found"})
+	}
+	v, err := h.view(ctx, set)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to load build set"})
+	}
+	return c.JSON(http.StatusOK, v)
+}
+
+// Cancel handles POST /api/v1/build-sets/:id/cancel.
+//
+//	@Summary		Cancel every unfinished build of a set
+//	@Description	Cancels each child still Pending or Building. Finished children are left alone. Returns the set as it stands after the cancellations.
+//	@Tags			Artifacts
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			id	path		string	true	"Build set ID"
+//	@Success		200	{object}	APIBuildSet
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+//	@Router			/api/v1/build-sets/{id}/cancel [post]
+func (h *BuildSetHandler) Cancel(c echo.Context) error {
+	ctx := c.Request().Context()
+	set, err := h.sets.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "build set not found"})
+	}
+	children, err := h.sets.Children(ctx, set.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to load build set"})
+	}
+	var failed int
+	for _, child := range children {
+		if child.Phase != store.ArtifactPending && child.Phase != store.ArtifactBuilding {
+			continue
+		}
+		err := h.artifacts.builder.Cancel(ctx, child.ID)
+		if errors.Is(err, builder.ErrNotSupported) {
+			return c.JSON(http.StatusNotImplemented, map[string]string{"error": err.Error()})
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "build set %s: cancel child %s: %v\n", set.ID, child.ID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to cancel %d build(s)", failed)})
+	}
+	v, err := h.view(ctx, set)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to load build set"})
+	}
+	return c.JSON(http.StatusOK, v)
+}
+
+// Delete handles DELETE /api/v1/build-sets/:id.
+//
+//	@Summary		Delete a build set
+//	@Description	Removes the grouping only. The child artifacts stay and can be managed individually.
+//	@Tags			Artifacts
+//	@Security		AdminBearer
+//	@Param			id	path	string	true	"Build set ID"
+//	@Success		204
+//	@Failure		404	{object}	APIError
+//	@Router			/api/v1/build-sets/{id} [delete]
+func (h *BuildSetHandler) Delete(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+	if _, err := h.sets.GetByID(ctx, id); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "build set not found"})
+	}
+	if err := h.sets.Delete(ctx, id); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to delete build set"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *BuildSetHandler) view(ctx context.Context, set *store.BuildSet) (*APIBuildSet, error) {
+	children, err := h.sets.Children(ctx, set.ID)
+	if err != nil {
+		return nil, err
+	}
+	if children == nil {
+		children = []*store.ArtifactRecord{}
+	}
+	counts := countBuildSetChildren(children)
+	return &APIBuildSet{
+		ID:        set.ID,
+		Name:      set.Name,
+		Phase:     aggregateBuildSetPhase(counts),
+		Counts:    counts,
+		Children:  children,
+		CreatedAt: set.CreatedAt,
+	}, nil
+}
+
+func countBuildSetChildren(children []*store.ArtifactRecord) APIBuildSetCounts {
+	counts := APIBuildSetCounts{Total: len(children)}
+	for _, child := range children {
+		switch child.Phase {
+		case store.ArtifactPending:
+			counts.Pending++
+		case store.ArtifactBuilding:
+			counts.Building++
+		case store.ArtifactReady:
+			counts.Ready++
+		case store.ArtifactError:
+			counts.Error++
+		}
+	}
+	return counts
+}
+
+// aggregateBuildSetPhase folds the child counts into one phase. A set is
+// Pending only while nothing has started; once any child runs or finishes
+// while others are still queued, the set as a whole is Building.
+func aggregateBuildSetPhase(c APIBuildSetCounts) string {
+	switch {
+	case c.Pending+c.Building > 0:
+		if c.Pending == c.Total {
+			return store.ArtifactPending
+		}
+		return store.ArtifactBuilding
+	case c.Total > 0 && c.Ready == c.Total:
+		return store.ArtifactReady
+	case c.Error > 0:
+		return store.ArtifactError
+	default:
+		return store.ArtifactPending
+	}
+}
+
+// expandBuildMatrix returns one create request per combination of the
+// matrix axes, in axis order (base image, arch, variant, Kubernetes,
+// outputs). Each child is named after the set plus the values of every
+// axis that actually varies, so siblings are distinguishable in the
+// artifact list.
+func expandBuildMatrix(req createBuildSetRequest) ([]createArtifactRequest, error) {
+	m := req.Matrix
+	total := max(len(m.BaseImages), 1) * max(len(m.Arches), 1) * max(len(m.Variants), 1) *
+		max(len(m.Kubernetes), 1) * max(len(m.Outputs), 1)
+	if total > maxBuildSetChildren {
+		return nil, fmt.Errorf("matrix expands to %d builds, the limit is %d", total, maxBuildSetChildren)
+	}
+
+	base := req.Name
+	if base == "" {
+		base = req.Template.Name
+	}
+
+	children := []createArtifactRequest{req.Template}
+	labels := [][]string{nil}
+	cross := func(n int, apply func(r *createArtifactRequest, i int) string) {
+		if n == 0 {
+			return
+		}
+		var next []createArtifactRequest
+		var nextLabels [][]string
+		for ci, child := range children {
+			for i := 0; i < n; i++ {
+				r := child
+				label := apply(&r, i)
+				l := append(append([]string(nil), labels[ci]...), label)
+				if n == 1 {
+					// A one-value axis still overrides the template but
+					// does not distinguish siblings.
+					l = labels[ci]
+				}
+				next = append(next, r)
+				nextLabels = append(nextLabels, l)
+			}
+		}
+		children, labels = next, nextLabels
+	}
+
+	cross(len(m.BaseImages), func(r *createArtifactRequest, i int) string {
+		r.BaseImage = m.BaseImages[i]
+		return m.BaseImages[i]
+	})
+	cross(len(m.Arches), func(r *createArtifactRequest, i int) string {
+		r.Arch = m.Arches[i]
+		return m.Arches[i]
+	})
+	cross(len(m.Variants), func(r *createArtifactRequest, i int) string {
+		r.Variant = m.Variants[i]
+		return m.Variants[i]
+	})
+	cross(len(m.Kubernetes), func(r *createArtifactRequest, i int) string {
+		r.KubernetesDistro = m.Kubernetes[i].Distro
+		r.KubernetesVersion = m.Kubernetes[i].Version
+		return strings.TrimSuffix(m.Kubernetes[i].Distro+" "+m.Kubernetes[i].Version, " ")
+	})
+	cross(len(m.Outputs), func(r *createArtifactRequest, i int) string {
+		r.Outputs = m.Outputs[i]
+		return outputsLabel(m.Outputs[i])
+	})
+
+	for i := range children {
+		name := base
+		if len(labels[i]) > 0 {
+			name = strings.TrimSpace(base + " [" + strings.Join(labels[i], ", ") + "]")
+		}
+		children[i].Name = name
+	}
+	return children, nil
+}
+
+// outputsLabel names the formats an output selection produces, e.g. "iso+raw".
+func outputsLabel(o artifactOutputs) string {
+	var parts []string
+	for _, f := range []struct {
+		on   bool
+		name string
+	}{
+		{o.ISO, "iso"}, {o.CloudImage, "cloud"}, {o.Netboot, "netboot"}, {o.RawDisk, "raw"},
+		{o.Tar, "tar"}, {o.GCE, "gce"}, {o.VHD, "vhd"}, {o.VHDX, "vhdx"}, {o.MAAS, "maas"},
+		{o.QCOW2, "qcow2"}, {o.VMDK, "vmdk"}, {o.OVA, "ova"}, {o.UKI, "uki"},
+		{o.SBOM, "sbom"},
+	} {
+		if f.on {
+			parts = append(parts, f.name)
+		}
+	}
+	if len(parts) == 0 {
+		return "no outputs"
+	}
+	return strings.Join(parts, "+")
+}
@@ -0,0 +1,152 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("BuildSetHandler", func() {
+	var (
+		e         *echo.Echo
+		fb        *fakeBuilder
+		artifacts *fakeArtifactStore
+		sets      *fakeBuildSetStore
+		handler   *handlers.BuildSetHandler
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		fb = &fakeBuilder{}
+		artifacts = &fakeArtifactStore{}
+		sets = newFakeBuildSetStore(artifacts)
+		ah := handlers.NewArtifactHandler(fb, artifacts, nil, nil, "", "reg-token", "http://localhost:8080")
+		handler = handlers.NewBuildSetHandler(ah, sets)
+	})
+
+	call := func(fn func(echo.Context) error, method, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/build-sets", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if id != "" {
+			c.SetParamNames("id")
+			c.SetParamValues(id)
+		}
+		Expect(fn(c)).To(Succeed())
+		return rec
+	}
+
+	decode := func(rec *httptest.ResponseRecorder) handlers.APIBuildSet {
+		var out handlers.APIBuildSet
+		Expect(json.Unmarshal(rec.Body.Bytes(), &out)).To(Succeed())
+		return out
+	}
+
+	const matrix = `{
+		"name": "release",
+		"template": {"baseImage": "quay.io/kairos/ubuntu:24.04", "variant": "core", "outputs": {"iso": true}},
+		"matrix": {"arches": ["amd64", "arm64"], "outputs": [{"iso": true}, {"rawDisk": true}]}
+	}`
+
+	It("expands the matrix into linked child builds", func() {
+		rec := call(handler.Create, http.MethodPost, "", matrix)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+
+		set := decode(rec)
+		Expect(set.Name).To(Equal("release"))
+		Expect(set.Phase).To(Equal(store.ArtifactPending))
+		Expect(set.Counts.Total).To(Equal(4))
+		Expect(set.Children).To(HaveLen(4))
+		Expect(fb.builds).To(HaveLen(4))
+
+		var names []string
+		for _, child := range set.Children {
+			names = append(names, child.Name)
+			Expect(child.BaseImage).To(Equal("quay.io/kairos/ubuntu:24.04"))
+			Expect(child.Variant).To(Equal("core"))
+		}
+		Expect(names).To(Equal([]string{
+			"release [amd64, iso]",
+			"release [amd64, raw]",
+			"release [arm64, iso]",
+			"release [arm64, raw]",
+		}))
+		Expect(set.Children[1].Arch).To(Equal("amd64"))
+		Expect(set.Children[1].RawDisk).To(BeTrue())
+		Expect(set.Children[1].ISO).To(BeFalse())
+	})
+
+	It("aggregates the children's phases", func() {
+		set := decode(call(handler.Create, http.MethodPost, "", matrix))
+		ctx := context.Background()
+		phase := func() string {
+			return decode(call(handler.Get, http.MethodGet, set.ID, "")).Phase
+		}
+
+		Expect(artifacts.UpdatePhaseMessage(ctx, set.Children[0].ID, store.ArtifactReady, "")).To(Succeed())
+		Expect(phase()).To(Equal(store.ArtifactBuilding))
+
+		for _, child := range set.Children {
+			Expect(artifacts.UpdatePhaseMessage(ctx, child.ID, store.ArtifactReady, "")).To(Succeed())
+		}
+		Expect(phase()).To(Equal(store.ArtifactReady))
+
+		Expect(artifacts.UpdatePhaseMessage(ctx, set.Children[2].ID, store.ArtifactError, "boom")).To(Succeed())
+		got := decode(call(handler.Get, http.MethodGet, set.ID, ""))
+		Expect(got.Phase).To(Equal(store.ArtifactError))
+		Expect(got.Counts).To(Equal(handlers.APIBuildSetCounts{Total: 4, Ready: 3, Error: 1}))
+	})
+
+	It("cancels only the unfinished children", func() {
+		set := decode(call(handler.Create, http.MethodPost, "", matrix))
+		Expect(artifacts.UpdatePhaseMessage(context.Background(), set.Children[0].ID, store.ArtifactReady, "")).To(Succeed())
+
+		rec := call(handler.Cancel, http.MethodPost, set.ID, "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(fb.cancelledIDs).To(ConsistOf(set.Children[1].ID, set.Children[2].ID, set.Children[3].ID))
+	})
+
+	It("clones the whole matrix into a new set", func() {
+		set := decode(call(handler.Create, http.MethodPost, "", matrix))
+
+		rec := call(handler.Clone, http.MethodPost, set.ID, "")
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		clone := decode(rec)
+		Expect(clone.ID).NotTo(Equal(set.ID))
+		Expect(clone.Counts.Total).To(Equal(4))
+		Expect(clone.Children[3].Name).To(Equal("release [arm64, raw]"))
+		Expect(fb.builds).To(HaveLen(8))
+	})
+
+	It("rejects a matrix that expands past the limit", func() {
+		var arches []string
+		for i := 0; i < 65; i++ {
+			arches = append(arches, fmt.Sprintf(`"a%d"`, i))
+		}
+		body := `{"matrix":{"arches":[` + strings.Join(arches, ",") + `]}}`
+		rec := call(handler.Create, http.MethodPost, "", body)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(fb.builds).To(BeEmpty())
+	})
+
+	It("discards the set and names the combination when a child fails to start", func() {
+		fb.buildErr = fmt.Errorf("%w: invalid arch", builder.ErrInvalidBuildOptions)
+
+		rec := call(handler.Create, http.MethodPost, "", matrix)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("release [amd64, iso]"))
+		Expect(sets.sets).To(BeEmpty())
+	})
+})
@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+// channelSourcePrefix marks an upgrade source that names a channel. It is
+// rewritten to artifactSourcePrefix plus the channel's current artifact
+// when the command is delivered, so a command queued before a promotion
+// installs what the channel points at by the time the node picks it up.
+const (
+	channelSourcePrefix  = "channel:"
+	artifactSourcePrefix = "artifact:"
+)
+
+// channelNameRe allows lowercase path-like names such as "ubuntu-k3s/stable".
+var channelNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*(/[a-z0-9][a-z0-9._-]*)*$`)
+
+// ChannelHandler serves artifact channels: named pointers such as
+// "ubuntu-k3s/stable" that are promoted and rolled back, and that upgrades
+// and groups can follow instead of a fixed image.
+type ChannelHandler struct {
+	channels  store.ChannelStore
+	artifacts store.ArtifactStore
+	groups    store.GroupStore
+	nodes     store.NodeStore
+	commands  *CommandHandler
+}
+
+// NewChannelHandler creates a new ChannelHandler. Upgrades for groups
+// following a channel are queued through commands, so they are pushed to
+// online nodes the same way as any other command.
+func NewChannelHandler(channels store.ChannelStore, artifacts store.ArtifactStore, groups store.GroupStore, nodes store.NodeStore, commands *CommandHandler) *ChannelHandler {
+	return &ChannelHandler{
+		channels:  channels,
+		artifacts: artifacts,
+		groups:    groups,
+		nodes:     nodes,
+		commands:  commands,
+	}
+}
+
+// createChannelRequest is the expected body for creating a channel.
+type createChannelRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// moveChannelRequest is the body of the promote and rollback endpoints.
+type moveChannelRequest struct {
+	ArtifactID string `json:"artifactId"`
+	// FromChannel promotes whatever that channel currently points at
+	// (promote only).
+	FromChannel string `json:"fromChannel"`
+	Note        string `json:"note"`
+}
+
+// channelMoveResponse is returned by promote and rollback.
+type channelMoveResponse struct {
+	Channel *store.Channel      `json:"channel"`
+	Entry   *store.ChannelEntry `json:"entry"`
+	// Queued is the number of upgrades queued for nodes in groups that
+	// follow the channel.
+	Queued int `json:"queued"`
+}
+
+// Create handles POST /api/v1/channels.
+//
+//	@Summary		Create a channel
+//	@Description	Creates an empty channel. Names are lowercase and may contain slashes, e.g. "ubuntu-k3s/stable".
+//	@Tags			Channels
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			body	body		APICreateChannelRequest	true	"Channel"
+//	@Success		201		{object}	store.Channel
+//	@Failure		400		{object}	APIError
+//	@Failure		409		{object}	APIError
+//	@Router			/api/v1/channels [post]
+func (h *ChannelHandler) Create(c echo.Context) error {
+	var req createChannelRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if len(req.Name) > 128 || !channelNameRe.MatchString(req.Name) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "name must be lowercase letters, digits, '.', '_', '-' and '/' separators"})
+	}
+	ctx := c.Request().Context()
+	if _, err := h.channels.GetByName(ctx, req.Name); err == nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "a channel with this name already exists"})
+	}
+	ch := &store.Channel{Name: req.Name, Description: req.Description}
+	if err := h.channels.Create(ctx, ch); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create channel"})
+	}
+	return c.JSON(http.StatusCreated, ch)
+}
+
+// List handles GET /api/v1/channels.
+//
+//	@Summary	List channels
+//	@Tags		Channels
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Success	200	{array}	store.Channel
+//	@Router		/api/v1/channels [get]
+func (h *ChannelHandler) List(c echo.Context) error {
+	chans, err := h.channels.List(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to list channels"})
+	}
+	if chans == nil {
+		chans = []*store.Channel{}
+	}
+	return c.JSON(http.StatusOK, chans)
+}
+
+// History handles GET /api/v1/channels/:id/history.
+//
+//	@Summary	List a channel's promotions and rollbacks
+//	@Tags		Channels
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Param		id	path	string	true	"Channel ID"
+//	@Success	200	{array}	store.ChannelEntry
+//	@Failure	404	{object}	APIError
+//	@Router		/api/v1/channels/{id}/history [get]
+func (h *ChannelHandler) History(c echo.Context) error {
+	ctx := c.Request().Context()
+	ch, err := h.channels.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "channel not found"})
+	}
+	entries, err := h.channels.History(ctx, ch.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to load channel history"})
+	}
+	if entries == nil {
+		entries = []*store.ChannelEntry{}
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+// Delete handles DELETE /api/v1/channels/:id. Groups following the channel
+// stop following it; queued channel: upgrades fail at delivery.
+//
+//	@Summary	Delete a channel
+//	@Tags		Channels
+//	@Security	AdminBearer
+//	@Param		id	path	string	true	"Channel ID"
+//	@Success	204
+//	@Router		/api/v1/channels/{id} [delete]
+func (h *ChannelHandler) Delete(c echo.Context) error {
+	if err := h.channels.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to delete channel"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Promote handles POST /api/v1/channels/:id/promote.
+//
+//	@Summary		Promote an artifact into a channel
+//	@Description	Points the channel at artifactId, or at whatever fromChannel currently points at (dev → staging → prod). The artifact must be Ready and have a container image. Every node in a group following the channel gets an upgrade queued.
+//	@Tags			Channels
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			id		path		string					true	"Channel ID"
+//	@Param			body	body		APIMoveChannelRequest	true	"Artifact or source channel"
+//	@Success		200		{object}	APIChannelMove
+//	@Failure		400		{object}	APIError
+//	@Failure		404		{object}	APIError
+//	@Failure		409		{object}	APIError
+//	@Router			/api/v1/channels/{id}/promote [post]
+func (h *ChannelHandler) Promote(c echo.Context) error {
+	ctx := c.Request().Context()
+	ch, err := h.channels.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "channel not found"})
+	}
+	var req moveChannelRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if (req.ArtifactID == "") == (req.FromChannel == "") {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "set exactly one of artifactId and fromChannel"})
+	}
+	entry := &store.ChannelEntry{Action: store.ChannelPromote, ArtifactID: req.ArtifactID, Note: req.Note}
+	if req.FromChannel != "" {
+		from, err := h.channels.GetByName(ctx, req.FromChannel)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown channel %q", req.FromChannel)})
+		}
+		if from.ArtifactID == "" {
+			return c.JSON(http.StatusConflict, map[string]string{"error": fmt.Sprintf("channel %q has nothing promoted yet", from.Name)})
+		}
+		entry.ArtifactID = from.ArtifactID
+		entry.FromChannel = from.Name
+	}
+	return h.move(c, ch, entry)
+}
+
+// Rollback handles POST /api/v1/channels/:id/rollback.
+//
+//	@Summary		Roll a channel back
+//	@Description	Points the channel back at the artifact it held before the current one was promoted, or at artifactId if given, which must appear in the channel's history. Rolling back repeatedly walks further back through the promotions. Groups following the channel get an upgrade queued.
+//	@Tags			Channels
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			id		path		string					true	"Channel ID"
+//	@Param			body	body		APIMoveChannelRequest	false	"Optional target artifact"
+//	@Success		200		{object}	APIChannelMove
+//	@Failure		400		{object}	APIError
+//	@Failure		404		{object}	APIError
+//	@Failure		409		{object}	APIError
+//	@Router			/api/v1/channels/{id}/rollback [post]
+func (h *ChannelHandler) Rollback(c echo.Context) error {
+	ctx := c.Request().Context()
+	ch, err := h.channels.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "channel not found"})
+	}
+	var req moveChannelRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if req.FromChannel != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "fromChannel only applies to promote"})
+	}
+	history, err := h.channels.History(ctx, ch.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to load channel history"})
+	}
+	target := req.ArtifactID
+	if target == "" {
+		target = rollbackTarget(history, ch.ArtifactID)
+		if target == "" {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "nothing to roll back to"})
+		}
+	} else if !inHistory(history, target) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "artifact was never in this channel"})
+	}
+	return h.move(c, ch, &store.ChannelEntry{Action: store.ChannelRollback, ArtifactID: target, Note: req.Note})
+}
+
+// rollbackTarget is the artifact current held before it was last promoted.
+// Rollback entries are skipped, so after rolling back from C to B the next
+// rollback finds B's own promotion and goes on to A rather than back to C.
+func rollbackTarget(history []*store.ChannelEntry, current string) string {
+	for _, e := range history {
+		if e.Action == store.ChannelPromote && e.ArtifactID == current {
+			return e.PreviousArtifactID
+		}
+	}
+	return ""
+}
+
+func inHistory(history []*store.ChannelEntry, artifactID string) bool {
+	for _, e := range history {
+		if e.ArtifactID == artifactID {
+			return true
+		}
+	}
+	return false
+}
+
+// move checks the target artifact, moves the channel and queues upgrades for
+// the groups following it.
+func (h *ChannelHandler) move(c echo.Context, ch *store.Channel, entry *store.ChannelEntry) error {
+	ctx := c.Request().Context()
+	if entry.ArtifactID == ch.ArtifactID {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "channel already points at this artifact"})
+	}
+	rec, err := h.artifacts.GetByID(ctx, entry.ArtifactID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "artifact not found"})
+	}
+	if rec.Phase != store.ArtifactReady {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "artifact is not ready"})
+	}
+	if rec.ContainerImage == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "artifact has no container image to upgrade to"})
+	}
+	if err := h.channels.Move(ctx, ch.ID, ch.ArtifactID, entry); err != nil {
+		if errors.Is(err, store.ErrChannelMoved) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "channel was moved by another request; reload and retry"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to move channel"})
+	}
+	ch.ArtifactID = entry.ArtifactID
+	return c.JSON(http.StatusOK, channelMoveResponse{Channel: ch, Entry: entry, Queued: h.notifyFollowers(ctx, ch)})
+}
+
+// notifyFollowers queues a channel: upgrade on every node of every group
+// following ch, and returns how many were queued. A node that already has
+// one pending is skipped: it resolves at delivery, so it will pick up this
+// move anyway. Failures are logged; the move itself has succeeded.
+func (h *ChannelHandler) notifyFollowers(ctx context.Context, ch *store.Channel) int {
+	groups, err := h.groups.List(ctx)
+	if err != nil {
+		log.Printf("channels: listing groups following %s: %v", ch.Name, err)
+		return 0
+	}
+	source := channelSourcePrefix + ch.Name
+	queued := 0
+	for _, g := range groups {
+		if g.FollowChannelID != ch.ID {
+			continue
+		}
+		nodes, err := h.nodes.ListByGroup(ctx, g.ID)
+		if err != nil {
+			log.Printf("channels: listing nodes of group %s: %v", g.Name, err)
+			continue
+		}
+		for _, n := range nodes {
+			if h.hasPendingSource(ctx, n.ID, source) {
+				continue
+			}
+			if _, err := h.commands.queue(ctx, n.ID, store.CmdUpgrade, map[string]string{"source": source}); err != nil {
+				log.Printf("channels: queueing upgrade for node %s: %v", n.ID, err)
+				continue
+			}
+			queued++
+		}
+	}
+	return queued
+}
+
+func (h *ChannelHandler) hasPendingSource(ctx context.Context, nodeID, source string) bool {
+	pending, err := h.commands.commands.GetPending(ctx, nodeID)
+	if err != nil {
+		return false
+	}
+	for _, cmd := range pending {
+		if cmd.Command == store.CmdUpgrade && cmd.Args["source"] == source {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSource rejects a channel: upgrade source naming a channel that does
+// not exist, so the mistake surfaces when the command is queued rather than
+// when a node picks it up.
+func (h *ChannelHandler) checkSource(ctx context.Context, command string, args map[string]string) error {
+	name, ok := channelSource(command, args)
+	if !ok {
+		return nil
+	}
+	if _, err := h.channels.GetByName(ctx, name); err != nil {
+		return fmt.Errorf("unknown channel %q", name)
+	}
+	return nil
+}
+
+// ResolveCommand rewrites a channel: upgrade source to the artifact the
+// channel points at now. Every delivery path (agent poll, WebSocket push and
+// the pending-command replay on connect) calls it right after claiming a
+// command, and only delivers the command when it returns true. An
+// unresolvable channel fails the command instead.
+//
+// The stored command keeps the channel name under the "channel" argument so
+// its history shows both what was asked for and what was delivered; the
+// agent only receives the rewritten source.
+func (h *ChannelHandler) ResolveCommand(ctx context.Context, cmd *store.NodeCommand) bool {
+	name, ok := channelSource(cmd.Command, cmd.Args)
+	if !ok {
+		return true
+	}
+	ch, err := h.channels.GetByName(ctx, name)
+	if err != nil {
+		_ = h.commands.commands.UpdateStatus(ctx, cmd.ID, store.CommandFailed, fmt.Sprintf("channel %q does not exist", name))
+		return false
+	}
+	if ch.ArtifactID == "" {
+		_ = h.commands.commands.UpdateStatus(ctx, cmd.ID, store.CommandFailed, fmt.Sprintf("channel %q has nothing promoted", name))
+		return false
+	}
+	args := maps.Clone(cmd.Args)
+	args["source"] = artifactSourcePrefix + ch.ArtifactID
+	recorded := maps.Clone(args)
+	recorded["channel"] = name
+	if err := h.commands.commands.UpdateArgs(ctx, cmd.ID, recorded); err != nil {
+		log.Printf("channels: recording resolved source for command %s: %v", cmd.ID, err)
+	}
+	cmd.Args = args
+	return true
+}
+
+func channelSource(command string, args map[string]string) (string, bool) {
+	if command != store.CmdUpgrade && command != store.CmdUpgradeRecovery {
+		return "", false
+	}
+	src := args["source"]
+	if !strings.HasPrefix(src, channelSourcePrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(src, channelSourcePrefix), true
+}
@@ -0,0 +1,261 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/auth"
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("ChannelHandler", func() {
+	var (
+		e       *echo.Echo
+		chs     *fakeChannelStore
+		as      *fakeArtifactStore
+		gs      *fakeGroupStore
+		ns      *fakeNodeStore
+		cs      *fakeCommandStore
+		cmds    *handlers.CommandHandler
+		handler *handlers.ChannelHandler
+		stable  *store.Channel
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		chs = &fakeChannelStore{}
+		as = &fakeArtifactStore{records: []*store.ArtifactRecord{
+			{ID: "a1", Phase: store.ArtifactReady, ContainerImage: "registry.lan/os:v1"},
+			{ID: "a2", Phase: store.ArtifactReady, ContainerImage: "registry.lan/os:v2"},
+			{ID: "a3", Phase: store.ArtifactReady, ContainerImage: "registry.lan/os:v3"},
+			{ID: "building", Phase: store.ArtifactBuilding, ContainerImage: "registry.lan/os:v4"},
+			{ID: "iso-only", Phase: store.ArtifactReady},
+		}}
+		gs = &fakeGroupStore{}
+		ns = &fakeNodeStore{}
+		cs = &fakeCommandStore{}
+		cmds = handlers.NewCommandHandler(cs, ns, nil)
+		handler = handlers.NewChannelHandler(chs, as, gs, ns, cmds)
+		cmds.WithChannels(handler)
+		stable = &store.Channel{Name: "ubuntu-k3s/stable"}
+		Expect(chs.Create(context.Background(), stable)).To(Succeed())
+	})
+
+	post := func(h echo.HandlerFunc, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		Expect(h(c)).To(Succeed())
+		return rec
+	}
+
+	current := func() string {
+		ch, err := chs.GetByID(context.Background(), stable.ID)
+		Expect(err).NotTo(HaveOccurred())
+		return ch.ArtifactID
+	}
+
+	Describe("Create", func() {
+		It("accepts path-like names and rejects duplicates and bad names", func() {
+			Expect(post(handler.Create, "", `{"name":"ubuntu-k3s/staging"}`).Code).To(Equal(http.StatusCreated))
+			Expect(post(handler.Create, "", `{"name":"ubuntu-k3s/staging"}`).Code).To(Equal(http.StatusConflict))
+			Expect(post(handler.Create, "", `{"name":"Stable"}`).Code).To(Equal(http.StatusBadRequest))
+			Expect(post(handler.Create, "", `{"name":"a//b"}`).Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("Promote", func() {
+		It("moves the channel and records the previous artifact", func() {
+			Expect(post(handler.Promote, stable.ID, `{"artifactId":"a1"}`).Code).To(Equal(http.StatusOK))
+			rec := post(handler.Promote, stable.ID, `{"artifactId":"a2","note":"cve fix"}`)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+
+			var resp handlers.APIChannelMove
+			Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+			Expect(resp.Channel.ArtifactID).To(Equal("a2"))
+			Expect(resp.Entry.PreviousArtifactID).To(Equal("a1"))
+			Expect(resp.Entry.Note).To(Equal("cve fix"))
+		})
+
+		It("promotes what another channel points at", func() {
+			staging := &store.Channel{Name: "ubuntu-k3s/staging"}
+			Expect(chs.Create(context.Background(), staging)).To(Succeed())
+			Expect(post(handler.Promote, staging.ID, `{"artifactId":"a3"}`).Code).To(Equal(http.StatusOK))
+
+			rec := post(handler.Promote, stable.ID, `{"fromChannel":"ubuntu-k3s/staging"}`)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(current()).To(Equal("a3"))
+			Expect(chs.entries[1].FromChannel).To(Equal("ubuntu-k3s/staging"))
+		})
+
+		It("rejects artifacts that are not ready or have no image", func() {
+			Expect(post(handler.Promote, stable.ID, `{"artifactId":"building"}`).Code).To(Equal(http.StatusConflict))
+			Expect(post(handler.Promote, stable.ID, `{"artifactId":"iso-only"}`).Code).To(Equal(http.StatusBadRequest))
+			Expect(post(handler.Promote, stable.ID, `{"artifactId":"missing"}`).Code).To(Equal(http.StatusBadRequest))
+			Expect(post(handler.Promote, stable.ID, `{}`).Code).To(Equal(http.StatusBadRequest))
+			Expect(current()).To(BeEmpty())
+		})
+
+		It("queues an upgrade on nodes of groups following the channel", func() {
+			gs.groups = []*store.NodeGroup{
+				{ID: "grp-edge", FollowChannelID: stable.ID},
+				{ID: "grp-lab"},
+			}
+			ns.nodes = []*store.ManagedNode{
+				{ID: "node-1", GroupID: "grp-edge"},
+				{ID: "node-2", GroupID: "grp-edge"},
+				{ID: "node-3", GroupID: "grp-lab"},
+			}
+
+			rec := post(handler.Promote, stable.ID, `{"artifactId":"a1"}`)
+			var resp handlers.APIChannelMove
+			Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+			Expect(resp.Queued).To(Equal(2))
+			Expect(cs.cmds).To(HaveLen(2))
+			for _, cmd := range cs.cmds {
+				Expect(cmd.ManagedNodeID).NotTo(Equal("node-3"))
+				Expect(cmd.Command).To(Equal(store.CmdUpgrade))
+				Expect(cmd.Args).To(HaveKeyWithValue("source", "channel:ubuntu-k3s/stable"))
+			}
+
+			// Still pending: the queued commands resolve at delivery, so a
+			// second promotion does not queue another round.
+			Expect(post(handler.Promote, stable.ID, `{"artifactId":"a2"}`).Code).To(Equal(http.StatusOK))
+			Expect(cs.cmds).To(HaveLen(2))
+		})
+	})
+
+	Describe("Rollback", func() {
+		BeforeEach(func() {
+			for _, id := range []string{"a1", "a2", "a3"} {
+				Expect(post(handler.Promote, stable.ID, `{"artifactId":"`+id+`"}`).Code).To(Equal(http.StatusOK))
+			}
+		})
+
+		It("walks back through the promotions", func() {
+			Expect(post(handler.Rollback, stable.ID, `{}`).Code).To(Equal(http.StatusOK))
+			Expect(current()).To(Equal("a2"))
+			Expect(post(handler.Rollback, stable.ID, `{}`).Code).To(Equal(http.StatusOK))
+			Expect(current()).To(Equal("a1"))
+			Expect(post(handler.Rollback, stable.ID, `{}`).Code).To(Equal(http.StatusConflict))
+			Expect(current()).To(Equal("a1"))
+
+			history := chs.entries
+			Expect(history[len(history)-1].Action).To(Equal(store.ChannelRollback))
+		})
+
+		It("rolls back to an artifact from the channel's history only", func() {
+			Expect(post(handler.Rollback, stable.ID, `{"artifactId":"a1"}`).Code).To(Equal(http.StatusOK))
+			Expect(current()).To(Equal("a1"))
+			Expect(post(handler.Rollback, stable.ID, `{"artifactId":"iso-only"}`).Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("delivery", func() {
+		BeforeEach(func() {
+			Expect(post(handler.Promote, stable.ID, `{"artifactId":"a1"}`).Code).To(Equal(http.StatusOK))
+		})
+
+		queue := func(source string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"command":"upgrade","args":{"source":"`+source+`"}}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("nodeID")
+			c.SetParamValues("node-1")
+			Expect(cmds.Create(c)).To(Succeed())
+			return rec
+		}
+
+		poll := func() []*store.NodeCommand {
+			nh := handlers.NewNodeHandler(ns, cs, gs, nil, "reg-token", "http://localhost:8080").
+				WithCommandResolver(handler.ResolveCommand)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("nodeID")
+			c.SetParamValues("node-1")
+			c.Set(auth.ContextKeyNodeID, "node-1")
+			Expect(nh.GetCommands(c)).To(Succeed())
+			var out []*store.NodeCommand
+			Expect(json.Unmarshal(rec.Body.Bytes(), &out)).To(Succeed())
+			return out
+		}
+
+		It("rejects an unknown channel when queued", func() {
+			Expect(queue("channel:nope").Code).To(Equal(http.StatusBadRequest))
+			Expect(cs.cmds).To(BeEmpty())
+		})
+
+		It("delivers the artifact the channel points at when the node polls", func() {
+			ns.nodes = []*store.ManagedNode{{ID: "node-1"}}
+			Expect(queue("channel:ubuntu-k3s/stable").Code).To(Equal(http.StatusCreated))
+			Expect(post(handler.Promote, stable.ID, `{"artifactId":"a2"}`).Code).To(Equal(http.StatusOK))
+
+			delivered := poll()
+			Expect(delivered).To(HaveLen(1))
+			Expect(delivered[0].Args).To(Equal(map[string]string{"source": "artifact:a2"}))
+			Expect(cs.cmds[0].Args).To(HaveKeyWithValue("channel", "ubuntu-k3s/stable"))
+			Expect(cs.cmds[0].Args).To(HaveKeyWithValue("source", "artifact:a2"))
+		})
+
+		It("fails the command when the channel is gone by delivery", func() {
+			ns.nodes = []*store.ManagedNode{{ID: "node-1"}}
+			Expect(queue("channel:ubuntu-k3s/stable").Code).To(Equal(http.StatusCreated))
+			Expect(chs.Delete(context.Background(), stable.ID)).To(Succeed())
+
+			Expect(poll()).To(BeEmpty())
+			Expect(cs.cmds[0].Phase).To(Equal(store.CommandFailed))
+			Expect(cs.cmds[0].Result).To(ContainSubstring("does not exist"))
+		})
+
+		It("leaves other sources alone", func() {
+			ns.nodes = []*store.ManagedNode{{ID: "node-1"}}
+			Expect(queue("oci:quay.io/kairos/ubuntu:24.04").Code).To(Equal(http.StatusCreated))
+			delivered := poll()
+			Expect(delivered).To(HaveLen(1))
+			Expect(delivered[0].Args).To(Equal(map[string]string{"source": "oci:quay.io/kairos/ubuntu:24.04"}))
+		})
+	})
+})
+
+var _ = Describe("GroupHandler channel following", func() {
+	It("validates the followed channel and clears it with an empty string", func() {
+		e := echo.New()
+		chs := &fakeChannelStore{}
+		ch := &store.Channel{Name: "stable"}
+		Expect(chs.Create(context.Background(), ch)).To(Succeed())
+		gs := &fakeGroupStore{groups: []*store.NodeGroup{{ID: "grp-1", Name: "edge"}}}
+		h := handlers.NewGroupHandler(gs).WithChannels(chs)
+
+		update := func(body string) int {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/groups/grp-1", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("grp-1")
+			Expect(h.Update(c)).To(Succeed())
+			return rec.Code
+		}
+
+		Expect(update(`{"followChannelId":"missing"}`)).To(Equal(http.StatusBadRequest))
+		Expect(update(`{"followChannelId":"` + ch.ID + `"}`)).To(Equal(http.StatusOK))
+		Expect(gs.groups[0].FollowChannelID).To(Equal(ch.ID))
+		Expect(update(`{"name":"edge-2"}`)).To(Equal(http.StatusOK))
+		Expect(gs.groups[0].FollowChannelID).To(Equal(ch.ID))
+		Expect(update(`{"followChannelId":""}`)).To(Equal(http.StatusOK))
+		Expect(gs.groups[0].FollowChannelID).To(BeEmpty())
+	})
+})
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/kairos-io/AuroraBoot/pkg/cloudconfig"
+	"github.com/labstack/echo/v4"
+)
+
+// CloudConfigHandler serves /api/v1/cloud-config/validate, which lets the
+// UI lint a cloud-config as it is typed with the same checks a build runs.
+type CloudConfigHandler struct{}
+
+// NewCloudConfigHandler creates a CloudConfigHandler.
+func NewCloudConfigHandler() *CloudConfigHandler {
+	return &CloudConfigHandler{}
+}
+
+// Validate handles POST /api/v1/cloud-config/validate.
+//
+//	@Summary		Lint a cloud-config
+//	@Description	Checks a cloud-config against the Kairos config schema and reports errors (YAML that does not parse, duplicate keys, values the schema rejects) and warnings (misspelt keys, unknown install or stage step options, a missing header), each with its line and column. A build is refused when its cloud-config has errors. Set `fragment` for the extra YAML of a build request, which is merged into the generated cloud-config and needs no header. The response is 200 whether or not the config is valid.
+//	@Tags			Artifacts
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			body	body		APIValidateCloudConfigRequest	true	"Cloud-config to lint"
+//	@Success		200		{object}	cloudconfig.Report
+//	@Failure		400		{object}	APIError
+//	@Router			/api/v1/cloud-config/validate [post]
+func (h *CloudConfigHandler) Validate(c echo.Context) error {
+	var req APIValidateCloudConfigRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if req.Fragment {
+		return c.JSON(http.StatusOK, cloudconfig.LintFragment(req.CloudConfig))
+	}
+	return c.JSON(http.StatusOK, cloudconfig.Lint(req.CloudConfig))
+}
@@ -0,0 +1,50 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/cloudconfig"
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("CloudConfigHandler", func() {
+	validate := func(body string) (*httptest.ResponseRecorder, cloudconfig.Report) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/cloud-config/validate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		Expect(handlers.NewCloudConfigHandler().Validate(echo.New().NewContext(req, rec))).To(Succeed())
+		var r cloudconfig.Report
+		if rec.Code == http.StatusOK {
+			Expect(json.Unmarshal(rec.Body.Bytes(), &r)).To(Succeed())
+		}
+		return rec, r
+	}
+
+	It("reports errors and warnings with their lines", func() {
+		rec, r := validate(`{"cloudConfig":"#cloud-config\ninstal:\n  auto: true\ninstall:\n  device: sda\n"}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(r.Valid).To(BeFalse())
+		Expect(r.Errors).To(HaveLen(1))
+		Expect(r.Errors[0].Line).To(Equal(5))
+		Expect(r.Warnings).To(HaveLen(1))
+		Expect(r.Warnings[0].Line).To(Equal(2))
+	})
+
+	It("does not ask a fragment for a header", func() {
+		_, r := validate(`{"cloudConfig":"install:\n  auto: true\n","fragment":true}`)
+		Expect(r.Valid).To(BeTrue())
+		Expect(r.Warnings).To(BeEmpty())
+	})
+
+	It("returns 400 for a malformed body", func() {
+		rec, _ := validate(`{`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+})
@@ -17,6 +17,7 @@ type CommandHandler struct {
 	commands store.CommandStore
 	nodes    store.NodeStore
 	hub      *ws.Hub
+	channels *ChannelHandler
 }
 
 // NewCommandHandler creates a new CommandHandler.
@@ -28,6 +29,43 @@ func NewCommandHandler(commands store.CommandStore, nodes store.NodeStore, hub *
 	}
 }
 
+// WithChannels enables channel: upgrade sources. They are checked against ch
+// when queued and resolved to the channel's current artifact when pushed.
+func (h *CommandHandler) WithChannels(ch *ChannelHandler) *CommandHandler {
+	h.channels = ch
+	return h
+}
+
+// checkSource validates a channel: upgrade source before it is queued.
+func (h *CommandHandler) checkSource(ctx context.Context, command string, args map[string]string) error {
+	if _, ok := channelSource(command, args); !ok {
+		return nil
+	}
+	if h.channels == nil {
+		return errors.New("channels are not enabled on this server")
+	}
+	return h.channels.checkSource(ctx, command, args)
+}
+
+// queue persists a Pending command for nodeID and pushes it if the node is
+// online. It is how commands raised by the server itself, such as upgrades
+// for groups following a channel, enter the same pipeline as the REST
+// endpoints.
+func (h *CommandHandler) queue(ctx context.Context, nodeID, command string, args map[string]string) (*store.NodeCommand, error) {
+	cmd := &store.NodeCommand{
+		ID:            uuid.New().String(),
+		ManagedNodeID: nodeID,
+		Command:       command,
+		Args:          args,
+		Phase:         store.CommandPending,
+	}
+	if err := h.commands.Create(ctx, cmd); err != nil {
+		return nil, err
+	}
+	h.pushCommand(ctx, cmd)
+	return cmd, nil
+}
+
 // createCommandRequest is the expected body for creating a command.
 type createCommandRequest struct {
 	Command string            `json:"command"`
@@ -55,6 +93,9 @@ func (h *CommandHandler) Create(c echo.Context) error {
 	if req.Command == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "command is required"})
 	}
+	if err := h.checkSource(c.Request().Context(), req.Command, req.Args); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
 	cmd := &store.NodeCommand{
 		ID:            uuid.New().String(),
@@ -100,6 +141,9 @@ func (h *CommandHandler) CreateBulk(c echo.Context) error {
 	if req.Command == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "command is required"})
 	}
+	if err := h.checkSource(c.Request().Context(), req.Command, req.Args); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
 	if req.Selector.GroupID == "" && len(req.Selector.NodeIDs) == 0 && len(req.Selector.Labels) == 0 {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "selector must specify at least one of: groupID, nodeIDs, or labels"})
@@ -147,6 +191,9 @@ func (h *CommandHandler) CreateForGroup(c echo.Context) error {
 	if req.Command == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "command is required"})
 	}
+	if err := h.checkSource(c.Request().Context(), req.Command, req.Args); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
 	ctx := c.Request().Context()
 	nodes, err := h.nodes.ListByGroup(ctx, groupID)
@@ -197,6 +244,9 @@ func (h *CommandHandler) pushCommand(ctx context.Context, cmd *store.NodeCommand
 		// claimed it and will deliver it; don't push a duplicate.
 		return
 	}
+	if h.channels != nil && !h.channels.ResolveCommand(ctx, cmd) {
+		return
+	}
 	payload := struct {
 		ID      string            `json:"id"`
 		Command string            `json:"command"`
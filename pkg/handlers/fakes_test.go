@@ -359,6 +359,18 @@ func (f *fakeCommandStore) ListByNode(_ context.Context, nodeID string) ([]*stor
 	return result, nil
 }
 
+func (f *fakeCommandStore) UpdateArgs(_ context.Context, id string, args map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, cmd := range f.cmds {
+		if cmd.ID == id {
+			cmd.Args = args
+			return nil
+		}
+	}
+	return store.ErrCommandNotFound
+}
+
 func (f *fakeCommandStore) Delete(_ context.Context, id string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -463,6 +475,19 @@ func (f *fakeArtifactStore) UpdateFiles(_ context.Context, id string, files []st
 	return fmt.Errorf("not found")
 }
 
+func (f *fakeArtifactStore) UpdateStep(_ context.Context, id, step string, progress int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.records {
+		if r.ID == id {
+			r.Step = step
+			r.StepProgress = progress
+			return nil
+		}
+	}
+	return fmt.Errorf("not found")
+}
+
 func (f *fakeArtifactStore) ClearUploadToken(_ context.Context, id string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -475,6 +500,18 @@ func (f *fakeArtifactStore) ClearUploadToken(_ context.Context, id string) error
 	return fmt.Errorf("not found")
 }
 
+func (f *fakeArtifactStore) SetUploadToken(_ context.Context, id, token string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.records {
+		if r.ID == id {
+			r.UploadToken = token
+			return nil
+		}
+	}
+	return fmt.Errorf("not found")
+}
+
 func (f *fakeArtifactStore) Delete(_ context.Context, id string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -745,3 +782,374 @@ func (f *fakeSecureBootKeySetStore) Delete(_ context.Context, id string) error {
 	}
 	return fmt.Errorf("not found")
 }
+
+// fakeBuildSetStore implements store.BuildSetStore for testing. Children are
+// resolved through artifacts so phase changes made on the artifact fake show
+// up in the aggregated set status.
+type fakeBuildSetStore struct {
+	mu        sync.Mutex
+	sets      []*store.BuildSet
+	links     map[string][]string
+	artifacts *fakeArtifactStore
+}
+
+func newFakeBuildSetStore(artifacts *fakeArtifactStore) *fakeBuildSetStore {
+	return &fakeBuildSetStore{links: map[string][]string{}, artifacts: artifacts}
+}
+
+func (f *fakeBuildSetStore) Create(_ context.Context, set *store.BuildSet) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	set.ID = fmt.Sprintf("set-%d", len(f.sets)+1)
+	set.CreatedAt = time.Now()
+	f.sets = append(f.sets, set)
+	return nil
+}
+
+func (f *fakeBuildSetStore) GetByID(_ context.Context, id string) (*store.BuildSet, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.sets {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func (f *fakeBuildSetStore) List(_ context.Context) ([]*store.BuildSet, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sets, nil
+}
+
+func (f *fakeBuildSetStore) Delete(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, s := range f.sets {
+		if s.ID == id {
+			f.sets = append(f.sets[:i], f.sets[i+1:]...)
+			delete(f.links, id)
+			return nil
+		}
+	}
+	return fmt.Errorf("not found")
+}
+
+func (f *fakeBuildSetStore) Attach(_ context.Context, setID, artifactID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.links[setID] = append(f.links[setID], artifactID)
+	return nil
+}
+
+func (f *fakeBuildSetStore) Children(ctx context.Context, setID string) ([]*store.ArtifactRecord, error) {
+	f.mu.Lock()
+	ids := append([]string(nil), f.links[setID]...)
+	f.mu.Unlock()
+	var out []*store.ArtifactRecord
+	for _, id := range ids {
+		rec, err := f.artifacts.GetByID(ctx, id)
+		if err != nil {
+			continue
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+// fakeRegistryStore implements store.RegistryStore for testing.
+type fakeRegistryStore struct {
+	mu         sync.Mutex
+	registries []*store.Registry
+}
+
+func (f *fakeRegistryStore) Create(_ context.Context, reg *store.Registry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reg.ID = fmt.Sprintf("reg-%d", len(f.registries)+1)
+	f.registries = append(f.registries, reg)
+	return nil
+}
+
+func (f *fakeRegistryStore) GetByID(_ context.Context, id string) (*store.Registry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.registries {
+		if r.ID == id {
+			cp := *r
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func (f *fakeRegistryStore) List(_ context.Context) ([]*store.Registry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*store.Registry(nil), f.registries...), nil
+}
+
+func (f *fakeRegistryStore) Update(_ context.Context, reg *store.Registry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, r := range f.registries {
+		if r.ID == reg.ID {
+			f.registries[i] = reg
+			return nil
+		}
+	}
+	return fmt.Errorf("not found")
+}
+
+func (f *fakeRegistryStore) Delete(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, r := range f.registries {
+		if r.ID == id {
+			f.registries = append(f.registries[:i], f.registries[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("not found")
+}
+
+// fakeChannelStore implements store.ChannelStore for testing.
+type fakeChannelStore struct {
+	mu       sync.Mutex
+	channels []*store.Channel
+	entries  []*store.ChannelEntry // oldest first
+}
+
+func (f *fakeChannelStore) Create(_ context.Context, ch *store.Channel) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch.ID = fmt.Sprintf("chan-%d", len(f.channels)+1)
+	f.channels = append(f.channels, ch)
+	return nil
+}
+
+func (f *fakeChannelStore) find(match func(*store.Channel) bool) (*store.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.channels {
+		if match(ch) {
+			cp := *ch
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func (f *fakeChannelStore) GetByID(_ context.Context, id string) (*store.Channel, error) {
+	return f.find(func(ch *store.Channel) bool { return ch.ID == id })
+}
+
+func (f *fakeChannelStore) GetByName(_ context.Context, name string) (*store.Channel, error) {
+	return f.find(func(ch *store.Channel) bool { return ch.Name == name })
+}
+
+func (f *fakeChannelStore) List(_ context.Context) ([]*store.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*store.Channel(nil), f.channels...), nil
+}
+
+func (f *fakeChannelStore) Move(_ context.Context, channelID, expect string, entry *store.ChannelEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.channels {
+		if ch.ID != channelID {
+			continue
+		}
+		if ch.ArtifactID != expect {
+			return store.ErrChannelMoved
+		}
+		ch.ArtifactID = entry.ArtifactID
+		entry.ID = fmt.Sprintf("entry-%d", len(f.entries)+1)
+		entry.ChannelID = channelID
+		entry.PreviousArtifactID = expect
+		f.entries = append(f.entries, entry)
+		return nil
+	}
+	return store.ErrChannelMoved
+}
+
+func (f *fakeChannelStore) History(_ context.Context, channelID string) ([]*store.ChannelEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*store.ChannelEntry
+	for i := len(f.entries) - 1; i >= 0; i-- {
+		if f.entries[i].ChannelID == channelID {
+			out = append(out, f.entries[i])
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeChannelStore) Delete(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, ch := range f.channels {
+		if ch.ID == id {
+			f.channels = append(f.channels[:i], f.channels[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("not found")
+}
+
+// fakeSecretStore implements store.SecretStore for testing.
+type fakeSecretStore struct {
+	mu      sync.Mutex
+	secrets map[string]*store.Secret
+}
+
+func newFakeSecretStore() *fakeSecretStore {
+	return &fakeSecretStore{secrets: map[string]*store.Secret{}}
+}
+
+func (f *fakeSecretStore) Set(_ context.Context, secret *store.Secret) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cp := *secret
+	f.secrets[secret.Name] = &cp
+	return nil
+}
+
+func (f *fakeSecretStore) Get(_ context.Context, name string) (*store.Secret, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.secrets[name]; ok {
+		cp := *s
+		return &cp, nil
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func (f *fakeSecretStore) List(_ context.Context) ([]*store.Secret, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*store.Secret
+	for _, s := range f.secrets {
+		cp := *s
+		cp.Value = ""
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (f *fakeSecretStore) Delete(_ context.Context, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.secrets, name)
+	return nil
+}
+
+// fakeArtifactSpecStore implements store.ArtifactSpecStore for testing.
+type fakeArtifactSpecStore struct {
+	mu    sync.Mutex
+	specs map[string]string
+}
+
+func newFakeArtifactSpecStore() *fakeArtifactSpecStore {
+	return &fakeArtifactSpecStore{specs: map[string]string{}}
+}
+
+func (f *fakeArtifactSpecStore) SetSpec(_ context.Context, id, spec string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.specs[id] = spec
+	return nil
+}
+
+func (f *fakeArtifactSpecStore) GetSpec(_ context.Context, id string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.specs[id], nil
+}
+
+// fakeBuildTemplateStore implements store.BuildTemplateStore for testing.
+type fakeBuildTemplateStore struct {
+	mu        sync.Mutex
+	templates []*store.BuildTemplate
+	links     map[string]string // artifact ID → template ID
+}
+
+func newFakeBuildTemplateStore() *fakeBuildTemplateStore {
+	return &fakeBuildTemplateStore{links: map[string]string{}}
+}
+
+func (f *fakeBuildTemplateStore) Create(_ context.Context, tpl *store.BuildTemplate) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tpl.ID = fmt.Sprintf("tpl-%d", len(f.templates)+1)
+	tpl.CreatedAt = time.Now()
+	cp := *tpl
+	f.templates = append(f.templates, &cp)
+	return nil
+}
+
+func (f *fakeBuildTemplateStore) find(match func(*store.BuildTemplate) bool) (*store.BuildTemplate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.templates {
+		if match(t) {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func (f *fakeBuildTemplateStore) GetByID(_ context.Context, id string) (*store.BuildTemplate, error) {
+	return f.find(func(t *store.BuildTemplate) bool { return t.ID == id })
+}
+
+func (f *fakeBuildTemplateStore) GetByName(_ context.Context, name string) (*store.BuildTemplate, error) {
+	return f.find(func(t *store.BuildTemplate) bool { return t.Name == name })
+}
+
+func (f *fakeBuildTemplateStore) List(_ context.Context) ([]*store.BuildTemplate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*store.BuildTemplate
+	for _, t := range f.templates {
+		cp := *t
+		cp.Spec = ""
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (f *fakeBuildTemplateStore) Update(_ context.Context, tpl *store.BuildTemplate) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, t := range f.templates {
+		if t.ID == tpl.ID {
+			cp := *tpl
+			f.templates[i] = &cp
+			return nil
+		}
+	}
+	return fmt.Errorf("not found")
+}
+
+func (f *fakeBuildTemplateStore) Delete(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, t := range f.templates {
+		if t.ID == id {
+			f.templates = append(f.templates[:i], f.templates[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeBuildTemplateStore) Attach(_ context.Context, templateID, artifactID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.links[artifactID] = templateID
+	return nil
+}
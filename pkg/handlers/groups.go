@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -10,7 +12,8 @@ import (
 
 // GroupHandler handles group-related REST endpoints.
 type GroupHandler struct {
-	groups store.GroupStore
+	groups   store.GroupStore
+	channels store.ChannelStore
 }
 
 // NewGroupHandler creates a new GroupHandler.
@@ -18,10 +21,32 @@ func NewGroupHandler(groups store.GroupStore) *GroupHandler {
 	return &GroupHandler{groups: groups}
 }
 
+// WithChannels lets groups follow a channel. Without it, setting
+// followChannelId is rejected.
+func (h *GroupHandler) WithChannels(channels store.ChannelStore) *GroupHandler {
+	h.channels = channels
+	return h
+}
+
+// checkFollow validates a followChannelId; an empty one stops following.
+func (h *GroupHandler) checkFollow(ctx context.Context, channelID string) error {
+	if channelID == "" {
+		return nil
+	}
+	if h.channels == nil {
+		return errors.New("channels are not enabled on this server")
+	}
+	if _, err := h.channels.GetByID(ctx, channelID); err != nil {
+		return errors.New("channel not found")
+	}
+	return nil
+}
+
 // createGroupRequest is the expected body for creating a group.
 type createGroupRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	FollowChannelID string `json:"followChannelId"`
 }
 
 // Create handles POST /api/v1/groups.
@@ -44,11 +69,15 @@ func (h *GroupHandler) Create(c echo.Context) error {
 	if req.Name == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "name is required"})
 	}
+	if err := h.checkFollow(c.Request().Context(), req.FollowChannelID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
 	group := &store.NodeGroup{
-		ID:          uuid.New().String(),
-		Name:        req.Name,
-		Description: req.Description,
+		ID:              uuid.New().String(),
+		Name:            req.Name,
+		Description:     req.Description,
+		FollowChannelID: req.FollowChannelID,
 	}
 
 	if err := h.groups.Create(c.Request().Context(), group); err != nil {
@@ -100,6 +129,8 @@ func (h *GroupHandler) Get(c echo.Context) error {
 type updateGroupRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// FollowChannelID is left unchanged when omitted; "" stops following.
+	FollowChannelID *string `json:"followChannelId"`
 }
 
 // Update handles PUT /api/v1/groups/:id.
@@ -134,6 +165,12 @@ func (h *GroupHandler) Update(c echo.Context) error {
 	if req.Description != "" {
 		group.Description = req.Description
 	}
+	if req.FollowChannelID != nil {
+		if err := h.checkFollow(ctx, *req.FollowChannelID); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		group.FollowChannelID = *req.FollowChannelID
+	}
 
 	if err := h.groups.Update(ctx, group); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update group"})
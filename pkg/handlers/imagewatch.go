@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kairos-io/AuroraBoot/pkg/imagewatch"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/kairos-io/AuroraBoot/pkg/ws"
+	"github.com/labstack/echo/v4"
+)
+
+// SettingImageWatchSchedule holds the cron schedule of the base image
+// watcher. Unset or empty disables it.
+const SettingImageWatchSchedule = "imagewatch.schedule"
+
+// imageWatchTick is how often Run looks at the schedule. Cron has minute
+// resolution, so checking more often gains nothing.
+const imageWatchTick = time.Minute
+
+// ImageWatchHandler rebuilds saved artifacts and template builds when the
+// tag of their base image moves to new content. On the configured schedule
+// it resolves each watched base image to a digest and, when that differs
+// from the digest the latest build used, starts the build again from its
+// stored request and tells UI clients over the WebSocket.
+type ImageWatchHandler struct {
+	settings  store.SettingsStore
+	artifacts *ArtifactHandler
+	templates store.BuildTemplateStore
+	hub       *ws.Hub
+	digest    imagewatch.DigestFunc
+	now       func() time.Time
+
+	// mu serialises checks so the scheduled run and an operator's "check
+	// now" never rebuild the same thing twice.
+	mu sync.Mutex
+	// triggered maps a watched lineage to the digest a rebuild was already
+	// started for, so a failing rebuild is not retried on every run.
+	triggered map[string]string
+	last      *APIImageWatchRun
+}
+
+// NewImageWatchHandler creates an ImageWatchHandler that reads its schedule
+// from settings and rebuilds through artifacts, which needs a spec store to
+// know what to rebuild.
+func NewImageWatchHandler(settings store.SettingsStore, artifacts *ArtifactHandler) *ImageWatchHandler {
+	return &ImageWatchHandler{
+		settings:  settings,
+		artifacts: artifacts,
+		digest:    imagewatch.Digest,
+		now:       time.Now,
+		triggered: map[string]string{},
+	}
+}
+
+// WithTemplates links rebuilds of template builds to their template.
+func (h *ImageWatchHandler) WithTemplates(templates store.BuildTemplateStore) *ImageWatchHandler {
+	h.templates = templates
+	return h
+}
+
+// WithHub broadcasts changed base images to UI clients.
+func (h *ImageWatchHandler) WithHub(hub *ws.Hub) *ImageWatchHandler {
+	h.hub = hub
+	return h
+}
+
+// WithDigestFunc replaces the registry lookup, for tests.
+func (h *ImageWatchHandler) WithDigestFunc(fn imagewatch.DigestFunc) *ImageWatchHandler {
+	h.digest = fn
+	return h
+}
+
+// Get handles GET /api/v1/image-watch.
+//
+//	@Summary	Read the base image watcher schedule and last run
+//	@Tags		Artifacts
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Success	200	{object}	APIImageWatchStatus
+//	@Router		/api/v1/image-watch [get]
+func (h *ImageWatchHandler) Get(c echo.Context) error {
+	expr, err := h.schedule(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to read settings"})
+	}
+	return c.JSON(http.StatusOK, h.status(expr))
+}
+
+// Update handles PUT /api/v1/image-watch.
+//
+//	@Summary		Set the base image watcher schedule
+//	@Description	A five-field cron expression (minute hour day-of-month month day-of-week, in the server's time zone) or one of @hourly, @daily, @weekly and @monthly. An empty schedule turns the watcher off. Saved artifacts and builds made from a template are watched.
+//	@Tags			Artifacts
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			body	body		APIImageWatchSettings	true	"Schedule"
+//	@Success		200		{object}	APIImageWatchStatus
+//	@Failure		400		{object}	APIError
+//	@Router			/api/v1/image-watch [put]
+func (h *ImageWatchHandler) Update(c echo.Context) error {
+	var req APIImageWatchSettings
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	expr := strings.TrimSpace(req.Schedule)
+	if expr != "" {
+		if _, err := imagewatch.ParseSchedule(expr); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+	if err := h.settings.Set(c.Request().Context(), SettingImageWatchSchedule, expr); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to persist schedule"})
+	}
+	return c.JSON(http.StatusOK, h.status(expr))
+}
+
+// Check handles POST /api/v1/image-watch/run: check the watched base images
+// now instead of waiting for the schedule.
+//
+//	@Summary	Check watched base images now
+//	@Tags		Artifacts
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Success	200	{object}	APIImageWatchRun
+//	@Router		/api/v1/image-watch/run [post]
+func (h *ImageWatchHandler) Check(c echo.Context) error {
+	run, err := h.check(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, run)
+}
+
+// Run checks the watched base images whenever the schedule fires, until ctx
+// is cancelled. The schedule is re-read every tick, so changing it takes
+// effect without a restart. The web server starts it once next to the HTTP
+// listener.
+func (h *ImageWatchHandler) Run(ctx context.Context) {
+	ticker := time.NewTicker(imageWatchTick)
+	defer ticker.Stop()
+	var expr string
+	var next time.Time
+	for {
+		cur, err := h.schedule(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "image watch: reading schedule: %v\n", err)
+		} else if cur != expr {
+			expr, next = cur, time.Time{}
+			if sched, err := imagewatch.ParseSchedule(expr); err == nil {
+				next = sched.Next(h.now())
+			}
+		}
+		if !next.IsZero() && !h.now().Before(next) {
+			if _, err := h.check(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "image watch: %v\n", err)
+			}
+			if sched, err := imagewatch.ParseSchedule(expr); err == nil {
+				next = sched.Next(h.now())
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *ImageWatchHandler) schedule(ctx context.Context) (string, error) {
+	expr, _, err := h.settings.Get(ctx, SettingImageWatchSchedule)
+	return strings.TrimSpace(expr), err
+}
+
+func (h *ImageWatchHandler) status(expr string) APIImageWatchStatus {
+	out := APIImageWatchStatus{Schedule: expr}
+	if sched, err := imagewatch.ParseSchedule(expr); expr != "" && err == nil {
+		if next := sched.Next(h.now()); !next.IsZero() {
+			out.NextRun = &next
+		}
+	}
+	h.mu.Lock()
+	out.LastRun = h.last
+	h.mu.Unlock()
+	return out
+}
+
+// check resolves every watched base image and rebuilds the lineages whose
+// image moved.
+func (h *ImageWatchHandler) check(ctx context.Context) (*APIImageWatchRun, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	recs, err := h.artifacts.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing artifacts: %w", err)
+	}
+	run := &APIImageWatchRun{StartedAt: h.now(), Checks: []APIImageCheck{}}
+	for _, w := range watchedBuilds(recs) {
+		check := APIImageCheck{
+			Image:          w.BaseImage,
+			ArtifactID:     w.ID,
+			TemplateID:     w.TemplateID,
+			PreviousDigest: w.BaseImageDigest,
+		}
+		digest, err := h.digest(ctx, w.BaseImage, w.AllowInsecureRegistries)
+		if err != nil {
+			check.Error = fmt.Sprintf("resolving %s: %v", w.BaseImage, err)
+			run.Checks = append(run.Checks, check)
+			continue
+		}
+		check.Digest = digest
+		check.Changed = digest != w.BaseImageDigest
+		key := watchKey(w)
+		if check.Changed && h.triggered[key] != digest {
+			h.triggered[key] = digest
+			check.RebuildID, err = h.artifacts.rebuild(ctx, w)
+			if err != nil {
+				check.Error = err.Error()
+				fmt.Fprintf(os.Stderr, "image watch: rebuilding %s for %s: %v\n", w.ID, digest, err)
+			} else if w.TemplateID != "" && h.templates != nil {
+				if err := h.templates.Attach(ctx, w.TemplateID, check.RebuildID); err != nil {
+					fmt.Fprintf(os.Stderr, "image watch: link build %s to template %s: %v\n", check.RebuildID, w.TemplateID, err)
+				}
+			}
+			h.broadcast(check)
+		}
+		run.Checks = append(run.Checks, check)
+	}
+	run.FinishedAt = h.now()
+	h.last = run
+	return run, nil
+}
+
+func (h *ImageWatchHandler) broadcast(check APIImageCheck) {
+	if h.hub == nil || h.hub.UI == nil {
+		return
+	}
+	h.hub.UI.Broadcast(map[string]any{"type": "base-image-changed", "data": check})
+}
+
+// watchKey names a lineage: the builds of one template, or of one build
+// name, from one base image.
+func watchKey(rec *store.ArtifactRecord) string {
+	if rec.TemplateID != "" {
+		return "template:" + rec.TemplateID + "|" + rec.BaseImage
+	}
+	return "name:" + rec.Name + "|" + rec.BaseImage
+}
+
+// watchedBuilds returns, for each watched lineage, the latest build that
+// recorded the digest of its base image. A lineage is watched when one of
+// its builds is saved or it was built from a template. Lineages with a
+// build still running are skipped: the next check compares against it once
+// it has finished. Images pinned by digest cannot move and are left out.
+func watchedBuilds(recs []*store.ArtifactRecord) []*store.ArtifactRecord {
+	type lineage struct {
+		latest  *store.ArtifactRecord
+		watched bool
+		running bool
+	}
+	lineages := map[string]*lineage{}
+	var order []string
+	for _, rec := range recs {
+		if rec.BaseImage == "" || strings.Contains(rec.BaseImage, "@") {
+			continue
+		}
+		k := watchKey(rec)
+		l, ok := lineages[k]
+		if !ok {
+			l = &lineage{}
+			lineages[k] = l
+			order = append(order, k)
+		}
+		l.watched = l.watched || rec.Saved || rec.TemplateID != ""
+		l.running = l.running || rec.Phase == store.ArtifactPending || rec.Phase == store.ArtifactBuilding
+		if rec.BaseImageDigest != "" && (l.latest == nil || rec.CreatedAt.After(l.latest.CreatedAt)) {
+			l.latest = rec
+		}
+	}
+	var out []*store.ArtifactRecord
+	for _, k := range order {
+		if l := lineages[k]; l.watched && !l.running && l.latest != nil {
+			out = append(out, l.latest)
+		}
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("ImageWatchHandler", func() {
+	const image = "quay.io/kairos/ubuntu:24.04"
+
+	var (
+		e         *echo.Echo
+		fb        *fakeBuilder
+		artifacts *fakeArtifactStore
+		specs     *fakeArtifactSpecStore
+		templates *fakeBuildTemplateStore
+		settings  *fakeSettingsStore
+		digests   map[string]string
+		handler   *handlers.ImageWatchHandler
+	)
+
+	build := func(id, name, digest string, saved bool, age time.Duration) *store.ArtifactRecord {
+		rec := &store.ArtifactRecord{
+			ID: id, Name: name, Saved: saved, Phase: store.ArtifactReady,
+			BaseImage: image, BaseImageDigest: digest, CreatedAt: time.Now().Add(-age),
+		}
+		artifacts.records = append(artifacts.records, rec)
+		specs.specs[id] = `{"name": "` + name + `", "baseImage": "` + image + `", "outputs": {"iso": true}}`
+		return rec
+	}
+
+	call := func(h echo.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/image-watch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		Expect(h(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	check := func() handlers.APIImageWatchRun {
+		rec := call(handler.Check, http.MethodPost, "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var run handlers.APIImageWatchRun
+		Expect(json.Unmarshal(rec.Body.Bytes(), &run)).To(Succeed())
+		return run
+	}
+
+	BeforeEach(func() {
+		e = echo.New()
+		fb = &fakeBuilder{}
+		artifacts = &fakeArtifactStore{}
+		specs = newFakeArtifactSpecStore()
+		templates = newFakeBuildTemplateStore()
+		settings = newFakeSettingsStore()
+		digests = map[string]string{image: "sha256:bbbb"}
+		ah := handlers.NewArtifactHandler(fb, artifacts, nil, nil, "", "reg-token", "http://localhost:8080").
+			WithSpecs(specs)
+		handler = handlers.NewImageWatchHandler(settings, ah).
+			WithTemplates(templates).
+			WithDigestFunc(func(_ context.Context, ref string, _ bool) (string, error) {
+				if d, ok := digests[ref]; ok {
+					return d, nil
+				}
+				return "", errors.New("manifest unknown")
+			})
+	})
+
+	It("rebuilds the latest saved build once its base image moves", func() {
+		build("old", "edge", "sha256:0000", true, 2*time.Hour)
+		build("new", "edge", "sha256:aaaa", false, time.Hour)
+		build("scratch", "scratch", "sha256:aaaa", false, time.Hour)
+
+		run := check()
+		Expect(run.Checks).To(HaveLen(1))
+		Expect(run.Checks[0].ArtifactID).To(Equal("new"))
+		Expect(run.Checks[0].PreviousDigest).To(Equal("sha256:aaaa"))
+		Expect(run.Checks[0].Changed).To(BeTrue())
+		Expect(run.Checks[0].RebuildID).NotTo(BeEmpty())
+		Expect(fb.builds).To(HaveLen(1))
+		Expect(fb.lastOpts.Name).To(Equal("edge"))
+
+		// While the rebuild runs the lineage is left alone; once it has
+		// built from the new digest there is nothing to do.
+		Expect(check().Checks).To(BeEmpty())
+		rebuilt, err := artifacts.GetByID(context.Background(), run.Checks[0].RebuildID)
+		Expect(err).NotTo(HaveOccurred())
+		rebuilt.Phase = store.ArtifactReady
+		rebuilt.CreatedAt = time.Now()
+		rebuilt.BaseImageDigest = "sha256:bbbb"
+		run = check()
+		Expect(run.Checks).To(HaveLen(1))
+		Expect(run.Checks[0].Changed).To(BeFalse())
+		Expect(fb.builds).To(HaveLen(1))
+	})
+
+	It("watches template builds and does not retry a failed rebuild", func() {
+		tplBuild := build("t1", "edge-a", "sha256:aaaa", false, time.Hour)
+		tplBuild.TemplateID = "tpl"
+		specs.specs["t1"] = ""
+
+		run := check()
+		Expect(run.Checks).To(HaveLen(1))
+		Expect(run.Checks[0].TemplateID).To(Equal("tpl"))
+		Expect(run.Checks[0].Error).To(ContainSubstring("predates kept requests"))
+
+		run = check()
+		Expect(run.Checks[0].Changed).To(BeTrue())
+		Expect(run.Checks[0].Error).To(BeEmpty())
+		Expect(run.Checks[0].RebuildID).To(BeEmpty())
+		Expect(fb.builds).To(BeEmpty())
+	})
+
+	It("reports registry errors per image", func() {
+		build("edge", "edge", "sha256:aaaa", true, time.Hour)
+		delete(digests, image)
+		run := check()
+		Expect(run.Checks).To(HaveLen(1))
+		Expect(run.Checks[0].Error).To(ContainSubstring("manifest unknown"))
+		Expect(fb.builds).To(BeEmpty())
+	})
+
+	It("validates and stores the schedule", func() {
+		Expect(call(handler.Update, http.MethodPut, `{"schedule": "every day"}`).Code).To(Equal(http.StatusBadRequest))
+
+		rec := call(handler.Update, http.MethodPut, `{"schedule": "0 3 * * *"}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(settings.values[handlers.SettingImageWatchSchedule]).To(Equal("0 3 * * *"))
+		var status handlers.APIImageWatchStatus
+		Expect(json.Unmarshal(rec.Body.Bytes(), &status)).To(Succeed())
+		Expect(status.NextRun).NotTo(BeNil())
+		Expect(status.NextRun.Hour()).To(Equal(3))
+
+		rec = call(handler.Update, http.MethodPut, `{"schedule": ""}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var off handlers.APIImageWatchStatus
+		Expect(json.Unmarshal(rec.Body.Bytes(), &off)).To(Succeed())
+		Expect(off.NextRun).To(BeNil())
+	})
+})
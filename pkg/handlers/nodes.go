@@ -44,6 +44,9 @@ type NodeHandler struct {
 	// the server lifecycle so a shutdown cancels an in-flight eject. Defaults to
 	// context.Background().
 	baseCtx context.Context
+	// resolve, when non-nil, runs on every command a poll claims and drops
+	// the ones it returns false for (see ChannelHandler.ResolveCommand).
+	resolve func(ctx context.Context, cmd *store.NodeCommand) bool
 }
 
 // NewNodeHandler creates a new NodeHandler.
@@ -70,6 +73,13 @@ func (h *NodeHandler) WithFinalizer(f nodeFinalizer, baseCtx context.Context) *N
 	return h
 }
 
+// WithCommandResolver sets the hook that rewrites claimed commands before a
+// poll returns them, and may withhold them. Returns the handler for chaining.
+func (h *NodeHandler) WithCommandResolver(resolve func(ctx context.Context, cmd *store.NodeCommand) bool) *NodeHandler {
+	h.resolve = resolve
+	return h
+}
+
 // triggerFinalize fires the auto eject-on-phone-home hook off the request goroutine
 // so it never adds latency to register/heartbeat. The background context is derived
 // from the server base context (cancelled on shutdown) plus a short timeout; the
@@ -601,6 +611,9 @@ func (h *NodeHandler) GetCommands(c echo.Context) error {
 			if !ok {
 				continue
 			}
+			if h.resolve != nil && !h.resolve(ctx, cmd) {
+				continue
+			}
 			cmd.Phase = store.CommandDelivered
 			cmd.DeliveredAt = &now
 			claimed = append(claimed, cmd)
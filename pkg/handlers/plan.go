@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/kairos-io/AuroraBoot/deployer"
+	sdklogger "github.com/kairos-io/kairos-sdk/types/logger"
+	"github.com/labstack/echo/v4"
+	"github.com/spectrocloud-labs/herd"
+)
+
+// PlanHandler serves /api/v1/plan, the dry run of an AuroraBoot
+// configuration: which steps it runs, which it skips and why.
+type PlanHandler struct{}
+
+// NewPlanHandler creates a PlanHandler.
+func NewPlanHandler() *PlanHandler {
+	return &PlanHandler{}
+}
+
+// Plan handles POST /api/v1/plan.
+//
+//	@Summary		Dry-run an AuroraBoot configuration
+//	@Description	Builds the steps of an AuroraBoot configuration, the YAML `auroraboot --dry-run` takes, and returns them in execution order: whether each step runs, why, and the paths it reads and writes. Nothing is downloaded or built. Steps of the same layer run concurrently. A configuration that does not validate is a 400.
+//	@Tags			Artifacts
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			body	body		APIPlanRequest	true	"AuroraBoot configuration"
+//	@Success		200		{object}	deployer.Plan
+//	@Failure		400		{object}	APIError
+//	@Router			/api/v1/plan [post]
+func (h *PlanHandler) Plan(c echo.Context) error {
+	var req APIPlanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	cfg, release, err := deployer.LoadByte([]byte(req.Config))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid config: " + err.Error()})
+	}
+	if cfg.State == "" {
+		cfg.State = "/tmp/auroraboot"
+	}
+	d := deployer.NewDeployer(*cfg, *release, herd.CollectOrphans)
+	d.Log = sdklogger.NewNullLogger()
+	if err := deployer.RegisterAll(d); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, d.Plan())
+}
@@ -0,0 +1,53 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/deployer"
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("PlanHandler", func() {
+	plan := func(body string) (*httptest.ResponseRecorder, deployer.Plan) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/plan", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		Expect(handlers.NewPlanHandler().Plan(echo.New().NewContext(req, rec))).To(Succeed())
+		var p deployer.Plan
+		if rec.Code == http.StatusOK {
+			Expect(json.Unmarshal(rec.Body.Bytes(), &p)).To(Succeed())
+		}
+		return rec, p
+	}
+
+	It("returns which steps run and why", func() {
+		rec, p := plan(`{"config":"container_image: docker:alpine\nstate_dir: /tmp/plan\ndisk:\n  efi: true\n"}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		steps := map[string]deployer.StepPlan{}
+		for _, step := range p.Steps {
+			steps[step.Name] = step
+		}
+		Expect(steps).To(HaveKey("gen-raw-efi-disk"))
+		Expect(steps["gen-raw-efi-disk"].Run).To(BeTrue())
+		Expect(steps["gen-raw-efi-disk"].Outputs).To(ConsistOf("/tmp/plan/kairos-*.raw"))
+		Expect(steps["gen-iso"].Run).To(BeFalse())
+		Expect(steps["gen-iso"].Reason).To(Equal("a raw disk output is requested"))
+	})
+
+	It("returns 400 for a config that does not validate", func() {
+		rec, _ := plan(`{"config":"container_image: docker:alpine\ncompression:\n  format: rar\n"}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns 400 for YAML that does not parse", func() {
+		rec, _ := plan(`{"config":"disk: [\n"}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+})
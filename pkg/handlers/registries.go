@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kairos-io/AuroraBoot/pkg/publish"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+// ImageLoader opens the OS image of a build by reference. The default is
+// publish.SourceImage; tests swap it to avoid needing a Docker daemon.
+type ImageLoader func(ctx context.Context, ref string, insecure bool) (v1.Image, error)
+
+// RegistryHandler manages the OCI registries artifacts are published to and
+// publishes to them.
+type RegistryHandler struct {
+	registries   store.RegistryStore
+	artifacts    store.ArtifactStore
+	artifactsDir string
+	loadImage    ImageLoader
+}
+
+// NewRegistryHandler creates a new RegistryHandler. Output files are read
+// from their build's directory under artifactsDir, which holds a copy even
+// when another storage backend serves downloads.
+func NewRegistryHandler(registries store.RegistryStore, artifacts store.ArtifactStore, artifactsDir string) *RegistryHandler {
+	return &RegistryHandler{
+		registries:   registries,
+		artifacts:    artifacts,
+		artifactsDir: artifactsDir,
+		loadImage:    publish.SourceImage,
+	}
+}
+
+// WithImageLoader replaces how a build's OS image is opened for publishing.
+func (h *RegistryHandler) WithImageLoader(l ImageLoader) *RegistryHandler {
+	h.loadImage = l
+	return h
+}
+
+// registryRequest is the body of POST and PUT /api/v1/registries. It is a
+// separate type from store.Registry because the password is accepted here
+// but never serialized back.
+type registryRequest struct {
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Namespace string `json:"namespace"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	Insecure  bool   `json:"insecure"`
+}
+
+func (r *registryRequest) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if strings.Contains(r.Host, "://") {
+		return fmt.Errorf("host must not include a scheme; set insecure for plain HTTP")
+	}
+	if _, err := name.NewRegistry(r.Host, name.StrictValidation); err != nil {
+		return fmt.Errorf("invalid host: %v", err)
+	}
+	r.Namespace = strings.Trim(r.Namespace, "/")
+	if r.Namespace != "" {
+		if _, err := name.NewRepository(r.Host+"/"+r.Namespace, name.StrictValidation); err != nil {
+			return fmt.Errorf("invalid namespace: %v", err)
+		}
+	}
+	return nil
+}
+
+// Create handles POST /api/v1/registries.
+//
+//	@Summary		Add a publishing registry
+//	@Description	Saves an OCI registry and the credentials to push to it. The password is encrypted at rest and never returned.
+//	@Tags			Registries
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			body	body		APIRegistryRequest	true	"Registry"
+//	@Success		201		{object}	store.Registry
+//	@Failure		400		{object}	APIError
+//	@Router			/api/v1/registries [post]
+func (h *RegistryHandler) Create(c echo.Context) error {
+	var req registryRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if err := req.validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	reg := &store.Registry{
+		Name:      req.Name,
+		Host:      req.Host,
+		Namespace: req.Namespace,
+		Username:  req.Username,
+		Password:  req.Password,
+		Insecure:  req.Insecure,
+	}
+	if err := h.registries.Create(c.Request().Context(), reg); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create registry"})
+	}
+	return c.JSON(http.StatusCreated, reg)
+}
+
+// List handles GET /api/v1/registries.
+//
+//	@Summary		List publishing registries
+//	@Tags			Registries
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Success		200	{array}	store.Registry
+//	@Router			/api/v1/registries [get]
+func (h *RegistryHandler) List(c echo.Context) error {
+	regs, err := h.registries.List(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to list registries"})
+	}
+	return c.JSON(http.StatusOK, regs)
+}
+
+// Update handles PUT /api/v1/registries/:id. An empty password keeps the
+// stored one.
+//
+//	@Summary		Update a publishing registry
+//	@Tags			Registries
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			id		path		string				true	"Registry ID"
+//	@Param			body	body		APIRegistryRequest	true	"Registry"
+//	@Success		200		{object}	store.Registry
+//	@Failure		400		{object}	APIError
+//	@Failure		404		{object}	APIError
+//	@Router			/api/v1/registries/{id} [put]
+func (h *RegistryHandler) Update(c echo.Context) error {
+	ctx := c.Request().Context()
+	reg, err := h.registries.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "registry not found"})
+	}
+	var req registryRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if err := req.validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	reg.Name = req.Name
+	reg.Host = req.Host
+	reg.Namespace = req.Namespace
+	reg.Username = req.Username
+	if req.Password != "" {
+		reg.Password = req.Password
+	}
+	reg.Insecure = req.Insecure
+	if err := h.registries.Update(ctx, reg); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update registry"})
+	}
+	return c.JSON(http.StatusOK, reg)
+}
+
+// Delete handles DELETE /api/v1/registries/:id.
+//
+//	@Summary		Remove a publishing registry
+//	@Tags			Registries
+//	@Security		AdminBearer
+//	@Param			id	path	string	true	"Registry ID"
+//	@Success		204
+//	@Router			/api/v1/registries/{id} [delete]
+func (h *RegistryHandler) Delete(c echo.Context) error {
+	if err := h.registries.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to delete registry"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// publishRequest is the body of POST /api/v1/artifacts/:id/publish.
+type publishRequest struct {
+	RegistryID string   `json:"registryId"`
+	Repository string   `json:"repository"`
+	Tags       []string `json:"tags"`
+	// Image pushes the OS container image.
+	Image bool `json:"image"`
+	// Outputs pushes the output files as an OCI artifact: every file the
+	// build produced, or only those named in Files.
+	Outputs bool     `json:"outputs"`
+	Files   []string `json:"files,omitempty"`
+}
+
+// Publish handles POST /api/v1/artifacts/:id/publish.
+//
+//	@Summary		Publish an artifact to a registry
+//	@Description	Pushes the build's OS container image to <host>/<namespace>/<repository> under every tag, and/or its output files as one OCI artifact tagged <tag>-artifacts, with one layer per file named by its org.opencontainers.image.title annotation (the ORAS layout). When both are pushed the artifact refers to the image as its subject. The call returns once the push completes and records it on the artifact.
+//	@Tags			Artifacts
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			id		path		string				true	"Artifact ID"
+//	@Param			body	body		APIPublishRequest	true	"What to publish and where"
+//	@Success		200		{object}	store.Publication
+//	@Failure		400		{object}	APIError
+//	@Failure		404		{object}	APIError
+//	@Failure		409		{object}	APIError
+//	@Failure		502		{object}	APIError
+//	@Router			/api/v1/artifacts/{id}/publish [post]
+func (h *RegistryHandler) Publish(c echo.Context) error {
+	ctx := c.Request().Context()
+	rec, err := h.artifacts.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "artifact not found"})
+	}
+	var req publishRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if rec.Phase != store.ArtifactReady {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "artifact is not ready"})
+	}
+	if !req.Image && !req.Outputs {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "nothing to publish: set image and/or outputs"})
+	}
+	if req.Image && rec.ContainerImage == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "artifact has no container image"})
+	}
+	reg, err := h.registries.GetByID(ctx, req.RegistryID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "unknown registry"})
+	}
+	repo, err := publishRepository(reg, req.Repository, req.Tags)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	var files []string
+	if req.Outputs {
+		if files, err = h.outputFiles(rec, req.Files); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	var auth authn.Authenticator = authn.Anonymous
+	if reg.Username != "" {
+		auth = &authn.Basic{Username: reg.Username, Password: reg.Password}
+	}
+	pub := store.Publication{RegistryID: reg.ID, Tags: req.Tags}
+	var subject *v1.Descriptor
+	if req.Image {
+		img, err := h.loadImage(ctx, rec.ContainerImage, rec.AllowInsecureRegistries)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("opening container image: %v", err)})
+		}
+		if subject, err = publish.Image(ctx, repo, img, req.Tags, remote.WithAuth(auth)); err != nil {
+			return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("publishing image: %v", err)})
+		}
+		pub.Image = repo.Digest(subject.Digest.String()).String()
+	}
+	if len(files) > 0 {
+		desc, err := publish.Files(ctx, repo, files, req.Tags, subject, remote.WithAuth(auth))
+		if err != nil {
+			return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("publishing outputs: %v", err)})
+		}
+		pub.Files = repo.Digest(desc.Digest.String()).String()
+	}
+	pub.PublishedAt = time.Now()
+
+	// Re-read so a push that took minutes does not write back a stale
+	// snapshot of the record.
+	if fresh, err := h.artifacts.GetByID(ctx, rec.ID); err == nil {
+		rec = fresh
+	}
+	rec.Publications = append(rec.Publications, pub)
+	if err := h.artifacts.Update(ctx, rec); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "published, but failed to record it"})
+	}
+	return c.JSON(http.StatusOK, pub)
+}
+
+// publishRepository joins the registry, its namespace and repo, and checks
+// that every tag is valid for it.
+func publishRepository(reg *store.Registry, repo string, tags []string) (name.Repository, error) {
+	repo = strings.Trim(repo, "/")
+	if repo == "" {
+		return name.Repository{}, fmt.Errorf("repository is required")
+	}
+	if len(tags) == 0 {
+		return name.Repository{}, fmt.Errorf("at least one tag is required")
+	}
+	opts := []name.Option{name.StrictValidation}
+	if reg.Insecure {
+		opts = append(opts, name.Insecure)
+	}
+	full := reg.Host + "/" + repo
+	if reg.Namespace != "" {
+		full = reg.Host + "/" + reg.Namespace + "/" + repo
+	}
+	r, err := name.NewRepository(full, opts...)
+	if err != nil {
+		return name.Repository{}, fmt.Errorf("invalid repository: %v", err)
+	}
+	for _, t := range tags {
+		if _, err := name.NewTag(full+":"+t+publish.FilesTagSuffix, opts...); err != nil {
+			return name.Repository{}, fmt.Errorf("invalid tag %q: %v", t, err)
+		}
+	}
+	return r, nil
+}
+
+// outputFiles resolves the build's output files to publish: all of them, or
+// the ones named in only.
+func (h *RegistryHandler) outputFiles(rec *store.ArtifactRecord, only []string) ([]string, error) {
+	want := map[string]bool{}
+	for _, f := range only {
+		want[f] = true
+	}
+	var files []string
+	for _, f := range rec.ArtifactFiles {
+		base := filepath.Base(f)
+		if len(only) > 0 && !want[base] {
+			continue
+		}
+		delete(want, base)
+		path := filepath.Join(h.artifactsDir, rec.ID, base)
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("output %s is not available on this server", base)
+		}
+		files = append(files, path)
+	}
+	for f := range want {
+		return nil, fmt.Errorf("artifact has no output named %q", f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("artifact has no output files")
+	}
+	return files, nil
+}
@@ -0,0 +1,197 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/publish"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("RegistryHandler", func() {
+	var (
+		e            *echo.Echo
+		regs         *fakeRegistryStore
+		as           *fakeArtifactStore
+		artifactsDir string
+		handler      *handlers.RegistryHandler
+		img          v1.Image
+		loadedRef    string
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		regs = &fakeRegistryStore{}
+		artifactsDir = GinkgoT().TempDir()
+		dir := filepath.Join(artifactsDir, "build-1")
+		Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "kairos.iso"), []byte("iso-bytes"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "SHA256SUMS"), []byte("sums"), 0o644)).To(Succeed())
+		as = &fakeArtifactStore{
+			records: []*store.ArtifactRecord{{
+				ID:             "build-1",
+				Phase:          store.ArtifactReady,
+				ContainerImage: "auroraboot-build-1:latest",
+				ArtifactFiles:  []string{filepath.Join(dir, "kairos.iso"), "SHA256SUMS"},
+			}},
+		}
+		var err error
+		img, err = random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		loadedRef = ""
+		handler = handlers.NewRegistryHandler(regs, as, artifactsDir).
+			WithImageLoader(func(_ context.Context, ref string, _ bool) (v1.Image, error) {
+				loadedRef = ref
+				return img, nil
+			})
+	})
+
+	call := func(method, path, body string, fn echo.HandlerFunc, params ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if len(params) > 0 {
+			c.SetParamNames("id")
+			c.SetParamValues(params[0])
+		}
+		Expect(fn(c)).To(Succeed())
+		return rec
+	}
+
+	Describe("CRUD", func() {
+		It("stores the password but never returns it", func() {
+			rec := call(http.MethodPost, "/api/v1/registries", `{"name":"ghcr","host":"ghcr.io","namespace":"/acme/","username":"bot","password":"ghp_x"}`, handler.Create)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("ghp_x"))
+			Expect(regs.registries[0].Password).To(Equal("ghp_x"))
+			Expect(regs.registries[0].Namespace).To(Equal("acme"))
+
+			rec = call(http.MethodGet, "/api/v1/registries", "", handler.List)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("ghp_x"))
+		})
+
+		It("keeps the stored password when an update leaves it empty", func() {
+			Expect(regs.Create(context.Background(), &store.Registry{Name: "ghcr", Host: "ghcr.io", Username: "bot", Password: "old"})).To(Succeed())
+			rec := call(http.MethodPut, "/api/v1/registries/reg-1", `{"name":"ghcr","host":"ghcr.io","username":"bot2"}`, handler.Update, "reg-1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(regs.registries[0].Username).To(Equal("bot2"))
+			Expect(regs.registries[0].Password).To(Equal("old"))
+		})
+
+		DescribeTable("rejects invalid registries",
+			func(body, msg string) {
+				rec := call(http.MethodPost, "/api/v1/registries", body, handler.Create)
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(rec.Body.String()).To(ContainSubstring(msg))
+			},
+			Entry("no host", `{"name":"x"}`, "host is required"),
+			Entry("scheme", `{"name":"x","host":"https://ghcr.io"}`, "must not include a scheme"),
+			Entry("bad namespace", `{"name":"x","host":"ghcr.io","namespace":"UPPER"}`, "invalid namespace"),
+		)
+	})
+
+	Describe("Publish", func() {
+		var host string
+
+		BeforeEach(func() {
+			reg := ggcrregistry.New()
+			// Require the stored credentials, the way a real registry would.
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if u, p, ok := r.BasicAuth(); !ok || u != "bot" || p != "s3cret" {
+					w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				reg.ServeHTTP(w, r)
+			}))
+			DeferCleanup(srv.Close)
+			host = strings.TrimPrefix(srv.URL, "http://")
+			Expect(regs.Create(context.Background(), &store.Registry{Name: "lab", Host: host, Namespace: "acme", Username: "bot", Password: "s3cret", Insecure: true})).To(Succeed())
+		})
+
+		publishBody := func(extra string) string {
+			return `{"registryId":"reg-1","repository":"edge-os","tags":["v1","latest"]` + extra + `}`
+		}
+
+		It("pushes the image and outputs and records the publication", func() {
+			rec := call(http.MethodPost, "/api/v1/artifacts/build-1/publish", publishBody(`,"image":true,"outputs":true`), handler.Publish, "build-1")
+			Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+			Expect(loadedRef).To(Equal("auroraboot-build-1:latest"))
+
+			var pub store.Publication
+			Expect(json.Unmarshal(rec.Body.Bytes(), &pub)).To(Succeed())
+			imgDigest, err := img.Digest()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pub.Image).To(Equal(host + "/acme/edge-os@" + imgDigest.String()))
+			Expect(pub.Files).To(HavePrefix(host + "/acme/edge-os@sha256:"))
+			Expect(as.records[0].Publications).To(HaveLen(1))
+			Expect(as.records[0].Publications[0].RegistryID).To(Equal("reg-1"))
+
+			opt := remote.WithAuth(&authn.Basic{Username: "bot", Password: "s3cret"})
+			ref, err := name.ParseReference(host+"/acme/edge-os:latest"+publish.FilesTagSuffix, name.Insecure)
+			Expect(err).NotTo(HaveOccurred())
+			art, err := remote.Image(ref, opt)
+			Expect(err).NotTo(HaveOccurred())
+			m, err := art.Manifest()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Subject.Digest).To(Equal(imgDigest))
+			var titles []string
+			for _, l := range m.Layers {
+				titles = append(titles, l.Annotations[publish.TitleAnnotation])
+			}
+			Expect(titles).To(ConsistOf("kairos.iso", "SHA256SUMS"))
+		})
+
+		It("pushes only the named outputs", func() {
+			rec := call(http.MethodPost, "/api/v1/artifacts/build-1/publish", publishBody(`,"outputs":true,"files":["kairos.iso"]`), handler.Publish, "build-1")
+			Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+			Expect(loadedRef).To(BeEmpty())
+			Expect(as.records[0].Publications[0].Image).To(BeEmpty())
+		})
+
+		It("reports a push the registry refuses", func() {
+			regs.registries[0].Password = "wrong"
+			rec := call(http.MethodPost, "/api/v1/artifacts/build-1/publish", publishBody(`,"image":true`), handler.Publish, "build-1")
+			Expect(rec.Code).To(Equal(http.StatusBadGateway))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("wrong"))
+			Expect(as.records[0].Publications).To(BeEmpty())
+		})
+
+		DescribeTable("rejects bad requests",
+			func(body string, code int, msg string) {
+				rec := call(http.MethodPost, "/api/v1/artifacts/build-1/publish", body, handler.Publish, "build-1")
+				Expect(rec.Code).To(Equal(code))
+				Expect(rec.Body.String()).To(ContainSubstring(msg))
+			},
+			Entry("nothing selected", `{"registryId":"reg-1","repository":"edge-os","tags":["v1"]}`, http.StatusBadRequest, "nothing to publish"),
+			Entry("unknown registry", `{"registryId":"nope","repository":"edge-os","tags":["v1"],"image":true}`, http.StatusBadRequest, "unknown registry"),
+			Entry("no tags", `{"registryId":"reg-1","repository":"edge-os","image":true}`, http.StatusBadRequest, "at least one tag"),
+			Entry("bad tag", `{"registryId":"reg-1","repository":"edge-os","tags":["no spaces"],"image":true}`, http.StatusBadRequest, "invalid tag"),
+			Entry("unknown file", `{"registryId":"reg-1","repository":"edge-os","tags":["v1"],"outputs":true,"files":["../etc/passwd"]}`, http.StatusBadRequest, "no output named"),
+		)
+
+		It("refuses an artifact that is not Ready", func() {
+			as.records[0].Phase = store.ArtifactBuilding
+			rec := call(http.MethodPost, "/api/v1/artifacts/build-1/publish", publishBody(`,"image":true`), handler.Publish, "build-1")
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+		})
+	})
+})
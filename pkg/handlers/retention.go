@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	netbootmgr "github.com/kairos-io/AuroraBoot/internal/netbootmgr"
+	"github.com/kairos-io/AuroraBoot/pkg/retention"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+// Settings keys holding the retention policy. Unset or zero disables a rule.
+const (
+	SettingRetentionKeepLastPerBase = "retention.keepLastPerBase"
+	SettingRetentionMaxAgeDays      = "retention.maxAgeDays"
+	SettingRetentionMaxTotalBytes   = "retention.maxTotalBytes"
+)
+
+// retentionInterval is how often Run applies the policy.
+const retentionInterval = time.Hour
+
+// RetentionHandler applies the artifact retention policy: on demand, as a
+// dry run, and periodically from Run. Builds are deleted the same way as
+// DELETE /api/v1/artifacts/:id.
+type RetentionHandler struct {
+	settings    store.SettingsStore
+	artifacts   *ArtifactHandler
+	deployments store.DeploymentStore
+	channels    store.ChannelStore
+	netboot     *netbootmgr.Manager
+
+	// mu serialises collections so the background run and an operator's
+	// "run now" never delete the same build twice.
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewRetentionHandler creates a RetentionHandler that reads its policy from
+// settings and deletes through artifacts.
+func NewRetentionHandler(settings store.SettingsStore, artifacts *ArtifactHandler) *RetentionHandler {
+	return &RetentionHandler{settings: settings, artifacts: artifacts, now: time.Now}
+}
+
+// WithDeployments protects the artifacts of deployments still in progress
+// or waiting to eject their media.
+func (h *RetentionHandler) WithDeployments(deployments store.DeploymentStore) *RetentionHandler {
+	h.deployments = deployments
+	return h
+}
+
+// WithChannels protects the artifact each channel points at and every
+// artifact in its history, so a rollback always has something to go to.
+func (h *RetentionHandler) WithChannels(channels store.ChannelStore) *RetentionHandler {
+	h.channels = channels
+	return h
+}
+
+// WithNetboot protects the artifact the netboot server is serving.
+func (h *RetentionHandler) WithNetboot(nb *netbootmgr.Manager) *RetentionHandler {
+	h.netboot = nb
+	return h
+}
+
+// retentionRunResponse is returned by Collect: the plan that was applied and
+// the removals that failed, by artifact ID.
+type retentionRunResponse struct {
+	retention.Plan
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// GetPolicy handles GET /api/v1/retention.
+//
+//	@Summary	Read the artifact retention policy
+//	@Tags		Retention
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Success	200	{object}	retention.Policy
+//	@Router		/api/v1/retention [get]
+func (h *RetentionHandler) GetPolicy(c echo.Context) error {
+	policy, err := h.policy(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to read settings"})
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy handles PUT /api/v1/retention.
+//
+//	@Summary		Set the artifact retention policy
+//	@Description	Zero disables a rule. Saved artifacts, running builds and artifacts used by an active deployment, a channel (now or in its history) or the netboot server are never removed.
+//	@Tags			Retention
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			body	body		retention.Policy	true	"Policy"
+//	@Success		200		{object}	retention.Policy
+//	@Failure		400		{object}	APIError
+//	@Router			/api/v1/retention [put]
+func (h *RetentionHandler) UpdatePolicy(c echo.Context) error {
+	var policy retention.Policy
+	if err := c.Bind(&policy); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if policy.KeepLastPerBase < 0 || policy.MaxAgeDays < 0 || policy.MaxTotalBytes < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "retention values cannot be negative"})
+	}
+	ctx := c.Request().Context()
+	for key, val := range map[string]string{
+		SettingRetentionKeepLastPerBase: strconv.Itoa(policy.KeepLastPerBase),
+		SettingRetentionMaxAgeDays:      strconv.Itoa(policy.MaxAgeDays),
+		SettingRetentionMaxTotalBytes:   strconv.FormatInt(policy.MaxTotalBytes, 10),
+	} {
+		if err := h.settings.Set(ctx, key, val); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to persist retention policy"})
+		}
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// Plan handles GET /api/v1/retention/plan: a dry run listing what the
+// policy would remove now.
+//
+//	@Summary	Preview what the retention policy would remove
+//	@Tags		Retention
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Success	200	{object}	retention.Plan
+//	@Router		/api/v1/retention/plan [get]
+func (h *RetentionHandler) Plan(c echo.Context) error {
+	ctx := c.Request().Context()
+	policy, err := h.policy(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to read settings"})
+	}
+	plan, err := h.plan(ctx, policy)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, plan)
+}
+
+// Collect handles POST /api/v1/retention/run: apply the policy now instead
+// of waiting for the next background run.
+//
+//	@Summary	Apply the retention policy now
+//	@Tags		Retention
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Success	200	{object}	APIRetentionRun
+//	@Router		/api/v1/retention/run [post]
+func (h *RetentionHandler) Collect(c echo.Context) error {
+	plan, failed, err := h.collect(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, retentionRunResponse{Plan: plan, Failed: failed})
+}
+
+// Run applies the policy at start and then every retentionInterval until ctx
+// is cancelled. The web server starts it once next to the HTTP listener.
+func (h *RetentionHandler) Run(ctx context.Context) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		if _, _, err := h.collect(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "retention: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect computes the plan under mu and removes what it lists. A build
+// that was saved since the plan was made is skipped.
+func (h *RetentionHandler) collect(ctx context.Context) (retention.Plan, map[string]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	policy, err := h.policy(ctx)
+	if err != nil {
+		return retention.Plan{}, nil, fmt.Errorf("reading retention policy: %w", err)
+	}
+	if !policy.Enabled() {
+		return retention.Plan{Remove: []retention.Removal{}}, nil, nil
+	}
+	plan, err := h.plan(ctx, policy)
+	if err != nil {
+		return retention.Plan{}, nil, err
+	}
+	var failed map[string]string
+	for _, r := range plan.Remove {
+		rec, err := h.artifacts.store.GetByID(ctx, r.ID)
+		if err != nil || rec.Saved {
+			continue
+		}
+		if err := h.artifacts.remove(ctx, rec); err != nil {
+			if failed == nil {
+				failed = map[string]string{}
+			}
+			failed[r.ID] = err.Error()
+			fmt.Fprintf(os.Stderr, "retention: removing %s failed: %v\n", r.ID, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "retention: removed %s (%s, %d bytes)\n", r.ID, r.Reason, r.Bytes)
+	}
+	return plan, failed, nil
+}
+
+// policy reads the policy from settings. Unparseable values count as unset.
+func (h *RetentionHandler) policy(ctx context.Context) (retention.Policy, error) {
+	all, err := h.settings.GetAll(ctx)
+	if err != nil {
+		return retention.Policy{}, err
+	}
+	keep, _ := strconv.Atoi(all[SettingRetentionKeepLastPerBase])
+	days, _ := strconv.Atoi(all[SettingRetentionMaxAgeDays])
+	total, _ := strconv.ParseInt(all[SettingRetentionMaxTotalBytes], 10, 64)
+	return retention.Policy{KeepLastPerBase: keep, MaxAgeDays: days, MaxTotalBytes: total}, nil
+}
+
+// plan gathers every artifact with its size on disk and what protects it,
+// and computes the removals.
+func (h *RetentionHandler) plan(ctx context.Context, policy retention.Policy) (retention.Plan, error) {
+	recs, err := h.artifacts.store.List(ctx)
+	if err != nil {
+		return retention.Plan{}, fmt.Errorf("listing artifacts: %w", err)
+	}
+	protected, err := h.protected(ctx)
+	if err != nil {
+		return retention.Plan{}, err
+	}
+	candidates := make([]retention.Artifact, 0, len(recs))
+	for _, rec := range recs {
+		candidates = append(candidates, retention.Artifact{
+			ID:        rec.ID,
+			Name:      rec.Name,
+			Base:      retentionBase(rec),
+			CreatedAt: rec.CreatedAt,
+			Bytes:     dirSize(filepath.Join(h.artifacts.artifactsDir, rec.ID)),
+			Saved:     rec.Saved,
+			Finished:  rec.Phase == store.ArtifactReady || rec.Phase == store.ArtifactError,
+			Failed:    rec.Phase == store.ArtifactError,
+			Protected: protected[rec.ID],
+		})
+	}
+	return retention.Compute(policy, h.now(), candidates), nil
+}
+
+// protected maps the IDs of artifacts still in use to why.
+func (h *RetentionHandler) protected(ctx context.Context) (map[string]string, error) {
+	out := map[string]string{}
+	if h.deployments != nil {
+		deps, err := h.deployments.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing deployments: %w", err)
+		}
+		for _, d := range deps {
+			// A completed deploy whose media is not ejected yet still has
+			// the ISO mounted on the BMC.
+			if d.Status == store.DeployActive || d.EjectState == store.EjectStatePending || d.EjectState == store.EjectStateEjecting {
+				out[d.ArtifactID] = "deployment " + d.ID
+			}
+		}
+	}
+	if h.channels != nil {
+		chans, err := h.channels.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing channels: %w", err)
+		}
+		for _, ch := range chans {
+			if ch.ArtifactID != "" {
+				out[ch.ArtifactID] = "channel " + ch.Name
+			}
+			// Anything the channel ever pointed at is a rollback target.
+			history, err := h.channels.History(ctx, ch.ID)
+			if err != nil {
+				return nil, fmt.Errorf("loading history of channel %s: %w", ch.Name, err)
+			}
+			for _, e := range history {
+				for _, id := range []string{e.ArtifactID, e.PreviousArtifactID} {
+					if _, ok := out[id]; id != "" && !ok {
+						out[id] = "channel " + ch.Name + " history"
+					}
+				}
+			}
+		}
+	}
+	if h.netboot != nil {
+		if st := h.netboot.GetStatus(); st.Running && st.ArtifactID != "" {
+			out[st.ArtifactID] = "netboot server"
+		}
+	}
+	return out, nil
+}
+
+// retentionBase is the image a build is based on, which keep-last groups by.
+func retentionBase(rec *store.ArtifactRecord) string {
+	if rec.BaseImage != "" {
+		return rec.BaseImage
+	}
+	if rec.HadronBase != "" {
+		return "hadron:" + rec.HadronBase
+	}
+	return ""
+}
+
+// dirSize is the total size of the regular files under dir, 0 if it is gone.
+func dirSize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
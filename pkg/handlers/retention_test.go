@@ -0,0 +1,168 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/retention"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("RetentionHandler", func() {
+	var (
+		e            *echo.Echo
+		as           *fakeArtifactStore
+		ds           *fakeDeploymentStore
+		chs          *fakeChannelStore
+		settings     *fakeSettingsStore
+		artifactsDir string
+		handler      *handlers.RetentionHandler
+	)
+
+	// build adds a finished, unsaved artifact created days ago with a file of
+	// size bytes in its output directory.
+	build := func(id, base string, days int, size int) *store.ArtifactRecord {
+		rec := &store.ArtifactRecord{
+			ID:        id,
+			Phase:     store.ArtifactReady,
+			BaseImage: base,
+			CreatedAt: time.Now().Add(-time.Duration(days) * 24 * time.Hour),
+		}
+		dir := filepath.Join(artifactsDir, id)
+		Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "kairos.iso"), make([]byte, size), 0o644)).To(Succeed())
+		as.records = append(as.records, rec)
+		return rec
+	}
+
+	call := func(h echo.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		Expect(h(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	remaining := func() []string {
+		var ids []string
+		for _, r := range as.records {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		e = echo.New()
+		artifactsDir = GinkgoT().TempDir()
+		as = &fakeArtifactStore{}
+		ds = &fakeDeploymentStore{}
+		chs = &fakeChannelStore{}
+		settings = newFakeSettingsStore()
+		artifacts := handlers.NewArtifactHandler(&fakeBuilder{}, as, nil, nil, artifactsDir, "reg-token", "http://localhost:8080")
+		handler = handlers.NewRetentionHandler(settings, artifacts).
+			WithDeployments(ds).
+			WithChannels(chs)
+	})
+
+	It("stores the policy in settings", func() {
+		rec := call(handler.UpdatePolicy, http.MethodPut, `{"keepLastPerBase":3,"maxAgeDays":30,"maxTotalBytes":1000}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(settings.values).To(HaveKeyWithValue(handlers.SettingRetentionMaxAgeDays, "30"))
+
+		var got retention.Policy
+		Expect(json.Unmarshal(call(handler.GetPolicy, http.MethodGet, "").Body.Bytes(), &got)).To(Succeed())
+		Expect(got).To(Equal(retention.Policy{KeepLastPerBase: 3, MaxAgeDays: 30, MaxTotalBytes: 1000}))
+
+		Expect(call(handler.UpdatePolicy, http.MethodPut, `{"maxAgeDays":-1}`).Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("previews removals without deleting anything", func() {
+		build("old", "ubuntu", 60, 10)
+		build("new", "ubuntu", 1, 10)
+		Expect(settings.Set(context.Background(), handlers.SettingRetentionMaxAgeDays, "30")).To(Succeed())
+
+		var plan retention.Plan
+		Expect(json.Unmarshal(call(handler.Plan, http.MethodGet, "").Body.Bytes(), &plan)).To(Succeed())
+		Expect(plan.Remove).To(HaveLen(1))
+		Expect(plan.Remove[0].ID).To(Equal("old"))
+		Expect(plan.Remove[0].Bytes).To(BeEquivalentTo(10))
+		Expect(plan.TotalBytes).To(BeEquivalentTo(20))
+		Expect(remaining()).To(ConsistOf("old", "new"))
+	})
+
+	It("deletes what the policy removes and spares artifacts still in use", func() {
+		build("old", "ubuntu", 60, 10)
+		build("deployed", "ubuntu", 60, 10)
+		build("promoted", "ubuntu", 60, 10)
+		saved := build("saved", "ubuntu", 60, 10)
+		saved.Saved = true
+		build("new", "ubuntu", 1, 10)
+		ds.deps = []*store.Deployment{{ID: "dep-1", ArtifactID: "deployed", Status: store.DeployActive}}
+		ch := &store.Channel{Name: "ubuntu/stable"}
+		Expect(chs.Create(context.Background(), ch)).To(Succeed())
+		Expect(chs.Move(context.Background(), ch.ID, "", &store.ChannelEntry{ArtifactID: "promoted"})).To(Succeed())
+		Expect(settings.Set(context.Background(), handlers.SettingRetentionMaxAgeDays, "30")).To(Succeed())
+
+		rec := call(handler.Collect, http.MethodPost, "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var out handlers.APIRetentionRun
+		Expect(json.Unmarshal(rec.Body.Bytes(), &out)).To(Succeed())
+		Expect(out.Kept).To(ConsistOf(
+			retention.Kept{ID: "deployed", Reason: retention.ReasonMaxAge, Protected: "deployment dep-1"},
+			retention.Kept{ID: "promoted", Reason: retention.ReasonMaxAge, Protected: "channel ubuntu/stable"},
+		))
+
+		Expect(remaining()).To(ConsistOf("deployed", "promoted", "saved", "new"))
+		_, err := os.Stat(filepath.Join(artifactsDir, "old"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("keeps the artifacts a channel can roll back to", func() {
+		for _, id := range []string{"v1", "v2", "v3"} {
+			build(id, "ubuntu", 60, 10).ContainerImage = "registry.lan/os:" + id
+		}
+		build("stray", "ubuntu", 60, 10)
+		ch := &store.Channel{Name: "ubuntu/stable"}
+		Expect(chs.Create(context.Background(), ch)).To(Succeed())
+		Expect(chs.Move(context.Background(), ch.ID, "", &store.ChannelEntry{Action: store.ChannelPromote, ArtifactID: "v1"})).To(Succeed())
+		Expect(chs.Move(context.Background(), ch.ID, "v1", &store.ChannelEntry{Action: store.ChannelPromote, ArtifactID: "v2"})).To(Succeed())
+		Expect(chs.Move(context.Background(), ch.ID, "v2", &store.ChannelEntry{Action: store.ChannelPromote, ArtifactID: "v3"})).To(Succeed())
+		Expect(settings.Set(context.Background(), handlers.SettingRetentionMaxAgeDays, "30")).To(Succeed())
+
+		Expect(call(handler.Collect, http.MethodPost, "").Code).To(Equal(http.StatusOK))
+		Expect(remaining()).To(ConsistOf("v1", "v2", "v3"))
+
+		cmds := handlers.NewCommandHandler(&fakeCommandStore{}, &fakeNodeStore{}, nil)
+		channels := handlers.NewChannelHandler(chs, as, &fakeGroupStore{}, &fakeNodeStore{}, cmds)
+		for range 2 {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(ch.ID)
+			Expect(channels.Rollback(c)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+		}
+		got, err := chs.GetByID(context.Background(), ch.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.ArtifactID).To(Equal("v1"))
+	})
+
+	It("does nothing while no rule is set", func() {
+		build("old", "ubuntu", 400, 10)
+		Expect(call(handler.Collect, http.MethodPost, "").Code).To(Equal(http.StatusOK))
+		Expect(remaining()).To(ConsistOf("old"))
+	})
+})
@@ -0,0 +1,154 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/deployer"
+	"github.com/kairos-io/AuroraBoot/internal/builder/auroraboot"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("Retry", func() {
+	var (
+		e         *echo.Echo
+		fb        *fakeBuilder
+		artifacts *fakeArtifactStore
+		ah        *handlers.ArtifactHandler
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		fb = &fakeBuilder{}
+		artifacts = &fakeArtifactStore{}
+		ah = handlers.NewArtifactHandler(fb, artifacts, nil, nil, "", "reg-token", "http://localhost:8080").
+			WithSpecs(newFakeArtifactSpecStore())
+	})
+
+	create := func() string {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts",
+			strings.NewReader(`{"name": "edge", "baseImage": "quay.io/kairos/ubuntu:24.04", "outputs": {"iso": true}}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		Expect(ah.Create(e.NewContext(req, rec))).To(Succeed())
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		var status builder.BuildStatus
+		Expect(json.Unmarshal(rec.Body.Bytes(), &status)).To(Succeed())
+		return status.ID
+	}
+
+	retry := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts/"+id+"/retry", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		Expect(ah.Retry(c)).To(Succeed())
+		return rec
+	}
+
+	It("reruns a failed build in place from its request", func() {
+		id := create()
+		Expect(artifacts.UpdatePhaseMessage(context.Background(), id, store.ArtifactError, "inject-cloud-config failed")).To(Succeed())
+
+		rec := retry(id)
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(fb.lastOpts.ID).To(Equal(id))
+		Expect(fb.lastOpts.Resume).To(BeTrue())
+		Expect(fb.lastOpts.BaseImage).To(Equal("quay.io/kairos/ubuntu:24.04"))
+		Expect(fb.builds).To(HaveLen(2))
+		Expect(artifacts.records).To(HaveLen(1))
+	})
+
+	It("stores the digest of the retried build's upload token", func() {
+		id := create()
+		first := fb.lastOpts.UploadToken
+		Expect(artifacts.UpdatePhaseMessage(context.Background(), id, store.ArtifactError, "failed")).To(Succeed())
+
+		Expect(retry(id).Code).To(Equal(http.StatusAccepted))
+		Expect(fb.lastOpts.UploadToken).NotTo(Equal(first))
+		rec, err := artifacts.GetByID(context.Background(), id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rec.UploadToken).To(Equal(sha256Hex(fb.lastOpts.UploadToken)))
+	})
+
+	It("refuses a build that did not fail", func() {
+		id := create()
+
+		rec := retry(id)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(ContainSubstring("only failed builds can be retried"))
+		Expect(fb.builds).To(HaveLen(1))
+	})
+
+	It("returns 404 for an unknown build", func() {
+		Expect(retry("nope").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns 501 when the backend cannot resume", func() {
+		id := create()
+		Expect(artifacts.UpdatePhaseMessage(context.Background(), id, store.ArtifactError, "failed")).To(Succeed())
+		fb.buildErr = fmt.Errorf("%w: builds on remote workers cannot be resumed", builder.ErrNotSupported)
+
+		Expect(retry(id).Code).To(Equal(http.StatusNotImplemented))
+	})
+
+	Context("with the local builder", func() {
+		BeforeEach(func() {
+			bin := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(bin, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755)).To(Succeed())
+			GinkgoT().Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			var mu sync.Mutex
+			runs := 0
+			deploy := func(context.Context, schema.Config, schema.ReleaseArtifact, string, io.Writer, func(deployer.Event)) error {
+				mu.Lock()
+				defer mu.Unlock()
+				runs++
+				if runs == 1 {
+					return errors.New("inject-cloud-config failed")
+				}
+				return nil
+			}
+			ah = handlers.NewArtifactHandler(auroraboot.New(GinkgoT().TempDir(), deploy, artifacts), artifacts, nil, nil, "", "reg-token", "http://localhost:8080").
+				WithSpecs(newFakeArtifactSpecStore())
+		})
+
+		record := func(id string) store.ArtifactRecord {
+			rec, err := artifacts.GetByID(context.Background(), id)
+			Expect(err).NotTo(HaveOccurred())
+			artifacts.mu.Lock()
+			defer artifacts.mu.Unlock()
+			return *rec
+		}
+
+		It("resumes the failed build with a fresh upload token", func() {
+			id := create()
+			Eventually(func() string { return record(id).Phase }, "5s").Should(Equal(store.ArtifactError))
+			Expect(artifacts.SetUploadToken(context.Background(), id, sha256Hex("failed run"))).To(Succeed())
+
+			Expect(retry(id).Code).To(Equal(http.StatusAccepted))
+			Eventually(func() string { return record(id).Phase }, "5s").Should(Equal(store.ArtifactReady))
+			rec := record(id)
+			Expect(rec.UploadToken).To(MatchRegexp("^[0-9a-f]{64}$"))
+			Expect(rec.UploadToken).NotTo(Equal(sha256Hex("failed run")))
+			Expect(artifacts.records).To(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+// secretNameRe restricts secret names to what reads unambiguously in a build
+// request and a URL path.
+var secretNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// SecretHandler manages named secrets, such as the tokens Git sources are
+// cloned with. Values are write-only.
+type SecretHandler struct {
+	secrets store.SecretStore
+}
+
+// NewSecretHandler creates a new SecretHandler.
+func NewSecretHandler(secrets store.SecretStore) *SecretHandler {
+	return &SecretHandler{secrets: secrets}
+}
+
+// secretRequest is the body of PUT /api/v1/secrets/:name.
+type secretRequest struct {
+	Value       string `json:"value"`
+	Description string `json:"description"`
+}
+
+// List handles GET /api/v1/secrets.
+//
+//	@Summary	List secrets
+//	@Tags		Secrets
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Success	200	{array}	store.Secret
+//	@Router		/api/v1/secrets [get]
+func (h *SecretHandler) List(c echo.Context) error {
+	secrets, err := h.secrets.List(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to list secrets"})
+	}
+	return c.JSON(http.StatusOK, secrets)
+}
+
+// Set handles PUT /api/v1/secrets/:name.
+//
+//	@Summary		Create or replace a secret
+//	@Description	Saves the value under name, replacing any previous one. The value is encrypted at rest and never returned.
+//	@Tags			Secrets
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			name	path		string				true	"Secret name"
+//	@Param			body	body		APISecretRequest	true	"Secret"
+//	@Success		200		{object}	store.Secret
+//	@Failure		400		{object}	APIError
+//	@Router			/api/v1/secrets/{name} [put]
+func (h *SecretHandler) Set(c echo.Context) error {
+	name := c.Param("name")
+	if !secretNameRe.MatchString(name) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid secret name"})
+	}
+	var req secretRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if req.Value == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "value is required"})
+	}
+	secret := &store.Secret{Name: name, Value: req.Value, Description: req.Description}
+	if err := h.secrets.Set(c.Request().Context(), secret); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to save secret"})
+	}
+	return c.JSON(http.StatusOK, secret)
+}
+
+// Delete handles DELETE /api/v1/secrets/:name. Rebuilds of artifacts whose
+// Git source names the secret fail to start afterwards.
+//
+//	@Summary	Delete a secret
+//	@Tags		Secrets
+//	@Security	AdminBearer
+//	@Param		name	path	string	true	"Secret name"
+//	@Success	204
+//	@Router		/api/v1/secrets/{name} [delete]
+func (h *SecretHandler) Delete(c echo.Context) error {
+	if err := h.secrets.Delete(c.Request().Context(), c.Param("name")); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to delete secret"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,587 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// maxTemplateBytes caps an imported template document.
+const maxTemplateBytes = 1024 * 1024
+
+// templatePlaceholderRe matches a parameter reference, ${{ name }}, in a
+// string value of a template spec. The "${{" opener stays clear of shell
+// ${VAR} expansion in Dockerfiles and of the {{ }} templating Kairos
+// cloud-configs support.
+var templatePlaceholderRe = regexp.MustCompile(`\$\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+
+var templateParameterNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]{0,63}$`)
+
+// buildTemplateDocument is both the JSON body of the create and update
+// endpoints and the YAML document import and export exchange. Spec is a
+// partial create-artifact request.
+type buildTemplateDocument struct {
+	Name        string                   `json:"name" yaml:"name"`
+	Description string                   `json:"description,omitempty" yaml:"description,omitempty"`
+	Parameters  []templateParameterField `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Spec        map[string]interface{}   `json:"spec" yaml:"spec"`
+}
+
+type templateParameterField struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Default     string `json:"default,omitempty" yaml:"default,omitempty"`
+	Required    bool   `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+// buildFromTemplateRequest is the body of POST /api/v1/build-templates/:id/build.
+type buildFromTemplateRequest struct {
+	Name       string                 `json:"name"`
+	Parameters map[string]string      `json:"parameters"`
+	Overrides  map[string]interface{} `json:"overrides"`
+}
+
+// BuildTemplateHandler serves build templates: vetted, parameterised build
+// recipes stored on the server so every admin and CI job builds from the
+// same one.
+type BuildTemplateHandler struct {
+	artifacts *ArtifactHandler
+	templates store.BuildTemplateStore
+}
+
+// NewBuildTemplateHandler creates a new BuildTemplateHandler. Builds are
+// started through artifacts, exactly like a POST /api/v1/artifacts of the
+// rendered request.
+func NewBuildTemplateHandler(artifacts *ArtifactHandler, templates store.BuildTemplateStore) *BuildTemplateHandler {
+	return &BuildTemplateHandler{artifacts: artifacts, templates: templates}
+}
+
+// List handles GET /api/v1/build-templates.
+//
+//	@Summary	List build templates
+//	@Tags		Artifacts
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Success	200	{array}	APIBuildTemplate
+//	@Router		/api/v1/build-templates [get]
+func (h *BuildTemplateHandler) List(c echo.Context) error {
+	tpls, err := h.templates.List(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to list build templates"})
+	}
+	out := make([]APIBuildTemplate, 0, len(tpls))
+	for _, tpl := range tpls {
+		view, err := buildTemplateView(tpl)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "stored build template is unreadable"})
+		}
+		out = append(out, view)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// Get handles GET /api/v1/build-templates/:id.
+//
+//	@Summary	Get a build template
+//	@Tags		Artifacts
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Param		id	path		string	true	"Template ID"
+//	@Success	200	{object}	APIBuildTemplate
+//	@Failure	404	{object}	APIError
+//	@Router		/api/v1/build-templates/{id} [get]
+func (h *BuildTemplateHandler) Get(c echo.Context) error {
+	tpl, err := h.templates.GetByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "build template not found"})
+	}
+	view, err := buildTemplateView(tpl)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "stored build template is unreadable"})
+	}
+	return c.JSON(http.StatusOK, view)
+}
+
+// Create handles POST /api/v1/build-templates.
+//
+//	@Summary		Create a build template
+//	@Description	Stores a partial build request under a unique name. String values of the spec may reference the declared parameters as ${{ name }}.
+//	@Tags			Artifacts
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			body	body		APIBuildTemplateRequest	true	"Template"
+//	@Success		201		{object}	APIBuildTemplate
+//	@Failure		400		{object}	APIError
+//	@Failure		409		{object}	APIError
+//	@Router			/api/v1/build-templates [post]
+func (h *BuildTemplateHandler) Create(c echo.Context) error {
+	var doc buildTemplateDocument
+	if err := c.Bind(&doc); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	tpl, err := doc.toTemplate()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	ctx := c.Request().Context()
+	if _, err := h.templates.GetByName(ctx, tpl.Name); err == nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "a build template with this name already exists"})
+	}
+	if err := h.templates.Create(ctx, tpl); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create build template"})
+	}
+	view, _ := buildTemplateView(tpl)
+	return c.JSON(http.StatusCreated, view)
+}
+
+// Update handles PUT /api/v1/build-templates/:id.
+//
+//	@Summary		Replace a build template
+//	@Description	Replaces the name, description, parameters and spec. Builds already started from the template are not affected.
+//	@Tags			Artifacts
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			id		path		string					true	"Template ID"
+//	@Param			body	body		APIBuildTemplateRequest	true	"Template"
+//	@Success		200		{object}	APIBuildTemplate
+//	@Failure		400		{object}	APIError
+//	@Failure		404		{object}	APIError
+//	@Failure		409		{object}	APIError
+//	@Router			/api/v1/build-templates/{id} [put]
+func (h *BuildTemplateHandler) Update(c echo.Context) error {
+	ctx := c.Request().Context()
+	existing, err := h.templates.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "build template not found"})
+	}
+	var doc buildTemplateDocument
+	if err := c.Bind(&doc); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	tpl, err := doc.toTemplate()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if other, err := h.templates.GetByName(ctx, tpl.Name); err == nil && other.ID != existing.ID {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "a build template with this name already exists"})
+	}
+	tpl.ID, tpl.CreatedAt = existing.ID, existing.CreatedAt
+	if err := h.templates.Update(ctx, tpl); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update build template"})
+	}
+	view, _ := buildTemplateView(tpl)
+	return c.JSON(http.StatusOK, view)
+}
+
+// Delete handles DELETE /api/v1/build-templates/:id.
+//
+//	@Summary	Delete a build template
+//	@Tags		Artifacts
+//	@Security	AdminBearer
+//	@Param		id	path	string	true	"Template ID"
+//	@Success	204
+//	@Router		/api/v1/build-templates/{id} [delete]
+func (h *BuildTemplateHandler) Delete(c echo.Context) error {
+	if err := h.templates.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to delete build template"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Build handles POST /api/v1/build-templates/:id/build.
+//
+//	@Summary		Build from a template
+//	@Description	Renders the template and starts a build from it. Overrides is a partial build request merged over the spec: objects merge key by key, a cloudConfig override is merged into the template's cloud-config as YAML, and any other value replaces the template's. Parameters are then substituted into every string value; a required parameter without a value, or an undeclared one, is a 400.
+//	@Tags			Artifacts
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			id		path		string						true	"Template ID"
+//	@Param			body	body		APIBuildFromTemplateRequest	true	"Parameters and overrides"
+//	@Success		201		{object}	store.ArtifactRecord
+//	@Failure		400		{object}	APIError
+//	@Failure		404		{object}	APIError
+//	@Failure		501		{object}	APIError
+//	@Router			/api/v1/build-templates/{id}/build [post]
+func (h *BuildTemplateHandler) Build(c echo.Context) error {
+	ctx := c.Request().Context()
+	tpl, err := h.templates.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "build template not found"})
+	}
+	var req buildFromTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	build, err := renderBuildTemplate(tpl, req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	status, err := h.artifacts.startBuild(ctx, build)
+	if err != nil {
+		return startBuildError(c, err)
+	}
+	if err := h.templates.Attach(ctx, tpl.ID, status.ID); err != nil {
+		fmt.Fprintf(os.Stderr, "build template %s: link build %s: %v\n", tpl.ID, status.ID, err)
+	}
+	return c.JSON(http.StatusCreated, status)
+}
+
+// Export handles GET /api/v1/build-templates/:id/export.
+//
+//	@Summary		Export a build template as YAML
+//	@Description	Returns the template as a YAML document that POST /api/v1/build-templates/import accepts, for keeping recipes in version control or moving them between servers.
+//	@Tags			Artifacts
+//	@Produce		application/yaml
+//	@Security		AdminBearer
+//	@Param			id	path		string	true	"Template ID"
+//	@Success		200	{string}	string
+//	@Failure		404	{object}	APIError
+//	@Router			/api/v1/build-templates/{id}/export [get]
+func (h *BuildTemplateHandler) Export(c echo.Context) error {
+	tpl, err := h.templates.GetByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "build template not found"})
+	}
+	doc, err := buildTemplateToDocument(tpl)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "stored build template is unreadable"})
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to encode build template"})
+	}
+	return c.Blob(http.StatusOK, "application/yaml", buf.Bytes())
+}
+
+// Import handles POST /api/v1/build-templates/import.
+//
+//	@Summary		Import a build template from YAML
+//	@Description	Creates the template in the YAML document, or replaces the one with the same name. Responds 201 when it was created and 200 when it replaced one.
+//	@Tags			Artifacts
+//	@Accept			application/yaml
+//	@Produce		json
+//	@Security		AdminBearer
+//	@Param			body	body		APIBuildTemplateRequest	true	"Template document"
+//	@Success		200		{object}	APIBuildTemplate
+//	@Success		201		{object}	APIBuildTemplate
+//	@Failure		400		{object}	APIError
+//	@Router			/api/v1/build-templates/import [post]
+func (h *BuildTemplateHandler) Import(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxTemplateBytes))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "failed to read template"})
+	}
+	var doc buildTemplateDocument
+	dec := yaml.NewDecoder(bytes.NewReader(body))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid template document: %v", err)})
+	}
+	tpl, err := doc.toTemplate()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	code := http.StatusCreated
+	if existing, err := h.templates.GetByName(ctx, tpl.Name); err == nil {
+		tpl.ID, tpl.CreatedAt = existing.ID, existing.CreatedAt
+		if err := h.templates.Update(ctx, tpl); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update build template"})
+		}
+		code = http.StatusOK
+	} else if err := h.templates.Create(ctx, tpl); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create build template"})
+	}
+	view, _ := buildTemplateView(tpl)
+	return c.JSON(code, view)
+}
+
+// toTemplate validates the document and converts it to a stored template:
+// the spec must be a well-formed partial build request, and every
+// parameter it references must be declared.
+func (doc buildTemplateDocument) toTemplate() (*store.BuildTemplate, error) {
+	name := strings.TrimSpace(doc.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(name) > 128 {
+		return nil, errors.New("name must be at most 128 characters")
+	}
+
+	declared := map[string]bool{}
+	params := make([]store.TemplateParameter, 0, len(doc.Parameters))
+	for _, p := range doc.Parameters {
+		if !templateParameterNameRe.MatchString(p.Name) {
+			return nil, fmt.Errorf("invalid parameter name %q", p.Name)
+		}
+		if declared[p.Name] {
+			return nil, fmt.Errorf("parameter %q is declared twice", p.Name)
+		}
+		declared[p.Name] = true
+		params = append(params, store.TemplateParameter(p))
+	}
+
+	spec := doc.Spec
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	for _, ref := range templatePlaceholders(spec) {
+		if !declared[ref] {
+			return nil, fmt.Errorf("spec references undeclared parameter %q", ref)
+		}
+	}
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+	if _, err := decodeBuildRequest(raw); err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+	return &store.BuildTemplate{
+		Name:        name,
+		Description: doc.Description,
+		Parameters:  params,
+		Spec:        string(raw),
+	}, nil
+}
+
+func buildTemplateToDocument(tpl *store.BuildTemplate) (buildTemplateDocument, error) {
+	doc := buildTemplateDocument{Name: tpl.Name, Description: tpl.Description}
+	for _, p := range tpl.Parameters {
+		doc.Parameters = append(doc.Parameters, templateParameterField(p))
+	}
+	if tpl.Spec != "" {
+		if err := json.Unmarshal([]byte(tpl.Spec), &doc.Spec); err != nil {
+			return doc, err
+		}
+	}
+	return doc, nil
+}
+
+func buildTemplateView(tpl *store.BuildTemplate) (APIBuildTemplate, error) {
+	view := APIBuildTemplate{
+		ID:          tpl.ID,
+		Name:        tpl.Name,
+		Description: tpl.Description,
+		Parameters:  tpl.Parameters,
+		CreatedAt:   tpl.CreatedAt,
+		UpdatedAt:   tpl.UpdatedAt,
+	}
+	if tpl.Spec != "" {
+		if err := json.Unmarshal([]byte(tpl.Spec), &view.Spec); err != nil {
+			return view, err
+		}
+	}
+	return view, nil
+}
+
+// renderBuildTemplate turns a template plus the caller's parameters and
+// overrides into the create request to start.
+func renderBuildTemplate(tpl *store.BuildTemplate, req buildFromTemplateRequest) (createArtifactRequest, error) {
+	spec := map[string]interface{}{}
+	if tpl.Spec != "" {
+		if err := json.Unmarshal([]byte(tpl.Spec), &spec); err != nil {
+			return createArtifactRequest{}, errors.New("stored build template is unreadable")
+		}
+	}
+	if err := mergeTemplateOverrides(spec, req.Overrides); err != nil {
+		return createArtifactRequest{}, err
+	}
+
+	values := map[string]string{}
+	declared := map[string]bool{}
+	for _, p := range tpl.Parameters {
+		declared[p.Name] = true
+		v, ok := req.Parameters[p.Name]
+		if !ok {
+			if p.Required {
+				return createArtifactRequest{}, fmt.Errorf("parameter %q is required", p.Name)
+			}
+			v = p.Default
+		}
+		values[p.Name] = v
+	}
+	for name := range req.Parameters {
+		if !declared[name] {
+			return createArtifactRequest{}, fmt.Errorf("the template has no parameter %q", name)
+		}
+	}
+	for _, ref := range templatePlaceholders(spec) {
+		if !declared[ref] {
+			return createArtifactRequest{}, fmt.Errorf("overrides reference undeclared parameter %q", ref)
+		}
+	}
+	rendered := substituteTemplateParameters(spec, values)
+
+	raw, err := json.Marshal(rendered)
+	if err != nil {
+		return createArtifactRequest{}, err
+	}
+	build, err := decodeBuildRequest(raw)
+	if err != nil {
+		return createArtifactRequest{}, fmt.Errorf("invalid overrides: %w", err)
+	}
+	switch {
+	case req.Name != "":
+		build.Name = req.Name
+	case build.Name == "":
+		build.Name = tpl.Name
+	}
+	return build, nil
+}
+
+// decodeBuildRequest decodes a create request, rejecting unknown fields so a
+// typo in a template fails when it is saved rather than silently building
+// something else.
+func decodeBuildRequest(raw []byte) (createArtifactRequest, error) {
+	var req createArtifactRequest
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// mergeTemplateOverrides merges overrides into spec. Objects merge key by
+// key; a cloudConfig string is merged into the template's as YAML so a
+// build can add a fragment without restating the template's; anything else
+// replaces the template's value.
+func mergeTemplateOverrides(spec, overrides map[string]interface{}) error {
+	for k, ov := range overrides {
+		sv, exists := spec[k]
+		if !exists {
+			spec[k] = ov
+			continue
+		}
+		if svMap, ok := sv.(map[string]interface{}); ok {
+			if ovMap, ok := ov.(map[string]interface{}); ok {
+				if err := mergeTemplateOverrides(svMap, ovMap); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if k == "cloudConfig" {
+			base, _ := sv.(string)
+			extra, ok := ov.(string)
+			if !ok {
+				return errors.New("overrides.cloudConfig must be a string")
+			}
+			merged, err := mergeCloudConfigFragments(base, extra)
+			if err != nil {
+				return err
+			}
+			spec[k] = merged
+			continue
+		}
+		spec[k] = ov
+	}
+	return nil
+}
+
+// mergeCloudConfigFragments merges the extra cloud-config YAML into base
+// with the same rules buildCloudConfig applies to the extra YAML of a build.
+func mergeCloudConfigFragments(base, extra string) (string, error) {
+	parse := func(s string) (map[string]interface{}, error) {
+		s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "#cloud-config"))
+		doc := map[string]interface{}{}
+		if s == "" {
+			return doc, nil
+		}
+		if err := yaml.Unmarshal([]byte(s), &doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+	baseDoc, err := parse(base)
+	if err != nil {
+		return "", fmt.Errorf("template cloudConfig is not valid YAML: %w", err)
+	}
+	extraDoc, err := parse(extra)
+	if err != nil {
+		return "", fmt.Errorf("overrides.cloudConfig is not valid YAML: %w", err)
+	}
+	mergeYAML(baseDoc, extraDoc)
+	if len(baseDoc) == 0 {
+		return "", nil
+	}
+	out, err := yaml.Marshal(baseDoc)
+	if err != nil {
+		return "", err
+	}
+	return "#cloud-config\n" + string(out), nil
+}
+
+// templatePlaceholders returns the parameter names referenced anywhere in v,
+// sorted and without duplicates.
+func templatePlaceholders(v interface{}) []string {
+	seen := map[string]bool{}
+	var walk func(interface{})
+	walk = func(v interface{}) {
+		switch t := v.(type) {
+		case string:
+			for _, m := range templatePlaceholderRe.FindAllStringSubmatch(t, -1) {
+				seen[m[1]] = true
+			}
+		case map[string]interface{}:
+			for _, e := range t {
+				walk(e)
+			}
+		case []interface{}:
+			for _, e := range t {
+				walk(e)
+			}
+		}
+	}
+	walk(v)
+	names := make([]string, 0, len(seen))
+	for n := range seen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// substituteTemplateParameters returns a copy of v with every placeholder in
+// its string values replaced by the parameter's value.
+func substituteTemplateParameters(v interface{}, values map[string]string) interface{} {
+	switch t := v.(type) {
+	case string:
+		return templatePlaceholderRe.ReplaceAllStringFunc(t, func(m string) string {
+			return values[templatePlaceholderRe.FindStringSubmatch(m)[1]]
+		})
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			out[k] = substituteTemplateParameters(e, values)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, e := range t {
+			out[i] = substituteTemplateParameters(e, values)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,123 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("BuildTemplateHandler", func() {
+	var (
+		e         *echo.Echo
+		fb        *fakeBuilder
+		templates *fakeBuildTemplateStore
+		handler   *handlers.BuildTemplateHandler
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		fb = &fakeBuilder{}
+		templates = newFakeBuildTemplateStore()
+		ah := handlers.NewArtifactHandler(fb, &fakeArtifactStore{}, nil, nil, "", "reg-token", "http://localhost:8080")
+		handler = handlers.NewBuildTemplateHandler(ah, templates)
+	})
+
+	call := func(fn echo.HandlerFunc, method, body, contentType, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/build-templates", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if id != "" {
+			c.SetParamNames("id")
+			c.SetParamValues(id)
+		}
+		Expect(fn(c)).To(Succeed())
+		return rec
+	}
+
+	const edge = `{"name": "edge", "description": "vetted edge image",
+		"parameters": [{"name": "version", "default": "v4.1.2"}, {"name": "site", "required": true}],
+		"spec": {"baseImage": "ubuntu:24.04", "kairosVersion": "${{ version }}",
+			"outputs": {"iso": true}, "signing": {"ukiKeySetId": "ks-1"},
+			"cloudConfig": "#cloud-config\nhostname: edge-${{site}}\n"}}`
+
+	create := func(body string) (*httptest.ResponseRecorder, handlers.APIBuildTemplate) {
+		rec := call(handler.Create, http.MethodPost, body, "application/json", "")
+		var out handlers.APIBuildTemplate
+		if rec.Code == http.StatusCreated {
+			Expect(json.Unmarshal(rec.Body.Bytes(), &out)).To(Succeed())
+		}
+		return rec, out
+	}
+
+	It("validates templates on save", func() {
+		rec, _ := create(edge)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+
+		rec, _ = create(edge)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		rec, _ = create(`{"name": "typo", "spec": {"baseImag": "ubuntu:24.04"}}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		rec, _ = create(`{"name": "undeclared", "spec": {"baseImage": "ubuntu:${{ tag }}"}}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("tag"))
+		rec, _ = create(`{"spec": {}}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("builds with parameters and overrides and links the build", func() {
+		_, tpl := create(edge)
+
+		build := func(body string) *httptest.ResponseRecorder {
+			return call(handler.Build, http.MethodPost, body, "application/json", tpl.ID)
+		}
+		Expect(build(`{}`).Code).To(Equal(http.StatusBadRequest))
+		Expect(build(`{"parameters": {"site": "a", "color": "red"}}`).Code).To(Equal(http.StatusBadRequest))
+		Expect(fb.builds).To(BeEmpty())
+
+		rec := build(`{"parameters": {"site": "berlin"},
+			"overrides": {"outputs": {"rawDisk": true}, "cloudConfig": "users:\n- name: ops\n"}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+
+		Expect(fb.lastOpts.Name).To(Equal("edge"))
+		Expect(fb.lastOpts.KairosVersion).To(Equal("v4.1.2"))
+		Expect(fb.lastOpts.Outputs.ISO).To(BeTrue())
+		Expect(fb.lastOpts.Outputs.RawDisk).To(BeTrue())
+		Expect(fb.lastOpts.CloudConfig).To(ContainSubstring("hostname: edge-berlin"))
+		Expect(fb.lastOpts.CloudConfig).To(ContainSubstring("name: ops"))
+		Expect(templates.links).To(HaveKeyWithValue(fb.builds[0].ID, tpl.ID))
+	})
+
+	It("round-trips through YAML export and import", func() {
+		_, tpl := create(edge)
+
+		rec := call(handler.Export, http.MethodGet, "", "", tpl.ID)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/yaml"))
+		doc := rec.Body.String()
+		Expect(doc).To(ContainSubstring("kairosVersion: ${{ version }}"))
+
+		// Importing the same name replaces the template in place.
+		edited := strings.Replace(doc, "vetted edge image", "edited", 1)
+		rec = call(handler.Import, http.MethodPost, edited, "application/yaml", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		got, err := templates.GetByID(context.Background(), tpl.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Description).To(Equal("edited"))
+
+		rec = call(handler.Import, http.MethodPost, strings.Replace(doc, "name: edge", "name: edge-copy", 1), "application/yaml", "")
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(templates.templates).To(HaveLen(2))
+
+		rec = call(handler.Import, http.MethodPost, "name: bad\nspecs: {}\n", "application/yaml", "")
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+})
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/kairos-io/AuroraBoot/pkg/gitsource"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+// maxWebhookBytes caps a push payload. Forges send at most a few hundred
+// KiB even for large pushes.
+const maxWebhookBytes = 5 * 1024 * 1024
+
+// zeroCommit is the "after" of a push that deleted its ref.
+const zeroCommit = "0000000000000000000000000000000000000000"
+
+// GitWebhookHandler rebuilds artifacts with a Git source when their ref is
+// pushed to. It understands the push events of GitHub, Gitea/Forgejo and
+// GitLab.
+type GitWebhookHandler struct {
+	artifacts *ArtifactHandler
+	secret    string
+}
+
+// NewGitWebhookHandler creates a new GitWebhookHandler. Deliveries must be
+// signed with (GitHub, Gitea) or carry (GitLab) secret. Rebuilds are started
+// through artifacts, which needs a spec store to know what to rebuild.
+func NewGitWebhookHandler(artifacts *ArtifactHandler, secret string) *GitWebhookHandler {
+	return &GitWebhookHandler{artifacts: artifacts, secret: secret}
+}
+
+// gitPushEvent is the subset of a push payload the handler reads. GitHub
+// and Gitea fill Repository.CloneURL and friends; GitLab fills the git_*
+// URLs and reports the default branch under Project.
+type gitPushEvent struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Repository struct {
+		CloneURL      string `json:"clone_url"`
+		HTMLURL       string `json:"html_url"`
+		SSHURL        string `json:"ssh_url"`
+		GitHTTPURL    string `json:"git_http_url"`
+		GitSSHURL     string `json:"git_ssh_url"`
+		Homepage      string `json:"homepage"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	Project struct {
+		DefaultBranch string `json:"default_branch"`
+	} `json:"project"`
+}
+
+func (ev *gitPushEvent) urls() []string {
+	r := ev.Repository
+	return []string{r.CloneURL, r.HTMLURL, r.SSHURL, r.GitHTTPURL, r.GitSSHURL, r.Homepage}
+}
+
+func (ev *gitPushEvent) defaultBranch() string {
+	if ev.Repository.DefaultBranch != "" {
+		return ev.Repository.DefaultBranch
+	}
+	return ev.Project.DefaultBranch
+}
+
+// matches reports whether a build of src follows the pushed ref.
+func (ev *gitPushEvent) matches(src *store.GitSource) bool {
+	sameRepo := false
+	for _, u := range ev.urls() {
+		if gitsource.SameRepository(src.URL, u) {
+			sameRepo = true
+			break
+		}
+	}
+	if !sameRepo {
+		return false
+	}
+	switch {
+	case src.Ref == ev.Ref:
+		return true
+	case src.Ref == "":
+		return ev.Ref == "refs/heads/"+ev.defaultBranch()
+	default:
+		return ev.Ref == "refs/heads/"+src.Ref || ev.Ref == "refs/tags/"+src.Ref
+	}
+}
+
+// Push handles POST /api/v1/webhooks/git.
+//
+//	@Summary		Rebuild on a Git push
+//	@Description	Receives a push event from GitHub, Gitea/Forgejo (X-Hub-Signature-256 over the body with the webhook secret) or GitLab (X-Gitlab-Token set to it). For every artifact whose Git source follows the pushed branch or tag, the most recent build is started again from its original request unless it was already built from the pushed commit.
+//	@Tags			Artifacts
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	APIGitPushResult
+//	@Failure		400	{object}	APIError
+//	@Failure		401	{object}	APIError
+//	@Router			/api/v1/webhooks/git [post]
+func (h *GitWebhookHandler) Push(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookBytes))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "failed to read payload"})
+	}
+	if !h.authorized(c.Request().Header, body) {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid webhook signature"})
+	}
+	// Forges send a ping when the hook is created; anything but a push is
+	// acknowledged and ignored.
+	if ev := c.Request().Header.Get("X-GitHub-Event"); ev != "" && ev != "push" {
+		return c.JSON(http.StatusOK, APIGitPushResult{})
+	}
+	if ev := c.Request().Header.Get("X-Gitea-Event"); ev != "" && ev != "push" {
+		return c.JSON(http.StatusOK, APIGitPushResult{})
+	}
+	if ev := c.Request().Header.Get("X-Gitlab-Event"); ev != "" && ev != "Push Hook" && ev != "Tag Push Hook" {
+		return c.JSON(http.StatusOK, APIGitPushResult{})
+	}
+
+	var ev gitPushEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid push payload"})
+	}
+	result := APIGitPushResult{Ref: ev.Ref, Commit: ev.After}
+	if ev.Ref == "" || ev.After == "" || ev.After == zeroCommit {
+		return c.JSON(http.StatusOK, result)
+	}
+
+	ctx := c.Request().Context()
+	recs, err := h.artifacts.store.List(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to list artifacts"})
+	}
+	for _, rec := range latestGitBuilds(recs, ev.matches) {
+		if rec.Git.Commit == ev.After {
+			continue
+		}
+		id, err := h.artifacts.rebuild(ctx, rec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "git webhook: rebuilding %q: %v\n", rec.ID, err)
+			result.Failed = append(result.Failed, APIGitPushFailure{ArtifactID: rec.ID, Error: err.Error()})
+			continue
+		}
+		result.Builds = append(result.Builds, id)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// authorized checks a delivery against the webhook secret.
+func (h *GitWebhookHandler) authorized(header http.Header, body []byte) bool {
+	if h.secret == "" {
+		return false
+	}
+	if token := header.Get("X-Gitlab-Token"); token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) == 1
+	}
+	sig := strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+	if sig == "" {
+		sig = header.Get("X-Gitea-Signature")
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil || len(got) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(h.secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// rebuild starts rec again from its stored request and returns the new
+// build's ID.
+func (h *ArtifactHandler) rebuild(ctx context.Context, rec *store.ArtifactRecord) (string, error) {
+	req, err := h.storedRequest(ctx, rec)
+	if err != nil {
+		return "", err
+	}
+	status, err := h.startBuild(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return status.ID, nil
+}
+
+// storedRequest returns the request rec was created from.
+func (h *ArtifactHandler) storedRequest(ctx context.Context, rec *store.ArtifactRecord) (createArtifactRequest, error) {
+	var req createArtifactRequest
+	if h.specs == nil {
+		return req, errors.New("build requests are not kept on this server")
+	}
+	spec, err := h.specs.GetSpec(ctx, rec.ID)
+	if err != nil {
+		return req, fmt.Errorf("reading request: %w", err)
+	}
+	if spec == "" {
+		return req, errors.New("the build predates kept requests; start it again once by hand")
+	}
+	if err := json.Unmarshal([]byte(spec), &req); err != nil {
+		return req, fmt.Errorf("decoding request: %w", err)
+	}
+	return req, nil
+}
+
+// latestGitBuilds returns, for each distinct build name and Git source that
+// match accepts, its most recent artifact. Older builds of the same thing
+// are history, not separate things to rebuild.
+func latestGitBuilds(recs []*store.ArtifactRecord, match func(*store.GitSource) bool) []*store.ArtifactRecord {
+	type key struct{ name, url, ref, subpath string }
+	latest := map[key]*store.ArtifactRecord{}
+	var order []key
+	for _, rec := range recs {
+		if rec.Git == nil || !match(rec.Git) {
+			continue
+		}
+		k := key{rec.Name, rec.Git.URL, rec.Git.Ref, rec.Git.Subpath}
+		prev, ok := latest[k]
+		if !ok {
+			order = append(order, k)
+		}
+		if !ok || rec.CreatedAt.After(prev.CreatedAt) {
+			latest[k] = rec
+		}
+	}
+	out := make([]*store.ArtifactRecord, 0, len(order))
+	for _, k := range order {
+		out = append(out, latest[k])
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/handlers"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+var _ = Describe("Git sources", func() {
+	const webhookSecret = "hook-secret"
+
+	var (
+		e         *echo.Echo
+		fb        *fakeBuilder
+		artifacts *fakeArtifactStore
+		secrets   *fakeSecretStore
+		specs     *fakeArtifactSpecStore
+		ah        *handlers.ArtifactHandler
+		webhook   *handlers.GitWebhookHandler
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		fb = &fakeBuilder{}
+		artifacts = &fakeArtifactStore{}
+		secrets = newFakeSecretStore()
+		specs = newFakeArtifactSpecStore()
+		ah = handlers.NewArtifactHandler(fb, artifacts, nil, nil, "", "reg-token", "http://localhost:8080").
+			WithSecrets(secrets).
+			WithSpecs(specs)
+		webhook = handlers.NewGitWebhookHandler(ah, webhookSecret)
+	})
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/artifacts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		Expect(ah.Create(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	push := func(body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/git", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		Expect(webhook.Push(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	signed := func(body string) map[string]string {
+		mac := hmac.New(sha256.New, []byte(webhookSecret))
+		mac.Write([]byte(body))
+		return map[string]string{
+			"X-GitHub-Event":      "push",
+			"X-Hub-Signature-256": "sha256=" + hex.EncodeToString(mac.Sum(nil)),
+		}
+	}
+
+	const gitBuild = `{"name": "edge", "outputs": {"iso": true},
+		"git": {"url": "https://github.com/acme/images.git", "ref": "main", "subpath": "edge", "secret": "gh"}}`
+
+	It("resolves the secret, redacts it and records the source", func() {
+		Expect(secrets.Set(context.Background(), &store.Secret{Name: "gh", Value: "ghp_token"})).To(Succeed())
+		rec := create(gitBuild)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+
+		Expect(fb.lastOpts.Git).NotTo(BeNil())
+		Expect(fb.lastOpts.Git.Password).To(Equal("ghp_token"))
+		Expect(fb.lastOpts.Git.Subpath).To(Equal("edge"))
+		Expect(fb.lastOpts.LogRedactValues).To(ContainElement("ghp_token"))
+
+		Expect(artifacts.records).To(HaveLen(1))
+		Expect(artifacts.records[0].Git).To(Equal(&store.GitSource{
+			URL: "https://github.com/acme/images.git", Ref: "main", Subpath: "edge", Secret: "gh",
+		}))
+		Expect(specs.specs[artifacts.records[0].ID]).NotTo(ContainSubstring("ghp_token"))
+	})
+
+	It("rejects unknown secrets and unsafe sources", func() {
+		Expect(create(gitBuild).Code).To(Equal(http.StatusBadRequest))
+		Expect(create(`{"git": {"url": "https://github.com/acme/images.git", "subpath": "../etc"}}`).Code).To(Equal(http.StatusBadRequest))
+		Expect(fb.builds).To(BeEmpty())
+	})
+
+	Describe("push webhook", func() {
+		const payload = `{"ref": "refs/heads/main", "after": "bbbb",
+			"repository": {"clone_url": "https://github.com/acme/images.git", "default_branch": "main"}}`
+
+		BeforeEach(func() {
+			Expect(secrets.Set(context.Background(), &store.Secret{Name: "gh", Value: "ghp_token"})).To(Succeed())
+			Expect(create(gitBuild).Code).To(Equal(http.StatusCreated))
+			Expect(create(`{"name": "other", "git": {"url": "https://github.com/acme/images.git", "ref": "dev"}}`).Code).To(Equal(http.StatusCreated))
+			artifacts.records[0].Git.Commit = "aaaa"
+			artifacts.records[0].CreatedAt = time.Now().Add(-time.Hour)
+		})
+
+		It("rejects unsigned deliveries", func() {
+			Expect(push(payload, nil).Code).To(Equal(http.StatusUnauthorized))
+			Expect(push(payload, map[string]string{"X-Hub-Signature-256": "sha256=00"}).Code).To(Equal(http.StatusUnauthorized))
+			Expect(fb.builds).To(HaveLen(2))
+		})
+
+		It("rebuilds the latest build following the pushed branch", func() {
+			rec := push(payload, signed(payload))
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var out handlers.APIGitPushResult
+			Expect(json.Unmarshal(rec.Body.Bytes(), &out)).To(Succeed())
+			Expect(out.Builds).To(HaveLen(1))
+			Expect(out.Failed).To(BeEmpty())
+
+			Expect(fb.builds).To(HaveLen(3))
+			Expect(fb.lastOpts.Name).To(Equal("edge"))
+			Expect(fb.lastOpts.Git.Password).To(Equal("ghp_token"))
+
+			// The rebuild is now the latest; once it has built the pushed
+			// commit, a redelivery starts nothing.
+			rebuilt, err := artifacts.GetByID(context.Background(), out.Builds[0])
+			Expect(err).NotTo(HaveOccurred())
+			rebuilt.Git.Commit = "bbbb"
+			rebuilt.CreatedAt = time.Now()
+			Expect(push(payload, signed(payload)).Code).To(Equal(http.StatusOK))
+			Expect(fb.builds).To(HaveLen(3))
+		})
+
+		It("accepts GitLab's token header and ignores other events", func() {
+			gitlab := `{"ref": "refs/heads/dev", "after": "cccc",
+				"repository": {"git_http_url": "https://github.com/acme/images"}, "project": {"default_branch": "main"}}`
+			rec := push(gitlab, map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": webhookSecret})
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(fb.builds).To(HaveLen(3))
+			Expect(fb.lastOpts.Name).To(Equal("other"))
+
+			ping := signed(`{}`)
+			ping["X-GitHub-Event"] = "ping"
+			Expect(push(`{}`, ping).Code).To(Equal(http.StatusOK))
+			Expect(fb.builds).To(HaveLen(3))
+		})
+	})
+})
+
+var _ = Describe("SecretHandler", func() {
+	var (
+		e       *echo.Echo
+		secrets *fakeSecretStore
+		handler *handlers.SecretHandler
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		secrets = newFakeSecretStore()
+		handler = handlers.NewSecretHandler(secrets)
+	})
+
+	set := func(name, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/secrets/"+name, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("name")
+		c.SetParamValues(name)
+		Expect(handler.Set(c)).To(Succeed())
+		return rec
+	}
+
+	It("stores the value without returning it", func() {
+		rec := set("gh", `{"value": "ghp_token", "description": "CI"}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).NotTo(ContainSubstring("ghp_token"))
+		got, err := secrets.Get(context.Background(), "gh")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Value).To(Equal("ghp_token"))
+	})
+
+	It("validates the name and value", func() {
+		Expect(set("..", `{"value": "x"}`).Code).To(Equal(http.StatusBadRequest))
+		Expect(set("gh", `{"value": ""}`).Code).To(Equal(http.StatusBadRequest))
+	})
+})
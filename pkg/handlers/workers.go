@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kairos-io/AuroraBoot/pkg/auth"
+	"github.com/kairos-io/AuroraBoot/pkg/builder"
+	"github.com/kairos-io/AuroraBoot/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+// workerAPIKeyBytes is the entropy of a minted worker API key, matching the
+// per-build upload token.
+const workerAPIKeyBytes = 32
+
+// maxWorkerLogChunk caps a single log POST from a worker. The worker flushes
+// every few KiB, so anything near this size is a misbehaving client.
+const maxWorkerLogChunk = 1 << 20
+
+// WorkerQueue is the server half of the remote build-worker protocol. It is
+// satisfied by the worker backend (internal/builder/worker.Builder); the
+// handler only translates HTTP to these calls and maps builder.ErrLeaseLost
+// to 409 so the worker knows to abandon the build.
+type WorkerQueue interface {
+	Lease(ctx context.Context, workerID, arch string) (*builder.WorkerLease, error)
+	Heartbeat(ctx context.Context, workerID, buildID string) error
+	AppendLog(ctx context.Context, workerID, buildID, text string) error
+	Finish(ctx context.Context, workerID, buildID string, res builder.WorkerResult) error
+}
+
+// WorkerHandler serves worker registration, the worker lease protocol and
+// the admin worker list.
+type WorkerHandler struct {
+	workers store.BuildWorkerStore
+	queue   WorkerQueue
+}
+
+// NewWorkerHandler creates a new WorkerHandler.
+func NewWorkerHandler(workers store.BuildWorkerStore, queue WorkerQueue) *WorkerHandler {
+	return &WorkerHandler{workers: workers, queue: queue}
+}
+
+// Register handles POST /api/v1/workers/register.
+//
+//	@Summary		Register a build worker
+//	@Description	Enrolls an `auroraboot worker` process. Authenticated by the worker registration token inside the request body. The returned apiKey is shown once; only its digest is stored.
+//	@Tags			Workers
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		APIWorkerRegisterRequest	true	"Registration payload"
+//	@Success		201		{object}	APIWorkerRegisterResponse
+//	@Failure		400		{object}	APIError
+//	@Failure		401		{object}	APIError
+//	@Router			/api/v1/workers/register [post]
+func (h *WorkerHandler) Register(c echo.Context) error {
+	var req APIWorkerRegisterRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "name is required"})
+	}
+	if req.Arch != "amd64" && req.Arch != "arm64" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "arch must be 'amd64' or 'arm64'"})
+	}
+
+	b := make([]byte, workerAPIKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to mint api key"})
+	}
+	apiKey := hex.EncodeToString(b)
+
+	now := time.Now()
+	w := &store.BuildWorker{
+		ID:            uuid.New().String(),
+		Name:          req.Name,
+		Arch:          req.Arch,
+		Version:       req.Version,
+		LastHeartbeat: &now,
+		APIKeyHash:    auth.HashWorkerAPIKey(apiKey),
+	}
+	if err := h.workers.Register(c.Request().Context(), w); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to register worker"})
+	}
+	return c.JSON(http.StatusCreated, APIWorkerRegisterResponse{ID: w.ID, APIKey: apiKey})
+}
+
+// List handles GET /api/v1/workers.
+//
+//	@Summary	List build workers
+//	@Tags		Workers
+//	@Produce	json
+//	@Security	AdminBearer
+//	@Success	200	{array}		store.BuildWorker
+//	@Failure	500	{object}	APIError
+//	@Router		/api/v1/workers [get]
+func (h *WorkerHandler) List(c echo.Context) error {
+	workers, err := h.workers.List(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to list workers"})
+	}
+	if workers == nil {
+		workers = []*store.BuildWorker{}
+	}
+	return c.JSON(http.StatusOK, workers)
+}
+
+// Delete handles DELETE /api/v1/workers/:id.
+//
+//	@Summary		Remove a build worker
+//	@Description	Revokes the worker's API key. A build it was running goes back to the queue once its lease lapses.
+//	@Tags			Workers
+//	@Security		AdminBearer
+//	@Param			id	path	string	true	"Worker ID"
+//	@Success		204
+//	@Failure		404	{object}	APIError
+//	@Router			/api/v1/workers/{id} [delete]
+func (h *WorkerHandler) Delete(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+	if _, err := h.workers.GetByID(ctx, id); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "worker not found"})
+	}
+	if err := h.workers.Delete(ctx, id); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to delete worker"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Lease handles POST /api/v1/worker/lease.
+//
+//	@Summary		Lease the next queued build
+//	@Description	Hands the oldest queued build for the worker's architecture to the calling worker. 204 means the queue is empty; poll again later. Every call also counts as a heartbeat.
+//	@Tags			Workers
+//	@Produce		json
+//	@Security		WorkerAPIKey
+//	@Success		200	{object}	builder.WorkerLease
+//	@Success		204
+//	@Failure		401	{object}	APIError
+//	@Router			/api/v1/worker/lease [post]
+func (h *WorkerHandler) Lease(c echo.Context) error {
+	ctx := c.Request().Context()
+	workerID := auth.AuthWorkerID(c)
+	w, err := h.workers.GetByID(ctx, workerID)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	lease, err := h.queue.Lease(ctx, w.ID, w.Arch)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to lease build"})
+	}
+	if lease == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+	return c.JSON(http.StatusOK, lease)
+}
+
+// Heartbeat handles POST /api/v1/worker/heartbeat.
+//
+//	@Summary		Build worker heartbeat
+//	@Description	Reports the worker alive and renews the lease of the build it is running. 409 means the lease was lost (expired or cancelled) and the worker must abandon the build.
+//	@Tags			Workers
+//	@Accept			json
+//	@Security		WorkerAPIKey
+//	@Param			body	body	APIWorkerHeartbeatRequest	true	"Current build"
+//	@Success		204
+//	@Failure		401	{object}	APIError
+//	@Failure		409	{object}	APIError
+//	@Router			/api/v1/worker/heartbeat [post]
+func (h *WorkerHandler) Heartbeat(c echo.Context) error {
+	var req APIWorkerHeartbeatRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if err := h.queue.Heartbeat(c.Request().Context(), auth.AuthWorkerID(c), req.BuildID); err != nil {
+		return leaseError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AppendLog handles POST /api/v1/worker/builds/:id/logs.
+//
+//	@Summary	Stream build output for a leased build
+//	@Tags		Workers
+//	@Accept		plain
+//	@Security	WorkerAPIKey
+//	@Param		id	path	string	true	"Build ID"
+//	@Success	204
+//	@Failure	401	{object}	APIError
+//	@Failure	409	{object}	APIError
+//	@Failure	413	{object}	APIError
+//	@Router		/api/v1/worker/builds/{id}/logs [post]
+func (h *WorkerHandler) AppendLog(c echo.Context) error {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response().Writer, c.Request().Body, maxWorkerLogChunk))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "log chunk exceeds size limit"})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "cannot read body"})
+	}
+	if err := h.queue.AppendLog(c.Request().Context(), auth.AuthWorkerID(c), c.Param("id"), string(body)); err != nil {
+		return leaseError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Finish handles POST /api/v1/worker/builds/:id/finish.
+//
+//	@Summary		Report the result of a leased build
+//	@Description	Marks the build Ready or Error and releases it from the queue. On success the worker must have uploaded every output through PUT /api/v1/artifacts/{id}/upload/{filename} first.
+//	@Tags			Workers
+//	@Accept			json
+//	@Security		WorkerAPIKey
+//	@Param			id		path	string					true	"Build ID"
+//	@Param			body	body	builder.WorkerResult	true	"Result"
+//	@Success		204
+//	@Failure		400	{object}	APIError
+//	@Failure		401	{object}	APIError
+//	@Failure		409	{object}	APIError
+//	@Router			/api/v1/worker/builds/{id}/finish [post]
+func (h *WorkerHandler) Finish(c echo.Context) error {
+	var res builder.WorkerResult
+	if err := c.Bind(&res); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if res.Phase != builder.BuildReady && res.Phase != builder.BuildError {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "phase must be 'Ready' or 'Error'"})
+	}
+	if err := h.queue.Finish(c.Request().Context(), auth.AuthWorkerID(c), c.Param("id"), res); err != nil {
+		return leaseError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// leaseError maps a WorkerQueue failure to a response: a lost lease is the
+// worker's signal to stop (409), anything else is a server fault.
+func leaseError(c echo.Context, err error) error {
+	if errors.Is(err, builder.ErrLeaseLost) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "build lease lost"})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "worker update failed"})
+}
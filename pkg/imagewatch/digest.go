@@ -0,0 +1,33 @@
+package imagewatch
+
+import (
+	"context"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// DigestFunc resolves image to the digest its tag points at now.
+type DigestFunc func(ctx context.Context, image string, insecure bool) (string, error)
+
+// Digest asks the registry for the digest image's tag points at, with a
+// HEAD on the manifest so nothing is pulled. Credentials come from the
+// default keychain (~/.docker/config.json), like the builder's. insecure
+// allows plain HTTP, for the same registries a build may pull from with
+// allow-insecure-registries.
+func Digest(ctx context.Context, image string, insecure bool) (string, error) {
+	var opts []name.Option
+	if insecure {
+		opts = append(opts, name.Insecure)
+	}
+	ref, err := name.ParseReference(image, opts...)
+	if err != nil {
+		return "", err
+	}
+	desc, err := remote.Head(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
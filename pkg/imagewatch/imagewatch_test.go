@@ -0,0 +1,86 @@
+package imagewatch_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/kairos-io/AuroraBoot/pkg/imagewatch"
+)
+
+var _ = Describe("Schedule", func() {
+	at := func(s string) time.Time {
+		t, err := time.Parse(time.RFC3339, s)
+		Expect(err).NotTo(HaveOccurred())
+		return t
+	}
+	next := func(expr, from string) time.Time {
+		s, err := imagewatch.ParseSchedule(expr)
+		Expect(err).NotTo(HaveOccurred())
+		return s.Next(at(from))
+	}
+
+	It("steps through minutes, hours and ranges", func() {
+		Expect(next("*/15 * * * *", "2026-03-01T10:07:30Z")).To(Equal(at("2026-03-01T10:15:00Z")))
+		Expect(next("0 3 * * *", "2026-03-01T03:00:00Z")).To(Equal(at("2026-03-02T03:00:00Z")))
+		Expect(next("30 9-17/4 * * *", "2026-03-01T14:00:00Z")).To(Equal(at("2026-03-01T17:30:00Z")))
+		Expect(next("@monthly", "2026-03-01T00:00:00Z")).To(Equal(at("2026-04-01T00:00:00Z")))
+	})
+
+	It("matches either day field when both are restricted", func() {
+		// 2026-03-01 is a Sunday.
+		Expect(next("0 0 * * 1-5", "2026-02-28T12:00:00Z")).To(Equal(at("2026-03-02T00:00:00Z")))
+		Expect(next("0 0 * * 7", "2026-03-01T12:00:00Z")).To(Equal(at("2026-03-08T00:00:00Z")))
+		Expect(next("0 0 15 * 3", "2026-03-01T12:00:00Z")).To(Equal(at("2026-03-04T00:00:00Z")))
+		Expect(next("0 0 30 2 *", "2026-03-01T12:00:00Z")).To(BeZero())
+	})
+
+	It("rejects malformed expressions", func() {
+		for _, expr := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "@yearly"} {
+			_, err := imagewatch.ParseSchedule(expr)
+			Expect(err).To(HaveOccurred(), expr)
+		}
+	})
+})
+
+var _ = Describe("Digest", func() {
+	It("follows a tag as it moves in a registry", func() {
+		srv := httptest.NewServer(registry.New())
+		DeferCleanup(srv.Close)
+		image := strings.TrimPrefix(srv.URL, "http://") + "/kairos/ubuntu:24.04"
+		ref, err := name.ParseReference(image)
+		Expect(err).NotTo(HaveOccurred())
+
+		push := func() string {
+			img, err := random.Image(64, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Write(ref, img)).To(Succeed())
+			d, err := img.Digest()
+			Expect(err).NotTo(HaveOccurred())
+			return d.String()
+		}
+
+		first := push()
+		got, err := imagewatch.Digest(context.Background(), image, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got).To(Equal(first))
+
+		second := push()
+		got, err = imagewatch.Digest(context.Background(), image, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got).To(Equal(second))
+		Expect(second).NotTo(Equal(first))
+
+		_, err = imagewatch.Digest(context.Background(), strings.TrimSuffix(image, "24.04")+"missing", true)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,13 @@
+package imagewatch_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestImageWatch(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ImageWatch Suite")
+}
@@ -0,0 +1,131 @@
+package ops
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/kairos-io/AuroraBoot/internal"
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// CompressedOutputs are the patterns of the outputs the compression option applies to.
+var CompressedOutputs = []string{"*.iso", "*.raw", "*.vhd"}
+
+// keptOutputs are the outputs whose uncompressed original stays next to the
+// compressed copy: the server boots machines from the ISO itself, over
+// netboot, Redfish virtual media and its ISO download URL.
+var keptOutputs = []string{"*.iso"}
+
+// Compress writes a copy of source compressed as c says, named source plus
+// the format's extension, and removes source unless keep is set. zstd
+// compresses on every CPU.
+func Compress(source string, c schema.Compression, keep bool) (string, error) {
+	name := source + c.Extension()
+	if c.Extension() == "" {
+		return name, fmt.Errorf("unsupported compression format %q", c.Format)
+	}
+	internal.Log.Logger.Info().Str("source", source).Str("format", c.Format).Msg("Compressing output")
+
+	in, err := os.Open(source)
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", source).Msg("Error opening output")
+		return name, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(name)
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", name).Msg("Error creating compressed output")
+		return name, err
+	}
+	defer out.Close()
+
+	w, err := newCompressor(out, c.Format)
+	if err != nil {
+		return name, err
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", source).Msg("Error compressing output")
+		_ = w.Close()
+		return name, err
+	}
+	if err := w.Close(); err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", name).Msg("Error finalizing compressed stream")
+		return name, err
+	}
+	if err := out.Sync(); err != nil {
+		return name, err
+	}
+	if keep {
+		return name, nil
+	}
+	return name, os.Remove(source)
+}
+
+func newCompressor(w io.Writer, format string) (io.WriteCloser, error) {
+	switch format {
+	case schema.CompressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(runtime.NumCPU()))
+	case schema.CompressionXZ:
+		return xz.NewWriter(w)
+	case schema.CompressionGzip:
+		return gzip.NewWriterLevel(w, gzip.DefaultCompression)
+	}
+	return nil, fmt.Errorf("unsupported compression format %q", format)
+}
+
+// CompressOutputs compresses every raw disk, ISO and VHD in dst and, when
+// dst already has a SHA256SUMS, lists the compressed files in it. Without
+// one it writes none: a manifest of only the compressed files would pass
+// for one of every output. Raw disks and VHDs are replaced; ISOs are kept.
+func CompressOutputs(dst string, c schema.Compression) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var files []string
+		for _, pattern := range CompressedOutputs {
+			glob, err := filepath.Glob(filepath.Join(dst, pattern))
+			if err != nil {
+				return err
+			}
+			files = append(files, glob...)
+		}
+
+		var outputs []string
+		for _, f := range files {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			output, err := Compress(f, c, isKeptOutput(f))
+			if err != nil {
+				internal.Log.Logger.Error().Msgf("Compressing '%s' failed with error '%s'", f, err.Error())
+				return err
+			}
+			internal.Log.Logger.Info().Msgf("Generated compressed output '%s'", output)
+			outputs = append(outputs, filepath.Base(output))
+		}
+		if len(outputs) == 0 {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(dst, checksums.FileName)); os.IsNotExist(err) {
+			return nil
+		}
+		_, err := checksums.Add(dst, outputs...)
+		return err
+	}
+}
+
+func isKeptOutput(path string) bool {
+	for _, pattern := range keptOutputs {
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
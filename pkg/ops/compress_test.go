@@ -0,0 +1,124 @@
+package ops
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/checksums"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func TestCompress(t *testing.T) {
+	decompress := map[string]func(io.Reader) (io.Reader, error){
+		schema.CompressionZstd: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		schema.CompressionXZ:   func(r io.Reader) (io.Reader, error) { return xz.NewReader(r) },
+		schema.CompressionGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}
+	for format, newReader := range decompress {
+		dir := t.TempDir()
+		raw := filepath.Join(dir, "kairos-test.raw")
+		want := make([]byte, 4<<20)
+		copy(want[512:], bytes.Repeat([]byte("kairos partition table and filesystem metadata\n"), 3000))
+		rand.New(rand.NewSource(1)).Read(want[2<<20 : 3<<20])
+		if err := os.WriteFile(raw, want, 0644); err != nil {
+			t.Fatal(err)
+		}
+		c := schema.Compression{Format: format}
+
+		out, err := Compress(raw, c, false)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if out != raw+c.Extension() {
+			t.Fatalf("%s: output name = %q", format, out)
+		}
+		if _, err := os.Stat(raw); !os.IsNotExist(err) {
+			t.Errorf("%s: the uncompressed output was left behind", format)
+		}
+
+		img, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := newReader(bytes.NewReader(img))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: decompressed output differs from the original", format)
+		}
+	}
+}
+
+func TestCompressOutputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"kairos.iso", "kairos-test.raw", "kairos-test.raw.vhd", "kairos-test.qcow2"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := checksums.Add(dir, "kairos-test.raw", "kairos-test.qcow2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CompressOutputs(dir, schema.Compression{Format: schema.CompressionZstd})(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"kairos.iso", "kairos.iso.zst", "kairos-test.raw.zst", "kairos-test.raw.vhd.zst", "kairos-test.qcow2"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"kairos-test.raw", "kairos-test.raw.vhd"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s: the uncompressed output was left behind", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "kairos-test.qcow2.zst")); err == nil {
+		t.Error("qcow2 output should not be compressed")
+	}
+	results, err := checksums.VerifyDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("%s: %v", r.Name, r.Err)
+		}
+		listed = append(listed, r.Name)
+	}
+	if want := []string{"kairos-test.qcow2", "kairos-test.raw.vhd.zst", "kairos-test.raw.zst", "kairos.iso.zst"}; !slices.Equal(listed, want) {
+		t.Errorf("%s lists %v, want %v", checksums.FileName, listed, want)
+	}
+}
+
+func TestCompressOutputsWithoutManifest(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"kairos.iso", "kairos-test.raw"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := CompressOutputs(dir, schema.Compression{Format: schema.CompressionZstd})(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "kairos-test.raw.zst")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, checksums.FileName)); !os.IsNotExist(err) {
+		t.Errorf("%s was written for the compressed outputs only", checksums.FileName)
+	}
+}
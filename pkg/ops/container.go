@@ -2,11 +2,14 @@ package ops
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net/http"
 
 	"github.com/kairos-io/AuroraBoot/internal"
 	"github.com/kairos-io/kairos-agent/v2/pkg/elemental"
 	sdkImage "github.com/kairos-io/kairos-sdk/types/images"
+	sdkutils "github.com/kairos-io/kairos-sdk/utils"
 	imageutils "github.com/kairos-io/kairos-sdk/utils/image"
 )
 
@@ -26,7 +29,10 @@ func DumpSource(image string, dstFunc valueGetOnCall, arch string, allowInsecure
 		internal.Log.Logger.Debug().Str("arch", arch).Bool("allow-insecure-registries", allowInsecureRegistries).Msg("DumpSource: arch parameter")
 
 		opts := []GenericOptions{
-			WithImageExtractor(imageutils.OCIImageExtractor{Insecure: allowInsecureRegistries}),
+			WithImageExtractor(progressExtractor{
+				OCIImageExtractor: imageutils.OCIImageExtractor{Insecure: allowInsecureRegistries},
+				progress:          ProgressFrom(ctx),
+			}),
 			WithLogger(internal.Log),
 		}
 		if arch != "" {
@@ -54,3 +60,44 @@ func DumpSource(image string, dstFunc valueGetOnCall, arch string, allowInsecure
 		return nil
 	}
 }
+
+// progressExtractor is the OCIImageExtractor of the SDK with the bytes of the
+// pull reported: it pulls through a transport counting what the registry
+// sends against the size of the image layers. Images found in the local
+// docker daemon are not pulled, so they report nothing.
+type progressExtractor struct {
+	imageutils.OCIImageExtractor
+	progress Progress
+}
+
+func (e progressExtractor) ExtractImage(imageRef, destination, platformRef string, excludes ...string) error {
+	if platformRef == "" {
+		platformRef = sdkutils.GetCurrentPlatform()
+	}
+	var opts []imageutils.GetOption
+	base := http.DefaultTransport
+	if e.Insecure {
+		opts = append(opts, imageutils.WithInsecureRegistry())
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // only with allow_insecure_registries
+		base = tr
+	}
+	t := &countingTransport{base: base, p: e.progress}
+	img, err := imageutils.GetImage(imageRef, platformRef, nil, t, opts...)
+	if err != nil {
+		return err
+	}
+	// The layers are fetched as they are extracted: count from here, against
+	// their compressed size as listed in the manifest.
+	var total int64
+	if layers, err := img.Layers(); err == nil {
+		for _, l := range layers {
+			if size, err := l.Size(); err == nil {
+				total += size
+			}
+		}
+	}
+	t.read.Store(0)
+	t.total.Store(total)
+	return imageutils.ExtractOCIImage(img, destination, excludes...)
+}
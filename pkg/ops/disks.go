@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/kairos-io/AuroraBoot/internal"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
 	"github.com/kairos-io/kairos-sdk/utils"
 )
 
-func GenEFIRawDisk(src, dst string, size uint64, stateSize, recoveryImageSize int64, noDefaultCloudConfig, separatePartitionsImages, maas bool) func(ctx context.Context) error {
+func GenEFIRawDisk(src, dst string, size uint64, stateSize, recoveryImageSize int64, noDefaultCloudConfig, separatePartitionsImages, maas bool, layout schema.Layout) func(ctx context.Context) error {
 	return func(ctx context.Context) error {
 		internal.Log.Logger.Info().Msgf("Generating raw disk '%s' from '%s' with final size %dMb", dst, src, size)
 		// TODO: We need to talk about how the config.yaml is magically here no? is done in a previous step but maybe we should have constant that we can check?
@@ -18,6 +20,7 @@ func GenEFIRawDisk(src, dst string, size uint64, stateSize, recoveryImageSize in
 		raw := NewEFIRawImage(src, dst, filepath.Join(dst, "config.yaml"), size, stateSize, recoveryImageSize, noDefaultCloudConfig)
 		raw.SeparatePartitionsImages = separatePartitionsImages
 		raw.maas = maas
+		raw.Layout = layout
 		err := raw.Build()
 		if err != nil {
 			internal.Log.Logger.Error().Msgf("Generating raw disk '%s' from '%s' failed with error '%s'", dst, src, err.Error())
@@ -26,12 +29,13 @@ func GenEFIRawDisk(src, dst string, size uint64, stateSize, recoveryImageSize in
 	}
 }
 
-func GenBiosRawDisk(src, dst string, size uint64, stateSize, recoveryImageSize int64, noDefaultCloudConfig bool) func(ctx context.Context) error {
+func GenBiosRawDisk(src, dst string, size uint64, stateSize, recoveryImageSize int64, noDefaultCloudConfig bool, layout schema.Layout) func(ctx context.Context) error {
 	return func(ctx context.Context) error {
 		internal.Log.Logger.Info().Msgf("Generating raw disk '%s' from '%s' with final size %dMb", dst, src, size)
 		// TODO: We need to talk about how the config.yaml is magically here no? is done in a previous step but maybe we should have constant that we can check?
 		// Maybe on its own function that returns the tmpdir + config.yaml or something? we need a safe way of accessing it form any step in the DAG.
 		raw := NewBiosRawImage(src, dst, filepath.Join(dst, "config.yaml"), size, stateSize, recoveryImageSize, noDefaultCloudConfig)
+		raw.Layout = layout
 		err := raw.Build()
 		if err != nil {
 			internal.Log.Logger.Error().Msgf("Generating raw disk '%s' from '%s' failed with error '%s'", dst, src, err.Error())
@@ -60,7 +64,10 @@ func ExtractSquashFS(srcFunc, dstFunc valueGetOnCall) func(ctx context.Context)
 	}
 }
 
-func ConvertRawDiskToVHD(src string) func(ctx context.Context) error {
+// ConvertRawDiskToVHD converts the single raw disk in src to a VHD: a fixed
+// one, which takes the raw disk's place, or with dynamic a sparse one next to
+// it.
+func ConvertRawDiskToVHD(src string, dynamic bool) func(ctx context.Context) error {
 	return func(ctx context.Context) error {
 		tmp, err := os.MkdirTemp("", "gendisk")
 		if err != nil {
@@ -78,7 +85,11 @@ func ConvertRawDiskToVHD(src string) func(ctx context.Context) error {
 		}
 
 		internal.Log.Logger.Info().Msgf("Generating raw disk from '%s'", glob[0])
-		output, err := Raw2Azure(glob[0])
+		convert := Raw2Azure
+		if dynamic {
+			convert = Raw2DynamicVHD
+		}
+		output, err := convert(glob[0])
 		if err != nil {
 			internal.Log.Logger.Error().Msgf("Generating raw disk from '%s' failed with error '%s'", glob[0], err.Error())
 		} else {
@@ -88,6 +99,107 @@ func ConvertRawDiskToVHD(src string) func(ctx context.Context) error {
 	}
 }
 
+// ConvertRawDiskToQCOW2 finds the single raw disk in src and writes a qcow2
+// image of it, deflating its clusters when compress is set.
+func ConvertRawDiskToQCOW2(src string, compress bool) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		glob, err := filepath.Glob(filepath.Join(src, "kairos-*.raw"))
+		if err != nil {
+			return err
+		}
+
+		if len(glob) == 0 || len(glob) > 1 {
+			return fmt.Errorf("expected to find one and only one raw disk file in '%s' but found %d", src, len(glob))
+		}
+
+		internal.Log.Logger.Info().Msgf("Converting raw disk '%s' to qcow2", glob[0])
+		output, err := Raw2Qcow2(glob[0], compress)
+		if err != nil {
+			internal.Log.Logger.Error().Msgf("Converting raw disk from '%s' failed with error '%s'", src, err.Error())
+		} else {
+			internal.Log.Logger.Info().Msgf("Generated qcow2 disk '%s'", output)
+		}
+		return err
+	}
+}
+
+// ConvertRawDiskToVMDK finds the single raw disk in src and writes a
+// stream-optimized VMDK of it.
+func ConvertRawDiskToVMDK(src string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		glob, err := filepath.Glob(filepath.Join(src, "kairos-*.raw"))
+		if err != nil {
+			return err
+		}
+
+		if len(glob) == 0 || len(glob) > 1 {
+			return fmt.Errorf("expected to find one and only one raw disk file in '%s' but found %d", src, len(glob))
+		}
+
+		internal.Log.Logger.Info().Msgf("Converting raw disk '%s' to VMDK", glob[0])
+		output, err := Raw2Vmdk(glob[0])
+		if err != nil {
+			internal.Log.Logger.Error().Msgf("Converting raw disk from '%s' failed with error '%s'", src, err.Error())
+		} else {
+			internal.Log.Logger.Info().Msgf("Generated VMDK disk '%s'", output)
+		}
+		return err
+	}
+}
+
+// ConvertRawDiskToOVA finds the single raw disk in src and packs it into an
+// OVA for the virtual machine vm describes. With reuseVMDK the VMDK written
+// by ConvertRawDiskToVMDK is packed instead of converting the disk again.
+func ConvertRawDiskToOVA(src string, vm schema.OVF, reuseVMDK bool) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		glob, err := filepath.Glob(filepath.Join(src, "kairos-*.raw"))
+		if err != nil {
+			return err
+		}
+
+		if len(glob) == 0 || len(glob) > 1 {
+			return fmt.Errorf("expected to find one and only one raw disk file in '%s' but found %d", src, len(glob))
+		}
+
+		vmdk := ""
+		if reuseVMDK {
+			vmdk = fmt.Sprintf("%s.vmdk", strings.TrimSuffix(glob[0], ".raw"))
+		}
+		internal.Log.Logger.Info().Msgf("Packing raw disk '%s' into an OVA", glob[0])
+		output, err := Raw2Ova(glob[0], vmdk, vm)
+		if err != nil {
+			internal.Log.Logger.Error().Msgf("Packing raw disk from '%s' failed with error '%s'", src, err.Error())
+		} else {
+			internal.Log.Logger.Info().Msgf("Generated OVA '%s'", output)
+		}
+		return err
+	}
+}
+
+// ConvertRawDiskToVHDX finds the single raw disk in src and writes a VHDX of
+// it for Hyper-V generation 2 virtual machines.
+func ConvertRawDiskToVHDX(src string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		glob, err := filepath.Glob(filepath.Join(src, "kairos-*.raw"))
+		if err != nil {
+			return err
+		}
+
+		if len(glob) == 0 || len(glob) > 1 {
+			return fmt.Errorf("expected to find one and only one raw disk file in '%s' but found %d", src, len(glob))
+		}
+
+		internal.Log.Logger.Info().Msgf("Converting raw disk '%s' to VHDX", glob[0])
+		output, err := Raw2Vhdx(glob[0])
+		if err != nil {
+			internal.Log.Logger.Error().Msgf("Converting raw disk from '%s' failed with error '%s'", src, err.Error())
+		} else {
+			internal.Log.Logger.Info().Msgf("Generated VHDX disk '%s'", output)
+		}
+		return err
+	}
+}
+
 func ConvertRawDiskToGCE(src string) func(ctx context.Context) error {
 	return func(ctx context.Context) error {
 		tmp, err := os.MkdirTemp("", "gendisk")
@@ -38,6 +38,20 @@ type LiveISO struct {
 	// ExtendLiveCmdline is appended to the kernel cmdline when booting from the live/installer ISO.
 	ExtendLiveCmdline string `yaml:"extend-live-cmdline,omitempty" mapstructure:"extend-live-cmdline"`
 	LiveConsole       string `yaml:"live-console,omitempty" mapstructure:"live-console"`
+	// Images are more systems to put on the ISO, each with its own entries in the boot menu.
+	Images []LiveImage `yaml:"images,omitempty" mapstructure:"images"`
+}
+
+// LiveImage is an additional system of a multi-image ISO. Its kernel and
+// initrd are stored under /boot/<Dir> and its squashfs under /<Dir>.
+type LiveImage struct {
+	Name   string                    `yaml:"name" mapstructure:"name"`
+	Dir    string                    `yaml:"dir" mapstructure:"dir"`
+	RootFS []*imagetypes.ImageSource `yaml:"rootfs,omitempty" mapstructure:"rootfs"`
+	// CloudConfig is written into the rootfs, so it only applies when booting this image.
+	CloudConfig string `yaml:"cloud-config,omitempty" mapstructure:"cloud-config"`
+	// ExtendLiveCmdline is appended to the kernel cmdline of this image's entries, after LiveISO.ExtendLiveCmdline.
+	ExtendLiveCmdline string `yaml:"extend-live-cmdline,omitempty" mapstructure:"extend-live-cmdline"`
 }
 
 // BuildConfig represents the config we need for building isos, raw images, artifacts
@@ -52,14 +66,23 @@ type BuildConfig struct {
 }
 
 type BuildISOAction struct {
-	cfg  *BuildConfig
-	spec *LiveISO
-	e    *elemental.Elemental
+	cfg      *BuildConfig
+	spec     *LiveISO
+	e        *elemental.Elemental
+	progress Progress
 }
 
 type BuildISOActionOption func(a *BuildISOAction)
+
 type GenericOptions func(a *sdkConfig.Config) error
 
+// WithSquashFSProgress reports the bytes of the squashfs as it is written.
+func WithSquashFSProgress(p Progress) BuildISOActionOption {
+	return func(a *BuildISOAction) {
+		a.progress = p
+	}
+}
+
 func NewBuildConfig(opts ...GenericOptions) *BuildConfig {
 	b := &BuildConfig{
 		Config: *NewConfig(opts...),
@@ -113,8 +136,10 @@ func NewConfig(opts ...GenericOptions) *sdkConfig.Config {
 	return c
 }
 
-// GenISO generates an ISO from a rootfs, and stores results in dst
-func GenISO(srcFunc, dstFunc valueGetOnCall, i schema.ISO) func(ctx context.Context) error {
+// GenISO generates an ISO from a rootfs, and stores results in dst. The
+// additional images in i.Images are read from the rootfs imageSrc returns for
+// them.
+func GenISO(srcFunc, dstFunc valueGetOnCall, i schema.ISO, imageSrc func(schema.ISOImage) string) func(ctx context.Context) error {
 	return func(ctx context.Context) error {
 		dst := dstFunc()
 		src := srcFunc()
@@ -167,8 +192,21 @@ func GenISO(srcFunc, dstFunc valueGetOnCall, i schema.ISO) func(ctx context.Cont
 		if i.OverlayISO != "" {
 			spec.Image = append(spec.Image, imagetypes.NewDirSrc(i.OverlayISO))
 		}
+		for _, img := range i.Images {
+			live := LiveImage{
+				Name:              img.Title(),
+				Dir:               img.Dir(),
+				RootFS:            []*imagetypes.ImageSource{imagetypes.NewDirSrc(imageSrc(img))},
+				CloudConfig:       img.CloudConfig,
+				ExtendLiveCmdline: img.ExtendLiveCmdline,
+			}
+			if i.OverlayRootfs != "" {
+				live.RootFS = append(live.RootFS, imagetypes.NewDirSrc(i.OverlayRootfs))
+			}
+			spec.Images = append(spec.Images, live)
+		}
 
-		buildISO := NewBuildISOAction(cfg, spec)
+		buildISO := NewBuildISOAction(cfg, spec, WithSquashFSProgress(ProgressFrom(ctx)))
 		err = buildISO.ISORun()
 		if err != nil {
 			internal.Log.Logger.Error().Msgf("Failed generating iso '%s' from '%s'. Error: %s", i.Name, src, err.Error())
@@ -305,6 +343,14 @@ func (b *BuildISOAction) ISORun() (err error) {
 		return err
 	}
 
+	for _, img := range b.spec.Images {
+		err = b.prepareLiveImage(isoTmpDir, isoDir, img)
+		if err != nil {
+			b.cfg.Logger.Errorf("Failed preparing image %s: %v", img.Name, err)
+			return err
+		}
+	}
+
 	err = b.prepareBootArtifacts(isoDir)
 	if err != nil {
 		b.cfg.Logger.Errorf("Failed preparing boot artifacts: %v", err)
@@ -333,6 +379,24 @@ func applyGrubTemplate(cfg []byte, nomodeset, extendCmdline, liveConsole string)
 	return []byte(out)
 }
 
+// liveImageMenu returns the boot menu entries of an additional image,
+// appended to the livecd grub config.
+func liveImageMenu(img LiveImage, nomodeset, extendCmdline, liveConsole string) []byte {
+	menu := strings.NewReplacer("{{TITLE}}", img.Name, "{{DIR}}", img.Dir).Replace(string(constants.GrubLiveImageCfg))
+	return applyGrubTemplate([]byte(menu), nomodeset, extendCmdline+cmdlineSuffix(img.ExtendLiveCmdline), liveConsole)
+}
+
+// cmdlineSuffix returns extra arguments to append to a kernel cmdline, with a
+// leading space when not empty.
+func cmdlineSuffix(cmdline string) string {
+	// Strip any newlines or carriage returns to prevent corruption of the grub config
+	cmdline = strings.NewReplacer("\n", "", "\r", "").Replace(strings.TrimSpace(cmdline))
+	if cmdline == "" {
+		return ""
+	}
+	return " " + cmdline
+}
+
 // prepareBootArtifacts will write the needed artifacts for BIOS cd boot into the isoDir
 // so xorriso can use those to build the bootable iso file
 func (b *BuildISOAction) prepareBootArtifacts(isoDir string) error {
@@ -357,17 +421,21 @@ func (b *BuildISOAction) prepareBootArtifacts(isoDir string) error {
 		if b.spec != nil {
 			extendCmdline = b.spec.ExtendLiveCmdline
 		}
-		if extendCmdline != "" {
-			// Strip any newlines or carriage returns to prevent corruption of the grub config
-			extendCmdline = strings.NewReplacer("\n", "", "\r", "").Replace(strings.TrimSpace(extendCmdline))
-			extendCmdline = " " + extendCmdline
-		}
+		extendCmdline = cmdlineSuffix(extendCmdline)
 		liveConsole := ""
 		if b.spec != nil {
 			liveConsole = b.spec.LiveConsole
 		}
 		grubCfg := applyGrubTemplate(constants.GrubLiveBiosCfg, nomodeset, extendCmdline, liveConsole)
+		if b.spec != nil {
+			for _, img := range b.spec.Images {
+				grubCfg = append(grubCfg, liveImageMenu(img, nomodeset, extendCmdline, liveConsole)...)
+			}
+		}
 		return os.WriteFile(filepath.Join(isoDir, constants.GrubPrefixDir, constants.GrubCfg), grubCfg, constants.FilePerm)
+	} else if b.spec != nil && len(b.spec.Images) > 0 {
+		// The menu entries of the other images only go into the default config
+		return fmt.Errorf("the ISO packages of the container image ship their own %s, which the boot menu of the other images cannot be added to", filepath.Join(constants.GrubPrefixDir, constants.GrubCfg))
 	} else {
 		b.cfg.Logger.Logger.Warn().Msgf("Grub config already exists at %s, skipping using default one", filepath.Join(isoDir, constants.GrubPrefixDir, constants.GrubCfg))
 	}
@@ -404,7 +472,13 @@ func (b BuildISOAction) prepareISORoot(isoDir string, rootDir string) error {
 	}
 
 	b.cfg.Logger.Info("Creating squashfs...")
-	err = utils.CreateSquashFS(b.cfg.Runner, b.cfg.Logger, rootDir, filepath.Join(isoDir, constants.IsoRootFile), constants.GetDefaultSquashfsOptions())
+	squashfs := filepath.Join(isoDir, constants.IsoRootFile)
+	stop := func() {}
+	if b.progress != nil {
+		stop = watchSize(squashfs, time.Second, b.progress)
+	}
+	err = utils.CreateSquashFS(b.cfg.Runner, b.cfg.Logger, rootDir, squashfs, constants.GetDefaultSquashfsOptions())
+	stop()
 	if err != nil {
 		return err
 	}
@@ -412,6 +486,66 @@ func (b BuildISOAction) prepareISORoot(isoDir string, rootDir string) error {
 	return nil
 }
 
+// prepareLiveImage adds an additional image to the ISO root tree: its kernel
+// and initrd under /boot/<dir> and its squashfs under /<dir>. The EFI image
+// and bootloader come from the main rootfs only.
+func (b BuildISOAction) prepareLiveImage(isoTmpDir, isoDir string, img LiveImage) error {
+	rootDir := filepath.Join(isoTmpDir, "rootfs-"+img.Dir)
+	err := utils.MkdirAll(b.cfg.Fs, rootDir, constants.DirPerm)
+	if err != nil {
+		return err
+	}
+	// Each rootfs is only needed until its squashfs is written
+	defer b.cfg.Fs.RemoveAll(rootDir)
+
+	b.cfg.Logger.Infof("Preparing squashfs root of %s...", img.Name)
+	err = b.applySources(rootDir, img.RootFS...)
+	if err != nil {
+		return err
+	}
+	err = utils.CreateDirStructure(b.cfg.Fs, rootDir)
+	if err != nil {
+		return err
+	}
+	if img.CloudConfig != "" {
+		cc := filepath.Join(rootDir, constants.IsoImageCloudConfig)
+		err = utils.MkdirAll(b.cfg.Fs, filepath.Dir(cc), constants.DirPerm)
+		if err != nil {
+			return err
+		}
+		err = b.cfg.Fs.WriteFile(cc, []byte(img.CloudConfig), 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	kernel, initrd, err := b.e.FindKernelInitrd(rootDir)
+	if err != nil {
+		b.cfg.Logger.Errorf("Could not find kernel and/or initrd of %s", img.Name)
+		return err
+	}
+	bootDir := filepath.Join(isoDir, "boot", img.Dir)
+	err = utils.MkdirAll(b.cfg.Fs, bootDir, constants.DirPerm)
+	if err != nil {
+		return err
+	}
+	err = utils.CopyFile(b.cfg.Fs, kernel, filepath.Join(bootDir, filepath.Base(constants.IsoKernelPath)))
+	if err != nil {
+		return err
+	}
+	err = utils.CopyFile(b.cfg.Fs, initrd, filepath.Join(bootDir, filepath.Base(constants.IsoInitrdPath)))
+	if err != nil {
+		return err
+	}
+
+	b.cfg.Logger.Infof("Creating squashfs of %s...", img.Name)
+	err = utils.MkdirAll(b.cfg.Fs, filepath.Join(isoDir, img.Dir), constants.DirPerm)
+	if err != nil {
+		return err
+	}
+	return utils.CreateSquashFS(b.cfg.Runner, b.cfg.Logger, rootDir, filepath.Join(isoDir, img.Dir, constants.IsoRootFile), constants.GetDefaultSquashfsOptions())
+}
+
 // createEFI creates the EFI image that is used for booting
 // it searches the rootfs for the shim/grub.efi file and copies it into a directory with the proper EFI structure
 // then it generates a grub.cfg that chainloads into the grub.cfg of the livecd (which is the normal livecd grub config from luet packages)
@@ -1,6 +1,7 @@
 package ops
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -56,6 +57,68 @@ var _ = Describe("applyGrubTemplate", Label("iso"), func() {
 	})
 })
 
+var _ = Describe("liveImageMenu", Label("iso"), func() {
+	img := LiveImage{Name: "Ubuntu standard", Dir: "ubuntu-standard", ExtendLiveCmdline: " console=ttyS0,115200\n"}
+
+	It("boots the kernel, initrd and squashfs of the image", func() {
+		menu := string(liveImageMenu(img, " nomodeset", "", ""))
+		Expect(menu).To(HavePrefix("\n"))
+		Expect(menu).To(ContainSubstring(`submenu "Ubuntu standard"`))
+		Expect(menu).To(ContainSubstring(`menuentry "Ubuntu standard (debug)"`))
+		Expect(strings.Count(menu, "linux ($root)/boot/ubuntu-standard/kernel ")).To(Equal(4))
+		Expect(strings.Count(menu, "initrd ($root)/boot/ubuntu-standard/initrd")).To(Equal(4))
+		Expect(strings.Count(menu, "rd.live.dir=/ubuntu-standard rd.live.squashimg=rootfs.squashfs")).To(Equal(4))
+		Expect(menu).To(ContainSubstring(" nomodeset install-mode "))
+		Expect(menu).ToNot(ContainSubstring("{{"))
+	})
+
+	It("appends the cmdline of the image after the one of the ISO", func() {
+		menu := string(liveImageMenu(img, "", " rd.debug", "console=tty1"))
+		Expect(menu).To(ContainSubstring("rd.live.overlay.overlayfs rd.debug console=ttyS0,115200\n"))
+		Expect(menu).ToNot(ContainSubstring("console=ttyS0 console=tty1"))
+	})
+})
+
+var _ = Describe("prepareBootArtifacts", Label("iso"), func() {
+	var isoDir string
+	var b *BuildISOAction
+
+	BeforeEach(func() {
+		isoDir = GinkgoT().TempDir()
+		// prepareISORoot creates it
+		Expect(os.MkdirAll(filepath.Join(isoDir, "boot"), 0o755)).To(Succeed())
+		b = &BuildISOAction{
+			cfg:  &BuildConfig{Config: sdkConfig.Config{Logger: logger.NewNullLogger()}},
+			spec: &LiveISO{Images: []LiveImage{{Name: "Ubuntu standard", Dir: "ubuntu-standard"}}},
+		}
+	})
+
+	It("adds the menu of the other images to the default grub config", func() {
+		Expect(b.prepareBootArtifacts(isoDir)).To(Succeed())
+		cfg, err := os.ReadFile(filepath.Join(isoDir, constants.GrubPrefixDir, constants.GrubCfg))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(cfg)).To(ContainSubstring(`submenu "Ubuntu standard"`))
+	})
+
+	It("fails when the ISO packages ship their own grub config", func() {
+		Expect(os.MkdirAll(filepath.Join(isoDir, constants.GrubPrefixDir), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(isoDir, constants.GrubPrefixDir, constants.GrubCfg), []byte("menuentry custom {}\n"), 0o644)).To(Succeed())
+
+		Expect(b.prepareBootArtifacts(isoDir)).To(MatchError(ContainSubstring("ship their own /boot/grub2/grub.cfg")))
+	})
+
+	It("keeps a shipped grub config of a single image ISO", func() {
+		b.spec.Images = nil
+		Expect(os.MkdirAll(filepath.Join(isoDir, constants.GrubPrefixDir), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(isoDir, constants.GrubPrefixDir, constants.GrubCfg), []byte("menuentry custom {}\n"), 0o644)).To(Succeed())
+
+		Expect(b.prepareBootArtifacts(isoDir)).To(Succeed())
+		cfg, err := os.ReadFile(filepath.Join(isoDir, constants.GrubPrefixDir, constants.GrubCfg))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(cfg)).To(Equal("menuentry custom {}\n"))
+	})
+})
+
 var _ = Describe("getEfiGrubFilesForArch", Label("iso"), func() {
 	It("prepends the openSUSE riscv64 path before SDK paths", func() {
 		paths := getEfiGrubFilesForArch("riscv64")
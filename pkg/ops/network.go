@@ -65,6 +65,7 @@ func download(ctx context.Context, url, dst string) (string, error) {
 	internal.Log.Logger.Printf("%s:  %v", url, resp.HTTPResponse.Status)
 
 	// start UI loop
+	progress := ProgressFrom(ctx)
 	t := time.NewTicker(500 * time.Millisecond)
 	defer t.Stop()
 	dstFile := filepath.Join(dst, resp.Filename)
@@ -80,10 +81,12 @@ Loop:
 				resp.BytesComplete(),
 				resp.Size(),
 				100*resp.Progress())
+			progress(resp.BytesComplete(), resp.Size())
 
 		case <-resp.Done:
 			// download is complete
-			fmt.Printf("transferred %v / %v bytes (%.2f%%)    \n", resp.BytesComplete(), resp.Size(), 100*resp.Progress())
+			internal.Log.Printf("%s: transferred %v / %v bytes (%.2f%%)", url, resp.BytesComplete(), resp.Size(), 100*resp.Progress())
+			progress(resp.BytesComplete(), resp.Size())
 			break Loop
 		}
 	}
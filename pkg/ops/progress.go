@@ -0,0 +1,93 @@
+package ops
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Progress receives the bytes a step has transferred or written so far, and
+// the total it expects when it knows it (zero otherwise). It may be called
+// often: callers throttle what they do with it.
+type Progress func(done, total int64)
+
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx the byte progress of a step is reported
+// to. The deployer sets it on the context of each step callback.
+func WithProgress(ctx context.Context, p Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// ProgressFrom returns the Progress of ctx, one discarding the reports when
+// ctx carries none.
+func ProgressFrom(ctx context.Context) Progress {
+	if p, ok := ctx.Value(progressKey{}).(Progress); ok && p != nil {
+		return p
+	}
+	return func(int64, int64) {}
+}
+
+// watchSize reports the size of path every interval until the returned func
+// is called, for tools such as mksquashfs that write a file without telling
+// how far along they are. The total is unknown.
+func watchSize(path string, interval time.Duration, p Progress) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				if info, err := os.Stat(path); err == nil {
+					p(info.Size(), 0)
+				}
+				return
+			case <-t.C:
+				if info, err := os.Stat(path); err == nil {
+					p(info.Size(), 0)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// countingTransport reports the bytes of the response bodies read through it
+// against total.
+type countingTransport struct {
+	base  http.RoundTripper
+	read  atomic.Int64
+	total atomic.Int64
+	p     Progress
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		return resp, err
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, t: t}
+	return resp, nil
+}
+
+type countingBody struct {
+	io.ReadCloser
+	t *countingTransport
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.t.p(b.t.read.Add(int64(n)), b.t.total.Load())
+	}
+	return n, err
+}
@@ -0,0 +1,75 @@
+package ops
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgressFrom(t *testing.T) {
+	// A context without a Progress still gives one to call
+	ProgressFrom(context.Background())(1, 2)
+
+	var got [2]int64
+	ctx := WithProgress(context.Background(), func(done, total int64) { got = [2]int64{done, total} })
+	ProgressFrom(ctx)(3, 4)
+	if got != [2]int64{3, 4} {
+		t.Errorf("got %v, want [3 4]", got)
+	}
+}
+
+func TestCountingTransport(t *testing.T) {
+	body := strings.Repeat("x", 10000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	var last [2]int64
+	tr := &countingTransport{base: http.DefaultTransport, p: func(done, total int64) { last = [2]int64{done, total} }}
+	tr.total.Store(20000)
+	client := &http.Client{Transport: tr}
+	for range 2 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if last != [2]int64{20000, 20000} {
+		t.Errorf("last report %v, want [20000 20000]", last)
+	}
+}
+
+func TestWatchSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rootfs.squashfs")
+	var mu sync.Mutex
+	var sizes []int64
+	stop := watchSize(path, 10*time.Millisecond, func(done, total int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		if total != 0 {
+			t.Errorf("total %d, want 0 as mksquashfs does not tell", total)
+		}
+		sizes = append(sizes, done)
+	})
+	if err := os.WriteFile(path, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sizes) == 0 || sizes[len(sizes)-1] != 4096 {
+		t.Errorf("sizes %v, want them to end with 4096", sizes)
+	}
+}
@@ -0,0 +1,183 @@
+package ops
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFakeRawDisk writes a sparse disk that is mostly zeroes, like a
+// freshly built raw disk: a compressible text region, a random
+// (incompressible) region, one more region past the first L2 table's 512 MiB
+// and a short tail that does not fill its last cluster.
+func writeFakeRawDisk(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	size := int64(qcow2L2Entries*qcow2ClusterSize + 5*qcow2ClusterSize + 1536)
+	random := make([]byte, 2*qcow2ClusterSize)
+	rand.New(rand.NewSource(1)).Read(random)
+	for _, w := range []struct {
+		off  int64
+		data []byte
+	}{
+		{512, bytes.Repeat([]byte("kairos partition table and filesystem metadata\n"), 3000)},
+		{4 * qcow2ClusterSize, random},
+		{qcow2L2Entries*qcow2ClusterSize + qcow2ClusterSize + 100, []byte("second L2 table")},
+		{size - 700, []byte("end of the disk")},
+	} {
+		if _, err := f.WriteAt(w.data, w.off); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRaw2Qcow2(t *testing.T) {
+	sizes := map[bool]int64{}
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		raw := filepath.Join(dir, "kairos-test.raw")
+		writeFakeRawDisk(t, raw)
+
+		out, err := Raw2Qcow2(raw, compress)
+		if err != nil {
+			t.Fatalf("Raw2Qcow2(compress=%v): %v", compress, err)
+		}
+		if out != filepath.Join(dir, "kairos-test.qcow2") {
+			t.Fatalf("output name = %q", out)
+		}
+
+		// The raw disk is kept, and the image holds exactly its content.
+		want, err := os.Open(raw)
+		if err != nil {
+			t.Fatalf("original raw should be left in place: %v", err)
+		}
+		defer want.Close()
+		st, _ := want.Stat()
+		clusters, size := readQcow2(t, out)
+		if size != uint64(st.Size()) {
+			t.Fatalf("compress=%v: virtual size %d, want %d", compress, size, st.Size())
+		}
+		buf := make([]byte, qcow2ClusterSize)
+		zero := make([]byte, qcow2ClusterSize)
+		for c := uint64(0); c*qcow2ClusterSize < size; c++ {
+			n, _ := io.ReadFull(want, buf)
+			clear(buf[n:])
+			got, ok := clusters[c]
+			if !ok {
+				got = zero
+			}
+			if !bytes.Equal(got, buf) {
+				t.Fatalf("compress=%v: cluster %d differs from the raw disk", compress, c)
+			}
+		}
+
+		img, _ := os.Stat(out)
+		sizes[compress] = img.Size()
+		// Only the clusters with data are stored.
+		if img.Size() > 16*qcow2ClusterSize {
+			t.Errorf("compress=%v: image of a mostly empty disk is %d bytes", compress, img.Size())
+		}
+	}
+	if sizes[true] >= sizes[false] {
+		t.Errorf("compressed image (%d bytes) should be smaller than the uncompressed one (%d bytes)", sizes[true], sizes[false])
+	}
+}
+
+// readQcow2 reads back an image written by Raw2Qcow2 and checks its
+// metadata the way qemu-img check does: every cluster is referenced exactly
+// as many times as its refcount says. It returns the allocated clusters of
+// the virtual disk, by index, and its size.
+func readQcow2(t *testing.T, path string) (map[uint64][]byte, uint64) {
+	t.Helper()
+	img, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h qcow2Header
+	if err := binary.Read(bytes.NewReader(img), binary.BigEndian, &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.Magic != qcow2Magic || h.Version != 3 || h.ClusterBits != qcow2ClusterBits || h.HeaderLength != qcow2HeaderLength || h.RefcountOrder != 4 {
+		t.Fatalf("unexpected header %+v", h)
+	}
+	u64 := func(off uint64) uint64 { return binary.BigEndian.Uint64(img[off:]) }
+	clusters := (len(img) + qcow2ClusterSize - 1) / qcow2ClusterSize
+	seen := make([]int, clusters)
+	use := func(off, length uint64) {
+		for c := off / qcow2ClusterSize; c <= (off+length-1)/qcow2ClusterSize; c++ {
+			seen[c]++
+		}
+	}
+	use(0, qcow2ClusterSize)
+	use(h.L1TableOffset, uint64(h.L1Size)*8)
+	use(h.RefcountTableOffset, uint64(h.RefcountTableClusters)*qcow2ClusterSize)
+
+	disk := map[uint64][]byte{}
+	const offsetMask = uint64(1)<<56 - 1
+	for i := uint64(0); i < uint64(h.L1Size); i++ {
+		l1 := u64(h.L1TableOffset + i*8)
+		if l1 == 0 {
+			continue
+		}
+		if l1&qcow2Copied == 0 {
+			t.Fatalf("L1 entry %d lacks the copied flag", i)
+		}
+		l2 := l1 & offsetMask &^ (qcow2ClusterSize - 1)
+		use(l2, qcow2ClusterSize)
+		for j := uint64(0); j < qcow2L2Entries; j++ {
+			e := u64(l2 + j*8)
+			if e == 0 {
+				continue
+			}
+			dst := make([]byte, qcow2ClusterSize)
+			disk[i*qcow2L2Entries+j] = dst
+			if e&qcow2Compressed != 0 {
+				if e&qcow2Copied != 0 {
+					t.Fatalf("compressed cluster %d has the copied flag", i*qcow2L2Entries+j)
+				}
+				off := e & (uint64(1)<<qcow2CompressedSectorsShift - 1)
+				sectors := (e>>qcow2CompressedSectorsShift)&0xff + 1
+				start := off &^ (qcow2SectorSize - 1)
+				use(start, sectors*qcow2SectorSize)
+				if _, err := io.ReadFull(flate.NewReader(bytes.NewReader(img[off:start+sectors*qcow2SectorSize])), dst); err != nil {
+					t.Fatalf("inflating cluster %d: %v", i*qcow2L2Entries+j, err)
+				}
+				continue
+			}
+			if e&qcow2Copied == 0 {
+				t.Fatalf("cluster %d lacks the copied flag", i*qcow2L2Entries+j)
+			}
+			off := e & offsetMask
+			use(off, qcow2ClusterSize)
+			copy(dst, img[off:])
+		}
+	}
+
+	var blocks []uint64
+	for i := uint64(0); i < uint64(h.RefcountTableClusters)*qcow2ClusterSize/8; i++ {
+		if b := u64(h.RefcountTableOffset + i*8); b != 0 {
+			blocks = append(blocks, b)
+			use(b, qcow2ClusterSize)
+		}
+	}
+	for c := range seen {
+		b := blocks[c/qcow2RefsPerBlock]
+		ref := binary.BigEndian.Uint16(img[b+uint64(c%qcow2RefsPerBlock)*2:])
+		if int(ref) != seen[c] {
+			t.Fatalf("cluster %d has refcount %d but is referenced %d times", c, ref, seen[c])
+		}
+	}
+	return disk, h.Size
+}
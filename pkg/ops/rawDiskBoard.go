@@ -0,0 +1,120 @@
+package ops
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/AuroraBoot/internal"
+	"github.com/kairos-io/AuroraBoot/pkg/boards"
+	"github.com/kairos-io/AuroraBoot/pkg/utils"
+	fsutils "github.com/kairos-io/kairos-agent/v2/pkg/utils/fs"
+)
+
+// resolveBoard looks up the board profile of the source's model. The layout
+// of the profile applies when disk.layout is left empty.
+func (r *RawImage) resolveBoard() error {
+	model, _, err := r.GetModelAndFlavor()
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Msg("failed to get flavor or model")
+		return err
+	}
+	board, ok := boards.DefaultRegistry().Lookup(model)
+	if !ok {
+		return nil
+	}
+	internal.Log.Logger.Info().Str("model", model).Str("profile", board.Name).Msg("Using board profile")
+	r.board = board
+	if board.Layout != nil && r.Layout.Table == "" && len(r.Layout.Partitions) == 0 {
+		r.Layout = *board.Layout
+	}
+	return nil
+}
+
+// copyBoardFiles copies the firmware of the board profile into the boot partition
+// directory target and writes the profile's files over it.
+func (r *RawImage) copyBoardFiles(target string) error {
+	if r.board == nil {
+		return nil
+	}
+	for _, f := range r.board.Firmware {
+		dst := filepath.Join(target, f.Target)
+		internal.Log.Logger.Info().Str("source", f.Source).Str("target", dst).Msg("Copying board firmware")
+		dir, err := utils.IsDir(r.config.Fs, f.Source)
+		if err != nil {
+			return fmt.Errorf("board profile %q: firmware %s: %w", r.board.Name, f.Source, err)
+		}
+		if dir {
+			err = utils.CopyDir(f.Source, dst)
+		} else if err = fsutils.MkdirAll(r.config.Fs, filepath.Dir(dst), 0755); err == nil {
+			err = utils.CopyFile(r.config.Fs, f.Source, dst)
+		}
+		if err != nil {
+			return fmt.Errorf("board profile %q: copying firmware %s: %w", r.board.Name, f.Source, err)
+		}
+	}
+	for _, f := range r.board.Files {
+		dst := filepath.Join(target, f.Path)
+		err := fsutils.MkdirAll(r.config.Fs, filepath.Dir(dst), 0755)
+		if err == nil {
+			err = r.config.Fs.WriteFile(dst, []byte(f.Content), 0o644)
+		}
+		if err != nil {
+			return fmt.Errorf("board profile %q: writing %s: %w", r.board.Name, f.Path, err)
+		}
+	}
+	return nil
+}
+
+// writeBootloader writes the bootloader blobs of the board profile at their
+// offsets of the partitioned disk image. A blob that would overwrite the MBR
+// partition entries or a partition is refused.
+func (r *RawImage) writeBootloader(image string, plan diskPlan) error {
+	if r.board == nil || len(r.board.Bootloader) == 0 {
+		return nil
+	}
+	disk, err := os.OpenFile(image, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+
+	for _, b := range r.board.Bootloader {
+		if err := writeBlob(disk, b, plan); err != nil {
+			return fmt.Errorf("board profile %q: bootloader %s: %w", r.board.Name, b.Source, err)
+		}
+		internal.Log.Logger.Debug().Str("source", b.Source).Uint64("offset", b.Offset).Msg("Wrote bootloader blob")
+	}
+	return disk.Sync()
+}
+
+func writeBlob(disk io.WriterAt, b boards.Blob, plan diskPlan) error {
+	src, err := os.Open(b.Source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := uint64(info.Size())
+	if b.Skip >= size {
+		return fmt.Errorf("skip %d is past the end of the %d byte file", b.Skip, size)
+	}
+	n := size - b.Skip
+	if b.Length > 0 {
+		if b.Length > n {
+			return fmt.Errorf("length %d runs past the end of the %d byte file", b.Length, size)
+		}
+		n = b.Length
+	}
+	if what := plan.overlap(b.Offset, n); what != "" {
+		return fmt.Errorf("%d bytes at offset %d would overwrite %s", n, b.Offset, what)
+	}
+
+	_, err = io.Copy(io.NewOffsetWriter(disk, int64(b.Offset)), io.NewSectionReader(src, int64(b.Skip), int64(n)))
+	return err
+}
@@ -0,0 +1,92 @@
+package ops
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/boards"
+)
+
+// Bootloader blobs land at their offset with skip and length applied, as dd
+// would write them, and never over the partition entries or a partition.
+func TestWriteBootloader(t *testing.T) {
+	dir := t.TempDir()
+	blob := filepath.Join(dir, "bl1.bin")
+	if err := os.WriteFile(blob, bytes.Repeat([]byte("0123456789"), 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := planDisk(sizedParts(4), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := filepath.Join(dir, "disk.raw")
+	if err := os.WriteFile(image, make([]byte, plan.sectors*diskSectorSize), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewEFIRawImage(dir, dir, "", 0, 0, 0, true)
+	r.board = &boards.Profile{Name: "board", Bootloader: []boards.Blob{
+		{Source: blob, Length: 10},
+		{Source: blob, Offset: 4096, Skip: 995},
+	}}
+	if err := r.writeBootloader(image, plan); err != nil {
+		t.Fatal(err)
+	}
+	disk, err := os.ReadFile(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(disk[:11]) != "0123456789\x00" || string(disk[4096:4102]) != "56789\x00" {
+		t.Errorf("blobs written as %q and %q", disk[:11], disk[4096:4102])
+	}
+
+	for _, b := range []boards.Blob{
+		{Source: blob},
+		{Source: blob, Offset: 1<<20 - 10},
+		{Source: blob, Skip: 1000},
+	} {
+		r.board.Bootloader = []boards.Blob{b}
+		if err := r.writeBootloader(image, plan); err == nil {
+			t.Errorf("blob %+v should be refused", b)
+		}
+	}
+}
+
+func TestCopyBoardFiles(t *testing.T) {
+	firmware := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(firmware, "overlays"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"start4.elf": "elf", "config.txt": "stock", "overlays/a.dtbo": "dtbo"} {
+		if err := os.WriteFile(filepath.Join(firmware, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewEFIRawImage(t.TempDir(), t.TempDir(), "", 0, 0, 0, true)
+	r.board = &boards.Profile{
+		Name: "board",
+		Firmware: []boards.Firmware{
+			{Source: firmware},
+			{Source: filepath.Join(firmware, "start4.elf"), Target: "boot/start.elf"},
+		},
+		Files: []boards.File{{Path: "config.txt", Content: "kernel=u-boot.bin\n"}},
+	}
+	target := t.TempDir()
+	if err := r.copyBoardFiles(target); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"start4.elf":      "elf",
+		"overlays/a.dtbo": "dtbo",
+		"boot/start.elf":  "elf",
+		"config.txt":      "kernel=u-boot.bin\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(target, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s: %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
@@ -11,10 +11,10 @@ import (
 	"github.com/diskfs/go-diskfs"
 	fileBackend "github.com/diskfs/go-diskfs/backend/file"
 	"github.com/diskfs/go-diskfs/partition"
-	"github.com/diskfs/go-diskfs/partition/gpt"
-	"github.com/gofrs/uuid"
 	"github.com/kairos-io/AuroraBoot/internal"
+	"github.com/kairos-io/AuroraBoot/pkg/boards"
 	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
 	"github.com/kairos-io/AuroraBoot/pkg/utils"
 	"github.com/kairos-io/kairos-agent/v2/pkg/config"
 	agentConstants "github.com/kairos-io/kairos-agent/v2/pkg/constants"
@@ -58,6 +58,10 @@ type RawImage struct {
 	// such as Nvidia Jetson AGX Orin. Only valid together with an EFI build.
 	SeparatePartitionsImages bool
 	maas                     bool // if true, add the curtin-landing partition (COS_CURTIN) carrying /curtin/curtin-hooks (MAAS deploy)
+	// Layout lays out the partitions of the disk, the boot, OEM and recovery ones when empty.
+	// Both the EFI and BIOS builds honour it.
+	Layout schema.Layout
+	board  *boards.Profile // profile of the source's board model, if any
 }
 
 // NewEFIRawImage creates a new RawImage struct
@@ -141,36 +145,24 @@ func (r *RawImage) createOemPartitionImage(recoveryImagePath string) (string, er
 
 	resetCloudInit := "01_reset.yaml"
 
-	// Calculate the size of the state partition based on the recovery image size
-	info, err := r.config.Fs.Stat(recoveryImagePath)
+	stateSize, err := r.stateSize(recoveryImagePath)
 	if err != nil {
-		internal.Log.Logger.Error().Err(err).Str("source", recoveryImagePath).Msg("failed to stat recovery image")
 		return "", err
 	}
 
-	var stateSize int64
-	if r.StateSize > 0 {
-		// Use the state size from the config
-		// stateSize is in MB so we can use it directly
-		stateSize = r.StateSize
-	} else {
-		stateSize = (info.Size()*3 + 100*1024*1024) / (1024 * 1024)
-	}
-
-	internal.Log.Logger.Debug().Int64("size", stateSize).Msg("calculated state partition size")
-
 	// Create a reset config
 	// This:
 	// - Adds a state partition with the calculated size
 	// - Adds a persistent partition with the rest of the disk
 	// - If the recovery mode file is present, it will run the reset command unattended
 	// - If the reset cloud init file is present, it will remove it. Magic! So we dont get any traces of the extra config for raw images
+	// MBR disks already have their state and persistent partitions, as the layout stage only grows GPT disks
 	conf := fmt.Sprintf(`name: Expand disk layout and autoreset
 stages:
     after-reset:
         - commands:
-            - rm /oem/%[8]s
-          if: '[ -f "/oem/%[8]s" ]'
+            - rm /oem/%[1]s
+          if: '[ -f "/oem/%[1]s" ]'
           name: Auto remove this file
     network:
         - commands:
@@ -180,7 +172,9 @@ stages:
             - kairos-agent --debug reset --unattended --reboot
           if: '[ -f "/run/cos/recovery_mode" ] && [ ! -f "/oem/.autoreset.skip" ]'
           name: Run auto reset
-    rootfs.before:
+`, resetCloudInit)
+	if !r.Layout.IsMBR() {
+		conf += fmt.Sprintf(`    rootfs.before:
         - name: Add state partition
           layout:
             device:
@@ -194,15 +188,15 @@ stages:
                   pLabel: %[7]s
                   filesystem: %[5]s
 `,
-		sdkConstants.RecoveryLabel,      // 1
-		sdkConstants.StateLabel,         // 2
-		stateSize,                       // 3
-		sdkConstants.StatePartName,      // 4
-		sdkConstants.LinuxImgFs,         // 5
-		sdkConstants.PersistentLabel,    // 6
-		sdkConstants.PersistentPartName, // 7
-		resetCloudInit,                  // 8
-	)
+			sdkConstants.RecoveryLabel,      // 1
+			sdkConstants.StateLabel,         // 2
+			stateSize,                       // 3
+			sdkConstants.StatePartName,      // 4
+			sdkConstants.LinuxImgFs,         // 5
+			sdkConstants.PersistentLabel,    // 6
+			sdkConstants.PersistentPartName, // 7
+		)
+	}
 
 	// Save the cloud config
 	internal.Log.Logger.Debug().Str("target", filepath.Join(tmpDirOem, resetCloudInit)).Msg("Creating reset cloud config")
@@ -232,6 +226,25 @@ stages:
 	return OemPartitionImage.File, nil
 }
 
+// stateSize returns the size of the state partition in MB, the configured one or one fitting
+// the active and passive images next to the recovery one.
+func (r *RawImage) stateSize(recoveryImagePath string) (int64, error) {
+	if r.StateSize > 0 {
+		// Use the state size from the config
+		// stateSize is in MB so we can use it directly
+		return r.StateSize, nil
+	}
+	// Calculate the size of the state partition based on the recovery image size
+	info, err := r.config.Fs.Stat(recoveryImagePath)
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Str("source", recoveryImagePath).Msg("failed to stat recovery image")
+		return 0, err
+	}
+	stateSize := (info.Size()*3 + 100*1024*1024) / (1024 * 1024)
+	internal.Log.Logger.Debug().Int64("size", stateSize).Msg("calculated state partition size")
+	return stateSize, nil
+}
+
 // createCurtinLandingPartitionImage builds a tiny ext2 partition that curtin
 // will select as its target (it holds /curtin). It carries a static busybox
 // (so the chroot needs no libc), stub cloud-init/netplan (so MAAS's in-target
@@ -452,7 +465,7 @@ func (r *RawImage) createEFIPartitionImage() (string, error) {
 		return "", err
 	}
 
-	model, flavor, err := r.GetModelAndFlavor()
+	_, flavor, err := r.GetModelAndFlavor()
 
 	if err != nil {
 		internal.Log.Logger.Error().Err(err).Msg("failed to get flavor or model")
@@ -491,13 +504,11 @@ func (r *RawImage) createEFIPartitionImage() (string, error) {
 		return "", err
 	}
 
-	// Copy RPI firmware, should be valid for rpi3,4,5 and beyond if the rpi firmware files are updated
-	if strings.HasPrefix(model, "rpi") {
-		err = copyFirmwareRpi(tmpDirEfi)
-		if err != nil {
-			internal.Log.Logger.Error().Err(err).Msg("failed to copy rpi firmware")
-			return "", err
-		}
+	// Copy the firmware and boot files of the board, like the Raspberry Pi firmware and its config.txt
+	err = r.copyBoardFiles(tmpDirEfi)
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Msg("failed to copy board firmware")
+		return "", err
 	}
 
 	efiPartitionImage := sdkImage.Image{
@@ -554,6 +565,12 @@ func (r *RawImage) Build() error {
 
 	defer r.config.Fs.RemoveAll(r.TempDir())
 
+	// Boards bring their firmware, bootloader and partition offsets from their profile
+	err = r.resolveBoard()
+	if err != nil {
+		return err
+	}
+
 	// Get the artifact version from the rootfs
 	outputName := fmt.Sprintf("%s-%s.raw", constants.KairosDefaultArtifactName, utils.NameFromRootfs(r.Source))
 	internal.Log.Logger.Debug().Str("name", outputName).Msg("Got output name")
@@ -616,15 +633,30 @@ func (r *RawImage) Build() error {
 
 	// Create the final disk image
 	internal.Log.Logger.Info().Str("target", filepath.Join(r.Output, outputName)).Msg("Assembling final disk image")
-	parts := []string{bootImagePath, oemImagePath, recoveryImagePath}
+	var landing string
 	if r.maas {
-		landing, err := r.createCurtinLandingPartitionImage()
+		landing, err = r.createCurtinLandingPartitionImage()
 		if err != nil {
 			internal.Log.Logger.Error().Err(err).Msg("failed to create curtin-landing partition")
 			return err
 		}
 		defer r.config.Fs.Remove(landing)
-		parts = []string{bootImagePath, landing, oemImagePath, recoveryImagePath}
+	}
+	parts, err := r.diskParts(bootImagePath, landing, oemImagePath, recoveryImagePath)
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Msg("failed to create layout partitions")
+		return err
+	}
+	if r.Layout.IsMBR() {
+		stateSize, err := r.stateSize(recoveryImagePath)
+		if err != nil {
+			return err
+		}
+		parts, err = r.appendMBRDataParts(parts, stateSize)
+		if err != nil {
+			internal.Log.Logger.Error().Err(err).Msg("failed to create state and persistent partitions")
+			return err
+		}
 	}
 	err = r.createDiskImage(filepath.Join(r.Output, outputName), parts)
 	if err != nil {
@@ -685,54 +717,56 @@ func (r *RawImage) emitPartitionImages(bootImagePath, oemImagePath, recoveryImag
 	return nil
 }
 
-// createDiskImage creates the final image by truncating the image with the proper size and
-// concatenating the contents of the given partitions.
-func (r *RawImage) createDiskImage(rawDiskFile string, partImgs []string) error {
-	var initDiskFile, endDiskFile string
-	var err error
-	var partFiles []string
-	var size uint64
-	var table partition.Table
-	var parts []*gpt.Partition
-
-	internal.Log.Logger.Debug().Str("disk", rawDiskFile).Strs("parts", partImgs).Msg("Creating disk image")
-
-	// Create disk image, 1Mb for alignment and GPT header, 2MB for bios boot partition
-	// Then concat all partition images
-	// Then add 1MB of free space at the end of the disk for gpt backup headers
+// createDiskImage creates the final image by concatenating the contents of the given partitions
+// at the sectors planDisk places them, and partitioning it.
+func (r *RawImage) createDiskImage(rawDiskFile string, parts []diskPart) error {
+	var partImgs, partFiles []string
 
-	// Create the start and end images
-	initDiskFile = filepath.Join(r.TempDir(), "init.raw")
-	endDiskFile = filepath.Join(r.TempDir(), "end.raw")
-	// THIS SIZE MARKS THE START SECTOR FOR THE PARTITIONS BELOW!
-	// So this mean we have an empty 2048 sectors at the start of the disk, partitions then start at that point
-	init, err := fileBackend.CreateFromPath(filepath.Join(r.TempDir(), "init.raw"), 1*1024*1024)
-	if err != nil {
-		internal.Log.Logger.Error().Err(err).Str("target", initDiskFile).Msg("failed to create init disk")
-		return err
+	for i, p := range parts {
+		partImgs = append(partImgs, p.img)
+		if p.size != 0 {
+			continue
+		}
+		stat, err := os.Stat(p.img)
+		if err != nil {
+			internal.Log.Logger.Error().Err(err).Str("target", p.img).Msg("failed to stat partition")
+			return err
+		}
+		parts[i].size = uint64(stat.Size())
 	}
-	end, err := fileBackend.CreateFromPath(filepath.Join(r.TempDir(), "end.raw"), 1*1024*1024)
+	internal.Log.Logger.Debug().Str("disk", rawDiskFile).Strs("parts", partImgs).Msg("Creating disk image")
+	plan, err := planDisk(parts, r.Layout.IsMBR())
 	if err != nil {
-		internal.Log.Logger.Error().Err(err).Str("target", endDiskFile).Msg("failed to create end disk")
 		return err
 	}
-	err = init.Close()
-	if err != nil {
-		internal.Log.Logger.Error().Err(err).Str("target", initDiskFile).Msg("failed to close init disk")
-		return err
+
+	// 1Mb for alignment and the partition table come first, then all partition images where
+	// they are placed, with zeroes filling the gaps between them.
+	// Then 1MB of free space at the end of the disk for gpt backup headers
+	gap := func(size uint64) error {
+		file := filepath.Join(r.TempDir(), fmt.Sprintf("gap-%d.raw", len(partFiles)))
+		f, err := fileBackend.CreateFromPath(file, int64(size))
+		if err != nil {
+			internal.Log.Logger.Error().Err(err).Str("target", file).Msg("failed to create disk gap")
+			return err
+		}
+		partFiles = append(partFiles, file)
+		return f.Close()
+	}
+	var written uint64
+	for _, p := range plan.parts {
+		if start := p.start * diskSectorSize; start > written {
+			if err = gap(start - written); err != nil {
+				return err
+			}
+			written = start
+		}
+		partFiles = append(partFiles, p.img)
+		written += p.size
 	}
-	err = end.Close()
-	if err != nil {
-		internal.Log.Logger.Error().Err(err).Str("target", endDiskFile).Msg("failed to close end disk")
+	if err = gap(plan.sectors*diskSectorSize - written); err != nil {
 		return err
 	}
-
-	// List and concatenate all image files
-	partFiles = append(partFiles, initDiskFile)
-	for _, img := range partImgs {
-		partFiles = append(partFiles, img)
-	}
-	partFiles = append(partFiles, endDiskFile)
 	err = utils.ConcatFiles(vfs.OSFS, partFiles, rawDiskFile)
 	if err != nil {
 		return err
@@ -746,123 +780,49 @@ func (r *RawImage) createDiskImage(rawDiskFile string, partImgs []string) error
 	}
 	defer finalDisk.Close()
 
-	// Truncate file to multiple of sector size
-	stat, _ := os.Stat(rawDiskFile)
-	size = roundToNearestSector(stat.Size(), finalDisk.LogicalBlocksize)
-	err = os.Truncate(rawDiskFile, int64(size))
+	// A GPT partition table, or an MBR one with the EBRs of its logical partitions
+	var table partition.Table = plan.gptTable(int(finalDisk.LogicalBlocksize), int(finalDisk.PhysicalBlocksize))
+	if plan.mbr {
+		table = plan.mbrTable(int(finalDisk.LogicalBlocksize), int(finalDisk.PhysicalBlocksize))
+	}
+	err = finalDisk.Partition(table)
 	if err != nil {
-		internal.Log.Logger.Error().Err(err).Str("target", rawDiskFile).Msg("failed to truncate final disk")
+		internal.Log.Logger.Error().Err(err).Str("target", rawDiskFile).Msg("failed to partition final disk")
 		return err
 	}
-
-	// Create a GPT partition table
-	// Bit 0: System partition This indicates the system that this is a required partition and to not mess with it.
-	// Bit 2: Legacy BIOS bootable This indicates that this partition is bootable by legacy BIOS.
-	if r.efi {
-		// EFI
-		stat, err = os.Stat(partImgs[0])
+	if plan.extStart != 0 {
+		f, err := os.OpenFile(rawDiskFile, os.O_WRONLY, 0)
 		if err != nil {
-			internal.Log.Logger.Error().Err(err).Str("target", partImgs[0]).Msg("failed to stat efi partition")
 			return err
 		}
-		size = roundToNearestSector(stat.Size(), finalDisk.LogicalBlocksize)
-		parts = append(parts, &gpt.Partition{
-			Index:      1,
-			Start:      2048,
-			End:        getSectorEndFromSize(2048, size, finalDisk.LogicalBlocksize),
-			Type:       gpt.EFISystemPartition,
-			Size:       size,
-			Name:       sdkConstants.EfiPartName,
-			GUID:       uuid.NewV5(uuid.NamespaceURL, sdkConstants.EfiLabel).String(),
-			Attributes: 1 << 0, // Sets bit 0
-		})
-	} else {
-		size = roundToNearestSector(int64(sdkConstants.BiosSize*1024*1024), finalDisk.LogicalBlocksize)
-		parts = append(parts, &gpt.Partition{
-			Index:      1,
-			Start:      2048,
-			End:        getSectorEndFromSize(2048, size, finalDisk.LogicalBlocksize),
-			Type:       gpt.BIOSBoot,
-			Size:       size,
-			Name:       sdkConstants.BiosPartName,
-			GUID:       uuid.NewV5(uuid.NamespaceURL, sdkConstants.EfiLabel).String(), // Same name as EFI, COS_GRUB usually
-			Attributes: (1 << 0) | (1 << 2),                                           // Sets bits 0 and 2
-		})
-	}
-
-	// Build the remaining partitions (OEM, Recovery for 3-part; landing, OEM, Recovery for 4-part)
-	type postBootPart struct {
-		img       string
-		name      string
-		guidLabel string
-	}
-	var pbp []postBootPart
-	if len(partImgs) == 4 {
-		// maas: boot, landing, oem, recovery
-		pbp = []postBootPart{
-			{partImgs[1], "curtin", "COS_CURTIN"},
-			{partImgs[2], sdkConstants.OEMPartName, sdkConstants.OEMLabel},
-			{partImgs[3], agentConstants.RecoveryImgName, sdkConstants.RecoveryLabel},
-		}
-	} else {
-		pbp = []postBootPart{
-			{partImgs[1], sdkConstants.OEMPartName, sdkConstants.OEMLabel},
-			{partImgs[2], agentConstants.RecoveryImgName, sdkConstants.RecoveryLabel},
-		}
-	}
-	idx := 2
-	for _, p := range pbp {
-		st, err := os.Stat(p.img)
-		if err != nil {
-			internal.Log.Logger.Error().Err(err).Str("target", p.img).Msg("failed to stat partition")
+		defer f.Close()
+		if err = plan.writeEBRs(f); err != nil {
+			internal.Log.Logger.Error().Err(err).Str("target", rawDiskFile).Msg("failed to write logical partitions")
 			return err
 		}
-		psize := roundToNearestSector(st.Size(), finalDisk.LogicalBlocksize)
-		start := parts[len(parts)-1].End + 1
-		parts = append(parts, &gpt.Partition{
-			Index: idx,
-			Start: start,
-			End:   getSectorEndFromSize(start, psize, finalDisk.LogicalBlocksize),
-			Type:  gpt.LinuxFilesystem,
-			Size:  psize,
-			Name:  p.name,
-			GUID:  uuid.NewV5(uuid.NamespaceURL, p.guidLabel).String(),
-		})
-		idx++
-	}
-
-	table = &gpt.Table{
-		ProtectiveMBR:      true,
-		GUID:               agentConstants.DiskUUID, // Set know predictable UUID
-		Partitions:         parts,
-		LogicalSectorSize:  int(finalDisk.LogicalBlocksize),
-		PhysicalSectorSize: int(finalDisk.PhysicalBlocksize),
-	}
-	err = finalDisk.Partition(table)
-	if err != nil {
-		internal.Log.Logger.Error().Err(err).Str("target", rawDiskFile).Msg("failed to partition final disk")
-		return err
 	}
 
 	// If its not efi, we need to install grub to the disk device directly
 	if !r.efi {
-		err = r.installGrubToDisk(rawDiskFile)
+		recovery, _ := plan.partitionByLabel(sdkConstants.RecoveryLabel)
+		err = r.installGrubToDisk(rawDiskFile, recovery.index)
 		if err != nil {
 			internal.Log.Logger.Error().Err(err).Str("target", rawDiskFile).Msg("failed to install grub to final disk")
 			return err
 		}
 	}
 
+	err = r.writeBootloader(rawDiskFile, plan)
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Str("target", rawDiskFile).Msg("failed to write board bootloader")
+		return err
+	}
+
 	internal.Log.Logger.Info().Str("disk", rawDiskFile).Msg("Created disk image")
 
 	return nil
 }
 
-// Helper function to calculate the end sector for a given start and size based on the sector size
-func getSectorEndFromSize(start, size uint64, sectorSize int64) uint64 {
-	return (size / uint64(sectorSize)) + start - 1
-}
-
 // Helper function to round size to the nearest multiple of the sector size
 func roundToNearestSector(size, sector int64) uint64 {
 	if size%sector == 0 {
@@ -944,7 +904,9 @@ func (r *RawImage) copyShimOrGrub(target, which string) error {
 	return nil
 }
 
-func (r *RawImage) installGrubToDisk(image string) error {
+// installGrubToDisk installs grub to the MBR of image and its BIOS boot partition, or the gap after
+// the MBR, keeping its modules and config in the recovery partition numbered recoveryIndex.
+func (r *RawImage) installGrubToDisk(image string, recoveryIndex int) error {
 	internal.Log.Logger.Debug().Str("backingFile", image).Msg("Attaching file to loop device")
 	// Create a dir to store the recovery partition contents
 	tmpDirRecovery := filepath.Join(r.TempDir(), "recovery")
@@ -1003,7 +965,7 @@ func (r *RawImage) installGrubToDisk(image string) error {
 	// so, the grub files are stored in the recovery partition
 	// Get only the loop device without the /dev/ prefix
 	cleanLoopDevice := string(loopDevice)[5:]
-	recoveryLoop := fmt.Sprintf("/dev/mapper/%s%s", cleanLoopDevice, "p3")
+	recoveryLoop := fmt.Sprintf("/dev/mapper/%sp%d", cleanLoopDevice, recoveryIndex)
 	err = unix.Mount(recoveryLoop, tmpDirRecovery, agentConstants.LinuxImgFs, 0, "")
 	if err != nil {
 		internal.Log.Logger.Error().Err(err).Str("device", recoveryLoop).Str("mountpoint", tmpDirRecovery).Msg("failed to mount recovery partition")
@@ -1083,55 +1045,11 @@ func (r *RawImage) GetModelAndFlavor() (string, string, error) {
 	return model, flavor, nil
 }
 
-// FinalizeImage does some final adjustments to the image
+// FinalizeImage does some final adjustments to the image.
+// Board bootloaders are written with the partition table, see writeBootloader.
 func (r *RawImage) FinalizeImage(image string) error {
-	var err error
-
-	// Get the model
-	model, _, err := r.GetModelAndFlavor()
-	if err != nil {
-		internal.Log.Logger.Error().Err(err).Msg("failed to get flavor or model")
-		return err
-	}
-
-	// Do board specific stuff
-	switch model {
-	case "rpi4", "rpi3":
-		// RPI firmware is done in the EFI partition
-		internal.Log.Logger.Debug().Str("model", model).Msg("Running on RPI.")
-	case "odroid-c2":
-		internal.Log.Logger.Debug().Str("model", model).Msg("Running on Odroid-C2.")
-		err = utils.DD("/arm/odroid-c2/bl1.bin.hardkernel", image, 1, 442, 0, 0)
-		if err != nil {
-			internal.Log.Logger.Error().Err(err).Msg("failed to dd bl1.bin.hardkernel")
-			return err
-		}
-		err = utils.DD("/arm/odroid-c2/bl1.bin.hardkernel", image, 512, 0, 1, 1)
-		if err != nil {
-			internal.Log.Logger.Error().Err(err).Msg("failed to dd bl1.bin.hardkernel")
-			return err
-		}
-		err = utils.DD("/arm/odroid-c2/u-boot.odroidc2", image, 512, 0, 0, 97)
-		if err != nil {
-			internal.Log.Logger.Error().Err(err).Msg("failed to dd u-boot.odroidc2")
-			return err
-		}
-	case "pinebookpro":
-		internal.Log.Logger.Debug().Str("model", model).Msg("Running on Pinebook Pro.")
-		err = utils.DD("/arm/pinebookpro/idbloader.img", image, 64, 0, 0, 0)
-		if err != nil {
-			internal.Log.Logger.Error().Err(err).Msg("failed to dd idbloader.img")
-			return err
-		}
-		err = utils.DD("/arm/pinebookpro/u-boot.itb", image, 16384, 0, 0, 0)
-		if err != nil {
-			internal.Log.Logger.Error().Err(err).Msg("failed to dd u-boot.itb")
-			return err
-		}
-	}
-
 	// Set the final image to be used by all as we run inside a container and the image is owned by root otherwise
-	err = r.config.Fs.Chmod(image, 0777)
+	err := r.config.Fs.Chmod(image, 0777)
 	if err != nil {
 		internal.Log.Logger.Error().Err(err).Msg("failed to chmod final image")
 		return err
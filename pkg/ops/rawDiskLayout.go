@@ -0,0 +1,419 @@
+package ops
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/diskfs/go-diskfs/partition/gpt"
+	"github.com/diskfs/go-diskfs/partition/mbr"
+	"github.com/gofrs/uuid"
+	"github.com/kairos-io/AuroraBoot/internal"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	agentConstants "github.com/kairos-io/kairos-agent/v2/pkg/constants"
+	fsutils "github.com/kairos-io/kairos-agent/v2/pkg/utils/fs"
+	sdkConstants "github.com/kairos-io/kairos-sdk/constants"
+	sdkImage "github.com/kairos-io/kairos-sdk/types/images"
+)
+
+const (
+	// diskSectorSize is the logical sector size of the raw disk files.
+	diskSectorSize = 512
+	// diskAlignment, in sectors, is where the first partition starts, the
+	// room an extended partition leaves for the EBR before each logical
+	// partition, and the room left after the last partition for the GPT
+	// backup header.
+	diskAlignment = 2048
+	// mbrPrimaryPartitions is how many partitions an MBR table holds before
+	// it needs an extended partition for the rest.
+	mbrPrimaryPartitions = 4
+)
+
+// diskPart is a partition of the raw disk, in disk order.
+type diskPart struct {
+	img       string // image written to the partition
+	size      uint64 // size of the partition in bytes, the size of img when zero
+	offset    uint64 // byte offset the partition starts at, zero to follow the previous one
+	name      string // GPT partition name
+	guidLabel string // label the GPT partition GUID is derived from
+	gptType   gpt.Type
+	mbrType   mbr.Type
+	attrs     uint64 // GPT attributes
+	bootable  bool   // MBR active flag
+
+	// Set by planDisk.
+	index int    // partition number, as the kernel names the partition
+	ebr   uint64 // sector of the EBR of a logical partition
+	start uint64 // first sector
+	end   uint64 // last sector
+}
+
+// diskPlan places the partitions of a raw disk.
+type diskPlan struct {
+	parts []diskPart
+	mbr   bool
+	// extStart and extEnd are the first and last sectors of the extended
+	// partition of an MBR disk with more than four partitions.
+	extStart, extEnd uint64
+	// sectors is the size of the disk.
+	sectors uint64
+}
+
+// planDisk places parts one after the other from the first MiB of the disk,
+// or at their offset. On an MBR disk with more than four partitions, the
+// fourth and later ones are logical partitions of an extended partition.
+func planDisk(parts []diskPart, mbrTable bool) (diskPlan, error) {
+	plan := diskPlan{mbr: mbrTable}
+	extended := mbrTable && len(parts) > mbrPrimaryPartitions
+	next := uint64(diskAlignment)
+	for i, p := range parts {
+		logical := extended && i >= mbrPrimaryPartitions-1
+		p.index = i + 1
+		p.start = next
+		if logical {
+			// Logical partitions are numbered from 5 whatever the primary ones are.
+			p.index = i + 2
+			p.start = next + diskAlignment
+		}
+		if p.offset > 0 {
+			at := p.offset / diskSectorSize
+			if at < p.start {
+				return plan, fmt.Errorf("partition %s at %d MiB overlaps the partition before it", p.name, p.offset>>20)
+			}
+			p.start = at
+		}
+		sectors := roundToNearestSector(int64(p.size), diskSectorSize) / diskSectorSize
+		if sectors == 0 {
+			return plan, fmt.Errorf("partition %s is empty", p.name)
+		}
+		p.end = p.start + sectors - 1
+		if logical {
+			p.ebr = p.start - diskAlignment
+			if plan.extStart == 0 {
+				plan.extStart = p.ebr
+			}
+			plan.extEnd = p.end
+		}
+		next = p.end + 1
+		plan.parts = append(plan.parts, p)
+	}
+	plan.sectors = next + diskAlignment
+	if mbrTable && plan.sectors > math.MaxUint32 {
+		return plan, fmt.Errorf("an MBR disk cannot address more than 2 TiB")
+	}
+	return plan, nil
+}
+
+// gptTable returns the GPT partition table of the plan.
+func (p diskPlan) gptTable(logicalSectorSize, physicalSectorSize int) *gpt.Table {
+	var parts []*gpt.Partition
+	for _, part := range p.parts {
+		parts = append(parts, &gpt.Partition{
+			Index:      part.index,
+			Start:      part.start,
+			End:        part.end,
+			Type:       part.gptType,
+			Size:       (part.end - part.start + 1) * diskSectorSize,
+			Name:       part.name,
+			GUID:       uuid.NewV5(uuid.NamespaceURL, part.guidLabel).String(),
+			Attributes: part.attrs,
+		})
+	}
+	return &gpt.Table{
+		ProtectiveMBR:      true,
+		GUID:               agentConstants.DiskUUID, // Set know predictable UUID
+		Partitions:         parts,
+		LogicalSectorSize:  logicalSectorSize,
+		PhysicalSectorSize: physicalSectorSize,
+	}
+}
+
+// mbrTable returns the MBR partition table of the plan. Logical partitions
+// are left to writeEBRs; the table only holds the extended partition that
+// spans them.
+func (p diskPlan) mbrTable(logicalSectorSize, physicalSectorSize int) *mbr.Table {
+	var parts []*mbr.Partition
+	for _, part := range p.parts {
+		if part.ebr != 0 {
+			break
+		}
+		parts = append(parts, &mbr.Partition{
+			Index:    part.index,
+			Bootable: part.bootable,
+			Type:     part.mbrType,
+			Start:    uint32(part.start),
+			Size:     uint32(part.end - part.start + 1),
+		})
+	}
+	if p.extStart != 0 {
+		parts = append(parts, &mbr.Partition{
+			Index: mbrPrimaryPartitions,
+			Type:  mbr.ExtendedLBA,
+			Start: uint32(p.extStart),
+			Size:  uint32(p.extEnd - p.extStart + 1),
+		})
+	}
+	return &mbr.Table{
+		Partitions:         parts,
+		LogicalSectorSize:  logicalSectorSize,
+		PhysicalSectorSize: physicalSectorSize,
+	}
+}
+
+// writeEBRs writes the chain of extended boot records describing the
+// logical partitions of the plan. Each one points at its partition relative
+// to itself and at the next EBR relative to the extended partition.
+func (p diskPlan) writeEBRs(w io.WriterAt) error {
+	var logical []diskPart
+	for _, part := range p.parts {
+		if part.ebr != 0 {
+			logical = append(logical, part)
+		}
+	}
+	for i, part := range logical {
+		ebr := make([]byte, diskSectorSize)
+		putMBREntry(ebr[446:], part.mbrType, part.bootable, part.start-part.ebr, part.end-part.start+1)
+		if i+1 < len(logical) {
+			n := logical[i+1]
+			putMBREntry(ebr[462:], mbr.ExtendedCHS, false, n.ebr-p.extStart, n.end-n.ebr+1)
+		}
+		ebr[510], ebr[511] = 0x55, 0xaa
+		if _, err := w.WriteAt(ebr, int64(part.ebr)*diskSectorSize); err != nil {
+			return fmt.Errorf("writing the EBR of partition %d: %w", part.index, err)
+		}
+	}
+	return nil
+}
+
+// putMBREntry writes a 16 byte MBR partition entry addressed by LBA only.
+func putMBREntry(b []byte, t mbr.Type, bootable bool, start, size uint64) {
+	if bootable {
+		b[0] = 0x80
+	}
+	b[4] = byte(t)
+	binary.LittleEndian.PutUint32(b[8:], uint32(start))
+	binary.LittleEndian.PutUint32(b[12:], uint32(size))
+}
+
+// partitionByLabel returns the planned partition whose GUID derives from
+// label.
+func (p diskPlan) partitionByLabel(label string) (diskPart, bool) {
+	for _, part := range p.parts {
+		if part.guidLabel == label {
+			return part, true
+		}
+	}
+	return diskPart{}, false
+}
+
+// overlap names what n bytes written at offset of the disk would overwrite:
+// the MBR partition entries, an EBR or a partition. It is empty when the
+// bytes fall in free space.
+func (p diskPlan) overlap(offset, n uint64) string {
+	end := offset + n
+	within := func(first, last uint64) bool {
+		return offset < (last+1)*diskSectorSize && end > first*diskSectorSize
+	}
+	if offset < diskSectorSize && end > 446 {
+		return "the MBR partition entries"
+	}
+	for _, part := range p.parts {
+		if part.ebr != 0 && within(part.ebr, part.ebr) {
+			return fmt.Sprintf("the EBR of partition %d", part.index)
+		}
+		if within(part.start, part.end) {
+			return fmt.Sprintf("partition %d", part.index)
+		}
+	}
+	return ""
+}
+
+// kairosPart returns the disk partition of a partition image Kairos builds.
+func kairosPart(img, name, label string) diskPart {
+	return diskPart{img: img, name: name, guidLabel: label, gptType: gpt.LinuxFilesystem, mbrType: mbr.Linux}
+}
+
+// bootPart returns the disk partition of the boot image: the EFI system
+// partition, or the BIOS boot partition grub-install writes its core image
+// to. It is nil for BIOS disks with an MBR table, which keep the core image
+// in the gap after the MBR.
+func (r *RawImage) bootPart(img string) *diskPart {
+	if r.efi {
+		return &diskPart{
+			img:       img,
+			name:      sdkConstants.EfiPartName,
+			guidLabel: sdkConstants.EfiLabel,
+			gptType:   gpt.EFISystemPartition,
+			mbrType:   mbr.EFISystem,
+			attrs:     1 << 0, // Sets bit 0
+			bootable:  true,
+		}
+	}
+	if r.Layout.IsMBR() {
+		return nil
+	}
+	return &diskPart{
+		img:       img,
+		name:      sdkConstants.BiosPartName,
+		guidLabel: sdkConstants.EfiLabel, // Same name as EFI, COS_GRUB usually
+		gptType:   gpt.BIOSBoot,
+		attrs:     (1 << 0) | (1 << 2), // Sets bits 0 and 2
+	}
+}
+
+// diskParts lays out the partition images Build created as r.Layout says,
+// creating the partitions the layout adds. The curtin landing partition of
+// MAAS disks, when given, follows the boot partition.
+func (r *RawImage) diskParts(boot, landing, oem, recovery string) ([]diskPart, error) {
+	entries := r.Layout.Partitions
+	if len(entries) == 0 {
+		entries = []schema.Partition{
+			{Role: schema.PartitionRoleBoot},
+			{Role: schema.PartitionRoleOEM},
+			{Role: schema.PartitionRoleRecovery},
+		}
+	}
+
+	var parts []diskPart
+	for i, e := range entries {
+		var part diskPart
+		switch e.Role {
+		case schema.PartitionRoleBoot:
+			p := r.bootPart(boot)
+			if p == nil {
+				continue
+			}
+			part = *p
+		case schema.PartitionRoleOEM:
+			part = kairosPart(oem, sdkConstants.OEMPartName, sdkConstants.OEMLabel)
+		case schema.PartitionRoleRecovery:
+			part = kairosPart(recovery, agentConstants.RecoveryImgName, sdkConstants.RecoveryLabel)
+			// With no boot partition, some BIOSes still look for an active one.
+			part.bootable = !r.efi && r.Layout.IsMBR()
+		default:
+			img, err := r.createLayoutPartitionImage(i, e)
+			if err != nil {
+				return nil, err
+			}
+			part = diskPart{img: img, name: e.Name, guidLabel: e.Label, gptType: gpt.LinuxFilesystem, mbrType: mbr.Linux}
+			if part.name == "" {
+				part.name = e.Label
+			}
+			if part.guidLabel == "" {
+				part.guidLabel = e.Name
+			}
+			if e.FS == "vfat" {
+				part.gptType, part.mbrType = gpt.MicrosoftBasicData, mbr.Fat32LBA
+			}
+		}
+		part.offset = e.Offset << 20
+		if e.Name != "" {
+			part.name = e.Name
+		}
+		if e.Type != "" {
+			if r.Layout.IsMBR() {
+				t, err := schema.ParseMBRType(e.Type)
+				if err != nil {
+					return nil, err
+				}
+				part.mbrType = mbr.Type(t)
+			} else {
+				part.gptType = gpt.Type(strings.ToUpper(e.Type))
+			}
+		}
+		parts = append(parts, part)
+		if e.Role == schema.PartitionRoleBoot && landing != "" {
+			parts = append(parts, kairosPart(landing, "curtin", "COS_CURTIN"))
+		}
+	}
+	return parts, nil
+}
+
+// createLayoutPartitionImage creates the image of a partition the layout
+// adds: a filesystem holding the layout's content directory, or an
+// unformatted partition when it sets no filesystem.
+func (r *RawImage) createLayoutPartitionImage(i int, p schema.Partition) (string, error) {
+	name := fmt.Sprintf("layout-%d", i)
+	if p.FS == "" {
+		file := filepath.Join(r.TempDir(), name+".img")
+		f, err := os.Create(file)
+		if err != nil {
+			internal.Log.Logger.Error().Err(err).Str("target", file).Msg("failed to create partition image")
+			return "", err
+		}
+		defer f.Close()
+		return file, f.Truncate(int64(p.Size) << 20)
+	}
+	if p.Content != "" {
+		if info, err := os.Stat(p.Content); err != nil || !info.IsDir() {
+			return "", fmt.Errorf("content of partition %s: %s is not a directory", p.Label, p.Content)
+		}
+	}
+	return r.createFilesystemImage(name, p.FS, p.Label, uint(p.Size), p.Content)
+}
+
+// createFilesystemImage creates a partition image of size MiB with a fs
+// filesystem labelled label, holding the contents of dir when given.
+func (r *RawImage) createFilesystemImage(name, fs, label string, size uint, dir string) (string, error) {
+	if dir == "" {
+		dir = filepath.Join(r.TempDir(), name)
+		if err := fsutils.MkdirAll(r.config.Fs, dir, 0755); err != nil {
+			return "", err
+		}
+		defer r.config.Fs.RemoveAll(dir)
+	}
+	mount := filepath.Join(r.TempDir(), name+"-mount")
+	if err := fsutils.MkdirAll(r.config.Fs, mount, 0755); err != nil {
+		return "", err
+	}
+	defer r.config.Fs.RemoveAll(mount)
+
+	img := sdkImage.Image{
+		File:       filepath.Join(r.TempDir(), name+".img"),
+		FS:         fs,
+		Label:      label,
+		Size:       size,
+		Source:     sdkImage.NewDirSrc(dir),
+		MountPoint: mount,
+	}
+	if _, err := r.elemental.DeployImageNodirs(&img, false); err != nil {
+		internal.Log.Logger.Error().Err(err).Interface("image", img).Msg("failed to create partition image")
+		return "", err
+	}
+	return img.File, nil
+}
+
+// appendMBRDataParts adds the state and persistent partitions to the parts
+// of an MBR disk, which are not added on first boot as on GPT disks. The
+// persistent partition fills the disk up to r.FinalSize.
+func (r *RawImage) appendMBRDataParts(parts []diskPart, stateSize int64) ([]diskPart, error) {
+	state, err := r.createFilesystemImage("state", sdkConstants.LinuxImgFs, sdkConstants.StateLabel, uint(stateSize), "")
+	if err != nil {
+		return nil, err
+	}
+	parts = append(parts,
+		kairosPart(state, sdkConstants.StatePartName, sdkConstants.StateLabel),
+		kairosPart("", sdkConstants.PersistentPartName, sdkConstants.PersistentLabel),
+	)
+	// Plan with a one sector persistent partition to find where it starts.
+	parts[len(parts)-1].size = diskSectorSize
+	plan, err := planDisk(parts, true)
+	if err != nil {
+		return nil, err
+	}
+	start := plan.parts[len(plan.parts)-1].start * diskSectorSize
+	if r.FinalSize<<20 < start+(1<<20)+diskAlignment*diskSectorSize {
+		return nil, fmt.Errorf("disk.size of %d MiB leaves no room for the persistent partition, which would start at %d MiB", r.FinalSize, start>>20)
+	}
+	end := r.FinalSize<<20 - diskAlignment*diskSectorSize
+	persistent, err := r.createFilesystemImage("persistent", sdkConstants.LinuxImgFs, sdkConstants.PersistentLabel, uint((end-start)>>20), "")
+	if err != nil {
+		return nil, err
+	}
+	parts[len(parts)-1] = kairosPart(persistent, sdkConstants.PersistentPartName, sdkConstants.PersistentLabel)
+	return parts, nil
+}
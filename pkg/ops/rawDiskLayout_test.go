@@ -0,0 +1,225 @@
+package ops
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/diskfs/go-diskfs/partition/gpt"
+	"github.com/diskfs/go-diskfs/partition/mbr"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	sdkConstants "github.com/kairos-io/kairos-sdk/constants"
+)
+
+const mib = 1 << 20
+
+// sizedParts returns partitions of the given sizes in MiB, named after their position.
+func sizedParts(sizes ...uint64) []diskPart {
+	var parts []diskPart
+	for i, size := range sizes {
+		parts = append(parts, diskPart{name: string(rune('a' + i)), size: size * mib, mbrType: mbr.Linux})
+	}
+	return parts
+}
+
+// Without a layout the partitions follow each other from the first MiB, as
+// they always have.
+func TestPlanDiskPacksPartitions(t *testing.T) {
+	plan, err := planDisk(sizedParts(64, 64, 4096), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		index      int
+		start, end uint64
+	}{{1, 2048, 133119}, {2, 133120, 264191}, {3, 264192, 8652799}}
+	for i, w := range want {
+		p := plan.parts[i]
+		if p.index != w.index || p.start != w.start || p.end != w.end {
+			t.Errorf("partition %d: index %d, sectors %d-%d, want %d, %d-%d", i, p.index, p.start, p.end, w.index, w.start, w.end)
+		}
+	}
+	if plan.sectors != 8652800+2048 {
+		t.Errorf("disk is %d sectors, want 1 MiB past the last partition", plan.sectors)
+	}
+	if plan.extStart != 0 {
+		t.Error("a GPT disk has no extended partition")
+	}
+}
+
+func TestPlanDiskOffsets(t *testing.T) {
+	parts := sizedParts(4, 64, 64)
+	parts[0].offset = 1 * mib
+	parts[1].offset = 8 * mib
+	plan, err := planDisk(parts, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.parts[0].start != 2048 || plan.parts[1].start != 8*2048 || plan.parts[2].start != 72*2048 {
+		t.Errorf("starts %d, %d, %d", plan.parts[0].start, plan.parts[1].start, plan.parts[2].start)
+	}
+
+	parts[1].offset = 4 * mib
+	if _, err := planDisk(parts, false); err == nil {
+		t.Error("a partition starting inside the one before it should be rejected")
+	}
+}
+
+func TestPlanDiskMBRLogicalPartitions(t *testing.T) {
+	plan, err := planDisk(sizedParts(64, 64, 64, 64, 64, 64), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range plan.parts {
+		if logical := i >= 3; logical != (p.ebr != 0) {
+			t.Fatalf("partition %d: ebr %d", i, p.ebr)
+		}
+		if want := []int{1, 2, 3, 5, 6, 7}[i]; p.index != want {
+			t.Errorf("partition %d is numbered %d, want %d", i, p.index, want)
+		}
+		if p.ebr != 0 && p.start-p.ebr != 2048 {
+			t.Errorf("logical partition %d starts %d sectors after its EBR", p.index, p.start-p.ebr)
+		}
+	}
+	if plan.extStart != plan.parts[2].end+1 || plan.extEnd != plan.parts[5].end {
+		t.Errorf("extended partition %d-%d", plan.extStart, plan.extEnd)
+	}
+
+	// Up to four partitions are all primary.
+	plan, err = planDisk(sizedParts(64, 64, 64, 64), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.extStart != 0 || plan.parts[3].index != 4 {
+		t.Error("four partitions should fit in the primary entries")
+	}
+}
+
+// The MBR and EBR chain written for a plan describe each partition where
+// the plan places it, as the kernel reads them.
+func TestMBRTableAndEBRs(t *testing.T) {
+	parts := sizedParts(64, 64, 64, 128, 256)
+	parts[0].bootable = true
+	parts[0].mbrType = mbr.Fat32LBA
+	plan, err := planDisk(parts, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk, err := os.Create(filepath.Join(t.TempDir(), "disk.raw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer disk.Close()
+	if err := disk.Truncate(int64(plan.sectors) * diskSectorSize); err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.mbrTable(diskSectorSize, diskSectorSize).Write(disk, int64(plan.sectors)*diskSectorSize); err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.writeEBRs(disk); err != nil {
+		t.Fatal(err)
+	}
+
+	sector := func(lba uint64) []byte {
+		b := make([]byte, diskSectorSize)
+		if _, err := disk.ReadAt(b, int64(lba)*diskSectorSize); err != nil {
+			t.Fatal(err)
+		}
+		if b[510] != 0x55 || b[511] != 0xaa {
+			t.Fatalf("sector %d has no boot signature", lba)
+		}
+		return b
+	}
+	type entry struct {
+		boot        bool
+		typ         byte
+		start, size uint64
+	}
+	read := func(b []byte, i int) entry {
+		e := b[446+16*i:]
+		return entry{e[0] == 0x80, e[4], uint64(binary.LittleEndian.Uint32(e[8:])), uint64(binary.LittleEndian.Uint32(e[12:]))}
+	}
+
+	var got []entry
+	mbrSector := sector(0)
+	for i := 0; i < 3; i++ {
+		got = append(got, read(mbrSector, i))
+	}
+	ext := read(mbrSector, 3)
+	if ext.typ != byte(mbr.ExtendedLBA) || ext.start != plan.extStart {
+		t.Fatalf("extended partition entry %+v", ext)
+	}
+	for ebr := ext.start; ; {
+		b := sector(ebr)
+		logical := read(b, 0)
+		logical.start += ebr
+		got = append(got, logical)
+		next := read(b, 1)
+		if next.typ == 0 {
+			break
+		}
+		ebr = ext.start + next.start
+	}
+
+	if len(got) != len(plan.parts) {
+		t.Fatalf("read %d partitions, want %d", len(got), len(plan.parts))
+	}
+	for i, p := range plan.parts {
+		want := entry{p.bootable, byte(p.mbrType), p.start, p.end - p.start + 1}
+		if got[i] != want {
+			t.Errorf("partition %d: %+v, want %+v", p.index, got[i], want)
+		}
+	}
+}
+
+func TestDiskPartsFollowsTheLayout(t *testing.T) {
+	r := NewEFIRawImage(t.TempDir(), t.TempDir(), "", 0, 0, 0, true)
+	defer os.RemoveAll(r.TempDir())
+	r.Layout = schema.Layout{Partitions: []schema.Partition{
+		{Name: "firmware", Size: 4, Offset: 1, Type: "21686148-6449-6e6f-744e-656564454649"},
+		{Role: schema.PartitionRoleBoot, Offset: 8},
+		{Role: schema.PartitionRoleOEM},
+		{Role: schema.PartitionRoleRecovery},
+	}}
+
+	parts, err := r.diskParts("efi.img", "curtin.img", "oem.img", "recovery.img")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range parts {
+		names = append(names, p.name)
+	}
+	if want := []string{"firmware", sdkConstants.EfiPartName, "curtin", sdkConstants.OEMPartName, "recovery"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("partitions %v, want %v", names, want)
+	}
+	firmware := parts[0]
+	if firmware.offset != 1*mib || firmware.gptType != gpt.Type("21686148-6449-6E6F-744E-656564454649") {
+		t.Errorf("firmware partition %+v", firmware)
+	}
+	if info, err := os.Stat(firmware.img); err != nil || info.Size() != 4*mib {
+		t.Errorf("unformatted firmware partition image: %v", err)
+	}
+	if parts[1].offset != 8*mib || parts[1].gptType != gpt.EFISystemPartition {
+		t.Errorf("boot partition %+v", parts[1])
+	}
+}
+
+// BIOS disks with an MBR table keep grub's core image after the MBR, so
+// they have no boot partition and their recovery partition is active.
+func TestDiskPartsBIOSMBR(t *testing.T) {
+	r := NewBiosRawImage(t.TempDir(), t.TempDir(), "", 0, 0, 0, true)
+	r.Layout = schema.Layout{Table: schema.PartitionTableMBR}
+	parts, err := r.diskParts("bios.img", "", "oem.img", "recovery.img")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 || parts[0].guidLabel != sdkConstants.OEMLabel || parts[1].guidLabel != sdkConstants.RecoveryLabel {
+		t.Fatalf("partitions %+v", parts)
+	}
+	if !parts[1].bootable || parts[1].mbrType != mbr.Linux {
+		t.Errorf("recovery partition %+v", parts[1])
+	}
+}
@@ -11,13 +11,14 @@ import (
 	"io/fs"
 	"math"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/kairos-io/AuroraBoot/pkg/utils"
-
 	uuidPkg "github.com/gofrs/uuid"
 	"github.com/kairos-io/AuroraBoot/internal"
 	"github.com/kairos-io/AuroraBoot/pkg/constants"
+	"github.com/klauspost/compress/flate"
 )
 
 // Raw2Azure converts a raw disk to a VHD disk compatible with Azure
@@ -208,6 +209,38 @@ func Raw2Gce(source string) (string, error) {
 	return name, nil
 }
 
+// Raw2Qcow2 converts a raw disk into a qcow2 image for KVM, Proxmox and OpenStack.
+// Clusters that are all zeroes are left unallocated, so the image only takes the space of the data on the disk.
+// With compress every allocated cluster is deflated too, like qemu-img convert -c does.
+// The raw disk is left in place and a sibling .qcow2 is produced.
+func Raw2Qcow2(source string, compress bool) (string, error) {
+	internal.Log.Logger.Info().Str("source", source).Bool("compress", compress).Msg("Converting raw disk to qcow2")
+	name := fmt.Sprintf("%s.qcow2", strings.TrimSuffix(source, ".raw"))
+
+	in, err := os.Open(source)
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", source).Msg("Error opening raw image")
+		return name, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(name)
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", name).Msg("Error creating qcow2 output")
+		return name, err
+	}
+	defer out.Close()
+
+	if err := writeQcow2(out, in, compress); err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", name).Msg("Error writing qcow2 image")
+		return name, err
+	}
+	if err := out.Sync(); err != nil {
+		return name, err
+	}
+	return name, nil
+}
+
 /// VHD utils!
 
 type VHDHeader struct {
@@ -327,11 +360,238 @@ func chsCalculation(sectors uint64) chs {
 	}
 }
 
-// Model specific functions
+/// QCOW2 utils!
+// The layout follows docs/interop/qcow2.txt in the QEMU tree. The image is written in one pass: the header cluster
+// is reserved, the data clusters are appended as the raw disk is read, and the tables that map them (L2 tables,
+// the L1 table, the refcount table and its blocks) are written after them once their sizes are known.
+
+const (
+	qcow2Magic         = 0x514649fb // "QFI\xfb"
+	qcow2Version       = 3
+	qcow2HeaderLength  = 104 // size of qcow2Header, there are no header extensions
+	qcow2ClusterBits   = 16
+	qcow2ClusterSize   = 1 << qcow2ClusterBits
+	qcow2RefcountOrder = 4 // 16 bit refcounts
+	qcow2L2Entries     = qcow2ClusterSize / 8
+	qcow2RefsPerBlock  = qcow2ClusterSize / 2
+	qcow2SectorSize    = 512
+	// A compressed cluster descriptor holds the host offset in its low bits and the number of extra 512 byte
+	// sectors the compressed data spans above them.
+	qcow2CompressedSectorsShift = 62 - (qcow2ClusterBits - 8)
+	// qcow2Copied marks a cluster whose refcount is exactly one, qcow2Compressed a compressed cluster descriptor.
+	qcow2Copied     = uint64(1) << 63
+	qcow2Compressed = uint64(1) << 62
+	// QEMU inflates compressed clusters with a 4 KiB window (zlib window bits -12), so they must not be deflated
+	// with back references further than that.
+	qcow2DeflateWindow = 1 << 12
+)
+
+// qcow2Header is the version 3 header, big endian on disk
+type qcow2Header struct {
+	Magic                 uint32
+	Version               uint32
+	BackingFileOffset     uint64
+	BackingFileSize       uint32
+	ClusterBits           uint32
+	Size                  uint64 // virtual disk size in bytes
+	CryptMethod           uint32
+	L1Size                uint32 // number of entries in the L1 table
+	L1TableOffset         uint64
+	RefcountTableOffset   uint64
+	RefcountTableClusters uint32
+	NbSnapshots           uint32
+	SnapshotsOffset       uint64
+	IncompatibleFeatures  uint64
+	CompatibleFeatures    uint64
+	AutoclearFeatures     uint64
+	RefcountOrder         uint32
+	HeaderLength          uint32
+}
+
+type qcow2Writer struct {
+	out    io.WriterAt
+	offset int64               // next free byte in the image
+	l2     map[uint64][]uint64 // L2 tables, by L1 index
+	refs   []uint16            // refcount of every host cluster
+	zbuf   bytes.Buffer
+	zw     *flate.Writer // nil unless compressing
+}
+
+// writeQcow2 writes the raw disk read from in as a qcow2 image to out
+func writeQcow2(out io.WriterAt, in io.Reader, compress bool) error {
+	w := &qcow2Writer{out: out, offset: qcow2ClusterSize, l2: map[uint64][]uint64{}}
+	w.ref(0, qcow2ClusterSize) // header
+	if compress {
+		zw, err := flate.NewWriterWindow(&w.zbuf, qcow2DeflateWindow)
+		if err != nil {
+			return err
+		}
+		w.zw = zw
+	}
+
+	buf := make([]byte, qcow2ClusterSize)
+	zero := make([]byte, qcow2ClusterSize)
+	var size uint64
+	for cluster := uint64(0); ; cluster++ {
+		n, err := io.ReadFull(in, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		clear(buf[n:])
+		size += uint64(n)
+		if !bytes.Equal(buf, zero) {
+			entry, err := w.writeCluster(buf)
+			if err != nil {
+				return err
+			}
+			l1, i := cluster/qcow2L2Entries, cluster%qcow2L2Entries
+			if w.l2[l1] == nil {
+				w.l2[l1] = make([]uint64, qcow2L2Entries)
+			}
+			w.l2[l1][i] = entry
+		}
+		if n < qcow2ClusterSize {
+			break
+		}
+	}
+	return w.finish(size)
+}
+
+// writeCluster appends one cluster of data and returns its L2 entry. A compressed cluster is packed right after
+// the previous one; one that does not shrink is stored as is, at the next cluster boundary.
+func (w *qcow2Writer) writeCluster(buf []byte) (uint64, error) {
+	if w.zw != nil {
+		w.zbuf.Reset()
+		w.zw.Reset(&w.zbuf)
+		if _, err := w.zw.Write(buf); err != nil {
+			return 0, err
+		}
+		if err := w.zw.Close(); err != nil {
+			return 0, err
+		}
+		if l := int64(w.zbuf.Len()); l < qcow2ClusterSize {
+			off := w.offset
+			if _, err := w.out.WriteAt(w.zbuf.Bytes(), off); err != nil {
+				return 0, err
+			}
+			w.offset += l
+			sectors := (off+l-1)/qcow2SectorSize - off/qcow2SectorSize + 1
+			w.ref(off&^(qcow2SectorSize-1), sectors*qcow2SectorSize)
+			return uint64(off) | uint64(sectors-1)<<qcow2CompressedSectorsShift | qcow2Compressed, nil
+		}
+	}
+	off := qcow2Align(w.offset)
+	if _, err := w.out.WriteAt(buf, off); err != nil {
+		return 0, err
+	}
+	w.offset = off + qcow2ClusterSize
+	w.ref(off, qcow2ClusterSize)
+	return uint64(off) | qcow2Copied, nil
+}
+
+// finish writes the L2 tables, the L1 table, the refcounts and finally the header
+func (w *qcow2Writer) finish(size uint64) error {
+	off := qcow2Align(w.offset)
+
+	l1 := make([]uint64, (size+qcow2L2Entries*qcow2ClusterSize-1)/(qcow2L2Entries*qcow2ClusterSize))
+	indexes := make([]uint64, 0, len(w.l2))
+	for i := range w.l2 {
+		indexes = append(indexes, i)
+	}
+	slices.Sort(indexes)
+	for _, i := range indexes {
+		if err := w.writeTable(off, w.l2[i]); err != nil {
+			return err
+		}
+		w.ref(off, qcow2ClusterSize)
+		l1[i] = uint64(off) | qcow2Copied
+		off += qcow2ClusterSize
+	}
+
+	l1Offset := off
+	if err := w.writeTable(l1Offset, l1); err != nil {
+		return err
+	}
+	w.ref(l1Offset, qcow2Clusters(int64(len(l1))*8)*qcow2ClusterSize)
+	off += qcow2Clusters(int64(len(l1))*8) * qcow2ClusterSize
+
+	// The refcount table and blocks count themselves as well, so grow them until they cover everything.
+	used := off / qcow2ClusterSize
+	var tableClusters, blocks int64 = 1, 0
+	for {
+		b := (used + tableClusters + blocks + qcow2RefsPerBlock - 1) / qcow2RefsPerBlock
+		t := qcow2Clusters(b * 8)
+		if b == blocks && t == tableClusters {
+			break
+		}
+		blocks, tableClusters = b, t
+	}
+	tableOffset := off
+	w.ref(tableOffset, tableClusters*qcow2ClusterSize)
+	blocksOffset := tableOffset + tableClusters*qcow2ClusterSize
+	w.ref(blocksOffset, blocks*qcow2ClusterSize)
+
+	table := make([]uint64, blocks)
+	for i := range table {
+		table[i] = uint64(blocksOffset + int64(i)*qcow2ClusterSize)
+	}
+	if err := w.writeTable(tableOffset, table); err != nil {
+		return err
+	}
+	refs := make([]byte, blocks*qcow2ClusterSize)
+	for i, r := range w.refs {
+		binary.BigEndian.PutUint16(refs[i*2:], r)
+	}
+	if _, err := w.out.WriteAt(refs, blocksOffset); err != nil {
+		return err
+	}
+
+	header := new(bytes.Buffer)
+	_ = binary.Write(header, binary.BigEndian, qcow2Header{
+		Magic:                 qcow2Magic,
+		Version:               qcow2Version,
+		ClusterBits:           qcow2ClusterBits,
+		Size:                  size,
+		L1Size:                uint32(len(l1)),
+		L1TableOffset:         uint64(l1Offset),
+		RefcountTableOffset:   uint64(tableOffset),
+		RefcountTableClusters: uint32(tableClusters),
+		RefcountOrder:         qcow2RefcountOrder,
+		HeaderLength:          qcow2HeaderLength,
+	})
+	_, err := w.out.WriteAt(header.Bytes(), 0)
+	return err
+}
+
+// writeTable writes a table of big endian entries at off, padded to whole clusters
+func (w *qcow2Writer) writeTable(off int64, entries []uint64) error {
+	b := make([]byte, qcow2Clusters(int64(len(entries))*8)*qcow2ClusterSize)
+	for i, e := range entries {
+		binary.BigEndian.PutUint64(b[i*8:], e)
+	}
+	_, err := w.out.WriteAt(b, off)
+	return err
+}
+
+// ref adds a reference to every host cluster the given byte range touches
+func (w *qcow2Writer) ref(off, length int64) {
+	for c := off / qcow2ClusterSize; c <= (off+length-1)/qcow2ClusterSize; c++ {
+		for int64(len(w.refs)) <= c {
+			w.refs = append(w.refs, 0)
+		}
+		w.refs[c]++
+	}
+}
+
+// qcow2Align rounds off up to a cluster boundary
+func qcow2Align(off int64) int64 {
+	return qcow2Clusters(off) * qcow2ClusterSize
+}
 
-// copyFirmwareRpi will copy the proper firmware files for a Raspberry Pi into the EFI partition
-func copyFirmwareRpi(target string) error {
-	internal.Log.Logger.Info().Str("target", target).Msg("Copying Raspberry Pi firmware")
-	// Copy the firmware files from /rpi/ into target
-	return utils.CopyDir("/arm/rpi/", target)
+// qcow2Clusters is the number of clusters n bytes take, at least one
+func qcow2Clusters(n int64) int64 {
+	return max(1, (n+qcow2ClusterSize-1)/qcow2ClusterSize)
 }
@@ -0,0 +1,60 @@
+package ops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/AuroraBoot/internal"
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+)
+
+// GenSBOM inventories the packages installed in the unpacked rootfs and writes
+// an SBOM in cfg.Format to dstFunc(). When cfg.VulnDB points at a local OSV
+// mirror the inventory is also matched against it and the findings are
+// written to sbom.ReportFileName next to the SBOM. name identifies the
+// document, usually the source image reference.
+func GenSBOM(srcFunc, dstFunc valueGetOnCall, cfg schema.SBOM, name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		src := srcFunc()
+		dst := dstFunc()
+
+		inv, err := sbom.Scan(src)
+		if err != nil {
+			return fmt.Errorf("scanning %s for installed packages: %w", src, err)
+		}
+		internal.Log.Logger.Info().Int("packages", len(inv.Packages)).Str("distro", inv.Distro.ID).Str("format", cfg.Format).Msg("Generating SBOM")
+
+		path := filepath.Join(dst, sbom.FileName(cfg.Format))
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := sbom.Write(f, cfg.Format, name, inv); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+
+		if cfg.VulnDB == "" {
+			return nil
+		}
+		db, err := sbom.LoadAdvisories(cfg.VulnDB)
+		if err != nil {
+			return err
+		}
+		report := sbom.Match(inv, db)
+		internal.Log.Logger.Info().Int("advisories", db.Len()).Int("findings", report.Summary.Total).
+			Int("critical", report.Summary.Critical).Int("high", report.Summary.High).Msg("Matched packages against the advisory database")
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, sbom.ReportFileName), data, 0644)
+	}
+}
@@ -0,0 +1,59 @@
+package ops
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GenSBOM", func() {
+	var rootfs, dst, vulnDB string
+
+	BeforeEach(func() {
+		rootfs = GinkgoT().TempDir()
+		dst = GinkgoT().TempDir()
+		vulnDB = GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(rootfs, "etc"), 0o755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(rootfs, "lib/apk/db"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(rootfs, "etc/os-release"), []byte("ID=alpine\nVERSION_ID=3.20.3\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(rootfs, "lib/apk/db/installed"), []byte("P:busybox\nV:1.36.1-r28\nA:x86_64\no:busybox\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(vulnDB, "ALPINE-1.json"), []byte(`{
+			"id": "ALPINE-1", "database_specific": {"severity": "high"},
+			"affected": [{"package": {"ecosystem": "Alpine:v3.20", "name": "busybox"},
+				"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.36.1-r29"}]}]}]
+		}`), 0o644)).To(Succeed())
+	})
+
+	get := func(s string) valueGetOnCall { return func() string { return s } }
+
+	It("writes the SBOM without a report when no advisory database is set", func() {
+		err := GenSBOM(get(rootfs), get(dst), schema.SBOM{Format: sbom.FormatCycloneDX}, "img")(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Join(dst, "sbom.cdx.json")).To(BeARegularFile())
+		Expect(filepath.Join(dst, sbom.ReportFileName)).NotTo(BeAnExistingFile())
+	})
+
+	It("writes the vulnerability report next to the SBOM", func() {
+		err := GenSBOM(get(rootfs), get(dst), schema.SBOM{Format: sbom.FormatSPDX, VulnDB: vulnDB}, "img")(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Join(dst, "sbom.spdx.json")).To(BeARegularFile())
+
+		data, err := os.ReadFile(filepath.Join(dst, sbom.ReportFileName))
+		Expect(err).NotTo(HaveOccurred())
+		var report sbom.VulnReport
+		Expect(json.Unmarshal(data, &report)).To(Succeed())
+		Expect(report.Summary).To(Equal(sbom.Summary{High: 1, Total: 1}))
+		Expect(report.Findings[0].FixedVersion).To(Equal("1.36.1-r29"))
+	})
+
+	It("fails when the rootfs has no package database", func() {
+		err := GenSBOM(get(GinkgoT().TempDir()), get(dst), schema.SBOM{Format: sbom.FormatSPDX}, "img")(context.Background())
+		Expect(err).To(MatchError(sbom.ErrNoPackageDB))
+	})
+})
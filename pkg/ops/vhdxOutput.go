@@ -0,0 +1,421 @@
+package ops
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	uuidPkg "github.com/gofrs/uuid"
+	"github.com/kairos-io/AuroraBoot/internal"
+	"github.com/kairos-io/AuroraBoot/pkg/constants"
+)
+
+// Raw2DynamicVHD converts a raw disk into a dynamic VHD: only the 2 MiB blocks that hold data are stored, so the
+// image takes the space of the data on the disk instead of the whole disk like the fixed VHD of Raw2Azure.
+// Like the fixed VHD, the virtual size is rounded up to 1 MiB.
+// The raw disk is left in place and a sibling .vhd is produced.
+func Raw2DynamicVHD(source string) (string, error) {
+	internal.Log.Logger.Info().Str("source", source).Msg("Converting raw disk to dynamic VHD")
+	name := fmt.Sprintf("%s.vhd", source)
+	if err := convertSparse(source, name, writeDynamicVHD); err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", name).Msg("Error writing dynamic VHD image")
+		return name, err
+	}
+	return name, nil
+}
+
+// Raw2Vhdx converts a raw disk into a dynamic VHDX, the format of Hyper-V generation 2 virtual machines, which
+// boot the EFI raw disk as is. Only the blocks that hold data are stored. The virtual size is rounded up to 1 MiB.
+// The raw disk is left in place and a sibling .vhdx is produced.
+func Raw2Vhdx(source string) (string, error) {
+	internal.Log.Logger.Info().Str("source", source).Msg("Converting raw disk to VHDX")
+	name := fmt.Sprintf("%s.vhdx", strings.TrimSuffix(source, ".raw"))
+	if err := convertSparse(source, name, writeVhdx); err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", name).Msg("Error writing VHDX image")
+		return name, err
+	}
+	return name, nil
+}
+
+// convertSparse runs write from the raw disk source to a new file name, with the disk size rounded up to 1 MiB
+func convertSparse(source, name string, write func(out io.WriterAt, in io.Reader, size uint64) error) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	size := uint64((info.Size() + constants.MB - 1) / constants.MB * constants.MB)
+	if err := write(out, in, size); err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
+// forEachBlock reads in block by block, up to size bytes, and calls fn with the index and content of every block
+// that is not all zeroes. The last block is padded with zeroes.
+func forEachBlock(in io.Reader, size, blockSize uint64, fn func(block uint64, buf []byte) error) error {
+	buf := make([]byte, blockSize)
+	zero := make([]byte, blockSize)
+	for block := uint64(0); block*blockSize < size; block++ {
+		n, err := io.ReadFull(in, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		clear(buf[n:])
+		if bytes.Equal(buf, zero) {
+			continue
+		}
+		if err := fn(block, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/// Dynamic VHD utils!
+// A dynamic VHD starts with a copy of the footer, then the dynamic disk header and the block allocation table
+// (BAT). Each allocated block is a sector bitmap followed by the block's data, and the footer ends the file.
+// Everything is big endian.
+
+const (
+	vhdBlockSize      = 2 << 20
+	vhdSectorSize     = 512
+	vhdDynamicHeader  = vhdSectorSize // offset of the dynamic disk header
+	vhdTableOffset    = vhdDynamicHeader + 1024
+	vhdBitmapSize     = vhdBlockSize / vhdSectorSize / 8
+	vhdUnusedBATEntry = 0xffffffff
+)
+
+// vhdDynamicDiskHeader is the header of a dynamic or differencing disk
+type vhdDynamicDiskHeader struct {
+	Cookie               [8]byte // "cxsparse"
+	DataOffset           uint64  // unused, all ones
+	TableOffset          uint64  // absolute offset of the BAT
+	HeaderVersion        uint32
+	MaxTableEntries      uint32 // number of blocks of the disk
+	BlockSize            uint32
+	Checksum             uint32 // one's complement of the sum of the header's bytes without the checksum
+	ParentUniqueID       [16]byte
+	ParentTimeStamp      uint32
+	Reserved             uint32
+	ParentUnicodeName    [512]byte
+	ParentLocatorEntries [192]byte
+	Reserved2            [256]byte
+}
+
+// newVHDDynamic returns the footer of a dynamic disk of size bytes
+func newVHDDynamic(size uint64) VHDHeader {
+	header := newVHDFixed(size)
+	binary.BigEndian.PutUint64(header.DataOffset[:], vhdDynamicHeader)
+	hexToField("00000003", header.DiskType[:]) // Dynamic 0x00000003
+	hexToField("00000000", header.Checksum[:])
+	generateChecksum(&header)
+	return header
+}
+
+// writeDynamicVHD writes the raw disk of size bytes read from in as a dynamic VHD to out
+func writeDynamicVHD(out io.WriterAt, in io.Reader, size uint64) error {
+	blocks := (size + vhdBlockSize - 1) / vhdBlockSize
+	bat := make([]uint32, blocks)
+	for i := range bat {
+		bat[i] = vhdUnusedBATEntry
+	}
+	// The BAT takes whole sectors; the entries past the last block are unused too.
+	batSize := (blocks*4 + vhdSectorSize - 1) / vhdSectorSize * vhdSectorSize
+
+	// Every sector of an allocated block is marked as holding data: the sectors of the block that are zero in
+	// the raw disk are zero in the image too.
+	bitmap := bytes.Repeat([]byte{0xff}, vhdBitmapSize)
+	offset := int64(vhdTableOffset + batSize)
+	err := forEachBlock(in, size, vhdBlockSize, func(block uint64, buf []byte) error {
+		bat[block] = uint32(offset / vhdSectorSize)
+		if _, err := out.WriteAt(bitmap, offset); err != nil {
+			return err
+		}
+		if _, err := out.WriteAt(buf, offset+vhdBitmapSize); err != nil {
+			return err
+		}
+		offset += vhdBitmapSize + vhdBlockSize
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	header := vhdDynamicDiskHeader{
+		DataOffset:      ^uint64(0),
+		TableOffset:     vhdTableOffset,
+		HeaderVersion:   0x00010000,
+		MaxTableEntries: uint32(blocks),
+		BlockSize:       vhdBlockSize,
+	}
+	copy(header.Cookie[:], "cxsparse")
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, &header)
+	var sum uint32
+	for _, b := range buf.Bytes() {
+		sum += uint32(b)
+	}
+	header.Checksum = ^sum
+
+	footer := newVHDDynamic(size)
+	table := bytes.Repeat([]byte{0xff}, int(batSize))
+	for i, e := range bat {
+		binary.BigEndian.PutUint32(table[i*4:], e)
+	}
+	for _, w := range []struct {
+		v   any
+		off int64
+	}{
+		{&footer, 0},
+		{&header, vhdDynamicHeader},
+		{table, vhdTableOffset},
+		{&footer, offset},
+	} {
+		buf.Reset()
+		_ = binary.Write(&buf, binary.BigEndian, w.v)
+		if _, err := out.WriteAt(buf.Bytes(), w.off); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/// VHDX utils!
+// The layout follows the VHDX format specification (MS-VHDX): the header section takes the first MiB with the
+// file identifier, two copies of the header and two copies of the region table. An empty log follows, then the
+// metadata region, the BAT region and the payload blocks, all at 1 MiB boundaries. Everything is little endian
+// and the headers and region tables are checksummed with CRC-32C.
+
+const (
+	vhdxAlign          = 1 << 20
+	vhdxBlockSize      = 32 << 20
+	vhdxSectorSize     = 512
+	vhdxHeader1        = 64 * 1024
+	vhdxHeader2        = 128 * 1024
+	vhdxRegionTable1   = 192 * 1024
+	vhdxRegionTable2   = 256 * 1024
+	vhdxLogOffset      = vhdxAlign
+	vhdxLogLength      = vhdxAlign
+	vhdxMetadataOffset = vhdxLogOffset + vhdxLogLength
+	vhdxMetadataLength = vhdxAlign
+	vhdxBATOffset      = vhdxMetadataOffset + vhdxMetadataLength
+	// vhdxChunkRatio is how many payload blocks a sector bitmap block covers. The BAT has a sector bitmap entry
+	// after every chunk of payload entries, unused without a parent disk.
+	vhdxChunkRatio = (1 << 23) * vhdxSectorSize / vhdxBlockSize
+
+	vhdxPayloadBlockNotPresent   = 0
+	vhdxPayloadBlockFullyPresent = 6
+)
+
+var (
+	vhdxBATRegion          = uuidPkg.Must(uuidPkg.FromString("2DC27766-F623-4200-9D64-115E9BFD4A08"))
+	vhdxMetadataRegion     = uuidPkg.Must(uuidPkg.FromString("8B7CA206-4790-4B9A-B8FE-575F050F886E"))
+	vhdxFileParameters     = uuidPkg.Must(uuidPkg.FromString("CAA16737-FA36-4D43-B3B6-33F0AA44E76B"))
+	vhdxVirtualDiskSize    = uuidPkg.Must(uuidPkg.FromString("2FA54224-CD1B-4876-B211-5DBED83BF4B8"))
+	vhdxVirtualDiskID      = uuidPkg.Must(uuidPkg.FromString("BECA12AB-B2E6-4523-93EF-C309E000C746"))
+	vhdxLogicalSectorSize  = uuidPkg.Must(uuidPkg.FromString("8141BF1D-A96F-4709-BA47-F233A8FAAB5F"))
+	vhdxPhysicalSectorSize = uuidPkg.Must(uuidPkg.FromString("CDA348C7-445D-4471-9CC9-E9885251C556"))
+
+	crc32c = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// vhdxGUID is a GUID as stored on disk: the first three fields little endian, the rest as is
+type vhdxGUID [16]byte
+
+func toVhdxGUID(u uuidPkg.UUID) vhdxGUID {
+	var g vhdxGUID
+	binary.LittleEndian.PutUint32(g[0:], binary.BigEndian.Uint32(u[0:]))
+	binary.LittleEndian.PutUint16(g[4:], binary.BigEndian.Uint16(u[4:]))
+	binary.LittleEndian.PutUint16(g[6:], binary.BigEndian.Uint16(u[6:]))
+	copy(g[8:], u[8:])
+	return g
+}
+
+func newVhdxGUID() vhdxGUID {
+	u, _ := uuidPkg.NewV4()
+	return toVhdxGUID(u)
+}
+
+// vhdxHeader is one copy of the header; the one with the highest sequence number is current
+type vhdxHeader struct {
+	Signature      [4]byte // "head"
+	Checksum       uint32
+	SequenceNumber uint64
+	FileWriteGUID  vhdxGUID
+	DataWriteGUID  vhdxGUID
+	LogGUID        vhdxGUID // zero: the log is empty
+	LogVersion     uint16
+	Version        uint16
+	LogLength      uint32
+	LogOffset      uint64
+	Reserved       [4016]byte
+}
+
+type vhdxRegionTableHeader struct {
+	Signature  [4]byte // "regi"
+	Checksum   uint32
+	EntryCount uint32
+	Reserved   uint32
+}
+
+type vhdxRegionTableEntry struct {
+	GUID       vhdxGUID
+	FileOffset uint64
+	Length     uint32
+	Required   uint32
+}
+
+type vhdxMetadataTableHeader struct {
+	Signature  [8]byte // "metadata"
+	Reserved   uint16
+	EntryCount uint16
+	Reserved2  [20]byte
+}
+
+type vhdxMetadataTableEntry struct {
+	ItemID   vhdxGUID
+	Offset   uint32 // from the start of the metadata region
+	Length   uint32
+	Flags    uint32
+	Reserved uint32
+}
+
+const (
+	vhdxMetadataIsVirtualDisk = 1 << 1
+	vhdxMetadataIsRequired    = 1 << 2
+)
+
+// writeVhdx writes the raw disk of size bytes read from in as a dynamic VHDX to out
+func writeVhdx(out io.WriterAt, in io.Reader, size uint64) error {
+	blocks := (size + vhdxBlockSize - 1) / vhdxBlockSize
+	batEntries := blocks + (blocks-1)/vhdxChunkRatio
+	batLength := (batEntries*8 + vhdxAlign - 1) / vhdxAlign * vhdxAlign
+	bat := make([]byte, batLength)
+
+	offset := uint64(vhdxBATOffset) + batLength
+	err := forEachBlock(in, size, vhdxBlockSize, func(block uint64, buf []byte) error {
+		if _, err := out.WriteAt(buf, int64(offset)); err != nil {
+			return err
+		}
+		entry := block + block/vhdxChunkRatio
+		binary.LittleEndian.PutUint64(bat[entry*8:], offset|vhdxPayloadBlockFullyPresent)
+		offset += vhdxBlockSize
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := out.WriteAt(bat, vhdxBATOffset); err != nil {
+		return err
+	}
+	if _, err := out.WriteAt(vhdxMetadata(size), vhdxMetadataOffset); err != nil {
+		return err
+	}
+	// Only the log region's place is reserved: with a zero log GUID there is nothing to replay.
+	if _, err := out.WriteAt(make([]byte, vhdxLogLength), vhdxLogOffset); err != nil {
+		return err
+	}
+
+	ident := make([]byte, 8+512)
+	copy(ident, "vhdxfile")
+	for i, c := range utf16.Encode([]rune("AuroraBoot")) {
+		binary.LittleEndian.PutUint16(ident[8+i*2:], c)
+	}
+	if _, err := out.WriteAt(ident, 0); err != nil {
+		return err
+	}
+
+	fileWrite, dataWrite := newVhdxGUID(), newVhdxGUID()
+	for i, off := range []int64{vhdxHeader1, vhdxHeader2} {
+		h := vhdxHeader{
+			SequenceNumber: uint64(i),
+			FileWriteGUID:  fileWrite,
+			DataWriteGUID:  dataWrite,
+			Version:        1,
+			LogLength:      vhdxLogLength,
+			LogOffset:      vhdxLogOffset,
+		}
+		copy(h.Signature[:], "head")
+		if _, err := out.WriteAt(vhdxChecksummed(&h, 4), off); err != nil {
+			return err
+		}
+	}
+
+	var regions bytes.Buffer
+	rh := vhdxRegionTableHeader{EntryCount: 2}
+	copy(rh.Signature[:], "regi")
+	_ = binary.Write(&regions, binary.LittleEndian, &rh)
+	_ = binary.Write(&regions, binary.LittleEndian, []vhdxRegionTableEntry{
+		{GUID: toVhdxGUID(vhdxBATRegion), FileOffset: vhdxBATOffset, Length: uint32(batLength), Required: 1},
+		{GUID: toVhdxGUID(vhdxMetadataRegion), FileOffset: vhdxMetadataOffset, Length: vhdxMetadataLength, Required: 1},
+	})
+	table := make([]byte, 64*1024)
+	copy(table, regions.Bytes())
+	binary.LittleEndian.PutUint32(table[4:], crc32.Checksum(table, crc32c))
+	for _, off := range []int64{vhdxRegionTable1, vhdxRegionTable2} {
+		if _, err := out.WriteAt(table, off); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vhdxChecksummed encodes v and stores the CRC-32C of the encoding at byte offset at
+func vhdxChecksummed(v any, at int) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, v)
+	b := buf.Bytes()
+	binary.LittleEndian.PutUint32(b[at:], crc32.Checksum(b, crc32c))
+	return b
+}
+
+// vhdxMetadata returns the metadata region of a dynamic disk of size bytes without a parent
+func vhdxMetadata(size uint64) []byte {
+	region := make([]byte, vhdxMetadataLength)
+	items := []struct {
+		id    uuidPkg.UUID
+		flags uint32
+		data  any
+	}{
+		{vhdxFileParameters, vhdxMetadataIsRequired, [2]uint32{vhdxBlockSize, 0}},
+		{vhdxVirtualDiskSize, vhdxMetadataIsVirtualDisk | vhdxMetadataIsRequired, size},
+		{vhdxVirtualDiskID, vhdxMetadataIsVirtualDisk | vhdxMetadataIsRequired, newVhdxGUID()},
+		{vhdxLogicalSectorSize, vhdxMetadataIsVirtualDisk | vhdxMetadataIsRequired, uint32(vhdxSectorSize)},
+		{vhdxPhysicalSectorSize, vhdxMetadataIsVirtualDisk | vhdxMetadataIsRequired, uint32(vhdxSectorSize)},
+	}
+	var table bytes.Buffer
+	th := vhdxMetadataTableHeader{EntryCount: uint16(len(items))}
+	copy(th.Signature[:], "metadata")
+	_ = binary.Write(&table, binary.LittleEndian, &th)
+	// The items follow the 64 KiB the table is given
+	offset := uint32(64 * 1024)
+	for _, item := range items {
+		var data bytes.Buffer
+		_ = binary.Write(&data, binary.LittleEndian, item.data)
+		copy(region[offset:], data.Bytes())
+		_ = binary.Write(&table, binary.LittleEndian, &vhdxMetadataTableEntry{
+			ItemID: toVhdxGUID(item.id),
+			Offset: offset,
+			Length: uint32(data.Len()),
+			Flags:  item.flags,
+		})
+		offset += uint32(data.Len())
+	}
+	copy(region, table.Bytes())
+	return region
+}
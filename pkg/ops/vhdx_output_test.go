@@ -0,0 +1,207 @@
+package ops
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// paddedRawDisk returns the raw disk as the VHD and VHDX writers see it: rounded up to 1 MiB with zeroes.
+func paddedRawDisk(t *testing.T, raw string) []byte {
+	t.Helper()
+	want, err := os.ReadFile(raw)
+	if err != nil {
+		t.Fatalf("original raw should be left in place: %v", err)
+	}
+	return append(want, make([]byte, (1<<20-len(want)%(1<<20))%(1<<20))...)
+}
+
+func TestRaw2DynamicVHD(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "kairos-test.raw")
+	writeFakeRawDisk(t, raw)
+
+	out, err := Raw2DynamicVHD(raw)
+	if err != nil {
+		t.Fatalf("Raw2DynamicVHD: %v", err)
+	}
+	if out != raw+".vhd" {
+		t.Fatalf("output name = %q", out)
+	}
+	img, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := paddedRawDisk(t, raw)
+
+	// The file starts and ends with the same footer, which points at the dynamic disk header.
+	var head, foot VHDHeader
+	_ = binary.Read(bytes.NewReader(img), binary.BigEndian, &head)
+	_ = binary.Read(bytes.NewReader(img[len(img)-512:]), binary.BigEndian, &foot)
+	if head != foot {
+		t.Fatal("the footer copy at the start differs from the footer")
+	}
+	if string(foot.Cookie[:]) != "conectix" || binary.BigEndian.Uint32(foot.DiskType[:]) != 3 {
+		t.Fatalf("unexpected footer %+v", foot)
+	}
+	if got := binary.BigEndian.Uint64(foot.CurrentSize[:]); got != uint64(len(want)) {
+		t.Fatalf("virtual size %d, want %d", got, len(want))
+	}
+	checksum := foot
+	checksum.Checksum = [4]byte{}
+	generateChecksum(&checksum)
+	if checksum.Checksum != foot.Checksum {
+		t.Fatal("footer checksum does not match")
+	}
+
+	var dyn vhdDynamicDiskHeader
+	_ = binary.Read(bytes.NewReader(img[binary.BigEndian.Uint64(foot.DataOffset[:]):]), binary.BigEndian, &dyn)
+	if string(dyn.Cookie[:]) != "cxsparse" || dyn.BlockSize != vhdBlockSize || dyn.HeaderVersion != 0x00010000 {
+		t.Fatalf("unexpected dynamic disk header %+v", dyn)
+	}
+	sum := dyn.Checksum
+	dyn.Checksum = 0
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, &dyn)
+	var s uint32
+	for _, b := range buf.Bytes() {
+		s += uint32(b)
+	}
+	if ^s != sum {
+		t.Fatal("dynamic disk header checksum does not match")
+	}
+
+	disk := make([]byte, uint64(dyn.MaxTableEntries)*vhdBlockSize)
+	allocated := 0
+	for i := uint64(0); i < uint64(dyn.MaxTableEntries); i++ {
+		e := binary.BigEndian.Uint32(img[dyn.TableOffset+i*4:])
+		if e == vhdUnusedBATEntry {
+			continue
+		}
+		allocated++
+		at := uint64(e) * vhdSectorSize
+		if !bytes.Equal(img[at:at+vhdBitmapSize], bytes.Repeat([]byte{0xff}, vhdBitmapSize)) {
+			t.Fatalf("block %d does not mark all its sectors present", i)
+		}
+		copy(disk[i*vhdBlockSize:], img[at+vhdBitmapSize:at+vhdBitmapSize+vhdBlockSize])
+	}
+	if !bytes.Equal(disk[:len(want)], want) {
+		t.Fatal("VHD content differs from the raw disk")
+	}
+	// The fake disk has data in the first 2 MiB block and in the last one.
+	if allocated != 2 {
+		t.Errorf("%d blocks allocated, want 2", allocated)
+	}
+}
+
+func TestRaw2Vhdx(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "kairos-test.raw")
+	writeFakeRawDisk(t, raw)
+
+	out, err := Raw2Vhdx(raw)
+	if err != nil {
+		t.Fatalf("Raw2Vhdx: %v", err)
+	}
+	if out != filepath.Join(dir, "kairos-test.vhdx") {
+		t.Fatalf("output name = %q", out)
+	}
+	img, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := paddedRawDisk(t, raw)
+	crc := func(b []byte, at int) bool {
+		c := make([]byte, len(b))
+		copy(c, b)
+		binary.LittleEndian.PutUint32(c[at:], 0)
+		return crc32.Checksum(c, crc32c) == binary.LittleEndian.Uint32(b[at:])
+	}
+
+	if string(img[:8]) != "vhdxfile" {
+		t.Fatal("missing file type identifier")
+	}
+	for i, off := range []int{vhdxHeader1, vhdxHeader2} {
+		var h vhdxHeader
+		_ = binary.Read(bytes.NewReader(img[off:]), binary.LittleEndian, &h)
+		if string(h.Signature[:]) != "head" || h.Version != 1 || h.LogGUID != (vhdxGUID{}) || !crc(img[off:off+4096], 4) {
+			t.Fatalf("header %d is not valid: %+v", i+1, h)
+		}
+		if h.LogOffset%vhdxAlign != 0 || h.LogLength%vhdxAlign != 0 || h.LogOffset < vhdxAlign {
+			t.Fatalf("header %d puts the log at %d+%d", i+1, h.LogOffset, h.LogLength)
+		}
+		if h.SequenceNumber != uint64(i) {
+			t.Errorf("header %d has sequence number %d", i+1, h.SequenceNumber)
+		}
+	}
+
+	regions := map[vhdxGUID]vhdxRegionTableEntry{}
+	for _, off := range []int{vhdxRegionTable1, vhdxRegionTable2} {
+		table := img[off : off+64*1024]
+		if string(table[:4]) != "regi" || !crc(table, 4) {
+			t.Fatalf("region table at %d is not valid", off)
+		}
+		entries := make([]vhdxRegionTableEntry, binary.LittleEndian.Uint32(table[8:]))
+		_ = binary.Read(bytes.NewReader(table[16:]), binary.LittleEndian, entries)
+		for _, e := range entries {
+			if e.FileOffset%vhdxAlign != 0 || e.Length%vhdxAlign != 0 {
+				t.Fatalf("region %x is not 1 MiB aligned", e.GUID)
+			}
+			regions[e.GUID] = e
+		}
+	}
+	bat, ok := regions[toVhdxGUID(vhdxBATRegion)]
+	if !ok {
+		t.Fatal("no BAT region")
+	}
+	meta, ok := regions[toVhdxGUID(vhdxMetadataRegion)]
+	if !ok {
+		t.Fatal("no metadata region")
+	}
+
+	md := img[meta.FileOffset : meta.FileOffset+uint64(meta.Length)]
+	if string(md[:8]) != "metadata" {
+		t.Fatal("metadata table signature missing")
+	}
+	items := map[vhdxGUID][]byte{}
+	entries := make([]vhdxMetadataTableEntry, binary.LittleEndian.Uint16(md[10:]))
+	_ = binary.Read(bytes.NewReader(md[32:]), binary.LittleEndian, entries)
+	for _, e := range entries {
+		if e.Flags&vhdxMetadataIsRequired == 0 {
+			t.Errorf("metadata item %x is not marked required", e.ItemID)
+		}
+		items[e.ItemID] = md[e.Offset : e.Offset+e.Length]
+	}
+	params := items[toVhdxGUID(vhdxFileParameters)]
+	blockSize := uint64(binary.LittleEndian.Uint32(params))
+	if blockSize != vhdxBlockSize || binary.LittleEndian.Uint32(params[4:]) != 0 {
+		t.Fatalf("file parameters %x", params)
+	}
+	size := binary.LittleEndian.Uint64(items[toVhdxGUID(vhdxVirtualDiskSize)])
+	if size != uint64(len(want)) {
+		t.Fatalf("virtual size %d, want %d", size, len(want))
+	}
+	if binary.LittleEndian.Uint32(items[toVhdxGUID(vhdxLogicalSectorSize)]) != 512 || len(items[toVhdxGUID(vhdxVirtualDiskID)]) != 16 || len(items[toVhdxGUID(vhdxPhysicalSectorSize)]) != 4 {
+		t.Fatal("sector sizes or virtual disk id missing")
+	}
+
+	disk := make([]byte, (size+blockSize-1)/blockSize*blockSize)
+	chunk := (uint64(1) << 23) * 512 / blockSize
+	for b := uint64(0); b*blockSize < size; b++ {
+		e := binary.LittleEndian.Uint64(img[bat.FileOffset+(b+b/chunk)*8:])
+		switch e & 7 {
+		case vhdxPayloadBlockNotPresent:
+		case vhdxPayloadBlockFullyPresent:
+			at := e &^ (vhdxAlign - 1)
+			copy(disk[b*blockSize:], img[at:at+blockSize])
+		default:
+			t.Fatalf("block %d has state %d", b, e&7)
+		}
+	}
+	if !bytes.Equal(disk[:len(want)], want) {
+		t.Fatal("VHDX content differs from the raw disk")
+	}
+}
@@ -0,0 +1,488 @@
+package ops
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kairos-io/AuroraBoot/internal"
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+)
+
+// Raw2Vmdk converts a raw disk into a stream-optimized VMDK, the format vSphere imports and OVAs carry.
+// Grains that are all zeroes are left out, the others are deflated.
+// The raw disk is left in place and a sibling .vmdk is produced.
+func Raw2Vmdk(source string) (string, error) {
+	internal.Log.Logger.Info().Str("source", source).Msg("Converting raw disk to stream-optimized VMDK")
+	name := fmt.Sprintf("%s.vmdk", strings.TrimSuffix(source, ".raw"))
+	if err := writeVmdkFile(source, name); err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", name).Msg("Error writing VMDK image")
+		return name, err
+	}
+	return name, nil
+}
+
+// Raw2Ova packs a raw disk into an OVA: an OVF descriptor for a virtual machine with EFI firmware sized by vm,
+// a manifest and a stream-optimized VMDK of the disk. When vmdk names an existing VMDK of the disk it is packed
+// as is, otherwise one is written next to the raw disk for the time of the packing.
+// The raw disk is left in place and a sibling .ova is produced.
+func Raw2Ova(source, vmdk string, vm schema.OVF) (string, error) {
+	internal.Log.Logger.Info().Str("source", source).Msg("Packing raw disk into an OVA")
+	base := strings.TrimSuffix(source, ".raw")
+	name := fmt.Sprintf("%s.ova", base)
+
+	info, err := os.Stat(source)
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", source).Msg("Error reading raw image")
+		return name, err
+	}
+	if vmdk == "" {
+		vmdk = fmt.Sprintf("%s-disk1.vmdk", base)
+		if err := writeVmdkFile(source, vmdk); err != nil {
+			internal.Log.Logger.Error().Err(err).Str("file", vmdk).Msg("Error writing VMDK image")
+			return name, err
+		}
+		defer os.Remove(vmdk)
+	}
+	disk, err := os.Open(vmdk)
+	if err != nil {
+		return name, err
+	}
+	defer disk.Close()
+	diskInfo, err := disk.Stat()
+	if err != nil {
+		return name, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, disk); err != nil {
+		return name, err
+	}
+	if _, err := disk.Seek(0, io.SeekStart); err != nil {
+		return name, err
+	}
+
+	vmName := filepath.Base(base)
+	var ovf bytes.Buffer
+	if err := ovfTemplate.Execute(&ovf, ovfDescriptor{
+		Name:         vmName,
+		Disk:         filepath.Base(vmdk),
+		DiskFileSize: diskInfo.Size(),
+		Capacity:     info.Size(),
+		CPUs:         vm.CPUsOrDefault(),
+		MemoryMB:     vm.MemoryMBOrDefault(),
+		UserData:     vm.UserDataProperty,
+	}); err != nil {
+		return name, err
+	}
+	ovfName := vmName + ".ovf"
+	manifest := fmt.Sprintf("SHA256(%s)= %x\nSHA256(%s)= %s\n",
+		ovfName, sha256.Sum256(ovf.Bytes()), filepath.Base(vmdk), hex.EncodeToString(h.Sum(nil)))
+
+	out, err := os.Create(name)
+	if err != nil {
+		internal.Log.Logger.Error().Err(err).Str("file", name).Msg("Error creating OVA output")
+		return name, err
+	}
+	defer out.Close()
+	// The descriptor has to come first and the manifest right after it, so the OVA can be imported as a stream.
+	tw := tar.NewWriter(out)
+	now := time.Now()
+	for _, f := range []struct {
+		name string
+		size int64
+		r    io.Reader
+	}{
+		{ovfName, int64(ovf.Len()), &ovf},
+		{vmName + ".mf", int64(len(manifest)), strings.NewReader(manifest)},
+		{filepath.Base(vmdk), diskInfo.Size(), disk},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Size: f.size, Mode: 0644, ModTime: now, Format: tar.FormatUSTAR}); err != nil {
+			return name, err
+		}
+		if _, err := io.Copy(tw, f.r); err != nil {
+			internal.Log.Logger.Error().Err(err).Str("file", f.name).Msg("Error writing OVA")
+			return name, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return name, err
+	}
+	return name, out.Sync()
+}
+
+func writeVmdkFile(source, name string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err := writeVmdk(out, in, info.Size(), filepath.Base(name)); err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
+/// VMDK utils!
+// The layout follows the "Virtual Disk Format 5.0" specification, stream-optimized flavour: a sparse header and
+// the descriptor, then every grain that holds data as a marker followed by its deflated bytes, then the grain
+// tables and the grain directory, each behind a marker, and a footer repeating the header with the directory's
+// offset. All offsets and sizes are in 512 byte sectors.
+
+const (
+	vmdkMagic       = 0x564d444b // "KDMV"
+	vmdkVersion     = 3          // stream-optimized extents are version 3
+	vmdkSectorSize  = 512
+	vmdkGrainSize   = 128 // sectors, 64 KiB
+	vmdkGTEntries   = 512 // grains per grain table
+	vmdkDescSectors = 20
+	// Header flags: the newline detection characters are valid, grains are compressed, markers are used.
+	vmdkFlags = 1 | 1<<16 | 1<<17
+	// vmdkOverhead is where the first grain starts, after the header and the descriptor.
+	vmdkOverhead        = vmdkGrainSize
+	vmdkGDAtEnd         = ^uint64(0)
+	vmdkCompressDeflate = 1
+
+	vmdkMarkerEOS    = 0
+	vmdkMarkerGT     = 1
+	vmdkMarkerGD     = 2
+	vmdkMarkerFooter = 3
+)
+
+// vmdkHeader is the sparse extent header, little endian on disk
+type vmdkHeader struct {
+	Magic              uint32
+	Version            uint32
+	Flags              uint32
+	Capacity           uint64 // virtual disk size in sectors
+	GrainSize          uint64
+	DescriptorOffset   uint64
+	DescriptorSize     uint64
+	NumGTEsPerGT       uint32
+	RGDOffset          uint64
+	GDOffset           uint64
+	OverHead           uint64
+	UncleanShutdown    uint8
+	SingleEndLineChar  byte
+	NonEndLineChar     byte
+	DoubleEndLineChar1 byte
+	DoubleEndLineChar2 byte
+	CompressAlgorithm  uint16
+	Pad                [433]byte
+}
+
+// vmdkMarker precedes the metadata sectors that follow it; the end of stream marker has none.
+type vmdkMarker struct {
+	NumSectors uint64
+	Size       uint32
+	Type       uint32
+	Pad        [496]byte
+}
+
+type vmdkWriter struct {
+	out    io.Writer
+	sector uint64 // next free sector in the file
+	zbuf   bytes.Buffer
+	zw     *zlib.Writer
+}
+
+// writeVmdk writes the raw disk of size bytes read from in as a stream-optimized VMDK to out. The image is written
+// strictly sequentially, so out may be a pipe. extent is the file name the descriptor gives the extent.
+func writeVmdk(out io.Writer, in io.Reader, size int64, extent string) error {
+	capacity := uint64(size+vmdkSectorSize-1) / vmdkSectorSize
+	header := vmdkHeader{
+		Magic:              vmdkMagic,
+		Version:            vmdkVersion,
+		Flags:              vmdkFlags,
+		Capacity:           capacity,
+		GrainSize:          vmdkGrainSize,
+		DescriptorOffset:   1,
+		DescriptorSize:     vmdkDescSectors,
+		NumGTEsPerGT:       vmdkGTEntries,
+		GDOffset:           vmdkGDAtEnd,
+		OverHead:           vmdkOverhead,
+		SingleEndLineChar:  '\n',
+		NonEndLineChar:     ' ',
+		DoubleEndLineChar1: '\r',
+		DoubleEndLineChar2: '\n',
+		CompressAlgorithm:  vmdkCompressDeflate,
+	}
+	w := &vmdkWriter{out: out}
+	w.zw = zlib.NewWriter(&w.zbuf)
+	if err := w.write(&header); err != nil {
+		return err
+	}
+	desc := vmdkDescriptor(capacity, extent)
+	if len(desc) > vmdkDescSectors*vmdkSectorSize {
+		return fmt.Errorf("VMDK descriptor is %d bytes, more than the %d reserved for it", len(desc), vmdkDescSectors*vmdkSectorSize)
+	}
+	if err := w.writePadded([]byte(desc), vmdkOverhead*vmdkSectorSize-vmdkSectorSize); err != nil {
+		return err
+	}
+
+	grains := (capacity + vmdkGrainSize - 1) / vmdkGrainSize
+	gts := make([][]uint32, (grains+vmdkGTEntries-1)/vmdkGTEntries)
+	buf := make([]byte, vmdkGrainSize*vmdkSectorSize)
+	zero := make([]byte, len(buf))
+	for grain := uint64(0); grain < grains; grain++ {
+		n, err := io.ReadFull(in, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		// A short last grain is padded; readers always inflate whole grains.
+		clear(buf[n:])
+		if bytes.Equal(buf, zero) {
+			continue
+		}
+		at, err := w.writeGrain(grain*vmdkGrainSize, buf)
+		if err != nil {
+			return err
+		}
+		gt := grain / vmdkGTEntries
+		if gts[gt] == nil {
+			gts[gt] = make([]uint32, vmdkGTEntries)
+		}
+		gts[gt][grain%vmdkGTEntries] = uint32(at)
+	}
+
+	// Grain tables with no grain are left out; the directory points at sector 0 for them.
+	gd := make([]uint32, len(gts))
+	for i, gt := range gts {
+		if gt == nil {
+			continue
+		}
+		at, err := w.writeTable(vmdkMarkerGT, gt)
+		if err != nil {
+			return err
+		}
+		gd[i] = uint32(at)
+	}
+	gdAt, err := w.writeTable(vmdkMarkerGD, gd)
+	if err != nil {
+		return err
+	}
+
+	if err := w.write(&vmdkMarker{NumSectors: 1, Type: vmdkMarkerFooter}); err != nil {
+		return err
+	}
+	header.GDOffset = gdAt
+	if err := w.write(&header); err != nil {
+		return err
+	}
+	return w.write(&vmdkMarker{Type: vmdkMarkerEOS})
+}
+
+// writeGrain deflates one grain starting at the virtual sector lba and writes it behind its marker: the lba and
+// the length of the deflated data, which follows right after them. It returns the sector it was written at.
+func (w *vmdkWriter) writeGrain(lba uint64, buf []byte) (uint64, error) {
+	w.zbuf.Reset()
+	w.zw.Reset(&w.zbuf)
+	if _, err := w.zw.Write(buf); err != nil {
+		return 0, err
+	}
+	if err := w.zw.Close(); err != nil {
+		return 0, err
+	}
+	at := w.sector
+	grain := make([]byte, 12, 12+w.zbuf.Len())
+	binary.LittleEndian.PutUint64(grain, lba)
+	binary.LittleEndian.PutUint32(grain[8:], uint32(w.zbuf.Len()))
+	grain = append(grain, w.zbuf.Bytes()...)
+	return at, w.writePadded(grain, vmdkAlign(len(grain)))
+}
+
+// writeTable writes a grain table or the grain directory behind its marker and returns the sector the table
+// itself starts at.
+func (w *vmdkWriter) writeTable(typ uint32, entries []uint32) (uint64, error) {
+	table := make([]byte, len(entries)*4)
+	for i, e := range entries {
+		binary.LittleEndian.PutUint32(table[i*4:], e)
+	}
+	sectors := vmdkAlign(len(table)) / vmdkSectorSize
+	if err := w.write(&vmdkMarker{NumSectors: uint64(sectors), Type: typ}); err != nil {
+		return 0, err
+	}
+	at := w.sector
+	return at, w.writePadded(table, sectors*vmdkSectorSize)
+}
+
+func (w *vmdkWriter) write(v any) error {
+	if err := binary.Write(w.out, binary.LittleEndian, v); err != nil {
+		return err
+	}
+	w.sector += uint64(binary.Size(v)) / vmdkSectorSize
+	return nil
+}
+
+// writePadded writes b followed by zeroes up to length bytes, a multiple of the sector size.
+func (w *vmdkWriter) writePadded(b []byte, length int) error {
+	if _, err := w.out.Write(b); err != nil {
+		return err
+	}
+	if _, err := w.out.Write(make([]byte, length-len(b))); err != nil {
+		return err
+	}
+	w.sector += uint64(length) / vmdkSectorSize
+	return nil
+}
+
+func vmdkAlign(n int) int {
+	return (n + vmdkSectorSize - 1) &^ (vmdkSectorSize - 1)
+}
+
+// vmdkDescriptor returns the text descriptor of a disk of capacity sectors stored in the extent file named extent.
+func vmdkDescriptor(capacity uint64, extent string) string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	cylinders := capacity / (255 * 63)
+	if cylinders > 65535 {
+		cylinders = 65535
+	}
+	return fmt.Sprintf(`# Disk DescriptorFile
+version=1
+CID=%08x
+parentCID=ffffffff
+createType="streamOptimized"
+
+# Extent description
+RW %d SPARSE "%s"
+
+# The Disk Data Base
+#DDB
+
+ddb.adapterType = "lsilogic"
+ddb.geometry.cylinders = "%d"
+ddb.geometry.heads = "255"
+ddb.geometry.sectors = "63"
+ddb.longContentID = "%x"
+ddb.toolsInstallType = "4"
+ddb.virtualHWVersion = "14"
+`, binary.LittleEndian.Uint32(id), capacity, extent, cylinders, id)
+}
+
+/// OVF utils!
+
+type ovfDescriptor struct {
+	Name         string
+	Disk         string
+	DiskFileSize int64
+	Capacity     int64
+	CPUs         int
+	MemoryMB     int
+	UserData     bool
+}
+
+// ovfTemplate describes a virtual machine booting the disk with EFI firmware on an LSI Logic SCSI controller,
+// with a vmxnet3 NIC on the default "VM Network". With UserData, a user-data vApp property takes a base64 encoded
+// cloud-config when the appliance is deployed; vSphere hands it to the machine through VMware Tools, in the OVF
+// environment document (guestinfo.ovfEnv).
+var ovfTemplate = template.Must(template.New("ovf").Funcs(template.FuncMap{
+	"xml": func(s string) (string, error) {
+		var b strings.Builder
+		err := xml.EscapeText(&b, []byte(s))
+		return b.String(), err
+	},
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vmw="http://www.vmware.com/schema/ovf" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <References>
+    <File ovf:href="{{xml .Disk}}" ovf:id="file1" ovf:size="{{.DiskFileSize}}"/>
+  </References>
+  <DiskSection>
+    <Info>Virtual disk information</Info>
+    <Disk ovf:capacity="{{.Capacity}}" ovf:capacityAllocationUnits="byte" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"/>
+  </DiskSection>
+  <NetworkSection>
+    <Info>The list of logical networks</Info>
+    <Network ovf:name="VM Network">
+      <Description>The VM Network network</Description>
+    </Network>
+  </NetworkSection>
+  <VirtualSystem ovf:id="{{xml .Name}}">
+    <Info>A virtual machine</Info>
+    <Name>{{xml .Name}}</Name>
+    <OperatingSystemSection ovf:id="101" vmw:osType="other5xLinux64Guest">
+      <Info>The kind of installed guest operating system</Info>
+    </OperatingSystemSection>
+    <VirtualHardwareSection{{if .UserData}} ovf:transport="com.vmware.guestInfo"{{end}}>
+      <Info>Virtual hardware requirements</Info>
+      <System>
+        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+        <vssd:InstanceID>0</vssd:InstanceID>
+        <vssd:VirtualSystemIdentifier>{{xml .Name}}</vssd:VirtualSystemIdentifier>
+        <vssd:VirtualSystemType>vmx-14</vssd:VirtualSystemType>
+      </System>
+      <Item>
+        <rasd:AllocationUnits>hertz * 10^6</rasd:AllocationUnits>
+        <rasd:Description>Number of Virtual CPUs</rasd:Description>
+        <rasd:ElementName>{{.CPUs}} virtual CPU(s)</rasd:ElementName>
+        <rasd:InstanceID>1</rasd:InstanceID>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>{{.CPUs}}</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>byte * 2^20</rasd:AllocationUnits>
+        <rasd:Description>Memory Size</rasd:Description>
+        <rasd:ElementName>{{.MemoryMB}}MB of memory</rasd:ElementName>
+        <rasd:InstanceID>2</rasd:InstanceID>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>{{.MemoryMB}}</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:Address>0</rasd:Address>
+        <rasd:Description>SCSI Controller</rasd:Description>
+        <rasd:ElementName>SCSI Controller 0</rasd:ElementName>
+        <rasd:InstanceID>3</rasd:InstanceID>
+        <rasd:ResourceSubType>lsilogic</rasd:ResourceSubType>
+        <rasd:ResourceType>6</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>0</rasd:AddressOnParent>
+        <rasd:ElementName>Hard Disk 1</rasd:ElementName>
+        <rasd:HostResource>ovf:/disk/vmdisk1</rasd:HostResource>
+        <rasd:InstanceID>4</rasd:InstanceID>
+        <rasd:Parent>3</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>7</rasd:AddressOnParent>
+        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
+        <rasd:Connection>VM Network</rasd:Connection>
+        <rasd:Description>VmxNet3 ethernet adapter on "VM Network"</rasd:Description>
+        <rasd:ElementName>Network adapter 1</rasd:ElementName>
+        <rasd:InstanceID>5</rasd:InstanceID>
+        <rasd:ResourceSubType>VmxNet3</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+      <vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>
+    </VirtualHardwareSection>{{if .UserData}}
+    <ProductSection ovf:required="false">
+      <Info>Kairos settings given at deployment</Info>
+      <Product>Kairos</Product>
+      <Property ovf:key="user-data" ovf:type="string" ovf:userConfigurable="true" ovf:value="">
+        <Label>Cloud-config</Label>
+        <Description>A base64 encoded Kairos cloud-config for the machine.</Description>
+      </Property>
+    </ProductSection>{{end}}
+  </VirtualSystem>
+</Envelope>
+`))
@@ -0,0 +1,244 @@
+package ops
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kairos-io/AuroraBoot/pkg/schema"
+)
+
+func TestRaw2Vmdk(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "kairos-test.raw")
+	writeFakeRawDisk(t, raw)
+
+	out, err := Raw2Vmdk(raw)
+	if err != nil {
+		t.Fatalf("Raw2Vmdk: %v", err)
+	}
+	if out != filepath.Join(dir, "kairos-test.vmdk") {
+		t.Fatalf("output name = %q", out)
+	}
+	img, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(raw)
+	if err != nil {
+		t.Fatalf("original raw should be left in place: %v", err)
+	}
+	checkVmdk(t, img, want)
+	if extent := fmt.Sprintf(`RW %d SPARSE "kairos-test.vmdk"`, len(want)/vmdkSectorSize); !bytes.Contains(img[vmdkSectorSize:], []byte(extent)) {
+		t.Errorf("descriptor does not name the extent with the disk's capacity:\n%s", img[vmdkSectorSize:2*vmdkSectorSize])
+	}
+	// Only the grains with data are stored.
+	if len(img) > 32*vmdkGrainSize*vmdkSectorSize {
+		t.Errorf("image of a mostly empty disk is %d bytes", len(img))
+	}
+}
+
+func TestRaw2Ova(t *testing.T) {
+	for _, reuse := range []bool{false, true} {
+		dir := t.TempDir()
+		raw := filepath.Join(dir, "kairos-test.raw")
+		writeFakeRawDisk(t, raw)
+		vmdk := ""
+		if reuse {
+			var err error
+			if vmdk, err = Raw2Vmdk(raw); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		out, err := Raw2Ova(raw, vmdk, schema.OVF{CPUs: 4, UserDataProperty: true})
+		if err != nil {
+			t.Fatalf("Raw2Ova(reuse=%v): %v", reuse, err)
+		}
+		if out != filepath.Join(dir, "kairos-test.ova") {
+			t.Fatalf("output name = %q", out)
+		}
+		entries, _ := os.ReadDir(dir)
+		if want := 2 + btoi(reuse); len(entries) != want {
+			t.Errorf("reuse=%v: want the raw disk, the OVA and only the VMDK that was asked for, got %v", reuse, entries)
+		}
+
+		f, err := os.Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files := map[string][]byte{}
+		var names []string
+		tr := tar.NewReader(f)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := io.ReadAll(tr)
+			files[h.Name] = b
+			names = append(names, h.Name)
+		}
+		disk := "kairos-test-disk1.vmdk"
+		if reuse {
+			disk = "kairos-test.vmdk"
+		}
+		if strings.Join(names, ",") != "kairos-test.ovf,kairos-test.mf,"+disk {
+			t.Fatalf("reuse=%v: OVA entries are %v", reuse, names)
+		}
+
+		ovf := files["kairos-test.ovf"]
+		if err := xml.Unmarshal(ovf, new(struct{})); err != nil {
+			t.Fatalf("OVF descriptor is not well-formed XML: %v", err)
+		}
+		for _, s := range []string{
+			`vmw:key="firmware" vmw:value="efi"`,
+			`<rasd:VirtualQuantity>4</rasd:VirtualQuantity>`,
+			`<rasd:VirtualQuantity>4096</rasd:VirtualQuantity>`,
+			`ovf:capacity="` + fmt.Sprint(fileSize(t, raw)) + `"`,
+			`ovf:href="` + disk + `" ovf:id="file1" ovf:size="` + fmt.Sprint(len(files[disk])) + `"`,
+			`ovf:transport="com.vmware.guestInfo"`,
+			`ovf:key="user-data"`,
+			// The controller must match the disk's ddb.adapterType.
+			`<rasd:ResourceSubType>lsilogic</rasd:ResourceSubType>`,
+		} {
+			if !bytes.Contains(ovf, []byte(s)) {
+				t.Errorf("reuse=%v: OVF descriptor lacks %s", reuse, s)
+			}
+		}
+		manifest := fmt.Sprintf("SHA256(kairos-test.ovf)= %x\nSHA256(%s)= %x\n", sha256.Sum256(ovf), disk, sha256.Sum256(files[disk]))
+		if string(files["kairos-test.mf"]) != manifest {
+			t.Errorf("reuse=%v: manifest is\n%s\nwant\n%s", reuse, files["kairos-test.mf"], manifest)
+		}
+		want, _ := os.ReadFile(raw)
+		checkVmdk(t, files[disk], want)
+		if !bytes.Contains(files[disk][vmdkSectorSize:2*vmdkSectorSize], []byte(`ddb.adapterType = "lsilogic"`)) {
+			t.Errorf("reuse=%v: disk descriptor does not declare the lsilogic adapter", reuse)
+		}
+	}
+}
+
+// The vApp property is left out unless asked for.
+func TestRaw2OvaWithoutUserData(t *testing.T) {
+	var ovf bytes.Buffer
+	if err := ovfTemplate.Execute(&ovf, ovfDescriptor{Name: "a<b", CPUs: 2, MemoryMB: 4096}); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ovf.Bytes(), []byte("ProductSection")) || bytes.Contains(ovf.Bytes(), []byte("transport")) {
+		t.Errorf("OVF descriptor has vApp properties:\n%s", ovf.String())
+	}
+	if err := xml.Unmarshal(ovf.Bytes(), new(struct{})); err != nil {
+		t.Errorf("names are not escaped: %v", err)
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.Size()
+}
+
+// checkVmdk reads back a stream-optimized VMDK the way a reader that trusts the footer does and checks that it
+// holds exactly want: the footer points at the grain directory, every table sits behind its marker, and every
+// grain inflates to the disk's content at the lba its marker gives.
+func checkVmdk(t *testing.T, img, want []byte) {
+	t.Helper()
+	sector := func(s uint64) []byte { return img[s*vmdkSectorSize:] }
+	var head, foot vmdkHeader
+	if err := binary.Read(bytes.NewReader(img), binary.LittleEndian, &head); err != nil {
+		t.Fatal(err)
+	}
+	if head.Magic != vmdkMagic || head.Version != 3 || head.Flags != vmdkFlags || head.GDOffset != vmdkGDAtEnd || head.CompressAlgorithm != 1 {
+		t.Fatalf("unexpected header %+v", head)
+	}
+	if head.Capacity*vmdkSectorSize != uint64(len(want)+vmdkSectorSize-1)/vmdkSectorSize*vmdkSectorSize {
+		t.Fatalf("capacity %d sectors for a disk of %d bytes", head.Capacity, len(want))
+	}
+	if len(img)%vmdkSectorSize != 0 {
+		t.Fatalf("image is %d bytes, not a whole number of sectors", len(img))
+	}
+
+	// The stream ends with the footer marker, the footer and the end of stream marker.
+	end := uint64(len(img)) / vmdkSectorSize
+	if !bytes.Equal(sector(end-1), make([]byte, vmdkSectorSize)) {
+		t.Fatal("image does not end with an end of stream marker")
+	}
+	marker := func(s uint64) vmdkMarker {
+		var m vmdkMarker
+		_ = binary.Read(bytes.NewReader(sector(s)), binary.LittleEndian, &m)
+		return m
+	}
+	if m := marker(end - 3); m.Type != vmdkMarkerFooter || m.NumSectors != 1 {
+		t.Fatalf("footer marker is %+v", m)
+	}
+	if err := binary.Read(bytes.NewReader(sector(end-2)), binary.LittleEndian, &foot); err != nil {
+		t.Fatal(err)
+	}
+	gdAt := foot.GDOffset
+	if foot.GDOffset = vmdkGDAtEnd; head != foot {
+		t.Fatal("footer does not repeat the header")
+	}
+	if m := marker(gdAt - 1); m.Type != vmdkMarkerGD {
+		t.Fatalf("grain directory marker is %+v", m)
+	}
+
+	grains := (head.Capacity + vmdkGrainSize - 1) / vmdkGrainSize
+	disk := make([]byte, grains*vmdkGrainSize*vmdkSectorSize)
+	for i := uint64(0); i < (grains+vmdkGTEntries-1)/vmdkGTEntries; i++ {
+		gtAt := uint64(binary.LittleEndian.Uint32(sector(gdAt)[i*4:]))
+		if gtAt == 0 {
+			continue
+		}
+		if m := marker(gtAt - 1); m.Type != vmdkMarkerGT || m.NumSectors != 4 {
+			t.Fatalf("grain table %d marker is %+v", i, m)
+		}
+		for j := uint64(0); j < vmdkGTEntries; j++ {
+			at := uint64(binary.LittleEndian.Uint32(sector(gtAt)[j*4:]))
+			if at == 0 {
+				continue
+			}
+			if at < head.OverHead {
+				t.Fatalf("grain at sector %d overlaps the descriptor", at)
+			}
+			g := sector(at)
+			lba := binary.LittleEndian.Uint64(g)
+			if grain := i*vmdkGTEntries + j; lba != grain*vmdkGrainSize {
+				t.Fatalf("grain %d has lba %d", grain, lba)
+			}
+			size := binary.LittleEndian.Uint32(g[8:])
+			zr, err := zlib.NewReader(bytes.NewReader(g[12 : 12+size]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.ReadFull(zr, disk[lba*vmdkSectorSize:(lba+vmdkGrainSize)*vmdkSectorSize]); err != nil {
+				t.Fatalf("inflating grain at lba %d: %v", lba, err)
+			}
+		}
+	}
+	if !bytes.Equal(disk[:len(want)], want) || !bytes.Equal(disk[len(want):], make([]byte, len(disk)-len(want))) {
+		t.Fatal("VMDK content differs from the raw disk")
+	}
+}
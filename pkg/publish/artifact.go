@@ -0,0 +1,119 @@
+package publish
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// EmptyConfigMediaType is the OCI empty descriptor artifacts use as their
+// config, whose content is always "{}".
+const EmptyConfigMediaType types.MediaType = "application/vnd.oci.empty.v1+json"
+
+var emptyConfig = []byte("{}")
+
+// NewArtifact returns an image manifest with one layer per file in paths,
+// read lazily from disk when pushed. Layer digests are computed up front,
+// which reads every file once.
+func NewArtifact(paths []string, subject *v1.Descriptor) (v1.Image, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files to publish")
+	}
+	cfgHash, cfgSize, err := v1.SHA256(bytes.NewReader(emptyConfig))
+	if err != nil {
+		return nil, err
+	}
+	a := &artifact{
+		layers: map[v1.Hash]*fileLayer{},
+		manifest: v1.Manifest{
+			SchemaVersion: 2,
+			MediaType:     types.OCIManifestSchema1,
+			ArtifactType:  ArtifactType,
+			Config: v1.Descriptor{
+				MediaType: EmptyConfigMediaType,
+				Digest:    cfgHash,
+				Size:      cfgSize,
+			},
+			Subject: subject,
+		},
+	}
+	seen := map[string]bool{}
+	for _, p := range paths {
+		title := filepath.Base(p)
+		if seen[title] {
+			return nil, fmt.Errorf("two files named %s", title)
+		}
+		seen[title] = true
+		l, err := newFileLayer(p)
+		if err != nil {
+			return nil, err
+		}
+		a.layers[l.digest] = l
+		a.manifest.Layers = append(a.manifest.Layers, v1.Descriptor{
+			MediaType:   l.mediaType,
+			Digest:      l.digest,
+			Size:        l.size,
+			Annotations: map[string]string{TitleAnnotation: title},
+		})
+	}
+	raw, err := json.Marshal(a.manifest)
+	if err != nil {
+		return nil, err
+	}
+	a.rawManifest = raw
+	return partial.CompressedToImage(a)
+}
+
+// artifact is the partial.CompressedImageCore behind NewArtifact. Its layers
+// are stored as-is, so "compressed" here just means "the bytes in the blob".
+type artifact struct {
+	manifest    v1.Manifest
+	rawManifest []byte
+	layers      map[v1.Hash]*fileLayer
+}
+
+func (a *artifact) RawConfigFile() ([]byte, error) { return emptyConfig, nil }
+
+func (a *artifact) MediaType() (types.MediaType, error) { return a.manifest.MediaType, nil }
+
+func (a *artifact) RawManifest() ([]byte, error) { return a.rawManifest, nil }
+
+func (a *artifact) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	if l, ok := a.layers[h]; ok {
+		return l, nil
+	}
+	return nil, fmt.Errorf("blob %s not found", h)
+}
+
+// fileLayer is a blob backed by a file on disk.
+type fileLayer struct {
+	path      string
+	digest    v1.Hash
+	size      int64
+	mediaType types.MediaType
+}
+
+func newFileLayer(path string) (*fileLayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h, size, err := v1.SHA256(f)
+	if err != nil {
+		return nil, fmt.Errorf("hashing %s: %w", path, err)
+	}
+	return &fileLayer{path: path, digest: h, size: size, mediaType: FileMediaType(path)}, nil
+}
+
+func (l *fileLayer) Digest() (v1.Hash, error)            { return l.digest, nil }
+func (l *fileLayer) Size() (int64, error)                { return l.size, nil }
+func (l *fileLayer) MediaType() (types.MediaType, error) { return l.mediaType, nil }
+func (l *fileLayer) Compressed() (io.ReadCloser, error)  { return os.Open(l.path) }
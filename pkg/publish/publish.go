@@ -0,0 +1,149 @@
+// Package publish pushes finished builds to OCI registries: the OS container
+// image under the requested tags, so `kairos-agent upgrade --source oci:` can
+// pull it directly, and the ISO, raw disk and other output files as an OCI
+// artifact next to it.
+//
+// The artifact follows the layout ORAS writes and reads: an image manifest
+// with an artifactType, the empty JSON config and one layer per file, named
+// by its org.opencontainers.image.title annotation, so `oras pull` restores
+// the files under their original names. When the OS image is pushed in the
+// same call the artifact names it as its subject and registries with the
+// referrers API list it as attached to the image.
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/daemon"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// ArtifactType is the artifactType of the manifest holding a build's
+	// output files.
+	ArtifactType = "application/vnd.kairos.build.v1"
+	// FilesTagSuffix is appended to every tag for the files artifact, so
+	// "v1.2" names the OS image and "v1.2-artifacts" its outputs.
+	FilesTagSuffix = "-artifacts"
+	// TitleAnnotation names the file a layer holds.
+	TitleAnnotation = "org.opencontainers.image.title"
+)
+
+// fileMediaTypes maps output file extensions to layer media types. Anything
+// else is pushed as application/octet-stream.
+var fileMediaTypes = map[string]types.MediaType{
+	".iso":   "application/vnd.kairos.iso.v1",
+	".raw":   "application/vnd.kairos.disk.raw.v1",
+	".img":   "application/vnd.kairos.disk.raw.v1",
+	".vhd":   "application/vnd.kairos.disk.vhd.v1",
+	".qcow2": "application/vnd.kairos.disk.qcow2.v1",
+	".vhdx":  "application/vnd.kairos.disk.vhdx.v1",
+	".vmdk":  "application/vnd.kairos.disk.vmdk.v1",
+	".ova":   "application/vnd.kairos.disk.ova.v1",
+	".efi":   "application/vnd.kairos.uki.v1",
+	".tar":   "application/vnd.oci.image.layer.v1.tar",
+	".gz":    "application/gzip",
+	".zst":   "application/zstd",
+	".xz":    "application/x-xz",
+	".sig":   "text/plain",
+	".json":  "application/json",
+}
+
+// FileMediaType returns the layer media type a file is pushed with.
+func FileMediaType(path string) types.MediaType {
+	base := filepath.Base(path)
+	if base == "SHA256SUMS" {
+		return "text/plain"
+	}
+	if mt, ok := fileMediaTypes[strings.ToLower(filepath.Ext(base))]; ok {
+		return mt
+	}
+	return "application/octet-stream"
+}
+
+// Image pushes img to repo under every tag and returns its descriptor.
+func Image(ctx context.Context, repo name.Repository, img v1.Image, tags []string, opts ...remote.Option) (*v1.Descriptor, error) {
+	if len(tags) == 0 {
+		return nil, errors.New("at least one tag is required")
+	}
+	desc, err := partial.Descriptor(img)
+	if err != nil {
+		return nil, fmt.Errorf("describing image: %w", err)
+	}
+	if err := push(ctx, repo, img, tags, opts); err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+// Files pushes the files at paths as one artifact under every tag plus
+// FilesTagSuffix and returns its descriptor. subject, when not nil, is the
+// OS image the files were built from, pushed to the same repository.
+func Files(ctx context.Context, repo name.Repository, paths []string, tags []string, subject *v1.Descriptor, opts ...remote.Option) (*v1.Descriptor, error) {
+	if len(tags) == 0 {
+		return nil, errors.New("at least one tag is required")
+	}
+	img, err := NewArtifact(paths, subject)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := partial.Descriptor(img)
+	if err != nil {
+		return nil, fmt.Errorf("describing artifact: %w", err)
+	}
+	fileTags := make([]string, len(tags))
+	for i, t := range tags {
+		fileTags[i] = t + FilesTagSuffix
+	}
+	if err := push(ctx, repo, img, fileTags, opts); err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+func push(ctx context.Context, repo name.Repository, img v1.Image, tags []string, opts []remote.Option) error {
+	opts = append([]remote.Option{remote.WithContext(ctx)}, opts...)
+	first := repo.Tag(tags[0])
+	if err := remote.Write(first, img, opts...); err != nil {
+		return fmt.Errorf("pushing %s: %w", first, err)
+	}
+	for _, t := range tags[1:] {
+		ref := repo.Tag(t)
+		if err := remote.Tag(ref, img, opts...); err != nil {
+			return fmt.Errorf("tagging %s: %w", ref, err)
+		}
+	}
+	return nil
+}
+
+// SourceImage opens the OS image a build produced. The local builder leaves
+// it in the Docker daemon; other backends record a reference to a registry,
+// which is pulled with the default keychain.
+func SourceImage(ctx context.Context, ref string, insecure bool) (v1.Image, error) {
+	var nameOpts []name.Option
+	if insecure {
+		nameOpts = append(nameOpts, name.Insecure)
+	}
+	r, err := name.ParseReference(ref, nameOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ref, err)
+	}
+	img, daemonErr := daemon.Image(r, daemon.WithContext(ctx))
+	if daemonErr == nil {
+		return img, nil
+	}
+	img, err = remote.Image(r, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in the local Docker daemon (%v) and could not be pulled: %w", ref, daemonErr, err)
+	}
+	return img, nil
+}
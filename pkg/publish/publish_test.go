@@ -0,0 +1,127 @@
+package publish_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/publish"
+)
+
+var _ = Describe("Publishing to a registry", func() {
+	var (
+		ctx  context.Context
+		repo name.Repository
+		dir  string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		srv := httptest.NewServer(ggcrregistry.New(ggcrregistry.WithReferrersSupport(true)))
+		DeferCleanup(srv.Close)
+		var err error
+		repo, err = name.NewRepository(strings.TrimPrefix(srv.URL, "http://")+"/kairos/my-os", name.Insecure)
+		Expect(err).NotTo(HaveOccurred())
+
+		dir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "kairos.iso"), []byte("iso-bytes"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "kairos.raw"), []byte("raw-bytes"), 0o644)).To(Succeed())
+	})
+
+	pull := func(ref string) v1.Image {
+		r, err := name.ParseReference(ref, name.Insecure)
+		Expect(err).NotTo(HaveOccurred())
+		img, err := remote.Image(r)
+		Expect(err).NotTo(HaveOccurred())
+		return img
+	}
+
+	It("pushes the OS image under every tag", func() {
+		img, err := random.Image(64, 2)
+		Expect(err).NotTo(HaveOccurred())
+		desc, err := publish.Image(ctx, repo, img, []string{"v1.0", "latest"})
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, tag := range []string{"v1.0", "latest"} {
+			got, err := pull(repo.Tag(tag).String()).Digest()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(desc.Digest))
+		}
+	})
+
+	It("pushes output files as an ORAS-style artifact referring to the image", func() {
+		img, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		imgDesc, err := publish.Image(ctx, repo, img, []string{"v1.0"})
+		Expect(err).NotTo(HaveOccurred())
+
+		paths := []string{filepath.Join(dir, "kairos.iso"), filepath.Join(dir, "kairos.raw")}
+		desc, err := publish.Files(ctx, repo, paths, []string{"v1.0"}, imgDesc)
+		Expect(err).NotTo(HaveOccurred())
+
+		art := pull(repo.Tag("v1.0" + publish.FilesTagSuffix).String())
+		m, err := art.Manifest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ArtifactType).To(Equal(publish.ArtifactType))
+		Expect(m.Config.MediaType).To(Equal(publish.EmptyConfigMediaType))
+		Expect(m.Subject).NotTo(BeNil())
+		Expect(m.Subject.Digest).To(Equal(imgDesc.Digest))
+		Expect(m.Layers).To(HaveLen(2))
+		Expect(m.Layers[0].Annotations).To(HaveKeyWithValue(publish.TitleAnnotation, "kairos.iso"))
+		Expect(m.Layers[0].MediaType).To(BeEquivalentTo("application/vnd.kairos.iso.v1"))
+		Expect(m.Layers[1].Annotations).To(HaveKeyWithValue(publish.TitleAnnotation, "kairos.raw"))
+
+		cfg, err := art.RawConfigFile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(cfg)).To(Equal("{}"))
+
+		layers, err := art.Layers()
+		Expect(err).NotTo(HaveOccurred())
+		rc, err := layers[0].Compressed()
+		Expect(err).NotTo(HaveOccurred())
+		data, err := io.ReadAll(rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rc.Close()).To(Succeed())
+		Expect(string(data)).To(Equal("iso-bytes"))
+
+		idx, err := remote.Referrers(repo.Digest(imgDesc.Digest.String()))
+		Expect(err).NotTo(HaveOccurred())
+		im, err := idx.IndexManifest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(im.Manifests).To(HaveLen(1))
+		Expect(im.Manifests[0].Digest).To(Equal(desc.Digest))
+	})
+
+	It("refuses two files with the same name", func() {
+		other := filepath.Join(GinkgoT().TempDir(), "kairos.iso")
+		Expect(os.WriteFile(other, []byte("x"), 0o644)).To(Succeed())
+		_, err := publish.NewArtifact([]string{filepath.Join(dir, "kairos.iso"), other}, nil)
+		Expect(err).To(MatchError(ContainSubstring("two files named kairos.iso")))
+	})
+
+	It("requires a tag", func() {
+		_, err := publish.Files(ctx, repo, []string{filepath.Join(dir, "kairos.iso")}, nil, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("file media types",
+		func(file, want string) {
+			Expect(publish.FileMediaType(file)).To(BeEquivalentTo(want))
+		},
+		Entry("ISO", "/out/kairos.iso", "application/vnd.kairos.iso.v1"),
+		Entry("raw disk", "kairos.raw", "application/vnd.kairos.disk.raw.v1"),
+		Entry("manifest", "SHA256SUMS", "text/plain"),
+		Entry("unknown", "kernel", "application/octet-stream"),
+	)
+})
@@ -0,0 +1,13 @@
+package publish_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPublish(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "pkg/publish suite")
+}
@@ -0,0 +1,171 @@
+// Package retention decides which finished builds to delete so the artifacts
+// directory does not grow without bound.
+//
+// A Policy combines up to three rules: keep only the newest N working builds
+// of each base image, delete builds older than a number of days, and cap the
+// bytes all builds take on disk. Compute only plans; the caller deletes. It
+// never plans to remove a build that is Saved, still running, or Protected (the
+// caller marks builds an active deployment, a channel or the netboot server
+// still needs), so a dry run and a real run agree on what goes.
+package retention
+
+import (
+	"sort"
+	"time"
+)
+
+// Policy holds the retention rules. A zero field disables its rule.
+type Policy struct {
+	// KeepLastPerBase keeps the newest N unsaved working builds of each base
+	// image. Failed builds do not count toward N; one goes once N working
+	// builds newer than it exist.
+	KeepLastPerBase int `json:"keepLastPerBase"`
+	// MaxAgeDays removes unsaved builds older than this many days.
+	MaxAgeDays int `json:"maxAgeDays"`
+	// MaxTotalBytes removes the oldest unsaved builds until all builds,
+	// saved ones included, fit in this many bytes.
+	MaxTotalBytes int64 `json:"maxTotalBytes"`
+}
+
+// Enabled reports whether any rule is set.
+func (p Policy) Enabled() bool {
+	return p.KeepLastPerBase > 0 || p.MaxAgeDays > 0 || p.MaxTotalBytes > 0
+}
+
+// Why a build is planned for removal.
+const (
+	ReasonKeepLast = "keep-last"
+	ReasonMaxAge   = "max-age"
+	ReasonMaxBytes = "max-total-bytes"
+)
+
+// Artifact is what Compute needs to know about a build.
+type Artifact struct {
+	ID        string
+	Name      string
+	Base      string
+	CreatedAt time.Time
+	Bytes     int64
+	Saved     bool
+	// Finished is false while the build is pending or running; those are
+	// never removed.
+	Finished bool
+	// Failed marks a build that finished with an error.
+	Failed bool
+	// Protected says why the build must stay (e.g. "channel ubuntu/stable"),
+	// or is empty.
+	Protected string
+}
+
+// Removal is a build the plan deletes.
+type Removal struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Base      string    `json:"base,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Bytes     int64     `json:"bytes"`
+	Reason    string    `json:"reason"`
+}
+
+// Kept is a build a rule matched that stays because it is protected.
+type Kept struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Reason    string `json:"reason"`
+	Protected string `json:"protected"`
+}
+
+// Plan is the outcome of Compute.
+type Plan struct {
+	Remove []Removal `json:"remove"`
+	Kept   []Kept    `json:"kept,omitempty"`
+	// TotalBytes is what all builds take now, FreedBytes what Remove frees.
+	TotalBytes int64 `json:"totalBytes"`
+	FreedBytes int64 `json:"freedBytes"`
+	// OverBudget is set when the builds left after Remove still exceed
+	// MaxTotalBytes, because the rest are saved, running or protected.
+	OverBudget bool `json:"overBudget,omitempty"`
+}
+
+// Compute plans the removals policy calls for at now. Rules apply in order
+// (keep-last, max-age, max-total-bytes), and each build is removed for the
+// first rule that matches it. The byte cap removes the oldest builds first.
+func Compute(policy Policy, now time.Time, artifacts []Artifact) Plan {
+	var plan Plan
+	byAge := append([]Artifact(nil), artifacts...)
+	sort.SliceStable(byAge, func(i, j int) bool { return byAge[i].CreatedAt.Before(byAge[j].CreatedAt) })
+
+	reasons := map[string]string{}
+	if policy.KeepLastPerBase > 0 {
+		seen := map[string]int{}
+		for i := len(byAge) - 1; i >= 0; i-- {
+			a := byAge[i]
+			if !eligible(a) {
+				continue
+			}
+			if a.Failed {
+				if seen[a.Base] >= policy.KeepLastPerBase {
+					reasons[a.ID] = ReasonKeepLast
+				}
+				continue
+			}
+			seen[a.Base]++
+			if seen[a.Base] > policy.KeepLastPerBase {
+				reasons[a.ID] = ReasonKeepLast
+			}
+		}
+	}
+	if policy.MaxAgeDays > 0 {
+		cutoff := now.Add(-time.Duration(policy.MaxAgeDays) * 24 * time.Hour)
+		for _, a := range byAge {
+			if eligible(a) && a.CreatedAt.Before(cutoff) && reasons[a.ID] == "" {
+				reasons[a.ID] = ReasonMaxAge
+			}
+		}
+	}
+
+	for _, a := range byAge {
+		plan.TotalBytes += a.Bytes
+	}
+	remove := func(a Artifact, reason string) {
+		plan.Remove = append(plan.Remove, Removal{ID: a.ID, Name: a.Name, Base: a.Base, CreatedAt: a.CreatedAt, Bytes: a.Bytes, Reason: reason})
+		plan.FreedBytes += a.Bytes
+	}
+	removed := map[string]bool{}
+	for _, a := range byAge {
+		reason := reasons[a.ID]
+		if reason == "" {
+			continue
+		}
+		if a.Protected != "" {
+			plan.Kept = append(plan.Kept, Kept{ID: a.ID, Name: a.Name, Reason: reason, Protected: a.Protected})
+			continue
+		}
+		remove(a, reason)
+		removed[a.ID] = true
+	}
+
+	if policy.MaxTotalBytes > 0 {
+		for _, a := range byAge {
+			if plan.TotalBytes-plan.FreedBytes <= policy.MaxTotalBytes {
+				break
+			}
+			if removed[a.ID] || !eligible(a) || a.Protected != "" {
+				continue
+			}
+			remove(a, ReasonMaxBytes)
+			removed[a.ID] = true
+		}
+		plan.OverBudget = plan.TotalBytes-plan.FreedBytes > policy.MaxTotalBytes
+	}
+
+	sort.SliceStable(plan.Remove, func(i, j int) bool { return plan.Remove[i].CreatedAt.Before(plan.Remove[j].CreatedAt) })
+	if plan.Remove == nil {
+		plan.Remove = []Removal{}
+	}
+	return plan
+}
+
+func eligible(a Artifact) bool {
+	return a.Finished && !a.Saved
+}
@@ -0,0 +1,107 @@
+package retention_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kairos-io/AuroraBoot/pkg/retention"
+)
+
+var _ = Describe("Compute", func() {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(d int) time.Time { return now.Add(-time.Duration(d) * 24 * time.Hour) }
+	build := func(id, base string, age int, bytes int64) retention.Artifact {
+		return retention.Artifact{ID: id, Base: base, CreatedAt: daysAgo(age), Bytes: bytes, Finished: true}
+	}
+	ids := func(plan retention.Plan) []string {
+		out := []string{}
+		for _, r := range plan.Remove {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+
+	It("plans nothing without rules", func() {
+		plan := retention.Compute(retention.Policy{}, now, []retention.Artifact{build("a", "ubuntu", 400, 10)})
+		Expect(plan.Remove).To(BeEmpty())
+		Expect(plan.TotalBytes).To(BeEquivalentTo(10))
+	})
+
+	It("keeps the newest builds of each base image", func() {
+		plan := retention.Compute(retention.Policy{KeepLastPerBase: 2}, now, []retention.Artifact{
+			build("u1", "ubuntu", 30, 1),
+			build("u2", "ubuntu", 20, 1),
+			build("u3", "ubuntu", 10, 1),
+			build("f1", "fedora", 40, 1),
+		})
+		Expect(ids(plan)).To(Equal([]string{"u1"}))
+		Expect(plan.Remove[0].Reason).To(Equal(retention.ReasonKeepLast))
+	})
+
+	It("counts only working builds toward keep-last", func() {
+		failed := func(id string, age int) retention.Artifact {
+			a := build(id, "ubuntu", age, 1)
+			a.Failed = true
+			return a
+		}
+		plan := retention.Compute(retention.Policy{KeepLastPerBase: 2}, now, []retention.Artifact{
+			build("u1", "ubuntu", 50, 1),
+			failed("e1", 45),
+			build("u2", "ubuntu", 40, 1),
+			build("u3", "ubuntu", 30, 1),
+			failed("e2", 20),
+			failed("e3", 10),
+		})
+		Expect(ids(plan)).To(ConsistOf("u1", "e1"))
+	})
+
+	It("never removes saved, running or protected builds", func() {
+		saved := build("saved", "ubuntu", 90, 1)
+		saved.Saved = true
+		running := build("running", "ubuntu", 90, 1)
+		running.Finished = false
+		pinned := build("pinned", "ubuntu", 90, 1)
+		pinned.Protected = "channel ubuntu/stable"
+
+		plan := retention.Compute(retention.Policy{MaxAgeDays: 30}, now, []retention.Artifact{
+			saved, running, pinned, build("old", "ubuntu", 60, 1), build("new", "ubuntu", 1, 1),
+		})
+		Expect(ids(plan)).To(Equal([]string{"old"}))
+		Expect(plan.Kept).To(ConsistOf(retention.Kept{ID: "pinned", Reason: retention.ReasonMaxAge, Protected: "channel ubuntu/stable"}))
+	})
+
+	It("removes the oldest builds until the byte cap is met", func() {
+		saved := build("saved", "ubuntu", 100, 50)
+		saved.Saved = true
+		plan := retention.Compute(retention.Policy{MaxTotalBytes: 100}, now, []retention.Artifact{
+			saved,
+			build("b1", "ubuntu", 50, 30),
+			build("b2", "ubuntu", 40, 30),
+			build("b3", "ubuntu", 30, 30),
+		})
+		Expect(ids(plan)).To(Equal([]string{"b1", "b2"}))
+		Expect(plan.FreedBytes).To(BeEquivalentTo(60))
+		Expect(plan.OverBudget).To(BeFalse())
+	})
+
+	It("reports a cap it cannot meet", func() {
+		saved := build("saved", "ubuntu", 100, 500)
+		saved.Saved = true
+		plan := retention.Compute(retention.Policy{MaxTotalBytes: 100}, now, []retention.Artifact{saved, build("b1", "ubuntu", 5, 10)})
+		Expect(ids(plan)).To(Equal([]string{"b1"}))
+		Expect(plan.OverBudget).To(BeTrue())
+	})
+
+	It("counts builds removed by earlier rules toward the cap", func() {
+		plan := retention.Compute(retention.Policy{MaxAgeDays: 30, MaxTotalBytes: 50}, now, []retention.Artifact{
+			build("old", "ubuntu", 60, 40),
+			build("mid", "ubuntu", 20, 40),
+			build("new", "ubuntu", 1, 40),
+		})
+		Expect(ids(plan)).To(Equal([]string{"old", "mid"}))
+		Expect(plan.Remove[0].Reason).To(Equal(retention.ReasonMaxAge))
+		Expect(plan.Remove[1].Reason).To(Equal(retention.ReasonMaxBytes))
+	})
+})
@@ -0,0 +1,65 @@
+package sbom
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+)
+
+const apkInstalledPath = "lib/apk/db/installed"
+
+// readAPK parses the apk installed database: one-letter keyed lines, one
+// stanza per package, stanzas separated by blank lines.
+func readAPK(rootfs string) ([]Package, error) {
+	f, err := os.Open(filepath.Join(rootfs, apkInstalledPath))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		pkgs []Package
+		cur  Package
+	)
+	flush := func() {
+		if cur.Name != "" {
+			cur.Type = TypeAPK
+			if cur.Source == cur.Name {
+				cur.Source = ""
+			}
+			pkgs = append(pkgs, cur)
+		}
+		cur = Package{}
+	}
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		v := line[2:]
+		switch line[0] {
+		case 'P':
+			cur.Name = v
+		case 'V':
+			cur.Version = v
+		case 'A':
+			cur.Arch = v
+		case 'L':
+			cur.License = v
+		case 'o':
+			cur.Source = v
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return pkgs, nil
+}
@@ -0,0 +1,88 @@
+package sbom
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// cvss3BaseScore computes the CVSS v3.x base score of a vector string such as
+// "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", following the equations in
+// section 7.1 of the CVSS v3.1 specification. OSV records usually carry only
+// the vector, so the score has to be derived to bucket the finding.
+func cvss3BaseScore(vector string) (float64, error) {
+	if !strings.HasPrefix(vector, "CVSS:3.") {
+		return 0, fmt.Errorf("not a CVSS v3 vector: %q", vector)
+	}
+	m := map[string]string{}
+	for _, part := range strings.Split(vector, "/")[1:] {
+		k, v, ok := strings.Cut(part, ":")
+		if ok {
+			m[k] = v
+		}
+	}
+	weights := map[string]map[string]float64{
+		"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+		"AC": {"L": 0.77, "H": 0.44},
+		"UI": {"N": 0.85, "R": 0.62},
+		"C":  {"H": 0.56, "L": 0.22, "N": 0},
+		"I":  {"H": 0.56, "L": 0.22, "N": 0},
+		"A":  {"H": 0.56, "L": 0.22, "N": 0},
+	}
+	w := map[string]float64{}
+	for k, table := range weights {
+		v, ok := table[m[k]]
+		if !ok {
+			return 0, fmt.Errorf("CVSS vector %q: missing or invalid %s", vector, k)
+		}
+		w[k] = v
+	}
+	changed := m["S"] == "C"
+	if m["S"] != "U" && !changed {
+		return 0, fmt.Errorf("CVSS vector %q: missing or invalid S", vector)
+	}
+	var pr float64
+	switch m["PR"] {
+	case "N":
+		pr = 0.85
+	case "L":
+		pr = 0.62
+		if changed {
+			pr = 0.68
+		}
+	case "H":
+		pr = 0.27
+		if changed {
+			pr = 0.5
+		}
+	default:
+		return 0, fmt.Errorf("CVSS vector %q: missing or invalid PR", vector)
+	}
+
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	var impact float64
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	} else {
+		impact = 6.42 * iss
+	}
+	exploitability := 8.22 * w["AV"] * w["AC"] * pr * w["UI"]
+	if impact <= 0 {
+		return 0, nil
+	}
+	if changed {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), nil
+}
+
+// roundUp is the specification's Roundup: the smallest number with one
+// decimal place that is equal to or higher than its input, computed on
+// integers to avoid floating point artefacts.
+func roundUp(x float64) float64 {
+	i := int(math.Round(x * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return (math.Floor(float64(i)/10000) + 1) / 10
+}
@@ -0,0 +1,76 @@
+package sbom
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The CycloneDX 1.5 JSON subset AuroraBoot emits: the OS as the metadata
+// component and one library component per installed package.
+
+type cdxDoc struct {
+	BOMFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber"`
+	Version      int            `json:"version"`
+	Metadata     cdxMetadata    `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     []cdxTool    `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTool struct {
+	Name string `json:"name"`
+}
+
+type cdxComponent struct {
+	Type     string       `json:"type"`
+	BOMRef   string       `json:"bom-ref,omitempty"`
+	Name     string       `json:"name"`
+	Version  string       `json:"version,omitempty"`
+	PURL     string       `json:"purl,omitempty"`
+	Licenses []cdxLicense `json:"licenses,omitempty"`
+}
+
+type cdxLicense struct {
+	Expression string `json:"expression"`
+}
+
+func cycloneDXDocument(name string, inv *Inventory) cdxDoc {
+	doc := cdxDoc{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools:     []cdxTool{{Name: "auroraboot"}},
+			Component: cdxComponent{
+				Type:    "operating-system",
+				Name:    name,
+				Version: inv.Distro.VersionID,
+			},
+		},
+		Components: make([]cdxComponent, 0, len(inv.Packages)),
+	}
+	for _, p := range inv.Packages {
+		ref := purl(p, inv.Distro)
+		c := cdxComponent{
+			Type:    "library",
+			BOMRef:  ref,
+			Name:    p.Name,
+			Version: p.Version,
+			PURL:    ref,
+		}
+		if p.License != "" {
+			c.Licenses = []cdxLicense{{Expression: p.License}}
+		}
+		doc.Components = append(doc.Components, c)
+	}
+	return doc
+}
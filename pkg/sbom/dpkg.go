@@ -0,0 +1,76 @@
+package sbom
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const dpkgStatusPath = "var/lib/dpkg/status"
+
+// readDpkg parses the dpkg status file: RFC 822 stanzas separated by blank
+// lines. Only packages whose Status ends in "installed" are reported; the file
+// also remembers removed packages whose config files are still around.
+func readDpkg(rootfs string) ([]Package, error) {
+	f, err := os.Open(filepath.Join(rootfs, dpkgStatusPath))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		pkgs   []Package
+		cur    Package
+		status string
+		key    string
+	)
+	flush := func() {
+		if cur.Name != "" && strings.HasSuffix(status, " installed") {
+			cur.Type = TypeDeb
+			pkgs = append(pkgs, cur)
+		}
+		cur, status, key = Package{}, "", ""
+	}
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		// Continuation lines belong to multi-line fields (Description,
+		// Conffiles) that are not recorded.
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = k
+		v = strings.TrimSpace(v)
+		switch key {
+		case "Package":
+			cur.Name = v
+		case "Version":
+			cur.Version = v
+		case "Architecture":
+			cur.Arch = v
+		case "Status":
+			status = v
+		case "Source":
+			// "Source: openssl (3.0.11-1)" carries the source version when
+			// it differs from the binary one; only the name is kept.
+			name, _, _ := strings.Cut(v, " ")
+			cur.Source = name
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return pkgs, nil
+}
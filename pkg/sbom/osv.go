@@ -0,0 +1,388 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity levels reported in a Summary, highest first.
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityHigh     = "HIGH"
+	SeverityMedium   = "MEDIUM"
+	SeverityLow      = "LOW"
+	SeverityUnknown  = "UNKNOWN"
+)
+
+// Advisory is the part of an OSV record (https://ossf.github.io/osv-schema/)
+// Match needs. Distribution feeds from osv.dev (Debian, Ubuntu, Alpine, Rocky
+// Linux, AlmaLinux, openSUSE, ...) are published in this format, so a local
+// mirror is just their JSON files unpacked into a directory.
+type Advisory struct {
+	ID       string              `json:"id"`
+	Summary  string              `json:"summary,omitempty"`
+	Aliases  []string            `json:"aliases,omitempty"`
+	Severity []osvSeverity       `json:"severity,omitempty"`
+	Affected []osvAffected       `json:"affected"`
+	DBSpec   osvDatabaseSpecific `json:"database_specific,omitempty"`
+}
+
+type osvSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type osvDatabaseSpecific struct {
+	Severity string `json:"severity,omitempty"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges   []osvRange `json:"ranges,omitempty"`
+	Versions []string   `json:"versions,omitempty"`
+	// Some feeds (Ubuntu) carry a per-package severity here.
+	EcosystemSpecific struct {
+		Severity string `json:"severity,omitempty"`
+		Urgency  string `json:"urgency,omitempty"`
+	} `json:"ecosystem_specific,omitempty"`
+}
+
+type osvRange struct {
+	Type   string     `json:"type"`
+	Events []osvEvent `json:"events"`
+}
+
+type osvEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// AdvisoryDB is an in-memory index of advisories by ecosystem name (the part
+// before the first ":") and package name.
+type AdvisoryDB struct {
+	Path  string
+	index map[string][]*Advisory
+	count int
+}
+
+// Len is the number of advisories loaded.
+func (db *AdvisoryDB) Len() int { return db.count }
+
+// LoadAdvisories reads every *.json OSV file under dir, recursively. Files
+// that do not parse as OSV are skipped, so a mirror may keep its own index or
+// metadata files alongside the advisories.
+func LoadAdvisories(dir string) (*AdvisoryDB, error) {
+	db := &AdvisoryDB{Path: dir, index: map[string][]*Advisory{}}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var a Advisory
+		if json.Unmarshal(data, &a) != nil || a.ID == "" {
+			return nil
+		}
+		db.count++
+		seen := map[string]bool{}
+		for _, af := range a.Affected {
+			key := ecosystemName(af.Package.Ecosystem) + "/" + af.Package.Name
+			if !seen[key] {
+				seen[key] = true
+				db.index[key] = append(db.index[key], &a)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading advisories from %s: %w", dir, err)
+	}
+	return db, nil
+}
+
+// Summary counts matched vulnerabilities by severity.
+type Summary struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Unknown  int `json:"unknown"`
+	Total    int `json:"total"`
+}
+
+// Finding is one advisory affecting one installed package.
+type Finding struct {
+	ID           string   `json:"id"`
+	Aliases      []string `json:"aliases,omitempty"`
+	Package      string   `json:"package"`
+	Version      string   `json:"version"`
+	FixedVersion string   `json:"fixedVersion,omitempty"`
+	Severity     string   `json:"severity"`
+	Summary      string   `json:"summary,omitempty"`
+}
+
+// VulnReport is the result of matching an inventory against an AdvisoryDB. It is
+// written as ReportFileName next to the SBOM.
+type VulnReport struct {
+	Database   string    `json:"database"`
+	Advisories int       `json:"advisories"`
+	ScannedAt  time.Time `json:"scannedAt"`
+	Distro     Distro    `json:"distro"`
+	Packages   int       `json:"packages"`
+	Summary    Summary   `json:"summary"`
+	Findings   []Finding `json:"findings"`
+}
+
+// Match reports every advisory in db that affects a package in inv. An
+// advisory only applies to the distribution release it was published for, so
+// an inventory whose distro is not covered by the mirror simply yields no
+// findings.
+func Match(inv *Inventory, db *AdvisoryDB) *VulnReport {
+	r := &VulnReport{
+		Database:   db.Path,
+		Advisories: db.count,
+		ScannedAt:  time.Now().UTC(),
+		Distro:     inv.Distro,
+		Packages:   len(inv.Packages),
+		Findings:   []Finding{},
+	}
+	eco, release := distroEcosystem(inv.Distro)
+	if eco == "" {
+		return r
+	}
+	for _, p := range inv.Packages {
+		names := []string{p.Name}
+		if p.Source != "" {
+			names = []string{p.Source, p.Name}
+		}
+		reported := map[string]bool{}
+		for _, n := range names {
+			for _, a := range db.index[eco+"/"+n] {
+				if reported[a.ID] {
+					continue
+				}
+				for _, af := range a.Affected {
+					if af.Package.Name != n || ecosystemName(af.Package.Ecosystem) != eco || !releaseMatches(af.Package.Ecosystem, release) {
+						continue
+					}
+					affected, fixed := affects(af, p)
+					if !affected {
+						continue
+					}
+					reported[a.ID] = true
+					sev := advisorySeverity(a, af)
+					r.Findings = append(r.Findings, Finding{
+						ID:           a.ID,
+						Aliases:      a.Aliases,
+						Package:      p.Name,
+						Version:      p.Version,
+						FixedVersion: fixed,
+						Severity:     sev,
+						Summary:      a.Summary,
+					})
+					r.Summary.add(sev)
+					break
+				}
+			}
+		}
+	}
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		si, sj := severityRank(r.Findings[i].Severity), severityRank(r.Findings[j].Severity)
+		if si != sj {
+			return si > sj
+		}
+		return r.Findings[i].Package < r.Findings[j].Package
+	})
+	return r
+}
+
+func (s *Summary) add(sev string) {
+	s.Total++
+	switch sev {
+	case SeverityCritical:
+		s.Critical++
+	case SeverityHigh:
+		s.High++
+	case SeverityMedium:
+		s.Medium++
+	case SeverityLow:
+		s.Low++
+	default:
+		s.Unknown++
+	}
+}
+
+func severityRank(s string) int {
+	switch s {
+	case SeverityCritical:
+		return 4
+	case SeverityHigh:
+		return 3
+	case SeverityMedium:
+		return 2
+	case SeverityLow:
+		return 1
+	}
+	return 0
+}
+
+// affects reports whether p's version falls in one of af's ranges or
+// explicit versions, and the first fixed version when known.
+func affects(af osvAffected, p Package) (bool, string) {
+	for _, v := range af.Versions {
+		if v == p.Version {
+			return true, ""
+		}
+	}
+	for _, rg := range af.Ranges {
+		if rg.Type != "ECOSYSTEM" {
+			continue
+		}
+		// Events are sorted by version; walk them keeping track of whether
+		// the installed version is inside an introduced..fixed window.
+		in := false
+		for _, ev := range rg.Events {
+			switch {
+			case ev.Introduced != "":
+				if ev.Introduced == "0" || compareVersions(p.Type, p.Version, ev.Introduced) >= 0 {
+					in = true
+				}
+			case ev.Fixed != "":
+				if in && compareVersions(p.Type, p.Version, ev.Fixed) < 0 {
+					return true, ev.Fixed
+				}
+				in = false
+			case ev.LastAffected != "":
+				if in && compareVersions(p.Type, p.Version, ev.LastAffected) <= 0 {
+					return true, ""
+				}
+				in = false
+			}
+		}
+		if in {
+			return true, ""
+		}
+	}
+	return false, ""
+}
+
+// advisorySeverity picks the most specific severity the advisory carries:
+// the per-package one, then the database's, then a CVSS v3 vector.
+func advisorySeverity(a *Advisory, af osvAffected) string {
+	for _, s := range []string{af.EcosystemSpecific.Severity, a.DBSpec.Severity} {
+		if sev := normalizeSeverity(s); sev != SeverityUnknown {
+			return sev
+		}
+	}
+	for _, s := range a.Severity {
+		if !strings.HasPrefix(s.Type, "CVSS_V3") {
+			continue
+		}
+		score, err := strconv.ParseFloat(s.Score, 64)
+		if err != nil {
+			score, err = cvss3BaseScore(s.Score)
+		}
+		if err == nil {
+			return severityFromScore(score)
+		}
+	}
+	return normalizeSeverity(af.EcosystemSpecific.Urgency)
+}
+
+func normalizeSeverity(s string) string {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "CRITICAL":
+		return SeverityCritical
+	case "HIGH", "IMPORTANT":
+		return SeverityHigh
+	case "MEDIUM", "MODERATE":
+		return SeverityMedium
+	case "LOW", "NEGLIGIBLE", "UNIMPORTANT":
+		return SeverityLow
+	}
+	return SeverityUnknown
+}
+
+func severityFromScore(score float64) string {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	}
+	return SeverityUnknown
+}
+
+// ecosystemName is the part of an OSV ecosystem before the release, e.g.
+// "Debian" in "Debian:12".
+func ecosystemName(eco string) string {
+	name, _, _ := strings.Cut(eco, ":")
+	return name
+}
+
+// distroEcosystem maps os-release to the OSV ecosystem name and the release
+// string advisories for it are qualified with.
+func distroEcosystem(d Distro) (string, string) {
+	major, _, _ := strings.Cut(d.VersionID, ".")
+	switch d.ID {
+	case "debian":
+		return "Debian", major
+	case "ubuntu":
+		return "Ubuntu", d.VersionID
+	case "alpine":
+		parts := strings.SplitN(d.VersionID, ".", 3)
+		if len(parts) >= 2 {
+			return "Alpine", "v" + parts[0] + "." + parts[1]
+		}
+		return "Alpine", ""
+	case "rocky":
+		return "Rocky Linux", major
+	case "almalinux":
+		return "AlmaLinux", major
+	case "opensuse-leap":
+		return "openSUSE", "Leap " + d.VersionID
+	case "opensuse-tumbleweed":
+		return "openSUSE", "Tumbleweed"
+	case "sles", "sle-micro":
+		return "SUSE", ""
+	case "rhel":
+		return "Red Hat", ""
+	}
+	return "", ""
+}
+
+// releaseMatches reports whether an advisory's ecosystem string applies to
+// release. "Debian" with no release applies to all of them; "Ubuntu:22.04:LTS"
+// and "Ubuntu:Pro:22.04:LTS" both apply to 22.04.
+func releaseMatches(eco, release string) bool {
+	_, rest, ok := strings.Cut(eco, ":")
+	if !ok || release == "" {
+		return true
+	}
+	for _, part := range strings.Split(rest, ":") {
+		if part == release {
+			return true
+		}
+	}
+	return false
+}
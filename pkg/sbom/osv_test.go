@@ -0,0 +1,80 @@
+package sbom
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Match", func() {
+	var db *AdvisoryDB
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		write := func(name, body string) {
+			Expect(os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644)).To(Succeed())
+		}
+		// Fixed in a newer revision than installed: affected.
+		write("debian/DSA-0001.json", `{
+			"id": "DSA-0001", "aliases": ["CVE-2024-0001"], "summary": "openssl bug",
+			"severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+			"affected": [{"package": {"ecosystem": "Debian:12", "name": "openssl"},
+				"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "3.0.11-1~deb12u3"}]}]}]
+		}`)
+		// Already fixed in the installed version: not affected.
+		write("debian/DSA-0002.json", `{
+			"id": "DSA-0002",
+			"affected": [{"package": {"ecosystem": "Debian:12", "name": "openssl"},
+				"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "3.0.11-1~deb12u1"}]}]}]
+		}`)
+		// Another release: not applicable.
+		write("debian/DSA-0003.json", `{
+			"id": "DSA-0003",
+			"affected": [{"package": {"ecosystem": "Debian:11", "name": "bash"},
+				"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]}]
+		}`)
+		// Unfixed, database-provided severity, matched by binary name.
+		write("debian/DSA-0004.json", `{
+			"id": "DSA-0004", "database_specific": {"severity": "low"},
+			"affected": [{"package": {"ecosystem": "Debian", "name": "bash"},
+				"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "5.0"}]}]}]
+		}`)
+		write("index.json", `{"not": "an advisory"}`)
+
+		var err error
+		db, err = LoadAdvisories(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Len()).To(Equal(4))
+	})
+
+	It("reports the advisories affecting installed packages", func() {
+		inv := &Inventory{
+			Distro: Distro{ID: "debian", VersionID: "12"},
+			Packages: []Package{
+				{Name: "bash", Version: "5.2.15-2+b2", Type: TypeDeb},
+				{Name: "libssl3", Version: "3.0.11-1~deb12u2", Source: "openssl", Type: TypeDeb},
+			},
+		}
+		r := Match(inv, db)
+		Expect(r.Packages).To(Equal(2))
+		Expect(r.Summary).To(Equal(Summary{Critical: 1, Low: 1, Total: 2}))
+		Expect(r.Findings).To(Equal([]Finding{
+			{ID: "DSA-0001", Aliases: []string{"CVE-2024-0001"}, Package: "libssl3", Version: "3.0.11-1~deb12u2",
+				FixedVersion: "3.0.11-1~deb12u3", Severity: SeverityCritical, Summary: "openssl bug"},
+			{ID: "DSA-0004", Package: "bash", Version: "5.2.15-2+b2", Severity: SeverityLow},
+		}))
+	})
+
+	It("finds nothing for a distribution the mirror does not cover", func() {
+		inv := &Inventory{
+			Distro:   Distro{ID: "alpine", VersionID: "3.20.3"},
+			Packages: []Package{{Name: "bash", Version: "5.2.26-r0", Type: TypeAPK}},
+		}
+		r := Match(inv, db)
+		Expect(r.Findings).To(BeEmpty())
+		Expect(r.Summary.Total).To(BeZero())
+	})
+})
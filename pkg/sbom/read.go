@@ -0,0 +1,72 @@
+package sbom
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Read parses an SPDX or CycloneDX document written by Write back into the
+// inventory it was rendered from. The purl of each package carries its type,
+// architecture and distribution; packages without one keep only their name
+// and version.
+func Read(r io.Reader) (*Inventory, error) {
+	var doc struct {
+		SPDXVersion string         `json:"spdxVersion"`
+		Packages    []spdxPackage  `json:"packages"`
+		BOMFormat   string         `json:"bomFormat"`
+		Components  []cdxComponent `json:"components"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	inv := &Inventory{Packages: []Package{}}
+	switch {
+	case doc.SPDXVersion != "":
+		for _, p := range doc.Packages {
+			pkg := Package{Name: p.Name, Version: p.VersionInfo}
+			if p.LicenseDeclared != "NOASSERTION" {
+				pkg.License = p.LicenseDeclared
+			}
+			for _, ref := range p.ExternalRefs {
+				if ref.ReferenceType == "purl" {
+					parsePurl(ref.ReferenceLocator, &pkg, &inv.Distro)
+				}
+			}
+			inv.Packages = append(inv.Packages, pkg)
+		}
+	case doc.BOMFormat == "CycloneDX":
+		for _, c := range doc.Components {
+			pkg := Package{Name: c.Name, Version: c.Version}
+			if len(c.Licenses) > 0 {
+				pkg.License = c.Licenses[0].Expression
+			}
+			parsePurl(c.PURL, &pkg, &inv.Distro)
+			inv.Packages = append(inv.Packages, pkg)
+		}
+	default:
+		return nil, errors.New("not an SPDX or CycloneDX document")
+	}
+	return inv, nil
+}
+
+// parsePurl fills p's type and architecture, and d when still empty, from a
+// purl as built by purl.
+func parsePurl(s string, p *Package, d *Distro) {
+	rest, ok := strings.CutPrefix(s, "pkg:")
+	if !ok {
+		return
+	}
+	rest, query, _ := strings.Cut(rest, "?")
+	typ, rest, _ := strings.Cut(rest, "/")
+	ns, _, _ := strings.Cut(rest, "/")
+	p.Type = typ
+	q, _ := url.ParseQuery(query)
+	p.Arch = q.Get("arch")
+	if d.ID == "" && ns != typ {
+		d.ID = ns
+		d.VersionID = strings.TrimPrefix(q.Get("distro"), ns+"-")
+	}
+}
@@ -0,0 +1,245 @@
+package sbom
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	// rpm 4.16+ keeps its database in SQLite; the driver is already linked
+	// in for the web UI's store.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// rpmDatabases are the rpm database locations read, in order. Fedora and
+// openSUSE moved the database under /usr/lib/sysimage/rpm (upgraded systems
+// keep /var/lib/rpm as a symlink to it), and openSUSE stores it there in the
+// ndb format rather than SQLite. The first one present wins.
+var rpmDatabases = []struct {
+	path string
+	read func(path string) ([]Package, error)
+}{
+	{"usr/lib/sysimage/rpm/rpmdb.sqlite", readRPMSQLite},
+	{"usr/lib/sysimage/rpm/Packages.db", readRPMNDB},
+	{"var/lib/rpm/rpmdb.sqlite", readRPMSQLite},
+}
+
+// rpmDBPath returns the first of rpmDatabases present under rootfs, or the
+// last candidate when there is none so callers stat a missing path.
+func rpmDBPath(rootfs string) string {
+	for _, db := range rpmDatabases {
+		if _, err := os.Stat(filepath.Join(rootfs, db.path)); err == nil {
+			return db.path
+		}
+	}
+	return rpmDatabases[len(rpmDatabases)-1].path
+}
+
+// rpm header tags and types used below (rpm's lib/rpmtag.h).
+const (
+	rpmTagName      = 1000
+	rpmTagVersion   = 1001
+	rpmTagRelease   = 1002
+	rpmTagEpoch     = 1003
+	rpmTagLicense   = 1014
+	rpmTagArch      = 1022
+	rpmTagSourceRPM = 1044
+
+	rpmTypeInt32      = 4
+	rpmTypeString     = 6
+	rpmTypeI18NString = 9
+)
+
+// readRPM reads the rpm database under rootfs. Each record, whether a row of
+// the SQLite Packages table or an ndb blob, is an rpm header parsed directly
+// so rpm itself is not needed on the host. The legacy Berkeley DB backend is
+// not supported; no distribution Kairos builds on still uses it.
+func readRPM(rootfs string) ([]Package, error) {
+	rel := rpmDBPath(rootfs)
+	for _, db := range rpmDatabases {
+		if db.path == rel {
+			return db.read(filepath.Join(rootfs, rel))
+		}
+	}
+	return nil, fmt.Errorf("unknown rpm database %s", rel)
+}
+
+func readRPMSQLite(path string) ([]Package, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro&immutable=1")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT blob FROM Packages")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blobs [][]byte
+	for rows.Next() {
+		var blob []byte
+		if err := rows.Scan(&blob); err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, blob)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return parseRPMHeaders(blobs)
+}
+
+// ndb layout constants (rpm's lib/backend/ndb/rpmpkg.c). The file starts
+// with a 32-byte header followed by slot pages; each in-use slot points at a
+// blob, in 16-byte blocks, holding one rpm header. Everything is
+// little-endian.
+const (
+	ndbHeaderMagic = 'R' | 'p'<<8 | 'm'<<16 | 'P'<<24
+	ndbSlotMagic   = 'S' | 'l'<<8 | 'o'<<16 | 't'<<24
+	ndbBlobMagic   = 'B' | 'l'<<8 | 'b'<<16 | 'S'<<24
+	ndbPageSize    = 4096
+	ndbBlockSize   = 16
+	ndbMaxSlotPage = 2048
+)
+
+// readRPMNDB reads an ndb Packages.db.
+func readRPMNDB(path string) ([]Package, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	le := binary.LittleEndian
+	if len(data) < 32 || le.Uint32(data[0:4]) != ndbHeaderMagic || le.Uint32(data[4:8]) != 0 {
+		return nil, fmt.Errorf("not an rpm ndb database")
+	}
+	slotPages := int(le.Uint32(data[12:16]))
+	if slotPages == 0 || slotPages > ndbMaxSlotPage || slotPages*ndbPageSize > len(data) {
+		return nil, fmt.Errorf("rpm ndb slot pages out of range")
+	}
+
+	var blobs [][]byte
+	// The header takes the room of the first two slots.
+	for off := 32; off < slotPages*ndbPageSize; off += 16 {
+		slot := data[off : off+16]
+		if le.Uint32(slot[0:4]) != ndbSlotMagic {
+			return nil, fmt.Errorf("rpm ndb slot at %d is corrupt", off)
+		}
+		idx := le.Uint32(slot[4:8])
+		if idx == 0 {
+			continue
+		}
+		start := int(le.Uint32(slot[8:12])) * ndbBlockSize
+		if start < 0 || start+16 > len(data) {
+			return nil, fmt.Errorf("rpm ndb blob for package %d out of range", idx)
+		}
+		head := data[start : start+16]
+		n := int(le.Uint32(head[12:16]))
+		if le.Uint32(head[0:4]) != ndbBlobMagic || le.Uint32(head[4:8]) != idx || start+16+n > len(data) {
+			return nil, fmt.Errorf("rpm ndb blob for package %d is corrupt", idx)
+		}
+		blobs = append(blobs, data[start+16:start+16+n])
+	}
+	return parseRPMHeaders(blobs)
+}
+
+// parseRPMHeaders decodes every header blob, dropping gpg-pubkey pseudo
+// packages: they are imported signing keys, not software.
+func parseRPMHeaders(blobs [][]byte) ([]Package, error) {
+	var pkgs []Package
+	for _, blob := range blobs {
+		p, err := parseRPMHeader(blob)
+		if err != nil {
+			return nil, err
+		}
+		if p.Name == "" || p.Name == "gpg-pubkey" {
+			continue
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// parseRPMHeader decodes the fields Package needs from an rpmdb header blob:
+// a big-endian index count and data length, the index entries (tag, type,
+// offset, count) and then the data store they point into.
+func parseRPMHeader(blob []byte) (Package, error) {
+	if len(blob) < 8 {
+		return Package{}, fmt.Errorf("rpm header too short")
+	}
+	il := int(binary.BigEndian.Uint32(blob[0:4]))
+	dl := int(binary.BigEndian.Uint32(blob[4:8]))
+	start := 8 + il*16
+	if il < 0 || dl < 0 || start+dl > len(blob) {
+		return Package{}, fmt.Errorf("rpm header index out of range")
+	}
+	data := blob[start : start+dl]
+
+	var (
+		p       Package
+		epoch   string
+		release string
+	)
+	for i := 0; i < il; i++ {
+		e := blob[8+i*16 : 8+(i+1)*16]
+		tag := binary.BigEndian.Uint32(e[0:4])
+		typ := binary.BigEndian.Uint32(e[4:8])
+		off := int(binary.BigEndian.Uint32(e[8:12]))
+		if off < 0 || off >= len(data) {
+			continue
+		}
+		switch typ {
+		case rpmTypeString, rpmTypeI18NString:
+			end := off
+			for end < len(data) && data[end] != 0 {
+				end++
+			}
+			s := string(data[off:end])
+			switch tag {
+			case rpmTagName:
+				p.Name = s
+			case rpmTagVersion:
+				p.Version = s
+			case rpmTagRelease:
+				release = s
+			case rpmTagLicense:
+				p.License = s
+			case rpmTagArch:
+				p.Arch = s
+			case rpmTagSourceRPM:
+				p.Source = sourceRPMName(s)
+			}
+		case rpmTypeInt32:
+			if tag == rpmTagEpoch && off+4 <= len(data) {
+				epoch = strconv.FormatUint(uint64(binary.BigEndian.Uint32(data[off:off+4])), 10)
+			}
+		}
+	}
+	if release != "" {
+		p.Version += "-" + release
+	}
+	if epoch != "" && epoch != "0" {
+		p.Version = epoch + ":" + p.Version
+	}
+	if p.Source == p.Name {
+		p.Source = ""
+	}
+	p.Type = TypeRPM
+	return p, nil
+}
+
+// sourceRPMName strips "-version-release.src.rpm" from a SOURCERPM value.
+func sourceRPMName(s string) string {
+	s = strings.TrimSuffix(s, ".src.rpm")
+	s = strings.TrimSuffix(s, ".nosrc.rpm")
+	for i := 0; i < 2; i++ {
+		if j := strings.LastIndex(s, "-"); j > 0 {
+			s = s[:j]
+		}
+	}
+	return s
+}
@@ -0,0 +1,192 @@
+// Package sbom inventories the packages installed in an unpacked OS rootfs
+// and renders that inventory as an SPDX or CycloneDX document. The inventory
+// is read straight from the package manager databases (dpkg, rpm, apk), so no
+// package manager has to run against the rootfs and the result does not
+// depend on the host distribution.
+//
+// Match compares an inventory against a local mirror of OSV advisories for an
+// offline vulnerability report; nothing in this package touches the network.
+package sbom
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Supported document formats.
+const (
+	FormatSPDX      = "spdx-json"
+	FormatCycloneDX = "cyclonedx-json"
+)
+
+// ReportFileName is the vulnerability report Match results are written to
+// next to the SBOM.
+const ReportFileName = "vulnerabilities.json"
+
+// Package types, matching the purl types they are published under.
+const (
+	TypeDeb = "deb"
+	TypeRPM = "rpm"
+	TypeAPK = "apk"
+)
+
+// ErrNoPackageDB is returned by Scan when the rootfs carries none of the
+// package databases this package understands.
+var ErrNoPackageDB = errors.New("no dpkg, rpm or apk package database found")
+
+// Package is one installed package.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch,omitempty"`
+	// Source is the source package (dpkg Source, apk origin, rpm SOURCERPM
+	// name) when it differs from Name. Distribution advisories are keyed by
+	// it, so Match prefers it over Name.
+	Source  string `json:"source,omitempty"`
+	License string `json:"license,omitempty"`
+	Type    string `json:"type"`
+}
+
+// Distro identifies the distribution from /etc/os-release.
+type Distro struct {
+	ID        string `json:"id"`
+	VersionID string `json:"versionId,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// Inventory is everything Scan found in a rootfs.
+type Inventory struct {
+	Distro   Distro    `json:"distro"`
+	Packages []Package `json:"packages"`
+}
+
+// Scan reads the package databases under rootfs. A rootfs can legitimately
+// carry more than one (an rpm-based image with a stray dpkg status file), so
+// every database found is read and the results concatenated.
+func Scan(rootfs string) (*Inventory, error) {
+	inv := &Inventory{Distro: readOSRelease(rootfs)}
+	found := false
+	for _, r := range []struct {
+		path string
+		read func(rootfs string) ([]Package, error)
+	}{
+		{dpkgStatusPath, readDpkg},
+		{apkInstalledPath, readAPK},
+		{rpmDBPath(rootfs), readRPM},
+	} {
+		if _, err := os.Stat(filepath.Join(rootfs, r.path)); err != nil {
+			continue
+		}
+		found = true
+		pkgs, err := r.read(rootfs)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", r.path, err)
+		}
+		inv.Packages = append(inv.Packages, pkgs...)
+	}
+	if !found {
+		return nil, ErrNoPackageDB
+	}
+	sort.Slice(inv.Packages, func(i, j int) bool {
+		if inv.Packages[i].Name != inv.Packages[j].Name {
+			return inv.Packages[i].Name < inv.Packages[j].Name
+		}
+		return inv.Packages[i].Arch < inv.Packages[j].Arch
+	})
+	return inv, nil
+}
+
+// Write renders inv as a document in format. name identifies the document
+// (the artifact name or ID).
+func Write(w io.Writer, format, name string, inv *Inventory) error {
+	var doc any
+	switch format {
+	case FormatSPDX:
+		doc = spdxDocument(name, inv)
+	case FormatCycloneDX:
+		doc = cycloneDXDocument(name, inv)
+	default:
+		return fmt.Errorf("unsupported SBOM format %q", format)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// FileName is the conventional file name for a document in format.
+func FileName(format string) string {
+	if format == FormatCycloneDX {
+		return "sbom.cdx.json"
+	}
+	return "sbom.spdx.json"
+}
+
+// ValidFormat reports whether format is one Write can produce.
+func ValidFormat(format string) bool {
+	return format == FormatSPDX || format == FormatCycloneDX
+}
+
+// readOSRelease parses /etc/os-release (falling back to
+// /usr/lib/os-release). A missing file yields an empty Distro rather than an
+// error: the inventory is still useful, only purl qualifiers and advisory
+// matching lose precision.
+func readOSRelease(rootfs string) Distro {
+	var d Distro
+	for _, p := range []string{"etc/os-release", "usr/lib/os-release"} {
+		f, err := os.Open(filepath.Join(rootfs, p))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			k, v, ok := strings.Cut(sc.Text(), "=")
+			if !ok {
+				continue
+			}
+			v = strings.Trim(v, `"'`)
+			switch k {
+			case "ID":
+				d.ID = v
+			case "VERSION_ID":
+				d.VersionID = v
+			case "PRETTY_NAME":
+				d.Name = v
+			}
+		}
+		f.Close()
+		break
+	}
+	return d
+}
+
+// purl is the package URL of p, e.g. pkg:deb/debian/openssl@3.0.11-1?arch=amd64.
+func purl(p Package, d Distro) string {
+	ns := d.ID
+	if ns == "" {
+		ns = p.Type
+	}
+	s := fmt.Sprintf("pkg:%s/%s/%s@%s", p.Type, ns, purlEscape(p.Name), purlEscape(p.Version))
+	var q []string
+	if p.Arch != "" {
+		q = append(q, "arch="+purlEscape(p.Arch))
+	}
+	if d.ID != "" && d.VersionID != "" {
+		q = append(q, "distro="+purlEscape(d.ID+"-"+d.VersionID))
+	}
+	if len(q) > 0 {
+		s += "?" + strings.Join(q, "&")
+	}
+	return s
+}
+
+func purlEscape(s string) string {
+	r := strings.NewReplacer("%", "%25", "@", "%40", "?", "%3F", "#", "%23", "+", "%2B", " ", "%20")
+	return r.Replace(s)
+}
@@ -0,0 +1,222 @@
+package sbom
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func writeRootfsFile(rootfs, path, content string) {
+	full := filepath.Join(rootfs, path)
+	Expect(os.MkdirAll(filepath.Dir(full), 0o755)).To(Succeed())
+	Expect(os.WriteFile(full, []byte(content), 0o644)).To(Succeed())
+}
+
+// rpmHeader builds an rpmdb header blob carrying the given string tags and
+// an optional epoch, laid out the way rpm stores it in rpmdb.sqlite.
+func rpmHeader(strs map[uint32]string, epoch uint32) []byte {
+	var index, data bytes.Buffer
+	entry := func(tag, typ uint32, off int) {
+		_ = binary.Write(&index, binary.BigEndian, [4]uint32{tag, typ, uint32(off), 1})
+	}
+	for tag, s := range strs {
+		entry(tag, rpmTypeString, data.Len())
+		data.WriteString(s)
+		data.WriteByte(0)
+	}
+	if epoch != 0 {
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+		entry(rpmTagEpoch, rpmTypeInt32, data.Len())
+		_ = binary.Write(&data, binary.BigEndian, epoch)
+	}
+	var out bytes.Buffer
+	_ = binary.Write(&out, binary.BigEndian, uint32(index.Len()/16))
+	_ = binary.Write(&out, binary.BigEndian, uint32(data.Len()))
+	out.Write(index.Bytes())
+	out.Write(data.Bytes())
+	return out.Bytes()
+}
+
+var _ = Describe("Scan", func() {
+	var rootfs string
+
+	BeforeEach(func() {
+		rootfs = GinkgoT().TempDir()
+	})
+
+	It("reads installed dpkg packages and skips removed ones", func() {
+		writeRootfsFile(rootfs, "etc/os-release", "ID=debian\nVERSION_ID=\"12\"\nPRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\n")
+		writeRootfsFile(rootfs, dpkgStatusPath, `Package: libssl3
+Status: install ok installed
+Architecture: amd64
+Source: openssl (3.0.11-1~deb12u2)
+Version: 3.0.11-1~deb12u2
+Description: Secure Sockets Layer toolkit
+ This package is part of the OpenSSL project.
+
+Package: bash
+Status: install ok installed
+Architecture: amd64
+Version: 5.2.15-2+b2
+
+Package: oldpkg
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0
+`)
+		inv, err := Scan(rootfs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Distro).To(Equal(Distro{ID: "debian", VersionID: "12", Name: "Debian GNU/Linux 12 (bookworm)"}))
+		Expect(inv.Packages).To(Equal([]Package{
+			{Name: "bash", Version: "5.2.15-2+b2", Arch: "amd64", Type: TypeDeb},
+			{Name: "libssl3", Version: "3.0.11-1~deb12u2", Arch: "amd64", Source: "openssl", Type: TypeDeb},
+		}))
+	})
+
+	It("reads the apk installed database", func() {
+		writeRootfsFile(rootfs, "etc/os-release", "ID=alpine\nVERSION_ID=3.20.3\n")
+		writeRootfsFile(rootfs, apkInstalledPath, `C:Q1abc=
+P:libcrypto3
+V:3.3.2-r0
+A:x86_64
+L:Apache-2.0
+o:openssl
+
+P:musl
+V:1.2.5-r0
+A:x86_64
+L:MIT
+o:musl
+`)
+		inv, err := Scan(rootfs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Packages).To(Equal([]Package{
+			{Name: "libcrypto3", Version: "3.3.2-r0", Arch: "x86_64", Source: "openssl", License: "Apache-2.0", Type: TypeAPK},
+			{Name: "musl", Version: "1.2.5-r0", Arch: "x86_64", License: "MIT", Type: TypeAPK},
+		}))
+	})
+
+	rpmHeaders := func() [][]byte {
+		return [][]byte{
+			rpmHeader(map[uint32]string{
+				rpmTagName: "openssl-libs", rpmTagVersion: "3.0.7", rpmTagRelease: "27.el9",
+				rpmTagArch: "x86_64", rpmTagLicense: "ASL 2.0", rpmTagSourceRPM: "openssl-3.0.7-27.el9.src.rpm",
+			}, 1),
+			rpmHeader(map[uint32]string{rpmTagName: "gpg-pubkey", rpmTagVersion: "350d275d", rpmTagRelease: "6279464b"}, 0),
+		}
+	}
+	wantRPM := []Package{
+		{Name: "openssl-libs", Version: "1:3.0.7-27.el9", Arch: "x86_64", Source: "openssl", License: "ASL 2.0", Type: TypeRPM},
+	}
+
+	DescribeTable("reads rpm headers out of rpmdb.sqlite", func(rel string) {
+		path := filepath.Join(rootfs, rel)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		db, err := sql.Open("sqlite3", path)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = db.Exec("CREATE TABLE Packages (hnum INTEGER PRIMARY KEY AUTOINCREMENT, blob BLOB NOT NULL)")
+		Expect(err).NotTo(HaveOccurred())
+		for _, h := range rpmHeaders() {
+			_, err = db.Exec("INSERT INTO Packages (blob) VALUES (?)", h)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(db.Close()).To(Succeed())
+
+		inv, err := Scan(rootfs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Packages).To(Equal(wantRPM))
+	},
+		Entry("in /var/lib/rpm", "var/lib/rpm/rpmdb.sqlite"),
+		Entry("in /usr/lib/sysimage/rpm", "usr/lib/sysimage/rpm/rpmdb.sqlite"),
+	)
+
+	It("reads rpm headers out of an ndb Packages.db", func() {
+		le := binary.LittleEndian
+		page := make([]byte, ndbPageSize)
+		le.PutUint32(page[0:4], ndbHeaderMagic)
+		le.PutUint32(page[12:16], 1)
+		var blobs bytes.Buffer
+		for off := 32; off < ndbPageSize; off += 16 {
+			le.PutUint32(page[off:off+4], ndbSlotMagic)
+		}
+		for i, h := range rpmHeaders() {
+			slot := page[32+i*16 : 48+i*16]
+			le.PutUint32(slot[4:8], uint32(i+1))
+			le.PutUint32(slot[8:12], uint32((ndbPageSize+blobs.Len())/ndbBlockSize))
+			_ = binary.Write(&blobs, le, [4]uint32{ndbBlobMagic, uint32(i + 1), 0, uint32(len(h))})
+			blobs.Write(h)
+			for blobs.Len()%ndbBlockSize != 0 {
+				blobs.WriteByte(0)
+			}
+		}
+		writeRootfsFile(rootfs, "usr/lib/sysimage/rpm/Packages.db", string(page)+blobs.String())
+
+		inv, err := Scan(rootfs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Packages).To(Equal(wantRPM))
+	})
+
+	It("fails on a rootfs without a package database", func() {
+		_, err := Scan(rootfs)
+		Expect(err).To(MatchError(ErrNoPackageDB))
+	})
+})
+
+var _ = Describe("Write", func() {
+	inv := &Inventory{
+		Distro: Distro{ID: "debian", VersionID: "12"},
+		Packages: []Package{
+			{Name: "libssl3", Version: "3.0.11-1~deb12u2", Arch: "amd64", Source: "openssl", Type: TypeDeb},
+		},
+	}
+
+	It("renders SPDX with a purl per package", func() {
+		var buf bytes.Buffer
+		Expect(Write(&buf, FormatSPDX, "my-image", inv)).To(Succeed())
+		var doc spdxDoc
+		Expect(json.Unmarshal(buf.Bytes(), &doc)).To(Succeed())
+		Expect(doc.SPDXVersion).To(Equal("SPDX-2.3"))
+		Expect(doc.Packages).To(HaveLen(1))
+		Expect(doc.Packages[0].ExternalRefs[0].ReferenceLocator).
+			To(Equal("pkg:deb/debian/libssl3@3.0.11-1~deb12u2?arch=amd64&distro=debian-12"))
+		Expect(doc.Relationships).To(HaveLen(1))
+	})
+
+	It("renders CycloneDX", func() {
+		var buf bytes.Buffer
+		Expect(Write(&buf, FormatCycloneDX, "my-image", inv)).To(Succeed())
+		var doc cdxDoc
+		Expect(json.Unmarshal(buf.Bytes(), &doc)).To(Succeed())
+		Expect(doc.BOMFormat).To(Equal("CycloneDX"))
+		Expect(doc.Metadata.Component.Type).To(Equal("operating-system"))
+		Expect(doc.Components).To(HaveLen(1))
+		Expect(doc.Components[0].PURL).To(HavePrefix("pkg:deb/debian/libssl3@"))
+	})
+
+	It("rejects an unknown format", func() {
+		Expect(Write(&bytes.Buffer{}, "syft-json", "x", inv)).To(MatchError(ContainSubstring("unsupported")))
+	})
+
+	It("reads both formats back", func() {
+		for _, format := range []string{FormatSPDX, FormatCycloneDX} {
+			var buf bytes.Buffer
+			Expect(Write(&buf, format, "my-image", inv)).To(Succeed())
+			got, err := Read(&buf)
+			Expect(err).NotTo(HaveOccurred(), format)
+			Expect(got.Distro).To(Equal(Distro{ID: "debian", VersionID: "12"}), format)
+			Expect(got.Packages).To(Equal([]Package{
+				{Name: "libssl3", Version: "3.0.11-1~deb12u2", Arch: "amd64", Type: TypeDeb},
+			}), format)
+		}
+		_, err := Read(bytes.NewBufferString(`{"foo": 1}`))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,96 @@
+package sbom
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The SPDX 2.3 JSON subset AuroraBoot emits: one package per installed
+// package, each described by the document and identified by its purl.
+
+type spdxDoc struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo"`
+	DownloadLocation string            `json:"downloadLocation"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxIDUnsafe matches what SPDX does not allow in an SPDXRef identifier.
+var spdxIDUnsafe = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+func spdxDocument(name string, inv *Inventory) spdxDoc {
+	doc := spdxDoc{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: "https://kairos.io/spdx/" + spdxIDUnsafe.ReplaceAllString(name, "-") + "-" + uuid.NewString(),
+		CreationInfo: spdxCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: auroraboot"},
+		},
+		Packages:      make([]spdxPackage, 0, len(inv.Packages)),
+		Relationships: make([]spdxRelationship, 0, len(inv.Packages)),
+	}
+	for i, p := range inv.Packages {
+		id := fmt.Sprintf("SPDXRef-Package-%s-%s-%d", p.Type, spdxIDUnsafe.ReplaceAllString(p.Name, "-"), i)
+		license := "NOASSERTION"
+		if p.License != "" {
+			license = p.License
+		}
+		doc.Packages = append(doc.Packages, spdxPackage{
+			Name:             p.Name,
+			SPDXID:           id,
+			VersionInfo:      p.Version,
+			DownloadLocation: "NOASSERTION",
+			// Distribution license strings ("GPL-2.0-or-later AND MIT",
+			// "GPLv2+") are not always valid SPDX expressions; they are
+			// passed through as declared rather than guessed at.
+			LicenseDeclared: license,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl(p, inv.Distro),
+			}},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: id,
+		})
+	}
+	return doc
+}
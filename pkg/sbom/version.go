@@ -0,0 +1,277 @@
+package sbom
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareVersions orders two versions of a package of type typ, returning
+// -1, 0 or 1. Each package manager has its own ordering rules, and advisory
+// ranges are expressed in the distribution's versions, so comparing them any
+// other way misreports fixed packages as vulnerable (or the reverse).
+func compareVersions(typ, a, b string) int {
+	switch typ {
+	case TypeDeb:
+		return compareDeb(a, b)
+	case TypeAPK:
+		return compareAPK(a, b)
+	default:
+		return compareRPM(a, b)
+	}
+}
+
+// splitEpoch separates "epoch:rest". A missing epoch is 0.
+func splitEpoch(v string) (int, string) {
+	if e, rest, ok := strings.Cut(v, ":"); ok {
+		if n, err := strconv.Atoi(e); err == nil {
+			return n, rest
+		}
+	}
+	return 0, v
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareDeb implements dpkg's version ordering (dpkg --compare-versions):
+// epoch, then upstream version, then Debian revision, each compared with
+// verrevcmp.
+func compareDeb(a, b string) int {
+	ea, ra := splitEpoch(a)
+	eb, rb := splitEpoch(b)
+	if c := cmpInt(ea, eb); c != 0 {
+		return c
+	}
+	ua, da := ra, ""
+	if i := strings.LastIndex(ra, "-"); i >= 0 {
+		ua, da = ra[:i], ra[i+1:]
+	}
+	ub, db := rb, ""
+	if i := strings.LastIndex(rb, "-"); i >= 0 {
+		ub, db = rb[:i], rb[i+1:]
+	}
+	if c := verrevcmp(ua, ub); c != 0 {
+		return c
+	}
+	return verrevcmp(da, db)
+}
+
+// debOrder is the weight dpkg gives a non-digit character: "~" sorts before
+// everything including the end of the string, letters before other symbols.
+func debOrder(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return 0
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	case c == '~':
+		return -1
+	case c != 0:
+		return int(c) + 256
+	}
+	return 0
+}
+
+func verrevcmp(a, b string) int {
+	i, j := 0, 0
+	at := func(s string, k int) byte {
+		if k < len(s) {
+			return s[k]
+		}
+		return 0
+	}
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	for i < len(a) || j < len(b) {
+		firstDiff := 0
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := debOrder(at(a, i)), debOrder(at(b, j))
+			if ac != bc {
+				return cmpInt(ac, bc)
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = cmpInt(int(a[i]), int(b[j]))
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
+
+// compareRPM implements rpm's EVR ordering: epoch, then rpmvercmp on version
+// and release.
+func compareRPM(a, b string) int {
+	ea, ra := splitEpoch(a)
+	eb, rb := splitEpoch(b)
+	if c := cmpInt(ea, eb); c != 0 {
+		return c
+	}
+	va, rela, _ := strings.Cut(ra, "-")
+	vb, relb, _ := strings.Cut(rb, "-")
+	if c := rpmvercmp(va, vb); c != 0 {
+		return c
+	}
+	if rela == "" || relb == "" {
+		// A range bound without a release matches every release.
+		return 0
+	}
+	return rpmvercmp(rela, relb)
+}
+
+// rpmvercmp compares alternating runs of digits and letters; separators only
+// delimit runs. "~" sorts before anything, "^" after the end of the string
+// but before any further run.
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	isAlnum := func(c byte) bool {
+		return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	}
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	for len(a) > 0 || len(b) > 0 {
+		for len(a) > 0 && !isAlnum(a[0]) && a[0] != '~' && a[0] != '^' {
+			a = a[1:]
+		}
+		for len(b) > 0 && !isAlnum(b[0]) && b[0] != '~' && b[0] != '^' {
+			b = b[1:]
+		}
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if len(a) == 0 {
+				return -1
+			}
+			if len(b) == 0 {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if len(a) == 0 || len(b) == 0 {
+			break
+		}
+		numeric := isDigit(a[0])
+		run := func(s string) (string, string) {
+			k := 0
+			for k < len(s) && isAlnum(s[k]) && isDigit(s[k]) == numeric {
+				k++
+			}
+			return s[:k], s[k:]
+		}
+		var sa, sb string
+		sa, a = run(a)
+		sb, b = run(b)
+		if sb == "" {
+			// Numeric runs are newer than alphabetic ones.
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		if numeric {
+			sa = strings.TrimLeft(sa, "0")
+			sb = strings.TrimLeft(sb, "0")
+			if c := cmpInt(len(sa), len(sb)); c != 0 {
+				return c
+			}
+		}
+		if c := strings.Compare(sa, sb); c != 0 {
+			return c
+		}
+	}
+	return cmpInt(len(a), len(b))
+}
+
+// apkSuffixRank orders apk's version suffixes: pre-releases sort before the
+// bare version, post-release markers after it.
+var apkSuffixRank = map[string]int{
+	"alpha": -4, "beta": -3, "pre": -2, "rc": -1,
+	"":    0,
+	"cvs": 1, "svn": 2, "git": 3, "hg": 4, "p": 5,
+}
+
+// compareAPK orders apk versions: dotted numeric components, an optional
+// trailing letter, "_suffixN" components, then the "-rN" package revision.
+func compareAPK(a, b string) int {
+	va, reva := splitAPKRevision(a)
+	vb, revb := splitAPKRevision(b)
+	pa := strings.Split(va, "_")
+	pb := strings.Split(vb, "_")
+	if c := rpmvercmp(pa[0], pb[0]); c != 0 {
+		return c
+	}
+	for k := 1; k < len(pa) || k < len(pb); k++ {
+		sa, na := apkSuffix(pa, k)
+		sb, nb := apkSuffix(pb, k)
+		if c := cmpInt(apkSuffixRank[sa], apkSuffixRank[sb]); c != 0 {
+			return c
+		}
+		if c := cmpInt(na, nb); c != 0 {
+			return c
+		}
+	}
+	return cmpInt(reva, revb)
+}
+
+func splitAPKRevision(v string) (string, int) {
+	if i := strings.LastIndex(v, "-r"); i >= 0 {
+		if n, err := strconv.Atoi(v[i+2:]); err == nil {
+			return v[:i], n
+		}
+	}
+	return v, 0
+}
+
+func apkSuffix(parts []string, k int) (string, int) {
+	if k >= len(parts) {
+		return "", 0
+	}
+	s := parts[k]
+	i := len(s)
+	for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+		i--
+	}
+	n, _ := strconv.Atoi(s[i:])
+	return s[:i], n
+}
@@ -0,0 +1,50 @@
+package sbom
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("compareVersions", func() {
+	DescribeTable("orders versions like the package manager does",
+		func(typ, a, b string, want int) {
+			Expect(compareVersions(typ, a, b)).To(Equal(want))
+			Expect(compareVersions(typ, b, a)).To(Equal(-want))
+		},
+		Entry("deb equal", TypeDeb, "1.2.3-1", "1.2.3-1", 0),
+		Entry("deb numeric", TypeDeb, "1.10", "1.9", 1),
+		Entry("deb tilde sorts first", TypeDeb, "3.0.11-1~deb12u1", "3.0.11-1", -1),
+		Entry("deb epoch wins", TypeDeb, "1:1.0", "2.0", 1),
+		Entry("deb revision", TypeDeb, "3.0.11-1~deb12u2", "3.0.11-1~deb12u1", 1),
+		Entry("deb letters before symbols", TypeDeb, "1.0a", "1.0+", -1),
+		Entry("rpm release", TypeRPM, "3.0.7-27.el9", "3.0.7-24.el9", 1),
+		Entry("rpm epoch", TypeRPM, "1:3.0.7-1", "3.0.8-1", 1),
+		Entry("rpm numbers beat letters", TypeRPM, "1.0.1", "1.0.a", 1),
+		Entry("rpm tilde", TypeRPM, "1.0~rc1", "1.0", -1),
+		Entry("rpm caret", TypeRPM, "1.0^git1", "1.0", 1),
+		Entry("rpm leading zeros", TypeRPM, "1.01", "1.1", 0),
+		Entry("apk revision", TypeAPK, "3.3.2-r1", "3.3.2-r0", 1),
+		Entry("apk pre-release", TypeAPK, "1.2.5_rc1-r0", "1.2.5-r0", -1),
+		Entry("apk patch", TypeAPK, "1.2.5_p1-r0", "1.2.5-r0", 1),
+		Entry("apk numeric", TypeAPK, "1.10.0-r0", "1.9.9-r5", 1),
+	)
+})
+
+var _ = Describe("cvss3BaseScore", func() {
+	DescribeTable("computes the base score",
+		func(vector string, want float64) {
+			got, err := cvss3BaseScore(vector)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(want))
+		},
+		Entry("critical", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8),
+		Entry("scope changed", "CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1),
+		Entry("local", "CVSS:3.0/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:N/A:N", 5.5),
+		Entry("no impact", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0.0),
+	)
+
+	It("rejects an incomplete vector", func() {
+		_, err := cvss3BaseScore("CVSS:3.1/AV:N")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -3,7 +3,9 @@ package schema
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
+	"github.com/kairos-io/AuroraBoot/pkg/sbom"
 	"github.com/kairos-io/kairos-sdk/types/logger"
 )
 
@@ -33,6 +35,11 @@ type Config struct {
 
 	State string `yaml:"state_dir"`
 
+	// Resume skips the steps a previous run with the same state_dir
+	// completed, as long as their inputs are unchanged and their outputs are
+	// still there.
+	Resume bool `yaml:"resume"`
+
 	ListenAddr string `yaml:"listen_addr"`
 
 	// Architecture to use for container image pulling (e.g., "amd64", "arm64")
@@ -59,6 +66,12 @@ type Config struct {
 	Disk Disk `yaml:"disk"`
 
 	System System `yaml:"system"`
+
+	// SBOM block configuration
+	SBOM SBOM `yaml:"sbom"`
+
+	// Compression block configuration
+	Compression Compression `yaml:"compression"`
 }
 
 type System struct {
@@ -77,12 +90,100 @@ type Disk struct {
 	// a separate image file (efi.img, oem.img, recovery_partition.img) instead
 	// of merging them into a single .raw disk. Intended for flashing workflows
 	// such as Nvidia Jetson AGX Orin. Implies an EFI build and is mutually
-	// exclusive with the gce/vhd cloud-image conversions.
-	Partitions        bool   `yaml:"partitions"`
-	MAAS              bool   `yaml:"maas"`
+	// exclusive with the gce/vhd/vhdx/qcow2/vmdk/ova cloud-image conversions.
+	Partitions bool `yaml:"partitions"`
+	MAAS       bool `yaml:"maas"`
+	// QCOW2 writes a qcow2 image of the EFI raw disk next to it, for KVM,
+	// Proxmox and OpenStack. QCOW2Compress deflates its clusters as
+	// qemu-img convert -c does: smaller, but slower to read.
+	QCOW2         bool `yaml:"qcow2"`
+	QCOW2Compress bool `yaml:"qcow2_compress"`
+	// VHDDynamic makes the vhd output a sparse, dynamic VHD instead of a
+	// fixed one; Azure only accepts fixed VHDs. VHDX writes a dynamic VHDX
+	// of the EFI raw disk for Hyper-V generation 2 virtual machines.
+	VHDDynamic bool `yaml:"vhd_dynamic"`
+	VHDX       bool `yaml:"vhdx"`
+	// VMDK writes a stream-optimized VMDK of the EFI raw disk for vSphere.
+	// OVA packs one with an OVF descriptor of the virtual machine described
+	// by OVF.
+	VMDK              bool   `yaml:"vmdk"`
+	OVA               bool   `yaml:"ova"`
+	OVF               OVF    `yaml:"ovf"`
 	Size              string `yaml:"size"`
 	StateSize         string `yaml:"state_size"`
 	RecoveryImageSize string `yaml:"recovery_image_size"`
+	// Layout lays out the partitions of the raw disks. See Layout.
+	Layout Layout `yaml:"layout"`
+}
+
+// OVF describes the virtual machine of the disk.ova output. It always boots
+// with EFI firmware.
+type OVF struct {
+	// CPUs and MemoryMB size the virtual machine; zero means 2 CPUs and
+	// 4096 MiB.
+	CPUs     int `yaml:"cpus"`
+	MemoryMB int `yaml:"memory_mb"`
+	// UserDataProperty adds a "user-data" vApp property, so a base64
+	// encoded cloud-config can be given when the appliance is deployed.
+	UserDataProperty bool `yaml:"user_data_property"`
+}
+
+// CPUsOrDefault returns the number of virtual CPUs of the virtual machine.
+func (o OVF) CPUsOrDefault() int {
+	if o.CPUs > 0 {
+		return o.CPUs
+	}
+	return 2
+}
+
+// MemoryMBOrDefault returns the memory of the virtual machine, in MiB.
+func (o OVF) MemoryMBOrDefault() int {
+	if o.MemoryMB > 0 {
+		return o.MemoryMB
+	}
+	return 4096
+}
+
+// SBOM configures the software bill of materials generated from the unpacked
+// container image. Leaving Format empty disables it.
+type SBOM struct {
+	// Format is the SBOM document format: "spdx-json" or "cyclonedx-json".
+	Format string `yaml:"format"`
+	// VulnDB is a directory holding a local mirror of OSV advisories. When set,
+	// the package inventory is matched against it and a vulnerability report
+	// is written next to the SBOM. Nothing is fetched over the network.
+	VulnDB string `yaml:"vuln_db"`
+}
+
+// Compression formats of the compression.format option.
+const (
+	CompressionZstd = "zstd"
+	CompressionXZ   = "xz"
+	CompressionGzip = "gzip"
+)
+
+// Compression compresses the raw disk, ISO and VHD outputs once every other
+// output has been built from them. Leaving Format empty disables it.
+type Compression struct {
+	// Format is "zstd", "xz" or "gzip". Each output gets a compressed copy
+	// with a .zst, .xz or .gz extension, listed in the output directory's
+	// SHA256SUMS if it has one. Raw disks and VHDs are replaced by it; the
+	// ISO is kept, since netboot and virtual media boot from it.
+	Format string `yaml:"format"`
+}
+
+// Extension returns the file extension of the compressed outputs, or an
+// empty string when compression is disabled or the format is unknown.
+func (c Compression) Extension() string {
+	switch c.Format {
+	case CompressionZstd:
+		return ".zst"
+	case CompressionXZ:
+		return ".xz"
+	case CompressionGzip:
+		return ".gz"
+	}
+	return ""
 }
 
 type NetBoot struct {
@@ -99,6 +200,85 @@ type ISO struct {
 	// ExtendLiveCmdline is appended to the kernel cmdline when booting from the live/installer ISO. Does not affect the installed system.
 	ExtendLiveCmdline string `yaml:"extend-live-cmdline"`
 	LiveConsole       string `yaml:"live_console"`
+	// Images are more source images to put on the ISO next to the container
+	// image, each with its own entries in the boot menu.
+	Images []ISOImage `yaml:"images"`
+}
+
+// ISOImage is an additional source image of a multi-image ISO.
+type ISOImage struct {
+	// Name titles the boot menu entries of the image. It defaults to the
+	// repository and tag of the image.
+	Name           string `yaml:"name"`
+	ContainerImage string `yaml:"container_image"`
+	// CloudConfig only applies when booting this image, on top of the cloud
+	// config of the ISO.
+	CloudConfig string `yaml:"cloud_config"`
+	// ExtendLiveCmdline is appended to the kernel cmdline of this image's
+	// entries, after iso.extend-live-cmdline.
+	ExtendLiveCmdline string `yaml:"extend-live-cmdline"`
+}
+
+// Title returns the boot menu title of the image.
+func (i ISOImage) Title() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	ref := i.ContainerImage
+	if at := strings.Index(ref, "@"); at >= 0 {
+		ref = ref[:at]
+	}
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// Dir returns the directory of the image on the ISO, derived from its title.
+func (i ISOImage) Dir() string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(i.Title()) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// reservedISODirs are the directories the boot files take on the ISO: /boot
+// and /EFI at its root, and the grub ones in /boot, next to which each image
+// gets its kernel and initrd.
+var reservedISODirs = map[string]bool{"boot": true, "efi": true, "grub": true, "grub2": true}
+
+// validateImages checks the images of a multi-image ISO can be told apart
+// both in the boot menu and on the ISO.
+func (i ISO) validateImages() error {
+	dirs := map[string]string{}
+	for n, img := range i.Images {
+		if img.ContainerImage == "" {
+			return fmt.Errorf("iso.images[%d]: container_image is required", n)
+		}
+		if strings.ContainsAny(img.Title(), "\"$\\\n\r") {
+			return fmt.Errorf("iso.images[%d]: name %q cannot contain quotes, backslashes, dollar signs or newlines", n, img.Title())
+		}
+		dir := img.Dir()
+		if dir == "" {
+			return fmt.Errorf("iso.images[%d]: name %q has no letters or digits to name its directory on the ISO", n, img.Title())
+		}
+		if reservedISODirs[dir] {
+			return fmt.Errorf("iso.images[%d]: name %q would be stored in %s/ on the ISO, which holds its boot files", n, img.Title(), dir)
+		}
+		if other, ok := dirs[dir]; ok {
+			return fmt.Errorf("iso.images[%d]: name %q is too close to %q: both are stored in %s/ on the ISO", n, img.Title(), other, dir)
+		}
+		dirs[dir] = img.Title()
+		if strings.ContainsAny(img.ExtendLiveCmdline, "\n\r") {
+			return fmt.Errorf("iso.images[%d]: extend-live-cmdline cannot contain newlines", n)
+		}
+	}
+	return nil
 }
 
 // HandleDeprecations checks for deprecated ISO options and migrates them.
@@ -149,8 +329,8 @@ func (c *Config) AllowInsecureRegistriesBool() bool {
 // step.
 func (c Config) Validate() error {
 	// Partition-image output skips the final merge into a single .raw disk, so
-	// the gce/vhd conversions (which operate on that merged disk) have nothing
-	// to convert. Reject the combination up front.
+	// the gce/vhd/vhdx/qcow2/vmdk/ova conversions (which operate on that merged
+	// disk) have nothing to convert. Reject the combination up front.
 	if c.Disk.Partitions {
 		if c.Disk.GCE {
 			return fmt.Errorf("disk.partitions cannot be combined with disk.gce: partition-image output does not produce a merged disk to convert")
@@ -158,6 +338,45 @@ func (c Config) Validate() error {
 		if c.Disk.VHD {
 			return fmt.Errorf("disk.partitions cannot be combined with disk.vhd: partition-image output does not produce a merged disk to convert")
 		}
+		if c.Disk.VHDX {
+			return fmt.Errorf("disk.partitions cannot be combined with disk.vhdx: partition-image output does not produce a merged disk to convert")
+		}
+		if c.Disk.QCOW2 {
+			return fmt.Errorf("disk.partitions cannot be combined with disk.qcow2: partition-image output does not produce a merged disk to convert")
+		}
+		if c.Disk.VMDK || c.Disk.OVA {
+			return fmt.Errorf("disk.partitions cannot be combined with disk.vmdk or disk.ova: partition-image output does not produce a merged disk to convert")
+		}
+	}
+	if c.Disk.QCOW2Compress && !c.Disk.QCOW2 {
+		return fmt.Errorf("disk.qcow2_compress requires disk.qcow2 to be set")
+	}
+	if c.Disk.VHDDynamic && !c.Disk.VHD {
+		return fmt.Errorf("disk.vhd_dynamic requires disk.vhd to be set")
+	}
+	if c.Disk.OVF.CPUs < 0 || c.Disk.OVF.MemoryMB < 0 {
+		return fmt.Errorf("disk.ovf.cpus and disk.ovf.memory_mb cannot be negative")
+	}
+	if err := c.Disk.Layout.Validate(); err != nil {
+		return err
+	}
+	if (len(c.Disk.Layout.Partitions) > 0 || c.Disk.Layout.IsMBR()) && c.Disk.Partitions {
+		return fmt.Errorf("disk.layout cannot be combined with disk.partitions: partition-image output does not assemble a disk to lay out")
+	}
+	if c.Disk.Layout.IsMBR() && c.Disk.Size == "" {
+		return fmt.Errorf("disk.layout.table %q requires disk.size: MBR disks are not grown on first boot, so the persistent partition is sized at build time", PartitionTableMBR)
+	}
+	if c.Compression.Format != "" && c.Compression.Extension() == "" {
+		return fmt.Errorf("compression.format %q is not supported: use %q, %q or %q", c.Compression.Format, CompressionZstd, CompressionXZ, CompressionGzip)
+	}
+	if c.SBOM.Format != "" && !sbom.ValidFormat(c.SBOM.Format) {
+		return fmt.Errorf("sbom.format %q is not supported: use %q or %q", c.SBOM.Format, sbom.FormatSPDX, sbom.FormatCycloneDX)
+	}
+	if c.SBOM.VulnDB != "" && c.SBOM.Format == "" {
+		return fmt.Errorf("sbom.vuln_db requires sbom.format to be set")
+	}
+	if err := c.ISO.validateImages(); err != nil {
+		return err
 	}
 	return nil
 }
@@ -152,4 +152,206 @@ var _ = Describe("Config Validate", func() {
 		Expect(err.Error()).To(ContainSubstring("partitions"))
 		Expect(err.Error()).To(ContainSubstring("vhd"))
 	})
+
+	It("rejects partition-image output combined with qcow2", func() {
+		cfg.Disk.Partitions = true
+		cfg.Disk.QCOW2 = true
+		err := cfg.Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("qcow2"))
+	})
+
+	It("rejects qcow2 compression without qcow2 output", func() {
+		cfg.Disk.QCOW2Compress = true
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("disk.qcow2_compress requires disk.qcow2")))
+		cfg.Disk.QCOW2 = true
+		Expect(cfg.Validate()).To(Succeed())
+	})
+
+	It("rejects partition-image output combined with vhdx", func() {
+		cfg.Disk.Partitions = true
+		cfg.Disk.VHDX = true
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("disk.vhdx")))
+	})
+
+	It("rejects a dynamic VHD without vhd output", func() {
+		cfg.Disk.VHDDynamic = true
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("disk.vhd_dynamic requires disk.vhd")))
+		cfg.Disk.VHD = true
+		Expect(cfg.Validate()).To(Succeed())
+	})
+
+	It("rejects partition-image output combined with an OVA", func() {
+		cfg.Disk.Partitions = true
+		cfg.Disk.OVA = true
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("disk.ova")))
+	})
+
+	It("rejects a negative OVF virtual machine size", func() {
+		cfg.Disk.OVA = true
+		cfg.Disk.OVF.MemoryMB = -1
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("disk.ovf")))
+	})
+
+	It("defaults the OVF virtual machine size", func() {
+		Expect(cfg.Disk.OVF.CPUsOrDefault()).To(Equal(2))
+		Expect(cfg.Disk.OVF.MemoryMBOrDefault()).To(Equal(4096))
+		cfg.Disk.OVF = schema.OVF{CPUs: 8, MemoryMB: 16384}
+		Expect(cfg.Disk.OVF.CPUsOrDefault()).To(Equal(8))
+		Expect(cfg.Disk.OVF.MemoryMBOrDefault()).To(Equal(16384))
+	})
+
+	It("rejects an unknown compression format", func() {
+		cfg.Compression.Format = "bzip2"
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("compression.format")))
+		for format, ext := range map[string]string{"zstd": ".zst", "xz": ".xz", "gzip": ".gz"} {
+			cfg.Compression.Format = format
+			Expect(cfg.Validate()).To(Succeed())
+			Expect(cfg.Compression.Extension()).To(Equal(ext))
+		}
+	})
+
+	Describe("disk.layout", func() {
+		var roles []schema.Partition
+
+		BeforeEach(func() {
+			cfg.Disk.EFI = true
+			roles = []schema.Partition{
+				{Role: schema.PartitionRoleBoot},
+				{Role: schema.PartitionRoleOEM},
+				{Role: schema.PartitionRoleRecovery},
+			}
+		})
+
+		It("passes for a layout adding data and firmware partitions", func() {
+			cfg.Disk.Layout.Partitions = append([]schema.Partition{
+				{Name: "firmware", Size: 4, Offset: 1, Type: "0FC63DAF-8483-4772-8E79-3D69D8477DE4"},
+				{Role: schema.PartitionRoleBoot, Offset: 8},
+			}, append(roles[1:], schema.Partition{Label: "DATA", FS: "ext4", Size: 512, Content: "/srv/data"})...)
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("requires the partitions Kairos builds to be placed once", func() {
+			cfg.Disk.Layout.Partitions = roles[:2]
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring(`must place the "recovery" partition`)))
+			cfg.Disk.Layout.Partitions = append(roles, schema.Partition{Role: schema.PartitionRoleOEM})
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("placed more than once")))
+			cfg.Disk.Layout.Partitions = append(roles, schema.Partition{Role: "state"})
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring(`role "state" is not supported`)))
+		})
+
+		It("rejects sizing or formatting the partitions Kairos builds", func() {
+			roles[2].Size = 4096
+			cfg.Disk.Layout.Partitions = roles
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("only takes name, type and offset")))
+		})
+
+		It("checks the partitions it adds", func() {
+			for extra, msg := range map[schema.Partition]string{
+				{Label: "DATA", FS: "ext4"}:                        "size is required",
+				{Label: "COS_DATA", FS: "ext4", Size: 8}:           "reserved",
+				{Label: "DATA", FS: "btrfs", Size: 8}:              `fs "btrfs" is not supported`,
+				{Label: "FIRMWARE-DATA", FS: "vfat", Size: 8}:      "longer than the 11 characters",
+				{Label: "DATA", Size: 8}:                           "label requires fs",
+				{Name: "data", Size: 8, Content: "/srv/data"}:      "content requires fs",
+				{Name: "data", Size: 8, Type: "linux"}:             "not a GPT partition type GUID",
+				{Label: "DATA", FS: "ext4", Size: 8, Offset: 2048}: "",
+			} {
+				cfg.Disk.Layout.Partitions = append(append([]schema.Partition{}, roles...), extra)
+				if msg == "" {
+					Expect(cfg.Validate()).To(Succeed())
+				} else {
+					Expect(cfg.Validate()).To(MatchError(ContainSubstring(msg)), "%+v", extra)
+				}
+			}
+		})
+
+		It("rejects duplicate labels and overlapping offsets", func() {
+			cfg.Disk.Layout.Partitions = append(roles,
+				schema.Partition{Label: "DATA", FS: "ext4", Size: 8},
+				schema.Partition{Label: "DATA", FS: "xfs", Size: 8})
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring(`label "DATA" is used more than once`)))
+
+			cfg.Disk.Layout.Partitions = append([]schema.Partition{
+				{Name: "firmware", Size: 16, Offset: 1},
+				{Role: schema.PartitionRoleBoot, Offset: 8},
+			}, roles[1:]...)
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("offset 8 MiB does not come after")))
+		})
+
+		It("takes MBR partition types and requires a disk size with an MBR table", func() {
+			roles[0].Type = "0x0c"
+			cfg.Disk.Layout = schema.Layout{Table: schema.PartitionTableMBR, Partitions: roles}
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("requires disk.size")))
+			cfg.Disk.Size = "16384"
+			Expect(cfg.Validate()).To(Succeed())
+			roles[0].Type = "0x0f"
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("reserved for extended")))
+			roles[0].Type = "0C"
+			Expect(cfg.Validate()).To(Succeed())
+			cfg.Disk.Layout.Table = "apm"
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring(`disk.layout.table "apm" is not supported`)))
+		})
+
+		It("rejects a layout combined with partition-image output", func() {
+			cfg.Disk.Layout.Partitions = roles
+			cfg.Disk.Partitions = true
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("disk.layout cannot be combined with disk.partitions")))
+		})
+	})
+
+	Describe("ISO images", func() {
+		It("names the images after their repository and tag by default", func() {
+			img := schema.ISOImage{ContainerImage: "quay.io/kairos/ubuntu:24.04-standard@sha256:abcd"}
+			Expect(img.Title()).To(Equal("ubuntu:24.04-standard"))
+			Expect(img.Dir()).To(Equal("ubuntu-24-04-standard"))
+
+			img.Name = "Ubuntu (core)"
+			Expect(img.Title()).To(Equal("Ubuntu (core)"))
+			Expect(img.Dir()).To(Equal("ubuntu-core"))
+		})
+
+		It("passes for images that can be told apart", func() {
+			cfg.ISO.Images = []schema.ISOImage{
+				{ContainerImage: "quay.io/kairos/ubuntu:24.04-core"},
+				{ContainerImage: "quay.io/kairos/ubuntu:24.04-standard", ExtendLiveCmdline: "console=ttyS0,115200"},
+			}
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("rejects images without a container image", func() {
+			cfg.ISO.Images = []schema.ISOImage{{Name: "core"}}
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("iso.images[0]: container_image is required")))
+		})
+
+		It("rejects images stored in the same directory", func() {
+			cfg.ISO.Images = []schema.ISOImage{
+				{Name: "Ubuntu Core", ContainerImage: "quay.io/kairos/ubuntu:24.04-core"},
+				{Name: "ubuntu-core", ContainerImage: "quay.io/kairos/ubuntu:22.04-core"},
+			}
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring(`iso.images[1]: name "ubuntu-core" is too close to "Ubuntu Core"`)))
+		})
+
+		DescribeTable("rejects names stored with the boot files",
+			func(name string) {
+				cfg.ISO.Images = []schema.ISOImage{{Name: name, ContainerImage: "quay.io/kairos/ubuntu:24.04-core"}}
+				Expect(cfg.Validate()).To(MatchError(ContainSubstring("which holds its boot files")))
+			},
+			Entry("boot", "Boot"),
+			Entry("EFI", "EFI"),
+			Entry("grub", "grub"),
+			Entry("grub2", "GRUB2"),
+		)
+
+		It("rejects names that would break the boot menu", func() {
+			cfg.ISO.Images = []schema.ISOImage{{Name: `core" --class`, ContainerImage: "quay.io/kairos/ubuntu:24.04-core"}}
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("cannot contain quotes")))
+
+			cfg.ISO.Images = []schema.ISOImage{{Name: "+++", ContainerImage: "quay.io/kairos/ubuntu:24.04-core"}}
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("has no letters or digits")))
+
+			cfg.ISO.Images = []schema.ISOImage{{ContainerImage: "quay.io/kairos/ubuntu:24.04-core", ExtendLiveCmdline: "rd.debug\nrd.shell"}}
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("extend-live-cmdline cannot contain newlines")))
+		})
+	})
 })
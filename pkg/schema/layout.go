@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Partition tables of the disk.layout.table option.
+const (
+	PartitionTableGPT = "gpt"
+	PartitionTableMBR = "mbr"
+)
+
+// Roles of the partitions Kairos builds itself, which a layout places among
+// its own partitions.
+const (
+	PartitionRoleBoot     = "boot"
+	PartitionRoleOEM      = "oem"
+	PartitionRoleRecovery = "recovery"
+)
+
+var gptTypeGUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Layout describes the partitions of the raw disk images, in disk order.
+// Left empty, a disk holds the boot, OEM and recovery partitions in that
+// order, and the state and persistent partitions are added after the last
+// partition on first boot.
+//
+// First boot only grows GPT disks, so with an MBR table the state and
+// persistent partitions are laid out at build time instead: state sized as
+// disk.state_size says and persistent filling the disk up to disk.size,
+// which is then required. Partitions past the third are logical ones in an
+// extended partition when they do not fit in the four primary entries.
+type Layout struct {
+	// Table is the partition table: "gpt", the default, or "mbr".
+	Table      string      `yaml:"table"`
+	Partitions []Partition `yaml:"partitions"`
+}
+
+// Partition is a partition of a Layout. It either places a partition Kairos
+// builds, by its Role, or adds one of its own.
+type Partition struct {
+	// Role is "boot", "oem" or "recovery" to place the EFI or BIOS boot,
+	// OEM or recovery partition. A layout must place all three; these
+	// entries may only set Name, Type and Offset. Empty adds a partition.
+	Role string `yaml:"role"`
+	// Name is the GPT partition name, defaulting to Label.
+	Name string `yaml:"name"`
+	// Label is the filesystem label of an added partition.
+	Label string `yaml:"label"`
+	// Size of an added partition, in MiB.
+	Size uint64 `yaml:"size"`
+	// FS is the filesystem of an added partition: "ext2", "ext3", "ext4",
+	// "xfs" or "vfat". Empty leaves it unformatted, as firmware partitions
+	// written after the build are.
+	FS string `yaml:"fs"`
+	// Type is the partition type: a type GUID for GPT or a hex byte such as
+	// "0x0c" for MBR. It defaults to the type of the partition's filesystem.
+	Type string `yaml:"type"`
+	// Offset, in MiB from the start of the disk, fixes where the partition
+	// starts. Zero places it right after the previous one.
+	Offset uint64 `yaml:"offset"`
+	// Content is a directory copied into the filesystem of an added
+	// partition.
+	Content string `yaml:"content"`
+}
+
+// IsMBR reports whether the layout asks for an MBR partition table.
+func (l Layout) IsMBR() bool {
+	return l.Table == PartitionTableMBR
+}
+
+// Validate checks the layout on its own; Config.Validate checks it against
+// the rest of the disk options.
+func (l Layout) Validate() error {
+	if l.Table != "" && l.Table != PartitionTableGPT && l.Table != PartitionTableMBR {
+		return fmt.Errorf("disk.layout.table %q is not supported: use %q or %q", l.Table, PartitionTableGPT, PartitionTableMBR)
+	}
+	if len(l.Partitions) == 0 {
+		return nil
+	}
+
+	roles := map[string]bool{}
+	labels := map[string]bool{}
+	var lastOffset uint64
+	for i, p := range l.Partitions {
+		field := fmt.Sprintf("disk.layout.partitions[%d]", i)
+		if err := p.validate(field, l.IsMBR()); err != nil {
+			return err
+		}
+		if p.Role != "" {
+			if roles[p.Role] {
+				return fmt.Errorf("%s: role %q is placed more than once", field, p.Role)
+			}
+			roles[p.Role] = true
+		}
+		if p.Label != "" {
+			if labels[p.Label] {
+				return fmt.Errorf("%s: label %q is used more than once", field, p.Label)
+			}
+			labels[p.Label] = true
+		}
+		if p.Offset > 0 {
+			if p.Offset <= lastOffset {
+				return fmt.Errorf("%s: offset %d MiB does not come after the offset of a previous partition", field, p.Offset)
+			}
+			lastOffset = p.Offset
+			if p.Role == "" {
+				lastOffset += p.Size - 1
+			}
+		}
+	}
+	for _, role := range []string{PartitionRoleBoot, PartitionRoleOEM, PartitionRoleRecovery} {
+		if !roles[role] {
+			return fmt.Errorf("disk.layout.partitions must place the %q partition", role)
+		}
+	}
+	return nil
+}
+
+func (p Partition) validate(field string, mbr bool) error {
+	switch p.Role {
+	case PartitionRoleBoot, PartitionRoleOEM, PartitionRoleRecovery:
+		if p.Label != "" || p.Size != 0 || p.FS != "" || p.Content != "" {
+			return fmt.Errorf("%s: the %q partition is built by Kairos and only takes name, type and offset", field, p.Role)
+		}
+	case "":
+		if p.Size == 0 {
+			return fmt.Errorf("%s: size is required", field)
+		}
+		if p.Label == "" && p.Name == "" {
+			return fmt.Errorf("%s: label or name is required", field)
+		}
+		if strings.HasPrefix(p.Label, "COS_") {
+			return fmt.Errorf("%s: label %q is reserved: COS_ labels belong to the partitions Kairos builds", field, p.Label)
+		}
+		if max := maxLabelLength(p.FS); p.FS != "" && max == 0 {
+			return fmt.Errorf("%s: fs %q is not supported: use ext2, ext3, ext4, xfs or vfat", field, p.FS)
+		} else if len(p.Label) > max {
+			if p.FS == "" {
+				return fmt.Errorf("%s: label requires fs to be set", field)
+			}
+			return fmt.Errorf("%s: label %q is longer than the %d characters %s allows", field, p.Label, max, p.FS)
+		}
+		if p.Content != "" && p.FS == "" {
+			return fmt.Errorf("%s: content requires fs to be set", field)
+		}
+	default:
+		return fmt.Errorf("%s: role %q is not supported: use %q, %q or %q, or leave it empty to add a partition", field, p.Role, PartitionRoleBoot, PartitionRoleOEM, PartitionRoleRecovery)
+	}
+
+	if len([]rune(p.Name)) > 36 {
+		return fmt.Errorf("%s: name %q is longer than the 36 characters GPT allows", field, p.Name)
+	}
+	if p.Type != "" {
+		if mbr {
+			t, err := ParseMBRType(p.Type)
+			if err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+			if t == 0x05 || t == 0x0f || t == 0x85 || t == 0xee {
+				return fmt.Errorf("%s: type %q is reserved for extended and protective partitions", field, p.Type)
+			}
+		} else if !gptTypeGUID.MatchString(p.Type) {
+			return fmt.Errorf("%s: type %q is not a GPT partition type GUID", field, p.Type)
+		}
+	}
+	return nil
+}
+
+// maxLabelLength returns the longest label fs takes, or zero for an
+// unknown filesystem and for no filesystem at all.
+func maxLabelLength(fs string) int {
+	switch fs {
+	case "ext2", "ext3", "ext4":
+		return 16
+	case "xfs":
+		return 12
+	case "vfat":
+		return 11
+	}
+	return 0
+}
+
+// ParseMBRType parses an MBR partition type such as "0x83" or "83".
+func ParseMBRType(s string) (byte, error) {
+	t, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 8)
+	if err != nil || t == 0 {
+		return 0, fmt.Errorf("type %q is not an MBR partition type: use a hex byte such as 0x83", s)
+	}
+	return byte(t), nil
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/kairos-io/AuroraBoot/pkg/builder"
 	"github.com/kairos-io/AuroraBoot/pkg/handlers"
 	"github.com/kairos-io/AuroraBoot/pkg/isoserve"
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
 	"github.com/kairos-io/AuroraBoot/pkg/store"
 	"github.com/kairos-io/AuroraBoot/pkg/ws"
 	"github.com/labstack/echo/v4"
@@ -34,6 +35,37 @@ type Config struct {
 	BMCTargetStore        store.BMCTargetStore
 	SettingsStore         store.SettingsStore
 	Builder               builder.ArtifactBuilder
+	// BuildSetStore enables the build-matrix endpoints under
+	// /api/v1/build-sets. Nil leaves them unregistered.
+	BuildSetStore store.BuildSetStore
+	// BuildTemplateStore enables the build templates under
+	// /api/v1/build-templates. Nil leaves them unregistered.
+	BuildTemplateStore store.BuildTemplateStore
+	// RegistryStore enables the publishing registries under
+	// /api/v1/registries and POST /api/v1/artifacts/:id/publish. Nil leaves
+	// them unregistered.
+	RegistryStore store.RegistryStore
+	// ChannelStore enables artifact channels under /api/v1/channels, group
+	// channel following and "channel:<name>" upgrade sources. Nil leaves
+	// them unregistered.
+	ChannelStore store.ChannelStore
+	// SecretStore enables /api/v1/secrets and lets Git sources name the
+	// secret they are cloned with. Nil leaves them unregistered.
+	SecretStore store.SecretStore
+	// ArtifactSpecStore keeps the request of every build. Together with
+	// GitWebhookSecret it enables POST /api/v1/webhooks/git, which rebuilds
+	// artifacts when their Git branch is pushed to.
+	ArtifactSpecStore store.ArtifactSpecStore
+	GitWebhookSecret  string
+	// BuildWorkerStore and WorkerQueue enable the remote build-worker
+	// endpoints (--builder=worker). Both nil leaves them unregistered.
+	BuildWorkerStore store.BuildWorkerStore
+	WorkerQueue      handlers.WorkerQueue
+	// WorkerToken is the shared secret `auroraboot worker` presents to
+	// register. It is deliberately separate from RegToken: the node token is
+	// baked into every built image, and a worker receives full build specs.
+	// Registration stays closed while it is empty.
+	WorkerToken string
 	// SystemInfo describes the active builder backend for the
 	// /api/v1/system/builder introspection endpoint. Populated at wire time in
 	// runWeb from the flags plus the resolved kube REST config.
@@ -45,6 +77,13 @@ type Config struct {
 	ArtifactsDir  string
 	KeysDir       string  // base directory for SecureBoot key sets
 	Hub           *ws.Hub // optional, created if nil
+	// ManifestSigningKey is the PEM private key SHA256SUMS manifests are
+	// signed with when a build does not name a SecureBoot key set. Empty
+	// leaves those builds unsigned.
+	ManifestSigningKey string
+	// Storage is where finished build outputs are kept for download. nil
+	// keeps them in ArtifactsDir.
+	Storage storage.Backend
 	// ISOServe serves a local artifact ISO over a tokenized, BMC-reachable URL
 	// for Redfish virtual-media deployments. Optional; when nil the Redfish
 	// deploy path requires an explicit imageUrl.
@@ -167,8 +206,27 @@ func New(cfg Config) *echo.Echo {
 		nodeHandler.WithFinalizer(deployHandler.MaybeFinalizeForNode, cfg.BaseContext)
 	}
 	cmdHandler := handlers.NewCommandHandler(cfg.CommandStore, cfg.NodeStore, hub)
-	artifactHandler := handlers.NewArtifactHandler(cfg.Builder, cfg.ArtifactStore, cfg.GroupStore, cfg.SecureBootKeySetStore, cfg.ArtifactsDir, regToken, cfg.AuroraBootURL)
+	artifactHandler := handlers.NewArtifactHandler(cfg.Builder, cfg.ArtifactStore, cfg.GroupStore, cfg.SecureBootKeySetStore, cfg.ArtifactsDir, regToken, cfg.AuroraBootURL).
+		WithManifestSigningKey(cfg.ManifestSigningKey)
+	if cfg.Storage != nil {
+		artifactHandler.WithStorage(cfg.Storage)
+	}
+	if cfg.SecretStore != nil {
+		artifactHandler.WithSecrets(cfg.SecretStore)
+	}
+	if cfg.ArtifactSpecStore != nil {
+		artifactHandler.WithSpecs(cfg.ArtifactSpecStore)
+	}
 	groupHandler := handlers.NewGroupHandler(cfg.GroupStore)
+	// Channel upgrades are resolved on every delivery path: the REST poll,
+	// the WS push on queue and the WS replay of pending commands on connect.
+	var channelHandler *handlers.ChannelHandler
+	if cfg.ChannelStore != nil {
+		channelHandler = handlers.NewChannelHandler(cfg.ChannelStore, cfg.ArtifactStore, cfg.GroupStore, cfg.NodeStore, cmdHandler)
+		cmdHandler.WithChannels(channelHandler)
+		nodeHandler.WithCommandResolver(channelHandler.ResolveCommand)
+		groupHandler.WithChannels(cfg.ChannelStore)
+	}
 	settingsHandler := handlers.NewSettingsHandler(&regToken, cfg.RegTokenFile).
 		WithImageSource(cfg.SettingsStore, cfg.ISOServe, cfg.RedfishServeURL)
 
@@ -185,6 +243,9 @@ func New(cfg Config) *echo.Echo {
 		agentWSHandler.Finalize = deployHandler.MaybeFinalizeForNode
 		agentWSHandler.BaseCtx = cfg.BaseContext
 	}
+	if channelHandler != nil {
+		agentWSHandler.Resolve = channelHandler.ResolveCommand
+	}
 	uiWSHandler := &ws.UIHandler{Hub: hub}
 
 	// Public endpoints
@@ -253,16 +314,109 @@ func New(cfg Config) *echo.Echo {
 	adminGroup.POST("/artifacts", artifactHandler.Create)
 	adminGroup.GET("/artifacts", artifactHandler.List)
 	adminGroup.DELETE("/artifacts/failed", artifactHandler.ClearFailed)
+	adminGroup.GET("/artifacts/diff", artifactHandler.Diff)
 	adminGroup.GET("/artifacts/:id", artifactHandler.Get)
 	adminGroup.GET("/artifacts/:id/logs", artifactHandler.GetLogs)
+	adminGroup.GET("/artifacts/:id/sbom", artifactHandler.GetSBOM)
 	adminGroup.POST("/artifacts/:id/cancel", artifactHandler.Cancel)
+	adminGroup.POST("/artifacts/:id/retry", artifactHandler.Retry)
 	adminGroup.PATCH("/artifacts/:id", artifactHandler.Update)
 	adminGroup.DELETE("/artifacts/:id", artifactHandler.Delete)
 
+	// Build sets (build matrix)
+	if cfg.BuildSetStore != nil {
+		buildSetHandler := handlers.NewBuildSetHandler(artifactHandler, cfg.BuildSetStore)
+		adminGroup.POST("/build-sets", buildSetHandler.Create)
+		adminGroup.GET("/build-sets", buildSetHandler.List)
+		adminGroup.GET("/build-sets/:id", buildSetHandler.Get)
+		adminGroup.POST("/build-sets/:id/cancel", buildSetHandler.Cancel)
+		adminGroup.POST("/build-sets/:id/clone", buildSetHandler.Clone)
+		adminGroup.DELETE("/build-sets/:id", buildSetHandler.Delete)
+	}
+
+	// Build templates
+	if cfg.BuildTemplateStore != nil {
+		templateHandler := handlers.NewBuildTemplateHandler(artifactHandler, cfg.BuildTemplateStore)
+		adminGroup.POST("/build-templates", templateHandler.Create)
+		adminGroup.GET("/build-templates", templateHandler.List)
+		adminGroup.POST("/build-templates/import", templateHandler.Import)
+		adminGroup.GET("/build-templates/:id", templateHandler.Get)
+		adminGroup.PUT("/build-templates/:id", templateHandler.Update)
+		adminGroup.DELETE("/build-templates/:id", templateHandler.Delete)
+		adminGroup.POST("/build-templates/:id/build", templateHandler.Build)
+		adminGroup.GET("/build-templates/:id/export", templateHandler.Export)
+	}
+
+	// Publishing to OCI registries
+	if cfg.RegistryStore != nil {
+		registryHandler := handlers.NewRegistryHandler(cfg.RegistryStore, cfg.ArtifactStore, cfg.ArtifactsDir)
+		adminGroup.POST("/registries", registryHandler.Create)
+		adminGroup.GET("/registries", registryHandler.List)
+		adminGroup.PUT("/registries/:id", registryHandler.Update)
+		adminGroup.DELETE("/registries/:id", registryHandler.Delete)
+		adminGroup.POST("/artifacts/:id/publish", registryHandler.Publish)
+	}
+
+	// Artifact retention. The policy lives in the settings store; the
+	// background run starts only for a server with a lifecycle context.
+	if cfg.SettingsStore != nil {
+		retentionHandler := handlers.NewRetentionHandler(cfg.SettingsStore, artifactHandler).
+			WithNetboot(cfg.NetbootManager)
+		if cfg.DeploymentStore != nil {
+			retentionHandler.WithDeployments(cfg.DeploymentStore)
+		}
+		if cfg.ChannelStore != nil {
+			retentionHandler.WithChannels(cfg.ChannelStore)
+		}
+		adminGroup.GET("/retention", retentionHandler.GetPolicy)
+		adminGroup.PUT("/retention", retentionHandler.UpdatePolicy)
+		adminGroup.GET("/retention/plan", retentionHandler.Plan)
+		adminGroup.POST("/retention/run", retentionHandler.Collect)
+		if cfg.BaseContext != nil {
+			go retentionHandler.Run(cfg.BaseContext)
+		}
+	}
+
+	// Rebuilds when a watched base image moves. Like retention, the schedule
+	// lives in the settings store and only a server with a lifecycle context
+	// runs it in the background.
+	if cfg.SettingsStore != nil {
+		imageWatchHandler := handlers.NewImageWatchHandler(cfg.SettingsStore, artifactHandler).
+			WithHub(hub)
+		if cfg.BuildTemplateStore != nil {
+			imageWatchHandler.WithTemplates(cfg.BuildTemplateStore)
+		}
+		adminGroup.GET("/image-watch", imageWatchHandler.Get)
+		adminGroup.PUT("/image-watch", imageWatchHandler.Update)
+		adminGroup.POST("/image-watch/run", imageWatchHandler.Check)
+		if cfg.BaseContext != nil {
+			go imageWatchHandler.Run(cfg.BaseContext)
+		}
+	}
+
+	// Secrets for Git sources
+	if cfg.SecretStore != nil {
+		secretHandler := handlers.NewSecretHandler(cfg.SecretStore)
+		adminGroup.GET("/secrets", secretHandler.List)
+		adminGroup.PUT("/secrets/:name", secretHandler.Set)
+		adminGroup.DELETE("/secrets/:name", secretHandler.Delete)
+	}
+
+	// Artifact channels
+	if channelHandler != nil {
+		adminGroup.POST("/channels", channelHandler.Create)
+		adminGroup.GET("/channels", channelHandler.List)
+		adminGroup.DELETE("/channels/:id", channelHandler.Delete)
+		adminGroup.GET("/channels/:id/history", channelHandler.History)
+		adminGroup.POST("/channels/:id/promote", channelHandler.Promote)
+		adminGroup.POST("/channels/:id/rollback", channelHandler.Rollback)
+	}
+
 	// Artifact downloads — accepts admin password OR node API key.
 	// Registered before the admin group catches them, using inline middleware.
 	dlAuth := auth.DownloadMiddleware(cfg.AdminPassword, cfg.NodeStore)
 	e.GET("/api/v1/artifacts/:id/download/*", artifactHandler.Download, dlAuth)
+	e.HEAD("/api/v1/artifacts/:id/download/*", artifactHandler.Download, dlAuth)
 	e.GET("/api/v1/artifacts/:id/image", artifactHandler.ExportImage, dlAuth)
 
 	// Artifact upload — per-build UploadToken bearer (minted at Create time,
@@ -271,9 +425,45 @@ func New(cfg Config) *echo.Echo {
 	// adminGroup so the admin middleware does not intercept the request.
 	e.PUT("/api/v1/artifacts/:id/upload/*", artifactHandler.Upload)
 
+	// Git push webhook — authenticated by the webhook secret the forge signs
+	// (or sends) each delivery with, not by the admin bearer.
+	if cfg.ArtifactSpecStore != nil && cfg.GitWebhookSecret != "" {
+		gitWebhookHandler := handlers.NewGitWebhookHandler(artifactHandler, cfg.GitWebhookSecret)
+		e.POST("/api/v1/webhooks/git", gitWebhookHandler.Push)
+	}
+
+	// Remote build workers. Registration uses the worker token; every other
+	// worker call carries the API key minted at registration and is scoped
+	// to the builds that worker holds a lease on.
+	if cfg.WorkerQueue != nil && cfg.BuildWorkerStore != nil {
+		workerHandler := handlers.NewWorkerHandler(cfg.BuildWorkerStore, cfg.WorkerQueue)
+		if cfg.WorkerToken != "" {
+			workerToken := cfg.WorkerToken
+			workerRegGroup := e.Group("/api/v1/workers")
+			workerRegGroup.Use(auth.RegistrationTokenAuth(&workerToken))
+			workerRegGroup.POST("/register", workerHandler.Register)
+		}
+		workerGroup := e.Group("/api/v1/worker")
+		workerGroup.Use(auth.WorkerAPIKeyMiddleware(cfg.BuildWorkerStore))
+		workerGroup.POST("/lease", workerHandler.Lease)
+		workerGroup.POST("/heartbeat", workerHandler.Heartbeat)
+		workerGroup.POST("/builds/:id/logs", workerHandler.AppendLog)
+		workerGroup.POST("/builds/:id/finish", workerHandler.Finish)
+
+		adminGroup.GET("/workers", workerHandler.List)
+		adminGroup.DELETE("/workers/:id", workerHandler.Delete)
+	}
+
 	// UI WebSocket (admin auth)
 	adminGroup.GET("/ws/ui", uiWSHandler.HandleUIWS)
 
+	// Cloud-config linting
+	cloudConfigHandler := handlers.NewCloudConfigHandler()
+	adminGroup.POST("/cloud-config/validate", cloudConfigHandler.Validate)
+
+	// Dry run of an AuroraBoot configuration
+	adminGroup.POST("/plan", handlers.NewPlanHandler().Plan)
+
 	// System introspection
 	systemHandler := handlers.NewSystemHandler(cfg.SystemInfo)
 	adminGroup.GET("/system/builder", systemHandler.GetBuilder)
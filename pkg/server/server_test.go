@@ -136,6 +136,9 @@ func (f *fakeCommandStore) UpdateStatusForNode(_ context.Context, id string, nod
 	}
 	return store.ErrCommandNotFound
 }
+func (f *fakeCommandStore) UpdateArgs(_ context.Context, _ string, _ map[string]string) error {
+	return nil
+}
 func (f *fakeCommandStore) ListByNode(_ context.Context, nodeID string) ([]*store.NodeCommand, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
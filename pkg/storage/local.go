@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Local keeps outputs in a directory on the server, laid out as
+// <root>/<build-id>/<file>. With root set to --artifacts-dir it is the
+// directory builds already write to, so Put has nothing to do for them.
+type Local struct {
+	root string
+}
+
+// NewLocal returns a Local backend rooted at root.
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.root, filepath.FromSlash(key))
+}
+
+// Put copies localPath into place, unless it already is the stored file.
+func (l *Local) Put(_ context.Context, key, localPath string) error {
+	dst := l.path(key)
+	if same, err := samePath(localPath, dst); err != nil || same {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Copy to a sibling temp file and rename, so a reader never sees a
+	// partly written file under the stored name.
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func samePath(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(ai, bi), nil
+}
+
+// Exists reports whether the file is present.
+func (l *Local) Exists(_ context.Context, key string) (bool, error) {
+	_, err := os.Stat(l.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// URL is always "": local files are served by the Download handler.
+func (l *Local) URL(context.Context, string) (string, error) {
+	return "", nil
+}
+
+// Delete removes the build's directory.
+func (l *Local) Delete(_ context.Context, buildID string) error {
+	return os.RemoveAll(l.path(buildID))
+}
@@ -0,0 +1,51 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Local", func() {
+	var (
+		root    string
+		backend *storage.Local
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		root = GinkgoT().TempDir()
+		backend = storage.NewLocal(root)
+	})
+
+	It("leaves a file that is already in place untouched", func() {
+		path := filepath.Join(root, "b1", "kairos.iso")
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte("iso"), 0o644)).To(Succeed())
+
+		Expect(backend.Put(ctx, storage.Key("b1", path), path)).To(Succeed())
+		Expect(os.ReadFile(path)).To(Equal([]byte("iso")))
+		Expect(backend.Exists(ctx, "b1/kairos.iso")).To(BeTrue())
+	})
+
+	It("copies a file from elsewhere and serves no URL for it", func() {
+		src := filepath.Join(GinkgoT().TempDir(), "kairos.iso")
+		Expect(os.WriteFile(src, []byte("iso"), 0o644)).To(Succeed())
+
+		Expect(backend.Put(ctx, "b1/kairos.iso", src)).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(root, "b1", "kairos.iso"))).To(Equal([]byte("iso")))
+		Expect(backend.URL(ctx, "b1/kairos.iso")).To(BeEmpty())
+	})
+
+	It("deletes a build's directory", func() {
+		Expect(os.MkdirAll(filepath.Join(root, "b1"), 0o755)).To(Succeed())
+		Expect(backend.Delete(ctx, "b1")).To(Succeed())
+		Expect(filepath.Join(root, "b1")).NotTo(BeADirectory())
+		Expect(backend.Exists(ctx, "b1/kairos.iso")).To(BeFalse())
+	})
+})
@@ -0,0 +1,507 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPartSize is the multipart upload part size used when S3Config
+	// leaves it unset. Files no larger than one part are sent with a single
+	// PUT.
+	DefaultPartSize = 64 << 20
+	// MinPartSize is the smallest part S3 accepts for any part but the last.
+	MinPartSize = 5 << 20
+	// DefaultPresignTTL is how long a download URL stays valid when
+	// S3Config leaves it unset.
+	DefaultPresignTTL = 15 * time.Minute
+)
+
+// unsignedPayload is sent instead of a body hash for file uploads, so a
+// multi-gigabyte image is not read twice. The transport still carries it
+// intact; SigV4 just does not cover the bytes.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Config configures an S3-compatible backend (AWS S3, MinIO, Ceph RGW...).
+type S3Config struct {
+	// Endpoint is the base URL of the service, e.g. https://minio.lan:9000.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is prepended to every key, to share a bucket with other data.
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket as <endpoint>/<bucket> instead of
+	// <bucket>.<endpoint>. MinIO and most self-hosted services need it.
+	PathStyle bool
+	// PartSize is the multipart upload part size (default DefaultPartSize).
+	PartSize int64
+	// PresignTTL is how long download URLs stay valid (default
+	// DefaultPresignTTL).
+	PresignTTL time.Duration
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// S3 stores outputs in an S3-compatible bucket. Requests are signed with
+// AWS Signature Version 4, which every S3-compatible service accepts.
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3 validates cfg and returns an S3 backend. It does not contact the
+// service; see Check.
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3: endpoint and bucket are required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("s3: access key and secret key are required")
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("s3: endpoint %q is not an http(s) URL", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.PartSize == 0 {
+		cfg.PartSize = DefaultPartSize
+	}
+	if cfg.PartSize < MinPartSize {
+		return nil, fmt.Errorf("s3: part size must be at least %d bytes", MinPartSize)
+	}
+	if cfg.PresignTTL == 0 {
+		cfg.PresignTTL = DefaultPresignTTL
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3{cfg: cfg, endpoint: u, client: client, now: time.Now}, nil
+}
+
+func (s *S3) objectKey(key string) string {
+	if s.cfg.Prefix == "" {
+		return key
+	}
+	return s.cfg.Prefix + "/" + key
+}
+
+// url returns the URL of an object, or of the bucket itself for key "".
+func (s *S3) url(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	base := strings.TrimSuffix(u.Path, "/")
+	if s.cfg.PathStyle {
+		u.Path = base + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = base + "/" + key
+	}
+	u.RawPath = ""
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// Check verifies the bucket is reachable with the configured credentials,
+// so a typo fails the server start rather than the first build.
+func (s *S3) Check(ctx context.Context) error {
+	_, err := s.list(ctx, s.objectKey(""), "", 1)
+	return err
+}
+
+// Put uploads the file, in parts when it is larger than PartSize.
+func (s *S3) Put(ctx context.Context, key, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	key = s.objectKey(key)
+	if info.Size() <= s.cfg.PartSize {
+		_, err := s.do(ctx, http.MethodPut, key, nil, io.NewSectionReader(f, 0, info.Size()), info.Size(), unsignedPayload)
+		return err
+	}
+	return s.putMultipart(ctx, key, f, info.Size())
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+func (s *S3) putMultipart(ctx context.Context, key string, f *os.File, size int64) error {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	var initiated initiateMultipartUploadResult
+	if err := xml.Unmarshal(resp, &initiated); err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("s3: starting multipart upload of %s: unexpected response", key)
+	}
+	uploadID := initiated.UploadID
+
+	// An abandoned upload keeps its parts (and their storage cost) until
+	// it is aborted, so abort on any failure. The request context may be
+	// what failed, hence a fresh one.
+	abort := func() {
+		abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, _ = s.do(abortCtx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, 0, "")
+	}
+
+	var done completeMultipartUpload
+	for n, off := 1, int64(0); off < size; n, off = n+1, off+s.cfg.PartSize {
+		length := min(s.cfg.PartSize, size-off)
+		query := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {uploadID}}
+		req, err := s.request(ctx, http.MethodPut, key, query, io.NewSectionReader(f, off, length), length, unsignedPayload)
+		if err != nil {
+			abort()
+			return err
+		}
+		res, err := s.client.Do(req)
+		if err != nil {
+			abort()
+			return fmt.Errorf("s3: uploading part %d of %s: %w", n, key, err)
+		}
+		_, err = readResponse(res, http.MethodPut, key)
+		if err != nil {
+			abort()
+			return err
+		}
+		done.Parts = append(done.Parts, completedPart{PartNumber: n, ETag: res.Header.Get("ETag")})
+	}
+
+	body, err := xml.Marshal(done)
+	if err != nil {
+		abort()
+		return err
+	}
+	resp, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body), int64(len(body)), "")
+	if err != nil {
+		abort()
+		return err
+	}
+	// CompleteMultipartUpload can fail after the 200 status has been
+	// sent, in which case the error is in the body.
+	if err := parseError(resp); err != nil {
+		abort()
+		return fmt.Errorf("s3: completing upload of %s: %w", key, err)
+	}
+	return nil
+}
+
+// Exists issues a HEAD for the object.
+func (s *S3) Exists(ctx context.Context, key string) (bool, error) {
+	req, err := s.request(ctx, http.MethodHead, s.objectKey(key), nil, nil, 0, "")
+	if err != nil {
+		return false, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return false, nil
+	case res.StatusCode/100 == 2:
+		return true, nil
+	}
+	return false, fmt.Errorf("s3: HEAD %s: %s", key, res.Status)
+}
+
+// URL presigns a GET for the object, asking the service to send it as an
+// attachment named after the file.
+func (s *S3) URL(_ context.Context, key string) (string, error) {
+	query := url.Values{
+		"response-content-disposition": {fmt.Sprintf("attachment; filename=%q", path.Base(key))},
+	}
+	return s.presign(http.MethodGet, s.objectKey(key), query, s.cfg.PresignTTL, s.now()).String(), nil
+}
+
+// Delete removes every object under the build's prefix.
+func (s *S3) Delete(ctx context.Context, buildID string) error {
+	prefix := s.objectKey(strings.Trim(buildID, "/") + "/")
+	token := ""
+	for {
+		page, err := s.list(ctx, prefix, token, 1000)
+		if err != nil {
+			return err
+		}
+		for _, obj := range page.Contents {
+			if _, err := s.do(ctx, http.MethodDelete, obj.Key, nil, nil, 0, ""); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3) list(ctx context.Context, prefix, token string, max int) (*listBucketResult, error) {
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}, "max-keys": {strconv.Itoa(max)}}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	resp, err := s.do(ctx, http.MethodGet, "", query, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	var page listBucketResult
+	if err := xml.Unmarshal(resp, &page); err != nil {
+		return nil, fmt.Errorf("s3: listing %s: %w", prefix, err)
+	}
+	return &page, nil
+}
+
+// do sends a signed request and returns the response body, or an error
+// built from the S3 error document for a non-2xx status.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, payloadHash string) ([]byte, error) {
+	req, err := s.request(ctx, method, key, query, body, size, payloadHash)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %s %s: %w", method, key, err)
+	}
+	return readResponse(res, method, key)
+}
+
+// request builds a signed request. An empty payloadHash means body is small
+// enough to hash (or empty) and is signed in full.
+func (s *S3) request(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, payloadHash string) (*http.Request, error) {
+	if payloadHash == "" {
+		var data []byte
+		if body != nil {
+			var err error
+			if data, err = io.ReadAll(body); err != nil {
+				return nil, err
+			}
+		}
+		sum := sha256.Sum256(data)
+		payloadHash = hex.EncodeToString(sum[:])
+		body, size = bytes.NewReader(data), int64(len(data))
+	}
+	if size == 0 {
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.url(key, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	s.sign(req, payloadHash, s.now())
+	return req, nil
+}
+
+func readResponse(res *http.Response, method, key string) ([]byte, error) {
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 != 2 {
+		if err := parseError(data); err != nil {
+			return nil, fmt.Errorf("s3: %s %s: %w", method, key, err)
+		}
+		return nil, fmt.Errorf("s3: %s %s: %s", method, key, res.Status)
+	}
+	return data, nil
+}
+
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// parseError returns the error an S3 error document describes, or nil when
+// data is not one.
+func parseError(data []byte) error {
+	var e s3Error
+	if xml.Unmarshal(data, &e) != nil || e.Code == "" {
+		return nil
+	}
+	return fmt.Errorf("%s: %s", e.Code, e.Message)
+}
+
+// SigV4 signing, following
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
+
+const (
+	sigAlgorithm  = "AWS4-HMAC-SHA256"
+	sigTimeFormat = "20060102T150405Z"
+)
+
+// unsignedHeaders are left out of the signature: proxies rewrite them.
+var unsignedHeaders = map[string]bool{"authorization": true, "user-agent": true}
+
+func (s *S3) sign(req *http.Request, payloadHash string, t time.Time) {
+	stamp := t.UTC().Format(sigTimeFormat)
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if k = strings.ToLower(k); !unsignedHeaders[k] {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		req.URL.RawQuery,
+		canonHeaders.String(),
+		signed,
+		payloadHash,
+	}, "\n")
+	scope, sig := s.signature(stamp, canonical)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigAlgorithm, s.cfg.AccessKey, scope, signed, sig))
+}
+
+// presign returns a URL carrying its signature in the query, valid for ttl
+// from t. Only the host header is signed, so any client can use it.
+func (s *S3) presign(method, key string, query url.Values, ttl time.Duration, t time.Time) *url.URL {
+	stamp := t.UTC().Format(sigTimeFormat)
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("X-Amz-Algorithm", sigAlgorithm)
+	q.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+s.scope(stamp))
+	q.Set("X-Amz-Date", stamp)
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+	u := s.url(key, q)
+
+	canonical := strings.Join([]string{
+		method,
+		escapePath(u.Path),
+		u.RawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	_, sig := s.signature(stamp, canonical)
+	u.RawQuery += "&X-Amz-Signature=" + sig
+	return u
+}
+
+func (s *S3) scope(stamp string) string {
+	return stamp[:8] + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// signature derives the signing key for the request date and signs the
+// canonical request with it.
+func (s *S3) signature(stamp, canonical string) (scope, sig string) {
+	scope = s.scope(stamp)
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{sigAlgorithm, stamp, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), stamp[:8])
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return scope, hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes query the way SigV4 requires: sorted by key, with
+// every byte outside the unreserved set percent-encoded (so a space is %20,
+// never +). The same string is sent, so it is always what was signed.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), query[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, escape(k, true)+"="+escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func escapePath(p string) string {
+	return escape(p, false)
+}
+
+func escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/AuroraBoot/pkg/storage"
+	"github.com/kairos-io/AuroraBoot/pkg/storage/s3fake"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("S3", func() {
+	var (
+		fake    *s3fake.Server
+		backend *storage.S3
+		dir     string
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		dir = GinkgoT().TempDir()
+		fake = s3fake.New("artifacts", "minio")
+		DeferCleanup(fake.Close)
+		var err error
+		backend, err = storage.NewS3(storage.S3Config{
+			Endpoint:  fake.URL,
+			Bucket:    "artifacts",
+			Prefix:    "auroraboot",
+			AccessKey: "minio",
+			SecretKey: "minio123",
+			PathStyle: true,
+			PartSize:  storage.MinPartSize,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	writeFile := func(name string, size int) (string, []byte) {
+		data := bytes.Repeat([]byte("0123456789abcdef"), size/16+1)[:size]
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, data, 0o644)).To(Succeed())
+		return path, data
+	}
+
+	It("rejects incomplete configuration", func() {
+		_, err := storage.NewS3(storage.S3Config{Endpoint: fake.URL, Bucket: "artifacts"})
+		Expect(err).To(MatchError(ContainSubstring("access key")))
+		_, err = storage.NewS3(storage.S3Config{Endpoint: "minio:9000", Bucket: "artifacts", AccessKey: "a", SecretKey: "b"})
+		Expect(err).To(MatchError(ContainSubstring("not an http(s) URL")))
+		_, err = storage.NewS3(storage.S3Config{Endpoint: fake.URL, Bucket: "artifacts", AccessKey: "a", SecretKey: "b", PartSize: 1024})
+		Expect(err).To(MatchError(ContainSubstring("part size")))
+	})
+
+	It("stores a small file with a single PUT under the prefix", func() {
+		path, data := writeFile("kairos.iso", 1024)
+		Expect(backend.Put(ctx, storage.Key("b1", path), path)).To(Succeed())
+
+		got, ok := fake.Object("auroraboot/b1/kairos.iso")
+		Expect(ok).To(BeTrue())
+		Expect(got).To(Equal(data))
+		Expect(fake.MultipartUploads()).To(BeZero())
+		Expect(backend.Exists(ctx, "b1/kairos.iso")).To(BeTrue())
+		Expect(backend.Exists(ctx, "b1/other.iso")).To(BeFalse())
+	})
+
+	It("uploads a file larger than a part in parts", func() {
+		path, data := writeFile("disk.raw", 2*storage.MinPartSize+123)
+		Expect(backend.Put(ctx, "b1/disk.raw", path)).To(Succeed())
+
+		got, ok := fake.Object("auroraboot/b1/disk.raw")
+		Expect(ok).To(BeTrue())
+		Expect(got).To(Equal(data))
+		Expect(fake.MultipartUploads()).To(Equal(1))
+		Expect(fake.PendingUploads()).To(BeZero())
+	})
+
+	It("aborts a multipart upload that fails", func() {
+		path, _ := writeFile("disk.raw", storage.MinPartSize+1)
+		fake.FailPart(2)
+		Expect(backend.Put(ctx, "b1/disk.raw", path)).To(MatchError(ContainSubstring("InternalError")))
+		Expect(fake.PendingUploads()).To(BeZero())
+		_, ok := fake.Object("auroraboot/b1/disk.raw")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("presigns a download URL that serves the file as an attachment", func() {
+		path, data := writeFile("kairos.iso", 64)
+		Expect(backend.Put(ctx, "b1/kairos.iso", path)).To(Succeed())
+
+		u, err := backend.URL(ctx, "b1/kairos.iso")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u).To(ContainSubstring("X-Amz-Signature="))
+
+		res, err := http.Get(u)
+		Expect(err).NotTo(HaveOccurred())
+		defer res.Body.Close()
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(res.Header.Get("Content-Disposition")).To(Equal(`attachment; filename="kairos.iso"`))
+		body, _ := io.ReadAll(res.Body)
+		Expect(body).To(Equal(data))
+	})
+
+	It("deletes every object of a build and nothing else", func() {
+		fake.PutObject("auroraboot/b1/kairos.iso", []byte("a"))
+		fake.PutObject("auroraboot/b1/SHA256SUMS", []byte("b"))
+		fake.PutObject("auroraboot/b10/kairos.iso", []byte("c"))
+
+		Expect(backend.Delete(ctx, "b1")).To(Succeed())
+		Expect(fake.Keys()).To(Equal([]string{"auroraboot/b10/kairos.iso"}))
+		Expect(backend.Delete(ctx, "missing")).To(Succeed())
+	})
+
+	It("surfaces S3 errors", func() {
+		other, err := storage.NewS3(storage.S3Config{
+			Endpoint: fake.URL, Bucket: "nope", AccessKey: "minio", SecretKey: "minio123", PathStyle: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other.Check(ctx)).To(MatchError(ContainSubstring("NoSuchBucket")))
+		Expect(backend.Check(ctx)).To(Succeed())
+	})
+})
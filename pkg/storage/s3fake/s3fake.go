@@ -0,0 +1,259 @@
+// Package s3fake is an in-memory, in-process stand-in for an S3-compatible
+// service, covering the calls storage.S3 makes: object PUT/HEAD/GET/DELETE,
+// ListObjectsV2 and multipart uploads, addressed path-style. It checks that
+// each request carries a SigV4 authorization (header or presigned query)
+// for the expected access key but does not recompute signatures; the
+// storage package tests those against the AWS examples.
+package s3fake
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server is a fake S3 service holding one bucket.
+type Server struct {
+	*httptest.Server
+	Bucket    string
+	AccessKey string
+
+	mu        sync.Mutex
+	objects   map[string][]byte
+	uploads   map[string]map[int][]byte
+	nextID    int
+	multipart int
+	failPart  int
+}
+
+// New starts a fake holding bucket, accepting requests signed with
+// accessKey. Close it when done.
+func New(bucket, accessKey string) *Server {
+	s := &Server{
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		objects:   map[string][]byte{},
+		uploads:   map[string]map[int][]byte{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Object returns the stored object under key.
+func (s *Server) Object(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	return data, ok
+}
+
+// PutObject stores data under key directly, bypassing the API.
+func (s *Server) PutObject(key string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+}
+
+// Keys returns the keys of every stored object, sorted.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MultipartUploads is the number of multipart uploads completed so far.
+func (s *Server) MultipartUploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.multipart
+}
+
+// FailPart makes every upload of part n fail with a server error, to
+// exercise the abort path. 0 turns it off.
+func (s *Server) FailPart(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failPart = n
+}
+
+// PendingUploads is the number of multipart uploads started but neither
+// completed nor aborted.
+func (s *Server) PendingUploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.uploads)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusForbidden, "AccessDenied", "missing or foreign signature")
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.Bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+	q := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.list(w, q.Get("prefix"))
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		s.nextID++
+		id := strconv.Itoa(s.nextID)
+		s.uploads[id] = map[int][]byte{}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+		}{Bucket: bucket, Key: key, UploadId: id})
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		parts, ok := s.uploads[q.Get("uploadId")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload", "unknown upload")
+			return
+		}
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		if n == s.failPart {
+			writeError(w, http.StatusInternalServerError, "InternalError", "injected failure")
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		parts[n] = data
+		w.Header().Set("ETag", etag(data))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		s.complete(w, r, key, q.Get("uploadId"))
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		delete(s.uploads, q.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		s.objects[key] = data
+		w.Header().Set("ETag", etag(data))
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		if v := q.Get("response-content-disposition"); v != "" {
+			w.Header().Set("Content-Disposition", v)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by the fake")
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	cred := r.URL.Query().Get("X-Amz-Credential")
+	if r.URL.Query().Get("X-Amz-Signature") == "" {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=") || !strings.Contains(auth, "Signature=") {
+			return false
+		}
+		cred = strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 Credential=")
+	}
+	return strings.HasPrefix(cred, s.AccessKey+"/")
+}
+
+func (s *Server) list(w http.ResponseWriter, prefix string) {
+	type content struct{ Key string }
+	var res struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		IsTruncated bool
+		Contents    []content
+	}
+	res.Name, res.Prefix = s.Bucket, prefix
+	keys := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		res.Contents = append(res.Contents, content{Key: k})
+	}
+	writeXML(w, res)
+}
+
+func (s *Server) complete(w http.ResponseWriter, r *http.Request, key, id string) {
+	parts, ok := s.uploads[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "unknown upload")
+		return
+	}
+	var req struct {
+		Parts []struct {
+			PartNumber int
+			ETag       string
+		} `xml:"Part"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Parts) == 0 {
+		writeError(w, http.StatusBadRequest, "MalformedXML", "bad CompleteMultipartUpload body")
+		return
+	}
+	var data []byte
+	for i, p := range req.Parts {
+		part, ok := parts[p.PartNumber]
+		if !ok || p.PartNumber != i+1 || p.ETag != etag(part) {
+			writeError(w, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("part %d is missing or has the wrong ETag", p.PartNumber))
+			return
+		}
+		data = append(data, part...)
+	}
+	delete(s.uploads, id)
+	s.objects[key] = data
+	s.multipart++
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Key     string
+		ETag    string
+	}{Key: key, ETag: etag(data)})
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: msg})
+}
@@ -0,0 +1,37 @@
+// Package storage is where the finished outputs of a build are kept for
+// download.
+//
+// A build always works in its directory under the server's --artifacts-dir:
+// the local builder writes there, remote uploads land there, and netboot and
+// the Redfish ISO-serve read from there. A Backend is the copy clients
+// download from. The Local backend is that same directory, so nothing moves;
+// the S3 backend pushes each finished file to an S3-compatible bucket and
+// hands out presigned URLs, so large images are fetched from the object
+// store rather than streamed through AuroraBoot.
+package storage
+
+import (
+	"context"
+	"path"
+)
+
+// Backend stores build outputs under keys of the form "<build-id>/<file>".
+type Backend interface {
+	// Put stores the file at localPath under key, replacing any object
+	// already there.
+	Put(ctx context.Context, key, localPath string) error
+	// Exists reports whether an object is stored under key.
+	Exists(ctx context.Context, key string) (bool, error)
+	// URL returns a time-limited URL the object under key can be fetched
+	// from directly, or "" when the backend has none and the file is served
+	// by AuroraBoot itself.
+	URL(ctx context.Context, key string) (string, error)
+	// Delete removes every object of a build. Removing a build that has
+	// nothing stored is not an error.
+	Delete(ctx context.Context, buildID string) error
+}
+
+// Key is the key a build's output file is stored under.
+func Key(buildID, name string) string {
+	return path.Join(buildID, path.Base(name))
+}
@@ -18,13 +18,21 @@ var ErrCommandNotFound = errors.New("command not found")
 // "waiting for capacity" and requeue instead of treating it as an error.
 var ErrNoClaimCapacity = errors.New("no unclaimed node available in group")
 
+// ErrChannelMoved is returned by ChannelStore.Move when the channel was
+// moved by someone else first.
+var ErrChannelMoved = errors.New("channel was moved concurrently")
+
 // NodeGroup represents a logical group/environment for nodes (e.g., "production", "staging").
 type NodeGroup struct {
-	ID          string    `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"uniqueIndex"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          string `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"uniqueIndex"`
+	Description string `json:"description"`
+	// FollowChannelID makes the group track a Channel: every promotion or
+	// rollback of the channel queues an upgrade to it on each node in the
+	// group. Empty follows nothing.
+	FollowChannelID string    `json:"followChannelId,omitempty" gorm:"index"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
 }
 
 // ManagedNode represents a Kairos node managed by auroraboot.
@@ -233,6 +241,9 @@ type CommandStore interface {
 	// instead of silently succeeding on a foreign or missing command.
 	UpdateStatusForNode(ctx context.Context, id string, nodeID string, phase string, result string) error
 	ListByNode(ctx context.Context, nodeID string) ([]*NodeCommand, error)
+	// UpdateArgs replaces a command's arguments. Delivery uses it to record
+	// what a channel: upgrade source resolved to.
+	UpdateArgs(ctx context.Context, id string, args map[string]string) error
 	Delete(ctx context.Context, id string) error
 	DeleteTerminal(ctx context.Context, nodeID string) error
 }
@@ -260,6 +271,16 @@ type ArtifactRecord struct {
 	GCE                     bool     `json:"gce"`
 	VHD                     bool     `json:"vhd"`
 	MAAS                    bool     `json:"maas"`
+	QCOW2                   bool     `json:"qcow2"`
+	QCOW2Compress           bool     `json:"qcow2Compress,omitempty"`
+	VHDDynamic              bool     `json:"vhdDynamic,omitempty"`
+	VHDX                    bool     `json:"vhdx"`
+	VMDK                    bool     `json:"vmdk"`
+	OVA                     bool     `json:"ova"`
+	OVACPUs                 int      `json:"ovaCpus,omitempty"`
+	OVAMemoryMB             int      `json:"ovaMemoryMB,omitempty"`
+	OVAUserDataProperty     bool     `json:"ovaUserDataProperty,omitempty"`
+	Compression             string   `json:"compression,omitempty"`
 	UKI                     bool     `json:"uki"`
 	KairosInitImage         string   `json:"kairosInitImage,omitempty"`
 	AutoInstall             bool     `json:"autoInstall"`
@@ -276,8 +297,53 @@ type ArtifactRecord struct {
 	TargetGroupID           string   `json:"targetGroupId,omitempty"`
 	ContainerImage          string   `json:"containerImage,omitempty"`
 	OverlayRootfs           string   `json:"overlayRootfs,omitempty"`
-	ArtifactFiles           []string `json:"artifacts" gorm:"serializer:json"`
-	Logs                    string   `json:"-" gorm:"type:text"`
+	// Git is the repository the build's Dockerfile and overlay came from,
+	// nil for builds without one.
+	Git           *GitSource `json:"git,omitempty" gorm:"serializer:json"`
+	ArtifactFiles []string   `json:"artifacts" gorm:"serializer:json"`
+	// Step is the deployer step a Building artifact is at, and StepProgress
+	// how far along that step is in percent when it reports its bytes
+	// (release downloads, image pulls). Both are left as they were when the
+	// build ends.
+	Step         string `json:"step,omitempty"`
+	StepProgress int    `json:"stepProgress,omitempty"`
+	// The digests below pin what BaseImage, KairosInitImage and
+	// ContainerImage resolved to when the build ran. The tags can move
+	// afterwards; "clone exactly" rebuilds from these instead. Empty when the
+	// builder could not resolve them (local-only images, the operator
+	// backend).
+	BaseImageDigest       string `json:"baseImageDigest,omitempty"`
+	KairosInitImageDigest string `json:"kairosInitImageDigest,omitempty"`
+	ContainerImageDigest  string `json:"containerImageDigest,omitempty"`
+	// SBOM records that the build was asked for an SBOM in SBOMFormat.
+	// Vulnerabilities summarises the offline advisory match run alongside
+	// it; nil until the build finishes, and stays nil when the builder has
+	// no advisory database configured.
+	SBOM            bool                  `json:"sbom,omitempty"`
+	SBOMFormat      string                `json:"sbomFormat,omitempty"`
+	Vulnerabilities *VulnerabilitySummary `json:"vulnerabilities,omitempty" gorm:"serializer:json"`
+	// ManifestKeySetID is the SecureBoot key set whose db.key signs the
+	// SHA256SUMS of the outputs. Empty signs with the server's manifest key,
+	// if one is configured. Kept on the record because remote backends ship
+	// their outputs back after Create returns, and the Upload handler signs
+	// the manifest when it arrives.
+	ManifestKeySetID string `json:"manifestKeySetId,omitempty"`
+	// Publications lists every push of this build to an OCI registry,
+	// oldest first.
+	Publications []Publication `json:"publications,omitempty" gorm:"serializer:json"`
+	// BuildSetID links a matrix child to its BuildSet. Written only by
+	// BuildSetStore.Attach; the full-row ArtifactStore.Update leaves it alone
+	// so a builder's final Save cannot unlink a child it never knew about.
+	BuildSetID string `json:"buildSetId,omitempty" gorm:"index"`
+	// TemplateID links a build to the BuildTemplate it was started from.
+	// Written only by BuildTemplateStore.Attach, for the same reason.
+	TemplateID string `json:"templateId,omitempty" gorm:"index"`
+	// Spec is the JSON of the create request the build was started from,
+	// kept so it can be rebuilt (e.g. when its Git branch moves). Like
+	// BuildSet.Spec it may carry a provisioning password and is encrypted
+	// at rest. Written only by ArtifactSpecStore.SetSpec.
+	Spec string `json:"-" gorm:"type:text"`
+	Logs string `json:"-" gorm:"type:text"`
 	// UploadToken holds the sha256 hex digest of the per-build bearer the
 	// operator backend's exporter Job uses to PUT /api/v1/artifacts/:id/upload/:file.
 	// The plaintext token is minted by the handler on Create, injected into
@@ -291,6 +357,50 @@ type ArtifactRecord struct {
 	UpdatedAt   time.Time `json:"updatedAt"`
 }
 
+// VulnerabilitySummary is the per-severity count of advisories that matched
+// an artifact's installed packages, and which advisory database produced it.
+// The full list of findings stays in the report file next to the SBOM.
+type VulnerabilitySummary struct {
+	Database  string    `json:"database"`
+	Critical  int       `json:"critical"`
+	High      int       `json:"high"`
+	Medium    int       `json:"medium"`
+	Low       int       `json:"low"`
+	Unknown   int       `json:"unknown"`
+	Total     int       `json:"total"`
+	ScannedAt time.Time `json:"scannedAt"`
+}
+
+// Publication records one push of an artifact to a registry. Image and
+// Files are digest references (repo@sha256:...), so they keep naming what
+// was pushed after the tags move on.
+type Publication struct {
+	RegistryID string `json:"registryId"`
+	// Image is the OS container image, consumable by
+	// `kairos-agent upgrade --source oci:<Image>`. Empty when it was not
+	// pushed.
+	Image string `json:"image,omitempty"`
+	// Files is the OCI artifact holding the ISO, raw disk and other
+	// outputs. Empty when none were pushed.
+	Files       string    `json:"files,omitempty"`
+	Tags        []string  `json:"tags"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+// GitSource is where a build fetched its Dockerfile and overlay from.
+type GitSource struct {
+	URL     string `json:"url"`
+	Ref     string `json:"ref,omitempty"`
+	Subpath string `json:"subpath,omitempty"`
+	// Username and Secret authenticate the clone: Secret names the entry
+	// in the SecretStore holding the password or token.
+	Username string `json:"username,omitempty"`
+	Secret   string `json:"secret,omitempty"`
+	// Commit is what Ref resolved to when the build ran. Empty until the
+	// build finishes.
+	Commit string `json:"commit,omitempty"`
+}
+
 // Artifact phases.
 const (
 	ArtifactPending  = "Pending"
@@ -318,16 +428,189 @@ type ArtifactStore interface {
 	// record at entry, then writes back a stale phase/message alongside the
 	// new file list. Column-scoped writes converge cleanly.
 	UpdateFiles(ctx context.Context, id string, files []string) error
+	// UpdateStep updates only the step and step_progress columns for id. The
+	// builder calls it for each progress event of the deployer, concurrently
+	// with AppendLog, so it must not rewrite the row the way Update does.
+	UpdateStep(ctx context.Context, id, step string, progress int) error
 	// ClearUploadToken zeroes the upload_token column for id. watchCRPhase
 	// calls this on the terminal transition so a leaked token cannot be
 	// used to overwrite artifacts of a finished build.
 	ClearUploadToken(ctx context.Context, id string) error
+	// SetUploadToken writes only the upload_token column for id. The create
+	// handler calls it when the builder wrote the row itself, or when a
+	// retried build reuses it, so the row holds the digest of the token the
+	// build was started with.
+	SetUploadToken(ctx context.Context, id, token string) error
 	Delete(ctx context.Context, id string) error
 	DeleteByPhase(ctx context.Context, phase string) error
 	GetLogs(ctx context.Context, id string) (string, error)
 	AppendLog(ctx context.Context, id string, text string) error
 }
 
+// ArtifactSpecStore keeps the request each artifact was created from. It is
+// separate from ArtifactStore because only the create handler writes it, and
+// the builders' full-row Updates must leave it alone.
+type ArtifactSpecStore interface {
+	SetSpec(ctx context.Context, id, spec string) error
+	// GetSpec returns "" for artifacts created before specs were kept.
+	GetSpec(ctx context.Context, id string) (string, error)
+}
+
+// BuildSet is one "build matrix" request: a list of image sources crossed
+// with a list of output selections, expanded into one child ArtifactRecord
+// per combination. The set itself carries no phase; its status is aggregated
+// from the children on read so it can never drift from them.
+//
+// Spec is the JSON of the original matrix request, kept so the whole set can
+// be cloned. It may carry a provisioning password, so the GORM store encrypts
+// it at rest with the same cipher as BMC credentials.
+type BuildSet struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name"`
+	Spec      string    `json:"-" gorm:"type:text"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// BuildSetStore manages build sets and the link from their child artifacts.
+type BuildSetStore interface {
+	Create(ctx context.Context, set *BuildSet) error
+	GetByID(ctx context.Context, id string) (*BuildSet, error)
+	List(ctx context.Context) ([]*BuildSet, error)
+	// Delete removes the set. Its children stay as ordinary artifacts.
+	Delete(ctx context.Context, id string) error
+	// Attach links artifactID to the set. It is a column-scoped write so it
+	// converges with a builder concurrently saving the same record.
+	Attach(ctx context.Context, setID, artifactID string) error
+	// Children returns the set's artifacts, oldest first, without logs.
+	Children(ctx context.Context, setID string) ([]*ArtifactRecord, error)
+}
+
+// BuildTemplate is a vetted, reusable build recipe: a partial artifact create
+// request (source, outputs, signing key set, provisioning, cloud-config
+// fragment) whose string values may reference Parameters as ${{ name }}.
+// Builds started from it are linked back through ArtifactRecord.TemplateID.
+//
+// Spec is the JSON of that partial request. Like BuildSet.Spec it may carry
+// a provisioning password and is encrypted at rest.
+type BuildTemplate struct {
+	ID          string              `json:"id" gorm:"primaryKey"`
+	Name        string              `json:"name" gorm:"uniqueIndex"`
+	Description string              `json:"description,omitempty"`
+	Parameters  []TemplateParameter `json:"parameters,omitempty" gorm:"serializer:json"`
+	Spec        string              `json:"-" gorm:"type:text"`
+	CreatedAt   time.Time           `json:"createdAt"`
+	UpdatedAt   time.Time           `json:"updatedAt"`
+}
+
+// TemplateParameter is one placeholder a BuildTemplate's spec may reference.
+// A build must supply a value for a Required parameter; others fall back to
+// Default.
+type TemplateParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// BuildTemplateStore manages build templates and the link from the artifacts
+// built from them.
+type BuildTemplateStore interface {
+	Create(ctx context.Context, tpl *BuildTemplate) error
+	GetByID(ctx context.Context, id string) (*BuildTemplate, error)
+	GetByName(ctx context.Context, name string) (*BuildTemplate, error)
+	// List returns every template by name, without specs.
+	List(ctx context.Context) ([]*BuildTemplate, error)
+	Update(ctx context.Context, tpl *BuildTemplate) error
+	// Delete removes the template. Artifacts built from it are kept.
+	Delete(ctx context.Context, id string) error
+	// Attach links artifactID to the template it was built from. Like
+	// BuildSetStore.Attach it is a column-scoped write.
+	Attach(ctx context.Context, templateID, artifactID string) error
+}
+
+// BuildWorker is a remote build host that registered with `auroraboot worker`.
+// Workers pull queued builds (BuildJob rows) over the REST API instead of the
+// server pushing work to them, so a worker only needs outbound reachability to
+// AuroraBoot — it can sit behind NAT on a different architecture.
+//
+// APIKeyHash holds the sha256 hex digest of the bearer returned from
+// POST /api/v1/workers/register, mirroring ArtifactRecord.UploadToken: the
+// plaintext is only ever handed to the worker, so DB read access does not
+// surface a credential that can lease builds (and with them, the per-build
+// upload tokens and injected cloud-configs).
+type BuildWorker struct {
+	ID      string `json:"id" gorm:"primaryKey"`
+	Name    string `json:"name"`
+	Arch    string `json:"arch" gorm:"index"`
+	Version string `json:"version,omitempty"`
+	// CurrentBuildID is the build the worker last reported working on in its
+	// heartbeat; empty when idle.
+	CurrentBuildID string     `json:"currentBuildId,omitempty"`
+	LastHeartbeat  *time.Time `json:"lastHeartbeat"`
+	APIKeyHash     string     `json:"-" gorm:"uniqueIndex"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// BuildWorkerStore manages registered remote build workers.
+type BuildWorkerStore interface {
+	Register(ctx context.Context, w *BuildWorker) error
+	GetByID(ctx context.Context, id string) (*BuildWorker, error)
+	GetByAPIKeyHash(ctx context.Context, hash string) (*BuildWorker, error)
+	List(ctx context.Context) ([]*BuildWorker, error)
+	// Heartbeat stamps LastHeartbeat and records the build the worker is
+	// currently running ("" when idle).
+	Heartbeat(ctx context.Context, id, currentBuildID string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// BuildJob is a build queued for the remote worker backend. Its ID is the
+// ArtifactRecord ID it produces; the record carries the user-visible phase
+// while the job carries the scheduling state (architecture, lease holder,
+// lease deadline). The row is deleted once the build reaches a terminal phase
+// or is cancelled, so "has a job row" means "still owned by the queue".
+//
+// Spec is the JSON-encoded builder.BuildOptions the worker replays. It holds
+// the per-build upload token and the injected cloud-config (registration
+// token, default node password), so the worker backend encrypts it with the
+// server's data key before it reaches the store; the JSON tag stays "-" so it
+// is never serialized to clients.
+type BuildJob struct {
+	ID   string `json:"id" gorm:"primaryKey"`
+	Arch string `json:"arch" gorm:"index"`
+	Spec string `json:"-" gorm:"type:text"`
+	// WorkerID is the worker holding the lease; empty while queued.
+	WorkerID string `json:"workerId,omitempty" gorm:"index"`
+	// LeaseExpiresAt is when an unrenewed lease lapses and the job is handed
+	// back to the queue. Nil while queued.
+	LeaseExpiresAt *time.Time `json:"leaseExpiresAt,omitempty"`
+	// Attempts counts how many times the job has been leased, so a build that
+	// keeps killing its workers is visible to the operator.
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// BuildJobStore manages the remote worker build queue.
+type BuildJobStore interface {
+	Enqueue(ctx context.Context, job *BuildJob) error
+	GetByID(ctx context.Context, id string) (*BuildJob, error)
+	List(ctx context.Context) ([]*BuildJob, error)
+	Delete(ctx context.Context, id string) error
+	// LeaseNext atomically hands the oldest queued job for arch to workerID,
+	// valid until `until`. It returns (nil, nil) when nothing is queued for
+	// that architecture. Concurrent callers never receive the same job.
+	LeaseNext(ctx context.Context, workerID, arch string, until time.Time) (*BuildJob, error)
+	// RenewLease extends the lease on id to `until`, but only while workerID
+	// still holds it. false means the lease was lost (expired and re-queued,
+	// or the job was cancelled) and the worker must abandon the build.
+	RenewLease(ctx context.Context, id, workerID string, until time.Time) (bool, error)
+	// RequeueExpired returns every leased job whose deadline is before now to
+	// the queue and reports the ids it re-queued.
+	RequeueExpired(ctx context.Context, now time.Time) ([]string, error)
+}
+
 // SecureBootKeySet tracks a named set of SecureBoot keys on the filesystem.
 type SecureBootKeySet struct {
 	ID               string    `json:"id" gorm:"primaryKey"`
@@ -413,6 +696,92 @@ type BMCTargetStore interface {
 	Delete(ctx context.Context, id string) error
 }
 
+// Channel is a named pointer to an artifact, such as "ubuntu-k3s/stable".
+// Upgrades can name a channel (source "channel:<name>") instead of an
+// image, and resolve to whatever it points at when the command is
+// delivered. Every move of the pointer is kept as a ChannelEntry.
+type Channel struct {
+	ID          string `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"uniqueIndex"`
+	Description string `json:"description,omitempty"`
+	// ArtifactID is the artifact the channel currently points at. Empty
+	// until the first promotion.
+	ArtifactID string    `json:"artifactId,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Channel history actions.
+const (
+	ChannelPromote  = "promote"
+	ChannelRollback = "rollback"
+)
+
+// ChannelEntry records one move of a channel.
+type ChannelEntry struct {
+	ID        string `json:"id" gorm:"primaryKey"`
+	ChannelID string `json:"channelId" gorm:"index"`
+	Action    string `json:"action" enums:"promote,rollback"`
+	// ArtifactID is the artifact the channel moved to, PreviousArtifactID
+	// the one it pointed at before.
+	ArtifactID         string `json:"artifactId"`
+	PreviousArtifactID string `json:"previousArtifactId,omitempty"`
+	// FromChannel names the channel the artifact was promoted from, when
+	// it was.
+	FromChannel string    `json:"fromChannel,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ChannelStore manages channels and their history.
+type ChannelStore interface {
+	Create(ctx context.Context, ch *Channel) error
+	GetByID(ctx context.Context, id string) (*Channel, error)
+	GetByName(ctx context.Context, name string) (*Channel, error)
+	List(ctx context.Context) ([]*Channel, error)
+	// Move points the channel at entry.ArtifactID and appends entry to its
+	// history in one transaction, filling in entry.PreviousArtifactID. It
+	// fails with ErrChannelMoved when the channel no longer points at
+	// expect, so two concurrent promotions cannot both record the same
+	// previous artifact.
+	Move(ctx context.Context, channelID, expect string, entry *ChannelEntry) error
+	// History returns the channel's entries, newest first.
+	History(ctx context.Context, channelID string) ([]*ChannelEntry, error)
+	// Delete removes the channel and its history, and unsets it on every
+	// group following it.
+	Delete(ctx context.Context, id string) error
+}
+
+// Registry stores an OCI registry artifacts can be published to, and the
+// credentials to push with.
+type Registry struct {
+	ID   string `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+	// Host is the registry host, with a port if it is not the default
+	// (e.g. "ghcr.io", "registry.lan:5000").
+	Host string `json:"host"`
+	// Namespace is prefixed to every repository pushed here, e.g. an
+	// organisation. Optional.
+	Namespace string `json:"namespace,omitempty"`
+	Username  string `json:"username,omitempty"`
+	// Password is the password or token for Username. Encrypted at rest
+	// and never returned to clients.
+	Password string `json:"-"`
+	// Insecure allows plain HTTP, for lab registries without TLS.
+	Insecure  bool      `json:"insecure,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// RegistryStore manages publishing registries.
+type RegistryStore interface {
+	Create(ctx context.Context, reg *Registry) error
+	GetByID(ctx context.Context, id string) (*Registry, error)
+	List(ctx context.Context) ([]*Registry, error)
+	Update(ctx context.Context, reg *Registry) error
+	Delete(ctx context.Context, id string) error
+}
+
 // Deployment tracks a deployment operation.
 type Deployment struct {
 	ID          string     `json:"id" gorm:"primaryKey"`
@@ -506,3 +875,22 @@ type SettingsStore interface {
 	// GetAll returns every setting as a key→value map.
 	GetAll(ctx context.Context) (map[string]string, error)
 }
+
+// Secret is a named credential, such as the token a Git source is cloned
+// with. The value is encrypted at rest and never returned to clients.
+type Secret struct {
+	Name        string    `json:"name" gorm:"primaryKey"`
+	Value       string    `json:"-" gorm:"type:text"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SecretStore manages named secrets.
+type SecretStore interface {
+	// Set creates the secret or replaces its value and description.
+	Set(ctx context.Context, secret *Secret) error
+	Get(ctx context.Context, name string) (*Secret, error)
+	List(ctx context.Context) ([]*Secret, error)
+	Delete(ctx context.Context, name string) error
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"io"
@@ -140,7 +141,8 @@ func IsDir(fs sdkFs.KairosFS, path string) (bool, error) {
 // ConcatFiles Copies source files to target file using Fs interface.
 // Source files are concatenated into target file in the given order.
 // If target is a directory source is copied into that directory using
-// 1st source name file.
+// 1st source name file. Runs of zeroes are skipped rather than written,
+// so the target is sparse wherever its sources read back as zeroes.
 func ConcatFiles(fs sdkFs.KairosFS, sources []string, target string) (err error) {
 	if len(sources) == 0 {
 		return fmt.Errorf("Empty sources list")
@@ -162,12 +164,14 @@ func ConcatFiles(fs sdkFs.KairosFS, sources []string, target string) (err error)
 	}()
 
 	var sourceFile iofs.File
+	var size, n int64
 	for _, source := range sources {
 		sourceFile, err = fs.Open(source)
 		if err != nil {
 			break
 		}
-		_, err = io.Copy(targetFile, sourceFile)
+		n, err = copySparse(targetFile, sourceFile)
+		size += n
 		if err != nil {
 			break
 		}
@@ -176,10 +180,60 @@ func ConcatFiles(fs sdkFs.KairosFS, sources []string, target string) (err error)
 			break
 		}
 	}
+	if err == nil {
+		// A trailing hole was seeked over, not written.
+		err = targetFile.Truncate(size)
+	}
 
 	return err
 }
 
+// sparseBlockSize is the granularity at which copySparse looks for zeroes.
+const sparseBlockSize = 4096
+
+// copySparse copies src to dst from dst's current offset, seeking over
+// blocks of zeroes instead of writing them. It returns the number of bytes
+// read from src; dst has to be truncated to its final size afterwards in
+// case it ends with a hole.
+func copySparse(dst *os.File, src io.Reader) (int64, error) {
+	buf := make([]byte, 1<<20)
+	zero := make([]byte, sparseBlockSize)
+	isZero := func(b []byte) bool { return bytes.Equal(b, zero[:len(b)]) }
+	var copied int64
+	for {
+		n, rerr := io.ReadFull(src, buf)
+		var err error
+		// Seek over or write whole runs of zero or data blocks at a time.
+		for off := 0; off < n; {
+			end := min(off+sparseBlockSize, n)
+			hole := isZero(buf[off:end])
+			for end < n {
+				next := min(end+sparseBlockSize, n)
+				if isZero(buf[end:next]) != hole {
+					break
+				}
+				end = next
+			}
+			if hole {
+				_, err = dst.Seek(int64(end-off), io.SeekCurrent)
+			} else {
+				_, err = dst.Write(buf[off:end])
+			}
+			if err != nil {
+				return copied, err
+			}
+			off = end
+		}
+		copied += int64(n)
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			return copied, nil
+		}
+		if rerr != nil {
+			return copied, rerr
+		}
+	}
+}
+
 // DirSize returns the accumulated size of all files in folder
 func DirSize(fs sdkFs.KairosFS, path string) (int64, error) {
 	var size int64
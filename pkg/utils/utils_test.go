@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/kairos-io/AuroraBoot/pkg/constants"
 	"github.com/kairos-io/AuroraBoot/pkg/utils"
@@ -97,6 +98,29 @@ var _ = Describe("Utils", Label("utils"), func() {
 			Expect(err).NotTo(BeNil())
 		})
 	})
+	Describe("ConcatFiles", Label("ConcatFiles"), func() {
+		It("Leaves the zeroes of its sources as holes in the target", func() {
+			Expect(utils.MkdirAll(fs, "/some", constants.DirPerm)).To(Succeed())
+			data := make([]byte, 8*1024*1024)
+			copy(data[100:], "partition header")
+			copy(data[5*1024*1024:], "filesystem")
+			Expect(fs.WriteFile("/some/part1", data, constants.FilePerm)).To(Succeed())
+			Expect(fs.WriteFile("/some/part2", []byte("last"), constants.FilePerm)).To(Succeed())
+			// A source that ends with zeroes leaves a hole at the end of the target.
+			Expect(fs.WriteFile("/some/part3", make([]byte, 1024*1024), constants.FilePerm)).To(Succeed())
+
+			Expect(utils.ConcatFiles(fs, []string{"/some/part1", "/some/part2", "/some/part3"}, "/some/disk")).To(Succeed())
+			got, err := fs.ReadFile("/some/disk")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got).To(Equal(append(append(data, "last"...), make([]byte, 1024*1024)...)))
+
+			fi, err := fs.Stat("/some/disk")
+			Expect(err).ToNot(HaveOccurred())
+			st, ok := fi.Sys().(*syscall.Stat_t)
+			Expect(ok).To(BeTrue())
+			Expect(st.Blocks * 512).To(BeNumerically("<", 1024*1024))
+		})
+	})
 	Describe("CreateDirStructure", Label("CreateDirStructure"), func() {
 		It("Creates essential directories", func() {
 			dirList := []string{"sys", "proc", "dev", "tmp", "boot", "usr/local", "oem"}
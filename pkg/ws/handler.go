@@ -63,6 +63,11 @@ type AgentHandler struct {
 	// BaseCtx is the server lifecycle context the finalize goroutine derives from
 	// (cancelled on shutdown). Nil means context.Background().
 	BaseCtx context.Context
+	// Resolve, when set, runs on every pending command claimed on connect,
+	// before it is sent; commands it returns false for are not sent. It is how
+	// channel: upgrade sources are resolved to the channel's current artifact
+	// at delivery time, the same hook the REST poll uses.
+	Resolve func(ctx context.Context, cmd *store.NodeCommand) bool
 }
 
 // triggerFinalize fires the auto eject-on-phone-home hook off the WS read loop so
@@ -225,6 +230,9 @@ func (h *AgentHandler) sendPendingCommands(nodeID string, conn *wsConn) {
 		if !claimed {
 			continue
 		}
+		if h.Resolve != nil && !h.Resolve(ctx, cmd) {
+			continue
+		}
 
 		cmdMsg := commandData{
 			ID:      cmd.ID,
@@ -10,6 +10,10 @@ export interface Artifact {
   saved?: boolean;
   phase: string;
   message: string;
+  // The deployer step a Building artifact is at, and its percent when the
+  // step reports it (downloads, image pulls).
+  step?: string;
+  stepProgress?: number;
   baseImage: string;
   kairosVersion: string;
   model: string;
@@ -24,6 +28,16 @@ export interface Artifact {
   gce: boolean;
   vhd: boolean;
   maas: boolean;
+  qcow2: boolean;
+  qcow2Compress?: boolean;
+  vhdDynamic?: boolean;
+  vhdx: boolean;
+  vmdk: boolean;
+  ova: boolean;
+  ovaCpus?: number;
+  ovaMemoryMB?: number;
+  ovaUserDataProperty?: boolean;
+  compression?: OutputCompression;
   uki: boolean;
   fips: boolean;
   trustedBoot: boolean;
@@ -41,11 +55,86 @@ export interface Artifact {
   kubernetesEnabled?: boolean;
   targetGroupId?: string;
   containerImage?: string;
+  baseImageDigest?: string;
+  kairosInitImageDigest?: string;
+  containerImageDigest?: string;
+  sbom?: boolean;
+  sbomFormat?: SBOMFormat;
+  vulnerabilities?: VulnerabilitySummary;
+  manifestKeySetId?: string;
+  publications?: Publication[];
+  git?: GitSource;
   artifacts: string[];
   createdAt: string;
   updatedAt: string;
 }
 
+/**
+ * A repository the build takes its Dockerfile and overlay/ from. `secret`
+ * names the secret holding the token; `commit` is set on artifacts once the
+ * build has checked it out.
+ */
+export interface GitSource {
+  url: string;
+  ref?: string;
+  subpath?: string;
+  username?: string;
+  secret?: string;
+  commit?: string;
+}
+
+/** One push of an artifact to a registry; image and files are digest refs. */
+export interface Publication {
+  registryId: string;
+  image?: string;
+  files?: string;
+  tags: string[];
+  publishedAt: string;
+}
+
+export interface PublishRequest {
+  registryId: string;
+  repository: string;
+  tags: string[];
+  image: boolean;
+  outputs: boolean;
+  files?: string[];
+}
+
+export type SBOMFormat = "spdx-json" | "cyclonedx-json";
+
+export type OutputCompression = "zstd" | "xz" | "gzip";
+
+/** Per-severity count of advisories matching an artifact's packages. */
+export interface VulnerabilitySummary {
+  database: string;
+  critical: number;
+  high: number;
+  medium: number;
+  low: number;
+  unknown: number;
+  total: number;
+  scannedAt: string;
+}
+
+export interface VulnerabilityFinding {
+  id: string;
+  aliases?: string[];
+  package: string;
+  version: string;
+  fixedVersion?: string;
+  severity: "CRITICAL" | "HIGH" | "MEDIUM" | "LOW" | "UNKNOWN";
+  summary?: string;
+}
+
+export interface ArtifactSBOM {
+  format: SBOMFormat;
+  /** Name of the SBOM document, downloadable like any other output. */
+  file: string;
+  vulnerabilities?: VulnerabilitySummary;
+  findings?: VulnerabilityFinding[];
+}
+
 export interface CreateArtifactOutputs {
   iso: boolean;
   cloudImage: boolean;
@@ -55,9 +144,29 @@ export interface CreateArtifactOutputs {
   gce: boolean;
   vhd: boolean;
   maas: boolean;
+  /** A qcow2 image of the raw disk; qcow2Compress deflates its clusters. */
+  qcow2?: boolean;
+  qcow2Compress?: boolean;
+  /** A sparse VHD instead of the fixed one Azure needs, and a Hyper-V Gen2 VHDX. */
+  vhdDynamic?: boolean;
+  vhdx?: boolean;
+  /**
+   * A stream-optimized VMDK for vSphere, and an OVA of an EFI virtual machine
+   * sized by ovaCpus and ovaMemoryMB (2 CPUs and 4096 MiB when unset).
+   */
+  vmdk?: boolean;
+  ova?: boolean;
+  ovaCpus?: number;
+  ovaMemoryMB?: number;
+  ovaUserDataProperty?: boolean;
   uki: boolean;
   fips: boolean;
   trustedBoot: boolean;
+  /** Add an SBOM of the image's installed packages to the outputs. */
+  sbom?: boolean;
+  sbomFormat?: SBOMFormat;
+  /** Replace the raw disk, ISO and VHD outputs with compressed copies. */
+  compression?: OutputCompression;
 }
 
 export interface CreateArtifactSigning {
@@ -67,6 +176,8 @@ export interface CreateArtifactSigning {
   ukiTpmPcrKey: string;
   ukiPublicKeysDir: string;
   ukiSecureBootEnroll: string;
+  /** SecureBoot key set whose db key signs SHA256SUMS; empty uses the server's manifest key. */
+  manifestKeySetId?: string;
 }
 
 export interface CreateArtifactProvisioning {
@@ -107,6 +218,7 @@ export interface CreateArtifactInput {
   hadronExtra?: string;
   overlayRootfs?: string;
   kairosInitImage?: string;
+  git?: GitSource;
   outputs: CreateArtifactOutputs;
   signing: CreateArtifactSigning;
   provisioning: CreateArtifactProvisioning;
@@ -197,15 +309,36 @@ export function getArtifactLogs(id: string): Promise<string> {
   return apiFetchText(`/api/v1/artifacts/${id}/logs`);
 }
 
+export function getArtifactSBOM(id: string): Promise<ArtifactSBOM> {
+  return apiFetch(`/api/v1/artifacts/${id}/sbom`);
+}
+
 export function cancelArtifact(id: string): Promise<void> {
   return apiFetch(`/api/v1/artifacts/${id}/cancel`, { method: "POST" });
 }
 
+// retryArtifact reruns a failed build in place, skipping the steps it
+// completed.
+export function retryArtifact(id: string): Promise<void> {
+  return apiFetch(`/api/v1/artifacts/${id}/retry`, { method: "POST" });
+}
+
 export function artifactDownloadUrl(id: string, filename: string): string {
   const token = localStorage.getItem("auroraboot_token") || "";
   return `/api/v1/artifacts/${encodeURIComponent(id)}/download/${encodeURIComponent(filename)}?token=${encodeURIComponent(token)}`;
 }
 
+// pinImage rewrites an image reference to address digest instead of its tag,
+// mirroring builder.PinnedImageRef on the server. A ":" after the last "/"
+// is a tag; one before it is a registry port.
+export function pinImage(ref: string | undefined, digest: string | undefined): string {
+  if (!ref || !digest) return ref || "";
+  let repo = ref.split("@")[0];
+  const colon = repo.lastIndexOf(":");
+  if (colon > repo.lastIndexOf("/")) repo = repo.slice(0, colon);
+  return `${repo}@${digest}`;
+}
+
 export function updateArtifact(
   id: string,
   patch: { name?: string; saved?: boolean }
@@ -216,6 +349,13 @@ export function updateArtifact(
   });
 }
 
+export function publishArtifact(id: string, req: PublishRequest): Promise<Publication> {
+  return apiFetch<Publication>(`/api/v1/artifacts/${id}/publish`, {
+    method: "POST",
+    body: JSON.stringify(req),
+  });
+}
+
 export function deleteArtifact(id: string): Promise<void> {
   return apiFetch(`/api/v1/artifacts/${id}`, { method: "DELETE" });
 }
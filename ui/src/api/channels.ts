@@ -0,0 +1,65 @@
+import { apiFetch } from "./client";
+
+// Channel is a named pointer to an artifact, such as "ubuntu-k3s/stable".
+// Upgrades with source "channel:<name>" install whatever it points at when
+// the node receives the command.
+export interface Channel {
+  id: string;
+  name: string;
+  description?: string;
+  artifactId?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface ChannelEntry {
+  id: string;
+  channelId: string;
+  action: "promote" | "rollback";
+  artifactId: string;
+  previousArtifactId?: string;
+  fromChannel?: string;
+  note?: string;
+  createdAt: string;
+}
+
+// MoveChannelRequest: promote takes exactly one of artifactId and
+// fromChannel; rollback takes an optional artifactId.
+export interface MoveChannelRequest {
+  artifactId?: string;
+  fromChannel?: string;
+  note?: string;
+}
+
+export interface ChannelMove {
+  channel: Channel;
+  entry: ChannelEntry;
+  // queued counts the upgrades queued for groups following the channel.
+  queued: number;
+}
+
+export const listChannels = () => apiFetch<Channel[]>("/api/v1/channels");
+
+export const createChannel = (name: string, description?: string) =>
+  apiFetch<Channel>("/api/v1/channels", {
+    method: "POST",
+    body: JSON.stringify({ name, description }),
+  });
+
+export const deleteChannel = (id: string) =>
+  apiFetch(`/api/v1/channels/${id}`, { method: "DELETE" });
+
+export const getChannelHistory = (id: string) =>
+  apiFetch<ChannelEntry[]>(`/api/v1/channels/${id}/history`);
+
+export const promoteChannel = (id: string, req: MoveChannelRequest) =>
+  apiFetch<ChannelMove>(`/api/v1/channels/${id}/promote`, {
+    method: "POST",
+    body: JSON.stringify(req),
+  });
+
+export const rollbackChannel = (id: string, req: MoveChannelRequest = {}) =>
+  apiFetch<ChannelMove>(`/api/v1/channels/${id}/rollback`, {
+    method: "POST",
+    body: JSON.stringify(req),
+  });
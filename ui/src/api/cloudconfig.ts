@@ -0,0 +1,25 @@
+import { apiFetch } from "./client";
+
+export interface CloudConfigIssue {
+  severity: "error" | "warning";
+  line?: number;
+  column?: number;
+  path?: string;
+  message: string;
+}
+
+// CloudConfigReport is the server's lint of a cloud-config. A build is
+// refused when errors is not empty; warnings are advisory.
+export interface CloudConfigReport {
+  valid: boolean;
+  errors: CloudConfigIssue[];
+  warnings: CloudConfigIssue[];
+}
+
+// validateCloudConfig lints a cloud-config. fragment marks the extra YAML of
+// a build, which is merged into the generated config and needs no header.
+export const validateCloudConfig = (cloudConfig: string, fragment = false) =>
+  apiFetch<CloudConfigReport>("/api/v1/cloud-config/validate", {
+    method: "POST",
+    body: JSON.stringify({ cloudConfig, fragment }),
+  });
@@ -6,6 +6,9 @@ export interface Group {
   name: string;
   description: string;
   node_count: number;
+  // followChannelId: every promotion or rollback of this channel queues an
+  // upgrade on the group's nodes.
+  followChannelId?: string;
   created_at: string;
   updated_at: string;
 }
@@ -18,6 +21,8 @@ export interface CreateGroupInput {
 export interface UpdateGroupInput {
   name?: string;
   description?: string;
+  // followChannelId: omitted leaves it unchanged, "" stops following.
+  followChannelId?: string;
 }
 
 export function listGroups(): Promise<Group[]> {
@@ -0,0 +1,35 @@
+import { apiFetch } from "./client";
+
+// ImageCheck is what the base image watcher found for one watched build.
+// The UI WebSocket receives the checks that found a change as
+// "base-image-changed" messages.
+export interface ImageCheck {
+  image: string;
+  artifactId: string;
+  templateId?: string;
+  previousDigest: string;
+  digest?: string;
+  changed: boolean;
+  rebuildId?: string;
+  error?: string;
+}
+
+export interface ImageWatchRun {
+  startedAt: string;
+  finishedAt: string;
+  checks: ImageCheck[];
+}
+
+// ImageWatchStatus: an empty schedule means the watcher is off.
+export interface ImageWatchStatus {
+  schedule: string;
+  nextRun?: string;
+  lastRun?: ImageWatchRun;
+}
+
+export const getImageWatch = () => apiFetch<ImageWatchStatus>("/api/v1/image-watch");
+
+export const setImageWatchSchedule = (schedule: string) =>
+  apiFetch<ImageWatchStatus>("/api/v1/image-watch", { method: "PUT", body: JSON.stringify({ schedule }) });
+
+export const runImageWatch = () => apiFetch<ImageWatchRun>("/api/v1/image-watch/run", { method: "POST" });
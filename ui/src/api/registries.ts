@@ -0,0 +1,35 @@
+import { apiFetch } from "./client";
+
+// Registry is an OCI registry artifacts can be published to. The password is
+// write-only: the server stores it encrypted and never returns it.
+export interface Registry {
+  id: string;
+  name: string;
+  host: string;
+  namespace?: string;
+  username?: string;
+  insecure?: boolean;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface RegistryInput {
+  name: string;
+  host: string;
+  namespace?: string;
+  username?: string;
+  // password, when empty on update, keeps the stored one.
+  password?: string;
+  insecure?: boolean;
+}
+
+export const listRegistries = () => apiFetch<Registry[]>("/api/v1/registries");
+
+export const createRegistry = (r: RegistryInput) =>
+  apiFetch<Registry>("/api/v1/registries", { method: "POST", body: JSON.stringify(r) });
+
+export const updateRegistry = (id: string, r: RegistryInput) =>
+  apiFetch<Registry>(`/api/v1/registries/${id}`, { method: "PUT", body: JSON.stringify(r) });
+
+export const deleteRegistry = (id: string) =>
+  apiFetch(`/api/v1/registries/${id}`, { method: "DELETE" });
@@ -0,0 +1,45 @@
+import { apiFetch } from "./client";
+
+// RetentionPolicy: zero disables a rule.
+export interface RetentionPolicy {
+  keepLastPerBase: number;
+  maxAgeDays: number;
+  maxTotalBytes: number;
+}
+
+export interface RetentionRemoval {
+  id: string;
+  name?: string;
+  base?: string;
+  createdAt: string;
+  bytes: number;
+  reason: "keep-last" | "max-age" | "max-total-bytes";
+}
+
+// RetentionKept is an artifact a rule matched that stays because a
+// deployment, a channel or the netboot server still uses it.
+export interface RetentionKept {
+  id: string;
+  name?: string;
+  reason: string;
+  protected: string;
+}
+
+export interface RetentionPlan {
+  remove: RetentionRemoval[];
+  kept?: RetentionKept[];
+  totalBytes: number;
+  freedBytes: number;
+  overBudget?: boolean;
+  // failed is only set by a run, keyed by artifact ID.
+  failed?: Record<string, string>;
+}
+
+export const getRetentionPolicy = () => apiFetch<RetentionPolicy>("/api/v1/retention");
+
+export const setRetentionPolicy = (p: RetentionPolicy) =>
+  apiFetch<RetentionPolicy>("/api/v1/retention", { method: "PUT", body: JSON.stringify(p) });
+
+export const getRetentionPlan = () => apiFetch<RetentionPlan>("/api/v1/retention/plan");
+
+export const runRetention = () => apiFetch<RetentionPlan>("/api/v1/retention/run", { method: "POST" });
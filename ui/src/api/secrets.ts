@@ -0,0 +1,24 @@
+import { apiFetch } from "./client";
+
+// Secret is a named credential, such as the token a Git source is cloned
+// with. The value is write-only: the server stores it encrypted and never
+// returns it.
+export interface Secret {
+  name: string;
+  description?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface SecretInput {
+  value: string;
+  description?: string;
+}
+
+export const listSecrets = () => apiFetch<Secret[]>("/api/v1/secrets");
+
+export const setSecret = (name: string, s: SecretInput) =>
+  apiFetch<Secret>(`/api/v1/secrets/${name}`, { method: "PUT", body: JSON.stringify(s) });
+
+export const deleteSecret = (name: string) =>
+  apiFetch(`/api/v1/secrets/${name}`, { method: "DELETE" });
@@ -3,9 +3,9 @@ import { apiFetch } from "./client";
 // SystemBuilder mirrors the server's GET /api/v1/system/builder response:
 // which build backend is active and enough context for the UI to render a
 // "Builds via cluster X" badge. cluster and namespace are empty on the
-// local backend.
+// local and worker backends.
 export interface SystemBuilder {
-  backend: "local" | "operator";
+  backend: "local" | "operator" | "worker";
   cluster?: string;
   namespace?: string;
   downloadSupported: boolean;
@@ -0,0 +1,82 @@
+import { apiFetch, apiFetchText } from "./client";
+import type { Artifact, CreateArtifactInput } from "./artifacts";
+
+// BuildTemplate is a vetted build recipe stored on the server. Spec is a
+// partial build request whose string values may reference parameters as
+// ${{ name }}. The list endpoint leaves spec out.
+export interface BuildTemplate {
+  id: string;
+  name: string;
+  description?: string;
+  parameters?: TemplateParameter[];
+  spec?: Partial<CreateArtifactInput>;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface TemplateParameter {
+  name: string;
+  description?: string;
+  default?: string;
+  required?: boolean;
+}
+
+export interface BuildTemplateInput {
+  name: string;
+  description?: string;
+  parameters?: TemplateParameter[];
+  spec: Partial<CreateArtifactInput>;
+}
+
+export interface BuildFromTemplateInput {
+  name?: string;
+  parameters?: Record<string, string>;
+  overrides?: Partial<CreateArtifactInput>;
+}
+
+export const listBuildTemplates = () => apiFetch<BuildTemplate[]>("/api/v1/build-templates");
+
+export const getBuildTemplate = (id: string) => apiFetch<BuildTemplate>(`/api/v1/build-templates/${id}`);
+
+export const createBuildTemplate = (t: BuildTemplateInput) =>
+  apiFetch<BuildTemplate>("/api/v1/build-templates", { method: "POST", body: JSON.stringify(t) });
+
+export const updateBuildTemplate = (id: string, t: BuildTemplateInput) =>
+  apiFetch<BuildTemplate>(`/api/v1/build-templates/${id}`, { method: "PUT", body: JSON.stringify(t) });
+
+export const deleteBuildTemplate = (id: string) =>
+  apiFetch(`/api/v1/build-templates/${id}`, { method: "DELETE" });
+
+export const buildFromTemplate = (id: string, input: BuildFromTemplateInput) =>
+  apiFetch<Artifact>(`/api/v1/build-templates/${id}/build`, { method: "POST", body: JSON.stringify(input) });
+
+export const exportBuildTemplate = (id: string) => apiFetchText(`/api/v1/build-templates/${id}/export`);
+
+export const importBuildTemplate = (yaml: string) =>
+  apiFetch<BuildTemplate>("/api/v1/build-templates/import", {
+    method: "POST",
+    headers: { "Content-Type": "application/yaml" },
+    body: yaml,
+  });
+
+const PLACEHOLDER_RE = /\$\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}/g;
+
+// applyTemplateDefaults substitutes each parameter's default into the spec,
+// the way the server renders a build that supplies no parameters. Required
+// parameters without a default are left as placeholders for the user to
+// fill in.
+export function applyTemplateDefaults(
+  spec: Partial<CreateArtifactInput>,
+  parameters: TemplateParameter[] = [],
+): Partial<CreateArtifactInput> {
+  const values = new Map(parameters.filter((p) => p.default !== undefined).map((p) => [p.name, p.default as string]));
+  const walk = (v: unknown): unknown => {
+    if (typeof v === "string") return v.replace(PLACEHOLDER_RE, (m, name: string) => values.get(name) ?? m);
+    if (Array.isArray(v)) return v.map(walk);
+    if (v && typeof v === "object") {
+      return Object.fromEntries(Object.entries(v).map(([k, e]) => [k, walk(e)]));
+    }
+    return v;
+  };
+  return walk(spec) as Partial<CreateArtifactInput>;
+}
@@ -19,6 +19,7 @@ import {
 } from "@/components/ui/select";
 import { Textarea } from "@/components/ui/textarea";
 import { listArtifacts, Artifact } from "@/api/artifacts";
+import { listChannels, type Channel } from "@/api/channels";
 import {
   ArrowUpCircle,
   RotateCcw,
@@ -130,7 +131,7 @@ const TONE_CLASSES: Record<CommandDef["tone"], { chip: string; hover: string }>
   },
 };
 
-type UpgradeSourceMode = "image" | "artifact";
+type UpgradeSourceMode = "image" | "artifact" | "channel";
 
 interface CommandDialogProps {
   open: boolean;
@@ -158,6 +159,8 @@ export function CommandDialog({
     useState<UpgradeSourceMode>("image");
   const [artifacts, setArtifacts] = useState<Artifact[]>([]);
   const [selectedArtifactId, setSelectedArtifactId] = useState("");
+  const [channels, setChannels] = useState<Channel[]>([]);
+  const [selectedChannel, setSelectedChannel] = useState("");
   const [resetOem, setResetOem] = useState(false);
   const [resetConfig, setResetConfig] = useState("");
 
@@ -183,6 +186,7 @@ export function CommandDialog({
       setShellCmd("");
       setUpgradeSourceMode("image");
       setSelectedArtifactId("");
+      setSelectedChannel("");
       setResetOem(false);
       setResetConfig("");
     }
@@ -201,6 +205,11 @@ export function CommandDialog({
         )
         .catch(() => setArtifacts([]));
     }
+    if (open && isUpgrade && upgradeSourceMode === "channel") {
+      listChannels()
+        .then(setChannels)
+        .catch(() => setChannels([]));
+    }
   }, [open, isUpgrade, upgradeSourceMode]);
 
   function handleSubmit() {
@@ -210,6 +219,10 @@ export function CommandDialog({
     if (isUpgrade) {
       if (upgradeSourceMode === "artifact") {
         args.source = "artifact:" + selectedArtifactId;
+      } else if (upgradeSourceMode === "channel") {
+        // Resolved server-side to the channel's artifact when the node
+        // receives the command.
+        args.source = "channel:" + selectedChannel;
       } else if (imageArg) {
         args.source = "oci:" + imageArg;
       }
@@ -239,6 +252,7 @@ export function CommandDialog({
     if (command === "exec") return shellCmd.trim().length > 0;
     if (command === "upgrade" || command === "upgrade-recovery") {
       if (upgradeSourceMode === "image") return imageArg.trim().length > 0;
+      if (upgradeSourceMode === "channel") return selectedChannel !== "";
       return selectedArtifactId !== "";
     }
     return true;
@@ -338,6 +352,7 @@ export function CommandDialog({
                     <SelectContent>
                       <SelectItem value="image">From image reference</SelectItem>
                       <SelectItem value="artifact">From built artifact</SelectItem>
+                      <SelectItem value="channel">From channel</SelectItem>
                     </SelectContent>
                   </Select>
                 </div>
@@ -414,6 +429,36 @@ export function CommandDialog({
                     )}
                   </div>
                 )}
+
+                {upgradeSourceMode === "channel" && (
+                  <div className="grid gap-2">
+                    <Label>Channel</Label>
+                    {channels.length === 0 ? (
+                      <p className="text-sm text-muted-foreground">
+                        No channels yet. Create one in Settings.
+                      </p>
+                    ) : (
+                      <Select value={selectedChannel} onValueChange={setSelectedChannel}>
+                        <SelectTrigger>
+                          <SelectValue placeholder="Select channel..." />
+                        </SelectTrigger>
+                        <SelectContent>
+                          {channels.map((ch) => (
+                            <SelectItem key={ch.id} value={ch.name} disabled={!ch.artifactId}>
+                              <span className="font-mono">{ch.name}</span>
+                              {!ch.artifactId && (
+                                <span className="text-xs text-muted-foreground"> (empty)</span>
+                              )}
+                            </SelectItem>
+                          ))}
+                        </SelectContent>
+                      </Select>
+                    )}
+                    <p className="text-xs text-muted-foreground">
+                      The node installs whatever the channel points at when it receives the command.
+                    </p>
+                  </div>
+                )}
               </>
             )}
 
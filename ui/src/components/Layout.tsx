@@ -21,6 +21,9 @@ import { getSystemBuilder, type SystemBuilder } from "@/api/system";
 import { cn } from "@/lib/utils";
 import { KairosLogo } from "@/components/KairosLogo";
 import { Toaster } from "@/components/ui/toaster";
+import { toast } from "@/hooks/useToast";
+import { useUIWebSocket } from "@/hooks/useUIWebSocket";
+import type { ImageCheck } from "@/api/imagewatch";
 
 // NavItem is either a client-side route (the common case) or an
 // `external: true` link that renders as a plain <a target="_blank">
@@ -89,7 +92,7 @@ function BuilderChip({ info }: { info: SystemBuilder }) {
           ["Backend:", "Operator"],
           ["Namespace:", info.namespace || "default"],
         ]
-      : [["Backend:", "Local"]];
+      : [["Backend:", info.backend === "worker" ? "Workers" : "Local"]];
   return (
     <div
       className="grid grid-cols-2 gap-x-1 px-4 pb-2 text-[10px] leading-tight text-sidebar-fg/40"
@@ -123,6 +126,18 @@ export function Layout() {
     };
   }, []);
 
+  // The base image watcher rebuilds in the background; tell whoever is
+  // looking, on any page.
+  useUIWebSocket((msg) => {
+    if (msg.type !== "base-image-changed") return;
+    const check = msg.data as ImageCheck;
+    if (check.error) {
+      toast(`${check.image} changed, but the rebuild failed: ${check.error}`, "error");
+    } else {
+      toast(`${check.image} changed; rebuilding as ${check.rebuildId?.slice(0, 8)}`, "info");
+    }
+  });
+
   function handleLogout() {
     logout();
     navigate("/login");
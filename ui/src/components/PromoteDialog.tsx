@@ -0,0 +1,113 @@
+import { useEffect, useState } from "react";
+import { Link } from "react-router";
+import {
+  Dialog,
+  DialogContent,
+  DialogHeader,
+  DialogTitle,
+  DialogDescription,
+} from "@/components/ui/dialog";
+import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
+import { Loader2 } from "lucide-react";
+import { type Channel, type ChannelMove, listChannels, promoteChannel } from "@/api/channels";
+
+interface PromoteDialogProps {
+  artifactId: string;
+  onClose: () => void;
+}
+
+// PromoteDialog points a channel at an artifact. Groups following the
+// channel get an upgrade queued on every node.
+export function PromoteDialog({ artifactId, onClose }: PromoteDialogProps) {
+  const [channels, setChannels] = useState<Channel[]>([]);
+  const [channelId, setChannelId] = useState("");
+  const [note, setNote] = useState("");
+  const [promoting, setPromoting] = useState(false);
+  const [error, setError] = useState("");
+  const [result, setResult] = useState<ChannelMove | null>(null);
+
+  useEffect(() => {
+    listChannels()
+      .then((c) => {
+        setChannels(c);
+        if (c.length === 1) setChannelId(c[0].id);
+      })
+      .catch(() => {});
+  }, []);
+
+  async function handlePromote() {
+    setPromoting(true);
+    setError("");
+    try {
+      setResult(await promoteChannel(channelId, { artifactId, note: note || undefined }));
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Promotion failed");
+    } finally {
+      setPromoting(false);
+    }
+  }
+
+  return (
+    <Dialog open onOpenChange={(open) => !open && onClose()}>
+      <DialogContent className="sm:max-w-md">
+        <DialogHeader>
+          <DialogTitle>Promote to Channel</DialogTitle>
+          <DialogDescription>
+            Nodes upgrading from the channel install this artifact from now on.
+          </DialogDescription>
+        </DialogHeader>
+
+        <div className="space-y-4">
+          <div className="space-y-2">
+            <div className="flex items-center justify-between">
+              <Label>Channel</Label>
+              <Link to="/settings" className="text-xs text-[#EE5007] hover:underline" onClick={onClose}>
+                Manage channels →
+              </Link>
+            </div>
+            <Select value={channelId} onValueChange={setChannelId}>
+              <SelectTrigger>
+                <SelectValue placeholder={channels.length ? "Select a channel..." : "No channels configured"} />
+              </SelectTrigger>
+              <SelectContent>
+                {channels.map((ch) => (
+                  <SelectItem key={ch.id} value={ch.id} disabled={ch.artifactId === artifactId}>
+                    <span className="font-mono">{ch.name}</span>
+                  </SelectItem>
+                ))}
+              </SelectContent>
+            </Select>
+          </div>
+          <div className="space-y-1">
+            <Label className="text-xs">Note</Label>
+            <Input value={note} onChange={(e) => setNote(e.target.value)} placeholder="Optional" />
+          </div>
+
+          {error && (
+            <div className="bg-red-500/10 border border-red-500/25 text-red-700 rounded-md p-3 text-sm">
+              {error}
+            </div>
+          )}
+          {result && (
+            <div className="bg-green-500/10 border border-green-500/25 text-green-700 rounded-md p-3 text-sm">
+              Promoted to {result.channel.name}; {result.queued} upgrade(s) queued.
+            </div>
+          )}
+          <Button className="w-full" onClick={handlePromote} disabled={!channelId || promoting || !!result}>
+            {promoting && <Loader2 className="h-4 w-4 mr-2 animate-spin" />}
+            {promoting ? "Promoting..." : "Promote"}
+          </Button>
+        </div>
+      </DialogContent>
+    </Dialog>
+  );
+}
@@ -0,0 +1,183 @@
+import { useEffect, useState } from "react";
+import { Link } from "react-router";
+import {
+  Dialog,
+  DialogContent,
+  DialogHeader,
+  DialogTitle,
+  DialogDescription,
+} from "@/components/ui/dialog";
+import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
+import { Loader2 } from "lucide-react";
+import { type Publication, publishArtifact } from "@/api/artifacts";
+import { type Registry, listRegistries } from "@/api/registries";
+
+interface PublishDialogProps {
+  artifactId: string;
+  artifactName?: string;
+  artifactFiles: string[];
+  hasImage: boolean;
+  onClose: () => void;
+  onPublished: (p: Publication) => void;
+}
+
+// slug turns an artifact name into a default repository name.
+function slug(name: string): string {
+  return name.toLowerCase().replace(/[^a-z0-9._-]+/g, "-").replace(/^-+|-+$/g, "");
+}
+
+export function PublishDialog({
+  artifactId,
+  artifactName,
+  artifactFiles,
+  hasImage,
+  onClose,
+  onPublished,
+}: PublishDialogProps) {
+  const [registries, setRegistries] = useState<Registry[]>([]);
+  const [registryId, setRegistryId] = useState("");
+  const [repository, setRepository] = useState(slug(artifactName || "") || "kairos");
+  const [tags, setTags] = useState("latest");
+  const [pushImage, setPushImage] = useState(hasImage);
+  const [selected, setSelected] = useState<Set<string>>(() => new Set(artifactFiles));
+  const [publishing, setPublishing] = useState(false);
+  const [error, setError] = useState("");
+  const [result, setResult] = useState<Publication | null>(null);
+
+  useEffect(() => {
+    listRegistries()
+      .then((r) => {
+        setRegistries(r);
+        if (r.length === 1) setRegistryId(r[0].id);
+      })
+      .catch(() => {});
+  }, []);
+
+  function toggleFile(f: string) {
+    const next = new Set(selected);
+    if (next.has(f)) next.delete(f);
+    else next.add(f);
+    setSelected(next);
+  }
+
+  async function handlePublish() {
+    setPublishing(true);
+    setError("");
+    setResult(null);
+    try {
+      const files = artifactFiles.filter((f) => selected.has(f));
+      const pub = await publishArtifact(artifactId, {
+        registryId,
+        repository,
+        tags: tags.split(/[\s,]+/).filter(Boolean),
+        image: pushImage,
+        outputs: files.length > 0,
+        files: files.length > 0 ? files : undefined,
+      });
+      setResult(pub);
+      onPublished(pub);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Publish failed");
+    } finally {
+      setPublishing(false);
+    }
+  }
+
+  const nothingSelected = !pushImage && selected.size === 0;
+
+  return (
+    <Dialog open onOpenChange={(open) => !open && onClose()}>
+      <DialogContent className="sm:max-w-lg">
+        <DialogHeader>
+          <DialogTitle>Publish to Registry</DialogTitle>
+          <DialogDescription>
+            Push the OS image for <code>kairos-agent upgrade --source oci:</code>, and the output
+            files as an OCI artifact tagged <code>&lt;tag&gt;-artifacts</code>.
+          </DialogDescription>
+        </DialogHeader>
+
+        <div className="space-y-4">
+          <div className="space-y-2">
+            <div className="flex items-center justify-between">
+              <Label>Registry</Label>
+              <Link to="/settings" className="text-xs text-[#EE5007] hover:underline" onClick={onClose}>
+                Manage registries →
+              </Link>
+            </div>
+            <Select value={registryId} onValueChange={setRegistryId}>
+              <SelectTrigger>
+                <SelectValue placeholder={registries.length ? "Select a registry..." : "No registries configured"} />
+              </SelectTrigger>
+              <SelectContent>
+                {registries.map((r) => (
+                  <SelectItem key={r.id} value={r.id}>
+                    {r.name} ({r.host}{r.namespace ? `/${r.namespace}` : ""})
+                  </SelectItem>
+                ))}
+              </SelectContent>
+            </Select>
+          </div>
+
+          <div className="grid grid-cols-2 gap-3">
+            <div className="space-y-1">
+              <Label className="text-xs">Repository</Label>
+              <Input value={repository} onChange={(e) => setRepository(e.target.value)} className="font-mono" />
+            </div>
+            <div className="space-y-1">
+              <Label className="text-xs">Tags</Label>
+              <Input value={tags} onChange={(e) => setTags(e.target.value)} placeholder="v1.0.0, latest" className="font-mono" />
+            </div>
+          </div>
+
+          <div className="space-y-2">
+            <Label className="text-xs">Contents</Label>
+            <label className="flex items-center gap-2 text-sm cursor-pointer">
+              <input
+                type="checkbox"
+                checked={pushImage}
+                disabled={!hasImage}
+                onChange={(e) => setPushImage(e.target.checked)}
+              />
+              OS container image
+            </label>
+            {artifactFiles.map((f) => (
+              <label key={f} className="flex items-center gap-2 text-sm cursor-pointer">
+                <input type="checkbox" checked={selected.has(f)} onChange={() => toggleFile(f)} />
+                <span className="font-mono text-xs">{f}</span>
+              </label>
+            ))}
+          </div>
+
+          {error && (
+            <div className="bg-red-500/10 border border-red-500/25 text-red-700 rounded-md p-3 text-sm">
+              {error}
+            </div>
+          )}
+          {result && (
+            <div className="bg-green-500/10 border border-green-500/25 text-green-700 rounded-md p-3 text-xs font-mono break-all space-y-1">
+              {result.image && <p>{result.image}</p>}
+              {result.files && <p>{result.files}</p>}
+            </div>
+          )}
+          <Button
+            className="w-full"
+            onClick={handlePublish}
+            disabled={!registryId || !repository || !tags.trim() || nothingSelected || publishing}
+          >
+            {publishing && <Loader2 className="h-4 w-4 mr-2 animate-spin" />}
+            {publishing ? "Publishing..." : "Publish"}
+          </Button>
+        </div>
+      </DialogContent>
+    </Dialog>
+  );
+}
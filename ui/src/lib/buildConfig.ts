@@ -59,6 +59,7 @@ export interface BuildConfigPayload {
   signing: {
     ukiKeySetName?: string;
     ukiSecureBootEnroll?: string;
+    manifestKeySetName?: string;
   };
   provisioning: {
     autoInstall: boolean;
@@ -91,6 +92,7 @@ export function payloadFromBuilder(args: {
   const { form, buildMode, groups, keySets, userMode, username, sshKeys, advancedConfig } = args;
   const groupName = groups.find((g) => g.id === form.provisioning.targetGroupId)?.name;
   const keySetName = keySets.find((k) => k.id === form.signing.ukiKeySetId)?.name;
+  const manifestKeySetName = keySets.find((k) => k.id === form.signing.manifestKeySetId)?.name;
   return {
     version: BUILD_CONFIG_VERSION,
     kind: BUILD_CONFIG_KIND,
@@ -116,6 +118,7 @@ export function payloadFromBuilder(args: {
     signing: {
       ukiKeySetName: keySetName,
       ukiSecureBootEnroll: form.signing.ukiSecureBootEnroll || undefined,
+      manifestKeySetName,
     },
     provisioning: {
       autoInstall: form.provisioning.autoInstall,
@@ -170,9 +173,21 @@ export function payloadFromArtifact(artifact: Artifact, groups: Group[]): BuildC
       gce: artifact.gce ?? false,
       vhd: artifact.vhd ?? false,
       maas: artifact.maas ?? false,
+      qcow2: artifact.qcow2 ?? false,
+      qcow2Compress: artifact.qcow2Compress ?? false,
+      vhdDynamic: artifact.vhdDynamic ?? false,
+      vhdx: artifact.vhdx ?? false,
+      vmdk: artifact.vmdk ?? false,
+      ova: artifact.ova ?? false,
+      ovaCpus: artifact.ovaCpus,
+      ovaMemoryMB: artifact.ovaMemoryMB,
+      ovaUserDataProperty: artifact.ovaUserDataProperty ?? false,
       uki: artifact.uki ?? false,
       fips: artifact.fips,
       trustedBoot: artifact.trustedBoot,
+      sbom: artifact.sbom ?? false,
+      sbomFormat: artifact.sbomFormat,
+      compression: artifact.compression,
     },
     signing: {},
     provisioning: {
@@ -4,11 +4,24 @@ import {
   createArtifact,
   getArtifact,
   listSecureBootKeySets,
+  pinImage,
   uploadOverlayFiles,
   type CreateArtifactInput,
+  type GitSource,
+  type SBOMFormat,
+  type OutputCompression,
   type SecureBootKeySet,
 } from "@/api/artifacts";
 import { listGroups, type Group } from "@/api/groups";
+import { listSecrets, type Secret } from "@/api/secrets";
+import { validateCloudConfig, type CloudConfigReport } from "@/api/cloudconfig";
+import {
+  applyTemplateDefaults,
+  createBuildTemplate,
+  getBuildTemplate,
+  listBuildTemplates,
+  type BuildTemplate as ServerBuildTemplate,
+} from "@/api/templates";
 import {
   PHONEHOME_SAFE_DEFAULTS,
   PHONEHOME_DESTRUCTIVE_COMMANDS,
@@ -44,6 +57,7 @@ import {
   CloudCog,
   Package,
   Download,
+  Save,
   FileUp,
   Cpu,
   Server,
@@ -61,7 +75,7 @@ import {
   sanitizeImportedBuildConfig,
 } from "@/lib/buildConfig";
 
-type OutputField = "iso" | "netboot" | "uki" | "rawDisk" | "cloudImage" | "gce" | "vhd" | "maas" | "tar";
+type OutputField = "iso" | "netboot" | "uki" | "rawDisk" | "cloudImage" | "qcow2" | "vmdk" | "ova" | "gce" | "vhd" | "vhdx" | "maas" | "tar";
 type OutputTone = "install" | "disk" | "archive";
 type OutputCardDef = {
   field: OutputField;
@@ -95,8 +109,12 @@ const OUTPUT_GROUPS: { title: string; tone: OutputTone; items: OutputCardDef[] }
     items: [
       { field: "rawDisk", label: "Raw Disk", desc: "Flat .raw disk image", icon: HardDrive },
       { field: "cloudImage", label: "Cloud Image", desc: "Generic cloud disk", icon: Cloud },
+      { field: "qcow2", label: "QCOW2", desc: "KVM, Proxmox and OpenStack image", icon: HardDrive },
+      { field: "vmdk", label: "VMDK", desc: "Stream-optimized vSphere disk", icon: HardDrive },
+      { field: "ova", label: "OVA", desc: "vSphere appliance (EFI)", icon: Server },
       { field: "gce", label: "Google Cloud", desc: "GCE-compatible image", icon: CloudCog },
       { field: "vhd", label: "Azure (VHD)", desc: "Azure VHD image", icon: CloudCog },
+      { field: "vhdx", label: "Hyper-V (VHDX)", desc: "Generation 2 VM disk", icon: Server },
       { field: "maas", label: "MAAS", desc: "MAAS-deployable image (ddgz)", icon: Server },
     ],
   },
@@ -336,7 +354,7 @@ const TEMPLATES: BuildTemplate[] = [
       model: "generic",
       arch: "amd64",
       variant: "core",
-      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, maas: false, uki: false, fips: false, trustedBoot: false },
+      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, vhdx: false, maas: false, qcow2: false, vmdk: false, ova: false, uki: false, fips: false, trustedBoot: false },
     },
   },
   {
@@ -348,7 +366,7 @@ const TEMPLATES: BuildTemplate[] = [
       model: "generic",
       arch: "amd64",
       variant: "core",
-      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, maas: false, uki: false, fips: false, trustedBoot: false },
+      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, vhdx: false, maas: false, qcow2: false, vmdk: false, ova: false, uki: false, fips: false, trustedBoot: false },
     },
   },
   {
@@ -360,7 +378,7 @@ const TEMPLATES: BuildTemplate[] = [
       model: "generic",
       arch: "amd64",
       variant: "core",
-      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, maas: false, uki: false, fips: false, trustedBoot: false },
+      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, vhdx: false, maas: false, qcow2: false, vmdk: false, ova: false, uki: false, fips: false, trustedBoot: false },
     },
   },
   {
@@ -372,7 +390,7 @@ const TEMPLATES: BuildTemplate[] = [
       model: "generic",
       arch: "amd64",
       variant: "core",
-      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, maas: false, uki: false, fips: false, trustedBoot: false },
+      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, vhdx: false, maas: false, qcow2: false, vmdk: false, ova: false, uki: false, fips: false, trustedBoot: false },
     },
   },
   {
@@ -384,7 +402,7 @@ const TEMPLATES: BuildTemplate[] = [
       model: "generic",
       arch: "amd64",
       variant: "core",
-      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, maas: false, uki: false, fips: false, trustedBoot: false },
+      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, vhdx: false, maas: false, qcow2: false, vmdk: false, ova: false, uki: false, fips: false, trustedBoot: false },
     },
   },
   {
@@ -396,7 +414,7 @@ const TEMPLATES: BuildTemplate[] = [
       model: "generic",
       arch: "amd64",
       variant: "core",
-      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, maas: false, uki: false, fips: false, trustedBoot: false },
+      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, vhdx: false, maas: false, qcow2: false, vmdk: false, ova: false, uki: false, fips: false, trustedBoot: false },
     },
   },
   {
@@ -408,7 +426,7 @@ const TEMPLATES: BuildTemplate[] = [
       model: "generic",
       arch: "amd64",
       variant: "core",
-      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, maas: false, uki: false, fips: false, trustedBoot: false },
+      outputs: { iso: true, cloudImage: false, netboot: false, rawDisk: false, tar: false, gce: false, vhd: false, vhdx: false, maas: false, qcow2: false, vmdk: false, ova: false, uki: false, fips: false, trustedBoot: false },
     },
   },
   {
@@ -427,10 +445,18 @@ const EMPTY_OUTPUTS = {
   tar: false,
   gce: false,
   vhd: false,
+  vhdDynamic: false,
+  vhdx: false,
   maas: false,
+  qcow2: false,
+  qcow2Compress: false,
+  vmdk: false,
+  ova: false,
+  ovaUserDataProperty: false,
   uki: false,
   fips: false,
   trustedBoot: false,
+  sbom: false,
 };
 
 const EMPTY_SIGNING = {
@@ -440,6 +466,7 @@ const EMPTY_SIGNING = {
   ukiTpmPcrKey: "",
   ukiPublicKeysDir: "",
   ukiSecureBootEnroll: "if-safe",
+  manifestKeySetId: "",
 };
 
 const EMPTY_PROVISIONING = {
@@ -590,7 +617,9 @@ export function ArtifactBuilder() {
   const [groups, setGroups] = useState<Group[]>([]);
   const [keySets, setKeySets] = useState<SecureBootKeySet[]>([]);
   const [selectedTemplate, setSelectedTemplate] = useState("");
-  const [buildMode, setBuildMode] = useState<"image" | "dockerfile">("image");
+  const [buildMode, setBuildMode] = useState<"image" | "dockerfile" | "git">("image");
+  const [secrets, setSecrets] = useState<Secret[]>([]);
+  const [serverTemplates, setServerTemplates] = useState<ServerBuildTemplate[]>([]);
   const [form, setForm] = useState<CreateArtifactInput>({ ...EMPTY_FORM, outputs: { ...EMPTY_OUTPUTS }, signing: { ...EMPTY_SIGNING }, provisioning: { ...EMPTY_PROVISIONING } });
   const [cloneSource, setCloneSource] = useState("");
   const [customModel, setCustomModel] = useState(false);
@@ -689,7 +718,7 @@ export function ArtifactBuilder() {
   // plain (non-composed) build path without an effect-driven cascade.
   function updateHadronBaseTag(next: string) {
     setHadronBaseTag(next);
-    if (buildMode === "dockerfile") return;
+    if (buildMode !== "image") return;
     const ref =
       next === HADRON_CUSTOM_TAG_SENTINEL
         ? hadronBaseCustom.trim()
@@ -702,7 +731,7 @@ export function ArtifactBuilder() {
   function updateHadronBaseCustom(next: string) {
     setHadronBaseCustom(next);
     if (hadronBaseTag !== HADRON_CUSTOM_TAG_SENTINEL) return;
-    if (buildMode === "dockerfile") return;
+    if (buildMode !== "image") return;
     const ref = next.trim();
     if (!ref) return;
     setForm((prev) => (prev.baseImage === ref ? prev : { ...prev, baseImage: ref }));
@@ -732,12 +761,34 @@ export function ArtifactBuilder() {
   // Advanced cloud-config
   const [advancedConfig, setAdvancedConfig] = useState("");
   const [showAdvanced, setShowAdvanced] = useState(false);
+  const [advancedLint, setAdvancedLint] = useState<CloudConfigReport | null>(null);
+
+  // Lint the extra YAML as it is typed, with the checks the server runs
+  // when the build is submitted.
+  useEffect(() => {
+    if (!advancedConfig.trim()) {
+      setAdvancedLint(null);
+      return;
+    }
+    let cancelled = false;
+    const t = setTimeout(() => {
+      validateCloudConfig(advancedConfig, true)
+        .then((r) => !cancelled && setAdvancedLint(r))
+        .catch(() => !cancelled && setAdvancedLint(null));
+    }, 400);
+    return () => {
+      cancelled = true;
+      clearTimeout(t);
+    };
+  }, [advancedConfig]);
 
   const importInputRef = useRef<HTMLInputElement>(null);
 
   useEffect(() => {
     listGroups().then(setGroups).catch(() => {});
     listSecureBootKeySets().then(setKeySets).catch(() => {});
+    listSecrets().then(setSecrets).catch(() => {});
+    listBuildTemplates().then(setServerTemplates).catch(() => {});
   }, []);
 
   // After focusFirstError queues a focusTarget and setStep has re-rendered
@@ -762,6 +813,7 @@ export function ArtifactBuilder() {
   // Raw secrets (manual UKI key/cert paths, advanced cloud-config passwords)
   // are intentionally included as-is because users need them on the target
   // instance too; if you're sharing a config more widely, strip them first.
+  // Secret references in the cloud-config are exported as written.
   function handleExportConfig() {
     const payload = payloadFromBuilder({
       form,
@@ -820,6 +872,9 @@ export function ArtifactBuilder() {
     const resolvedKeySetId = sign.ukiKeySetName
       ? keySets.find((k) => k.name === sign.ukiKeySetName)?.id || ""
       : "";
+    const resolvedManifestKeySetId = sign.manifestKeySetName
+      ? keySets.find((k) => k.name === sign.manifestKeySetName)?.id || ""
+      : "";
 
     setForm({
       ...EMPTY_FORM,
@@ -841,6 +896,7 @@ export function ArtifactBuilder() {
         ...EMPTY_SIGNING,
         ukiKeySetId: resolvedKeySetId,
         ukiSecureBootEnroll: sign.ukiSecureBootEnroll || "if-safe",
+        manifestKeySetId: resolvedManifestKeySetId,
       },
       provisioning: {
         ...EMPTY_PROVISIONING,
@@ -876,6 +932,9 @@ export function ArtifactBuilder() {
     if (sign.ukiKeySetName && !resolvedKeySetId) {
       warnings.push(`key set "${sign.ukiKeySetName}" not found`);
     }
+    if (sign.manifestKeySetName && !resolvedManifestKeySetId) {
+      warnings.push(`key set "${sign.manifestKeySetName}" not found`);
+    }
     if (warnings.length > 0) {
       toast(`Imported with warnings: ${warnings.join("; ")}`, "info");
     } else {
@@ -886,6 +945,9 @@ export function ArtifactBuilder() {
   // Clone pre-fill
   useEffect(() => {
     const cloneId = searchParams.get("clone");
+    // exact=1 is "Clone exactly": rebuild from the digests the source build
+    // resolved instead of whatever its tags point to today.
+    const exact = searchParams.get("exact") === "1";
     if (cloneId) {
       getArtifact(cloneId).then((a) => {
         setCloneSource(a.name || a.id.slice(0, 8));
@@ -898,12 +960,15 @@ export function ArtifactBuilder() {
           const HADRON_PREFIX = "ghcr.io/kairos-io/hadron:";
           setSelectedTemplate(HADRON_TEMPLATE_NAME);
           startHadronCatalogs();
-          if (a.hadronBase.startsWith(HADRON_PREFIX)) {
-            setHadronBaseTag(a.hadronBase.slice(HADRON_PREFIX.length));
+          // A pinned base is not one of the catalog's tags, so an exact
+          // clone lands in the custom field.
+          const clonedBase = exact ? pinImage(a.hadronBase, a.baseImageDigest) : a.hadronBase;
+          if (clonedBase.startsWith(HADRON_PREFIX)) {
+            setHadronBaseTag(clonedBase.slice(HADRON_PREFIX.length));
             setHadronBaseCustom("");
           } else {
             setHadronBaseTag(HADRON_CUSTOM_TAG_SENTINEL);
-            setHadronBaseCustom(a.hadronBase);
+            setHadronBaseCustom(clonedBase);
           }
           const firmware = a.hadronFirmware || [];
           const layers = a.hadronLayers || [];
@@ -919,7 +984,7 @@ export function ArtifactBuilder() {
             name: `Copy of ${a.name || a.id.slice(0, 8)}`,
             // Composed clones ship a Dockerfile so baseImage stays blank;
             // peer clones use the plain hadronBase as their base image.
-            baseImage: hasComposition ? "" : a.hadronBase,
+            baseImage: hasComposition ? "" : clonedBase,
             kairosVersion: a.kairosVersion,
             model: a.model,
             arch: a.arch || "amd64",
@@ -927,6 +992,9 @@ export function ArtifactBuilder() {
             kubernetesDistro: a.kubernetesDistro || "",
             kubernetesVersion: a.kubernetesVersion || "",
             kubernetesEnabled: a.variant === "standard" ? a.kubernetesEnabled ?? true : true,
+            kairosInitImage: exact
+              ? pinImage(a.kairosInitImage, a.kairosInitImageDigest)
+              : a.kairosInitImage || "",
             outputs: {
               iso: a.iso,
               cloudImage: a.cloudImage,
@@ -935,12 +1003,24 @@ export function ArtifactBuilder() {
               tar: a.tar ?? false,
               gce: a.gce ?? false,
               vhd: a.vhd ?? false,
+              vhdDynamic: a.vhdDynamic ?? false,
+              vhdx: a.vhdx ?? false,
               maas: a.maas ?? false,
+              qcow2: a.qcow2 ?? false,
+              qcow2Compress: a.qcow2Compress ?? false,
+              vmdk: a.vmdk ?? false,
+              ova: a.ova ?? false,
+              ovaCpus: a.ovaCpus,
+              ovaMemoryMB: a.ovaMemoryMB,
+              ovaUserDataProperty: a.ovaUserDataProperty ?? false,
               uki: a.uki ?? false,
               fips: a.fips,
               trustedBoot: a.trustedBoot,
+              sbom: a.sbom ?? false,
+              sbomFormat: a.sbomFormat,
+              compression: a.compression,
             },
-            signing: { ...EMPTY_SIGNING },
+            signing: { ...EMPTY_SIGNING, manifestKeySetId: a.manifestKeySetId || "" },
             provisioning: {
               autoInstall: a.autoInstall ?? true,
               registerAuroraBoot: a.registerAuroraBoot ?? true,
@@ -962,7 +1042,7 @@ export function ArtifactBuilder() {
         setForm({
           ...EMPTY_FORM,
           name: `Copy of ${a.name || a.id.slice(0, 8)}`,
-          baseImage: a.baseImage,
+          baseImage: exact ? pinImage(a.baseImage, a.baseImageDigest) : a.baseImage,
           kairosVersion: a.kairosVersion,
           model: a.model,
           arch: a.arch || "amd64",
@@ -972,7 +1052,20 @@ export function ArtifactBuilder() {
           kubernetesEnabled: a.variant === "standard" ? a.kubernetesEnabled ?? true : true,
           "allow-insecure-registries": a["allow-insecure-registries"] ?? false,
           dockerfile: a.dockerfile || "",
-          kairosInitImage: a.kairosInitImage || "",
+          // An exact clone checks out the commit the original was built
+          // from rather than wherever its branch has moved since.
+          git: a.git
+            ? {
+                url: a.git.url,
+                ref: exact && a.git.commit ? a.git.commit : a.git.ref,
+                subpath: a.git.subpath,
+                username: a.git.username,
+                secret: a.git.secret,
+              }
+            : undefined,
+          kairosInitImage: exact
+            ? pinImage(a.kairosInitImage, a.kairosInitImageDigest)
+            : a.kairosInitImage || "",
           outputs: {
             iso: a.iso,
             cloudImage: a.cloudImage,
@@ -981,12 +1074,24 @@ export function ArtifactBuilder() {
             tar: a.tar ?? false,
             gce: a.gce ?? false,
             vhd: a.vhd ?? false,
+            vhdDynamic: a.vhdDynamic ?? false,
+            vhdx: a.vhdx ?? false,
             maas: a.maas ?? false,
+            qcow2: a.qcow2 ?? false,
+            qcow2Compress: a.qcow2Compress ?? false,
+            vmdk: a.vmdk ?? false,
+            ova: a.ova ?? false,
+            ovaCpus: a.ovaCpus,
+            ovaMemoryMB: a.ovaMemoryMB,
+            ovaUserDataProperty: a.ovaUserDataProperty ?? false,
             uki: a.uki ?? false,
             fips: a.fips,
             trustedBoot: a.trustedBoot,
+            sbom: a.sbom ?? false,
+            sbomFormat: a.sbomFormat,
+            compression: a.compression,
           },
-          signing: { ...EMPTY_SIGNING },
+          signing: { ...EMPTY_SIGNING, manifestKeySetId: a.manifestKeySetId || "" },
           provisioning: {
             autoInstall: a.autoInstall ?? true,
             registerAuroraBoot: a.registerAuroraBoot ?? true,
@@ -996,7 +1101,8 @@ export function ArtifactBuilder() {
             allowedCommands: [...PHONEHOME_SAFE_DEFAULTS],
           },
         });
-        if (a.dockerfile) setBuildMode("dockerfile");
+        if (a.git) setBuildMode("git");
+        else if (a.dockerfile) setBuildMode("dockerfile");
         if (a.cloudConfig) {
           setAdvancedConfig(a.cloudConfig);
           setShowAdvanced(true);
@@ -1012,6 +1118,10 @@ export function ArtifactBuilder() {
     setForm((prev) => ({ ...prev, [field]: value }));
   }
 
+  function updateGit(field: keyof GitSource, value: string) {
+    setForm((prev) => ({ ...prev, git: { url: "", ...prev.git, [field]: value } }));
+  }
+
   function updateOutput(field: keyof typeof EMPTY_OUTPUTS, value: boolean) {
     setForm((prev) => ({ ...prev, outputs: { ...prev.outputs, [field]: value } }));
   }
@@ -1077,6 +1187,9 @@ export function ArtifactBuilder() {
       if (buildMode === "dockerfile" && !form.dockerfile?.trim()) {
         errs.push({ field: "dockerfile", step: 0, message: "Dockerfile is required." });
       }
+      if (buildMode === "git" && !form.git?.url.trim()) {
+        errs.push({ field: "git", step: 0, message: "Repository URL is required." });
+      }
     }
 
     // Step 1 — Configure
@@ -1160,7 +1273,14 @@ export function ArtifactBuilder() {
     }
     setErrors([]);
 
-    const input: CreateArtifactInput = {
+    const result = await createArtifact(buildInput());
+    navigate(`/artifacts/${result.id}`);
+  }
+
+  // buildInput assembles the create request from the wizard state. It is
+  // what Build submits and what "Save as template" stores.
+  function buildInput(): CreateArtifactInput {
+    return {
       name: form.name || undefined,
       baseImage: form.baseImage,
       // Belt and braces: if the user cleared the Version field after we
@@ -1177,6 +1297,7 @@ export function ArtifactBuilder() {
       "allow-insecure-registries":
         buildMode === "image" ? form["allow-insecure-registries"] : undefined,
       dockerfile: buildMode === "dockerfile" ? form.dockerfile : undefined,
+      git: buildMode === "git" ? form.git : undefined,
       hadronBase:
         selectedTemplate === HADRON_TEMPLATE_NAME
           ? hadronBase || undefined
@@ -1203,9 +1324,54 @@ export function ArtifactBuilder() {
       // Send only the user's extra YAML — backend builds the canonical document.
       cloudConfig: advancedConfig.trim() || undefined,
     };
+  }
 
-    const result = await createArtifact(input);
-    navigate(`/artifacts/${result.id}`);
+  // Server templates are shared recipes; saving one stores the current
+  // wizard state without its name, so builds from it are named after the
+  // template unless they say otherwise.
+  async function handleSaveTemplate() {
+    const name = window.prompt("Save these settings as a server template named:", form.name || "");
+    if (!name?.trim()) return;
+    const spec = buildInput();
+    delete spec.name;
+    try {
+      await createBuildTemplate({ name: name.trim(), spec });
+      toast(`Saved template ${name.trim()}`, "success");
+      listBuildTemplates().then(setServerTemplates).catch(() => {});
+    } catch (err) {
+      toast(err instanceof Error ? err.message : "Failed to save template", "error");
+    }
+  }
+
+  // applyServerTemplate prefills the wizard from a server template with its
+  // parameter defaults substituted, like the built-in templates do.
+  async function applyServerTemplate(t: ServerBuildTemplate) {
+    let full: ServerBuildTemplate;
+    try {
+      full = await getBuildTemplate(t.id);
+    } catch {
+      toast(`Failed to load template ${t.name}`, "error");
+      return;
+    }
+    const values = applyTemplateDefaults(full.spec ?? {}, full.parameters);
+    setSelectedTemplate(`server:${t.id}`);
+    setForm((prev) => ({
+      ...EMPTY_FORM,
+      ...values,
+      name: prev.name,
+      outputs: { ...EMPTY_OUTPUTS, ...values.outputs },
+      signing: { ...EMPTY_SIGNING, ...values.signing },
+      provisioning: { ...EMPTY_PROVISIONING, ...values.provisioning },
+    }));
+    setBuildMode(values.git ? "git" : values.dockerfile ? "dockerfile" : "image");
+    setAdvancedConfig(values.cloudConfig ?? "");
+    const mode = values.provisioning?.userMode;
+    if (mode === "default" || mode === "custom" || mode === "none") setUserMode(mode);
+    if (values.provisioning?.username) setUsername(values.provisioning.username);
+    if (values.provisioning?.password) setPassword(values.provisioning.password);
+    if (values.provisioning?.sshKeys) setSshKeys(values.provisioning.sshKeys);
+    setCustomModel(false);
+    setStep(1);
   }
 
   const availableModels = modelsForArch(form.arch);
@@ -1249,6 +1415,10 @@ export function ArtifactBuilder() {
           <Download className="h-4 w-4 mr-2" />
           Export
         </Button>
+        <Button type="button" variant="outline" size="sm" onClick={handleSaveTemplate}>
+          <Save className="h-4 w-4 mr-2" />
+          Save as template
+        </Button>
       </PageHeader>
 
       {/* Step indicator */}
@@ -1359,6 +1529,22 @@ export function ArtifactBuilder() {
                       </CardContent>
                     </Card>
                   ))}
+                  {serverTemplates.map((t) => (
+                    <Card
+                      key={t.id}
+                      className={`cursor-pointer transition-colors ${
+                        selectedTemplate === `server:${t.id}`
+                          ? "border-[#EE5007] bg-[#EE5007]/5 ring-1 ring-[#EE5007]/20"
+                          : "hover:border-[#FF7442]/40"
+                      }`}
+                      onClick={() => applyServerTemplate(t)}
+                    >
+                      <CardContent className="p-3">
+                        <p className="font-medium text-sm">{t.name}</p>
+                        <p className="text-xs text-muted-foreground mt-1">{t.description || "Server template"}</p>
+                      </CardContent>
+                    </Card>
+                  ))}
                 </div>
               </div>
             )}
@@ -1388,9 +1574,85 @@ export function ArtifactBuilder() {
                   >
                     Dockerfile
                   </Button>
+                  <Button
+                    type="button"
+                    size="sm"
+                    variant={buildMode === "git" ? "default" : "outline"}
+                    onClick={() => { setBuildMode("git"); update("dockerfile", ""); }}
+                  >
+                    Git Repository
+                  </Button>
                 </div>
 
-                {buildMode === "image" ? (
+                {buildMode === "git" ? (
+                  <div className="grid grid-cols-2 gap-4">
+                    <div className="col-span-2 grid gap-2">
+                      <Label>
+                        Repository URL
+                        <InfoTooltip>
+                          Cloned at build time. The Dockerfile and overlay/ directory in the subpath are used as the
+                          build's Dockerfile and overlay files.
+                        </InfoTooltip>
+                      </Label>
+                      <Input
+                        ref={bindRef("git")}
+                        placeholder="https://github.com/acme/edge-images.git"
+                        value={form.git?.url || ""}
+                        onChange={(e) => updateGit("url", e.target.value)}
+                        className="font-mono"
+                      />
+                    </div>
+                    <div className="grid gap-2">
+                      <Label>Branch, tag or commit</Label>
+                      <Input
+                        placeholder="default branch"
+                        value={form.git?.ref || ""}
+                        onChange={(e) => updateGit("ref", e.target.value)}
+                        className="font-mono"
+                      />
+                    </div>
+                    <div className="grid gap-2">
+                      <Label>Subpath</Label>
+                      <Input
+                        placeholder="repository root"
+                        value={form.git?.subpath || ""}
+                        onChange={(e) => updateGit("subpath", e.target.value)}
+                        className="font-mono"
+                      />
+                    </div>
+                    <div className="grid gap-2">
+                      <Label>
+                        Credentials
+                        <InfoTooltip>A secret holding a token for private repositories. Manage secrets in Settings.</InfoTooltip>
+                      </Label>
+                      <Select
+                        value={form.git?.secret || "__none__"}
+                        onValueChange={(v) => updateGit("secret", v === "__none__" ? "" : v)}
+                      >
+                        <SelectTrigger>
+                          <SelectValue />
+                        </SelectTrigger>
+                        <SelectContent>
+                          <SelectItem value="__none__">Public repository</SelectItem>
+                          {secrets.map((sec) => (
+                            <SelectItem key={sec.name} value={sec.name}>
+                              {sec.name}
+                            </SelectItem>
+                          ))}
+                        </SelectContent>
+                      </Select>
+                    </div>
+                    <div className="grid gap-2">
+                      <Label>Username</Label>
+                      <Input
+                        placeholder="git"
+                        value={form.git?.username || ""}
+                        onChange={(e) => updateGit("username", e.target.value)}
+                        disabled={!form.git?.secret}
+                      />
+                    </div>
+                  </div>
+                ) : buildMode === "image" ? (
                   <>
                   <div className="grid gap-2">
                     <Label>
@@ -2291,6 +2553,125 @@ export function ArtifactBuilder() {
                     </div>
                   ))}
 
+                  {form.outputs.vhd && (
+                    <div>
+                      <label className="flex items-center gap-2 text-sm font-medium">
+                        <input
+                          type="checkbox"
+                          checked={!!form.outputs.vhdDynamic}
+                          onChange={(e) => updateOutput("vhdDynamic", e.target.checked)}
+                          className="rounded border-input"
+                        />
+                        Dynamic VHD
+                      </label>
+                      <p className="text-xs text-muted-foreground mt-1 ml-6">
+                        Store only the blocks that hold data instead of the whole disk. Azure only accepts fixed
+                        VHDs; use this for Hyper-V and other hypervisors.
+                      </p>
+                    </div>
+                  )}
+
+                  {form.outputs.qcow2 && (
+                    <div>
+                      <label className="flex items-center gap-2 text-sm font-medium">
+                        <input
+                          type="checkbox"
+                          checked={!!form.outputs.qcow2Compress}
+                          onChange={(e) => updateOutput("qcow2Compress", e.target.checked)}
+                          className="rounded border-input"
+                        />
+                        Compress QCOW2
+                      </label>
+                      <p className="text-xs text-muted-foreground mt-1 ml-6">
+                        Deflate the image's clusters, like qemu-img convert -c. Smaller to ship, slower to read.
+                      </p>
+                    </div>
+                  )}
+
+                  {form.outputs.ova && (
+                    <div className="space-y-3">
+                      <p className="text-sm font-medium">OVA virtual machine</p>
+                      <div className="grid grid-cols-2 gap-3">
+                        <div className="space-y-1">
+                          <Label className="text-xs">CPUs</Label>
+                          <Input
+                            type="number"
+                            min={1}
+                            value={form.outputs.ovaCpus || ""}
+                            onChange={(e) =>
+                              setForm((prev) => ({
+                                ...prev,
+                                outputs: { ...prev.outputs, ovaCpus: Number(e.target.value) || undefined },
+                              }))
+                            }
+                            placeholder="2"
+                          />
+                        </div>
+                        <div className="space-y-1">
+                          <Label className="text-xs">Memory (MiB)</Label>
+                          <Input
+                            type="number"
+                            min={1}
+                            value={form.outputs.ovaMemoryMB || ""}
+                            onChange={(e) =>
+                              setForm((prev) => ({
+                                ...prev,
+                                outputs: { ...prev.outputs, ovaMemoryMB: Number(e.target.value) || undefined },
+                              }))
+                            }
+                            placeholder="4096"
+                          />
+                        </div>
+                      </div>
+                      <label className="flex items-center gap-2 text-sm font-medium">
+                        <input
+                          type="checkbox"
+                          checked={!!form.outputs.ovaUserDataProperty}
+                          onChange={(e) => updateOutput("ovaUserDataProperty", e.target.checked)}
+                          className="rounded border-input"
+                        />
+                        Cloud-config vApp property
+                      </label>
+                      <p className="text-xs text-muted-foreground ml-6">
+                        Adds a user-data property to the appliance, so a base64 encoded cloud-config can be
+                        given when it is deployed in vSphere. The machine always boots with EFI firmware.
+                      </p>
+                    </div>
+                  )}
+
+                  {(form.outputs.iso || form.outputs.rawDisk || form.outputs.cloudImage || form.outputs.vhd) && (
+                    <div className="space-y-2">
+                      <Label>Compression</Label>
+                      <Select
+                        value={form.outputs.compression || "none"}
+                        onValueChange={(v) =>
+                          setForm((prev) => ({
+                            ...prev,
+                            outputs: {
+                              ...prev.outputs,
+                              compression: v === "none" ? undefined : (v as OutputCompression),
+                            },
+                          }))
+                        }
+                      >
+                        <SelectTrigger>
+                          <SelectValue />
+                        </SelectTrigger>
+                        <SelectContent>
+                          <SelectItem value="none">None</SelectItem>
+                          <SelectItem value="zstd">zstd</SelectItem>
+                          <SelectItem value="xz">xz</SelectItem>
+                          <SelectItem value="gzip">gzip</SelectItem>
+                        </SelectContent>
+                      </Select>
+                      <p className="text-xs text-muted-foreground">
+                        Replace the raw disk and VHD with compressed copies once every other output has been
+                        built from them. The ISO gets a compressed copy too but is kept for booting. zstd is the fastest and compresses on every CPU; xz is the
+                        smallest.
+                      </p>
+                    </div>
+                  )}
+
                   {form.outputs.uki && (
                     <div className="rounded-md bg-amber-500/10 border border-amber-500/25 p-3 flex gap-2">
                       <AlertTriangle className="h-4 w-4 text-amber-600 shrink-0 mt-0.5" />
@@ -2563,6 +2944,84 @@ export function ArtifactBuilder() {
                 </CardContent>
               </Card>
 
+              {/* SBOM */}
+              <Card>
+                <CardHeader className="pb-3">
+                  <CardTitle className="text-sm">Software Bill of Materials</CardTitle>
+                </CardHeader>
+                <CardContent className="space-y-4">
+                  <div>
+                    <label className="flex items-center gap-2 text-sm font-medium">
+                      <input
+                        type="checkbox"
+                        checked={!!form.outputs.sbom}
+                        onChange={(e) => updateOutput("sbom", e.target.checked)}
+                        className="rounded border-input"
+                      />
+                      Generate SBOM
+                    </label>
+                    <p className="text-xs text-muted-foreground mt-1 ml-6">
+                      Inventory the installed packages (dpkg, rpm, apk) into an SBOM stored with the
+                      artifacts. If the server has an advisory database configured, the packages are
+                      also checked for known vulnerabilities.
+                    </p>
+                  </div>
+                  {form.outputs.sbom && (
+                    <div className="ml-6 space-y-2">
+                      <Label>Format</Label>
+                      <Select
+                        value={form.outputs.sbomFormat || "spdx-json"}
+                        onValueChange={(v) =>
+                          setForm((prev) => ({
+                            ...prev,
+                            outputs: { ...prev.outputs, sbomFormat: v as SBOMFormat },
+                          }))
+                        }
+                      >
+                        <SelectTrigger>
+                          <SelectValue />
+                        </SelectTrigger>
+                        <SelectContent>
+                          <SelectItem value="spdx-json">SPDX 2.3 (JSON)</SelectItem>
+                          <SelectItem value="cyclonedx-json">CycloneDX 1.5 (JSON)</SelectItem>
+                        </SelectContent>
+                      </Select>
+                    </div>
+                  )}
+                </CardContent>
+              </Card>
+
+              {/* Checksums */}
+              <Card>
+                <CardHeader className="pb-3">
+                  <CardTitle className="text-sm">Checksums</CardTitle>
+                </CardHeader>
+                <CardContent className="space-y-2">
+                  <p className="text-xs text-muted-foreground">
+                    Every build writes a SHA256SUMS manifest of its outputs. Pick a key set to sign it
+                    with its db key, or leave the server default (signed only if the server has a
+                    manifest signing key). Check a download with <code>auroraboot verify</code>.
+                  </p>
+                  <Label>Signing key set</Label>
+                  <Select
+                    value={form.signing.manifestKeySetId || "__none__"}
+                    onValueChange={(v) => updateSigning("manifestKeySetId", v === "__none__" ? "" : v)}
+                  >
+                    <SelectTrigger>
+                      <SelectValue />
+                    </SelectTrigger>
+                    <SelectContent>
+                      <SelectItem value="__none__">Server default</SelectItem>
+                      {keySets.map((ks) => (
+                        <SelectItem key={ks.id} value={ks.id}>
+                          {ks.name}
+                        </SelectItem>
+                      ))}
+                    </SelectContent>
+                  </Select>
+                </CardContent>
+              </Card>
+
               {/* Overlay Files */}
               <Card>
                 <CardHeader className="pb-3">
@@ -2677,8 +3136,24 @@ export function ArtifactBuilder() {
                         rows={6}
                         className="font-mono text-xs"
                       />
+                      {advancedLint && [...advancedLint.errors, ...advancedLint.warnings].length > 0 && (
+                        <ul className="space-y-0.5 text-xs font-mono">
+                          {[...advancedLint.errors, ...advancedLint.warnings].map((issue, i) => (
+                            <li
+                              key={i}
+                              className={issue.severity === "error" ? "text-destructive" : "text-amber-700 dark:text-amber-400"}
+                            >
+                              {issue.line ? `line ${issue.line}: ` : ""}
+                              {issue.path ? `${issue.path}: ` : ""}
+                              {issue.message}
+                            </li>
+                          ))}
+                        </ul>
+                      )}
                       <p className="text-xs text-muted-foreground">
                         Appended to the generated config. AuroraBoot registration is auto-injected by the server.
+                        Use <code className="font-mono">{'\'{{ secret "name" }}\''}</code> for a password or key kept in
+                        Settings &rarr; Secrets; it is filled in only inside the build.
                       </p>
                     </div>
                     <div className="grid gap-2">
@@ -2734,7 +3209,11 @@ export function ArtifactBuilder() {
                     <div className="flex gap-2">
                       <span className="text-muted-foreground w-28 shrink-0">Image source:</span>
                       <span className="font-mono text-xs break-all">
-                        {buildMode === "dockerfile" ? "(Dockerfile)" : form.baseImage || "\u2014"}
+                        {buildMode === "git"
+                          ? `${form.git?.url || "\u2014"} @ ${form.git?.ref || "default branch"}`
+                          : buildMode === "dockerfile"
+                            ? "(Dockerfile)"
+                            : form.baseImage || "\u2014"}
                       </span>
                     </div>
                     {buildMode === "image" && form["allow-insecure-registries"] && (
@@ -4,6 +4,7 @@ import {
   getArtifact,
   getArtifactLogs,
   cancelArtifact,
+  retryArtifact,
   deleteArtifact,
   updateArtifact,
   artifactDownloadUrl,
@@ -18,6 +19,7 @@ import {
   Download,
   XCircle,
   Trash2,
+  RotateCcw,
   Bookmark,
   Pencil,
   Check,
@@ -47,8 +49,13 @@ import {
   Loader2,
   ChevronDown,
   ChevronRight,
+  Pin,
+  Upload,
+  Tags,
 } from "lucide-react";
 import { DeployDialog } from "@/components/DeployDialog";
+import { PublishDialog } from "@/components/PublishDialog";
+import { PromoteDialog } from "@/components/PromoteDialog";
 import { ansiToHtml } from "@/lib/ansi";
 
 // LogLine is a memoized single-row renderer for the build-log pane. Long
@@ -100,9 +107,12 @@ export function ArtifactDetail() {
   const [groups, setGroups] = useState<Group[]>([]);
   const [logs, setLogs] = useState<string>("");
   const [cancelling, setCancelling] = useState(false);
+  const [retrying, setRetrying] = useState(false);
   const [deleting, setDeleting] = useState(false);
   const [editingName, setEditingName] = useState(false);
   const [showDeploy, setShowDeploy] = useState(false);
+  const [showPublish, setShowPublish] = useState(false);
+  const [showPromote, setShowPromote] = useState(false);
   const [nameInput, setNameInput] = useState("");
   const [confirmOpen, setConfirmOpen] = useState(false);
   const logsContainerRef = useRef<HTMLDivElement>(null);
@@ -242,6 +252,17 @@ export function ArtifactDetail() {
     }
   }
 
+  async function handleRetry() {
+    if (!id) return;
+    setRetrying(true);
+    try {
+      await retryArtifact(id);
+      fetchArtifact();
+    } finally {
+      setRetrying(false);
+    }
+  }
+
   async function handleDelete() {
     if (!id) return;
     setDeleting(true);
@@ -350,15 +371,22 @@ export function ArtifactDetail() {
       items: [
         { on: artifact.rawDisk, label: "Raw disk", icon: HardDrive },
         { on: artifact.cloudImage, label: "Cloud image", icon: Cloud },
+        { on: artifact.qcow2, label: "QCOW2", icon: HardDrive },
+        { on: artifact.vmdk, label: "VMDK", icon: HardDrive },
+        { on: artifact.ova, label: "OVA", icon: Server },
         { on: artifact.gce, label: "Google Cloud", icon: CloudCog },
-        { on: artifact.vhd, label: "Azure (VHD)", icon: CloudCog },
+        { on: artifact.vhd, label: artifact.vhdDynamic ? "VHD (dynamic)" : "Azure (VHD)", icon: CloudCog },
+        { on: artifact.vhdx, label: "Hyper-V (VHDX)", icon: Server },
         { on: artifact.maas, label: "MAAS", icon: Server },
       ],
     },
     {
       title: "Archives",
       tone: "neutral" as const,
-      items: [{ on: artifact.tar, label: "TAR", icon: Package }],
+      items: [
+        { on: artifact.tar, label: "TAR", icon: Package },
+        { on: !!artifact.compression, label: `Compressed (${artifact.compression})`, icon: Package },
+      ],
     },
   ];
   const hasSecurityFlags = artifact.fips || artifact.trustedBoot;
@@ -466,6 +494,17 @@ export function ArtifactDetail() {
           <Copy className="h-4 w-4 mr-2" />
           Clone Build
         </Button>
+        {(artifact.baseImageDigest || artifact.kairosInitImageDigest) && (
+          <Button
+            variant="outline"
+            size="sm"
+            title="Rebuild from the image digests this build resolved, not the current tags"
+            onClick={() => navigate(`/artifacts/new?clone=${artifact.id}&exact=1`)}
+          >
+            <Pin className="h-4 w-4 mr-2" />
+            Clone Exactly
+          </Button>
+        )}
         <Button variant="outline" size="sm" onClick={handleExportConfig}>
           <FileDown className="h-4 w-4 mr-2" />
           Export Config
@@ -479,6 +518,16 @@ export function ArtifactDetail() {
             <Rocket className="h-4 w-4 mr-2" /> Deploy
           </Button>
         )}
+        {!isActive && artifact.phase === "Ready" && (
+          <Button variant="outline" size="sm" onClick={() => setShowPublish(true)}>
+            <Upload className="h-4 w-4 mr-2" /> Publish
+          </Button>
+        )}
+        {!isActive && artifact.phase === "Ready" && artifact.containerImage && (
+          <Button variant="outline" size="sm" onClick={() => setShowPromote(true)}>
+            <Tags className="h-4 w-4 mr-2" /> Promote
+          </Button>
+        )}
         {isActive && (
           <Button
             variant="destructive"
@@ -524,6 +573,12 @@ export function ArtifactDetail() {
               <span className="relative inline-flex rounded-full h-2 w-2 bg-[#EE5007]" />
             </span>
             Building · {durationText}
+            {artifact.step && (
+              <span className="font-mono text-xs">
+                · {artifact.step}
+                {artifact.stepProgress ? ` ${artifact.stepProgress}%` : ""}
+              </span>
+            )}
           </span>
         )}
         {!isActive && durationSec > 0 && (
@@ -538,7 +593,8 @@ export function ArtifactDetail() {
       </div>
 
       {/* Error hero: promoted when a build failed, with the failure reason
-          and a one-click "Clone & retry" path so the user isn't left
+          and one-click "Retry from failed step" and "Clone & retry" paths
+          so the user isn't left
           staring at a log trace without a next step. */}
       {artifact.phase === "Error" && artifact.message && (
         <div className="rounded-xl border border-red-500/30 bg-red-500/5 p-5 animate-fade-up">
@@ -557,6 +613,16 @@ export function ArtifactDetail() {
                 <Button
                   size="sm"
                   className="bg-[#EE5007] hover:bg-[#FF7442] text-white"
+                  onClick={handleRetry}
+                  disabled={retrying}
+                  title="Rerun this build, skipping the steps it completed"
+                >
+                  <RotateCcw className="h-4 w-4 mr-2" />
+                  {retrying ? "Retrying..." : "Retry from failed step"}
+                </Button>
+                <Button
+                  size="sm"
+                  variant="outline"
                   onClick={() => navigate(`/artifacts/new?clone=${artifact.id}`)}
                 >
                   <Copy className="h-4 w-4 mr-2" />
@@ -642,6 +708,27 @@ export function ArtifactDetail() {
                   {artifact.containerImage}
                   <span className="text-muted-foreground">(docker tar)</span>
                 </a>
+                {artifact.containerImageDigest && (
+                  <p className="mt-1 font-mono text-[11px] text-muted-foreground break-all">
+                    {artifact.containerImageDigest}
+                  </p>
+                )}
+              </div>
+            )}
+            {artifact.publications && artifact.publications.length > 0 && (
+              <div className="border-t px-5 py-3">
+                <p className="text-[11px] uppercase tracking-wide text-muted-foreground mb-1.5">
+                  Published
+                </p>
+                <ul className="space-y-1.5">
+                  {artifact.publications.map((p) => (
+                    <li key={p.publishedAt} className="font-mono text-[11px] break-all">
+                      <span className="text-muted-foreground">{p.tags.join(", ")}</span>
+                      {p.image && <p>{p.image}</p>}
+                      {p.files && <p>{p.files}</p>}
+                    </li>
+                  ))}
+                </ul>
               </div>
             )}
           </CardContent>
@@ -683,6 +770,21 @@ export function ArtifactDetail() {
               <h3 className="text-sm font-semibold">Source</h3>
             </div>
             <dl className="grid grid-cols-1 md:grid-cols-3 gap-x-6 gap-y-4 text-sm">
+              {artifact.git && (
+                <div className="md:col-span-3">
+                  <dt className="text-xs uppercase tracking-wide text-muted-foreground mb-1">Git source</dt>
+                  <dd className="font-mono text-xs break-all">
+                    {artifact.git.url}
+                    {artifact.git.subpath ? ` /${artifact.git.subpath}` : ""}
+                    <span className="text-muted-foreground"> @ {artifact.git.ref || "default branch"}</span>
+                  </dd>
+                  {artifact.git.commit && (
+                    <dd className="font-mono text-[11px] text-muted-foreground break-all mt-0.5">
+                      {artifact.git.commit}
+                    </dd>
+                  )}
+                </div>
+              )}
               {artifact.dockerfile ? (
                 <div className="md:col-span-3">
                   <dt className="text-xs uppercase tracking-wide text-muted-foreground mb-1.5 flex items-center gap-1.5">
@@ -699,6 +801,20 @@ export function ArtifactDetail() {
                 <div className="md:col-span-2">
                   <dt className="text-xs uppercase tracking-wide text-muted-foreground mb-1">Base image</dt>
                   <dd className="font-mono text-xs break-all">{artifact.baseImage || "—"}</dd>
+                  {artifact.baseImageDigest && (
+                    <dd className="font-mono text-[11px] text-muted-foreground break-all mt-0.5">
+                      {artifact.baseImageDigest}
+                    </dd>
+                  )}
+                </div>
+              )}
+              {artifact.kairosInitImageDigest && (
+                <div className="md:col-span-2">
+                  <dt className="text-xs uppercase tracking-wide text-muted-foreground mb-1">kairos-init</dt>
+                  <dd className="font-mono text-xs break-all">{artifact.kairosInitImage || "—"}</dd>
+                  <dd className="font-mono text-[11px] text-muted-foreground break-all mt-0.5">
+                    {artifact.kairosInitImageDigest}
+                  </dd>
                 </div>
               )}
               <div>
@@ -787,6 +903,56 @@ export function ArtifactDetail() {
                   </div>
                 </div>
               )}
+              {artifact.sbom && (
+                <div className="flex items-center gap-3 flex-wrap">
+                  <span className="text-xs text-muted-foreground w-28 shrink-0">SBOM</span>
+                  <div className="flex gap-2 flex-wrap items-center">
+                    <span className="inline-flex items-center gap-1.5 text-xs font-medium px-2.5 py-1 rounded-md border">
+                      <FileCode className="h-3.5 w-3.5" />
+                      {artifact.sbomFormat === "cyclonedx-json" ? "CycloneDX" : "SPDX"}
+                    </span>
+                    {artifact.vulnerabilities ? (
+                      <>
+                        {(
+                          [
+                            ["Critical", artifact.vulnerabilities.critical, "border-red-500/30 bg-red-500/10 text-red-700"],
+                            ["High", artifact.vulnerabilities.high, "border-orange-500/30 bg-orange-500/10 text-orange-700"],
+                            ["Medium", artifact.vulnerabilities.medium, "border-amber-500/30 bg-amber-500/10 text-amber-700"],
+                            ["Low", artifact.vulnerabilities.low, "border-slate-500/30 bg-slate-500/10 text-slate-700"],
+                          ] as const
+                        ).map(([label, count, cls]) => (
+                          <span
+                            key={label}
+                            className={`inline-flex items-center text-xs font-medium px-2.5 py-1 rounded-md border ${cls}`}
+                          >
+                            {count} {label}
+                          </span>
+                        ))}
+                      </>
+                    ) : (
+                      <span className="text-xs text-muted-foreground">No vulnerability scan</span>
+                    )}
+                  </div>
+                </div>
+              )}
+              {artifactFiles.some((f) => f.endsWith("SHA256SUMS")) && (
+                <div className="flex items-center gap-3 flex-wrap">
+                  <span className="text-xs text-muted-foreground w-28 shrink-0">Checksums</span>
+                  <div className="flex gap-2 flex-wrap items-center">
+                    <span className="inline-flex items-center gap-1.5 text-xs font-medium px-2.5 py-1 rounded-md border">
+                      <ShieldCheck className="h-3.5 w-3.5" />
+                      SHA256SUMS
+                    </span>
+                    {artifactFiles.some((f) => f.endsWith("SHA256SUMS.sig")) ? (
+                      <span className="inline-flex items-center text-xs font-medium px-2.5 py-1 rounded-md border border-green-500/30 bg-green-500/10 text-green-700">
+                        Signed
+                      </span>
+                    ) : (
+                      <span className="text-xs text-muted-foreground">Unsigned</span>
+                    )}
+                  </div>
+                </div>
+              )}
             </div>
           </section>
 
@@ -1012,6 +1178,19 @@ export function ArtifactDetail() {
         />
       )}
 
+      {showPublish && (
+        <PublishDialog
+          artifactId={id!}
+          artifactName={artifact.name}
+          artifactFiles={artifactFiles.map(extractFilename)}
+          hasImage={!!artifact.containerImage}
+          onClose={() => setShowPublish(false)}
+          onPublished={() => fetchArtifact()}
+        />
+      )}
+
+      {showPromote && <PromoteDialog artifactId={id!} onClose={() => setShowPromote(false)} />}
+
       <ConfirmDialog
         open={confirmOpen}
         onOpenChange={setConfirmOpen}
@@ -1,9 +1,17 @@
 import { useEffect, useState } from "react";
 import { useParams, useNavigate, Link } from "react-router";
-import { getGroup, deleteGroup, sendGroupCommand, type Group } from "@/api/groups";
+import { getGroup, deleteGroup, sendGroupCommand, updateGroup, type Group } from "@/api/groups";
+import { listChannels, type Channel } from "@/api/channels";
 import { listNodes, type Node } from "@/api/nodes";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { Button } from "@/components/ui/button";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
 import { NodeTable } from "@/components/NodeTable";
 import { PageHeader } from "@/components/PageHeader";
 import { CommandDialog } from "@/components/CommandDialog";
@@ -25,6 +33,7 @@ export function GroupDetail() {
   const [cmdOpen, setCmdOpen] = useState(false);
   const [quickCommand, setQuickCommand] = useState<string | null>(null);
   const [confirmDelete, setConfirmDelete] = useState(false);
+  const [channels, setChannels] = useState<Channel[]>([]);
 
   async function handleDelete() {
     if (!id || !group) return;
@@ -42,8 +51,18 @@ export function GroupDetail() {
     if (!id) return;
     getGroup(id).then(setGroup).catch(() => {});
     listNodes({ group_id: id }).then(setNodes).catch(() => {});
+    listChannels().then(setChannels).catch(() => {});
   }, [id]);
 
+  async function handleFollow(channelId: string) {
+    if (!id) return;
+    try {
+      setGroup(await updateGroup(id, { followChannelId: channelId === "none" ? "" : channelId }));
+    } catch (err) {
+      toast(`Failed to update channel: ${(err as Error).message}`, "error");
+    }
+  }
+
   async function handleCommand(command: string, args: Record<string, unknown>) {
     if (!id) return;
     await sendGroupCommand(id, command, args);
@@ -133,6 +152,27 @@ export function GroupDetail() {
               <dt className="text-muted-foreground">Node Count</dt>
               <dd>{group.node_count}</dd>
             </div>
+            <div>
+              <dt className="text-muted-foreground">Follows channel</dt>
+              <dd>
+                <Select value={group.followChannelId || "none"} onValueChange={handleFollow}>
+                  <SelectTrigger className="h-8 max-w-xs">
+                    <SelectValue />
+                  </SelectTrigger>
+                  <SelectContent>
+                    <SelectItem value="none">None</SelectItem>
+                    {channels.map((ch) => (
+                      <SelectItem key={ch.id} value={ch.id}>
+                        <span className="font-mono">{ch.name}</span>
+                      </SelectItem>
+                    ))}
+                  </SelectContent>
+                </Select>
+                <p className="mt-1 text-xs text-muted-foreground">
+                  Nodes upgrade whenever the channel is promoted or rolled back.
+                </p>
+              </dd>
+            </div>
           </dl>
         </CardContent>
       </Card>
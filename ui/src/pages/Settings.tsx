@@ -1,10 +1,38 @@
-import { useEffect, useState } from "react";
+import { useEffect, useRef, useState, type ChangeEvent, type FormEvent } from "react";
 import { getRegistrationToken, rotateRegistrationToken } from "@/api/settings";
+import { type Registry, type RegistryInput, listRegistries, createRegistry, deleteRegistry } from "@/api/registries";
+import { type Secret, listSecrets, setSecret, deleteSecret } from "@/api/secrets";
+import {
+  type BuildTemplate,
+  listBuildTemplates,
+  deleteBuildTemplate,
+  exportBuildTemplate,
+  importBuildTemplate,
+} from "@/api/templates";
+import {
+  type Channel,
+  type ChannelEntry,
+  listChannels,
+  createChannel,
+  deleteChannel,
+  getChannelHistory,
+  rollbackChannel,
+} from "@/api/channels";
+import {
+  type RetentionPlan,
+  type RetentionPolicy,
+  getRetentionPolicy,
+  setRetentionPolicy,
+  getRetentionPlan,
+  runRetention,
+} from "@/api/retention";
+import { type ImageWatchStatus, getImageWatch, setImageWatchSchedule, runImageWatch } from "@/api/imagewatch";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 import { PageHeader } from "@/components/PageHeader";
-import { Eye, EyeOff, RefreshCw } from "lucide-react";
+import { Label } from "@/components/ui/label";
+import { Download, Eye, EyeOff, FileUp, History, RefreshCw, Trash2, Undo2 } from "lucide-react";
 
 export function Settings() {
   const [token, setToken] = useState("");
@@ -66,7 +94,684 @@ export function Settings() {
             </div>
           </CardContent>
         </Card>
+
+        <RegistriesCard />
+        <SecretsCard />
+        <BuildTemplatesCard />
+        <ChannelsCard />
+        <RetentionCard />
+        <ImageWatchCard />
       </div>
     </div>
   );
 }
+
+const EMPTY_REGISTRY: RegistryInput = { name: "", host: "", namespace: "", username: "", password: "", insecure: false };
+
+// RegistriesCard manages the OCI registries artifacts can be published to.
+// Passwords are write-only; the server never sends them back.
+function RegistriesCard() {
+  const [registries, setRegistries] = useState<Registry[]>([]);
+  const [form, setForm] = useState<RegistryInput>(EMPTY_REGISTRY);
+  const [error, setError] = useState("");
+  const [saving, setSaving] = useState(false);
+
+  const refresh = () => listRegistries().then(setRegistries).catch(() => {});
+  useEffect(() => {
+    refresh();
+  }, []);
+
+  async function handleAdd(e: FormEvent) {
+    e.preventDefault();
+    setSaving(true);
+    setError("");
+    try {
+      await createRegistry(form);
+      setForm(EMPTY_REGISTRY);
+      refresh();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to add registry");
+    } finally {
+      setSaving(false);
+    }
+  }
+
+  async function handleDelete(r: Registry) {
+    if (!confirm(`Remove registry "${r.name}"?`)) return;
+    await deleteRegistry(r.id);
+    refresh();
+  }
+
+  const field = (key: keyof RegistryInput) => ({
+    value: (form[key] as string) || "",
+    onChange: (e: ChangeEvent<HTMLInputElement>) => setForm({ ...form, [key]: e.target.value }),
+  });
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle className="text-sm font-medium">Publishing Registries</CardTitle>
+      </CardHeader>
+      <CardContent className="grid gap-4">
+        <p className="text-sm text-muted-foreground">
+          OCI registries finished builds can be published to. Credentials are stored encrypted.
+        </p>
+        {registries.length > 0 && (
+          <ul className="divide-y rounded-md border">
+            {registries.map((r) => (
+              <li key={r.id} className="flex items-center gap-3 px-3 py-2 text-sm">
+                <span className="font-medium">{r.name}</span>
+                <span className="flex-1 font-mono text-xs text-muted-foreground truncate">
+                  {r.host}
+                  {r.namespace ? `/${r.namespace}` : ""}
+                  {r.insecure ? " (HTTP)" : ""}
+                </span>
+                <Button variant="ghost" size="icon" className="h-7 w-7" onClick={() => handleDelete(r)}>
+                  <Trash2 className="h-4 w-4" />
+                </Button>
+              </li>
+            ))}
+          </ul>
+        )}
+        <form onSubmit={handleAdd} className="grid grid-cols-2 gap-3">
+          <div className="space-y-1">
+            <Label className="text-xs">Name</Label>
+            <Input {...field("name")} placeholder="ghcr" />
+          </div>
+          <div className="space-y-1">
+            <Label className="text-xs">Host</Label>
+            <Input {...field("host")} placeholder="ghcr.io" className="font-mono" />
+          </div>
+          <div className="space-y-1">
+            <Label className="text-xs">Namespace</Label>
+            <Input {...field("namespace")} placeholder="my-org" className="font-mono" />
+          </div>
+          <div className="space-y-1">
+            <Label className="text-xs">Username</Label>
+            <Input {...field("username")} />
+          </div>
+          <div className="space-y-1">
+            <Label className="text-xs">Password or token</Label>
+            <Input type="password" {...field("password")} />
+          </div>
+          <label className="flex items-end gap-2 pb-2 text-sm cursor-pointer">
+            <input
+              type="checkbox"
+              checked={!!form.insecure}
+              onChange={(e) => setForm({ ...form, insecure: e.target.checked })}
+            />
+            Plain HTTP
+          </label>
+          {error && (
+            <div className="col-span-2 bg-red-500/10 border border-red-500/25 text-red-700 rounded-md p-3 text-sm">
+              {error}
+            </div>
+          )}
+          <div className="col-span-2">
+            <Button type="submit" variant="outline" disabled={!form.name || !form.host || saving}>
+              Add Registry
+            </Button>
+          </div>
+        </form>
+      </CardContent>
+    </Card>
+  );
+}
+
+// SecretsCard manages named secrets, such as the tokens Git sources are
+// cloned with. Saving under an existing name replaces its value.
+function SecretsCard() {
+  const [secrets, setSecrets] = useState<Secret[]>([]);
+  const [name, setName] = useState("");
+  const [value, setValue] = useState("");
+  const [description, setDescription] = useState("");
+  const [error, setError] = useState("");
+  const [saving, setSaving] = useState(false);
+
+  const refresh = () => listSecrets().then(setSecrets).catch(() => {});
+  useEffect(() => {
+    refresh();
+  }, []);
+
+  async function handleSave(e: FormEvent) {
+    e.preventDefault();
+    setSaving(true);
+    setError("");
+    try {
+      await setSecret(name, { value, description: description || undefined });
+      setName("");
+      setValue("");
+      setDescription("");
+      refresh();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to save secret");
+    } finally {
+      setSaving(false);
+    }
+  }
+
+  async function handleDelete(s: Secret) {
+    if (!confirm(`Delete secret "${s.name}"? Rebuilds that use it will fail to start.`)) return;
+    await deleteSecret(s.name);
+    refresh();
+  }
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle className="text-sm font-medium">Secrets</CardTitle>
+      </CardHeader>
+      <CardContent className="grid gap-4">
+        <p className="text-sm text-muted-foreground">
+          Credentials builds can refer to by name, such as the token a Git source is cloned with. A cloud-config
+          uses one as <code className="font-mono text-xs">{'\'{{ secret "name" }}\''}</code>; the value is only filled in
+          inside the build, never saved with it. Values are stored encrypted and cannot be read back.
+        </p>
+        {secrets.length > 0 && (
+          <ul className="divide-y rounded-md border">
+            {secrets.map((s) => (
+              <li key={s.name} className="flex items-center gap-3 px-3 py-2 text-sm">
+                <span className="font-medium font-mono">{s.name}</span>
+                <span className="flex-1 text-xs text-muted-foreground truncate">{s.description}</span>
+                <Button variant="ghost" size="icon" className="h-7 w-7" onClick={() => handleDelete(s)}>
+                  <Trash2 className="h-4 w-4" />
+                </Button>
+              </li>
+            ))}
+          </ul>
+        )}
+        <form onSubmit={handleSave} className="grid grid-cols-2 gap-3">
+          <div className="space-y-1">
+            <Label className="text-xs">Name</Label>
+            <Input value={name} onChange={(e) => setName(e.target.value)} placeholder="github-token" className="font-mono" />
+          </div>
+          <div className="space-y-1">
+            <Label className="text-xs">Value</Label>
+            <Input type="password" value={value} onChange={(e) => setValue(e.target.value)} />
+          </div>
+          <div className="col-span-2 space-y-1">
+            <Label className="text-xs">Description</Label>
+            <Input value={description} onChange={(e) => setDescription(e.target.value)} />
+          </div>
+          {error && (
+            <div className="col-span-2 bg-red-500/10 border border-red-500/25 text-red-700 rounded-md p-3 text-sm">
+              {error}
+            </div>
+          )}
+          <div className="col-span-2">
+            <Button type="submit" variant="outline" disabled={!name || !value || saving}>
+              Save Secret
+            </Button>
+          </div>
+        </form>
+      </CardContent>
+    </Card>
+  );
+}
+
+// BuildTemplatesCard lists the server's build templates and moves them in
+// and out as YAML. Templates are created from the Artifact Builder with
+// "Save as template".
+function BuildTemplatesCard() {
+  const [templates, setTemplates] = useState<BuildTemplate[]>([]);
+  const [error, setError] = useState("");
+  const importRef = useRef<HTMLInputElement>(null);
+
+  const refresh = () => listBuildTemplates().then(setTemplates).catch(() => {});
+  useEffect(() => {
+    refresh();
+  }, []);
+
+  async function handleExport(t: BuildTemplate) {
+    setError("");
+    try {
+      const yaml = await exportBuildTemplate(t.id);
+      const url = URL.createObjectURL(new Blob([yaml], { type: "application/yaml" }));
+      const a = document.createElement("a");
+      a.href = url;
+      a.download = `${t.name.replace(/[^A-Za-z0-9._-]+/g, "-")}.yaml`;
+      a.click();
+      URL.revokeObjectURL(url);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to export template");
+    }
+  }
+
+  async function handleImport(e: ChangeEvent<HTMLInputElement>) {
+    const file = e.target.files?.[0];
+    e.target.value = "";
+    if (!file) return;
+    setError("");
+    try {
+      await importBuildTemplate(await file.text());
+      refresh();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to import template");
+    }
+  }
+
+  async function handleDelete(t: BuildTemplate) {
+    if (!confirm(`Delete template "${t.name}"? Artifacts built from it are kept.`)) return;
+    await deleteBuildTemplate(t.id);
+    refresh();
+  }
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle className="text-sm font-medium">Build Templates</CardTitle>
+      </CardHeader>
+      <CardContent className="grid gap-4">
+        <p className="text-sm text-muted-foreground">
+          Shared build recipes offered in the Artifact Builder and buildable through the API. Importing a template with
+          an existing name replaces it.
+        </p>
+        {templates.length > 0 && (
+          <ul className="divide-y rounded-md border">
+            {templates.map((t) => (
+              <li key={t.id} className="flex items-center gap-3 px-3 py-2 text-sm">
+                <span className="font-medium">{t.name}</span>
+                <span className="flex-1 text-xs text-muted-foreground truncate">
+                  {t.parameters?.length ? `Parameters: ${t.parameters.map((p) => p.name).join(", ")}` : t.description}
+                </span>
+                <Button variant="ghost" size="icon" className="h-7 w-7" onClick={() => handleExport(t)}>
+                  <Download className="h-4 w-4" />
+                </Button>
+                <Button variant="ghost" size="icon" className="h-7 w-7" onClick={() => handleDelete(t)}>
+                  <Trash2 className="h-4 w-4" />
+                </Button>
+              </li>
+            ))}
+          </ul>
+        )}
+        {error && (
+          <div className="bg-red-500/10 border border-red-500/25 text-red-700 rounded-md p-3 text-sm">{error}</div>
+        )}
+        <div>
+          <input
+            ref={importRef}
+            type="file"
+            accept=".yaml,.yml,application/yaml"
+            className="hidden"
+            onChange={handleImport}
+          />
+          <Button variant="outline" onClick={() => importRef.current?.click()}>
+            <FileUp className="h-4 w-4 mr-2" />
+            Import YAML
+          </Button>
+        </div>
+      </CardContent>
+    </Card>
+  );
+}
+
+// ChannelsCard manages artifact channels. Artifacts are promoted into a
+// channel from their detail page; rollback and history live here.
+function ChannelsCard() {
+  const [channels, setChannels] = useState<Channel[]>([]);
+  const [name, setName] = useState("");
+  const [error, setError] = useState("");
+  const [notice, setNotice] = useState("");
+  const [historyFor, setHistoryFor] = useState("");
+  const [history, setHistory] = useState<ChannelEntry[]>([]);
+
+  const refresh = () => listChannels().then(setChannels).catch(() => {});
+  useEffect(() => {
+    refresh();
+  }, []);
+
+  async function handleAdd(e: FormEvent) {
+    e.preventDefault();
+    setError("");
+    try {
+      await createChannel(name);
+      setName("");
+      refresh();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to create channel");
+    }
+  }
+
+  async function handleRollback(ch: Channel) {
+    if (!confirm(`Roll "${ch.name}" back to its previous artifact?`)) return;
+    setError("");
+    setNotice("");
+    try {
+      const res = await rollbackChannel(ch.id);
+      setNotice(`${ch.name} now points at ${res.channel.artifactId?.slice(0, 8)}; ${res.queued} upgrade(s) queued.`);
+      refresh();
+      if (historyFor === ch.id) setHistory(await getChannelHistory(ch.id));
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Rollback failed");
+    }
+  }
+
+  async function toggleHistory(ch: Channel) {
+    if (historyFor === ch.id) {
+      setHistoryFor("");
+      return;
+    }
+    setHistoryFor(ch.id);
+    setHistory(await getChannelHistory(ch.id).catch(() => []));
+  }
+
+  async function handleDelete(ch: Channel) {
+    if (!confirm(`Delete channel "${ch.name}"? Groups following it stop following.`)) return;
+    await deleteChannel(ch.id);
+    refresh();
+  }
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle className="text-sm font-medium">Channels</CardTitle>
+      </CardHeader>
+      <CardContent className="grid gap-4">
+        <p className="text-sm text-muted-foreground">
+          Named pointers such as <code>ubuntu-k3s/stable</code>. Promote artifacts from their detail page; groups
+          following a channel upgrade whenever it moves.
+        </p>
+        {channels.length > 0 && (
+          <ul className="divide-y rounded-md border">
+            {channels.map((ch) => (
+              <li key={ch.id} className="px-3 py-2 text-sm">
+                <div className="flex items-center gap-3">
+                  <span className="font-mono font-medium">{ch.name}</span>
+                  <span className="flex-1 font-mono text-xs text-muted-foreground truncate">
+                    {ch.artifactId ? ch.artifactId.slice(0, 8) : "empty"}
+                  </span>
+                  <Button variant="ghost" size="icon" className="h-7 w-7" title="History" onClick={() => toggleHistory(ch)}>
+                    <History className="h-4 w-4" />
+                  </Button>
+                  <Button
+                    variant="ghost"
+                    size="icon"
+                    className="h-7 w-7"
+                    title="Roll back"
+                    disabled={!ch.artifactId}
+                    onClick={() => handleRollback(ch)}
+                  >
+                    <Undo2 className="h-4 w-4" />
+                  </Button>
+                  <Button variant="ghost" size="icon" className="h-7 w-7" onClick={() => handleDelete(ch)}>
+                    <Trash2 className="h-4 w-4" />
+                  </Button>
+                </div>
+                {historyFor === ch.id && (
+                  <ul className="mt-2 space-y-1 text-xs text-muted-foreground">
+                    {history.length === 0 && <li>No promotions yet.</li>}
+                    {history.map((h) => (
+                      <li key={h.id} className="font-mono">
+                        {new Date(h.createdAt).toLocaleString()} {h.action} {h.previousArtifactId?.slice(0, 8) || "∅"} →{" "}
+                        {h.artifactId.slice(0, 8)}
+                        {h.fromChannel ? ` from ${h.fromChannel}` : ""}
+                        {h.note ? ` — ${h.note}` : ""}
+                      </li>
+                    ))}
+                  </ul>
+                )}
+              </li>
+            ))}
+          </ul>
+        )}
+        {notice && (
+          <div className="bg-green-500/10 border border-green-500/25 text-green-700 rounded-md p-3 text-sm">{notice}</div>
+        )}
+        {error && (
+          <div className="bg-red-500/10 border border-red-500/25 text-red-700 rounded-md p-3 text-sm">{error}</div>
+        )}
+        <form onSubmit={handleAdd} className="flex items-end gap-3">
+          <div className="flex-1 space-y-1">
+            <Label className="text-xs">Name</Label>
+            <Input
+              value={name}
+              onChange={(e) => setName(e.target.value)}
+              placeholder="ubuntu-k3s/stable"
+              className="font-mono"
+            />
+          </div>
+          <Button type="submit" variant="outline" disabled={!name}>
+            Add Channel
+          </Button>
+        </form>
+      </CardContent>
+    </Card>
+  );
+}
+
+const GIB = 1024 * 1024 * 1024;
+
+function formatBytes(n: number): string {
+  if (n >= GIB) return `${(n / GIB).toFixed(1)} GiB`;
+  return `${(n / (1024 * 1024)).toFixed(1)} MiB`;
+}
+
+// RetentionCard edits the artifact retention policy. Saved artifacts and
+// those used by a deployment, a channel or the netboot server are never
+// removed; the preview lists what the next run would delete.
+function RetentionCard() {
+  const [policy, setPolicy] = useState<RetentionPolicy>({ keepLastPerBase: 0, maxAgeDays: 0, maxTotalBytes: 0 });
+  const [plan, setPlan] = useState<RetentionPlan | null>(null);
+  const [ran, setRan] = useState(false);
+  const [error, setError] = useState("");
+  const [busy, setBusy] = useState(false);
+
+  useEffect(() => {
+    getRetentionPolicy().then(setPolicy).catch(() => {});
+  }, []);
+
+  async function wrap(fn: () => Promise<void>) {
+    setBusy(true);
+    setError("");
+    try {
+      await fn();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Request failed");
+    } finally {
+      setBusy(false);
+    }
+  }
+
+  const handleSave = (e: FormEvent) => {
+    e.preventDefault();
+    return wrap(async () => {
+      setPolicy(await setRetentionPolicy(policy));
+      setRan(false);
+      setPlan(await getRetentionPlan());
+    });
+  };
+
+  const handlePreview = () =>
+    wrap(async () => {
+      setRan(false);
+      setPlan(await getRetentionPlan());
+    });
+
+  const handleRun = () => {
+    if (!confirm("Delete the artifacts the retention policy selects now?")) return;
+    return wrap(async () => {
+      setPlan(await runRetention());
+      setRan(true);
+    });
+  };
+
+  const num = (key: "keepLastPerBase" | "maxAgeDays") => ({
+    type: "number",
+    min: 0,
+    value: policy[key] || "",
+    onChange: (e: ChangeEvent<HTMLInputElement>) => setPolicy({ ...policy, [key]: Number(e.target.value) || 0 }),
+  });
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle className="text-sm font-medium">Artifact Retention</CardTitle>
+      </CardHeader>
+      <CardContent className="grid gap-4">
+        <p className="text-sm text-muted-foreground">
+          Unsaved artifacts are removed hourly by the rules below; leave a rule empty to disable it. Saved artifacts and
+          those used by an active deployment, a channel (now or in its history) or the netboot server are always kept.
+        </p>
+        <form onSubmit={handleSave} className="grid grid-cols-3 gap-3">
+          <div className="space-y-1">
+            <Label className="text-xs">Keep last per base image</Label>
+            <Input {...num("keepLastPerBase")} placeholder="e.g. 5" />
+          </div>
+          <div className="space-y-1">
+            <Label className="text-xs">Delete after (days)</Label>
+            <Input {...num("maxAgeDays")} placeholder="e.g. 30" />
+          </div>
+          <div className="space-y-1">
+            <Label className="text-xs">Total size cap (GiB)</Label>
+            <Input
+              type="number"
+              min={0}
+              value={policy.maxTotalBytes ? policy.maxTotalBytes / GIB : ""}
+              onChange={(e) => setPolicy({ ...policy, maxTotalBytes: Math.round((Number(e.target.value) || 0) * GIB) })}
+              placeholder="e.g. 200"
+            />
+          </div>
+          <div className="col-span-3 flex gap-2">
+            <Button type="submit" variant="outline" disabled={busy}>
+              Save
+            </Button>
+            <Button type="button" variant="outline" onClick={handlePreview} disabled={busy}>
+              Preview
+            </Button>
+            <Button type="button" variant="outline" className="text-red-500" onClick={handleRun} disabled={busy}>
+              Run now
+            </Button>
+          </div>
+        </form>
+        {error && (
+          <div className="bg-red-500/10 border border-red-500/25 text-red-700 rounded-md p-3 text-sm">{error}</div>
+        )}
+        {plan && (
+          <div className="space-y-2 text-sm">
+            <p className="text-muted-foreground">
+              {ran ? "Removed" : "Would remove"} {plan.remove.length} artifact(s), freeing {formatBytes(plan.freedBytes)} of{" "}
+              {formatBytes(plan.totalBytes)}.
+              {plan.overBudget && " The size cap cannot be met: the rest is saved, in use or still building."}
+            </p>
+            {plan.remove.length > 0 && (
+              <ul className="divide-y rounded-md border">
+                {plan.remove.map((r) => (
+                  <li key={r.id} className="flex items-center gap-3 px-3 py-1.5 text-xs">
+                    <span className="font-mono">{r.name || r.id.slice(0, 8)}</span>
+                    <span className="flex-1 truncate text-muted-foreground">{r.base}</span>
+                    <span className="text-muted-foreground">{formatBytes(r.bytes)}</span>
+                    <span className="font-mono">{plan.failed?.[r.id] ? "failed" : r.reason}</span>
+                  </li>
+                ))}
+              </ul>
+            )}
+            {plan.kept && plan.kept.length > 0 && (
+              <p className="text-xs text-muted-foreground">
+                Kept: {plan.kept.map((k) => `${k.name || k.id.slice(0, 8)} (${k.protected})`).join(", ")}
+              </p>
+            )}
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
+
+// ImageWatchCard sets when the server checks the base images of saved
+// artifacts and template builds, and shows what the last check found.
+function ImageWatchCard() {
+  const [status, setStatus] = useState<ImageWatchStatus | null>(null);
+  const [schedule, setSchedule] = useState("");
+  const [error, setError] = useState("");
+  const [busy, setBusy] = useState(false);
+
+  useEffect(() => {
+    getImageWatch()
+      .then((s) => {
+        setStatus(s);
+        setSchedule(s.schedule);
+      })
+      .catch(() => {});
+  }, []);
+
+  async function wrap(fn: () => Promise<void>) {
+    setBusy(true);
+    setError("");
+    try {
+      await fn();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Request failed");
+    } finally {
+      setBusy(false);
+    }
+  }
+
+  const handleSave = (e: FormEvent) => {
+    e.preventDefault();
+    return wrap(async () => setStatus(await setImageWatchSchedule(schedule.trim())));
+  };
+
+  const handleRun = () =>
+    wrap(async () => {
+      const lastRun = await runImageWatch();
+      setStatus((s) => (s ? { ...s, lastRun } : s));
+    });
+
+  const last = status?.lastRun;
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle className="text-sm font-medium">Base Image Updates</CardTitle>
+      </CardHeader>
+      <CardContent className="grid gap-4">
+        <p className="text-sm text-muted-foreground">
+          On this cron schedule the server checks the base images of saved artifacts and template builds, and rebuilds
+          them when the tag points at new content. Leave it empty to turn the checks off.
+        </p>
+        <form onSubmit={handleSave} className="flex gap-2">
+          <Input
+            value={schedule}
+            onChange={(e) => setSchedule(e.target.value)}
+            placeholder="e.g. 0 3 * * * or @daily"
+            className="font-mono"
+          />
+          <Button type="submit" variant="outline" disabled={busy}>
+            Save
+          </Button>
+          <Button type="button" variant="outline" onClick={handleRun} disabled={busy}>
+            <RefreshCw className={`h-4 w-4 mr-2 ${busy ? "animate-spin" : ""}`} />
+            Check now
+          </Button>
+        </form>
+        {status?.nextRun && (
+          <p className="text-xs text-muted-foreground">Next check: {new Date(status.nextRun).toLocaleString()}</p>
+        )}
+        {error && (
+          <div className="bg-red-500/10 border border-red-500/25 text-red-700 rounded-md p-3 text-sm">{error}</div>
+        )}
+        {last && (
+          <div className="space-y-2 text-sm">
+            <p className="text-muted-foreground">
+              Last checked {new Date(last.finishedAt).toLocaleString()}: {last.checks.length} image(s),{" "}
+              {last.checks.filter((c) => c.changed).length} changed.
+            </p>
+            {last.checks.length > 0 && (
+              <ul className="divide-y rounded-md border">
+                {last.checks.map((c) => (
+                  <li key={c.artifactId} className="flex items-center gap-3 px-3 py-1.5 text-xs">
+                    <span className="flex-1 truncate font-mono">{c.image}</span>
+                    <span className="font-mono text-muted-foreground">{(c.digest || c.previousDigest).slice(7, 19)}</span>
+                    <span className={c.error ? "text-red-500" : ""} title={c.error}>
+                      {c.error ? "error" : c.rebuildId ? `rebuilding ${c.rebuildId.slice(0, 8)}` : c.changed ? "changed" : "up to date"}
+                    </span>
+                  </li>
+                ))}
+              </ul>
+            )}
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
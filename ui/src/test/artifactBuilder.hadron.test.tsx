@@ -155,4 +155,52 @@ describe("ArtifactBuilder: Hadron clone", () => {
       screen.getByDisplayValue(/v1\.31\.4\+k3s1/),
     ).toBeInTheDocument();
   });
+
+  it("pins the Hadron base to its recorded digest on an exact clone", async () => {
+    (getArtifact as ReturnType<typeof vi.fn>).mockResolvedValueOnce({
+      id: "src-2",
+      name: "src-hadron",
+      phase: "done",
+      message: "",
+      baseImage: "",
+      hadronBase: "ghcr.io/kairos-io/hadron:v0.5.1",
+      baseImageDigest: "sha256:aaaa",
+      kairosInitImage: "quay.io/kairos/kairos-init:v0.5.0",
+      kairosInitImageDigest: "sha256:bbbb",
+      hadronFirmware: [],
+      hadronLayers: [],
+      hadronExtra: "",
+      kairosVersion: "v4.1.2",
+      model: "generic",
+      arch: "amd64",
+      variant: "core",
+      iso: true,
+      cloudImage: false,
+      netboot: false,
+      rawDisk: false,
+      tar: false,
+      gce: false,
+      vhd: false,
+      maas: false,
+      uki: false,
+      fips: false,
+      trustedBoot: false,
+      autoInstall: true,
+      registerAuroraBoot: true,
+      artifacts: [],
+      createdAt: "2026-01-01T00:00:00Z",
+      updatedAt: "2026-01-01T00:00:00Z",
+    });
+
+    renderBuilder("/artifacts/new?clone=src-2&exact=1");
+
+    // The collapsed composer shows the effective base when nothing is
+    // composed on top of it.
+    await waitFor(() => {
+      expect(
+        screen.getByText("ghcr.io/kairos-io/hadron@sha256:aaaa"),
+      ).toBeInTheDocument();
+    });
+    expect(screen.queryByText("ghcr.io/kairos-io/hadron:v0.5.1")).not.toBeInTheDocument();
+  });
 });